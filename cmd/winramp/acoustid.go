@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/library"
+	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/metadata"
+)
+
+func (a *App) newAcoustIDClient() *metadata.AcoustIDClient {
+	network := a.config.Network
+	client := metadata.NewAcoustIDClient(network.AcoustIDKey, network.AcoustIDUserKey, network.Timeout)
+	client.SetConnectivity(a.connectivity)
+	return client
+}
+
+// GetAcoustIDStatus reports whether tracks can be identified by their
+// audio and fingerprints submitted
+func (a *App) GetAcoustIDStatus() map[string]interface{} {
+	return map[string]interface{}{
+		"configured":   a.acoustID.Configured(),
+		"canSubmit":    a.acoustID.CanSubmit(),
+		"fingerprints": a.fingerprinter.Available(),
+	}
+}
+
+// identifyTracks identifies tracks by their audio. Progress and the
+// summary are published through the identifier's events.
+func (a *App) identifyTracks(tracks []*domain.Track, submit bool) {
+	if _, err := a.identifier.Identify(a.ctx, tracks, submit); err != nil && a.ctx.Err() == nil {
+		logger.Warn("Identification failed", logger.Error(err))
+	}
+}
+
+// IdentifyTracks fingerprints tracks in the background and fills in the
+// tags of those missing their title or artist from AcoustID. With submit
+// set, or always when the settings ask for it, fingerprints of tagged
+// tracks are contributed back. Progress is reported through
+// "library:identifyProgress" and the summary through
+// "library:identifyFinished".
+func (a *App) IdentifyTracks(ids []string, submit bool) error {
+	tracks, err := a.resolveTracks(ids)
+	if err != nil {
+		return err
+	}
+	return a.startIdentify(tracks, submit)
+}
+
+// IdentifyUntaggedTracks identifies every library track missing its title
+// or artist, in the background
+func (a *App) IdentifyUntaggedTracks() error {
+	all, err := a.trackRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	var tracks []*domain.Track
+	for _, track := range all {
+		if library.NeedsIdentifying(track) {
+			tracks = append(tracks, track)
+		}
+	}
+	return a.startIdentify(tracks, false)
+}
+
+// startIdentify checks an identification can run before starting it in
+// the background, so the caller hears why it can't
+func (a *App) startIdentify(tracks []*domain.Track, submit bool) error {
+	submit = submit || a.config.Network.AcoustIDSubmit && a.acoustID.CanSubmit()
+	switch {
+	case a.identifier.IsRunning():
+		return fmt.Errorf("identification already in progress")
+	case !a.acoustID.Configured():
+		return metadata.ErrAcoustIDNotConfigured
+	case submit && !a.acoustID.CanSubmit():
+		return metadata.ErrAcoustIDNoUserKey
+	case !a.fingerprinter.Available():
+		return library.ErrFingerprinterMissing
+	}
+	go a.identifyTracks(tracks, submit)
+	return nil
+}
+
+// CancelIdentify stops a running identification
+func (a *App) CancelIdentify() {
+	a.identifier.Cancel()
+}
+
+// GetIdentifyProgress returns whether an identification is running and how
+// far it is (0-100)
+func (a *App) GetIdentifyProgress() map[string]interface{} {
+	return map[string]interface{}{
+		"running":  a.identifier.IsRunning(),
+		"progress": a.identifier.GetProgress(),
+	}
+}
+
+// onIdentifyFinished refreshes what depends on the tags of identified
+// tracks
+func (a *App) onIdentifyFinished(result *library.IdentifyResult) {
+	if result.Identified == 0 {
+		return
+	}
+	a.playlistMgr.LibraryChanged()
+	go a.rebuildSuggestions()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/metadata"
+)
+
+// GetAlbumArt returns the cover of an album, by AlbumKey, as a data URL.
+// Art missing from the library is looked for in the album's files and
+// folders and, when allowed, online. It returns nil when there is none.
+func (a *App) GetAlbumArt(albumKey string) (map[string]interface{}, error) {
+	tracks, err := a.findAlbumByKey(albumKey)
+	if err != nil {
+		return nil, err
+	}
+	return a.albumArt(tracks)
+}
+
+// GetTrackArt returns the cover of a track's album as a data URL, or the
+// track's own art when it is on no album. It returns nil when there is
+// none.
+func (a *App) GetTrackArt(trackID string) (map[string]interface{}, error) {
+	track, err := a.trackRepo.FindByID(trackID)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := []*domain.Track{track}
+	if key := track.AlbumKey(); key != "" {
+		if album, err := a.trackRepo.FindByAlbumKey(key); err == nil && len(album) > 0 {
+			tracks = album
+		}
+	}
+	return a.albumArt(tracks)
+}
+
+// albumArt finds the art of an album's tracks and reads it into a data URL
+func (a *App) albumArt(tracks []*domain.Track) (map[string]interface{}, error) {
+	path, err := a.artStore.AlbumArt(a.ctx, tracks)
+	if err != nil {
+		if errors.Is(err, metadata.ErrNoAlbumArt) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return albumArtToMap(path)
+}
+
+// albumArtToMap reads stored album art into a data URL, since the frontend
+// cannot load files from the cache directory
+func albumArtToMap(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mime := "image/jpeg"
+	if strings.EqualFold(filepath.Ext(path), ".png") {
+		mime = "image/png"
+	}
+	return map[string]interface{}{
+		"path":    path,
+		"dataUrl": "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
@@ -2,35 +2,132 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 	
 	"github.com/winramp/winramp/internal/audio"
+	"github.com/winramp/winramp/internal/audio/decoder"
 	"github.com/winramp/winramp/internal/config"
+	"github.com/winramp/winramp/internal/connectivity"
+	"github.com/winramp/winramp/internal/discovery"
 	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
+	"github.com/winramp/winramp/internal/hooks"
 	"github.com/winramp/winramp/internal/infrastructure/db"
+	"github.com/winramp/winramp/internal/library"
 	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/metadata"
+	"github.com/winramp/winramp/internal/network"
 	"github.com/winramp/winramp/internal/playlist"
+	"github.com/winramp/winramp/internal/remote"
 )
 
 // App struct
 type App struct {
 	ctx           context.Context
 	config        *config.Config
+	bus           *events.Bus
 	player        *audio.Player
 	playlistMgr   *playlist.Manager
 	libraryMgr    *LibraryManager
+	verifier      *library.Verifier
+	artEmbedder   *library.ArtEmbedder
+	artStore      *library.ArtStore
+	folderExport  *library.FolderExporter
+	lyrics        *library.LyricsService
+	problems      *library.ProblemFiles
+	fileOps       *library.FileOps
+	folders       *library.FolderBrowser
+	watcher       *library.Watcher // Nil unless watching for changes
+	scanSchedule  *library.ScanScheduler
+	normalizer    *library.Normalizer
+	contextSvc    *metadata.ContextService
+	connectivity  *connectivity.Monitor
+	artistImages  *library.ArtistImageStore
+	discogs       *metadata.DiscogsClient
 	trackRepo     domain.TrackRepository
 	playlistRepo  domain.PlaylistRepository
+	markerRepo    domain.MarkerRepository
+	historyRepo   domain.PlayHistoryRepository
+	userTagRepo   domain.UserTagRepository
+	scanReports   domain.ScanReportRepository
+	importRules   domain.ImportRuleRepository
+	watchFolders  domain.WatchFolderRepository
+	streams       domain.StreamStationRepository
+	episodes      domain.EpisodeProgressRepository
+	skipRules     domain.FeedSkipRuleRepository
+	remoteClients domain.RemoteClientRepository
+	releases      domain.AlbumReleaseRepository
+	recommender   *playlist.Recommender
+	remote        *remote.Server
+	streamMgr     *network.StreamManager
+	hooks         *hooks.Runner
+	nowPlaying    *hooks.NowPlaying
+	
+	markersMu      sync.Mutex
+	silenceScanned map[string]bool // Tracks analysed for silence this session
+	chaptersRead   map[string]bool // Tracks whose embedded chapters were read this session
+	
+	gaplessMu      sync.Mutex
+	
+	gainScanner    *library.GainScanner
+	suggestions    *library.SuggestIndex
+	enricher       *metadata.Chain // Fills in missing metadata; see newEnricher
+	acoustID       *metadata.AcoustIDClient
+	fingerprinter  *library.Fingerprinter
+	identifier     *library.Identifier
+	
+	onboardingMu   sync.Mutex
+	onboarding     onboardingState
+	
+	partyMu        sync.Mutex
+	partyMode      bool // Auto-DJ keeps the queue filled with similar tracks
+	
+	idleMu         sync.Mutex
+	idle           idleState
+	
+	streamMu       sync.Mutex
+	streamListen   *streamListen // Stream being listened to, for its history
+	
+	episodeMu      sync.Mutex
+	episode        *episodeState // Podcast episode being played
+	
+	lyricsMu       sync.Mutex
+	nowLyrics      *nowPlayingLyrics // Lyrics of the track being played, for the line being heard
+	
+	discoveryMu    sync.Mutex
+	advertiser     *discovery.Advertiser // Set while advertised on the network
+	
+	sessionMu      sync.Mutex
+	sessionStore   *playlist.SessionStore
+	sessionSave    *time.Timer // Pending save after queue changes; see scheduleSessionSave
+	
+	ratingHooks    []ratingHook // Run after a rating or favorite change is saved
 }
 
+// ratingHook propagates a saved rating or favorite change elsewhere, such
+// as to file tags or a scrobbling service
+type ratingHook func(track *domain.Track) error
+
 // NewApp creates a new App application struct
 func NewApp() *App {
+	bus := events.NewBus()
+	player := audio.NewPlayer()
+	player.SetEventBus(bus)
+	
 	return &App{
 		config: config.Get(),
-		player: audio.NewPlayer(),
+		bus:    bus,
+		player: player,
 	}
 }
 
@@ -42,24 +139,209 @@ func (a *App) startup(ctx context.Context) {
 	// Initialize repositories
 	database := db.Get()
 	a.trackRepo = db.NewTrackRepository(database)
+	a.markerRepo = db.NewMarkerRepository(database)
+	a.historyRepo = db.NewPlayHistoryRepository(database)
+	a.userTagRepo = db.NewUserTagRepository(database)
+	a.scanReports = db.NewScanReportRepository(database)
+	a.importRules = db.NewImportRuleRepository(database)
+	a.watchFolders = db.NewWatchFolderRepository(database)
+	a.streams = db.NewStreamStationRepository(database)
+	a.episodes = db.NewEpisodeProgressRepository(database)
+	a.skipRules = db.NewFeedSkipRuleRepository(database)
+	a.remoteClients = db.NewRemoteClientRepository(database)
+	a.releases = db.NewAlbumReleaseRepository(database)
+	a.silenceScanned = make(map[string]bool)
+	a.chaptersRead = make(map[string]bool)
 	
 	// Initialize managers
 	a.playlistMgr = playlist.NewManager(a.playlistRepo)
+	a.playlistMgr.SetTrackRepository(a.trackRepo)
 	a.libraryMgr = NewLibraryManager(a.trackRepo)
+	a.recommender = playlist.NewRecommender(a.trackRepo, a.historyRepo)
+	a.applyFamilyMode(a.config.App.FamilyMode)
+	a.applyLiveFilters()
+	a.remote = remote.NewServer(a, a.bus, a.remoteClients)
+	a.streamMgr = network.NewStreamManager()
+	a.hooks = hooks.NewRunner()
+	a.applyHooks()
+	a.nowPlaying = hooks.NewNowPlaying()
+	if err := a.applyNowPlaying(); err != nil {
+		logger.Warn("Invalid now playing template", logger.Error(err))
+	}
+	a.remote.SetLimits(a.remoteLimits())
+	if a.config.Network.RemoteTLS {
+		if cert, err := remote.LoadOrCreateCertificate(a.config.App.DataDir); err != nil {
+			logger.Warn("Failed to load remote control certificate", logger.Error(err))
+		} else {
+			a.remote.SetCertificate(cert)
+		}
+	}
+	a.playlistMgr.SetEventBus(a.bus)
+	a.libraryMgr.scanner.SetEventBus(a.bus)
+	a.libraryMgr.scanner.SetReportRepository(a.scanReports)
+	a.libraryMgr.scanner.SetImportRules(a.importRules, a.applyImportRule)
+	if policy, err := domain.ParseDuplicatePolicy(a.config.Library.DuplicatePolicy); err == nil {
+		a.libraryMgr.scanner.SetDuplicatePolicy(policy)
+	} else {
+		logger.Warn("Invalid duplicate policy", logger.String("policy", a.config.Library.DuplicatePolicy))
+	}
+	a.libraryMgr.scanner.SetRemoveMissing(a.config.Library.RemoveMissing)
+	a.libraryMgr.scanner.SetConcurrency(
+		a.config.Library.LocalIOWorkers,
+		a.config.Library.NetworkIOWorkers,
+		a.config.Library.CPUWorkers,
+	)
+	a.libraryMgr.scanner.SetBackgroundCPULimit(a.config.Advanced.CPULimit)
+	a.artStore = library.NewArtStore(a.config.App.CacheDir, a.config.Library.AlbumArtMaxSize, a.trackRepo)
+	if a.config.Library.ExtractAlbumArt {
+		a.libraryMgr.scanner.SetArtStore(a.artStore)
+	}
+	a.verifier = library.NewVerifier(a.trackRepo)
+	a.artEmbedder = library.NewArtEmbedder(a.trackRepo, a.artStore)
+	a.folderExport = library.NewFolderExporter(a.artStore)
+	a.lyrics = library.NewLyricsService()
+	a.gainScanner = library.NewGainScanner(a.trackRepo)
+	a.gainScanner.SetEventBus(a.bus)
+	a.gainScanner.SetWriteTags(a.config.Audio.ReplayGainWriteTags)
+	a.suggestions = library.NewSuggestIndex()
+	a.problems = library.NewProblemFiles(a.trackRepo, a.verifier, a.artStore)
+	a.fileOps = library.NewFileOps(a.trackRepo, a.markerRepo, a.artStore)
+	a.folders = library.NewFolderBrowser(a.trackRepo)
+	if norm := a.config.Library.Normalization; norm.Enabled {
+		a.normalizer = library.NewNormalizer(library.NormalizeRules{
+			TrimWhitespace: norm.TrimWhitespace,
+			FeatFormat:     norm.FeatFormat,
+			FixTitleCase:   norm.FixTitleCase,
+			MoveArticles:   norm.MoveArticles,
+			GenreAliases:   norm.GenreAliases,
+			ArtistAliases:  norm.ArtistAliases,
+		})
+		a.libraryMgr.scanner.SetNormalizer(a.normalizer)
+	}
+	a.connectivity = connectivity.NewMonitor(a.config.Network.ConnectivityProbe, a.bus)
+	a.connectivity.SetOfflineMode(a.config.Network.OfflineMode)
+	a.contextSvc = metadata.NewContextService(a.config.App.CacheDir, a.config.Network.LastFMAPIKey, a.config.Network.FanartAPIKey, a.config.Network.Timeout)
+	a.contextSvc.SetConnectivity(a.connectivity)
+	a.artistImages = library.NewArtistImageStore(a.config.App.CacheDir, a.contextSvc)
+	if a.config.Library.FetchAlbumArt {
+		a.artStore.SetFinder(a.contextSvc)
+	}
+	a.discogs = a.newDiscogsClient()
+	a.acoustID = a.newAcoustIDClient()
+	a.fingerprinter = library.NewFingerprinter(a.config.Network.FpcalcPath)
+	a.libraryMgr.scanner.SetFingerprinter(a.fingerprinter)
+	a.identifier = library.NewIdentifier(a.trackRepo, a.fingerprinter, a.acoustID)
+	a.identifier.SetEventBus(a.bus)
+	if a.normalizer != nil {
+		a.identifier.SetNormalizer(a.normalizer)
+	}
+	a.enricher = a.newEnricher()
+	if a.config.Library.Enrichment.OnScan {
+		a.libraryMgr.scanner.SetEnricher(a.enricher)
+	}
+	
+	// Warm the search suggestions so the first keystroke is instant
+	go a.rebuildSuggestions()
 	
-	// Set up player event listeners
-	a.player.AddListener(func(event audio.PlayerEvent, data interface{}) {
-		a.handlePlayerEvent(event, data)
+	// Remove album art left behind by deleted tracks. Art saved from here on
+	// may belong to a scan whose tracks are not stored yet, so it is kept.
+	artCutoff := time.Now()
+	go func() {
+		if removed, err := a.artStore.Cleanup(artCutoff); err != nil {
+			logger.Warn("Album art cleanup failed", logger.Error(err))
+		} else if removed > 0 {
+			logger.Info("Removed unused album art", logger.Int("files", removed))
+		}
+	}()
+	
+	// Playlists that rediscover music from the listening history
+	go func() {
+		for _, g := range playlist.HistoryGenerators(a.trackRepo, a.historyRepo) {
+			if _, err := a.playlistMgr.AddGenerator(g); err != nil {
+				logger.Warn("Failed to generate playlist", logger.String("generator", g.ID), logger.Error(err))
+			}
+		}
+	}()
+	
+	// Apply audio settings
+	if device := a.config.Audio.OutputDevice; device != "" && device != "default" {
+		if err := a.player.SetOutputDevice(device); err != nil {
+			logger.Warn("Configured output device unavailable, using default",
+				logger.String("device", device), logger.Error(err))
+		}
+	}
+	a.player.SetBufferFrames(a.config.Audio.BufferSize)
+	a.player.SetTransitionPolicy(a.transitionPolicy())
+	a.player.SetGapless(a.config.Audio.GaplessPlayback)
+	a.applyEffects()
+	if err := a.player.SetCrossfade(a.config.Audio.CrossfadeDuration, a.config.Audio.CrossfadeCurve); err != nil {
+		logger.Warn("Invalid crossfade curve, using equal power",
+			logger.String("curve", a.config.Audio.CrossfadeCurve))
+		a.config.Audio.CrossfadeCurve = "equal_power"
+		a.player.SetCrossfade(a.config.Audio.CrossfadeDuration, a.config.Audio.CrossfadeCurve)
+	}
+	a.applySyncOffset()
+	a.player.SetMaxVolumeDB(a.config.Audio.MaxVolumeDB)
+	if err := a.player.SetVolume(a.config.Audio.Volume); err != nil {
+		logger.Warn("Invalid saved volume", logger.Float64("volume", a.config.Audio.Volume))
+	}
+	a.addRatingHook(a.syncPlayingRating)
+	a.addRatingHook(a.writeRatingTags)
+	a.player.SetFade(a.config.Audio.FadeOnPause, a.config.Audio.FadeDuration)
+	a.player.SetPauseOnDeviceLost(a.config.Audio.PauseOnDeviceLost)
+	a.player.SetTrackEndingNotice(a.config.Audio.TrackEndingNotice)
+	a.player.SetStreamPrebuffer(audio.PrebufferSettings{
+		Duration: a.config.Network.StreamPrebuffer,
+		Bytes:    a.config.Network.StreamPrebufferKB * 1024,
 	})
+	a.player.SetReplayGain(a.config.Audio.ReplayGain)
+	if err := a.player.SetReplayGainMode(a.config.Audio.ReplayGainMode); err != nil {
+		logger.Warn("Invalid ReplayGain mode", logger.String("mode", a.config.Audio.ReplayGainMode))
+	}
+	a.player.SetVolumeLeveling(a.config.Audio.VolumeLeveling)
+	if err := a.player.SetSpeedMode(a.config.Audio.SpeedMode); err != nil {
+		logger.Warn("Invalid speed mode", logger.String("mode", a.config.Audio.SpeedMode))
+	}
+	
+	// Pick up where the last run left off
+	a.startSession()
+	
+	// Forward backend events to the frontend
+	a.subscribeEvents()
+	a.startIdleActions()
+	a.startWatching()
+	a.startScanSchedule()
+	a.connectivity.Start(a.ctx)
+	if a.config.Network.RemoteEnabled {
+		if err := a.startRemote(); err != nil {
+			logger.Warn("Failed to start remote control", logger.Error(err))
+		} else {
+			a.startAdvertising()
+		}
+	}
 	
 	logger.Info("WinRamp UI started")
 }
 
 // shutdown is called when the app is closing
 func (a *App) shutdown(ctx context.Context) {
+	// Saved before the player closes, while its position is still known
+	a.stopSession()
+	a.stopWatching()
 	if a.player != nil {
 		a.player.Close()
 	}
+	a.finishStreamListen()
+	a.stopAdvertising()
+	if err := a.remote.Shutdown(ctx); err != nil {
+		logger.Warn("Failed to stop remote control", logger.Error(err))
+	}
+	a.bus.Close()
+	
+	// Volume changes are saved here rather than on every slider movement
+	if err := a.config.Save(); err != nil {
+		logger.Warn("Failed to save settings", logger.Error(err))
+	}
 	logger.Info("WinRamp UI shutdown")
 }
 
@@ -67,197 +349,2600 @@ func (a *App) shutdown(ctx context.Context) {
 
 // Play starts playback
 func (a *App) Play() error {
+	a.noteActivity()
+	return a.player.Play()
+}
+
+// Pause pauses playback
+func (a *App) Pause() error {
+	return a.player.Pause()
+}
+
+// Stop stops playback
+func (a *App) Stop() error {
+	return a.player.Stop()
+}
+
+// Next skips to the next track, even when the current one is set to repeat
+func (a *App) Next() error {
+	a.noteActivity()
+	track := a.playlistMgr.GetNextTrack()
+	if track == nil {
+		return fmt.Errorf("no next track")
+	}
+	return a.LoadTrack(track)
+}
+
+// previousRestartThreshold is how far into a track Previous restarts it
+// instead of going back
+const previousRestartThreshold = 3 * time.Second
+
+// Previous restarts the current track when more than a few seconds in, and
+// otherwise goes back to the track played before it. Playback carries on
+// if it was playing.
+func (a *App) Previous() error {
+	a.noteActivity()
+	loaded := a.player.GetCurrentTrack() != nil
+	if loaded && a.player.GetPosition() > previousRestartThreshold {
+		return a.player.Seek(0)
+	}
+	
+	track := a.playlistMgr.GetPreviousTrack()
+	if track == nil {
+		if loaded {
+			return a.player.Seek(0)
+		}
+		return fmt.Errorf("no previous track")
+	}
+	
+	playing := a.player.GetState() == audio.StatePlaying
+	a.emitQueueChanged()
+	if err := a.LoadTrack(track); err != nil {
+		return err
+	}
+	if playing {
+		return a.player.Play()
+	}
+	return nil
+}
+
+// GetSessionHistory returns up to limit tracks played since WinRamp
+// started, most recent first, with when each was played
+func (a *App) GetSessionHistory(limit int) []map[string]interface{} {
+	entries := a.playlistMgr.GetHistory(limit)
+	result := make([]map[string]interface{}, len(entries))
+	for i, entry := range entries {
+		result[i] = a.trackToMap(entry.Track)
+		result[i]["playedAt"] = entry.PlayedAt
+	}
+	return result
+}
+
+// JumpTo plays the track at an index in the queue, as when it is picked
+// from the queue view
+func (a *App) JumpTo(index int) error {
+	a.noteActivity()
+	track, err := a.playlistMgr.JumpTo(index)
+	if err != nil {
+		return err
+	}
+	a.emitQueueChanged()
+	
+	if err := a.LoadTrack(track); err != nil {
+		return err
+	}
 	return a.player.Play()
 }
 
-// Pause pauses playback
-func (a *App) Pause() error {
-	return a.player.Pause()
+// Seek seeks to a position in seconds
+func (a *App) Seek(seconds float64) error {
+	a.noteActivity()
+	duration := time.Duration(seconds * float64(time.Second))
+	return a.player.Seek(duration)
+}
+
+// SetVolume sets the volume (0.0 to 1.0)
+func (a *App) SetVolume(volume float64) error {
+	a.noteActivity()
+	if err := a.player.SetVolume(volume); err != nil {
+		return err
+	}
+	a.rememberVolume(volume)
+	return nil
+}
+
+// SetPlaybackSpeed sets the playback speed, from 0.5 to 2.0
+func (a *App) SetPlaybackSpeed(speed float64) error {
+	if err := a.player.SetSpeed(speed); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+	return nil
+}
+
+// GetPlaybackSpeed returns the playback speed and how it is changed
+func (a *App) GetPlaybackSpeed() map[string]interface{} {
+	speed, mode := a.player.GetSpeed()
+	return map[string]interface{}{
+		"speed": speed,
+		"mode":  mode,
+		"modes": audio.SpeedModes,
+	}
+}
+
+// SetSpeedMode sets whether speed changes resample, shifting pitch, or
+// time-stretch, keeping it
+func (a *App) SetSpeedMode(mode string) error {
+	if err := a.player.SetSpeedMode(mode); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+	a.config.Audio.SpeedMode = mode
+	a.config.Set("audio.speed_mode", mode)
+	return a.config.Save()
+}
+
+// VolumeUp raises the volume by one step and returns the new volume
+func (a *App) VolumeUp() float64 {
+	volume := a.player.StepVolume(a.volumeStep())
+	a.rememberVolume(volume)
+	return volume
+}
+
+// VolumeDown lowers the volume by one step and returns the new volume
+func (a *App) VolumeDown() float64 {
+	volume := a.player.StepVolume(-a.volumeStep())
+	a.rememberVolume(volume)
+	return volume
+}
+
+// volumeStep returns the configured hotkey volume step in dB
+func (a *App) volumeStep() float64 {
+	if a.config.Audio.VolumeStepDB > 0 {
+		return a.config.Audio.VolumeStepDB
+	}
+	return audio.DefaultVolumeStepDB
+}
+
+// rememberVolume records the volume so it is restored on the next startup
+func (a *App) rememberVolume(volume float64) {
+	a.config.Audio.Volume = volume
+	a.config.Set("audio.volume", volume)
+}
+
+// PreviewTrack plays a short, quiet audition of a track, such as when it is
+// hovered in the library, without affecting the main playback
+func (a *App) PreviewTrack(trackID string) error {
+	track, err := a.trackRepo.FindByID(trackID)
+	if err != nil {
+		return err
+	}
+	return a.player.StartPreview(track, audio.DefaultPreviewStart, audio.DefaultPreviewLength)
+}
+
+// StopPreview ends the current audition
+func (a *App) StopPreview() {
+	a.player.StopPreview()
+}
+
+// GetPlayerState returns the current player state
+func (a *App) GetPlayerState() map[string]interface{} {
+	state := make(map[string]interface{})
+	state["state"] = a.player.GetState().String()
+	state["position"] = a.player.GetPosition().Seconds()
+	state["duration"] = a.player.GetDuration().Seconds()
+	state["volume"] = a.player.GetVolume()
+	if db := a.player.GetVolumeDB(); !math.IsInf(db, -1) {
+		state["volumeDb"] = db
+	}
+	state["partyMode"] = a.isPartyMode()
+	state["familyMode"] = a.config.App.FamilyMode
+	for key, value := range a.queueState() {
+		state[key] = value
+	}
+	for key, value := range a.queueTiming() {
+		state[key] = value
+	}
+	
+	if track := a.player.GetCurrentTrack(); track != nil {
+		state["track"] = a.trackToMap(track)
+	}
+	
+	return state
+}
+
+// SetShuffle turns queue shuffle on or off
+func (a *App) SetShuffle(shuffle bool) map[string]interface{} {
+	a.playlistMgr.GetQueue().SetShuffle(shuffle)
+	return a.transportChanged()
+}
+
+// SetShuffleMode shuffles the queue by "tracks", "albums", which plays
+// albums in random order but each in sequence, or "artists", which keeps
+// each artist's tracks together; "off" stops shuffling
+func (a *App) SetShuffleMode(mode string) (map[string]interface{}, error) {
+	shuffle, err := playlist.ParseShuffleMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	a.playlistMgr.GetQueue().SetShuffleMode(shuffle)
+	a.emitQueueChanged()
+	return a.transportChanged(), nil
+}
+
+// UnshuffleQueue turns shuffle off and puts the queue back in the order
+// its tracks were added
+func (a *App) UnshuffleQueue() map[string]interface{} {
+	a.playlistMgr.GetQueue().Unshuffle()
+	a.emitQueueChanged()
+	return a.transportChanged()
+}
+
+// SetRepeat sets the repeat mode: "off", "one" or "all"
+func (a *App) SetRepeat(mode string) (map[string]interface{}, error) {
+	repeat, err := playlist.ParseRepeatMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	a.playlistMgr.GetQueue().SetRepeat(repeat)
+	return a.transportChanged(), nil
+}
+
+// GetTransportState returns the playback state together with the shuffle,
+// repeat and queue settings that decide what plays next
+func (a *App) GetTransportState() map[string]interface{} {
+	state := a.queueState()
+	state["state"] = a.player.GetState().String()
+	state["position"] = a.player.GetPosition().Seconds()
+	state["duration"] = a.player.GetDuration().Seconds()
+	state["volume"] = a.player.GetVolume()
+	state["partyMode"] = a.isPartyMode()
+	state["familyMode"] = a.config.App.FamilyMode
+	if track := a.player.GetCurrentTrack(); track != nil {
+		state["trackId"] = track.ID
+	}
+	return state
+}
+
+// queueState describes the queue settings for the state payloads
+func (a *App) queueState() map[string]interface{} {
+	queue := a.playlistMgr.GetQueue()
+	return map[string]interface{}{
+		"shuffle":     queue.IsShuffle(),
+		"shuffleMode": queue.GetShuffleMode().String(),
+		"repeat":      queue.GetRepeat().String(),
+		"queueIndex":  queue.GetPosition(),
+		"queueLength": queue.GetLength(),
+	}
+}
+
+// queueTiming describes how long the queue has left to play, and when it
+// will finish if playback carries on, so the UI can show "ends at 23:42".
+// endsAt is left out when repeat keeps the queue going forever.
+func (a *App) queueTiming() map[string]interface{} {
+	duration := a.playlistMgr.GetQueue().Duration()
+	
+	remaining := duration.Upcoming
+	if a.player.GetCurrentTrack() != nil {
+		remaining += max(a.player.GetDuration()-a.player.GetPosition(), 0)
+	}
+	
+	timing := map[string]interface{}{
+		"queueDuration":  duration.Total.Seconds(),
+		"queueRemaining": remaining.Seconds(),
+		"unknownLength":  duration.Unknown,
+		"endless":        duration.Endless,
+	}
+	if !duration.Endless {
+		timing["endsAt"] = time.Now().Add(remaining)
+	}
+	return timing
+}
+
+// GetQueueInfo returns the queue settings and timing
+func (a *App) GetQueueInfo() map[string]interface{} {
+	info := a.queueState()
+	for key, value := range a.queueTiming() {
+		info[key] = value
+	}
+	return info
+}
+
+// transportChanged refreshes the gapless next track after the queue order
+// or repeat mode changes and tells the frontend
+func (a *App) transportChanged() map[string]interface{} {
+	if err := a.player.SetNextTrack(a.playlistMgr.PeekNextTrack()); err != nil {
+		logger.Warn("Failed to prepare next track", logger.Error(err))
+	}
+	
+	state := a.GetTransportState()
+	runtime.EventsEmit(a.ctx, "player:transportChanged", state)
+	a.scheduleSessionSave()
+	return state
+}
+
+// LoadTrack loads a track for playback
+func (a *App) LoadTrack(track *domain.Track) error {
+	if err := a.player.Load(track); err != nil {
+		return err
+	}
+	
+	if a.isPartyMode() {
+		a.fillAutoDJQueue(track)
+	}
+	
+	// Set next track for gapless playback
+	if next := a.playlistMgr.PeekNextTrack(); next != nil {
+		a.player.SetNextTrack(next)
+		go a.checkAlbumGapless(track, next)
+	}
+	
+	return nil
+}
+
+// SetAlbumCrossfade chooses crossfading instead of gapless transitions for
+// an album, typically in response to a "player:gaplessHint" event
+func (a *App) SetAlbumCrossfade(albumKey string, enabled bool) error {
+	if albumKey == "" {
+		return fmt.Errorf("album key is required")
+	}
+	
+	a.gaplessMu.Lock()
+	defer a.gaplessMu.Unlock()
+	
+	albums := make([]string, 0, len(a.config.Audio.CrossfadeAlbums)+1)
+	for _, key := range a.config.Audio.CrossfadeAlbums {
+		if key != albumKey {
+			albums = append(albums, key)
+		}
+	}
+	if enabled {
+		albums = append(albums, albumKey)
+	}
+	
+	a.config.Audio.CrossfadeAlbums = albums
+	a.config.Set("audio.crossfade_albums", albums)
+	a.player.SetTransitionPolicy(a.transitionPolicy())
+	return a.config.Save()
+}
+
+// transitionPolicy builds the player's transition policy from the config,
+// falling back to the defaults for names it doesn't know
+func (a *App) transitionPolicy() audio.TransitionPolicy {
+	policy := audio.DefaultTransitionPolicy()
+	transitions := a.config.Audio.Transitions
+	if t, err := audio.ParseTransition(transitions.SameAlbum); err == nil {
+		policy.SameAlbum = t
+	}
+	if t, err := audio.ParseTransition(transitions.DifferentAlbum); err == nil {
+		policy.DifferentAlbum = t
+	}
+	if transitions.Gap > 0 {
+		policy.Gap = transitions.Gap
+	}
+	policy.CrossfadeAlbums = a.config.Audio.CrossfadeAlbums
+	for _, ruleConfig := range a.config.Audio.Transitions.Rules {
+		rule, err := transitionRuleFromConfig(ruleConfig)
+		if err != nil {
+			logger.Warn("Ignoring invalid transition rule", logger.String("rule", ruleConfig.Name), logger.Error(err))
+			continue
+		}
+		policy.Rules = append(policy.Rules, rule)
+	}
+	return policy
+}
+
+// setTransition checks a transition name and stores it in a setting
+func (a *App) setTransition(key string, setting *string, name string) error {
+	if _, err := audio.ParseTransition(name); err != nil {
+		return err
+	}
+	*setting = name
+	a.config.Set(key, name)
+	return nil
+}
+
+// checkAlbumGapless emits a one-time hint when consecutive tracks from the
+// same album lack encoder delay information, since they will play with an
+// audible gap. The hint suggests re-encoding or crossfading the album.
+func (a *App) checkAlbumGapless(current, next *domain.Track) {
+	if !a.config.Audio.GaplessPlayback || !a.config.Audio.GaplessHints {
+		return
+	}
+	if !current.IsSameAlbum(next) || current.IsNetworkPath() || next.IsNetworkPath() {
+		return
+	}
+	
+	albumKey := current.AlbumKey()
+	
+	a.gaplessMu.Lock()
+	defer a.gaplessMu.Unlock()
+	
+	for _, key := range a.config.Audio.HintedAlbums {
+		if key == albumKey {
+			return
+		}
+	}
+	for _, key := range a.config.Audio.CrossfadeAlbums {
+		if key == albumKey {
+			return
+		}
+	}
+	
+	missing := make([]string, 0, 2)
+	for _, track := range []*domain.Track{current, next} {
+		info, err := decoder.ReadGaplessInfo(track.FilePath)
+		if err != nil {
+			logger.Debug("Failed to read gapless info", logger.String("path", track.FilePath), logger.Error(err))
+			return
+		}
+		if info == nil {
+			missing = append(missing, track.ID)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	
+	hinted := append(a.config.Audio.HintedAlbums, albumKey)
+	a.config.Audio.HintedAlbums = hinted
+	a.config.Set("audio.gapless_hinted_albums", hinted)
+	if err := a.config.Save(); err != nil {
+		logger.Warn("Failed to save gapless hint state", logger.Error(err))
+	}
+	
+	logger.Info("Album is missing gapless information",
+		logger.String("album", current.Album),
+		logger.String("artist", current.GetDisplayArtist()))
+	
+	runtime.EventsEmit(a.ctx, "player:gaplessHint", map[string]interface{}{
+		"albumKey":    albumKey,
+		"album":       current.Album,
+		"artist":      current.GetDisplayArtist(),
+		"trackIds":    missing,
+		"suggestions": []string{"crossfade", "reencode"},
+	})
+}
+
+// LoadFile loads a file for playback
+func (a *App) LoadFile(path string) error {
+	track, err := a.libraryMgr.ImportTrack(path)
+	if err != nil {
+		return err
+	}
+	return a.LoadTrack(track)
+}
+
+// Marker Methods
+
+// GetTrackMarkers returns chapters, bookmarks and silence regions for the
+// current track so the seek bar can render them from a single call.
+// Silence analysis runs in the background the first time a track is
+// requested; "player:markersChanged" is emitted when it completes.
+func (a *App) GetTrackMarkers() (map[string]interface{}, error) {
+	track := a.player.GetCurrentTrack()
+	if track == nil {
+		return nil, fmt.Errorf("no track loaded")
+	}
+	
+	chapters := a.readChapters(track)
+	markers, err := a.markerRepo.FindByTrack(track.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chapters) > 0 {
+		markers = append(chapters, markers...)
+		sort.SliceStable(markers, func(i, j int) bool { return markers[i].Position < markers[j].Position })
+	}
+	
+	hasSilence := false
+	result := make([]map[string]interface{}, 0, len(markers))
+	for _, marker := range markers {
+		if marker.Type == domain.MarkerTypeSilence {
+			hasSilence = true
+		}
+		result = append(result, markerToMap(marker))
+	}
+	
+	analysing := false
+	if !hasSilence && !track.IsNetworkPath() && !track.Transient {
+		analysing = a.scanSilence(track)
+	}
+	
+	return map[string]interface{}{
+		"trackId":   track.ID,
+		"duration":  a.player.GetDuration().Seconds(),
+		"markers":   result,
+		"analysing": analysing,
+	}, nil
+}
+
+// AddBookmark adds a bookmark at the current playback position
+func (a *App) AddBookmark(label string) (map[string]interface{}, error) {
+	track := a.player.GetCurrentTrack()
+	if track == nil {
+		return nil, fmt.Errorf("no track loaded")
+	}
+	if track.Transient {
+		return nil, fmt.Errorf("bookmarks need a library track")
+	}
+	
+	marker, err := domain.NewTrackMarker(track.ID, domain.MarkerTypeBookmark, a.player.GetPosition(), label)
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := a.markerRepo.Create(marker); err != nil {
+		return nil, err
+	}
+	
+	return markerToMap(marker), nil
+}
+
+// DeleteMarker removes a bookmark or chapter marker
+func (a *App) DeleteMarker(id string) error {
+	return a.markerRepo.Delete(id)
+}
+
+// readChapters reads the chapters embedded in a track's file. Library
+// tracks keep them as chapter markers, read again once a session in case
+// the file was retagged; a transient track's chapters are returned instead.
+func (a *App) readChapters(track *domain.Track) []*domain.TrackMarker {
+	if !track.Transient {
+		a.markersMu.Lock()
+		read := a.chaptersRead[track.ID]
+		a.chaptersRead[track.ID] = true
+		a.markersMu.Unlock()
+		if read {
+			return nil
+		}
+	}
+	
+	chapters, err := a.player.Sources().ReadChapters(a.ctx, track)
+	if err != nil {
+		logger.Warn("Failed to read chapters", logger.String("path", track.FilePath), logger.Error(err))
+		return nil
+	}
+	if track.Transient {
+		return chapters
+	}
+	
+	if err := a.markerRepo.DeleteByTrack(track.ID, domain.MarkerTypeChapter); err != nil {
+		logger.Warn("Failed to clear chapter markers", logger.Error(err))
+		return nil
+	}
+	for _, chapter := range chapters {
+		if err := a.markerRepo.Create(chapter); err != nil {
+			logger.Warn("Failed to save chapter marker", logger.Error(err))
+		}
+	}
+	return nil
+}
+
+// scanSilence starts a background silence analysis for a track unless one
+// has already run this session. Returns true if an analysis was started.
+func (a *App) scanSilence(track *domain.Track) bool {
+	a.markersMu.Lock()
+	if a.silenceScanned[track.ID] {
+		a.markersMu.Unlock()
+		return false
+	}
+	a.silenceScanned[track.ID] = true
+	a.markersMu.Unlock()
+	
+	go func() {
+		dec, err := a.player.Sources().OpenDecoder(a.ctx, track)
+		if err != nil {
+			logger.Warn("Failed to open track for silence analysis", logger.String("path", track.FilePath), logger.Error(err))
+			return
+		}
+		defer dec.Close()
+		
+		regions, err := audio.DetectSilence(dec, track.ID)
+		if err != nil {
+			logger.Warn("Silence analysis failed", logger.String("path", track.FilePath), logger.Error(err))
+			return
+		}
+		
+		if err := a.markerRepo.DeleteByTrack(track.ID, domain.MarkerTypeSilence); err != nil {
+			logger.Warn("Failed to clear silence markers", logger.Error(err))
+			return
+		}
+		for _, region := range regions {
+			if err := a.markerRepo.Create(region); err != nil {
+				logger.Warn("Failed to save silence marker", logger.Error(err))
+			}
+		}
+		
+		if len(regions) > 0 {
+			runtime.EventsEmit(a.ctx, "player:markersChanged", track.ID)
+		}
+	}()
+	
+	return true
+}
+
+// Playlist Methods
+
+// GetPlaylists returns all playlists
+func (a *App) GetPlaylists() []map[string]interface{} {
+	playlists := a.playlistMgr.GetAll()
+	result := make([]map[string]interface{}, len(playlists))
+	
+	for i, pl := range playlists {
+		result[i] = a.playlistToMap(pl)
+	}
+	
+	return result
+}
+
+// GetPlaylist returns a playlist by ID
+func (a *App) GetPlaylist(id string) (map[string]interface{}, error) {
+	playlist, err := a.playlistMgr.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return a.playlistToMap(playlist), nil
+}
+
+// CreatePlaylist creates a new playlist
+func (a *App) CreatePlaylist(name string) (map[string]interface{}, error) {
+	playlist, err := a.playlistMgr.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return a.playlistToMap(playlist), nil
+}
+
+// RefreshPlaylist rebuilds a smart playlist: one generated from the
+// listening history, such as "On This Day", which changes daily, or one
+// made from rules. Rule playlists also refresh by themselves when the
+// library changes.
+func (a *App) RefreshPlaylist(id string) (map[string]interface{}, error) {
+	if err := a.playlistMgr.Refresh(id); err != nil {
+		return nil, err
+	}
+	return a.GetPlaylist(id)
+}
+
+// smartPreviewCount is how many matches a rules preview returns when the
+// caller doesn't say
+const smartPreviewCount = 50
+
+// PreviewSmartPlaylist evaluates smart playlist rules against the library
+// without saving anything, so the rules editor can show what they match as
+// they are edited. It returns the first count matches and how many there
+// are in all. Problems with the rules come back in "errors", one for each
+// condition at fault, rather than failing the call.
+func (a *App) PreviewSmartPlaylist(rules domain.SmartRules, count int) (map[string]interface{}, error) {
+	if count <= 0 {
+		count = smartPreviewCount
+	}
+	
+	if errs := rules.Check(); len(errs) > 0 {
+		return map[string]interface{}{
+			"valid":  false,
+			"errors": errs,
+			"total":  0,
+			"tracks": []map[string]interface{}{},
+		}, nil
+	}
+	
+	matches, err := a.trackRepo.FindBySmartRules(&rules, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	
+	preview := make([]map[string]interface{}, 0, min(count, len(matches)))
+	for _, track := range matches[:min(count, len(matches))] {
+		preview = append(preview, a.trackToMap(track))
+	}
+	return map[string]interface{}{
+		"valid":  true,
+		"errors": []*domain.RuleError{},
+		"total":  len(matches),
+		"tracks": preview,
+	}, nil
+}
+
+// CreateSmartPlaylist creates a playlist of the tracks meeting rules. It
+// stays up to date as the library changes. Invalid rules fail with the
+// first problem; PreviewSmartPlaylist reports them all.
+func (a *App) CreateSmartPlaylist(name string, rules domain.SmartRules) (map[string]interface{}, error) {
+	playlist, err := a.playlistMgr.CreateSmart(name, rules)
+	if err != nil {
+		return nil, err
+	}
+	return a.playlistToMap(playlist), nil
+}
+
+// UpdateSmartPlaylist renames a smart playlist and replaces its rules
+func (a *App) UpdateSmartPlaylist(id, name string, rules domain.SmartRules) (map[string]interface{}, error) {
+	playlist, err := a.playlistMgr.UpdateSmart(id, name, rules)
+	if err != nil {
+		return nil, err
+	}
+	return a.playlistToMap(playlist), nil
+}
+
+// GetSmartRuleFields returns the fields smart playlist rules can test,
+// with the operators each takes, for the rules editor
+func (a *App) GetSmartRuleFields() map[string][]string {
+	return domain.RuleFields()
+}
+
+// DeletePlaylist deletes a playlist
+func (a *App) DeletePlaylist(id string) error {
+	return a.playlistMgr.Delete(id)
+}
+
+// AddToPlaylist adds tracks to a playlist
+func (a *App) AddToPlaylist(playlistID string, trackIDs []string) error {
+	for _, trackID := range trackIDs {
+		track, err := a.trackRepo.FindByID(trackID)
+		if err != nil {
+			logger.Warn("Track not found", logger.String("id", trackID))
+			continue
+		}
+		if err := a.playlistMgr.AddTrack(playlistID, track); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveFromPlaylist removes tracks from a playlist
+func (a *App) RemoveFromPlaylist(playlistID string, trackIDs []string) error {
+	for _, trackID := range trackIDs {
+		if err := a.playlistMgr.RemoveTrack(playlistID, trackID); err != nil {
+			logger.Warn("Failed to remove track", logger.String("id", trackID), logger.Error(err))
+		}
+	}
+	return nil
+}
+
+// SortPlaylist orders a playlist's tracks by title, artist, album,
+// duration, year, rating, play_count or date_added
+func (a *App) SortPlaylist(playlistID, field string, descending bool) error {
+	return a.playlistMgr.Sort(playlistID, field, descending)
+}
+
+// Queue Methods
+
+// PlayTracks plays the given tracks, such as search results, in order.
+// With replaceQueue the queue is replaced by them; otherwise they are
+// inserted after the current track.
+func (a *App) PlayTracks(ids []string, replaceQueue bool) error {
+	tracks, err := a.resolveTracks(ids)
+	if err != nil {
+		return err
+	}
+	return a.playTrackList(tracks, replaceQueue)
+}
+
+// EnqueueTracks adds tracks to the end of the queue, or after the current
+// track when next is set, and returns how many were added
+func (a *App) EnqueueTracks(ids []string, next bool) (int, error) {
+	tracks, err := a.resolveTracks(ids)
+	if err != nil {
+		return 0, err
+	}
+	
+	a.enqueueTrackList(tracks, next)
+	return len(tracks), nil
+}
+
+// EnqueueAlbumNext queues an album, by the key the UI is given for it, to
+// play after the current track, in disc and track order and in the
+// preferred format. It returns how many tracks were added.
+func (a *App) EnqueueAlbumNext(albumKey string) (int, error) {
+	tracks, err := a.findAlbumByKey(albumKey)
+	if err != nil {
+		return 0, err
+	}
+	
+	groups := domain.GroupAlbums(playableTracks(tracks))
+	if len(groups) == 0 {
+		return 0, fmt.Errorf("%w: no playable tracks on album %q", domain.ErrTrackNotFound, albumKey)
+	}
+	domain.PreferVersions(groups[:1], a.formatPreference())
+	return a.enqueueNext(groups[0].Tracks())
+}
+
+// findAlbumByKey returns the tracks of the album with the given
+// Track.AlbumKey, every version of it included
+func (a *App) findAlbumByKey(albumKey string) ([]*domain.Track, error) {
+	tracks, err := a.trackRepo.FindByAlbumKey(albumKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(tracks) == 0 {
+		artist, album, _ := strings.Cut(albumKey, "\x00")
+		return nil, fmt.Errorf("%w: album %q by %q", domain.ErrTrackNotFound, album, artist)
+	}
+	return tracks, nil
+}
+
+// EnqueueArtistNext queues an artist's tracks to play after the current
+// track: their albums oldest first, each in disc and track order and in
+// the preferred format, then tracks on no album. It returns how many
+// tracks were added.
+func (a *App) EnqueueArtistNext(artist string) (int, error) {
+	tracks, err := a.trackRepo.FindByArtist(artist)
+	if err != nil {
+		return 0, err
+	}
+	tracks = playableTracks(tracks)
+	
+	groups := domain.GroupAlbums(tracks)
+	domain.PreferVersions(groups, a.formatPreference())
+	domain.SortByRelease(groups)
+	
+	ordered := make([]*domain.Track, 0, len(tracks))
+	for _, group := range groups {
+		ordered = append(ordered, group.Tracks()...)
+	}
+	for _, track := range tracks {
+		if track.AlbumKey() == "" {
+			ordered = append(ordered, track)
+		}
+	}
+	if len(ordered) == 0 {
+		return 0, fmt.Errorf("%w: artist %q", domain.ErrTrackNotFound, artist)
+	}
+	return a.enqueueNext(ordered)
+}
+
+// enqueueNext inserts tracks after the current one as one change, and
+// returns how many there were
+func (a *App) enqueueNext(tracks []*domain.Track) (int, error) {
+	a.enqueueTrackList(tracks, true)
+	return len(tracks), nil
+}
+
+// playableTracks drops the tracks whose files can't be played
+func playableTracks(tracks []*domain.Track) []*domain.Track {
+	playable := make([]*domain.Track, 0, len(tracks))
+	for _, track := range tracks {
+		if track.IsValid {
+			playable = append(playable, track)
+		}
+	}
+	return playable
+}
+
+// PlayLocation plays a file path or stream URL without adding it to the
+// library. With replaceQueue the queue holds only it; otherwise it is
+// inserted after the current track. The title is optional.
+func (a *App) PlayLocation(location, title string, replaceQueue bool) error {
+	track, err := newTransientTrack(location, title)
+	if err != nil {
+		return err
+	}
+	return a.playTrackList([]*domain.Track{track}, replaceQueue)
+}
+
+// EnqueueLocation queues a file path or stream URL without adding it to the
+// library. The entry is kept when the queue is exported.
+func (a *App) EnqueueLocation(location, title string, next bool) error {
+	track, err := newTransientTrack(location, title)
+	if err != nil {
+		return err
+	}
+	a.enqueueTrackList([]*domain.Track{track}, next)
+	return nil
+}
+
+// GetQueueTracks returns the tracks in the queue, including transient
+// entries that can't be looked up in the library
+func (a *App) GetQueueTracks() []map[string]interface{} {
+	tracks := a.playlistMgr.GetQueue().GetTracks()
+	result := make([]map[string]interface{}, len(tracks))
+	for i, track := range tracks {
+		result[i] = a.trackToMap(track)
+	}
+	return result
+}
+
+// newTransientTrack creates a queue entry for a file or stream outside the
+// library. Files must exist and be in a supported format.
+func newTransientTrack(location, title string) (*domain.Track, error) {
+	source := domain.SourceFromPath(strings.TrimSpace(location))
+	if source.IsLocal() {
+		if !domain.IsAudioFile(source.URI) {
+			return nil, domain.ErrUnsupportedFormat
+		}
+		if _, err := os.Stat(source.URI); err != nil {
+			return nil, err
+		}
+	}
+	return domain.NewTransientTrack(source, title)
+}
+
+// playTrackList queues tracks as one change and starts the first
+func (a *App) playTrackList(tracks []*domain.Track, replaceQueue bool) error {
+	track := a.playlistMgr.PlayTracks(tracks, replaceQueue)
+	a.emitQueueChanged()
+	
+	if err := a.LoadTrack(track); err != nil {
+		return err
+	}
+	return a.player.Play()
+}
+
+// enqueueTrackList adds tracks to the queue as one change
+func (a *App) enqueueTrackList(tracks []*domain.Track, next bool) {
+	a.playlistMgr.AddTracksToQueue(tracks, next)
+	if err := a.player.SetNextTrack(a.playlistMgr.PeekNextTrack()); err != nil {
+		logger.Warn("Failed to prepare next track", logger.Error(err))
+	}
+	a.emitQueueChanged()
+}
+
+// resolveTracks looks up tracks by ID, keeping their order. Missing and
+// unplayable tracks are skipped.
+func (a *App) resolveTracks(ids []string) ([]*domain.Track, error) {
+	tracks := make([]*domain.Track, 0, len(ids))
+	for _, id := range ids {
+		track, err := a.trackRepo.FindByID(id)
+		if err != nil {
+			logger.Warn("Skipping track", logger.String("id", id), logger.Error(err))
+			continue
+		}
+		if !track.IsValid {
+			continue
+		}
+		tracks = append(tracks, track)
+	}
+	
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("%w: no playable tracks", domain.ErrTrackNotFound)
+	}
+	return tracks, nil
+}
+
+// emitQueueChanged tells the frontend the queue has changed
+func (a *App) emitQueueChanged() {
+	tracks := a.playlistMgr.GetQueue().GetTracks()
+	ids := make([]string, len(tracks))
+	for i, track := range tracks {
+		ids[i] = track.ID
+	}
+	
+	payload := a.queueState()
+	payload["trackIds"] = ids
+	runtime.EventsEmit(a.ctx, "queue:changed", payload)
+	a.scheduleSessionSave()
+}
+
+// Queue Sharing Methods
+
+// ExportQueue saves the current queue as a shareable file holding track
+// metadata and fingerprints but no audio. When path is empty the user is
+// asked where to save. It returns the path written, or "" if cancelled.
+func (a *App) ExportQueue(path string) (string, error) {
+	tracks := a.playlistMgr.GetQueue().GetTracks()
+	if len(tracks) == 0 {
+		return "", playlist.ErrEmptyQueue
+	}
+	
+	if path == "" {
+		var err error
+		path, err = runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+			Title:           "Export Queue",
+			DefaultFilename: "Queue" + playlist.ShareFileExtension,
+			Filters: []runtime.FileFilter{
+				{DisplayName: "WinRamp Queue", Pattern: "*" + playlist.ShareFileExtension},
+			},
+		})
+		if err != nil || path == "" {
+			return "", err
+		}
+	}
+	
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	
+	name := fmt.Sprintf("Queue %s", time.Now().Format("2006-01-02"))
+	if err := playlist.WriteSharedQueue(file, playlist.NewSharedQueue(name, tracks)); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	
+	logger.Info("Queue exported", logger.String("path", path), logger.Int("tracks", len(tracks)))
+	return path, nil
+}
+
+// ImportQueue reads a shared queue file, matches its tracks against the
+// library and appends the matches to the queue. When path is empty the user
+// is asked to pick a file. Tracks that could not be found are returned so
+// the UI can list them.
+func (a *App) ImportQueue(path string) (map[string]interface{}, error) {
+	if path == "" {
+		var err error
+		path, err = runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+			Title: "Import Queue",
+			Filters: []runtime.FileFilter{
+				{DisplayName: "WinRamp Queue", Pattern: "*" + playlist.ShareFileExtension},
+			},
+		})
+		if err != nil || path == "" {
+			return nil, err
+		}
+	}
+	
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	
+	shared, err := playlist.ReadSharedQueue(file)
+	if err != nil {
+		return nil, err
+	}
+	
+	resolved, err := playlist.ResolveSharedQueue(shared, a.trackRepo)
+	if err != nil {
+		return nil, err
+	}
+	
+	for _, track := range resolved.Matched {
+		a.playlistMgr.AddToQueue(track)
+	}
+	
+	missing := make([]map[string]interface{}, len(resolved.Missing))
+	for i, track := range resolved.Missing {
+		missing[i] = map[string]interface{}{
+			"title":    track.Title,
+			"artist":   track.Artist,
+			"album":    track.Album,
+			"duration": float64(track.DurationMs) / 1000,
+		}
+	}
+	
+	return map[string]interface{}{
+		"name":    shared.Name,
+		"matched": len(resolved.Matched),
+		"missing": missing,
+	}, nil
+}
+
+// Library Methods
+
+// GetLibraryTracks returns all tracks in the library. When a format
+// preference is set, copies of an album in less preferred formats are left
+// out; GetAlbum lists them as other versions.
+func (a *App) GetLibraryTracks() []map[string]interface{} {
+	tracks, err := a.trackRepo.FindAll()
+	if err != nil {
+		logger.Error("Failed to get library tracks", logger.Error(err))
+		return []map[string]interface{}{}
+	}
+	tracks = a.hideOtherVersions(tracks)
+	
+	result := make([]map[string]interface{}, len(tracks))
+	for i, track := range tracks {
+		result[i] = a.trackToMap(track)
+	}
+	
+	return result
+}
+
+// SearchTracks searches for tracks, most relevant first. Each result
+// carries its "score" and, in "highlights", where the query matched each
+// field, in UTF-16 offsets.
+func (a *App) SearchTracks(query string) []map[string]interface{} {
+	tracks, err := a.trackRepo.Search(query)
+	if err != nil {
+		logger.ErrorLog("Failed to search tracks", logger.Error(err))
+		return []map[string]interface{}{}
+	}
+	
+	ranked := library.RankSearch(tracks, query, time.Now())
+	result := make([]map[string]interface{}, len(ranked))
+	for i, match := range ranked {
+		result[i] = a.trackToMap(match.Track)
+		result[i]["score"] = match.Score
+		result[i]["highlights"] = match.Highlights
+	}
+	
+	return result
+}
+
+// suggestCount is how many suggestions GetSearchSuggestions returns when
+// the caller doesn't say
+const suggestCount = 10
+
+// GetSearchSuggestions suggests artists, albums and titles for a search as
+// it is typed, from the start of any of their words, allowing a typo or
+// two in longer queries. It answers from memory, so it can run on every
+// keystroke.
+func (a *App) GetSearchSuggestions(query string, limit int) []library.Suggestion {
+	if limit <= 0 {
+		limit = suggestCount
+	}
+	suggestions := a.suggestions.Suggest(query, limit)
+	if suggestions == nil {
+		return []library.Suggestion{}
+	}
+	return suggestions
+}
+
+// rebuildSuggestions indexes the whole library for search suggestions
+func (a *App) rebuildSuggestions() {
+	tracks, err := a.trackRepo.FindAll()
+	if err != nil {
+		logger.Warn("Failed to index search suggestions", logger.Error(err))
+		return
+	}
+	a.suggestions.Build(tracks)
+}
+
+// GetComposers returns the composers in the library
+func (a *App) GetComposers() ([]string, error) {
+	return a.trackRepo.GetComposers()
+}
+
+// GetPublishers returns the publishers and labels in the library
+func (a *App) GetPublishers() ([]string, error) {
+	return a.trackRepo.GetPublishers()
+}
+
+// GetTracksByComposer returns the tracks by a composer
+func (a *App) GetTracksByComposer(composer string) ([]map[string]interface{}, error) {
+	tracks, err := a.trackRepo.FindByComposer(composer)
+	if err != nil {
+		return nil, err
+	}
+	return a.tracksToMaps(tracks), nil
+}
+
+// GetTracksByPublisher returns the tracks released by a publisher or label
+func (a *App) GetTracksByPublisher(publisher string) ([]map[string]interface{}, error) {
+	tracks, err := a.trackRepo.FindByPublisher(publisher)
+	if err != nil {
+		return nil, err
+	}
+	return a.tracksToMaps(tracks), nil
+}
+
+// GetAlbum returns an album release split into discs, with disc subtitles
+// and tracks in play order. albumArtist may be empty when the album has
+// no album artist.
+func (a *App) GetAlbum(album, albumArtist string) (map[string]interface{}, error) {
+	group, err := a.findAlbum(album, albumArtist)
+	if err != nil {
+		return nil, err
+	}
+	domain.PreferVersions([]*domain.AlbumGroup{group}, a.formatPreference())
+	
+	discs := make([]map[string]interface{}, len(group.Discs))
+	for i, disc := range group.Discs {
+		discs[i] = map[string]interface{}{
+			"number":   disc.Number,
+			"subtitle": disc.Subtitle,
+			"tracks":   a.tracksToMaps(disc.Tracks),
+		}
+	}
+	
+	versions := make([]map[string]interface{}, len(group.OtherVersions))
+	for i, version := range group.OtherVersions {
+		versions[i] = map[string]interface{}{
+			"format": string(version.Format),
+			"tracks": a.tracksToMaps(version.Tracks),
+		}
+	}
+	
+	return map[string]interface{}{
+		"title":         group.Title,
+		"artist":        group.Artist,
+		"year":          group.Year,
+		"duration":      group.Duration.Seconds(),
+		"trackCount":    group.TrackCount(),
+		"multiDisc":     group.IsMultiDisc(),
+		"live":          group.IsLive(),
+		"discs":         discs,
+		"otherVersions": versions,
+	}, nil
+}
+
+// PlayAlbum plays an album from the first track of the first disc, in the
+// preferred format when the album exists in several
+func (a *App) PlayAlbum(album, albumArtist string, replaceQueue bool) error {
+	group, err := a.findAlbum(album, albumArtist)
+	if err != nil {
+		return err
+	}
+	domain.PreferVersions([]*domain.AlbumGroup{group}, a.formatPreference())
+	return a.playTrackList(group.Tracks(), replaceQueue)
+}
+
+// GetFormatPreference returns the formats shown first when an album exists
+// in several, most preferred first
+func (a *App) GetFormatPreference() []string {
+	return a.config.Library.FormatPreference
+}
+
+// SetFormatPreference sets the formats shown first when an album exists in
+// several, e.g. ["flac", "mp3"]. Other copies stay reachable as the album's
+// other versions. An empty list shows every copy.
+func (a *App) SetFormatPreference(formats []string) error {
+	pref, err := domain.ParseFormatPreference(formats)
+	if err != nil {
+		return err
+	}
+	
+	names := make([]string, len(pref))
+	for i, format := range pref {
+		names[i] = string(format)
+	}
+	
+	a.config.Library.FormatPreference = names
+	a.config.Set("library.format_preference", names)
+	if err := a.config.Save(); err != nil {
+		return err
+	}
+	
+	runtime.EventsEmit(a.ctx, "library:formatPreferenceChanged", names)
+	return nil
+}
+
+func (a *App) formatPreference() domain.FormatPreference {
+	pref, err := domain.ParseFormatPreference(a.config.Library.FormatPreference)
+	if err != nil {
+		logger.Warn("Invalid format preference", logger.Error(err))
+		return nil
+	}
+	return pref
+}
+
+// hideOtherVersions drops the tracks of album copies in less preferred
+// formats
+func (a *App) hideOtherVersions(tracks []*domain.Track) []*domain.Track {
+	pref := a.formatPreference()
+	if len(pref) == 0 {
+		return tracks
+	}
+	
+	albums := domain.GroupAlbums(tracks)
+	domain.PreferVersions(albums, pref)
+	
+	hidden := make(map[string]bool)
+	for _, album := range albums {
+		for _, track := range album.HiddenTracks() {
+			hidden[track.ID] = true
+		}
+	}
+	if len(hidden) == 0 {
+		return tracks
+	}
+	
+	visible := make([]*domain.Track, 0, len(tracks)-len(hidden))
+	for _, track := range tracks {
+		if !hidden[track.ID] {
+			visible = append(visible, track)
+		}
+	}
+	return visible
+}
+
+// findAlbum finds the release with the given title and album artist
+func (a *App) findAlbum(album, albumArtist string) (*domain.AlbumGroup, error) {
+	tracks, err := a.trackRepo.FindByAlbum(album)
+	if err != nil {
+		return nil, err
+	}
+	
+	for _, group := range domain.GroupAlbums(tracks) {
+		if albumArtist == "" || strings.EqualFold(group.Artist, albumArtist) {
+			return group, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: album %q", domain.ErrTrackNotFound, album)
+}
+
+// ImportFiles imports audio files to the library. policy says what to do
+// with files already in the library (skip, update_in_place, keep_both or
+// prefer_higher_quality); empty uses the library's duplicate policy.
+func (a *App) ImportFiles(paths []string, policy string) (int, error) {
+	if policy == "" {
+		policy = a.config.Library.DuplicatePolicy
+	}
+	duplicates, err := domain.ParseDuplicatePolicy(policy)
+	if err != nil {
+		return 0, err
+	}
+	
+	imported := 0
+	for _, path := range paths {
+		if _, err := a.libraryMgr.scanner.ImportFile(a.ctx, path, duplicates); err != nil {
+			logger.Warn("Failed to import file", logger.String("path", path), logger.Error(err))
+			continue
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// ScanFolder scans a folder for audio files
+func (a *App) ScanFolder(path string) error {
+	_, err := a.libraryMgr.ScanWatchFolder(a.watchFolder(path))
+	return err
+}
+
+// SetDuplicatePolicy sets what scans do with files already in the library:
+// skip, update_in_place, keep_both or prefer_higher_quality. Watch folders
+// with a policy of their own keep it.
+func (a *App) SetDuplicatePolicy(policy string) error {
+	duplicates, err := domain.ParseDuplicatePolicy(policy)
+	if err != nil {
+		return err
+	}
+	
+	a.libraryMgr.scanner.SetDuplicatePolicy(duplicates)
+	a.config.Library.DuplicatePolicy = string(duplicates)
+	a.config.Set("library.duplicate_policy", string(duplicates))
+	return a.config.Save()
+}
+
+// RescanLibrary scans every enabled watch folder again, reading files
+// that changed since they were last read, following files that moved and
+// flagging, or removing, tracks whose files are gone. It returns what
+// changed across the folders.
+func (a *App) RescanLibrary() (map[string]interface{}, error) {
+	total := domain.ScanReport{}
+	var errs []string
+	for _, path := range a.config.Library.WatchFolders {
+		folder := a.watchFolder(path)
+		if !folder.IsEnabled {
+			continue
+		}
+		result, err := a.libraryMgr.ScanWatchFolder(folder)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		total.Added += result.Report.Added
+		total.Updated += result.Report.Updated
+		total.Moved += result.Report.Moved
+		total.Removed += result.Report.Removed
+		total.Failed += result.Report.Failed
+	}
+	
+	return map[string]interface{}{
+		"added":   total.Added,
+		"updated": total.Updated,
+		"moved":   total.Moved,
+		"removed": total.Removed,
+		"failed":  total.Failed,
+		"errors":  errs,
+	}, nil
+}
+
+// SetRemoveMissing sets whether scans delete the tracks of files that are
+// gone instead of flagging them as missing. Deleted tracks lose their
+// ratings and play history.
+func (a *App) SetRemoveMissing(enabled bool) error {
+	a.libraryMgr.scanner.SetRemoveMissing(enabled)
+	a.config.Library.RemoveMissing = enabled
+	a.config.Set("library.remove_missing", enabled)
+	return a.config.Save()
+}
+
+// SetWatchFolderDuplicatePolicy gives a watch folder a duplicate policy of
+// its own. An empty policy returns the folder to the library's policy.
+func (a *App) SetWatchFolderDuplicatePolicy(path, policy string) error {
+	folder := a.watchFolder(path)
+	if policy == "" {
+		folder.DuplicatePolicy = ""
+	} else {
+		duplicates, err := domain.ParseDuplicatePolicy(policy)
+		if err != nil {
+			return err
+		}
+		folder.DuplicatePolicy = duplicates
+	}
+	
+	return a.watchFolders.Save(folder)
+}
+
+// watchFolder returns the saved settings for a folder, or defaults when it
+// has none
+func (a *App) watchFolder(path string) *domain.WatchFolder {
+	folder, err := a.watchFolders.FindByPath(path)
+	if err != nil {
+		if !errors.Is(err, domain.ErrWatchFolderNotFound) {
+			logger.Warn("Failed to load watch folder settings", logger.String("path", path), logger.Error(err))
+		}
+		return domain.NewWatchFolder(path)
+	}
+	return folder
+}
+
+// afterScan follows up every finished scan, including those started by
+// onboarding, with background work on the albums it changed
+func (a *App) afterScan(result *library.ScanResult) {
+	a.playlistMgr.LibraryChanged()
+	go a.rebuildSuggestions()
+	
+	if a.config.Audio.ReplayGainScan && len(result.Albums) > 0 {
+		var tracks []*domain.Track
+		for _, album := range result.Albums {
+			tracks = append(tracks, album.Tracks()...)
+		}
+		go a.scanReplayGain(tracks, false)
+	}
+}
+
+// scanReplayGain measures tracks and stores their ReplayGain. Progress and
+// the summary are published through the gain scanner's events.
+func (a *App) scanReplayGain(tracks []*domain.Track, force bool) {
+	if _, err := a.gainScanner.Scan(a.ctx, tracks, force); err != nil && a.ctx.Err() == nil {
+		logger.Warn("ReplayGain scan failed", logger.Error(err))
+	}
+}
+
+// ScanReplayGain measures the loudness of the library in the background
+// and stores track and album gain. Tracks that already have gain from a
+// full scan are skipped unless force is set. Progress is reported through
+// "library:gainProgress" and the summary through "library:gainFinished".
+func (a *App) ScanReplayGain(force bool) error {
+	if a.gainScanner.IsRunning() {
+		return fmt.Errorf("ReplayGain scan already in progress")
+	}
+
+	tracks, err := a.trackRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	go a.scanReplayGain(tracks, force)
+	return nil
+}
+
+// CancelReplayGainScan stops a running ReplayGain scan
+func (a *App) CancelReplayGainScan() {
+	a.gainScanner.Cancel()
+}
+
+// GetReplayGainProgress returns whether a ReplayGain scan is running and
+// how far it is (0-100)
+func (a *App) GetReplayGainProgress() map[string]interface{} {
+	return map[string]interface{}{
+		"running":  a.gainScanner.IsRunning(),
+		"progress": a.gainScanner.GetProgress(),
+	}
+}
+
+// PreviewScan reports what scanning a folder with the given rules would
+// import, update, skip and exclude, and why, without changing the library.
+// Empty pattern lists use the scanner's defaults.
+func (a *App) PreviewScan(path string, filePatterns, excludePatterns []string, recursive, includeHidden bool) (map[string]interface{}, error) {
+	folder := &domain.WatchFolder{
+		Path:            path,
+		IsRecursive:     recursive,
+		IsEnabled:       true,
+		IncludeHidden:   includeHidden,
+		FilePatterns:    filePatterns,
+		ExcludePatterns: excludePatterns,
+	}
+	
+	preview, err := a.libraryMgr.PreviewFolder(a.ctx, folder)
+	if err != nil {
+		return nil, err
+	}
+	
+	return scanPreviewToMap(preview), nil
+}
+
+// GetScanReport returns what a scan changed in the library: tracks added,
+// updated and removed, and new albums. An empty id returns the latest scan.
+func (a *App) GetScanReport(id string) (map[string]interface{}, error) {
+	var report *domain.ScanReport
+	var err error
+	if id == "" {
+		report, err = a.scanReports.FindLatest()
+	} else {
+		report, err = a.scanReports.FindByID(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	result := scanReportToMap(report)
+	entries := make([]map[string]interface{}, len(report.Entries))
+	for i, entry := range report.Entries {
+		entries[i] = map[string]interface{}{
+			"change":  string(entry.Change),
+			"trackId": entry.TrackID,
+			"path":    entry.Path,
+			"title":   entry.Title,
+			"artist":  entry.Artist,
+			"album":   entry.Album,
+		}
+	}
+	result["entries"] = entries
+	return result, nil
+}
+
+// GetScanReports returns summaries of recent scans, newest first
+func (a *App) GetScanReports(limit int) ([]map[string]interface{}, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	
+	reports, err := a.scanReports.FindRecent(limit)
+	if err != nil {
+		return nil, err
+	}
+	
+	result := make([]map[string]interface{}, len(reports))
+	for i, report := range reports {
+		result[i] = scanReportToMap(report)
+	}
+	return result, nil
+}
+
+// VerifyLibrary checks all library files for corruption or truncation in
+// the background. Progress is reported through "library:verifyProgress"
+// and the summary through "library:verifyComplete".
+func (a *App) VerifyLibrary() error {
+	if a.verifier.IsRunning() {
+		return fmt.Errorf("verification already in progress")
+	}
+	
+	go func() {
+		done := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(500 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					runtime.EventsEmit(a.ctx, "library:verifyProgress", a.verifier.GetProgress())
+				}
+			}
+		}()
+		
+		result, err := a.verifier.Verify(a.ctx)
+		close(done)
+		if err != nil {
+			logger.Warn("Library verification stopped", logger.Error(err))
+			runtime.EventsEmit(a.ctx, "library:verifyComplete", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		
+		runtime.EventsEmit(a.ctx, "library:verifyComplete", map[string]interface{}{
+			"checked":  result.Checked,
+			"valid":    result.Valid,
+			"invalid":  result.Invalid,
+			"repaired": result.Repaired,
+			"duration": result.Duration.Seconds(),
+		})
+	}()
+	
+	return nil
+}
+
+// CancelVerify stops a running library verification
+func (a *App) CancelVerify() {
+	a.verifier.Cancel()
+}
+
+// Folder Browse Methods
+
+// GetFolderRoots returns the watch folders as the roots of the folder tree
+func (a *App) GetFolderRoots() ([]library.FolderNode, error) {
+	return a.folders.Roots(a.config.Library.WatchFolders)
+}
+
+// BrowseFolder returns the subfolders of a folder and a page of the tracks
+// directly inside it. Large folders are loaded by calling again with a
+// higher offset; a limit of zero uses the default page size.
+func (a *App) BrowseFolder(path string, offset, limit int) (map[string]interface{}, error) {
+	listing, err := a.folders.List(path, a.config.Library.WatchFolders, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	
+	tracks := make([]map[string]interface{}, len(listing.Tracks))
+	for i, track := range listing.Tracks {
+		tracks[i] = a.trackToMap(track)
+	}
+	
+	return map[string]interface{}{
+		"path":        listing.Path,
+		"folders":     listing.Folders,
+		"tracks":      tracks,
+		"totalTracks": listing.TotalTracks,
+		"offset":      listing.Offset,
+		"hasMore":     int64(listing.Offset+len(listing.Tracks)) < listing.TotalTracks,
+	}, nil
+}
+
+// PlayFolder plays every track in a folder and its subfolders in path order
+func (a *App) PlayFolder(path string, replaceQueue bool) error {
+	tracks, err := a.folderTracks(path)
+	if err != nil {
+		return err
+	}
+	return a.playTrackList(tracks, replaceQueue)
+}
+
+// EnqueueFolder queues every track in a folder and its subfolders and
+// returns how many were added
+func (a *App) EnqueueFolder(path string, next bool) (int, error) {
+	tracks, err := a.folderTracks(path)
+	if err != nil {
+		return 0, err
+	}
+	a.enqueueTrackList(tracks, next)
+	return len(tracks), nil
+}
+
+// folderTracks returns the playable tracks under a folder
+func (a *App) folderTracks(path string) ([]*domain.Track, error) {
+	tracks, err := a.folders.Tracks(path, a.config.Library.WatchFolders)
+	if err != nil {
+		return nil, err
+	}
+	
+	playable := tracks[:0]
+	for _, track := range tracks {
+		if track.IsValid {
+			playable = append(playable, track)
+		}
+	}
+	if len(playable) == 0 {
+		return nil, fmt.Errorf("%w: no playable tracks in %s", domain.ErrTrackNotFound, path)
+	}
+	return playable, nil
+}
+
+// Rating Methods
+
+// SetTrackRating sets a track's rating from 0 (unrated) to 5 stars
+func (a *App) SetTrackRating(trackID string, rating int) (map[string]interface{}, error) {
+	track, err := a.trackRepo.FindByID(trackID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := track.SetRating(rating); err != nil {
+		return nil, err
+	}
+	
+	return a.saveRating(track)
+}
+
+// ToggleFavorite marks a track as a favorite, or unmarks it, and returns
+// the updated track
+func (a *App) ToggleFavorite(trackID string) (map[string]interface{}, error) {
+	track, err := a.trackRepo.FindByID(trackID)
+	if err != nil {
+		return nil, err
+	}
+	
+	track.SetFavorite(!track.Favorite)
+	return a.saveRating(track)
+}
+
+// GetFavoriteTracks returns all tracks marked as favorites
+func (a *App) GetFavoriteTracks() ([]map[string]interface{}, error) {
+	tracks, err := a.trackRepo.FindFavorites()
+	if err != nil {
+		return nil, err
+	}
+	
+	result := make([]map[string]interface{}, len(tracks))
+	for i, track := range tracks {
+		result[i] = a.trackToMap(track)
+	}
+	return result, nil
+}
+
+// saveRating persists a rating or favorite change, runs the rating hooks
+// and tells the frontend, so views filtered on ratings refresh at once
+func (a *App) saveRating(track *domain.Track) (map[string]interface{}, error) {
+	if err := a.trackRepo.UpdateRating(track); err != nil {
+		return nil, err
+	}
+	
+	for _, hook := range a.ratingHooks {
+		if err := hook(track); err != nil {
+			logger.Warn("Rating sync failed", logger.String("id", track.ID), logger.Error(err))
+		}
+	}
+	
+	result := a.trackToMap(track)
+	runtime.EventsEmit(a.ctx, "library:ratingChanged", map[string]interface{}{
+		"trackId":  track.ID,
+		"rating":   track.Rating,
+		"favorite": track.Favorite,
+	})
+	a.playlistMgr.LibraryChanged()
+	runtime.EventsEmit(a.ctx, "library:trackUpdated", result)
+	return result, nil
+}
+
+// addRatingHook registers a hook to run after each rating change
+func (a *App) addRatingHook(hook ratingHook) {
+	a.ratingHooks = append(a.ratingHooks, hook)
+}
+
+// syncPlayingRating keeps the player's copy of the current track in step
+// so the player state reports the new rating
+func (a *App) syncPlayingRating(track *domain.Track) error {
+	if current := a.player.GetCurrentTrack(); current != nil && current.ID == track.ID {
+		current.Rating = track.Rating
+		current.Favorite = track.Favorite
+	}
+	return nil
+}
+
+// writeRatingTags writes a new rating into the track's file, when the
+// settings ask for it, so other players see it too
+func (a *App) writeRatingTags(track *domain.Track) error {
+	if !a.config.Library.WriteRatingTags || !library.CanWriteRating(track) {
+		return nil
+	}
+	if err := library.WriteRating(track); err != nil {
+		return err
+	}
+	
+	// The rewritten file has a new size and checksum
+	return a.trackRepo.Update(track)
+}
+
+// SetWriteRatingTags sets whether ratings are also written into files
+func (a *App) SetWriteRatingTags(enabled bool) error {
+	a.config.Library.WriteRatingTags = enabled
+	a.config.Set("library.write_rating_tags", enabled)
+	return a.config.Save()
+}
+
+// Tag Editing Methods
+
+// UpdateTrackTags edits a track's tags in the library. Keys are the track
+// fields as sent to the frontend; values are normalized with the same rules
+// as imported tags.
+func (a *App) UpdateTrackTags(trackID string, tags map[string]interface{}) (map[string]interface{}, error) {
+	return a.updateTrack(trackID, tags, false)
+}
+
+// UpdateTrackMetadata edits the tags of a track like UpdateTrackTags and
+// also writes them into its file: ID3v2 for MP3, Vorbis comments for FLAC
+// and Ogg, and iTunes atoms for MP4. Formats that can't be written are only
+// changed in the library.
+func (a *App) UpdateTrackMetadata(trackID string, tags map[string]interface{}) (map[string]interface{}, error) {
+	return a.updateTrack(trackID, tags, true)
+}
+
+// BatchUpdateMetadata sets the same tags on many tracks, such as an album
+// or genre for a selection, writing them into the files that can take
+// them. Tracks that fail are skipped and reported by ID.
+func (a *App) BatchUpdateMetadata(trackIDs []string, tags map[string]interface{}) (map[string]interface{}, error) {
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("%w: no tags to set", domain.ErrInvalidInput)
+	}
+	
+	updated := 0
+	failed := make(map[string]string)
+	for _, id := range trackIDs {
+		track, err := a.editTrack(id, tags, true)
+		if err != nil {
+			// A bad tag value fails every track the same way
+			if errors.Is(err, domain.ErrInvalidInput) {
+				return nil, err
+			}
+			logger.Warn("Failed to update track metadata",
+				logger.String("track", id),
+				logger.Error(err))
+			failed[id] = err.Error()
+			continue
+		}
+		updated++
+		runtime.EventsEmit(a.ctx, "library:trackUpdated", a.trackToMap(track))
+	}
+	if updated > 0 {
+		a.playlistMgr.LibraryChanged()
+	}
+	
+	return map[string]interface{}{
+		"updated": updated,
+		"failed":  failed,
+	}, nil
+}
+
+func (a *App) updateTrack(trackID string, tags map[string]interface{}, writeFile bool) (map[string]interface{}, error) {
+	track, err := a.editTrack(trackID, tags, writeFile)
+	if err != nil {
+		return nil, err
+	}
+	
+	result := a.trackToMap(track)
+	a.playlistMgr.LibraryChanged()
+	runtime.EventsEmit(a.ctx, "library:trackUpdated", result)
+	return result, nil
+}
+
+// editTrack sets tags on a track and saves it, writing them into its file
+// first when asked and the format allows. A failed file write leaves the
+// library as it was.
+func (a *App) editTrack(trackID string, tags map[string]interface{}, writeFile bool) (*domain.Track, error) {
+	track, err := a.trackRepo.FindByID(trackID)
+	if err != nil {
+		return nil, err
+	}
+	
+	for key, value := range tags {
+		if err := setTrackTag(track, key, value); err != nil {
+			return nil, err
+		}
+	}
+	
+	// The file gets the tags as entered; the library keeps them normalized
+	// as a scan of the file would
+	written := writeFile && library.CanWriteTags(track)
+	if written {
+		if err := library.WriteTags(track); err != nil {
+			return nil, err
+		}
+	}
+	if a.normalizer != nil {
+		a.normalizer.Apply(track)
+	}
+	track.UpdatedAt = time.Now()
+	
+	// A rewritten file also has a new size and checksum
+	if written {
+		err = a.trackRepo.Update(track)
+	} else {
+		err = a.trackRepo.UpdateTags(track)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.suggestions.Update(track)
+	return track, nil
+}
+
+// setTrackTag sets one editable tag field from a frontend value
+func setTrackTag(track *domain.Track, key string, value interface{}) error {
+	switch key {
+	case "year", "trackNumber", "discNumber":
+		number, ok := value.(float64)
+		if !ok || number < 0 {
+			return fmt.Errorf("%w: %s must be a non-negative number", domain.ErrInvalidInput, key)
+		}
+		switch key {
+		case "year":
+			track.Year = int(number)
+		case "trackNumber":
+			track.TrackNumber = int(number)
+		case "discNumber":
+			track.DiscNumber = int(number)
+		}
+		return nil
+	}
+	
+	text, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%w: %s must be text", domain.ErrInvalidInput, key)
+	}
+	switch key {
+	case "title":
+		track.Title = text
+	case "artist":
+		track.Artist = text
+	case "album":
+		track.Album = text
+	case "albumArtist":
+		track.AlbumArtist = text
+	case "genre":
+		track.Genre = text
+	case "discSubtitle":
+		track.DiscSubtitle = text
+	case "composer":
+		track.Composer = text
+	case "publisher":
+		track.Publisher = text
+	case "comment":
+		track.Comment = text
+	default:
+		return fmt.Errorf("%w: unknown tag %q", domain.ErrInvalidInput, key)
+	}
+	return nil
+}
+
+// EmbedAlbumArt writes a cover image into the files of an album in the
+// background. When imagePath is empty the user is asked to pick an image.
+// maxSize shrinks the image to fit (0 keeps its size) and format converts it
+// to "jpeg" or "png" (empty keeps it). Progress is reported through
+// "library:artEmbedProgress" and the summary through
+// "library:artEmbedComplete".
+func (a *App) EmbedAlbumArt(album, albumArtist, imagePath string, maxSize int, format string) error {
+	if a.artEmbedder.IsRunning() {
+		return fmt.Errorf("art embedding already in progress")
+	}
+	
+	group, err := a.findAlbum(album, albumArtist)
+	if err != nil {
+		return err
+	}
+	
+	if imagePath == "" {
+		imagePath, err = runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+			Title: "Choose Cover Image",
+			Filters: []runtime.FileFilter{
+				{DisplayName: "Images", Pattern: "*.jpg;*.jpeg;*.png;*.gif"},
+			},
+		})
+		if err != nil || imagePath == "" {
+			return err
+		}
+	}
+	
+	image, err := os.ReadFile(imagePath)
+	if err != nil {
+		return err
+	}
+	
+	opts := library.ArtEmbedOptions{MaxSize: maxSize, Format: format}
+	tracks := group.Tracks()
+	
+	go func() {
+		done := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(500 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					runtime.EventsEmit(a.ctx, "library:artEmbedProgress", a.artEmbedder.GetProgress())
+				}
+			}
+		}()
+		
+		result, err := a.artEmbedder.Embed(a.ctx, tracks, image, opts)
+		close(done)
+		if err != nil && result == nil {
+			logger.Warn("Album art embedding failed", logger.String("album", group.Title), logger.Error(err))
+			runtime.EventsEmit(a.ctx, "library:artEmbedComplete", map[string]interface{}{
+				"album": group.Title,
+				"error": err.Error(),
+			})
+			return
+		}
+		
+		for _, track := range tracks {
+			runtime.EventsEmit(a.ctx, "library:trackUpdated", a.trackToMap(track))
+		}
+		
+		summary := map[string]interface{}{
+			"album":    group.Title,
+			"embedded": result.Embedded,
+			"skipped":  result.Skipped,
+			"failed":   result.Failed,
+			"duration": result.Duration.Seconds(),
+		}
+		if err != nil {
+			summary["error"] = err.Error()
+		}
+		runtime.EventsEmit(a.ctx, "library:artEmbedComplete", summary)
+	}()
+	
+	return nil
+}
+
+// CancelEmbedAlbumArt stops a running art embedding job
+func (a *App) CancelEmbedAlbumArt() {
+	a.artEmbedder.Cancel()
+}
+
+// User Tag Methods
+
+// GetUserTags returns all user tags with the number of tracks carrying each
+func (a *App) GetUserTags() ([]map[string]interface{}, error) {
+	tags, err := a.userTagRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	
+	counts, err := a.userTagRepo.CountTracks()
+	if err != nil {
+		return nil, err
+	}
+	
+	result := make([]map[string]interface{}, len(tags))
+	for i, tag := range tags {
+		result[i] = userTagToMap(tag)
+		result[i]["trackCount"] = counts[tag.ID]
+	}
+	return result, nil
+}
+
+// CreateUserTag creates a tag. color is "#rrggbb" or empty.
+func (a *App) CreateUserTag(name, color string) (map[string]interface{}, error) {
+	tag, err := domain.NewUserTag(name, color)
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := a.userTagRepo.Create(tag); err != nil {
+		return nil, err
+	}
+	
+	runtime.EventsEmit(a.ctx, "library:userTagsChanged")
+	return userTagToMap(tag), nil
+}
+
+// UpdateUserTag renames or recolors a tag
+func (a *App) UpdateUserTag(id, name, color string) (map[string]interface{}, error) {
+	tag, err := a.userTagRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	
+	tag.Name = strings.TrimSpace(name)
+	tag.Color = color
+	if err := a.userTagRepo.Update(tag); err != nil {
+		return nil, err
+	}
+	
+	a.playlistMgr.LibraryChanged()
+	runtime.EventsEmit(a.ctx, "library:userTagsChanged")
+	return userTagToMap(tag), nil
+}
+
+// DeleteUserTag deletes a tag and removes it from every track
+func (a *App) DeleteUserTag(id string) error {
+	if err := a.userTagRepo.Delete(id); err != nil {
+		return err
+	}
+	
+	a.playlistMgr.LibraryChanged()
+	runtime.EventsEmit(a.ctx, "library:userTagsChanged")
+	return nil
+}
+
+// TagTracks adds a tag to a selection of tracks, creating the tag if no tag
+// has that name yet
+func (a *App) TagTracks(name string, trackIDs []string) (map[string]interface{}, error) {
+	tag, err := a.tagTracks(name, trackIDs)
+	if err != nil {
+		return nil, err
+	}
+	
+	a.playlistMgr.LibraryChanged()
+	runtime.EventsEmit(a.ctx, "library:userTagsChanged")
+	return userTagToMap(tag), nil
+}
+
+func (a *App) tagTracks(name string, trackIDs []string) (*domain.UserTag, error) {
+	tag, err := a.userTagRepo.FindByName(strings.TrimSpace(name))
+	if errors.Is(err, domain.ErrUserTagNotFound) {
+		if tag, err = domain.NewUserTag(name, ""); err == nil {
+			err = a.userTagRepo.Create(tag)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := a.userTagRepo.AddToTracks(tag.ID, trackIDs); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// UntagTracks removes a tag from a selection of tracks
+func (a *App) UntagTracks(tagID string, trackIDs []string) error {
+	if err := a.userTagRepo.RemoveFromTracks(tagID, trackIDs); err != nil {
+		return err
+	}
+	
+	a.playlistMgr.LibraryChanged()
+	runtime.EventsEmit(a.ctx, "library:userTagsChanged")
+	return nil
+}
+
+// GetTrackUserTags returns the tags on a track
+func (a *App) GetTrackUserTags(trackID string) ([]map[string]interface{}, error) {
+	tags, err := a.userTagRepo.FindByTrack(trackID)
+	if err != nil {
+		return nil, err
+	}
+	
+	result := make([]map[string]interface{}, len(tags))
+	for i, tag := range tags {
+		result[i] = userTagToMap(tag)
+	}
+	return result, nil
+}
+
+// GetTracksByUserTag returns the tracks carrying a tag
+func (a *App) GetTracksByUserTag(tagID string) ([]map[string]interface{}, error) {
+	ids, err := a.userTagRepo.FindTrackIDs(tagID)
+	if err != nil {
+		return nil, err
+	}
+	
+	tracks := make([]*domain.Track, 0, len(ids))
+	for _, id := range ids {
+		track, err := a.trackRepo.FindByID(id)
+		if err != nil {
+			continue
+		}
+		tracks = append(tracks, track)
+	}
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return tracks[i].GetSortKey() < tracks[j].GetSortKey()
+	})
+	
+	if err := a.userTagRepo.LoadForTracks(tracks); err != nil {
+		return nil, err
+	}
+	return a.tracksToMaps(tracks), nil
+}
+
+func userTagToMap(tag *domain.UserTag) map[string]interface{} {
+	return map[string]interface{}{
+		"id":    tag.ID,
+		"name":  tag.Name,
+		"color": tag.Color,
+	}
+}
+
+// Import Rule Methods
+
+// GetImportRules returns the import rules attached to a watch folder, or
+// every rule when folder is empty
+func (a *App) GetImportRules(folder string) ([]map[string]interface{}, error) {
+	var rules []*domain.ImportRule
+	var err error
+	if folder == "" {
+		rules, err = a.importRules.FindAll()
+	} else {
+		rules, err = a.importRules.FindByFolder(folder)
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	result := make([]map[string]interface{}, len(rules))
+	for i, rule := range rules {
+		result[i] = importRuleToMap(rule)
+	}
+	return result, nil
+}
+
+// AddImportRule attaches a rule to a watch folder that the scanner applies
+// to new files found under it. Actions are add_to_playlist (value is the
+// playlist ID), add_tag (a user tag name, created if missing), set_genre
+// and mark_audiobook.
+func (a *App) AddImportRule(folder, action, value string) (map[string]interface{}, error) {
+	rule, err := domain.NewImportRule(folder, domain.ImportAction(action), value)
+	if err != nil {
+		return nil, err
+	}
+	
+	if rule.Action == domain.ImportAddToPlaylist {
+		if _, err := a.playlistMgr.Get(rule.Value); err != nil {
+			return nil, err
+		}
+	}
+	
+	if err := a.importRules.Create(rule); err != nil {
+		return nil, err
+	}
+	return importRuleToMap(rule), nil
+}
+
+// DeleteImportRule removes an import rule. Tracks it already changed are
+// left as they are.
+func (a *App) DeleteImportRule(id string) error {
+	return a.importRules.Delete(id)
+}
+
+// applyImportRule carries out the import rules the scanner leaves to the
+// app, for a newly imported track
+func (a *App) applyImportRule(track *domain.Track, rule *domain.ImportRule) error {
+	switch rule.Action {
+	case domain.ImportAddToPlaylist:
+		return a.playlistMgr.AddTrack(rule.Value, track)
+	case domain.ImportAddTag:
+		_, err := a.tagTracks(rule.Value, []string{track.ID})
+		return err
+	}
+	return nil
+}
+
+func importRuleToMap(rule *domain.ImportRule) map[string]interface{} {
+	return map[string]interface{}{
+		"id":     rule.ID,
+		"folder": rule.Folder,
+		"action": string(rule.Action),
+		"value":  rule.Value,
+	}
+}
+
+// Problem File Methods
+
+// GetProblemFiles returns tracks that failed to decode or verify
+func (a *App) GetProblemFiles() []map[string]interface{} {
+	tracks, err := a.problems.List()
+	if err != nil {
+		logger.ErrorLog("Failed to get problem files", logger.Error(err))
+		return []map[string]interface{}{}
+	}
+	
+	result := make([]map[string]interface{}, len(tracks))
+	for i, track := range tracks {
+		result[i] = a.trackToMap(track)
+	}
+	
+	return result
+}
+
+// RetryProblemFile verifies a problem file again
+func (a *App) RetryProblemFile(id string) (map[string]interface{}, error) {
+	track, err := a.problems.Retry(id)
+	if err != nil {
+		return nil, err
+	}
+	return a.trackToMap(track), nil
 }
 
-// Stop stops playback
-func (a *App) Stop() error {
-	return a.player.Stop()
+// RescanProblemFile accepts the file's current content as the new reference
+func (a *App) RescanProblemFile(id string) (map[string]interface{}, error) {
+	track, err := a.problems.Rescan(id)
+	if err != nil {
+		return nil, err
+	}
+	return a.trackToMap(track), nil
 }
 
-// Next plays the next track
-func (a *App) Next() error {
-	track := a.playlistMgr.GetNextTrack()
-	if track == nil {
-		return fmt.Errorf("no next track")
+// LocateProblemFile points a problem track at a replacement file
+func (a *App) LocateProblemFile(id string, path string) (map[string]interface{}, error) {
+	track, err := a.problems.Relocate(id, path)
+	if err != nil {
+		return nil, err
 	}
-	return a.LoadTrack(track)
+	return a.trackToMap(track), nil
 }
 
-// Previous plays the previous track
-func (a *App) Previous() error {
-	track := a.playlistMgr.GetPreviousTrack()
-	if track == nil {
-		return fmt.Errorf("no previous track")
+// RemoveProblemFile removes a problem track from the library
+func (a *App) RemoveProblemFile(id string) error {
+	if err := a.problems.Remove(id); err != nil {
+		return err
 	}
-	return a.LoadTrack(track)
+	a.suggestions.Remove(id)
+	a.playlistMgr.LibraryChanged()
+	return nil
 }
 
-// Seek seeks to a position in seconds
-func (a *App) Seek(seconds float64) error {
-	duration := time.Duration(seconds * float64(time.Second))
-	return a.player.Seek(duration)
+// File Operation Methods
+
+// RevealTrack opens Explorer on the track's folder with the file selected
+func (a *App) RevealTrack(id string) error {
+	return a.fileOps.Reveal(id)
 }
 
-// SetVolume sets the volume (0.0 to 1.0)
-func (a *App) SetVolume(volume float64) error {
-	return a.player.SetVolume(volume)
+// CopyTrackPath copies the track's file path to the clipboard
+func (a *App) CopyTrackPath(id string) error {
+	track, err := a.trackRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	return runtime.ClipboardSetText(a.ctx, track.FilePath)
 }
 
-// GetPlayerState returns the current player state
-func (a *App) GetPlayerState() map[string]interface{} {
-	state := make(map[string]interface{})
-	state["state"] = a.player.GetState().String()
-	state["position"] = a.player.GetPosition().Seconds()
-	state["duration"] = a.player.GetDuration().Seconds()
+// DeleteTrackFile asks for confirmation, sends the track's file to the
+// Recycle Bin and removes it from the library and all playlists. It returns
+// false if the user cancelled.
+func (a *App) DeleteTrackFile(id string) (bool, error) {
+	track, err := a.trackRepo.FindByID(id)
+	if err != nil {
+		return false, err
+	}
 	
-	if track := a.player.GetCurrentTrack(); track != nil {
-		state["track"] = a.trackToMap(track)
+	answer, err := runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+		Type:          runtime.QuestionDialog,
+		Title:         "Delete File",
+		Message:       fmt.Sprintf("Move \"%s\" to the Recycle Bin?\n\n%s", track.GetDisplayTitle(), track.FilePath),
+		Buttons:       []string{"Yes", "No"},
+		DefaultButton: "No",
+		CancelButton:  "No",
+	})
+	if err != nil {
+		return false, err
+	}
+	if answer != "Yes" {
+		return false, nil
 	}
 	
-	return state
-}
-
-// LoadTrack loads a track for playback
-func (a *App) LoadTrack(track *domain.Track) error {
-	if err := a.player.Load(track); err != nil {
-		return err
+	// Windows won't recycle a file that is still open
+	a.player.Release(id)
+	
+	if _, err := a.fileOps.Recycle(id); err != nil {
+		return false, err
 	}
 	
-	// Set next track for gapless playback
-	if next := a.playlistMgr.PeekNextTrack(); next != nil {
-		a.player.SetNextTrack(next)
+	// A playlist may hold the track more than once
+	for _, pl := range a.playlistMgr.GetAll() {
+		for {
+			err := a.playlistMgr.RemoveTrack(pl.ID, id)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, domain.ErrTrackNotFound) {
+				logger.Warn("Failed to remove deleted track from playlist", logger.String("playlist", pl.ID), logger.Error(err))
+			}
+			break
+		}
 	}
 	
-	return nil
+	if a.playlistMgr.GetQueue().RemoveTrack(id) > 0 {
+		a.emitQueueChanged()
+	}
+	
+	a.suggestions.Remove(id)
+	a.playlistMgr.LibraryChanged()
+	runtime.EventsEmit(a.ctx, "library:trackRemoved", id)
+	return true, nil
 }
 
-// LoadFile loads a file for playback
-func (a *App) LoadFile(path string) error {
-	track, err := a.libraryMgr.ImportTrack(path)
+// MoveTrackFile moves the track's file to a folder inside one of the watch
+// folders and updates the library
+func (a *App) MoveTrackFile(id string, destFolder string) (map[string]interface{}, error) {
+	if current := a.player.GetCurrentTrack(); current != nil && current.ID == id {
+		return nil, fmt.Errorf("cannot move the track that is currently loaded")
+	}
+	
+	track, err := a.fileOps.Move(id, destFolder, a.config.Library.WatchFolders)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return a.LoadTrack(track)
+	
+	result := a.trackToMap(track)
+	runtime.EventsEmit(a.ctx, "library:trackUpdated", result)
+	return result, nil
 }
 
-// Playlist Methods
+// Recommendation Methods
 
-// GetPlaylists returns all playlists
-func (a *App) GetPlaylists() []map[string]interface{} {
-	playlists := a.playlistMgr.GetAll()
-	result := make([]map[string]interface{}, len(playlists))
+// autoDJQueueAhead is how many upcoming tracks party mode keeps queued
+const autoDJQueueAhead = 2
+
+// GetSimilarTracks returns library tracks similar to the given track, based
+// on genre, BPM, year and what has been played together before
+func (a *App) GetSimilarTracks(trackID string, limit int) ([]map[string]interface{}, error) {
+	seed, err := a.trackRepo.FindByID(trackID)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 20
+	}
 	
-	for i, pl := range playlists {
-		result[i] = a.playlistToMap(pl)
+	tracks, err := a.recommender.Similar(seed, limit, nil)
+	if err != nil {
+		return nil, err
 	}
 	
-	return result
+	result := make([]map[string]interface{}, len(tracks))
+	for i, track := range tracks {
+		result[i] = a.trackToMap(track)
+	}
+	
+	return result, nil
 }
 
-// GetPlaylist returns a playlist by ID
-func (a *App) GetPlaylist(id string) (map[string]interface{}, error) {
-	playlist, err := a.playlistMgr.Get(id)
+// QueueSimilarTracks adds tracks similar to the given track to the end of
+// the queue and returns how many were added
+func (a *App) QueueSimilarTracks(trackID string, count int) (int, error) {
+	seed, err := a.trackRepo.FindByID(trackID)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	return a.playlistToMap(playlist), nil
+	
+	tracks, err := a.recommender.Similar(seed, count, a.queuedTrackIDs())
+	if err != nil {
+		return 0, err
+	}
+	
+	for _, track := range tracks {
+		a.playlistMgr.AddToQueue(track)
+	}
+	
+	return len(tracks), nil
 }
 
-// CreatePlaylist creates a new playlist
-func (a *App) CreatePlaylist(name string) (map[string]interface{}, error) {
-	playlist, err := a.playlistMgr.Create(name)
-	if err != nil {
-		return nil, err
+// SetPartyMode turns the auto-DJ on or off. While on, the queue is kept
+// filled with tracks that follow on from what is playing.
+func (a *App) SetPartyMode(enabled bool) {
+	a.partyMu.Lock()
+	a.partyMode = enabled
+	a.partyMu.Unlock()
+	
+	if enabled {
+		if track := a.player.GetCurrentTrack(); track != nil {
+			a.fillAutoDJQueue(track)
+			if next := a.playlistMgr.PeekNextTrack(); next != nil {
+				a.player.SetNextTrack(next)
+			}
+		}
 	}
-	return a.playlistToMap(playlist), nil
 }
 
-// DeletePlaylist deletes a playlist
-func (a *App) DeletePlaylist(id string) error {
-	return a.playlistMgr.Delete(id)
+// SetFamilyMode turns family mode on or off. While on, shuffle passes over
+// tracks rated explicit and the auto-DJ and similar-track suggestions
+// leave them out; tracks chosen directly still play.
+func (a *App) SetFamilyMode(enabled bool) error {
+	a.applyFamilyMode(enabled)
+	a.config.App.FamilyMode = enabled
+	a.config.Set("app.family_mode", enabled)
+	a.transportChanged()
+	return a.config.Save()
 }
 
-// AddToPlaylist adds tracks to a playlist
-func (a *App) AddToPlaylist(playlistID string, trackIDs []string) error {
-	for _, trackID := range trackIDs {
-		track, err := a.trackRepo.FindByID(trackID)
+// applyFamilyMode passes family mode on to the queue and recommender
+func (a *App) applyFamilyMode(enabled bool) {
+	a.playlistMgr.GetQueue().SetFamilyMode(enabled)
+	a.recommender.SetFamilyMode(enabled)
+}
+
+// IsPartyMode returns whether the auto-DJ is on
+func (a *App) IsPartyMode() bool {
+	return a.isPartyMode()
+}
+
+func (a *App) isPartyMode() bool {
+	a.partyMu.Lock()
+	defer a.partyMu.Unlock()
+	return a.partyMode
+}
+
+// fillAutoDJQueue tops up the queue so a few tracks are always ahead of the
+// current one
+func (a *App) fillAutoDJQueue(current *domain.Track) {
+	queue := a.playlistMgr.GetQueue()
+	tracks := queue.GetTracks()
+	position := queue.GetPosition()
+	
+	// The last few played tracks seed the next pick
+	recent := make([]*domain.Track, 0, 4)
+	for i := max(0, position-2); i <= position && i < len(tracks); i++ {
+		if tracks[i].ID != current.ID {
+			recent = append(recent, tracks[i])
+		}
+	}
+	recent = append(recent, current)
+	
+	exclude := a.queuedTrackIDs()
+	for ahead := len(tracks) - position - 1; ahead < autoDJQueueAhead; ahead++ {
+		next, err := a.recommender.NextForAutoDJ(recent, exclude)
 		if err != nil {
-			logger.Warn("Track not found", logger.String("id", trackID))
-			continue
+			logger.Warn("Auto-DJ failed to pick a track", logger.Error(err))
+			return
 		}
-		if err := a.playlistMgr.AddTrack(playlistID, track); err != nil {
-			return err
+		if next == nil {
+			return
 		}
+		
+		a.playlistMgr.AddToQueue(next)
+		exclude[next.ID] = true
+		recent = append(recent, next)
+		logger.Debug("Auto-DJ queued track", logger.String("id", next.ID))
 	}
-	return nil
 }
 
-// RemoveFromPlaylist removes tracks from a playlist
-func (a *App) RemoveFromPlaylist(playlistID string, trackIDs []string) error {
-	for _, trackID := range trackIDs {
-		if err := a.playlistMgr.RemoveTrack(playlistID, trackID); err != nil {
-			logger.Warn("Failed to remove track", logger.String("id", trackID), logger.Error(err))
-		}
+// queuedTrackIDs returns the IDs of all tracks in the queue
+func (a *App) queuedTrackIDs() map[string]bool {
+	ids := make(map[string]bool)
+	for _, track := range a.playlistMgr.GetQueue().GetTracks() {
+		ids[track.ID] = true
 	}
-	return nil
+	return ids
 }
 
-// Library Methods
+// Now Playing Context Methods
 
-// GetLibraryTracks returns all tracks in the library
-func (a *App) GetLibraryTracks() []map[string]interface{} {
-	tracks, err := a.trackRepo.FindAll()
-	if err != nil {
-		logger.Error("Failed to get library tracks", logger.Error(err))
-		return []map[string]interface{}{}
+// GetNowPlayingContext returns artist biography, album review and similar
+// artists for the current track. It returns nil when nothing is playing or
+// context fetching is disabled.
+func (a *App) GetNowPlayingContext() (map[string]interface{}, error) {
+	track := a.player.GetCurrentTrack()
+	if track == nil || !a.config.Network.FetchContext {
+		return nil, nil
 	}
 	
-	result := make([]map[string]interface{}, len(tracks))
-	for i, track := range tracks {
-		result[i] = a.trackToMap(track)
+	info, err := a.contextSvc.Get(a.ctx, contextArtist(track), track.Album)
+	if err != nil {
+		if errors.Is(err, metadata.ErrNoContext) {
+			return nil, nil
+		}
+		return nil, err
 	}
 	
-	return result
+	return contextToMap(track, info), nil
 }
 
-// SearchTracks searches for tracks
-func (a *App) SearchTracks(query string) []map[string]interface{} {
-	tracks, err := a.trackRepo.Search(query)
+// prefetchNowPlayingContext fetches context when a track starts and pushes
+// it to the UI through "player:nowPlayingContext"
+func (a *App) prefetchNowPlayingContext(track *domain.Track) {
+	info, err := a.contextSvc.Get(a.ctx, contextArtist(track), track.Album)
 	if err != nil {
-		logger.Error("Failed to search tracks", logger.Error(err))
-		return []map[string]interface{}{}
+		if !errors.Is(err, metadata.ErrNoContext) {
+			logger.Debug("Failed to fetch now playing context", logger.String("artist", track.Artist), logger.Error(err))
+		}
+		return
 	}
 	
-	result := make([]map[string]interface{}, len(tracks))
-	for i, track := range tracks {
-		result[i] = a.trackToMap(track)
+	// Skip if playback moved on while fetching
+	if current := a.player.GetCurrentTrack(); current == nil || current.ID != track.ID {
+		return
 	}
 	
-	return result
+	runtime.EventsEmit(a.ctx, "player:nowPlayingContext", contextToMap(track, info))
 }
 
-// ImportFiles imports audio files to the library
-func (a *App) ImportFiles(paths []string) (int, error) {
-	imported := 0
-	for _, path := range paths {
-		if _, err := a.libraryMgr.ImportTrack(path); err != nil {
-			logger.Warn("Failed to import file", logger.String("path", path), logger.Error(err))
-			continue
+// GetArtistImage returns an image of an artist as a data URL, at "thumb",
+// "medium" or "large" size. artistID is the MusicBrainz ID when known. It
+// returns nil when no image is available.
+func (a *App) GetArtistImage(artist, artistID, size string) (map[string]interface{}, error) {
+	image, err := a.artistImages.Get(a.ctx, artistID, artist, library.ArtistImageSize(size))
+	if err != nil {
+		if errors.Is(err, metadata.ErrNoArtistImage) {
+			return nil, nil
 		}
-		imported++
+		return nil, err
 	}
-	return imported, nil
+	
+	return artistImageToMap(image)
 }
 
-// ScanFolder scans a folder for audio files
-func (a *App) ScanFolder(path string) error {
-	return a.libraryMgr.ScanFolder(path, true)
+// prefetchArtistImage fetches the artist image when a track starts and
+// pushes it to the UI through "player:artistImage"
+func (a *App) prefetchArtistImage(track *domain.Track) {
+	image, err := a.artistImages.Get(a.ctx, track.ArtistMBID, contextArtist(track), library.ArtistImageMedium)
+	if err != nil {
+		if !errors.Is(err, metadata.ErrNoArtistImage) {
+			logger.Debug("Failed to fetch artist image", logger.String("artist", track.Artist), logger.Error(err))
+		}
+		return
+	}
+	
+	// Skip if playback moved on while fetching
+	if current := a.player.GetCurrentTrack(); current == nil || current.ID != track.ID {
+		return
+	}
+	
+	result, err := artistImageToMap(image)
+	if err != nil {
+		logger.Warn("Failed to read artist image", logger.String("path", image.Path), logger.Error(err))
+		return
+	}
+	result["trackId"] = track.ID
+	runtime.EventsEmit(a.ctx, "player:artistImage", result)
+}
+
+// contextArtist prefers the track artist, falling back to the album artist
+func contextArtist(track *domain.Track) string {
+	if track.Artist != "" {
+		return track.Artist
+	}
+	return track.AlbumArtist
 }
 
 // Settings Methods
@@ -267,14 +2952,36 @@ func (a *App) GetSettings() map[string]interface{} {
 	return map[string]interface{}{
 		"audio": map[string]interface{}{
 			"volume":        a.config.Audio.Volume,
+			"maxVolumeDb":   a.config.Audio.MaxVolumeDB,
+			"volumeStepDb":  a.config.Audio.VolumeStepDB,
 			"crossfade":     a.config.Audio.CrossfadeDuration.Seconds(),
+			"crossfadeCurve": a.config.Audio.CrossfadeCurve,
 			"replayGain":    a.config.Audio.ReplayGain,
+			"replayGainMode": a.config.Audio.ReplayGainMode,
+			"replayGainScan": a.config.Audio.ReplayGainScan,
+			"replayGainWriteTags": a.config.Audio.ReplayGainWriteTags,
+			"volumeLeveling": a.config.Audio.VolumeLeveling,
 			"gapless":       a.config.Audio.GaplessPlayback,
 			"fadeOnPause":   a.config.Audio.FadeOnPause,
+			"speedMode":     a.config.Audio.SpeedMode,
+			"pauseOnDeviceLost": a.config.Audio.PauseOnDeviceLost,
+			"exclusiveMode":  a.config.Audio.ExclusiveMode,
+			"preampDb":       a.config.Audio.PreAmp,
+			"syncOffset":     a.config.Audio.SyncOffset.Seconds(),
+			"transitionSameAlbum":      a.config.Audio.Transitions.SameAlbum,
+			"transitionDifferentAlbum": a.config.Audio.Transitions.DifferentAlbum,
+			"transitionGap":            a.config.Audio.Transitions.Gap.Seconds(),
+			"pauseOnLock":    a.config.Audio.Idle.PauseOnLock,
+			"resumeOnUnlock": a.config.Audio.Idle.ResumeOnUnlock,
+			"stopAfterHours": a.config.Audio.Idle.StopAfter.Hours(),
 		},
 		"library": map[string]interface{}{
-			"watchFolders": a.config.Library.WatchFolders,
-			"autoScan":     a.config.Library.AutoScan,
+			"watchFolders":    a.config.Library.WatchFolders,
+			"autoScan":        a.config.Library.AutoScan,
+			"scanIntervalMinutes":   a.config.Library.ScanInterval.Minutes(),
+			"pauseScanWhilePlaying": a.config.Library.PauseScanWhilePlaying,
+			"duplicatePolicy": a.config.Library.DuplicatePolicy,
+			"writeRatingTags": a.config.Library.WriteRatingTags,
 		},
 		"ui": map[string]interface{}{
 			"theme":         a.config.App.Theme,
@@ -288,15 +2995,121 @@ func (a *App) GetSettings() map[string]interface{} {
 func (a *App) UpdateSettings(settings map[string]interface{}) error {
 	// Update configuration
 	if audio, ok := settings["audio"].(map[string]interface{}); ok {
+		if maxVolume, ok := audio["maxVolumeDb"].(float64); ok {
+			a.player.SetMaxVolumeDB(maxVolume)
+			a.config.Audio.MaxVolumeDB = maxVolume
+			a.config.Set("audio.max_volume_db", maxVolume)
+		}
+		if step, ok := audio["volumeStepDb"].(float64); ok && step > 0 {
+			a.config.Audio.VolumeStepDB = step
+			a.config.Set("audio.volume_step_db", step)
+		}
 		if volume, ok := audio["volume"].(float64); ok {
-			a.config.Audio.Volume = volume
-			a.player.SetVolume(volume)
+			if err := a.player.SetVolume(volume); err != nil {
+				return err
+			}
+			a.rememberVolume(volume)
 		}
-		if crossfade, ok := audio["crossfade"].(float64); ok {
+		if crossfade, ok := audio["crossfade"].(float64); ok && crossfade >= 0 {
 			a.config.Audio.CrossfadeDuration = time.Duration(crossfade * float64(time.Second))
+			a.config.Set("audio.crossfade_duration", a.config.Audio.CrossfadeDuration)
+		}
+		if curve, ok := audio["crossfadeCurve"].(string); ok {
+			if err := a.player.SetCrossfade(a.config.Audio.CrossfadeDuration, curve); err != nil {
+				return err
+			}
+			a.config.Audio.CrossfadeCurve = curve
+			a.config.Set("audio.crossfade_curve", curve)
+		}
+		a.player.SetCrossfade(a.config.Audio.CrossfadeDuration, a.config.Audio.CrossfadeCurve)
+		if gapless, ok := audio["gapless"].(bool); ok {
+			a.config.Audio.GaplessPlayback = gapless
+			a.config.Set("audio.gapless_playback", gapless)
+			a.player.SetGapless(gapless)
 		}
 		if replayGain, ok := audio["replayGain"].(bool); ok {
 			a.config.Audio.ReplayGain = replayGain
+			a.player.SetReplayGain(replayGain)
+		}
+		if mode, ok := audio["replayGainMode"].(string); ok {
+			if err := a.player.SetReplayGainMode(mode); err != nil {
+				return err
+			}
+			a.config.Audio.ReplayGainMode = mode
+			a.config.Set("audio.replay_gain_mode", mode)
+		}
+		if scan, ok := audio["replayGainScan"].(bool); ok {
+			a.config.Audio.ReplayGainScan = scan
+			a.config.Set("audio.replay_gain_scan", scan)
+		}
+		if writeTags, ok := audio["replayGainWriteTags"].(bool); ok {
+			a.config.Audio.ReplayGainWriteTags = writeTags
+			a.config.Set("audio.replay_gain_write_tags", writeTags)
+			a.gainScanner.SetWriteTags(writeTags)
+		}
+		if leveling, ok := audio["volumeLeveling"].(bool); ok {
+			a.config.Audio.VolumeLeveling = leveling
+			a.player.SetVolumeLeveling(leveling)
+		}
+		if mode, ok := audio["speedMode"].(string); ok {
+			if err := a.player.SetSpeedMode(mode); err != nil {
+				return fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+			}
+			a.config.Audio.SpeedMode = mode
+			a.config.Set("audio.speed_mode", mode)
+		}
+		if fade, ok := audio["fadeOnPause"].(bool); ok {
+			a.config.Audio.FadeOnPause = fade
+			a.config.Set("audio.fade_on_pause", fade)
+			a.player.SetFade(fade, a.config.Audio.FadeDuration)
+		}
+		if pause, ok := audio["pauseOnDeviceLost"].(bool); ok {
+			a.config.Audio.PauseOnDeviceLost = pause
+			a.config.Set("audio.pause_on_device_lost", pause)
+			a.player.SetPauseOnDeviceLost(pause)
+		}
+		if exclusive, ok := audio["exclusiveMode"].(bool); ok {
+			a.config.Audio.ExclusiveMode = exclusive
+			a.config.Set("audio.exclusive_mode", exclusive)
+			a.applyBypass()
+		}
+		if preamp, ok := audio["preampDb"].(float64); ok {
+			a.config.Audio.PreAmp = preamp
+			a.config.Set("audio.preamp", preamp)
+			a.player.SetPreamp(preamp)
+		}
+		if name, ok := audio["transitionSameAlbum"].(string); ok {
+			if err := a.setTransition("audio.transitions.same_album", &a.config.Audio.Transitions.SameAlbum, name); err != nil {
+				return err
+			}
+		}
+		if name, ok := audio["transitionDifferentAlbum"].(string); ok {
+			if err := a.setTransition("audio.transitions.different_album", &a.config.Audio.Transitions.DifferentAlbum, name); err != nil {
+				return err
+			}
+		}
+		if gap, ok := audio["transitionGap"].(float64); ok && gap >= 0 {
+			a.config.Audio.Transitions.Gap = time.Duration(gap * float64(time.Second))
+			a.config.Set("audio.transitions.gap", a.config.Audio.Transitions.Gap)
+		}
+		a.player.SetTransitionPolicy(a.transitionPolicy())
+		if offset, ok := audio["syncOffset"].(float64); ok {
+			a.config.Audio.SyncOffset = time.Duration(offset * float64(time.Second))
+			a.config.Set("audio.sync_offset", a.config.Audio.SyncOffset)
+			a.applySyncOffset()
+		}
+		if pause, ok := audio["pauseOnLock"].(bool); ok {
+			a.config.Audio.Idle.PauseOnLock = pause
+			a.config.Set("audio.idle.pause_on_lock", pause)
+		}
+		if resume, ok := audio["resumeOnUnlock"].(bool); ok {
+			a.config.Audio.Idle.ResumeOnUnlock = resume
+			a.config.Set("audio.idle.resume_on_unlock", resume)
+		}
+		if hours, ok := audio["stopAfterHours"].(float64); ok && hours >= 0 {
+			stopAfter := time.Duration(hours * float64(time.Hour))
+			a.config.Audio.Idle.StopAfter = stopAfter
+			a.config.Set("audio.idle.stop_after", stopAfter)
 		}
 	}
 	
@@ -306,43 +3119,153 @@ func (a *App) UpdateSettings(settings map[string]interface{}) error {
 
 // Helper methods
 
-func (a *App) handlePlayerEvent(event audio.PlayerEvent, data interface{}) {
-	eventData := map[string]interface{}{
-		"event": event,
-		"data":  data,
+func (a *App) tracksToMaps(tracks []*domain.Track) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(tracks))
+	for i, track := range tracks {
+		result[i] = a.trackToMap(track)
+	}
+	return result
+}
+
+func (a *App) trackToMap(track *domain.Track) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":           track.ID,
+		"title":        track.GetDisplayTitle(),
+		"artist":       track.GetDisplayArtist(),
+		"artistMbid":   track.ArtistMBID,
+		"album":        track.Album,
+		"trackNumber":  track.TrackNumber,
+		"discNumber":   track.DiscNumber,
+		"discSubtitle": track.DiscSubtitle,
+		"duration":     track.Duration.Seconds(),
+		"path":         track.FilePath,
+		"source":       string(track.GetSource().Kind),
+		"year":         track.Year,
+		"genre":        track.Genre,
+		"composer":     track.Composer,
+		"publisher":    track.Publisher,
+		"rating":       track.Rating,
+		"favorite":     track.Favorite,
+		"audiobook":    track.IsAudiobook,
+		"live":         track.IsLive,
+		"language":     track.Language,
+		"advisory":     string(track.Advisory),
+		"transient":    track.Transient,
+		"userTags":     track.UserTags,
+		"isValid":      track.IsValid,
+		"error":        track.Error,
+	}
+	
+	// Tracks without measured gain show the player's estimate, if it has one
+	rg := track.ReplayGain
+	if rg == nil || rg.Estimated || rg.TrackPeak == 0 {
+		rg = a.player.GainEstimate(track.ID)
+	}
+	if rg != nil {
+		result["replayGain"] = map[string]interface{}{
+			"trackGain": rg.TrackGain,
+			"trackPeak": rg.TrackPeak,
+			"albumGain": rg.AlbumGain,
+			"albumPeak": rg.AlbumPeak,
+			"estimated": rg.Estimated,
+		}
+	}
+	
+	return result
+}
+
+func markerToMap(marker *domain.TrackMarker) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       marker.ID,
+		"type":     string(marker.Type),
+		"label":    marker.Label,
+		"position": marker.Position.Seconds(),
+		"end":      marker.End.Seconds(),
+		"region":   marker.IsRegion(),
+	}
+}
+
+// artistImageToMap reads a stored artist image into a data URL, since the
+// frontend cannot load files from the cache directory
+func artistImageToMap(image *library.ArtistImage) (map[string]interface{}, error) {
+	data, err := os.ReadFile(image.Path)
+	if err != nil {
+		return nil, err
 	}
 	
-	switch event {
-	case audio.EventStateChanged:
-		runtime.EventsEmit(a.ctx, "player:stateChanged", data)
-	case audio.EventTrackChanged:
-		if track, ok := data.(*domain.Track); ok {
-			runtime.EventsEmit(a.ctx, "player:trackChanged", a.trackToMap(track))
+	return map[string]interface{}{
+		"artistId": image.ArtistID,
+		"size":     string(image.Size),
+		"stale":    image.Stale,
+		"dataUrl":  "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+func scanReportToMap(report *domain.ScanReport) map[string]interface{} {
+	albums := make([]map[string]interface{}, len(report.NewAlbums))
+	for i, album := range report.NewAlbums {
+		albums[i] = map[string]interface{}{
+			"title":  album.Title,
+			"artist": album.Artist,
+			"tracks": album.Tracks,
 		}
-	case audio.EventPositionChanged:
-		if pos, ok := data.(time.Duration); ok {
-			runtime.EventsEmit(a.ctx, "player:positionChanged", pos.Seconds())
+	}
+	
+	return map[string]interface{}{
+		"id":         report.ID,
+		"root":       report.Root,
+		"startedAt":  report.StartedAt,
+		"finishedAt": report.FinishedAt,
+		"added":      report.Added,
+		"updated":    report.Updated,
+		"removed":    report.Removed,
+		"moved":      report.Moved,
+		"failed":     report.Failed,
+		"cancelled":  report.Cancelled,
+		"newAlbums":  albums,
+	}
+}
+
+func scanPreviewToMap(preview *library.ScanPreview) map[string]interface{} {
+	counts := make(map[string]interface{}, len(preview.Counts))
+	for action, count := range preview.Counts {
+		counts[string(action)] = count
+	}
+	reasons := make(map[string]interface{}, len(preview.Reasons))
+	for reason, count := range preview.Reasons {
+		reasons[string(reason)] = count
+	}
+	
+	entries := make([]map[string]interface{}, len(preview.Entries))
+	for i, entry := range preview.Entries {
+		entries[i] = map[string]interface{}{
+			"path":   entry.Path,
+			"action": string(entry.Action),
+			"reason": string(entry.Reason),
 		}
-	case audio.EventVolumeChanged:
-		runtime.EventsEmit(a.ctx, "player:volumeChanged", data)
-	case audio.EventTrackFinished:
-		runtime.EventsEmit(a.ctx, "player:trackFinished", eventData)
-	case audio.EventError:
-		runtime.EventsEmit(a.ctx, "player:error", data)
+	}
+	
+	return map[string]interface{}{
+		"root":      preview.Root,
+		"counts":    counts,
+		"reasons":   reasons,
+		"entries":   entries,
+		"truncated": preview.Truncated,
+		"duration":  preview.Duration.Milliseconds(),
 	}
 }
 
-func (a *App) trackToMap(track *domain.Track) map[string]interface{} {
+func contextToMap(track *domain.Track, info *metadata.NowPlayingContext) map[string]interface{} {
 	return map[string]interface{}{
-		"id":       track.ID,
-		"title":    track.GetDisplayTitle(),
-		"artist":   track.GetDisplayArtist(),
-		"album":    track.Album,
-		"duration": track.Duration.Seconds(),
-		"path":     track.FilePath,
-		"year":     track.Year,
-		"genre":    track.Genre,
-		"rating":   track.Rating,
+		"trackId":        track.ID,
+		"artist":         info.Artist,
+		"album":          info.Album,
+		"artistBio":      info.ArtistBio,
+		"artistUrl":      info.ArtistURL,
+		"albumReview":    info.AlbumReview,
+		"similarArtists": info.SimilarArtists,
+		"tags":           info.Tags,
+		"source":         info.Source,
 	}
 }
 
@@ -356,6 +3279,9 @@ func (a *App) playlistToMap(playlist *domain.Playlist) map[string]interface{} {
 		"id":          playlist.ID,
 		"name":        playlist.Name,
 		"description": playlist.Description,
+		"type":        playlist.Type,
+		"generator":   playlist.Generator,
+		"rules":       playlist.Rules,
 		"trackCount":  playlist.TrackCount,
 		"duration":    playlist.Duration.Seconds(),
 		"tracks":      tracks,
@@ -365,11 +3291,13 @@ func (a *App) playlistToMap(playlist *domain.Playlist) map[string]interface{} {
 // LibraryManager manages the music library
 type LibraryManager struct {
 	trackRepo domain.TrackRepository
+	scanner   *library.Scanner
 }
 
 func NewLibraryManager(repo domain.TrackRepository) *LibraryManager {
 	return &LibraryManager{
 		trackRepo: repo,
+		scanner:   library.NewScanner(repo, nil),
 	}
 }
 
@@ -389,6 +3317,12 @@ func (l *LibraryManager) ImportTrack(path string) (*domain.Track, error) {
 	// Extract metadata
 	// TODO: Use decoder to extract metadata
 	
+	if checksum, err := library.ComputeChecksum(path); err == nil {
+		track.Checksum = checksum
+	} else {
+		logger.Warn("Failed to compute checksum", logger.String("path", path), logger.Error(err))
+	}
+	
 	// Save to database
 	if err := l.trackRepo.Create(track); err != nil {
 		return nil, err
@@ -397,7 +3331,10 @@ func (l *LibraryManager) ImportTrack(path string) (*domain.Track, error) {
 	return track, nil
 }
 
-func (l *LibraryManager) ScanFolder(path string, recursive bool) error {
-	// TODO: Implement folder scanning
-	return nil
+func (l *LibraryManager) ScanWatchFolder(folder *domain.WatchFolder) (*library.ScanResult, error) {
+	return l.scanner.ScanWatchFolder(context.Background(), folder)
+}
+
+func (l *LibraryManager) PreviewFolder(ctx context.Context, folder *domain.WatchFolder) (*library.ScanPreview, error) {
+	return l.scanner.PreviewWatchFolder(ctx, folder)
 }
\ No newline at end of file
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/library"
+)
+
+// GetArtists returns the artists the library's tracks are listed under,
+// by album artist where tracks have one, ordered by name. It is the first
+// level of the artist, album and track browser.
+func (a *App) GetArtists() ([]map[string]interface{}, error) {
+	artists, err := a.trackRepo.FindArtists()
+	if err != nil {
+		return nil, err
+	}
+
+	maps := make([]map[string]interface{}, len(artists))
+	for i, artist := range artists {
+		// A fetched artist image beats the art of their newest album
+		if image := a.artistImages.Cached(artist.ArtistMBID, artist.Name, library.ArtistImageThumb); image != nil {
+			artist.ArtPath = image.Path
+		}
+		maps[i] = map[string]interface{}{
+			"name":       artist.Name,
+			"sortName":   artist.SortName,
+			"artistMbid": artist.ArtistMBID,
+			"albumCount": artist.AlbumCount,
+			"trackCount": artist.TrackCount,
+			"artPath":    artist.ArtPath,
+		}
+	}
+	return maps, nil
+}
+
+// GetAlbums returns the albums listed under an artist, oldest first, or
+// every album by artist and title when artist is empty. Each album's key
+// is what GetAlbumTracks and EnqueueAlbumNext take.
+func (a *App) GetAlbums(artist string) ([]map[string]interface{}, error) {
+	albums, err := a.trackRepo.FindAlbums(artist)
+	if err != nil {
+		return nil, err
+	}
+	if artist != "" {
+		sortAlbumsByYear(albums)
+	}
+
+	maps := make([]map[string]interface{}, len(albums))
+	for i, album := range albums {
+		maps[i] = albumToMap(album)
+	}
+	return maps, nil
+}
+
+// GetAlbumTracks returns an album's tracks, by its key, in disc and track
+// order and in the preferred format when the album exists in several
+func (a *App) GetAlbumTracks(albumKey string) ([]map[string]interface{}, error) {
+	tracks, err := a.findAlbumByKey(albumKey)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := domain.GroupAlbums(tracks)
+	domain.PreferVersions(groups, a.formatPreference())
+	var ordered []*domain.Track
+	for _, group := range groups {
+		ordered = append(ordered, group.Tracks()...)
+	}
+	return a.tracksToMaps(ordered), nil
+}
+
+// sortAlbumsByYear orders an artist's albums oldest first, with undated
+// ones last, as SortByRelease orders album groups
+func sortAlbumsByYear(albums []*domain.Album) {
+	sort.SliceStable(albums, func(i, j int) bool {
+		a, b := albums[i].Year, albums[j].Year
+		if a == 0 || b == 0 {
+			return a != 0 && b == 0
+		}
+		return a < b
+	})
+}
+
+// albumToMap describes an album for the browser, without its tracks
+func albumToMap(album *domain.Album) map[string]interface{} {
+	return map[string]interface{}{
+		"key":         album.Key,
+		"title":       album.Title,
+		"albumArtist": album.AlbumArtist,
+		"sortTitle":   album.SortTitle,
+		"sortArtist":  album.SortArtist,
+		"year":        album.Year,
+		"genre":       album.Genre,
+		"trackCount":  album.TrackCount,
+		"discCount":   album.DiscCount,
+		"duration":    album.Duration.Seconds(),
+		"artPath":     album.ArtPath,
+		"dateAdded":   album.DateAdded,
+		"live":        album.IsLive,
+	}
+}
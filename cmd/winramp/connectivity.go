@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/winramp/winramp/internal/connectivity"
+)
+
+// GetConnectivity returns whether network features are available, whether
+// offline mode is on, and whether the internet could be reached
+func (a *App) GetConnectivity() connectivity.Status {
+	return a.connectivity.Status()
+}
+
+// SetOfflineMode turns offline mode on or off. While on, no internet
+// requests are made: now playing context and artist images come from the
+// cache, and lookups for what it lacks wait until offline mode ends. Local
+// network features such as the remote API are unaffected.
+func (a *App) SetOfflineMode(enabled bool) (connectivity.Status, error) {
+	a.connectivity.SetOfflineMode(enabled)
+
+	a.config.Network.OfflineMode = enabled
+	a.config.Set("network.offline_mode", enabled)
+	if err := a.config.Save(); err != nil {
+		return a.connectivity.Status(), err
+	}
+	return a.connectivity.Status(), nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/library"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// detailsPlays is how many of a track's latest plays its details list
+const detailsPlays = 50
+
+// GetTrackDetails returns everything known about a track in one call, for
+// a properties dialog: its tags, technical details, ReplayGain, checksum,
+// play history, the playlists it is on and its file's attributes. Parts
+// that can't be read are left out rather than failing the call.
+func (a *App) GetTrackDetails(id string) (map[string]interface{}, error) {
+	track, err := a.trackRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]interface{}{
+		"track": a.trackToMap(track),
+		"tags": map[string]interface{}{
+			"title":        track.Title,
+			"artist":       track.Artist,
+			"artistMbid":   track.ArtistMBID,
+			"album":        track.Album,
+			"albumArtist":  track.AlbumArtist,
+			"genre":        track.Genre,
+			"year":         track.Year,
+			"trackNumber":  track.TrackNumber,
+			"discNumber":   track.DiscNumber,
+			"discSubtitle": track.DiscSubtitle,
+			"composer":     track.Composer,
+			"publisher":    track.Publisher,
+			"bpm":          track.BPM,
+			"comment":      track.Comment,
+			"lyrics":       track.Lyrics,
+		},
+		"technical": map[string]interface{}{
+			"format":     string(track.Format),
+			"duration":   track.Duration.Seconds(),
+			"bitrate":    track.Bitrate,
+			"sampleRate": track.SampleRate,
+			"channels":   track.Channels,
+			"bitDepth":   track.BitDepth,
+			"fileSize":   track.FileSize,
+			"source":     string(track.GetSource().Kind),
+		},
+		"integrity": map[string]interface{}{
+			"checksum":       track.Checksum,
+			"hasFingerprint": track.Fingerprint != "",
+			"isValid":        track.IsValid,
+			"error":          track.Error,
+		},
+		"library": map[string]interface{}{
+			"dateAdded": track.DateAdded,
+			"updatedAt": track.UpdatedAt,
+			"albumArt":  track.AlbumArtPath,
+		},
+	}
+
+	history := map[string]interface{}{
+		"playCount":  track.PlayCount,
+		"lastPlayed": track.LastPlayed,
+	}
+	if plays, err := a.historyRepo.FindByTrack(id, detailsPlays); err == nil {
+		times := make([]time.Time, len(plays))
+		for i, play := range plays {
+			times[i] = play.PlayedAt
+		}
+		history["plays"] = times
+	} else {
+		logger.Warn("Failed to load track play history", logger.String("id", id), logger.Error(err))
+	}
+	details["history"] = history
+
+	playlists := []map[string]interface{}{}
+	for _, pl := range a.playlistMgr.FindContaining(id) {
+		playlists = append(playlists, map[string]interface{}{
+			"id":   pl.ID,
+			"name": pl.Name,
+		})
+	}
+	details["playlists"] = playlists
+
+	if tags, err := a.userTagRepo.FindByTrack(id); err == nil {
+		userTags := make([]map[string]interface{}, len(tags))
+		for i, tag := range tags {
+			userTags[i] = userTagToMap(tag)
+		}
+		details["userTags"] = userTags
+	}
+	if markers, err := a.markerRepo.FindByTrack(id); err == nil {
+		result := make([]map[string]interface{}, len(markers))
+		for i, marker := range markers {
+			result[i] = markerToMap(marker)
+		}
+		details["markers"] = result
+	}
+
+	if track.GetSource().Kind == domain.SourceFile {
+		if attrs, err := library.StatFile(track.FilePath); err == nil {
+			details["file"] = map[string]interface{}{
+				"size":     attrs.Size,
+				"modified": attrs.Modified,
+				"readOnly": attrs.ReadOnly,
+				"hidden":   attrs.Hidden,
+				"remote":   attrs.Remote,
+			}
+		} else {
+			details["fileError"] = err.Error()
+		}
+	}
+
+	return details, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/winramp/winramp/internal/audio"
+	"github.com/winramp/winramp/internal/audio/output"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// onDevicesChanged tells the frontend output devices came or went, and goes
+// back to the chosen device when it is plugged in again
+func (a *App) onDevicesChanged(change *audio.DeviceChange) {
+	chosen := a.config.Audio.OutputDevice
+	for _, device := range change.Added {
+		if device.ID != chosen {
+			continue
+		}
+		if err := a.player.SetOutputDevice(chosen); err != nil {
+			logger.Warn("Failed to switch back to output device",
+				logger.String("device", device.Name), logger.Error(err))
+			break
+		}
+		a.applySyncOffset()
+		logger.Info("Switched back to output device", logger.String("device", device.Name))
+	}
+
+	payload := map[string]interface{}{
+		"added":   deviceNames(change.Added),
+		"removed": deviceNames(change.Removed),
+		"paused":  change.Paused,
+	}
+	if change.Lost != nil {
+		payload["lost"] = change.Lost.Name
+	}
+	runtime.EventsEmit(a.ctx, audio.TopicDevicesChanged.Name(), payload)
+}
+
+func deviceNames(devices []*output.Device) []string {
+	names := make([]string, len(devices))
+	for i, device := range devices {
+		names[i] = device.Name
+	}
+	return names
+}
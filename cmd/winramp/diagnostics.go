@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/winramp/winramp/internal/audio"
+	"github.com/winramp/winramp/internal/audio/diagnostics"
+	"github.com/winramp/winramp/internal/audio/output"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// RunAudioDiagnostics plays test signals on an output device, "" meaning
+// the configured one. tests picks from "channels", "sweep" and "silence",
+// defaulting to all. Playback pauses while the signals play and resumes
+// afterwards.
+func (a *App) RunAudioDiagnostics(deviceID string, tests []string) (*diagnostics.Report, error) {
+	if deviceID == "" {
+		deviceID = a.config.Audio.OutputDevice
+	}
+
+	opts := diagnostics.Options{}
+	for _, test := range tests {
+		opts.Tests = append(opts.Tests, diagnostics.Test(test))
+	}
+
+	if a.player.GetState() == audio.StatePlaying {
+		if err := a.player.Pause(); err != nil {
+			return nil, err
+		}
+		defer a.player.Play()
+	}
+
+	return diagnostics.Run(a.ctx, a.player.DeviceManager(), deviceID, opts)
+}
+
+// runDiagnostics runs the audio diagnostics from the command line, printing
+// the report, and returns the exit code
+func runDiagnostics(deviceID string) int {
+	report, err := diagnostics.Run(context.Background(), output.NewOtoDeviceManager(), deviceID, diagnostics.Options{})
+	if err != nil {
+		logger.ErrorLog("Audio diagnostics failed", logger.Error(err))
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	report.Write(os.Stdout)
+	if !report.Passed() {
+		return 1
+	}
+	return 0
+}
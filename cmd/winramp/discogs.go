@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/metadata"
+)
+
+// discogsTokenFile holds the connected Discogs account's OAuth token, in
+// the data directory
+const discogsTokenFile = "discogs_token.json"
+
+func (a *App) newDiscogsClient() *metadata.DiscogsClient {
+	network := a.config.Network
+	client := metadata.NewDiscogsClient(
+		network.DiscogsKey,
+		network.DiscogsSecret,
+		network.DiscogsToken,
+		filepath.Join(a.config.App.DataDir, discogsTokenFile),
+		network.Timeout,
+	)
+	client.SetConnectivity(a.connectivity)
+	return client
+}
+
+// GetDiscogsStatus reports whether Discogs can be searched and which
+// account, if any, is connected
+func (a *App) GetDiscogsStatus() metadata.DiscogsStatus {
+	return a.discogs.Status()
+}
+
+// BeginDiscogsAuthorization starts connecting a Discogs account and
+// returns the page where the user approves it. Discogs then shows them a
+// code to pass to CompleteDiscogsAuthorization.
+func (a *App) BeginDiscogsAuthorization() (string, error) {
+	return a.discogs.BeginAuthorization(a.ctx, "oob")
+}
+
+// CompleteDiscogsAuthorization finishes connecting a Discogs account with
+// the code the user was shown, and returns the account's username
+func (a *App) CompleteDiscogsAuthorization(code string) (string, error) {
+	username, err := a.discogs.CompleteAuthorization(a.ctx, code)
+	if err != nil {
+		return "", err
+	}
+	logger.Info("Connected Discogs account", logger.String("username", username))
+	return username, nil
+}
+
+// DisconnectDiscogs forgets the connected Discogs account
+func (a *App) DisconnectDiscogs() error {
+	return a.discogs.Disconnect()
+}
+
+// GetAlbumRelease returns the release details fetched for an album, or
+// nil if none have been
+func (a *App) GetAlbumRelease(albumKey string) (*domain.AlbumRelease, error) {
+	release, err := a.releases.FindByAlbumKey(albumKey)
+	if errors.Is(err, domain.ErrReleaseNotFound) {
+		return nil, nil
+	}
+	return release, err
+}
+
+// FetchAlbumFromDiscogs looks an album up on Discogs, saves its release
+// details (label, catalog number, credits) and fills in the album's
+// tracks' missing fields from it, saving them to the library but not to
+// the files. It returns the release and how many tracks changed.
+func (a *App) FetchAlbumFromDiscogs(albumKey string) (map[string]interface{}, error) {
+	tracks, err := a.findAlbumByKey(albumKey)
+	if err != nil {
+		return nil, err
+	}
+
+	group := domain.GroupAlbums(tracks)[0]
+	release, err := a.discogs.FindRelease(a.ctx, albumKey, group.Artist, group.Title, group.Year)
+	if err != nil {
+		if errors.Is(err, metadata.ErrNoMatch) {
+			return nil, fmt.Errorf("%w: %q is not on Discogs", domain.ErrReleaseNotFound, group.Title)
+		}
+		return nil, err
+	}
+	if err := a.releases.Save(release); err != nil {
+		return nil, err
+	}
+
+	// The release was just found, so the provider doesn't search again
+	chain := metadata.NewChain(metadata.MergeFill, a.discogs.Provider())
+	changes, err := a.enrichTracks(chain, tracks)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"release": release,
+		"updated": len(changes),
+	}, nil
+}
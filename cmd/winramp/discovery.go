@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/winramp/winramp/internal/discovery"
+	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/remote"
+)
+
+// startAdvertising announces the running remote API on the local network,
+// so other WinRamp instances can find it
+func (a *App) startAdvertising() {
+	if !a.config.Network.Discovery {
+		return
+	}
+
+	_, portText, err := net.SplitHostPort(a.remote.Addr())
+	if err != nil {
+		return
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		return
+	}
+
+	text := map[string]string{
+		"api":     remote.APIVersion,
+		"version": Version,
+		"tls":     "0",
+	}
+	if fingerprint := a.remote.Fingerprint(); fingerprint != "" {
+		text["tls"] = "1"
+		text["fp"] = fingerprint
+	}
+
+	a.discoveryMu.Lock()
+	defer a.discoveryMu.Unlock()
+
+	if a.advertiser != nil {
+		return
+	}
+	advertiser := discovery.NewAdvertiser("", port, text)
+	if err := advertiser.Start(a.ctx); err != nil {
+		logger.Warn("Failed to advertise on the local network", logger.Error(err))
+		return
+	}
+	a.advertiser = advertiser
+}
+
+// stopAdvertising withdraws the announcement made by startAdvertising
+func (a *App) stopAdvertising() {
+	a.discoveryMu.Lock()
+	advertiser := a.advertiser
+	a.advertiser = nil
+	a.discoveryMu.Unlock()
+
+	if advertiser != nil {
+		advertiser.Stop()
+	}
+}
+
+// DiscoverPeers looks for other WinRamp instances on the local network.
+// Only instances with their remote API running can be found.
+func (a *App) DiscoverPeers() ([]*discovery.Peer, error) {
+	self := ""
+	a.discoveryMu.Lock()
+	if a.advertiser != nil {
+		self = a.advertiser.Instance()
+	}
+	a.discoveryMu.Unlock()
+
+	return discovery.Browse(a.ctx, discovery.DefaultBrowseTimeout, self)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/library"
+	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/metadata"
+)
+
+// newEnricher builds the metadata provider chain the settings ask for.
+// Unknown provider names are skipped.
+func (a *App) newEnricher() *metadata.Chain {
+	settings := a.config.Library.Enrichment
+	strategy, err := metadata.ParseMergeStrategy(settings.Merge)
+	if err != nil {
+		logger.Warn("Invalid metadata merge strategy, filling missing fields", logger.String("merge", settings.Merge))
+	}
+
+	patterns, err := a.filenamePatterns(nil)
+	if err != nil {
+		logger.Warn("Invalid filename pattern, reading no fields from paths", logger.Error(err))
+	}
+	available := map[string]metadata.Provider{
+		"tags":        library.NewTagProvider(),
+		"filename":    library.NewFilenameProvider(patterns),
+		"musicbrainz": a.contextSvc.MusicBrainzProvider(),
+		"discogs":     a.discogs.Provider(),
+		"acoustid":    a.acoustID.Provider(a.fingerprinter.ForTrack),
+	}
+	var providers []metadata.Provider
+	for _, name := range settings.Providers {
+		provider, ok := available[name]
+		if !ok {
+			logger.Warn("Unknown metadata provider", logger.String("provider", name))
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return metadata.NewChain(strategy, providers...)
+}
+
+// EnrichTracks fills in the tracks' missing metadata from the configured
+// providers, saving what they find to the library but not to the files.
+// It returns how many tracks changed and, by track ID, which fields did
+// and the provider each came from.
+func (a *App) EnrichTracks(ids []string) (map[string]interface{}, error) {
+	tracks, err := a.resolveTracks(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := a.enrichTracks(a.enricher, tracks)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"updated":   len(changes),
+		"changes":   changes,
+		"providers": a.enricher.Providers(),
+	}, nil
+}
+
+// enrichTracks fills in tracks' metadata from a chain and saves those that
+// change, returning by track ID which fields did and where each came from
+func (a *App) enrichTracks(chain *metadata.Chain, tracks []*domain.Track) (map[string]map[string]string, error) {
+	changes := make(map[string]map[string]string)
+	for _, track := range tracks {
+		changed, err := chain.Enrich(a.ctx, track)
+		if err != nil {
+			return nil, err
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		if a.normalizer != nil {
+			a.normalizer.Apply(track)
+		}
+		track.UpdatedAt = time.Now()
+		if err := a.trackRepo.UpdateTags(track); err != nil {
+			logger.Warn("Failed to save enriched metadata", logger.String("id", track.ID), logger.Error(err))
+			continue
+		}
+		a.suggestions.Update(track)
+		changes[track.ID] = changed
+		runtime.EventsEmit(a.ctx, "library:trackUpdated", a.trackToMap(track))
+	}
+
+	if len(changes) > 0 {
+		a.playlistMgr.LibraryChanged()
+	}
+	return changes, nil
+}
+
+// filenamePatterns compiles filename patterns, or the configured ones
+// when none are given
+func (a *App) filenamePatterns(patterns []string) ([]*library.FilenamePattern, error) {
+	if len(patterns) == 0 {
+		patterns = a.config.Library.Enrichment.FilenamePatterns
+	}
+	return library.ParseFilenamePatterns(patterns)
+}
+
+// PreviewFilenameMetadata shows what reading tracks' paths with filename
+// patterns, such as "{artist}/{album}/{track} - {title}", would fill in,
+// without changing anything. Patterns are tried in order; none uses the
+// configured ones. Only tracks that would change are listed, each with
+// its path and the fields' current and new values.
+func (a *App) PreviewFilenameMetadata(ids []string, patterns []string) ([]map[string]interface{}, error) {
+	parsed, err := a.filenamePatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	tracks, err := a.resolveTracks(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := metadata.NewChain(metadata.MergeFill, library.NewFilenameProvider(parsed))
+	preview := make([]map[string]interface{}, 0)
+	for _, track := range tracks {
+		enriched := *track
+		changed, err := chain.Enrich(a.ctx, &enriched)
+		if err != nil {
+			return nil, err
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		before, after := metadata.FieldsOf(track), metadata.FieldsOf(&enriched)
+		fields := make(map[string]interface{}, len(changed))
+		for name := range changed {
+			fields[name] = map[string]interface{}{
+				"from": before.Value(name),
+				"to":   after.Value(name),
+			}
+		}
+		preview = append(preview, map[string]interface{}{
+			"id":     track.ID,
+			"path":   track.FilePath,
+			"fields": fields,
+		})
+	}
+	return preview, nil
+}
+
+// ApplyFilenameMetadata fills in tracks' missing fields from their paths,
+// as PreviewFilenameMetadata shows, saving them to the library but not to
+// the files. It returns how many tracks changed and which fields did.
+func (a *App) ApplyFilenameMetadata(ids []string, patterns []string) (map[string]interface{}, error) {
+	parsed, err := a.filenamePatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	tracks, err := a.resolveTracks(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := metadata.NewChain(metadata.MergeFill, library.NewFilenameProvider(parsed))
+	changes, err := a.enrichTracks(chain, tracks)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"updated": len(changes),
+		"changes": changes,
+	}, nil
+}
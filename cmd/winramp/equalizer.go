@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/winramp/winramp/internal/audio"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// customPreset names equalizer settings that don't match a preset
+const customPreset = "custom"
+
+// applyEffects sets up the player's effect chain from the config
+func (a *App) applyEffects() {
+	a.player.SetPreamp(a.config.Audio.PreAmp)
+
+	eq := a.player.Equalizer()
+	eq.SetAllBands(a.config.Audio.Equalizer.Bands)
+	eq.SetEnabled(a.config.Audio.Equalizer.Enabled)
+
+	if order := a.config.Audio.Effects.Order; len(order) > 0 {
+		if err := a.player.Effects().SetOrder(order); err != nil {
+			logger.Warn("Invalid effect order, using the default", logger.Error(err))
+		}
+	}
+	a.applyBypass()
+}
+
+// applyBypass bypasses the effects the config lists. In exclusive mode the
+// limiter is always bypassed so the output is bit-perfect.
+func (a *App) applyBypass() {
+	for _, name := range a.player.Effects().Names() {
+		if name == audio.EffectEqualizer {
+			continue // Switched by its own setting
+		}
+		enabled := !slices.Contains(a.config.Audio.Effects.Bypass, name)
+		if name == audio.EffectLimiter && a.config.Audio.ExclusiveMode {
+			enabled = false
+		}
+		a.player.Effects().SetEffectEnabled(name, enabled)
+	}
+}
+
+// GetEqualizer returns the equalizer's state: whether it is on, the preset,
+// each band's gain and center frequency, and the presets to choose from
+func (a *App) GetEqualizer() map[string]interface{} {
+	eq := a.player.Equalizer()
+	bands := eq.GetAllBands()
+	frequencies := eq.Frequencies()
+
+	return map[string]interface{}{
+		"enabled":     eq.IsEnabled(),
+		"preset":      a.config.Audio.Equalizer.Preset,
+		"bands":       bands[:],
+		"frequencies": frequencies[:],
+		"presets":     eq.GetPresets(),
+	}
+}
+
+// SetEqualizerEnabled switches the equalizer on or off
+func (a *App) SetEqualizerEnabled(enabled bool) error {
+	a.player.Equalizer().SetEnabled(enabled)
+	a.config.Audio.Equalizer.Enabled = enabled
+	a.config.Set("audio.equalizer.enabled", enabled)
+	return a.config.Save()
+}
+
+// SetEqualizerBand sets one band's gain in dB, from -12 to +12. The
+// settings no longer match a preset afterwards.
+func (a *App) SetEqualizerBand(band int, gain float64) error {
+	if err := a.player.Equalizer().SetBandGain(band, gain); err != nil {
+		return fmt.Errorf("%w: no equalizer band %d", domain.ErrInvalidInput, band)
+	}
+	return a.saveEqualizer(customPreset)
+}
+
+// SetEqualizerBands sets every band's gain in dB at once
+func (a *App) SetEqualizerBands(gains []float64) error {
+	var bands [10]float64
+	if len(gains) != len(bands) {
+		return fmt.Errorf("%w: expected %d equalizer bands, got %d", domain.ErrInvalidInput, len(bands), len(gains))
+	}
+	copy(bands[:], gains)
+	a.player.Equalizer().SetAllBands(bands)
+	return a.saveEqualizer(customPreset)
+}
+
+// LoadEqualizerPreset sets the bands from a named preset
+func (a *App) LoadEqualizerPreset(preset string) error {
+	eq := a.player.Equalizer()
+	if !slices.Contains(eq.GetPresets(), preset) {
+		return fmt.Errorf("%w: unknown equalizer preset %q", domain.ErrInvalidInput, preset)
+	}
+	eq.LoadPreset(preset)
+	return a.saveEqualizer(preset)
+}
+
+// saveEqualizer stores the equalizer's bands under a preset name
+func (a *App) saveEqualizer(preset string) error {
+	a.config.Audio.Equalizer.Bands = a.player.Equalizer().GetAllBands()
+	a.config.Audio.Equalizer.Preset = preset
+	a.config.Set("audio.equalizer.bands", a.config.Audio.Equalizer.Bands)
+	a.config.Set("audio.equalizer.preset", preset)
+	return a.config.Save()
+}
+
+// GetEffects returns the effects playback runs through, in order, and
+// whether each is enabled
+func (a *App) GetEffects() []map[string]interface{} {
+	chain := a.player.Effects()
+	names := chain.Names()
+
+	effects := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		effect, err := chain.Effect(name)
+		if err != nil {
+			continue
+		}
+		effects = append(effects, map[string]interface{}{
+			"name":    name,
+			"enabled": effect.IsEnabled(),
+		})
+	}
+	return effects
+}
+
+// SetEffectOrder reorders the effects by name; any left out follow in
+// their current order
+func (a *App) SetEffectOrder(names []string) error {
+	if err := a.player.Effects().SetOrder(names); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+	a.config.Audio.Effects.Order = a.player.Effects().Names()
+	a.config.Set("audio.effects.order", a.config.Audio.Effects.Order)
+	return a.config.Save()
+}
+
+// SetEffectEnabled bypasses an effect, or puts it back in the chain
+func (a *App) SetEffectEnabled(name string, enabled bool) error {
+	if _, err := a.player.Effects().Effect(name); err != nil {
+		return fmt.Errorf("%w: unknown effect %q", domain.ErrInvalidInput, name)
+	}
+	if name == audio.EffectEqualizer {
+		return a.SetEqualizerEnabled(enabled)
+	}
+
+	bypass := make([]string, 0, len(a.config.Audio.Effects.Bypass)+1)
+	for _, bypassed := range a.config.Audio.Effects.Bypass {
+		if bypassed != name {
+			bypass = append(bypass, bypassed)
+		}
+	}
+	if !enabled {
+		bypass = append(bypass, name)
+	}
+
+	a.config.Audio.Effects.Bypass = bypass
+	a.config.Set("audio.effects.bypass", bypass)
+	a.applyBypass()
+	return a.config.Save()
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/winramp/winramp/internal/audio"
+	"github.com/winramp/winramp/internal/connectivity"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
+	"github.com/winramp/winramp/internal/library"
+	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/playlist"
+	"github.com/winramp/winramp/internal/remote"
+)
+
+// subscribeEvents reacts to backend events and forwards them to the
+// frontend under their topic names
+func (a *App) subscribeEvents() {
+	forward(a, audio.TopicStateChanged)
+	forward(a, audio.TopicVolumeChanged)
+	events.Subscribe(a.bus, audio.TopicTrackChanged, a.onTrackChanged)
+	events.Subscribe(a.bus, audio.TopicTrackChanged, a.onStreamTrackChanged)
+	events.Subscribe(a.bus, audio.TopicStateChanged, a.onStreamStateChanged)
+	events.Subscribe(a.bus, audio.TopicTrackChanged, a.onEpisodeTrackChanged)
+	events.Subscribe(a.bus, audio.TopicPositionChanged, a.onEpisodePosition)
+	events.Subscribe(a.bus, audio.TopicStateChanged, a.onEpisodeStateChanged)
+	events.Subscribe(a.bus, audio.TopicTrackChanged, a.onHeadroomTrackChanged)
+	events.Subscribe(a.bus, audio.TopicTrackChanged, a.onLyricsTrackChanged)
+	events.Subscribe(a.bus, audio.TopicPositionChanged, a.onLyricsPosition)
+	events.Subscribe(a.bus, audio.TopicTrackChanged, a.onHookTrackChanged)
+	events.Subscribe(a.bus, audio.TopicStateChanged, a.onHookStateChanged)
+	events.Subscribe(a.bus, audio.TopicTrackChanged, func(*domain.Track) { a.scheduleSessionSave() })
+	events.Subscribe(a.bus, audio.TopicStateChanged, func(audio.PlayerState) { a.scheduleSessionSave() })
+	events.Subscribe(a.bus, audio.TopicPositionChanged, func(position time.Duration) {
+		runtime.EventsEmit(a.ctx, audio.TopicPositionChanged.Name(), position.Seconds())
+	})
+	events.Subscribe(a.bus, audio.TopicTrackFinished, func(track *domain.Track) {
+		runtime.EventsEmit(a.ctx, audio.TopicTrackFinished.Name(), map[string]interface{}{
+			"event": audio.EventTrackFinished,
+			"data":  track,
+		})
+	})
+	events.Subscribe(a.bus, audio.TopicTrackEnding, a.onTrackEnding)
+	events.Subscribe(a.bus, audio.TopicError, a.onPlayerError)
+	events.Subscribe(a.bus, audio.TopicOutputWarning, a.onOutputWarning)
+	events.Subscribe(a.bus, audio.TopicDevicesChanged, a.onDevicesChanged)
+	events.Subscribe(a.bus, audio.TopicStreamMetadata, func(metadata *audio.StreamMetadata) {
+		runtime.EventsEmit(a.ctx, audio.TopicStreamMetadata.Name(), map[string]interface{}{
+			"trackId": metadata.Track.ID,
+			"station": metadata.Station,
+			"title":   metadata.Title,
+			"artist":  metadata.Artist,
+			"song":    metadata.Song,
+			"url":     metadata.URL,
+		})
+	})
+	events.Subscribe(a.bus, audio.TopicClipping, func(report *audio.ClipReport) {
+		payload := map[string]interface{}{
+			"clipped": report.Clipped,
+			"peakDb":  report.PeakDB,
+			"gainDb":  report.GainDB,
+		}
+		if report.Track != nil {
+			payload["trackId"] = report.Track.ID
+		}
+		runtime.EventsEmit(a.ctx, audio.TopicClipping.Name(), payload)
+	})
+	events.Subscribe(a.bus, audio.TopicBuffering, func(progress *audio.BufferProgress) {
+		runtime.EventsEmit(a.ctx, audio.TopicBuffering.Name(), map[string]interface{}{
+			"trackId": progress.Track.ID,
+			"percent": progress.Percent,
+		})
+	})
+
+	forward(a, library.TopicScanStarted)
+	forward(a, library.TopicScanProgress)
+	events.Subscribe(a.bus, library.TopicScanFinished, func(result *library.ScanResult) {
+		a.afterScan(result)
+		if result.Report != nil {
+			runtime.EventsEmit(a.ctx, library.TopicScanFinished.Name(), scanReportToMap(result.Report))
+		}
+	})
+
+	forward(a, library.TopicGainProgress)
+	events.Subscribe(a.bus, library.TopicGainFinished, func(result *library.GainScanResult) {
+		runtime.EventsEmit(a.ctx, library.TopicGainFinished.Name(), map[string]interface{}{
+			"analysed":    result.Analysed,
+			"skipped":     result.Skipped,
+			"tagsWritten": result.TagsWritten,
+			"failed":      result.Failed,
+			"duration":    result.Duration.Seconds(),
+		})
+	})
+	forward(a, library.TopicIdentifyProgress)
+	events.Subscribe(a.bus, library.TopicIdentifyFinished, func(result *library.IdentifyResult) {
+		a.onIdentifyFinished(result)
+		runtime.EventsEmit(a.ctx, library.TopicIdentifyFinished.Name(), map[string]interface{}{
+			"identified": result.Identified,
+			"unmatched":  result.Unmatched,
+			"submitted":  result.Submitted,
+			"skipped":    result.Skipped,
+			"changes":    result.Changes,
+			"failed":     result.Failed,
+			"duration":   result.Duration.Seconds(),
+		})
+	})
+
+	forward(a, playlist.TopicPlaylistChanged)
+	forward(a, playlist.TopicPlaylistDeleted)
+
+	forward(a, remote.TopicClientPaired)
+	forward(a, connectivity.TopicChanged)
+}
+
+// forward emits a topic's events to the frontend unchanged
+func forward[T any](a *App, topic events.Topic[T]) {
+	events.Subscribe(a.bus, topic, func(payload T) {
+		runtime.EventsEmit(a.ctx, topic.Name(), payload)
+	})
+}
+
+func (a *App) onTrackChanged(track *domain.Track) {
+	runtime.EventsEmit(a.ctx, audio.TopicTrackChanged.Name(), a.trackToMap(track))
+
+	// Gapless transitions move the player on by itself; the queue follows
+	// and the next track is prepared
+	if a.playlistMgr.FollowTrack(track) {
+		a.transportChanged()
+		a.emitQueueChanged()
+	}
+
+	// Transient entries are not in the library, so have no history
+	if !track.Transient {
+		if err := a.historyRepo.Record(domain.NewPlayHistoryEntry(track.ID)); err != nil {
+			logger.Warn("Failed to record play history", logger.String("id", track.ID), logger.Error(err))
+		}
+	}
+	if a.config.Network.FetchContext {
+		go a.prefetchNowPlayingContext(track)
+		go a.prefetchArtistImage(track)
+
+		// Offline only the cache can answer; fetch the rest once back online
+		if !a.connectivity.Online() {
+			a.connectivity.WhenOnline("nowPlaying", func() {
+				a.prefetchNowPlayingContext(track)
+				a.prefetchArtistImage(track)
+			})
+		}
+	}
+}
+
+func (a *App) onTrackEnding(ending *audio.TrackEnding) {
+	payload := map[string]interface{}{
+		"trackId":   ending.Track.ID,
+		"remaining": ending.Remaining.Seconds(),
+	}
+	next := ending.Next
+	if next == nil {
+		next = a.playlistMgr.PeekNextTrack()
+	}
+	if next != nil {
+		payload["nextTrack"] = a.trackToMap(next)
+		payload["transition"] = string(ending.Transition)
+	}
+	runtime.EventsEmit(a.ctx, audio.TopicTrackEnding.Name(), payload)
+}
+
+func (a *App) onPlayerError(trackErr *audio.TrackError) {
+	if err := a.problems.Report(trackErr.Track, trackErr.Err); err != nil {
+		logger.Warn("Failed to record problem file", logger.Error(err))
+	}
+	runtime.EventsEmit(a.ctx, audio.TopicError.Name(), map[string]interface{}{
+		"trackId": trackErr.Track.ID,
+		"message": trackErr.Err.Error(),
+	})
+}
+
+// onOutputWarning keeps the enlarged output buffer for next time and tells
+// the user playback is struggling
+func (a *App) onOutputWarning(warning *audio.OutputWarning) {
+	a.config.Set("audio.buffer_size", warning.BufferFrames)
+	if err := a.config.Save(); err != nil {
+		logger.Warn("Failed to save output buffer size", logger.Error(err))
+	}
+
+	runtime.EventsEmit(a.ctx, audio.TopicOutputWarning.Name(), map[string]interface{}{
+		"underruns":    warning.Underruns,
+		"stalls":       warning.Stalls,
+		"bufferFrames": warning.BufferFrames,
+		"latency":      warning.Latency.Seconds(),
+		"device":       warning.Device,
+	})
+
+	// A bigger buffer delays what is heard
+	a.applySyncOffset()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// GetLibraryFacets counts the genres, decades, formats, ratings and
+// languages of the tracks the filter matches, for the library view's
+// filter chips. Each facet is counted as if none of its own chips were
+// chosen, so choosing another widens the view by the count shown.
+func (a *App) GetLibraryFacets(filter domain.TrackFilter) (map[string]interface{}, error) {
+	counts, err := a.trackRepo.FacetCounts(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(counts))
+	for facet, values := range counts {
+		result[string(facet)] = values
+	}
+	return result, nil
+}
+
+// GetFilteredTracks returns the tracks the library view's filter chips and
+// search box match. Like GetLibraryTracks it leaves out copies of an album
+// in less preferred formats.
+func (a *App) GetFilteredTracks(filter domain.TrackFilter) ([]map[string]interface{}, error) {
+	tracks, err := a.trackRepo.FindByFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	tracks = a.hideOtherVersions(tracks)
+
+	result := make([]map[string]interface{}, len(tracks))
+	for i, track := range tracks {
+		result[i] = a.trackToMap(track)
+	}
+	return result, nil
+}
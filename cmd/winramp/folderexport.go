@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/library"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// ExportAlbumFolders writes covers and summaries into album folders in the
+// background, so Explorer shows covers for them: folder.jpg, a desktop.ini
+// setting the folder up as music and an album.nfo, as the options choose.
+// albumKeys picks the albums; empty exports the whole library. Progress is
+// reported through "library:folderExportProgress" and the summary through
+// "library:folderExportComplete".
+func (a *App) ExportAlbumFolders(albumKeys []string, opts library.FolderExportOptions) error {
+	if a.folderExport.IsRunning() {
+		return fmt.Errorf("folder export already in progress")
+	}
+
+	var tracks []*domain.Track
+	if len(albumKeys) == 0 {
+		all, err := a.trackRepo.FindAll()
+		if err != nil {
+			return err
+		}
+		tracks = all
+	}
+	for _, key := range albumKeys {
+		album, err := a.findAlbumByKey(key)
+		if err != nil {
+			return err
+		}
+		tracks = append(tracks, album...)
+	}
+
+	go func() {
+		done := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(500 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					runtime.EventsEmit(a.ctx, "library:folderExportProgress", a.folderExport.GetProgress())
+				}
+			}
+		}()
+
+		result, err := a.folderExport.Export(a.ctx, tracks, opts)
+		close(done)
+		if err != nil && result == nil {
+			logger.Warn("Album folder export failed", logger.Error(err))
+			runtime.EventsEmit(a.ctx, "library:folderExportComplete", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		summary := map[string]interface{}{
+			"written":  result.Written,
+			"skipped":  result.Skipped,
+			"failed":   result.Failed,
+			"duration": result.Duration.Seconds(),
+		}
+		if err != nil {
+			summary["error"] = err.Error()
+		}
+		runtime.EventsEmit(a.ctx, "library:folderExportComplete", summary)
+	}()
+
+	return nil
+}
+
+// CancelFolderExport stops a running folder export
+func (a *App) CancelFolderExport() {
+	a.folderExport.Cancel()
+}
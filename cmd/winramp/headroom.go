@@ -0,0 +1,114 @@
+package main
+
+import (
+	"math"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/winramp/winramp/internal/audio/dsp"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// checkHeadroom checks the preamp and equalizer settings against the
+// current track's peak
+func (a *App) checkHeadroom() dsp.Headroom {
+	return dsp.CheckHeadroom(a.config.Audio.PreAmp, a.player.Equalizer(), a.player.CurrentPeak())
+}
+
+// onHeadroomTrackChanged warns through "audio:clipWarning" when a track
+// about to play in exclusive mode would clip, as no limiter can step in
+func (a *App) onHeadroomTrackChanged(track *domain.Track) {
+	if !a.config.Audio.ExclusiveMode {
+		return
+	}
+
+	headroom := a.checkHeadroom()
+	if !headroom.Clips() {
+		return
+	}
+
+	logger.Info("Gain staging would clip in exclusive mode",
+		logger.String("track", track.ID),
+		logger.Float64("overDb", headroom.OverDB))
+	result := headroomToMap(headroom)
+	result["trackId"] = track.ID
+	runtime.EventsEmit(a.ctx, "audio:clipWarning", result)
+}
+
+// CheckClipping returns whether the preamp and equalizer would clip the
+// current track, and the preamp that would avoid it. Outside exclusive mode
+// overs are caught by the limiter instead.
+func (a *App) CheckClipping() map[string]interface{} {
+	result := headroomToMap(a.checkHeadroom())
+	result["exclusiveMode"] = a.config.Audio.ExclusiveMode
+	return result
+}
+
+// ReducePreamp lowers the preamp just enough that the current track no
+// longer clips, returning the check with the new setting
+func (a *App) ReducePreamp() (map[string]interface{}, error) {
+	headroom := a.checkHeadroom()
+	if headroom.Clips() {
+		a.config.Audio.PreAmp = headroom.SuggestedPreampDB
+		a.config.Set("audio.preamp", headroom.SuggestedPreampDB)
+		a.player.SetPreamp(headroom.SuggestedPreampDB)
+		if err := a.config.Save(); err != nil {
+			return nil, err
+		}
+		logger.Info("Reduced preamp to avoid clipping", logger.Float64("preampDb", headroom.SuggestedPreampDB))
+		a.player.ResetClipStats()
+	}
+	return a.CheckClipping(), nil
+}
+
+// GetClipStats returns how much playback has clipped since the stats were
+// last reset, with the tracks that clipped most. suggestedPreampDb is the
+// preamp that would have kept the loudest of them under full scale.
+func (a *App) GetClipStats() map[string]interface{} {
+	stats := a.player.ClipStats()
+
+	tracks := make([]map[string]interface{}, len(stats.Tracks))
+	for i, clipping := range stats.Tracks {
+		tracks[i] = map[string]interface{}{
+			"track":       a.trackToMap(clipping.Track),
+			"clipped":     clipping.Clipped,
+			"clipSeconds": clipping.ClipSeconds,
+			"peakDb":      clipping.PeakDB,
+			"gainDb":      clipping.GainDB,
+		}
+	}
+
+	preamp := a.config.Audio.PreAmp
+	suggested := preamp
+	if stats.PeakDB > 0 {
+		suggested = math.Floor((preamp-stats.PeakDB)*10) / 10
+	}
+
+	return map[string]interface{}{
+		"since":             stats.Since,
+		"seconds":           stats.Seconds,
+		"clipSeconds":       stats.ClipSeconds,
+		"clipped":           stats.Clipped,
+		"peakDb":            stats.PeakDB,
+		"preampDb":          preamp,
+		"suggestedPreampDb": suggested,
+		"tracks":            tracks,
+	}
+}
+
+// ResetClipStats starts the clipping stats afresh
+func (a *App) ResetClipStats() {
+	a.player.ResetClipStats()
+}
+
+func headroomToMap(headroom dsp.Headroom) map[string]interface{} {
+	return map[string]interface{}{
+		"clips":             headroom.Clips(),
+		"preampDb":          headroom.PreampDB,
+		"eqGainDb":          headroom.EQGainDB,
+		"peakDb":            headroom.PeakDB,
+		"overDb":            headroom.OverDB,
+		"suggestedPreampDb": headroom.SuggestedPreampDB,
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/library"
+)
+
+// GetLibraryHealth checks the library for missing tags, missing art,
+// missing ReplayGain, zero durations, suspicious bitrates and unreadable
+// files. Tracks are grouped by issue, and each group names the fix that
+// FixLibraryHealth can apply to it, if there is one.
+func (a *App) GetLibraryHealth() (map[string]interface{}, error) {
+	tracks, err := a.trackRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	report := library.CheckHealth(tracks)
+	groups := make([]map[string]interface{}, len(report.Groups))
+	for i, group := range report.Groups {
+		entries := make([]map[string]interface{}, len(group.Entries))
+		for j, entry := range group.Entries {
+			entries[j] = map[string]interface{}{
+				"track":  a.trackToMap(entry.Track),
+				"detail": entry.Detail,
+			}
+		}
+		groups[i] = map[string]interface{}{
+			"issue":  string(group.Issue),
+			"fix":    string(group.Fix),
+			"tracks": entries,
+		}
+	}
+
+	return map[string]interface{}{
+		"checked":   report.Checked,
+		"healthy":   report.Healthy(),
+		"groups":    groups,
+		"generated": report.Generated,
+	}, nil
+}
+
+// FixLibraryHealth applies an issue's one-click fix to tracks from the
+// health report. Unreadable files are verified again and zero durations
+// re-read from the file. Missing ReplayGain starts a background scan of
+// the library rather than of the tracks alone, so albums are measured
+// whole; its progress is reported as for ScanReplayGain.
+func (a *App) FixLibraryHealth(issue string, trackIDs []string) (map[string]interface{}, error) {
+	fix, ok := library.FixFor(library.HealthIssue(issue))
+	if !ok {
+		return nil, fmt.Errorf("no fix for %q", issue)
+	}
+
+	if fix == library.HealthFixReplayGain {
+		if err := a.ScanReplayGain(false); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"started": true}, nil
+	}
+
+	fixed := 0
+	failed := make(map[string]string)
+	for _, id := range trackIDs {
+		var track *domain.Track
+		var err error
+		switch fix {
+		case library.HealthFixRetry:
+			track, err = a.problems.Retry(id)
+		case library.HealthFixRescan:
+			track, err = a.problems.Rescan(id)
+		}
+		switch {
+		case err != nil:
+			failed[id] = err.Error()
+		case !track.IsValid:
+			// The file was checked but is still bad
+			failed[id] = track.Error
+		default:
+			fixed++
+		}
+	}
+	if fixed > 0 {
+		a.playlistMgr.LibraryChanged()
+	}
+
+	return map[string]interface{}{
+		"fixed":  fixed,
+		"failed": failed,
+	}, nil
+}
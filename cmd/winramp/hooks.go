@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/winramp/winramp/internal/audio"
+	"github.com/winramp/winramp/internal/config"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/hooks"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// stopHookDelay is how long the player must stay stopped before stop hooks
+// run. Loading the next track stops the player for a moment, which isn't
+// the kind of stop hooks are interested in.
+const stopHookDelay = time.Second
+
+// applyHooks gives the runner the enabled hooks from the config
+func (a *App) applyHooks() {
+	if err := a.hooks.Set(enabledHooks(a.config.Advanced.Hooks)); err != nil {
+		logger.Warn("Ignoring invalid hooks", logger.Error(err))
+	}
+}
+
+// onHookTrackChanged runs the track_changed hooks and updates the now
+// playing file
+func (a *App) onHookTrackChanged(track *domain.Track) {
+	data := hooks.NewData(hooks.EventTrackChanged, track)
+	a.hooks.Fire(data)
+	a.updateNowPlaying(data)
+}
+
+// onHookStateChanged runs the play, pause and stop hooks
+func (a *App) onHookStateChanged(state audio.PlayerState) {
+	switch state {
+	case audio.StatePlaying:
+		a.hooks.Fire(hooks.NewData(hooks.EventPlay, a.player.GetCurrentTrack()))
+	case audio.StatePaused:
+		a.hooks.Fire(hooks.NewData(hooks.EventPause, a.player.GetCurrentTrack()))
+	case audio.StateStopped:
+		time.AfterFunc(stopHookDelay, func() {
+			if a.player.GetState() == audio.StateStopped {
+				a.hooks.Fire(hooks.NewData(hooks.EventStop, a.player.GetCurrentTrack()))
+				a.updateNowPlaying(hooks.NewData(hooks.EventStop, nil))
+			}
+		})
+	}
+}
+
+// GetHooks returns the commands and webhooks run on playback events
+func (a *App) GetHooks() []config.HookConfig {
+	return a.config.Advanced.Hooks
+}
+
+// SetHooks replaces the hooks run on playback events. Each runs a command,
+// without a shell, or posts the event as JSON to a URL. Arguments and URLs
+// are templates such as "{{.Artist}} - {{.Title}}". Nothing is saved if any
+// hook is invalid, disabled ones included.
+func (a *App) SetHooks(hookConfigs []config.HookConfig) error {
+	for _, hookConfig := range hookConfigs {
+		if err := hooks.Validate(hookFromConfig(hookConfig)); err != nil {
+			return err
+		}
+	}
+
+	a.config.Advanced.Hooks = hookConfigs
+	a.config.Set("advanced.hooks", hookConfigs)
+	a.applyHooks()
+	return a.config.Save()
+}
+
+// TestHook runs a hook once on the current track, or on no track when
+// nothing is loaded, and returns its error if it fails
+func (a *App) TestHook(hookConfig config.HookConfig) error {
+	hook := hookFromConfig(hookConfig)
+	return a.hooks.Test(hook, hooks.NewData(hook.Event, a.player.GetCurrentTrack()))
+}
+
+// applyNowPlaying sets up the now playing file from the config
+func (a *App) applyNowPlaying() error {
+	settings := a.config.Advanced.NowPlaying
+	path := ""
+	if settings.Enabled {
+		path = settings.Path
+	}
+	return a.nowPlaying.Configure(settings.Template, path, settings.IdleText)
+}
+
+// updateNowPlaying writes the now playing file for an event's track
+func (a *App) updateNowPlaying(data hooks.Data) {
+	if err := a.nowPlaying.Update(data); err != nil {
+		logger.Warn("Failed to update now playing file", logger.Error(err))
+	}
+}
+
+// GetNowPlayingSettings returns the now playing file settings
+func (a *App) GetNowPlayingSettings() config.NowPlayingConfig {
+	return a.config.Advanced.NowPlaying
+}
+
+// SetNowPlayingSettings sets up the now playing file, which holds the
+// current track rendered through a template such as
+// "{{.Artist}} - {{.Title}}" for streaming overlays to read. idleText is
+// written while nothing plays.
+func (a *App) SetNowPlayingSettings(enabled bool, path, template, idleText string) error {
+	if enabled && strings.TrimSpace(path) == "" {
+		return errors.New("a file is needed for the now playing text")
+	}
+
+	previous := a.config.Advanced.NowPlaying
+	a.config.Advanced.NowPlaying = config.NowPlayingConfig{
+		Enabled:  enabled,
+		Path:     strings.TrimSpace(path),
+		Template: template,
+		IdleText: idleText,
+	}
+	if err := a.applyNowPlaying(); err != nil {
+		a.config.Advanced.NowPlaying = previous
+		return err
+	}
+
+	a.config.Set("advanced.now_playing.enabled", enabled)
+	a.config.Set("advanced.now_playing.path", a.config.Advanced.NowPlaying.Path)
+	a.config.Set("advanced.now_playing.template", template)
+	a.config.Set("advanced.now_playing.idle_text", idleText)
+	if err := a.config.Save(); err != nil {
+		return err
+	}
+
+	a.updateNowPlaying(a.nowPlayingData())
+	return nil
+}
+
+// CopyNowPlaying copies the current track, rendered through the now
+// playing template, to the clipboard and returns the text
+func (a *App) CopyNowPlaying() (string, error) {
+	text, err := a.nowPlaying.Render(a.nowPlayingData())
+	if err != nil {
+		return "", err
+	}
+	if text == "" {
+		return "", audio.ErrNoTrackLoaded
+	}
+	return text, runtime.ClipboardSetText(a.ctx, text)
+}
+
+// nowPlayingData describes the current track, or nothing when stopped
+func (a *App) nowPlayingData() hooks.Data {
+	if a.player.GetState() == audio.StateStopped {
+		return hooks.NewData(hooks.EventStop, nil)
+	}
+	return hooks.NewData(hooks.EventTrackChanged, a.player.GetCurrentTrack())
+}
+
+func enabledHooks(hookConfigs []config.HookConfig) []hooks.Hook {
+	enabled := make([]hooks.Hook, 0, len(hookConfigs))
+	for _, hookConfig := range hookConfigs {
+		if hookConfig.Enabled {
+			enabled = append(enabled, hookFromConfig(hookConfig))
+		}
+	}
+	return enabled
+}
+
+func hookFromConfig(hookConfig config.HookConfig) hooks.Hook {
+	return hooks.Hook{
+		Event:   hooks.Event(hookConfig.Event),
+		Command: hookConfig.Command,
+		Args:    hookConfig.Args,
+		URL:     hookConfig.URL,
+	}
+}
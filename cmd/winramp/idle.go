@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/winramp/winramp/internal/audio"
+	"github.com/winramp/winramp/internal/events"
+	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/session"
+)
+
+// idleCheckInterval is how often continuous playback is measured against
+// the stop-after limit
+const idleCheckInterval = time.Minute
+
+// idleResumeGrace is how long playback may stop, as between tracks loaded
+// one at a time, and still count as continuous
+const idleResumeGrace = time.Minute
+
+// Reasons the idle actions pause playback, sent with "player:idlePaused"
+const (
+	idleReasonLocked     = "locked"
+	idleReasonContinuous = "continuous"
+)
+
+// idleState tracks what the idle actions have seen and done
+type idleState struct {
+	playingSince time.Time // Start of continuous playback, zero when not playing
+	stoppedAt    time.Time // When playback last stopped or paused
+	pausedByLock bool      // Playback was paused because the session locked
+}
+
+// startIdleActions watches the session for locks and playback for long
+// stretches without the user touching anything. The settings are read as
+// each event arrives, so changes apply straight away.
+func (a *App) startIdleActions() {
+	events.Subscribe(a.bus, audio.TopicStateChanged, a.onIdleStateChanged)
+
+	if err := session.Watch(a.ctx, a.onSessionChange); err != nil {
+		if errors.Is(err, session.ErrUnavailable) {
+			logger.Debug("Session notifications unavailable", logger.Error(err))
+		} else {
+			logger.Warn("Failed to watch session", logger.Error(err))
+		}
+	}
+
+	go a.watchContinuousPlayback()
+}
+
+// onIdleStateChanged times continuous playback. Short stops, such as a
+// track being loaded, don't restart the clock.
+func (a *App) onIdleStateChanged(state audio.PlayerState) {
+	a.idleMu.Lock()
+	defer a.idleMu.Unlock()
+
+	now := time.Now()
+	if state != audio.StatePlaying {
+		if !a.idle.playingSince.IsZero() {
+			a.idle.stoppedAt = now
+		}
+		return
+	}
+
+	if a.idle.playingSince.IsZero() || now.Sub(a.idle.stoppedAt) > idleResumeGrace {
+		a.idle.playingSince = now
+	}
+	a.idle.stoppedAt = time.Time{}
+}
+
+// noteActivity records that the user controlled playback, which shows
+// someone is still listening
+func (a *App) noteActivity() {
+	a.idleMu.Lock()
+	defer a.idleMu.Unlock()
+
+	a.idle.playingSince = time.Now()
+	a.idle.stoppedAt = time.Time{}
+	a.idle.pausedByLock = false
+}
+
+func (a *App) onSessionChange(change session.Change) {
+	idle := a.config.Audio.Idle
+	logger.Debug("Session changed", logger.String("change", change.String()))
+
+	switch change {
+	case session.Locked:
+		if !idle.PauseOnLock || a.player.GetState() != audio.StatePlaying {
+			return
+		}
+		if err := a.player.Pause(); err != nil {
+			logger.Warn("Failed to pause on lock", logger.Error(err))
+			return
+		}
+
+		a.idleMu.Lock()
+		a.idle.pausedByLock = true
+		a.idleMu.Unlock()
+		runtime.EventsEmit(a.ctx, "player:idlePaused", map[string]interface{}{
+			"reason": idleReasonLocked,
+		})
+
+	case session.Unlocked:
+		a.idleMu.Lock()
+		resume := a.idle.pausedByLock
+		a.idle.pausedByLock = false
+		a.idleMu.Unlock()
+
+		if !resume || !idle.ResumeOnUnlock || a.player.GetState() != audio.StatePaused {
+			return
+		}
+		if err := a.player.Play(); err != nil {
+			logger.Warn("Failed to resume on unlock", logger.Error(err))
+		}
+	}
+}
+
+// watchContinuousPlayback pauses playback that has run for longer than the
+// stop-after limit without the user touching anything, and asks the
+// frontend whether anyone is still listening
+func (a *App) watchContinuousPlayback() {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		limit := a.config.Audio.Idle.StopAfter
+		if limit <= 0 || a.player.GetState() != audio.StatePlaying {
+			continue
+		}
+
+		a.idleMu.Lock()
+		since := a.idle.playingSince
+		a.idleMu.Unlock()
+		if since.IsZero() || time.Since(since) < limit {
+			continue
+		}
+
+		if err := a.player.Pause(); err != nil {
+			logger.Warn("Failed to pause idle playback", logger.Error(err))
+			continue
+		}
+		logger.Info("Paused after continuous playback", logger.Duration("played", time.Since(since)))
+		runtime.EventsEmit(a.ctx, "player:idlePaused", map[string]interface{}{
+			"reason":    idleReasonContinuous,
+			"playedFor": time.Since(since).Seconds(),
+		})
+	}
+}
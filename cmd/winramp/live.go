@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// applyLiveFilters passes on whether live recordings are shuffled and
+// suggested
+func (a *App) applyLiveFilters() {
+	a.playlistMgr.GetQueue().SetShuffleLive(a.config.App.ShuffleLive)
+	a.recommender.SetSuggestLive(a.config.App.SuggestLive)
+}
+
+// SetShuffleLive sets whether shuffle plays live recordings or passes over
+// them. Live tracks chosen directly still play.
+func (a *App) SetShuffleLive(enabled bool) error {
+	a.config.App.ShuffleLive = enabled
+	a.config.Set("app.shuffle_live", enabled)
+	a.applyLiveFilters()
+	a.transportChanged()
+	return a.config.Save()
+}
+
+// SetSuggestLive sets whether similar-track suggestions and the auto-DJ
+// may pick live recordings
+func (a *App) SetSuggestLive(enabled bool) error {
+	a.config.App.SuggestLive = enabled
+	a.config.Set("app.suggest_live", enabled)
+	a.applyLiveFilters()
+	return a.config.Save()
+}
+
+// GetArtistRecordings returns an artist's albums with the live ones apart,
+// each in release order, so live albums show under the artist with a
+// badge rather than mixed in with the studio albums. Live tracks on no
+// album are listed too.
+func (a *App) GetArtistRecordings(artist string) (map[string]interface{}, error) {
+	tracks, err := a.trackRepo.FindByArtist(artist)
+	if err != nil {
+		return nil, err
+	}
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("%w: artist %q", domain.ErrTrackNotFound, artist)
+	}
+
+	groups := domain.GroupAlbums(tracks)
+	domain.PreferVersions(groups, a.formatPreference())
+	domain.SortByRelease(groups)
+	studio, live := domain.SplitLive(groups)
+
+	var liveTracks []*domain.Track
+	for _, track := range tracks {
+		if track.AlbumKey() == "" && track.IsLive {
+			liveTracks = append(liveTracks, track)
+		}
+	}
+
+	return map[string]interface{}{
+		"artist":     artist,
+		"albums":     albumSummaries(studio),
+		"live":       albumSummaries(live),
+		"liveTracks": a.tracksToMaps(liveTracks),
+	}, nil
+}
+
+// albumSummaries describes albums for a list, without their tracks
+func albumSummaries(groups []*domain.AlbumGroup) []map[string]interface{} {
+	summaries := make([]map[string]interface{}, len(groups))
+	for i, group := range groups {
+		summaries[i] = map[string]interface{}{
+			"key":        group.Key,
+			"title":      group.Title,
+			"artist":     group.Artist,
+			"year":       group.Year,
+			"duration":   group.Duration.Seconds(),
+			"trackCount": group.TrackCount(),
+			"live":       group.IsLive(),
+		}
+	}
+	return summaries
+}
+
+// DetectLiveTracks flags the library tracks whose titles, albums or genres
+// mark them as live recordings, for tracks scanned before live recordings
+// were detected. It returns how many were flagged.
+func (a *App) DetectLiveTracks() (int, error) {
+	tracks, err := a.trackRepo.FindAll()
+	if err != nil {
+		return 0, err
+	}
+
+	flagged := 0
+	for _, track := range tracks {
+		if track.IsLive || !track.LooksLive() {
+			continue
+		}
+		track.IsLive = true
+		track.UpdatedAt = time.Now()
+		if err := a.trackRepo.UpdateTags(track); err != nil {
+			return flagged, err
+		}
+		flagged++
+	}
+	if flagged > 0 {
+		a.playlistMgr.LibraryChanged()
+	}
+	return flagged, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/library"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// nowPlayingLyrics are the lyrics of the track being played and the line
+// last reported to the frontend
+type nowPlayingLyrics struct {
+	trackID string
+	lyrics  *library.Lyrics // Nil while loading or when there are none
+	line    int
+}
+
+// GetLyrics returns the lyrics of a track from an .lrc file beside it, its
+// tags or an online provider, with times on the lines when they are
+// synced. It returns nil when there are none.
+func (a *App) GetLyrics(trackID string) (map[string]interface{}, error) {
+	track := a.player.GetCurrentTrack()
+	if track == nil || track.ID != trackID {
+		var err error
+		if track, err = a.trackRepo.FindByID(trackID); err != nil {
+			return nil, err
+		}
+	}
+
+	lyrics, err := a.lyrics.Load(a.ctx, track)
+	if err != nil {
+		if errors.Is(err, library.ErrNoLyrics) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return lyricsToMap(track.ID, lyrics), nil
+}
+
+// onLyricsTrackChanged loads the lyrics of a new track and sends them
+// through "player:lyrics", with no lines when there are none
+func (a *App) onLyricsTrackChanged(track *domain.Track) {
+	a.lyricsMu.Lock()
+	a.nowLyrics = &nowPlayingLyrics{trackID: track.ID, line: -1}
+	a.lyricsMu.Unlock()
+
+	go func() {
+		lyrics, err := a.lyrics.Load(a.ctx, track)
+		if err != nil {
+			if !errors.Is(err, library.ErrNoLyrics) {
+				logger.Debug("Failed to load lyrics", logger.String("track", track.ID), logger.Error(err))
+			}
+			lyrics = &library.Lyrics{}
+		}
+
+		// Skip if playback moved on while loading
+		a.lyricsMu.Lock()
+		now := a.nowLyrics
+		if now == nil || now.trackID != track.ID {
+			a.lyricsMu.Unlock()
+			return
+		}
+		now.lyrics = lyrics
+		a.lyricsMu.Unlock()
+
+		runtime.EventsEmit(a.ctx, "player:lyrics", lyricsToMap(track.ID, lyrics))
+	}()
+}
+
+// onLyricsPosition sends the line of synced lyrics being heard through
+// "player:lyricsLine" each time it changes. The position is taken back by
+// the sync offset, so lines change as they are heard rather than decoded.
+func (a *App) onLyricsPosition(position time.Duration) {
+	heard := max(position-a.player.SyncOffset(), 0)
+
+	a.lyricsMu.Lock()
+	now := a.nowLyrics
+	if now == nil || now.lyrics == nil || !now.lyrics.Synced {
+		a.lyricsMu.Unlock()
+		return
+	}
+	line := now.lyrics.LineAt(heard)
+	if line == now.line {
+		a.lyricsMu.Unlock()
+		return
+	}
+	now.line = line
+	a.lyricsMu.Unlock()
+
+	payload := map[string]interface{}{
+		"trackId": now.trackID,
+		"index":   line,
+		"text":    "",
+		"time":    0.0,
+	}
+	if line >= 0 {
+		payload["text"] = now.lyrics.Lines[line].Text
+		payload["time"] = now.lyrics.Lines[line].Time.Seconds()
+	}
+	runtime.EventsEmit(a.ctx, "player:lyricsLine", payload)
+}
+
+func lyricsToMap(trackID string, lyrics *library.Lyrics) map[string]interface{} {
+	lines := make([]map[string]interface{}, len(lyrics.Lines))
+	for i, line := range lyrics.Lines {
+		lines[i] = map[string]interface{}{
+			"time": line.Time.Seconds(),
+			"text": line.Text,
+		}
+	}
+	return map[string]interface{}{
+		"trackId": trackID,
+		"synced":  lyrics.Synced,
+		"source":  lyrics.Source,
+		"lines":   lines,
+	}
+}
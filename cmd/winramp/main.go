@@ -33,6 +33,7 @@ func main() {
 		migrate    = flag.String("migrate", "", "Run database migrations (up/down)")
 		backup     = flag.String("backup", "", "Backup database to specified path")
 		restore    = flag.String("restore", "", "Restore database from specified path")
+		diagnose   = flag.String("diagnose", "", "Play audio test signals on a device (\"default\" for the default) and exit")
 	)
 	flag.Parse()
 
@@ -59,6 +60,11 @@ func main() {
 	logConfig.FilePath = cfg.App.LogDir + "/winramp.log"
 	logger.Initialize(logConfig)
 
+	// Audio diagnostics need neither the database nor the window
+	if *diagnose != "" {
+		os.Exit(runDiagnostics(*diagnose))
+	}
+
 	// Log startup
 	logger.Info("WinRamp starting",
 		logger.String("version", Version),
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/library"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// Onboarding steps, in wizard order
+const (
+	onboardingStepFolders = "folders"
+	onboardingStepImport  = "import"
+	onboardingStepDevice  = "device"
+	onboardingStepScan    = "scan"
+	onboardingStepDone    = "done"
+)
+
+// onboardingState tracks the first-run wizard. It lives on the App rather
+// than in the UI so a reloaded window picks up where the user left off.
+type onboardingState struct {
+	step        string
+	folders     []string
+	sources     []library.ImportSource
+	scanning    bool
+	cancel      context.CancelFunc
+	progress    float64
+	currentItem string
+	imported    int
+	playlists   int
+	failed      int
+	err         string
+}
+
+// Onboarding Methods
+
+// GetOnboardingState returns the first-run wizard state
+func (a *App) GetOnboardingState() map[string]interface{} {
+	a.onboardingMu.Lock()
+	defer a.onboardingMu.Unlock()
+
+	return a.onboardingSnapshot()
+}
+
+// DetectMusicFolders returns the standard music folders that exist on this
+// machine
+func (a *App) DetectMusicFolders() []string {
+	return detectMusicFolders()
+}
+
+// DetectLibraryImports returns iTunes and Windows Media Player libraries
+// found in the standard music folders
+func (a *App) DetectLibraryImports() []map[string]interface{} {
+	sources := library.DetectImportSources(detectMusicFolders())
+
+	result := make([]map[string]interface{}, len(sources))
+	for i, source := range sources {
+		result[i] = importSourceToMap(source)
+	}
+
+	return result
+}
+
+// SetOnboardingFolders chooses the folders the initial scan will cover
+func (a *App) SetOnboardingFolders(folders []string) error {
+	cleaned := make([]string, 0, len(folders))
+	for _, folder := range folders {
+		info, err := os.Stat(folder)
+		if err != nil || !info.IsDir() {
+			return fmt.Errorf("%w: %s", domain.ErrFileNotFound, folder)
+		}
+		cleaned = append(cleaned, filepath.Clean(folder))
+	}
+
+	a.onboardingMu.Lock()
+	defer a.onboardingMu.Unlock()
+
+	if a.onboarding.scanning {
+		return fmt.Errorf("initial scan already in progress")
+	}
+	a.onboarding.folders = cleaned
+	a.onboarding.step = onboardingStepImport
+	return nil
+}
+
+// SetOnboardingImports chooses which detected libraries to import, by path
+func (a *App) SetOnboardingImports(paths []string) error {
+	selected := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		selected[path] = true
+	}
+
+	sources := make([]library.ImportSource, 0, len(paths))
+	for _, source := range library.DetectImportSources(detectMusicFolders()) {
+		if selected[source.Path] {
+			sources = append(sources, source)
+		}
+	}
+
+	a.onboardingMu.Lock()
+	defer a.onboardingMu.Unlock()
+
+	if a.onboarding.scanning {
+		return fmt.Errorf("initial scan already in progress")
+	}
+	a.onboarding.sources = sources
+	a.onboarding.step = onboardingStepDevice
+	return nil
+}
+
+// GetAudioDevices returns the available output devices
+func (a *App) GetAudioDevices() ([]map[string]interface{}, error) {
+	devices, err := a.player.GetOutputDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, len(devices))
+	for i, device := range devices {
+		result[i] = map[string]interface{}{
+			"id":          device.ID,
+			"name":        device.Name,
+			"type":        device.Type,
+			"isDefault":   device.IsDefault,
+			"isSelected":  device.ID == a.config.Audio.OutputDevice,
+			"maxChannels": device.MaxChannels,
+			"sampleRates": device.SampleRates,
+			"exclusive":   device.Exclusive,
+		}
+	}
+
+	return result, nil
+}
+
+// SetAudioDevice switches output to a device and remembers the choice
+func (a *App) SetAudioDevice(id string) error {
+	if err := a.player.SetOutputDevice(id); err != nil {
+		return err
+	}
+
+	a.config.Audio.OutputDevice = id
+	a.config.Set("audio.output_device", id)
+	a.applySyncOffset()
+
+	a.onboardingMu.Lock()
+	if a.onboarding.step == onboardingStepDevice {
+		a.onboarding.step = onboardingStepScan
+	}
+	a.onboardingMu.Unlock()
+
+	return a.config.Save()
+}
+
+// StartOnboardingScan adds the chosen folders to the watch list, scans them
+// and imports the chosen libraries in the background. Progress is reported
+// through "onboarding:progress" and the outcome through
+// "onboarding:complete"; both carry the full wizard state.
+func (a *App) StartOnboardingScan() error {
+	a.onboardingMu.Lock()
+	defer a.onboardingMu.Unlock()
+
+	if a.onboarding.scanning {
+		return fmt.Errorf("initial scan already in progress")
+	}
+	if len(a.onboarding.folders) == 0 && len(a.onboarding.sources) == 0 {
+		return fmt.Errorf("no folders or libraries selected")
+	}
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.onboarding.step = onboardingStepScan
+	a.onboarding.scanning = true
+	a.onboarding.cancel = cancel
+	a.onboarding.progress = 0
+	a.onboarding.imported = 0
+	a.onboarding.playlists = 0
+	a.onboarding.failed = 0
+	a.onboarding.err = ""
+
+	// Watch the chosen folders from now on
+	watchFolders := a.config.Library.WatchFolders
+	for _, folder := range a.onboarding.folders {
+		if !containsPath(watchFolders, folder) {
+			watchFolders = append(watchFolders, folder)
+			a.watchPath(folder)
+		}
+	}
+	a.config.Library.WatchFolders = watchFolders
+	a.config.Set("library.watch_folders", watchFolders)
+	if err := a.config.Save(); err != nil {
+		logger.Warn("Failed to save watch folders", logger.Error(err))
+	}
+
+	go a.runOnboardingScan(ctx, a.onboarding.folders, a.onboarding.sources)
+	return nil
+}
+
+// CancelOnboardingScan stops a running initial scan
+func (a *App) CancelOnboardingScan() {
+	a.onboardingMu.Lock()
+	defer a.onboardingMu.Unlock()
+
+	if a.onboarding.cancel != nil {
+		a.onboarding.cancel()
+	}
+}
+
+// CompleteOnboarding marks the first-run wizard as finished so it is not
+// shown again. It is also used when the user skips the wizard.
+func (a *App) CompleteOnboarding() error {
+	a.onboardingMu.Lock()
+	if a.onboarding.cancel != nil {
+		a.onboarding.cancel()
+	}
+	a.onboarding.step = onboardingStepDone
+	a.onboardingMu.Unlock()
+
+	a.config.App.FirstRunComplete = true
+	a.config.Set("app.first_run_complete", true)
+	return a.config.Save()
+}
+
+func (a *App) runOnboardingScan(ctx context.Context, folders []string, sources []library.ImportSource) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				a.onboardingMu.Lock()
+				if file := a.libraryMgr.scanner.GetCurrentFile(); file != "" && a.libraryMgr.scanner.IsScanning() {
+					a.onboarding.currentItem = file
+				}
+				state := a.onboardingSnapshot()
+				a.onboardingMu.Unlock()
+				runtime.EventsEmit(a.ctx, "onboarding:progress", state)
+			}
+		}
+	}()
+
+	total := len(folders) + len(sources)
+	step := 0
+	var runErr error
+
+	for _, folder := range folders {
+		if ctx.Err() != nil {
+			break
+		}
+		a.setOnboardingItem(folder, step, total)
+
+		result, err := a.libraryMgr.scanner.ScanWatchFolder(ctx, a.watchFolder(folder))
+		if err != nil {
+			logger.Warn("Initial scan of folder failed", logger.String("path", folder), logger.Error(err))
+			runErr = err
+		}
+		if result != nil {
+			a.onboardingMu.Lock()
+			a.onboarding.imported += result.ImportedTracks
+			a.onboarding.failed += result.FailedFiles
+			a.onboardingMu.Unlock()
+		}
+		step++
+	}
+
+	for _, source := range sources {
+		if ctx.Err() != nil {
+			break
+		}
+		a.setOnboardingItem(source.Path, step, total)
+
+		if err := a.importExternalLibrary(ctx, source); err != nil {
+			logger.Warn("Library import failed",
+				logger.String("source", source.Kind),
+				logger.String("path", source.Path),
+				logger.Error(err))
+			runErr = err
+		}
+		step++
+	}
+
+	close(done)
+	if ctx.Err() != nil {
+		runErr = ctx.Err()
+	}
+
+	a.onboardingMu.Lock()
+	a.onboarding.scanning = false
+	a.onboarding.cancel = nil
+	a.onboarding.currentItem = ""
+	if runErr != nil {
+		a.onboarding.err = runErr.Error()
+	} else {
+		a.onboarding.progress = 100
+	}
+	imported, playlists := a.onboarding.imported, a.onboarding.playlists
+	state := a.onboardingSnapshot()
+	a.onboardingMu.Unlock()
+
+	logger.Info("Initial scan finished",
+		logger.Int("imported", imported),
+		logger.Int("playlists", playlists))
+
+	runtime.EventsEmit(a.ctx, "onboarding:complete", state)
+}
+
+// importExternalLibrary adds the tracks and playlists of another player's
+// library. Ratings and play counts are only taken over where the library
+// has none of its own.
+func (a *App) importExternalLibrary(ctx context.Context, source library.ImportSource) error {
+	lib, err := library.ReadImportSource(source)
+	if err != nil {
+		return err
+	}
+
+	for _, ext := range lib.Tracks {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := os.Stat(ext.Path); err != nil {
+			continue
+		}
+
+		existing, _ := a.trackRepo.FindByPath(ext.Path)
+		track, err := a.libraryMgr.ImportTrack(ext.Path)
+		if err != nil {
+			a.onboardingMu.Lock()
+			a.onboarding.failed++
+			a.onboardingMu.Unlock()
+			continue
+		}
+		if existing == nil {
+			a.onboardingMu.Lock()
+			a.onboarding.imported++
+			a.onboardingMu.Unlock()
+		}
+
+		changed := false
+		if track.Rating == 0 && ext.Rating > 0 {
+			changed = track.SetRating(ext.Rating) == nil
+		}
+		if track.PlayCount == 0 && ext.PlayCount > 0 {
+			track.PlayCount = ext.PlayCount
+			track.LastPlayed = ext.LastPlayed
+			changed = true
+		}
+		if changed {
+			if err := a.trackRepo.Update(track); err != nil {
+				logger.Warn("Failed to save imported track details", logger.String("path", ext.Path), logger.Error(err))
+			}
+		}
+	}
+
+	for _, ext := range lib.Playlists {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		playlist, err := a.playlistMgr.Create(ext.Name)
+		if err != nil {
+			logger.Warn("Failed to create imported playlist", logger.String("name", ext.Name), logger.Error(err))
+			continue
+		}
+		for _, path := range ext.Paths {
+			track, err := a.trackRepo.FindByPath(path)
+			if err != nil || track == nil {
+				continue
+			}
+			if err := a.playlistMgr.AddTrack(playlist.ID, track); err != nil {
+				logger.Warn("Failed to add track to imported playlist", logger.String("path", path), logger.Error(err))
+			}
+		}
+
+		a.onboardingMu.Lock()
+		a.onboarding.playlists++
+		a.onboardingMu.Unlock()
+	}
+
+	return nil
+}
+
+func (a *App) setOnboardingItem(item string, step, total int) {
+	a.onboardingMu.Lock()
+	defer a.onboardingMu.Unlock()
+
+	a.onboarding.currentItem = item
+	a.onboarding.progress = float64(step) / float64(total) * 100
+}
+
+// onboardingSnapshot returns the wizard state. Callers must hold
+// onboardingMu.
+func (a *App) onboardingSnapshot() map[string]interface{} {
+	step := a.onboarding.step
+	if step == "" {
+		step = onboardingStepFolders
+		if a.config.App.FirstRunComplete {
+			step = onboardingStepDone
+		}
+	}
+
+	sources := make([]map[string]interface{}, len(a.onboarding.sources))
+	for i, source := range a.onboarding.sources {
+		sources[i] = importSourceToMap(source)
+	}
+
+	return map[string]interface{}{
+		"required":    !a.config.App.FirstRunComplete,
+		"step":        step,
+		"folders":     append([]string{}, a.onboarding.folders...),
+		"imports":     sources,
+		"device":      a.config.Audio.OutputDevice,
+		"scanning":    a.onboarding.scanning,
+		"progress":    a.onboarding.progress,
+		"currentItem": a.onboarding.currentItem,
+		"imported":    a.onboarding.imported,
+		"playlists":   a.onboarding.playlists,
+		"failed":      a.onboarding.failed,
+		"error":       a.onboarding.err,
+	}
+}
+
+func importSourceToMap(source library.ImportSource) map[string]interface{} {
+	return map[string]interface{}{
+		"kind": source.Kind,
+		"name": source.Name,
+		"path": source.Path,
+	}
+}
+
+// detectMusicFolders returns the user's, public and OneDrive music folders
+// that exist
+func detectMusicFolders() []string {
+	candidates := make([]string, 0, 4)
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates,
+			filepath.Join(home, "Music"),
+			filepath.Join(home, "OneDrive", "Music"))
+	}
+	if oneDrive := os.Getenv("OneDrive"); oneDrive != "" {
+		candidates = append(candidates, filepath.Join(oneDrive, "Music"))
+	}
+	if public := os.Getenv("PUBLIC"); public != "" {
+		candidates = append(candidates, filepath.Join(public, "Music"))
+	}
+
+	folders := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if containsPath(folders, candidate) {
+			continue
+		}
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			folders = append(folders, candidate)
+		}
+	}
+
+	return folders
+}
+
+// containsPath reports whether paths holds path, ignoring case as Windows
+// does
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if strings.EqualFold(filepath.Clean(p), filepath.Clean(path)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/winramp/winramp/internal/audio"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// episodeSaveInterval is how often an episode's position is saved while it
+// plays, on top of the saves on pause and track change
+const episodeSaveInterval = 15 * time.Second
+
+// episodeState follows the podcast episode being played
+type episodeState struct {
+	track        *domain.Track
+	progress     *domain.EpisodeProgress
+	rule         *domain.FeedSkipRule // Nil when the podcast has no skip rule
+	position     time.Duration
+	savedAt      time.Time
+	outroSkipped bool
+}
+
+// onEpisodeTrackChanged saves the position in the previous episode and, for
+// a new one, resumes where it was left or skips the podcast's intro
+func (a *App) onEpisodeTrackChanged(track *domain.Track) {
+	a.episodeMu.Lock()
+	defer a.episodeMu.Unlock()
+
+	if a.episode != nil {
+		a.saveEpisode(a.episode)
+		a.episode = nil
+	}
+	if track.Transient || !track.IsPodcastEpisode() {
+		return
+	}
+
+	progress, err := a.episodes.FindByTrack(track.ID)
+	if err != nil {
+		logger.Warn("Failed to load episode progress", logger.String("id", track.ID), logger.Error(err))
+	}
+	if progress == nil {
+		progress = domain.NewEpisodeProgress(track.ID)
+	}
+	rule, err := a.skipRules.FindByFeed(track.PodcastFeed())
+	if err != nil {
+		logger.Warn("Failed to load skip rule", logger.String("id", track.ID), logger.Error(err))
+	}
+
+	state := &episodeState{track: track, progress: progress, rule: rule, savedAt: time.Now()}
+	a.episode = state
+
+	start := progress.ResumeAt()
+	if start == 0 && rule != nil {
+		start, _ = rule.PlayWindow(track.Duration)
+	}
+	if start > 0 {
+		state.position = start
+		if err := a.player.Seek(start); err != nil {
+			logger.Warn("Failed to resume episode", logger.String("id", track.ID), logger.Error(err))
+		}
+	}
+}
+
+// onEpisodePosition saves progress as an episode plays, marks it played
+// near the end and skips the podcast's outro
+func (a *App) onEpisodePosition(position time.Duration) {
+	a.episodeMu.Lock()
+	defer a.episodeMu.Unlock()
+
+	state := a.episode
+	if state == nil {
+		return
+	}
+	state.position = position
+
+	duration := state.track.Duration
+	if state.progress.Update(position, duration) {
+		logger.Info("Episode played", logger.String("title", state.track.GetDisplayTitle()))
+		a.saveEpisode(state)
+	} else if time.Since(state.savedAt) >= episodeSaveInterval {
+		a.saveEpisode(state)
+	}
+
+	if state.rule == nil || state.outroSkipped || state.rule.SkipOutro <= 0 {
+		return
+	}
+	if _, end := state.rule.PlayWindow(duration); end < duration && position >= end {
+		// Jumping to the end lets the track finish as usual, so the queue
+		// moves on the same way it would have
+		state.outroSkipped = true
+		go func() {
+			if err := a.player.Seek(duration); err != nil {
+				logger.Warn("Failed to skip episode outro", logger.Error(err))
+			}
+		}()
+	}
+}
+
+// onEpisodeStateChanged saves an episode's position when it stops playing
+func (a *App) onEpisodeStateChanged(state audio.PlayerState) {
+	if state == audio.StatePlaying || state == audio.StateBuffering {
+		return
+	}
+
+	a.episodeMu.Lock()
+	defer a.episodeMu.Unlock()
+	if a.episode != nil {
+		a.saveEpisode(a.episode)
+	}
+}
+
+// saveEpisode stores an episode's progress. Must be called with episodeMu
+// held.
+func (a *App) saveEpisode(state *episodeState) {
+	if !state.progress.Played {
+		state.progress.Update(state.position, state.track.Duration)
+	}
+	if err := a.episodes.Save(state.progress); err != nil {
+		logger.Warn("Failed to save episode progress", logger.String("id", state.track.ID), logger.Error(err))
+		return
+	}
+	state.savedAt = time.Now()
+}
+
+// GetEpisodeProgress returns how far through a podcast episode the user is
+func (a *App) GetEpisodeProgress(trackID string) (map[string]interface{}, error) {
+	progress, err := a.episodes.FindByTrack(trackID)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		progress = domain.NewEpisodeProgress(trackID)
+	}
+
+	return map[string]interface{}{
+		"trackId":  progress.TrackID,
+		"position": progress.ResumeAt().Seconds(),
+		"played":   progress.Played,
+	}, nil
+}
+
+// SetEpisodePlayed marks a podcast episode played or unplayed
+func (a *App) SetEpisodePlayed(trackID string, played bool) error {
+	track, err := a.trackRepo.FindByID(trackID)
+	if err != nil {
+		return err
+	}
+	if !track.IsPodcastEpisode() {
+		return fmt.Errorf("%w: not a podcast episode", domain.ErrInvalidInput)
+	}
+
+	a.episodeMu.Lock()
+	defer a.episodeMu.Unlock()
+
+	progress := domain.NewEpisodeProgress(trackID)
+	if a.episode != nil && a.episode.track.ID == trackID {
+		progress = a.episode.progress
+	}
+	progress.SetPlayed(played)
+	return a.episodes.Save(progress)
+}
+
+// GetFeedSkipRules returns the intro and outro skips set for podcasts
+func (a *App) GetFeedSkipRules() ([]map[string]interface{}, error) {
+	rules, err := a.skipRules.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, len(rules))
+	for i, rule := range rules {
+		result[i] = map[string]interface{}{
+			"feed":      rule.Feed,
+			"name":      rule.Name,
+			"skipIntro": rule.SkipIntro.Seconds(),
+			"skipOutro": rule.SkipOutro.Seconds(),
+		}
+	}
+	return result, nil
+}
+
+// SetFeedSkipRule skips the first and last seconds of every episode of a
+// podcast, named as in its episodes' album tag. It applies from the next
+// episode played.
+func (a *App) SetFeedSkipRule(podcast string, skipIntro, skipOutro float64) error {
+	rule, err := domain.NewFeedSkipRule(podcast,
+		time.Duration(skipIntro*float64(time.Second)),
+		time.Duration(skipOutro*float64(time.Second)))
+	if err != nil {
+		return err
+	}
+	return a.skipRules.Save(rule)
+}
+
+// DeleteFeedSkipRule stops skipping parts of a podcast's episodes
+func (a *App) DeleteFeedSkipRule(feed string) error {
+	return a.skipRules.Delete(feed)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/remote"
+)
+
+// GetRemoteControl returns whether the remote control API is running and
+// where, with the certificate fingerprint clients should pin when it is
+// served over TLS
+func (a *App) GetRemoteControl() map[string]interface{} {
+	address := a.remote.Addr()
+	fingerprint := a.remote.Fingerprint()
+	return map[string]interface{}{
+		"enabled":     address != "",
+		"address":     address,
+		"grpcAddress": a.remote.GRPCAddr(),
+		"tls":         fingerprint != "",
+		"fingerprint": fingerprint,
+	}
+}
+
+// startRemote starts the remote control API over HTTP and, unless its
+// address is empty, gRPC
+func (a *App) startRemote() error {
+	if err := a.remote.Start(a.config.Network.RemoteAddress); err != nil {
+		return err
+	}
+	if address := a.config.Network.RemoteGRPCAddress; address != "" {
+		if err := a.remote.StartGRPC(address); err != nil {
+			ctx, cancel := context.WithTimeout(a.ctx, 5*time.Second)
+			defer cancel()
+			a.remote.Shutdown(ctx)
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRemoteControl starts or stops the remote control API. The setting is
+// kept for the next launch.
+func (a *App) SetRemoteControl(enabled bool) (map[string]interface{}, error) {
+	if enabled {
+		if a.remote.Addr() == "" {
+			if err := a.startRemote(); err != nil {
+				return nil, err
+			}
+			a.startAdvertising()
+		}
+	} else {
+		a.stopAdvertising()
+		ctx, cancel := context.WithTimeout(a.ctx, 5*time.Second)
+		defer cancel()
+		if err := a.remote.Shutdown(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	a.config.Network.RemoteEnabled = enabled
+	a.config.Set("network.remote_enabled", enabled)
+	if err := a.config.Save(); err != nil {
+		return nil, err
+	}
+	return a.GetRemoteControl(), nil
+}
+
+// StartRemotePairing starts pairing a new remote client with a scope of
+// "read", "control" or "admin". The returned PIN is shown to the user, who
+// enters it in the client before it expires.
+func (a *App) StartRemotePairing(scope string) (map[string]interface{}, error) {
+	clientScope, err := domain.ParseClientScope(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	pin, expires, err := a.remote.StartPairing(clientScope)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"pin":       pin,
+		"scope":     string(clientScope),
+		"expiresAt": expires,
+	}, nil
+}
+
+// CancelRemotePairing withdraws the PIN of a pairing in progress
+func (a *App) CancelRemotePairing() {
+	a.remote.CancelPairing()
+}
+
+// GetRemoteClients returns the paired remote clients
+func (a *App) GetRemoteClients() ([]*domain.RemoteClient, error) {
+	return a.remoteClients.FindAll()
+}
+
+// RevokeRemoteClient removes a paired client, whose token stops working
+// straight away
+func (a *App) RevokeRemoteClient(id string) error {
+	return a.remoteClients.Delete(id)
+}
+
+// GetTrack returns a library track
+func (a *App) GetTrack(id string) (*domain.Track, error) {
+	return a.trackRepo.FindByID(id)
+}
+
+// remoteLimits builds the remote server's limits from the network settings.
+// Library streaming is off unless enabled.
+func (a *App) remoteLimits() remote.Limits {
+	network := a.config.Network
+	limits := remote.Limits{
+		MaxConnections:    network.MaxConnections,
+		RequestsPerSecond: network.RemoteRateLimit,
+		Burst:             int(2 * network.RemoteRateLimit),
+		MaxStreams:        network.MaxStreams,
+	}
+	if !network.EnableStreaming {
+		limits.MaxStreams = 0
+	}
+	return limits
+}
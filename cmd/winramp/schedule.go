@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/winramp/winramp/internal/audio"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/library"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// startScanSchedule scans the watch folders every scan interval while the
+// app runs, when auto-scan is on. Scans publish the same started, progress
+// and finished events as those the user starts.
+func (a *App) startScanSchedule() {
+	a.scanSchedule = library.NewScanScheduler(a.scanInterval(), a.scheduledScan)
+	a.scanSchedule.SetBusy(a.scanBusy)
+	a.scanSchedule.Start(a.ctx)
+}
+
+// scanInterval returns how often scheduled scans run, or zero when
+// auto-scan is off
+func (a *App) scanInterval() time.Duration {
+	if !a.config.Library.AutoScan {
+		return 0
+	}
+	return a.config.Library.ScanInterval
+}
+
+// scanBusy reports whether a scheduled scan has to wait: while another
+// scan runs, or while music plays if the settings ask for that
+func (a *App) scanBusy() bool {
+	if a.libraryMgr.scanner.IsScanning() {
+		return true
+	}
+	return a.config.Library.PauseScanWhilePlaying && a.player.GetState() == audio.StatePlaying
+}
+
+// scheduledScan scans each enabled watch folder in turn, with the
+// scanner's decoding held to the CPU limit
+func (a *App) scheduledScan(ctx context.Context) {
+	logger.Info("Starting scheduled library scan")
+	for _, path := range a.config.Library.WatchFolders {
+		if ctx.Err() != nil {
+			return
+		}
+		folder := a.watchFolder(path)
+		if !folder.IsEnabled {
+			continue
+		}
+		if _, err := a.libraryMgr.scanner.BackgroundScanWatchFolder(ctx, folder); err != nil && ctx.Err() == nil {
+			logger.Warn("Scheduled scan of folder failed", logger.String("path", path), logger.Error(err))
+		}
+	}
+}
+
+// GetScanSchedule returns whether the library is scanned on a schedule,
+// how often in minutes, and when the last scan finished and the next is
+// due as Unix seconds (0 when none is scheduled)
+func (a *App) GetScanSchedule() map[string]interface{} {
+	var next int64
+	if at := a.scanSchedule.NextScan(); !at.IsZero() {
+		next = at.Unix()
+	}
+	return map[string]interface{}{
+		"enabled":           a.config.Library.AutoScan,
+		"intervalMinutes":   a.config.Library.ScanInterval.Minutes(),
+		"pauseWhilePlaying": a.config.Library.PauseScanWhilePlaying,
+		"lastScan":          a.scanSchedule.LastScan().Unix(),
+		"nextScan":          next,
+	}
+}
+
+// SetScanSchedule turns scheduled scans on or off and sets how often they
+// run, in minutes. The next scan is due one interval after the last.
+func (a *App) SetScanSchedule(enabled bool, intervalMinutes float64) error {
+	interval := time.Duration(intervalMinutes * float64(time.Minute))
+	if interval < time.Minute {
+		return fmt.Errorf("%w: scan interval must be at least a minute", domain.ErrInvalidInput)
+	}
+
+	a.config.Library.AutoScan = enabled
+	a.config.Library.ScanInterval = interval
+	a.config.Set("library.auto_scan", enabled)
+	a.config.Set("library.scan_interval", interval)
+	a.scanSchedule.SetInterval(a.scanInterval())
+	return a.config.Save()
+}
+
+// SetPauseScanWhilePlaying sets whether scheduled scans wait until music
+// stops playing before they start
+func (a *App) SetPauseScanWhilePlaying(enabled bool) error {
+	a.config.Library.PauseScanWhilePlaying = enabled
+	a.config.Set("library.pause_scan_while_playing", enabled)
+	return a.config.Save()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/winramp/winramp/internal/audio"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/playlist"
+)
+
+// sessionSaveDelay is how long the queue and transport must settle before
+// the session is saved, so a burst of changes writes it once
+const sessionSaveDelay = 2 * time.Second
+
+// sessionFile is the session's file name in the data directory
+const sessionFile = "session.json"
+
+// startSession opens the session store and puts back the queue, current
+// track and position of the last run. Playback resumes only when it was
+// playing at exit and the app is set to resume. It runs before events are
+// forwarded, so loading the track isn't taken for a new play.
+func (a *App) startSession() {
+	store := playlist.NewSessionStore(filepath.Join(a.config.App.DataDir, sessionFile))
+	a.sessionMu.Lock()
+	a.sessionStore = store
+	a.sessionMu.Unlock()
+
+	session, err := store.Load()
+	if err != nil {
+		logger.Warn("Failed to load playback session", logger.Error(err))
+		return
+	}
+	if session == nil {
+		return
+	}
+
+	queue := a.playlistMgr.GetQueue()
+	queue.Restore(session, func(id string) *domain.Track {
+		track, err := a.trackRepo.FindByID(id)
+		if err != nil || !track.IsValid {
+			return nil
+		}
+		return track
+	})
+
+	track := queue.Current()
+	if track == nil {
+		return
+	}
+	if err := a.LoadTrack(track); err != nil {
+		logger.Warn("Failed to load session track", logger.String("id", track.ID), logger.Error(err))
+		return
+	}
+	if session.Offset > 0 {
+		if err := a.player.Seek(session.Offset); err != nil {
+			logger.Warn("Failed to restore session position", logger.Error(err))
+		}
+	}
+	if session.Playing && a.config.App.ResumePlayback {
+		if err := a.player.Play(); err != nil {
+			logger.Warn("Failed to resume playback", logger.Error(err))
+		}
+	}
+
+	logger.Info("Restored playback session",
+		logger.Int("tracks", queue.GetLength()),
+		logger.String("track", track.GetDisplayTitle()))
+}
+
+// scheduleSessionSave saves the session once changes have settled
+func (a *App) scheduleSessionSave() {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+
+	store := a.sessionStore
+	if store == nil {
+		return
+	}
+	if a.sessionSave != nil {
+		a.sessionSave.Reset(sessionSaveDelay)
+		return
+	}
+	a.sessionSave = time.AfterFunc(sessionSaveDelay, func() {
+		a.sessionMu.Lock()
+		a.sessionSave = nil
+		a.sessionMu.Unlock()
+		a.saveSession(store)
+	})
+}
+
+// saveSession writes the queue and where playback is in it
+func (a *App) saveSession(store *playlist.SessionStore) {
+	queue := a.playlistMgr.GetQueue()
+	session := queue.Session()
+	if current := queue.Current(); current != nil && !current.Transient && current == a.player.GetCurrentTrack() {
+		session.Offset = a.player.GetPosition()
+		state := a.player.GetState()
+		session.Playing = state == audio.StatePlaying || state == audio.StateBuffering
+	}
+	session.SavedAt = time.Now()
+
+	if err := store.Save(session); err != nil {
+		logger.Warn("Failed to save playback session", logger.Error(err))
+	}
+}
+
+// stopSession saves the session a last time. Pending saves are dropped,
+// and changes made while the app closes aren't saved.
+func (a *App) stopSession() {
+	a.sessionMu.Lock()
+	if a.sessionSave != nil {
+		a.sessionSave.Stop()
+		a.sessionSave = nil
+	}
+	store := a.sessionStore
+	a.sessionStore = nil
+	a.sessionMu.Unlock()
+
+	if store != nil {
+		a.saveSession(store)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/winramp/winramp/internal/audio"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// minStreamListen is how long a stream must play to count as listened to,
+// so failed connections and quick channel flicks stay out of the history
+const minStreamListen = 10 * time.Second
+
+// streamListen is the stream session in progress
+type streamListen struct {
+	track        *domain.Track
+	name         string // Station name, before any now-playing title
+	started      time.Time
+	playingSince time.Time // Zero while paused or stopped
+	listened     time.Duration
+}
+
+// onStreamTrackChanged ends the listening session for the previous stream
+// and starts one when the new track is a stream
+func (a *App) onStreamTrackChanged(track *domain.Track) {
+	// The station sent what it is now playing; the session goes on
+	a.streamMu.Lock()
+	same := a.streamListen != nil && a.streamListen.track == track
+	a.streamMu.Unlock()
+	if same {
+		return
+	}
+
+	a.finishStreamListen()
+	if track.GetSource().Kind != domain.SourceStream {
+		return
+	}
+
+	now := time.Now()
+	listen := &streamListen{track: track, name: track.GetDisplayTitle(), started: now}
+	if a.player.GetState() == audio.StatePlaying {
+		listen.playingSince = now
+	}
+
+	a.streamMu.Lock()
+	a.streamListen = listen
+	a.streamMu.Unlock()
+}
+
+// onStreamStateChanged counts only the time a stream is actually playing
+func (a *App) onStreamStateChanged(state audio.PlayerState) {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+
+	listen := a.streamListen
+	if listen == nil {
+		return
+	}
+
+	playing := state == audio.StatePlaying
+	switch {
+	case playing && listen.playingSince.IsZero():
+		listen.playingSince = time.Now()
+	case !playing && !listen.playingSince.IsZero():
+		listen.listened += time.Since(listen.playingSince)
+		listen.playingSince = time.Time{}
+	}
+}
+
+// finishStreamListen records the stream session in progress, if any, in
+// the stream's history
+func (a *App) finishStreamListen() {
+	a.streamMu.Lock()
+	listen := a.streamListen
+	a.streamListen = nil
+	a.streamMu.Unlock()
+
+	if listen == nil {
+		return
+	}
+	if !listen.playingSince.IsZero() {
+		listen.listened += time.Since(listen.playingSince)
+	}
+	if listen.listened < minStreamListen {
+		return
+	}
+
+	station, err := a.streamStation(listen.track.FilePath, listen.name)
+	if err != nil {
+		logger.Warn("Failed to record stream listen", logger.String("url", listen.track.FilePath), logger.Error(err))
+		return
+	}
+	station.RecordListen(listen.started, listen.listened)
+	if err := a.streams.Save(station); err != nil {
+		logger.Warn("Failed to record stream listen", logger.String("url", station.URL), logger.Error(err))
+	}
+}
+
+// PlayStream connects to an internet radio station or other stream by URL
+// and plays it. Stations that send what they are playing update the
+// track's title and artist as songs change.
+func (a *App) PlayStream(url string) error {
+	stream, err := a.streamMgr.OpenStream(a.ctx, strings.TrimSpace(url))
+	if err != nil {
+		return err
+	}
+	if err := a.player.LoadStream(stream); err != nil {
+		return err
+	}
+	return a.player.Play()
+}
+
+// streamStation returns the saved station for a URL, or a new one with the
+// given name
+func (a *App) streamStation(url, name string) (*domain.StreamStation, error) {
+	station, err := a.streams.FindByURL(url)
+	if errors.Is(err, domain.ErrStreamNotFound) {
+		return domain.NewStreamStation(url, name)
+	}
+	return station, err
+}
+
+// GetStreamHistory returns the streams played most recently, with how long
+// each has been listened to
+func (a *App) GetStreamHistory(limit int) ([]map[string]interface{}, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	stations, err := a.streams.FindRecent(limit)
+	if err != nil {
+		return nil, err
+	}
+	return streamStationsToMaps(stations), nil
+}
+
+// GetFavoriteStreams returns the streams marked as favorites
+func (a *App) GetFavoriteStreams() ([]map[string]interface{}, error) {
+	stations, err := a.streams.FindFavorites()
+	if err != nil {
+		return nil, err
+	}
+	return streamStationsToMaps(stations), nil
+}
+
+// SetStreamFavorite marks a stream as a favorite or clears the mark. The
+// name is used for streams not played before, and to rename favorites.
+func (a *App) SetStreamFavorite(url, name string, favorite bool) error {
+	station, err := a.streamStation(url, name)
+	if err != nil {
+		return err
+	}
+	if favorite && name != "" {
+		station.Name = name
+	}
+	station.Favorite = favorite
+	return a.streams.Save(station)
+}
+
+// RemoveStream forgets a stream's history and favorite mark
+func (a *App) RemoveStream(url string) error {
+	return a.streams.Delete(url)
+}
+
+func streamStationsToMaps(stations []*domain.StreamStation) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(stations))
+	for i, station := range stations {
+		entry := map[string]interface{}{
+			"url":        station.URL,
+			"name":       station.GetDisplayName(),
+			"favorite":   station.Favorite,
+			"playCount":  station.PlayCount,
+			"listenTime": station.ListenTime.Seconds(),
+		}
+		if station.LastPlayedAt != nil {
+			entry["lastPlayedAt"] = *station.LastPlayedAt
+		}
+		result[i] = entry
+	}
+	return result
+}
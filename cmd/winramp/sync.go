@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// deviceLatency returns the measured extra latency of the output device in
+// use. Config keys are lowercased, so device IDs are matched that way.
+func (a *App) deviceLatency() time.Duration {
+	return a.config.Audio.DeviceLatency[strings.ToLower(a.config.Audio.OutputDevice)]
+}
+
+// applySyncOffset gives the player the global offset plus the device's own
+// and tells the frontend through "audio:syncOffsetChanged"
+func (a *App) applySyncOffset() {
+	a.player.SetSyncOffset(a.config.Audio.SyncOffset + a.deviceLatency())
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, "audio:syncOffsetChanged", a.GetSyncOffset())
+	}
+}
+
+// GetSyncOffset returns how far lyrics and visualization should run behind
+// the playback position to match what is heard, in seconds, with the parts
+// that make it up
+func (a *App) GetSyncOffset() map[string]interface{} {
+	total := a.player.SyncOffset()
+	extra := a.config.Audio.SyncOffset + a.deviceLatency()
+	return map[string]interface{}{
+		"offset":        total.Seconds(),
+		"outputLatency": (total - extra).Seconds(),
+		"userOffset":    a.config.Audio.SyncOffset.Seconds(),
+		"deviceLatency": a.deviceLatency().Seconds(),
+		"device":        a.config.Audio.OutputDevice,
+	}
+}
+
+// SetSyncOffset sets the global extra latency in seconds, for adjusting by
+// ear. It may be negative to pull lyrics earlier.
+func (a *App) SetSyncOffset(seconds float64) error {
+	offset := time.Duration(seconds * float64(time.Second))
+	a.config.Audio.SyncOffset = offset
+	a.config.Set("audio.sync_offset", offset)
+	a.applySyncOffset()
+	return a.config.Save()
+}
+
+// SetDeviceLatency records the measured extra latency of a device in
+// seconds, "" meaning the one in use. It applies whenever that device is
+// selected, on top of the global offset.
+func (a *App) SetDeviceLatency(deviceID string, seconds float64) error {
+	if deviceID == "" {
+		deviceID = a.config.Audio.OutputDevice
+	}
+
+	latencies := make(map[string]time.Duration, len(a.config.Audio.DeviceLatency)+1)
+	for id, latency := range a.config.Audio.DeviceLatency {
+		latencies[id] = latency
+	}
+	latencies[strings.ToLower(deviceID)] = time.Duration(seconds * float64(time.Second))
+
+	a.config.Audio.DeviceLatency = latencies
+	a.config.Set("audio.device_latency", latencies)
+	a.applySyncOffset()
+	return a.config.Save()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/winramp/winramp/internal/audio"
+	"github.com/winramp/winramp/internal/config"
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// transitionRuleFromConfig checks a transition rule from the config and
+// builds the player's rule from it
+func transitionRuleFromConfig(ruleConfig config.TransitionRuleConfig) (audio.TransitionRule, error) {
+	transition, err := audio.ParseTransition(ruleConfig.Transition)
+	if err != nil {
+		return audio.TransitionRule{}, fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+	if ruleConfig.GapSeconds < 0 {
+		return audio.TransitionRule{}, fmt.Errorf("%w: gap must not be negative", domain.ErrInvalidInput)
+	}
+
+	rule := audio.TransitionRule{
+		Name:       ruleConfig.Name,
+		Transition: transition,
+		Gap:        time.Duration(ruleConfig.GapSeconds * float64(time.Second)),
+	}
+	for _, c := range ruleConfig.Conditions {
+		rule.Rules.Conditions = append(rule.Rules.Conditions, domain.RuleCondition{
+			Field:    c.Field,
+			Operator: c.Operator,
+			Value:    c.Value,
+			AndOr:    c.AndOr,
+		})
+	}
+	if len(rule.Rules.Conditions) == 0 {
+		return audio.TransitionRule{}, fmt.Errorf("%w: a transition rule needs conditions", domain.ErrInvalidInput)
+	}
+	if err := rule.Rules.Validate(); err != nil {
+		return audio.TransitionRule{}, fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+	return rule, nil
+}
+
+// GetTransitionRules returns the rules that set the transition between
+// tracks they both match, such as gapless for classical music, in the
+// order they are checked
+func (a *App) GetTransitionRules() []config.TransitionRuleConfig {
+	return a.config.Audio.Transitions.Rules
+}
+
+// SetTransitionRules replaces the transition rules. The first rule both
+// tracks match decides the transition between them; tracks no rule
+// matches get the same-album or different-album transition. Nothing is
+// saved if any rule is invalid.
+func (a *App) SetTransitionRules(rules []config.TransitionRuleConfig) error {
+	for i, ruleConfig := range rules {
+		if _, err := transitionRuleFromConfig(ruleConfig); err != nil {
+			return fmt.Errorf("rule %d (%s): %w", i+1, ruleConfig.Name, err)
+		}
+	}
+
+	a.config.Audio.Transitions.Rules = rules
+	a.config.Set("audio.transitions.rules", rules)
+	a.player.SetTransitionPolicy(a.transitionPolicy())
+	return a.config.Save()
+}
+
+// PreviewTransitions shows how a list of tracks would lead into each
+// other: for each track after the first, the transition into it, the gap
+// before it in seconds and the rule that decided, if any
+func (a *App) PreviewTransitions(trackIDs []string) ([]map[string]interface{}, error) {
+	tracks, err := a.resolveTracks(trackIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := a.transitionPolicy()
+	preview := make([]map[string]interface{}, 0, max(len(tracks)-1, 0))
+	for i := 1; i < len(tracks); i++ {
+		choice := policy.Explain(tracks[i-1], tracks[i])
+		preview = append(preview, map[string]interface{}{
+			"fromId":     tracks[i-1].ID,
+			"toId":       tracks[i].ID,
+			"transition": string(choice.Transition),
+			"gap":        choice.Gap.Seconds(),
+			"rule":       choice.Rule,
+		})
+	}
+	return preview, nil
+}
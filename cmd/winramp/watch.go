@@ -0,0 +1,81 @@
+package main
+
+import (
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
+	"github.com/winramp/winramp/internal/library"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// startWatching keeps the library up to date with changes to the watch
+// folders' files while the app runs, when enabled
+func (a *App) startWatching() {
+	if !a.config.Library.WatchForChanges {
+		return
+	}
+
+	watcher, err := library.NewWatcher(a.libraryMgr.scanner, a.trackRepo)
+	if err != nil {
+		logger.Warn("Failed to start watching folders", logger.Error(err))
+		return
+	}
+	a.watcher = watcher
+	events.Subscribe(a.bus, library.TopicFilesChanged, a.onFilesChanged)
+	for _, path := range a.config.Library.WatchFolders {
+		a.watchPath(path)
+	}
+	watcher.Start(a.ctx)
+}
+
+// watchPath starts watching a watch folder with its saved settings
+func (a *App) watchPath(path string) {
+	if a.watcher == nil {
+		return
+	}
+	folder := a.watchFolder(path)
+	if !folder.IsEnabled {
+		return
+	}
+	if err := a.watcher.Watch(folder); err != nil {
+		logger.Warn("Failed to watch folder", logger.String("path", path), logger.Error(err))
+	}
+}
+
+// stopWatching stops watching the watch folders
+func (a *App) stopWatching() {
+	if a.watcher == nil {
+		return
+	}
+	if err := a.watcher.Close(); err != nil {
+		logger.Warn("Failed to stop watching folders", logger.Error(err))
+	}
+}
+
+// onFilesChanged follows up changes the watcher made to the library and
+// tells the frontend which tracks they touched
+func (a *App) onFilesChanged(changes *library.WatchChanges) {
+	for _, track := range changes.Added {
+		a.suggestions.Update(track)
+	}
+	for _, track := range changes.Updated {
+		a.suggestions.Update(track)
+	}
+	a.playlistMgr.LibraryChanged()
+
+	runtime.EventsEmit(a.ctx, library.TopicFilesChanged.Name(), map[string]interface{}{
+		"added":   a.tracksToMaps(changes.Added),
+		"updated": a.tracksToMaps(changes.Updated),
+		"removed": trackIDs(changes.Removed),
+	})
+}
+
+// trackIDs returns the IDs of tracks, in order
+func trackIDs(tracks []*domain.Track) []string {
+	ids := make([]string, len(tracks))
+	for i, track := range tracks {
+		ids[i] = track.ID
+	}
+	return ids
+}
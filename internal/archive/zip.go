@@ -0,0 +1,138 @@
+// Package archive reads the files inside zip archives, the way mixes and
+// albums are often distributed, so they can be played without unpacking
+// them first.
+package archive
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrEntryNotFound is returned when an archive has no file of the name asked
+// for
+var ErrEntryNotFound = errors.New("archive entry not found")
+
+// Entry is a file inside an archive
+type Entry struct {
+	Name     string    `json:"name"` // Path inside the archive, with forward slashes
+	Size     int64     `json:"size"` // Uncompressed size in bytes
+	Modified time.Time `json:"modified"`
+}
+
+// List returns the files in a zip archive in the order they are stored,
+// leaving out folders
+func List(archivePath string) ([]Entry, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer r.Close()
+
+	entries := make([]Entry, 0, len(r.File))
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		entries = append(entries, Entry{
+			Name:     f.Name,
+			Size:     int64(f.UncompressedSize64),
+			Modified: f.Modified,
+		})
+	}
+	return entries, nil
+}
+
+// Open opens a file in a zip archive for reading and seeking, as decoders
+// need. Stored files are read in place; compressed ones are inflated into a
+// temporary file removed on Close.
+func Open(archivePath, name string) (io.ReadSeekCloser, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer r.Close()
+
+	var entry *zip.File
+	for _, f := range r.File {
+		if f.Name == name {
+			entry = f
+			break
+		}
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, name)
+	}
+
+	if entry.Method == zip.Store {
+		return openStored(archivePath, entry)
+	}
+	return inflate(entry)
+}
+
+// storedEntry reads an uncompressed entry straight from the archive file
+type storedEntry struct {
+	*io.SectionReader
+	file *os.File
+}
+
+func (e *storedEntry) Close() error {
+	return e.file.Close()
+}
+
+func openStored(archivePath string, entry *zip.File) (io.ReadSeekCloser, error) {
+	offset, err := entry.DataOffset()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	return &storedEntry{
+		SectionReader: io.NewSectionReader(file, offset, int64(entry.UncompressedSize64)),
+		file:          file,
+	}, nil
+}
+
+// inflatedEntry is a compressed entry unpacked into a temporary file
+type inflatedEntry struct {
+	*os.File
+}
+
+// Close closes the temporary file and removes it
+func (e *inflatedEntry) Close() error {
+	err := e.File.Close()
+	if removeErr := os.Remove(e.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+func inflate(entry *zip.File) (io.ReadSeekCloser, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive entry: %w", err)
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp("", "winramp-archive-*"+path.Ext(entry.Name))
+	if err != nil {
+		return nil, err
+	}
+	inflated := &inflatedEntry{File: tmp}
+	if _, err := io.Copy(tmp, rc); err != nil {
+		inflated.Close()
+		return nil, fmt.Errorf("failed to unpack archive entry: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		inflated.Close()
+		return nil, err
+	}
+	return inflated, nil
+}
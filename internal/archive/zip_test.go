@@ -0,0 +1,71 @@
+package archive
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeZip writes an archive holding one stored and one compressed file
+// and a folder
+func writeZip(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "mix.zip")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	w := zip.NewWriter(file)
+	_, err = w.Create("Mix/")
+	require.NoError(t, err)
+	for _, f := range []struct {
+		name   string
+		method uint16
+	}{
+		{"Mix/01 Intro.wav", zip.Store},
+		{"Mix/02 Outro.wav", zip.Deflate},
+	} {
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: f.name, Method: f.method})
+		require.NoError(t, err)
+		_, err = fw.Write([]byte("0123456789" + f.name))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return path
+}
+
+func TestList(t *testing.T) {
+	entries, err := List(writeZip(t))
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "folders are left out")
+	assert.Equal(t, "Mix/01 Intro.wav", entries[0].Name)
+	assert.EqualValues(t, 26, entries[0].Size)
+
+	_, err = List(filepath.Join(t.TempDir(), "gone.zip"))
+	assert.Error(t, err)
+}
+
+func TestOpen(t *testing.T) {
+	path := writeZip(t)
+
+	for _, name := range []string{"Mix/01 Intro.wav", "Mix/02 Outro.wav"} {
+		t.Run(name, func(t *testing.T) {
+			r, err := Open(path, name)
+			require.NoError(t, err)
+
+			_, err = r.Seek(5, io.SeekStart)
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, "56789"+name, string(data))
+			require.NoError(t, r.Close())
+		})
+	}
+
+	_, err := Open(path, "Mix/03 Missing.wav")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+}
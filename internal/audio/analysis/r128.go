@@ -0,0 +1,281 @@
+// Package analysis measures the loudness of decoded audio the way EBU R128
+// (ITU-R BS.1770) defines it, for ReplayGain 2.0 track and album gain.
+package analysis
+
+import (
+	"errors"
+	"math"
+
+	"github.com/winramp/winramp/internal/audio/decoder"
+)
+
+const (
+	// ReferenceLoudness is the level ReplayGain 2.0 brings tracks to, in LUFS
+	ReferenceLoudness = -18.0
+
+	// absoluteGate leaves silence out of the integrated loudness, in LUFS
+	absoluteGate = -70.0
+	// relativeGate leaves out blocks this far below the ungated loudness, in LU
+	relativeGate = -10.0
+
+	// blockSteps is how many 100ms steps a 400ms gating block spans; blocks
+	// overlap by 75%
+	blockSteps = 4
+)
+
+// ErrSilent is returned when audio has no gated blocks to measure
+var ErrSilent = errors.New("audio is silent")
+
+// Loudness is the measurement of a stretch of audio: the mean square of
+// each gating block and the sample peak. Measurements of an album's tracks
+// can be pooled, as ReplayGain 2.0 album gain requires.
+type Loudness struct {
+	blocks []float64 // Channel-weighted mean square of each gating block
+	peak   float64
+}
+
+// Integrated returns the gated loudness in LUFS, or -Inf for silence
+func (l *Loudness) Integrated() float64 {
+	var sum float64
+	var n int
+	for _, block := range l.blocks {
+		if energyToLUFS(block) > absoluteGate {
+			sum += block
+			n++
+		}
+	}
+	if n == 0 {
+		return math.Inf(-1)
+	}
+
+	threshold := energyToLUFS(sum/float64(n)) + relativeGate
+	sum, n = 0, 0
+	for _, block := range l.blocks {
+		if loudness := energyToLUFS(block); loudness > absoluteGate && loudness > threshold {
+			sum += block
+			n++
+		}
+	}
+	return energyToLUFS(sum / float64(n))
+}
+
+// Gain returns the ReplayGain 2.0 gain in dB, or 0 for silence
+func (l *Loudness) Gain() float64 {
+	loudness := l.Integrated()
+	if math.IsInf(loudness, -1) {
+		return 0
+	}
+	return ReferenceLoudness - loudness
+}
+
+// Peak returns the highest absolute sample value
+func (l *Loudness) Peak() float64 {
+	return l.peak
+}
+
+// Combine pools measurements, as of an album's tracks, so the result is the
+// loudness of them played one after another
+func Combine(measurements []*Loudness) *Loudness {
+	combined := &Loudness{}
+	for _, m := range measurements {
+		combined.blocks = append(combined.blocks, m.blocks...)
+		combined.peak = math.Max(combined.peak, m.peak)
+	}
+	return combined
+}
+
+// Measure reads a decoder to the end and measures its loudness
+func Measure(dec decoder.Decoder) (*Loudness, error) {
+	format := dec.Format()
+	meter, err := NewMeter(format.SampleRate, format.Channels)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := make([]float32, 4096*format.Channels)
+	for {
+		n, err := dec.Decode(buffer)
+		if n > 0 {
+			meter.Write(buffer[:n*format.Channels])
+		}
+		if errors.Is(err, decoder.ErrEndOfStream) || (err == nil && n == 0) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	loudness := meter.Loudness()
+	if math.IsInf(loudness.Integrated(), -1) {
+		return nil, ErrSilent
+	}
+	return loudness, nil
+}
+
+// Meter measures audio written to it in interleaved blocks
+type Meter struct {
+	channels []channelFilter
+	weights  []float64
+	step     int       // Frames in 100ms
+	frames   int       // Frames into the current step
+	sums     []float64 // Sum of squares of each channel in the current step
+	steps    []float64 // Mean squares of the last steps, up to blockSteps
+	result   Loudness
+}
+
+// NewMeter creates a meter for audio of a sample rate and channel count
+func NewMeter(sampleRate, channels int) (*Meter, error) {
+	if sampleRate <= 0 || channels <= 0 {
+		return nil, errors.New("invalid audio format")
+	}
+
+	m := &Meter{
+		channels: make([]channelFilter, channels),
+		weights:  channelWeights(channels),
+		step:     max(sampleRate/10, 1),
+		sums:     make([]float64, channels),
+	}
+	for i := range m.channels {
+		m.channels[i] = newChannelFilter(float64(sampleRate))
+	}
+	return m, nil
+}
+
+// Write measures interleaved samples
+func (m *Meter) Write(samples []float32) {
+	channels := len(m.channels)
+	for i := 0; i+channels <= len(samples); i += channels {
+		for ch := 0; ch < channels; ch++ {
+			v := float64(samples[i+ch])
+			m.result.peak = math.Max(m.result.peak, math.Abs(v))
+			filtered := m.channels[ch].process(v)
+			m.sums[ch] += filtered * filtered
+		}
+
+		m.frames++
+		if m.frames == m.step {
+			m.endStep()
+		}
+	}
+}
+
+// endStep closes a 100ms step, and with it the gating block ending there
+func (m *Meter) endStep() {
+	var energy float64
+	for ch, sum := range m.sums {
+		energy += m.weights[ch] * sum / float64(m.frames)
+		m.sums[ch] = 0
+	}
+	m.frames = 0
+
+	m.steps = append(m.steps, energy)
+	if len(m.steps) > blockSteps {
+		m.steps = m.steps[1:]
+	}
+	if len(m.steps) == blockSteps {
+		var block float64
+		for _, step := range m.steps {
+			block += step
+		}
+		m.result.blocks = append(m.result.blocks, block/blockSteps)
+	}
+}
+
+// Loudness returns the measurement of everything written so far
+func (m *Meter) Loudness() *Loudness {
+	result := &Loudness{
+		blocks: append([]float64(nil), m.result.blocks...),
+		peak:   m.result.peak,
+	}
+
+	// Audio shorter than one block is measured as one short block
+	if frames := len(m.steps)*m.step + m.frames; len(result.blocks) == 0 && frames > 0 {
+		var total float64
+		for _, step := range m.steps {
+			total += step * float64(m.step)
+		}
+		for ch, sum := range m.sums {
+			total += m.weights[ch] * sum
+		}
+		result.blocks = []float64{total / float64(frames)}
+	}
+	return result
+}
+
+// channelWeights returns BS.1770's weight for each channel: surrounds count
+// for more and the LFE channel of 5.1 is left out
+func channelWeights(channels int) []float64 {
+	weights := make([]float64, channels)
+	for i := range weights {
+		weights[i] = 1
+	}
+	switch channels {
+	case 5: // L R C Ls Rs
+		weights[3], weights[4] = 1.41, 1.41
+	case 6: // L R C LFE Ls Rs
+		weights[3], weights[4], weights[5] = 0, 1.41, 1.41
+	}
+	return weights
+}
+
+func energyToLUFS(energy float64) float64 {
+	if energy <= 0 {
+		return math.Inf(-1)
+	}
+	return -0.691 + 10*math.Log10(energy)
+}
+
+// biquad is a second-order filter in direct form I
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// channelFilter is the K-weighting filter: a high shelf modelling the
+// head, then a high pass
+type channelFilter struct {
+	shelf, highPass biquad
+}
+
+// newChannelFilter designs the K-weighting filter for a sample rate, so
+// rates other than 48kHz are weighted the same
+func newChannelFilter(sampleRate float64) channelFilter {
+	var f channelFilter
+
+	f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / sampleRate)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	f.shelf = biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / sampleRate)
+	a0 = 1 + k/q + k*k
+	f.highPass = biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	return f
+}
+
+func (f *channelFilter) process(x float64) float64 {
+	return f.highPass.process(f.shelf.process(x))
+}
@@ -0,0 +1,79 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sine returns interleaved frames of a tone at a level in dBFS on every
+// channel
+func sine(sampleRate, channels int, freq, dbfs, seconds float64) []float32 {
+	amplitude := math.Pow(10, dbfs/20)
+	frames := int(seconds * float64(sampleRate))
+	samples := make([]float32, frames*channels)
+	for i := 0; i < frames; i++ {
+		v := float32(amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)))
+		for ch := 0; ch < channels; ch++ {
+			samples[i*channels+ch] = v
+		}
+	}
+	return samples
+}
+
+func TestMeterReferenceTone(t *testing.T) {
+	// EBU Tech 3341: a 1kHz stereo tone at -23dBFS measures -23 LUFS,
+	// whatever the sample rate
+	for _, rate := range []int{44100, 48000, 96000} {
+		meter, err := NewMeter(rate, 2)
+		require.NoError(t, err)
+		meter.Write(sine(rate, 2, 1000, -23, 20))
+
+		loudness := meter.Loudness()
+		assert.InDelta(t, -23, loudness.Integrated(), 0.1, "%d Hz", rate)
+		assert.InDelta(t, 5, loudness.Gain(), 0.1, "%d Hz", rate)
+		assert.InDelta(t, math.Pow(10, -23.0/20), loudness.Peak(), 0.001)
+	}
+}
+
+func TestMeterGating(t *testing.T) {
+	// Tech 3341 test 3: -36, -23 and -36dBFS tones for 10, 60 and 10s;
+	// the relative gate leaves the quiet parts out
+	meter, err := NewMeter(48000, 2)
+	require.NoError(t, err)
+	meter.Write(sine(48000, 2, 1000, -36, 10))
+	meter.Write(sine(48000, 2, 1000, -23, 60))
+	meter.Write(sine(48000, 2, 1000, -36, 10))
+	assert.InDelta(t, -23, meter.Loudness().Integrated(), 0.1)
+
+	// Silence is below the absolute gate
+	silent, err := NewMeter(48000, 2)
+	require.NoError(t, err)
+	silent.Write(make([]float32, 48000*2*2))
+	assert.True(t, math.IsInf(silent.Loudness().Integrated(), -1))
+	assert.Zero(t, silent.Loudness().Gain())
+}
+
+func TestCombine(t *testing.T) {
+	loud, err := NewMeter(48000, 2)
+	require.NoError(t, err)
+	loud.Write(sine(48000, 2, 1000, -14, 10))
+	quiet, err := NewMeter(48000, 2)
+	require.NoError(t, err)
+	quiet.Write(sine(48000, 2, 1000, -20, 10))
+
+	// Pooled blocks are averaged as energy, so the album sits nearer the
+	// loud track than the mean of the two levels
+	album := Combine([]*Loudness{loud.Loudness(), quiet.Loudness()})
+	assert.InDelta(t, -15.97, album.Integrated(), 0.1)
+	assert.InDelta(t, math.Pow(10, -14.0/20), album.Peak(), 0.001)
+}
+
+func TestMeterShortAudio(t *testing.T) {
+	meter, err := NewMeter(48000, 1)
+	require.NoError(t, err)
+	meter.Write(sine(48000, 1, 1000, -20, 0.25))
+	assert.InDelta(t, -23, meter.Loudness().Integrated(), 0.5)
+}
@@ -0,0 +1,467 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// maxChapterData bounds how much of a tag block is read looking for
+// chapters, as comment blocks may carry embedded cover art
+const maxChapterData = 16 << 20
+
+// errNoChapterData is returned for containers whose chapters live in a
+// block that is too large to read
+var errNoChapterData = errors.New("chapter data too large")
+
+// chapter is a chapter start read from a file's tags
+type chapter struct {
+	start time.Duration
+	title string
+}
+
+// ReadChapters returns the chapters embedded in a track as chapter markers,
+// from ID3v2 CHAP frames, an MP4 Nero chpl box or Vorbis CHAPTERxx
+// comments. Live streams and formats without chapters have none.
+func (r *SourceResolver) ReadChapters(ctx context.Context, track *domain.Track) ([]*domain.TrackMarker, error) {
+	source := track.GetSource()
+	if source.Kind == domain.SourceStream {
+		return nil, nil
+	}
+
+	resolved, err := r.Resolve(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if resolved.Stream != nil {
+		resolved.Stream.Close()
+		return nil, nil
+	}
+	if closer, ok := resolved.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	return ReadChapters(resolved.Reader, track.ID)
+}
+
+// ReadChapters reads the chapters embedded in an audio file, picking the
+// tag format from the file's signature
+func ReadChapters(r io.ReadSeeker, trackID string) ([]*domain.TrackMarker, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var chapters []chapter
+	var err error
+	switch {
+	case bytes.HasPrefix(header, []byte("ID3")):
+		chapters, err = readID3Chapters(r)
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		chapters, err = readFLACChapters(r)
+	case bytes.HasPrefix(header, []byte("OggS")):
+		chapters, err = readOggChapters(r)
+	case bytes.Equal(header[4:8], []byte("ftyp")):
+		chapters, err = readMP4Chapters(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].start < chapters[j].start })
+
+	markers := make([]*domain.TrackMarker, 0, len(chapters))
+	for _, ch := range chapters {
+		marker, err := domain.NewTrackMarker(trackID, domain.MarkerTypeChapter, ch.start, ch.title)
+		if err != nil {
+			return nil, err
+		}
+		markers = append(markers, marker)
+	}
+	return markers, nil
+}
+
+// readID3Chapters reads the CHAP frames of an ID3v2.3 or v2.4 tag, titled
+// by their embedded TIT2 frames
+func readID3Chapters(r io.Reader) ([]chapter, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	version := header[3]
+	if version != 3 && version != 4 {
+		return nil, nil // ID3v2.2 has no chapters
+	}
+
+	tag := make([]byte, syncsafe(header[6:10]))
+	if _, err := io.ReadFull(r, tag); err != nil {
+		return nil, fmt.Errorf("%w: truncated ID3 tag", domain.ErrTrackCorrupted)
+	}
+
+	// Skip the extended header
+	if header[5]&0x40 != 0 && len(tag) >= 4 {
+		size := int(binary.BigEndian.Uint32(tag))
+		if version == 3 {
+			size += 4
+		} else {
+			size = syncsafe(tag[:4])
+		}
+		tag = tag[min(size, len(tag)):]
+	}
+
+	var chapters []chapter
+	for _, frame := range id3Frames(tag, version) {
+		if frame.id != "CHAP" {
+			continue
+		}
+		if ch, ok := parseCHAP(frame.data, version); ok {
+			chapters = append(chapters, ch)
+		}
+	}
+	return chapters, nil
+}
+
+type id3Frame struct {
+	id   string
+	data []byte
+}
+
+// id3Frames splits tag data into frames, stopping at the padding
+func id3Frames(data []byte, version byte) []id3Frame {
+	var frames []id3Frame
+	for len(data) >= 10 && data[0] != 0 {
+		size := int(binary.BigEndian.Uint32(data[4:8]))
+		if version == 4 {
+			size = syncsafe(data[4:8])
+		}
+		if size > len(data)-10 {
+			break
+		}
+		frames = append(frames, id3Frame{id: string(data[:4]), data: data[10 : 10+size]})
+		data = data[10+size:]
+	}
+	return frames
+}
+
+// parseCHAP reads a CHAP frame: an element ID, the start and end times in
+// milliseconds, byte offsets, then sub-frames
+func parseCHAP(data []byte, version byte) (chapter, bool) {
+	end := bytes.IndexByte(data, 0)
+	if end < 0 || len(data) < end+17 {
+		return chapter{}, false
+	}
+	data = data[end+1:]
+	ch := chapter{start: time.Duration(binary.BigEndian.Uint32(data)) * time.Millisecond}
+
+	for _, frame := range id3Frames(data[16:], version) {
+		if frame.id == "TIT2" {
+			ch.title = id3Text(frame.data)
+		}
+	}
+	return ch, true
+}
+
+// id3Text decodes a text frame's encoding byte and text
+func id3Text(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	encoding, text := data[0], data[1:]
+
+	switch encoding {
+	case 1, 2: // UTF-16 with a byte order mark, or big-endian
+		order := binary.ByteOrder(binary.BigEndian)
+		if len(text) >= 2 && encoding == 1 {
+			if text[0] == 0xFF && text[1] == 0xFE {
+				order = binary.LittleEndian
+			}
+			text = text[2:]
+		}
+		units := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			unit := order.Uint16(text[i:])
+			if unit == 0 {
+				break
+			}
+			units = append(units, unit)
+		}
+		return string(utf16.Decode(units))
+
+	case 3: // UTF-8
+		return strings.TrimRight(string(text), "\x00")
+	}
+
+	// ISO-8859-1
+	runes := make([]rune, 0, len(text))
+	for _, b := range text {
+		if b == 0 {
+			break
+		}
+		runes = append(runes, rune(b))
+	}
+	return string(runes)
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// readMP4Chapters reads the Nero chpl box in moov/udta, whose chapter
+// starts are in 100 ns units
+func readMP4Chapters(r io.ReadSeeker) ([]chapter, error) {
+	data, err := findMP4Box(r, -1, "moov", "udta", "chpl")
+	if err != nil || len(data) < 5 {
+		return nil, err
+	}
+	version := data[0]
+	data = data[4:]
+	if version != 0 {
+		if len(data) < 4 {
+			return nil, nil
+		}
+		data = data[4:]
+	}
+	if len(data) < 1 {
+		return nil, nil
+	}
+	count := int(data[0])
+	data = data[1:]
+
+	chapters := make([]chapter, 0, count)
+	for i := 0; i < count && len(data) >= 9; i++ {
+		start := binary.BigEndian.Uint64(data)
+		length := int(data[8])
+		data = data[9:]
+		if length > len(data) {
+			break
+		}
+		chapters = append(chapters, chapter{
+			start: time.Duration(start * 100),
+			title: string(data[:length]),
+		})
+		data = data[length:]
+	}
+	return chapters, nil
+}
+
+// findMP4Box walks down a path of nested boxes from the reader's position,
+// reading at most limit bytes at each level (-1 for all), and returns the
+// body of the last. It returns nil when the path isn't there.
+func findMP4Box(r io.ReadSeeker, limit int64, path ...string) ([]byte, error) {
+	header := make([]byte, 16)
+	for read := int64(0); limit < 0 || read+8 <= limit; {
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		size := int64(binary.BigEndian.Uint32(header))
+		name := string(header[4:8])
+		headerSize := int64(8)
+		switch size {
+		case 0: // Runs to the end
+			size = -1
+		case 1:
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return nil, nil
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size >= 0 && size < headerSize {
+			return nil, nil
+		}
+
+		body := int64(-1)
+		if size >= 0 {
+			body = size - headerSize
+		}
+		if name == path[0] {
+			if len(path) > 1 {
+				return findMP4Box(r, body, path[1:]...)
+			}
+			if body < 0 || body > maxChapterData {
+				return nil, errNoChapterData
+			}
+			data := make([]byte, body)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, nil
+			}
+			return data, nil
+		}
+
+		if body < 0 {
+			return nil, nil
+		}
+		if _, err := r.Seek(body, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		read += size
+	}
+	return nil, nil
+}
+
+// readFLACChapters reads the Vorbis comment block of a FLAC file
+func readFLACChapters(r io.ReadSeeker) ([]chapter, error) {
+	if _, err := r.Seek(4, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, fmt.Errorf("%w: truncated FLAC metadata", domain.ErrTrackCorrupted)
+		}
+		length := int64(binary.BigEndian.Uint32(header) & 0x00FFFFFF)
+
+		if header[0]&0x7F == 4 {
+			block := make([]byte, length)
+			if _, err := io.ReadFull(r, block); err != nil {
+				return nil, fmt.Errorf("%w: truncated FLAC metadata", domain.ErrTrackCorrupted)
+			}
+			return vorbisChapters(vorbisComments(block)), nil
+		}
+		if header[0]&0x80 != 0 {
+			return nil, nil
+		}
+		if _, err := r.Seek(length, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readOggChapters reads the comment header, the second packet of an Ogg
+// Vorbis or Opus stream
+func readOggChapters(r io.Reader) ([]chapter, error) {
+	header := make([]byte, 27)
+	segments := make([]byte, 255)
+	var packet []byte
+	packets := 0
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, nil
+		}
+		if !bytes.Equal(header[:4], []byte("OggS")) {
+			return nil, nil
+		}
+		table := segments[:header[26]]
+		if _, err := io.ReadFull(r, table); err != nil {
+			return nil, nil
+		}
+
+		for _, length := range table {
+			data := make([]byte, length)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, nil
+			}
+			if packets == 1 {
+				packet = append(packet, data...)
+				if len(packet) > maxChapterData {
+					return nil, errNoChapterData
+				}
+			}
+			if length < 255 {
+				packets++
+				if packets == 2 {
+					return oggCommentChapters(packet), nil
+				}
+			}
+		}
+	}
+}
+
+func oggCommentChapters(packet []byte) []chapter {
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x03vorbis")):
+		packet = packet[7:]
+	case bytes.HasPrefix(packet, []byte("OpusTags")):
+		packet = packet[8:]
+	default:
+		return nil
+	}
+	return vorbisChapters(vorbisComments(packet))
+}
+
+// vorbisComments reads a Vorbis comment list into upper-cased keys
+func vorbisComments(data []byte) map[string]string {
+	comments := make(map[string]string)
+	if len(data) < 4 {
+		return comments
+	}
+	vendor := int(binary.LittleEndian.Uint32(data))
+	if 4+vendor+4 > len(data) {
+		return comments
+	}
+	data = data[4+vendor:]
+	count := int(binary.LittleEndian.Uint32(data))
+	data = data[4:]
+
+	for i := 0; i < count && len(data) >= 4; i++ {
+		length := int(binary.LittleEndian.Uint32(data))
+		data = data[4:]
+		if length > len(data) {
+			break
+		}
+		if key, value, ok := strings.Cut(string(data[:length]), "="); ok {
+			comments[strings.ToUpper(key)] = value
+		}
+		data = data[length:]
+	}
+	return comments
+}
+
+// vorbisChapters reads CHAPTERxx=HH:MM:SS.mmm and CHAPTERxxNAME=title
+// comments
+func vorbisChapters(comments map[string]string) []chapter {
+	var chapters []chapter
+	for key, value := range comments {
+		number, ok := strings.CutPrefix(key, "CHAPTER")
+		if !ok || number == "" || strings.Trim(number, "0123456789") != "" {
+			continue
+		}
+		start, ok := parseChapterTime(value)
+		if !ok {
+			continue
+		}
+		chapters = append(chapters, chapter{start: start, title: comments[key+"NAME"]})
+	}
+	return chapters
+}
+
+// parseChapterTime parses HH:MM:SS with optional fractional seconds
+func parseChapterTime(value string) (time.Duration, bool) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, false
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil || hours < 0 || minutes < 0 || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)), true
+}
@@ -0,0 +1,210 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadChapters(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"ID3v2.3", id3File(3)},
+		{"ID3v2.4", id3File(4)},
+		{"MP4", mp4File()},
+		{"FLAC", flacFile()},
+		{"Ogg Vorbis", oggFile("\x03vorbis")},
+		{"Opus", oggFile("OpusTags")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markers, err := ReadChapters(bytes.NewReader(tt.file), "t1")
+			require.NoError(t, err)
+			require.Len(t, markers, 2)
+
+			assert.Equal(t, "t1", markers[0].TrackID)
+			assert.Equal(t, time.Duration(0), markers[0].Position)
+			assert.Equal(t, "Intro", markers[0].Label)
+			assert.Equal(t, 90500*time.Millisecond, markers[1].Position)
+			assert.Equal(t, "Chapter Two", markers[1].Label)
+			assert.False(t, markers[1].IsRegion())
+		})
+	}
+}
+
+func TestReadChaptersNone(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"empty", nil},
+		{"WAV", append([]byte("RIFF\x00\x00\x00\x00WAVE"), make([]byte, 32)...)},
+		{"ID3 without chapters", id3Tag(3, id3FrameBytes(3, "TIT2", append([]byte{3}, "Song"...)))},
+		{"FLAC without comments", append([]byte("fLaC"), 0x80, 0, 0, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markers, err := ReadChapters(bytes.NewReader(tt.file), "t1")
+			require.NoError(t, err)
+			assert.Empty(t, markers)
+		})
+	}
+}
+
+func TestID3Text(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"latin-1", []byte{0, 'C', 0xE9, 0}, "Cé"},
+		{"UTF-16 LE", []byte{1, 0xFF, 0xFE, 'H', 0, 'i', 0, 0, 0}, "Hi"},
+		{"UTF-16 BE", []byte{2, 0, 'H', 0, 'i'}, "Hi"},
+		{"UTF-8", append([]byte{3}, "Café\x00"...), "Café"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, id3Text(tt.data))
+		})
+	}
+}
+
+func TestParseChapterTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"00:00:00.000", 0, true},
+		{"01:02:03.5", time.Hour + 2*time.Minute + 3500*time.Millisecond, true},
+		{"00:01:30", 90 * time.Second, true},
+		{"1:30", 0, false},
+		{"aa:00:00", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseChapterTime(tt.value)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// id3File builds an ID3 tag holding two chapters, out of order
+func id3File(version byte) []byte {
+	chap := func(id string, start uint32, title string) []byte {
+		var b bytes.Buffer
+		b.WriteString(id + "\x00")
+		binary.Write(&b, binary.BigEndian, [4]uint32{start, start + 1000, 0xFFFFFFFF, 0xFFFFFFFF})
+		b.Write(id3FrameBytes(version, "TIT2", append([]byte{3}, title...)))
+		return id3FrameBytes(version, "CHAP", b.Bytes())
+	}
+
+	frames := append(chap("ch1", 90500, "Chapter Two"), chap("ch0", 0, "Intro")...)
+	return append(id3Tag(version, frames), 0xFF, 0xFB, 0x90, 0x00)
+}
+
+func id3Tag(version byte, frames []byte) []byte {
+	frames = append(frames, make([]byte, 16)...) // Padding
+	return append([]byte{'I', 'D', '3', version, 0, 0}, append(syncsafeBytes(len(frames)), frames...)...)
+}
+
+func id3FrameBytes(version byte, id string, data []byte) []byte {
+	size := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	if version == 4 {
+		size = syncsafeBytes(len(data))
+	}
+	frame := append([]byte(id), size...)
+	return append(append(frame, 0, 0), data...)
+}
+
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+}
+
+// mp4File builds boxes with the chpl box after the media data
+func mp4File() []byte {
+	var chpl bytes.Buffer
+	chpl.Write([]byte{1, 0, 0, 0, 0, 0, 0, 0, 2})
+	for _, ch := range []struct {
+		start uint64
+		title string
+	}{{0, "Intro"}, {905_000_000, "Chapter Two"}} {
+		binary.Write(&chpl, binary.BigEndian, ch.start)
+		chpl.WriteByte(byte(len(ch.title)))
+		chpl.WriteString(ch.title)
+	}
+
+	udta := mp4Box("udta", mp4Box("chpl", chpl.Bytes()))
+	moov := mp4Box("moov", append(mp4Box("mvhd", make([]byte, 100)), udta...))
+	file := mp4Box("ftyp", []byte("M4B \x00\x00\x00\x00"))
+	file = append(file, mp4Box("mdat", make([]byte, 64))...)
+	return append(file, moov...)
+}
+
+func mp4Box(name string, body []byte) []byte {
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(box, name...), body...)
+}
+
+func vorbisCommentBlock() []byte {
+	comments := []string{
+		"TITLE=Book",
+		"CHAPTER002=00:01:30.500",
+		"chapter002name=Chapter Two",
+		"CHAPTER001=00:00:00.000",
+		"CHAPTER001NAME=Intro",
+	}
+
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, uint32(6))
+	b.WriteString("vendor")
+	binary.Write(&b, binary.LittleEndian, uint32(len(comments)))
+	for _, comment := range comments {
+		binary.Write(&b, binary.LittleEndian, uint32(len(comment)))
+		b.WriteString(comment)
+	}
+	return b.Bytes()
+}
+
+func flacFile() []byte {
+	flacBlock := func(blockType byte, last bool, data []byte) []byte {
+		if last {
+			blockType |= 0x80
+		}
+		return append([]byte{blockType, byte(len(data) >> 16), byte(len(data) >> 8), byte(len(data))}, data...)
+	}
+
+	file := []byte("fLaC")
+	file = append(file, flacBlock(0, false, make([]byte, 34))...)
+	file = append(file, flacBlock(1, false, make([]byte, 10))...)
+	return append(file, flacBlock(4, true, vorbisCommentBlock())...)
+}
+
+// oggFile builds Ogg pages whose comment packet spans two pages
+func oggFile(magic string) []byte {
+	page := func(segments []byte, data []byte) []byte {
+		header := make([]byte, 27)
+		copy(header, "OggS")
+		header[26] = byte(len(segments))
+		return append(append(header, segments...), data...)
+	}
+
+	// 255 bytes of a packet continue it onto the next segment
+	comment := append([]byte(magic), vorbisCommentBlock()...)
+	comment = append(comment, make([]byte, 300-len(comment))...)
+
+	file := page([]byte{30}, make([]byte, 30))
+	file = append(file, page([]byte{255}, comment[:255])...)
+	return append(file, page([]byte{45}, comment[255:])...)
+}
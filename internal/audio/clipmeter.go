@@ -0,0 +1,172 @@
+package audio
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// ClipReport is sent with EventClipping for each second of playback in
+// which samples went over full scale, and once more for the first clean
+// second after, so a clip indicator can light up and go out again
+type ClipReport struct {
+	Track   *domain.Track
+	Clipped int     // Samples over full scale in the second
+	PeakDB  float64 // Highest sample level in the second, in dBFS
+	GainDB  float64 // ReplayGain or leveling gain applied ahead of the output
+}
+
+// TrackClipping is how much one track has clipped
+type TrackClipping struct {
+	Track       *domain.Track
+	Clipped     int64
+	ClipSeconds int
+	PeakDB      float64
+	GainDB      float64 // Gain applied when it last clipped
+}
+
+// ClipStats totals clipping since they were last reset, to help tune the
+// preamp and equalizer
+type ClipStats struct {
+	Since       time.Time
+	Seconds     int // Seconds of playback measured
+	ClipSeconds int // Seconds in which samples clipped
+	Clipped     int64
+	PeakDB      float64         // Highest sample level seen, in dBFS
+	Tracks      []TrackClipping // Tracks that clipped, most first
+}
+
+// clipMeter counts samples over full scale on their way to the output.
+// Measuring is done by the playback goroutine; stats are read from others.
+type clipMeter struct {
+	mu      sync.Mutex
+	frames  int // Measured since the last report
+	clipped int
+	peak    float32
+	lit     bool // The last report had clipping, so a clear one is due
+
+	stats map[string]*TrackClipping
+	total ClipStats
+}
+
+func newClipMeter() *clipMeter {
+	m := &clipMeter{}
+	m.reset()
+	return m
+}
+
+// measure counts the clipped samples in a buffer about to be written. Once
+// a second's worth has been measured it returns a report when one is due.
+func (m *clipMeter) measure(samples []float32, channels, sampleRate int, track *domain.Track, gain float64) *ClipReport {
+	var peak float32
+	clipped := 0
+	for _, sample := range samples {
+		level := max(sample, -sample)
+		peak = max(peak, level)
+		if level > 1 {
+			clipped++
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.clipped += clipped
+	m.peak = max(m.peak, peak)
+	m.frames += len(samples) / max(channels, 1)
+	if m.frames < sampleRate {
+		return nil
+	}
+
+	report := &ClipReport{
+		Track:   track,
+		Clipped: m.clipped,
+		PeakDB:  levelToDB(float64(m.peak)),
+		GainDB:  levelToDB(gain),
+	}
+	m.record(report)
+	m.frames, m.clipped, m.peak = 0, 0, 0
+
+	if report.Clipped == 0 && !m.lit {
+		return nil
+	}
+	m.lit = report.Clipped > 0
+	return report
+}
+
+// record adds a second's report to the stats. Must be called with m.mu held.
+func (m *clipMeter) record(report *ClipReport) {
+	m.total.Seconds++
+	m.total.PeakDB = max(m.total.PeakDB, report.PeakDB)
+	if report.Clipped == 0 || report.Track == nil {
+		return
+	}
+
+	m.total.ClipSeconds++
+	m.total.Clipped += int64(report.Clipped)
+
+	track, ok := m.stats[report.Track.ID]
+	if !ok {
+		track = &TrackClipping{Track: report.Track, PeakDB: floorDB}
+		m.stats[report.Track.ID] = track
+	}
+	track.Clipped += int64(report.Clipped)
+	track.ClipSeconds++
+	track.PeakDB = max(track.PeakDB, report.PeakDB)
+	track.GainDB = report.GainDB
+}
+
+// snapshot returns the stats so far
+func (m *clipMeter) snapshot() ClipStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.total
+	stats.Tracks = make([]TrackClipping, 0, len(m.stats))
+	for _, track := range m.stats {
+		stats.Tracks = append(stats.Tracks, *track)
+	}
+	slices.SortFunc(stats.Tracks, func(a, b TrackClipping) int {
+		switch {
+		case a.Clipped > b.Clipped:
+			return -1
+		case a.Clipped < b.Clipped:
+			return 1
+		}
+		return 0
+	})
+	return stats
+}
+
+func (m *clipMeter) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.total = ClipStats{Since: time.Now(), PeakDB: floorDB}
+	m.stats = make(map[string]*TrackClipping)
+}
+
+// ClipStats returns how much playback has clipped since the stats were
+// last reset
+func (p *Player) ClipStats() ClipStats {
+	return p.clips.snapshot()
+}
+
+// ResetClipStats starts the clipping stats afresh, as after changing the
+// preamp or equalizer
+func (p *Player) ResetClipStats() {
+	p.clips.reset()
+}
+
+// floorDB stands in for the level of silence, which has none
+const floorDB = -120.0
+
+// levelToDB converts a linear level to dBFS, no lower than floorDB
+func levelToDB(level float64) float64 {
+	if level <= 0 {
+		return floorDB
+	}
+	return max(20*math.Log10(level), floorDB)
+}
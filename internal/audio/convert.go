@@ -0,0 +1,243 @@
+package audio
+
+import (
+	"math"
+
+	"github.com/winramp/winramp/internal/audio/output"
+)
+
+const (
+	// sincZeroCrossings is how many zero crossings of the sinc the resampling
+	// kernel spans on each side; more gives a steeper cutoff for more work
+	sincZeroCrossings = 16
+
+	// sincResolution is how many kernel values the table holds per zero
+	// crossing. Values in between are interpolated.
+	sincResolution = 256
+
+	// sincRolloff puts the cutoff a little below the lower of the two
+	// Nyquist frequencies, so the transition band doesn't alias
+	sincRolloff = 0.94
+
+	// kaiserBeta shapes the window, trading passband ripple against
+	// stopband rejection; 8 gives about 80 dB
+	kaiserBeta = 8.0
+)
+
+// sincTable holds one side of the windowed-sinc kernel
+var sincTable = newSincTable()
+
+func newSincTable() []float64 {
+	n := sincZeroCrossings * sincResolution
+	table := make([]float64, n+2) // The last two stay zero for interpolation at the edge
+	for i := 0; i < n; i++ {
+		x := float64(i) / sincResolution
+		table[i] = sinc(x) * kaiser(x/sincZeroCrossings)
+	}
+	return table
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// kaiser returns the Kaiser window at t, from -1 to 1
+func kaiser(t float64) float64 {
+	return besselI0(kaiserBeta*math.Sqrt(1-t*t)) / besselI0(kaiserBeta)
+}
+
+// besselI0 is the zeroth-order modified Bessel function of the first kind
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > sum*1e-12; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+	}
+	return sum
+}
+
+// rateConverter converts interleaved audio between sample rates, and
+// changes playback speed, with a windowed-sinc filter band-limited to the
+// lower of the two Nyquist frequencies. It keeps the input it still needs
+// between buffers, so a stream can be converted in pieces without clicks at
+// the joins. Output lags input by the kernel's reach, a fraction of a
+// millisecond that is held back until more input arrives.
+type rateConverter struct {
+	from     int
+	to       int
+	channels int
+
+	pos float64   // Position of the next output frame in buf, in input frames
+	buf []float32 // Input frames still needed, interleaved
+	out []float32
+	sum []float64
+}
+
+func newRateConverter(from, to, channels int) *rateConverter {
+	return &rateConverter{
+		from:     from,
+		to:       to,
+		channels: channels,
+		sum:      make([]float64, channels),
+	}
+}
+
+// newTrackConverter returns the converter a track of a sample rate needs to
+// play on an output at a speed, or nil if it plays as it is
+func newTrackConverter(rate int, format output.Format, speed float64) *rateConverter {
+	if rate <= 0 || format.SampleRate <= 0 || (rate == format.SampleRate && speed == 1.0) {
+		return nil
+	}
+	return newRateConverter(rate, format.SampleRate, format.Channels)
+}
+
+// converts reports whether the converter is for a pair of rates and a
+// channel count
+func (c *rateConverter) converts(from, to, channels int) bool {
+	return c.from == from && c.to == to && c.channels == channels
+}
+
+// process converts a buffer of samples, played at speed times the normal
+// rate. The returned slice is reused by the next call.
+func (c *rateConverter) process(samples []float32, speed float64) []float32 {
+	channels := c.channels
+	c.buf = append(c.buf, samples[:len(samples)/channels*channels]...)
+	frames := len(c.buf) / channels
+
+	// Input frames per output frame, and the cutoff relative to the input's
+	// Nyquist frequency: below 1 when the output can hold less
+	step := float64(c.from) / float64(c.to) * speed
+	cutoff := sincRolloff * math.Min(1, 1/step)
+	reach := sincZeroCrossings / cutoff // Input frames the kernel spans on each side
+	scale := cutoff * sincResolution    // Table entries per input frame
+
+	c.out = c.out[:0]
+	for ; c.pos+reach < float64(frames); c.pos += step {
+		first := max(int(math.Ceil(c.pos-reach)), 0)
+		last := int(c.pos + reach)
+		clear(c.sum)
+		for i := first; i <= last; i++ {
+			w := kernel(math.Abs(float64(i)-c.pos) * scale)
+			frame := c.buf[i*channels : (i+1)*channels]
+			for ch, v := range frame {
+				c.sum[ch] += w * float64(v)
+			}
+		}
+		for _, v := range c.sum {
+			c.out = append(c.out, float32(v*cutoff))
+		}
+	}
+
+	// Drop the frames no later output frame reaches
+	if used := int(c.pos - reach); used > 0 {
+		c.buf = c.buf[:copy(c.buf, c.buf[used*channels:])]
+		c.pos -= float64(used)
+	}
+
+	return c.out
+}
+
+// kernel looks up the windowed sinc at x table entries from its centre
+func kernel(x float64) float64 {
+	i := int(x)
+	if i >= len(sincTable)-1 {
+		return 0
+	}
+	frac := x - float64(i)
+	return sincTable[i] + (sincTable[i+1]-sincTable[i])*frac
+}
+
+// Downmix coefficients for centre and surround channels, which are mixed
+// into the front pair at -3 dB
+const surroundMix = 0.7071
+
+// remix converts interleaved samples between channel counts, writing into
+// dst, which is grown as needed and returned. Mono is copied to both front
+// channels. Multichannel audio in the standard WAVE order (L, R, C, LFE,
+// surrounds) is folded down to stereo with the LFE channel dropped and the
+// result scaled so it cannot clip. Other conversions keep the channels both
+// layouts share and leave any extra output channels silent.
+func remix(dst, samples []float32, from, to int) []float32 {
+	frames := len(samples) / from
+	if cap(dst) < frames*to {
+		dst = make([]float32, frames*to)
+	}
+	dst = dst[:frames*to]
+
+	switch {
+	case from == to:
+		copy(dst, samples)
+
+	case from == 1:
+		for i := 0; i < frames; i++ {
+			out := dst[i*to : (i+1)*to]
+			for c := range out {
+				out[c] = 0
+			}
+			out[0] = samples[i]
+			out[1%to] = samples[i]
+		}
+
+	case to == 1:
+		for i := 0; i < frames; i++ {
+			dst[i] = (samples[i*from] + samples[i*from+1]) / 2
+		}
+
+	case to == 2:
+		left, right, scale := downmixWeights(from)
+		for i := 0; i < frames; i++ {
+			in := samples[i*from : (i+1)*from]
+			var l, r float32
+			for c, s := range in {
+				l += s * left[c]
+				r += s * right[c]
+			}
+			dst[i*2] = l * scale
+			dst[i*2+1] = r * scale
+		}
+
+	default:
+		shared := min(from, to)
+		for i := 0; i < frames; i++ {
+			out := dst[i*to : (i+1)*to]
+			copy(out, samples[i*from:i*from+shared])
+			for c := shared; c < to; c++ {
+				out[c] = 0
+			}
+		}
+	}
+
+	return dst
+}
+
+// downmixWeights returns how much each input channel contributes to the
+// left and right outputs, and the scale that keeps the sum within range
+func downmixWeights(channels int) (left, right []float32, scale float32) {
+	left = make([]float32, channels)
+	right = make([]float32, channels)
+	left[0], right[1] = 1, 1
+
+	for c := 2; c < channels; c++ {
+		switch {
+		case c == 2 && channels != 4:
+			// Centre
+			left[c], right[c] = surroundMix, surroundMix
+		case c == 3 && channels >= 6:
+			// LFE is left out; speakers are expected to carry their own bass
+		case (c-channels)%2 == 0:
+			// Surround pairs alternate left and right, counted from the end
+			left[c] = surroundMix
+		default:
+			right[c] = surroundMix
+		}
+	}
+
+	sum := float32(0)
+	for _, w := range left {
+		sum += w
+	}
+	return left, right, 1 / sum
+}
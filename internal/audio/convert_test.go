@@ -0,0 +1,176 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/audio/decoder"
+	"github.com/winramp/winramp/internal/audio/dsp"
+	"github.com/winramp/winramp/internal/audio/output"
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// tone returns seconds of a sine as mono samples
+func tone(freq float64, rate int, seconds float64) []float32 {
+	samples := make([]float32, int(seconds*float64(rate)))
+	for i := range samples {
+		samples[i] = float32(0.5 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+	}
+	return samples
+}
+
+// rms measures the middle half of samples, clear of the filter's start
+func rms(samples []float32) float64 {
+	middle := samples[len(samples)/4 : len(samples)*3/4]
+	var sum float64
+	for _, v := range middle {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum / float64(len(middle)))
+}
+
+// zeroCrossings counts rising zero crossings in the middle half of samples
+func zeroCrossings(samples []float32) int {
+	middle := samples[len(samples)/4 : len(samples)*3/4]
+	n := 0
+	for i := 1; i < len(middle); i++ {
+		if middle[i-1] < 0 && middle[i] >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRateConverter(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int
+		speed    float64
+		freq     float64 // Of the input tone
+		wantFreq float64 // Of the output, 0 if it must be filtered out
+	}{
+		{"upsample", 44100, 48000, 1, 1000, 1000},
+		{"downsample", 96000, 44100, 1, 1000, 1000},
+		{"same rate at normal speed", 48000, 48000, 1, 1000, 1000},
+		{"double speed", 44100, 44100, 2, 1000, 2000},
+		{"half speed", 44100, 44100, 0.5, 1000, 500},
+		{"speed and rate", 48000, 44100, 1.5, 1000, 1500},
+		{"above output Nyquist", 48000, 22050, 1, 15000, 0},
+		{"pushed above Nyquist by speed", 44100, 44100, 2, 15000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRateConverter(tt.from, tt.to, 1)
+			out := append([]float32(nil), c.process(tone(tt.freq, tt.from, 1), tt.speed)...)
+
+			// The kernel's reach is held back until more input arrives
+			wantFrames := float64(tt.to) / tt.speed
+			assert.InDelta(t, wantFrames, len(out), 0.01*wantFrames)
+
+			if tt.wantFreq == 0 {
+				assert.Less(t, rms(out), 0.5/math.Sqrt2*0.001, "tone must be attenuated by 60 dB")
+				return
+			}
+			assert.InDelta(t, 0.5/math.Sqrt2, rms(out), 0.005)
+			seconds := float64(len(out)/2) / float64(tt.to)
+			assert.InDelta(t, tt.wantFreq*seconds, zeroCrossings(out), 2)
+		})
+	}
+}
+
+func TestRateConverterPieces(t *testing.T) {
+	input := tone(1000, 44100, 0.5)
+	stereo := make([]float32, len(input)*2)
+	for i, v := range input {
+		stereo[2*i], stereo[2*i+1] = v, -v
+	}
+
+	whole := append([]float32(nil), newRateConverter(44100, 48000, 2).process(stereo, 1)...)
+
+	c := newRateConverter(44100, 48000, 2)
+	var pieces []float32
+	for start := 0; start < len(stereo); start += 2 * 333 {
+		end := min(start+2*333, len(stereo))
+		pieces = append(pieces, c.process(stereo[start:end], 1)...)
+	}
+
+	assert.Equal(t, len(whole), len(pieces))
+	for i := range whole {
+		if math.Abs(float64(whole[i]-pieces[i])) > 1e-5 {
+			t.Fatalf("sample %d differs: %v converted whole, %v in pieces", i, whole[i], pieces[i])
+		}
+	}
+	for i := 0; i < len(whole); i += 2 {
+		if whole[i] != -whole[i+1] {
+			t.Fatalf("channels mixed at frame %d", i/2)
+		}
+	}
+}
+
+// countingOutput records how often it is opened and closed, and what is
+// written to it
+type countingOutput struct {
+	output.BaseOutput
+	opens, closes int
+	written       []float32
+}
+
+func (o *countingOutput) Open(format output.Format) error { o.opens++; return nil }
+func (o *countingOutput) Write(samples []float32) (int, error) {
+	o.written = append(o.written, samples...)
+	return len(samples), nil
+}
+func (o *countingOutput) WriteInt16(samples []int16) (int, error) {
+	return len(samples), nil
+}
+func (o *countingOutput) Close() error              { o.closes++; return nil }
+func (o *countingOutput) Pause() error              { return nil }
+func (o *countingOutput) Resume() error             { return nil }
+func (o *countingOutput) Flush() error              { return nil }
+func (o *countingOutput) GetLatency() time.Duration { return 0 }
+func (o *countingOutput) GetBufferSize() int        { return 0 }
+
+// rateDecoder is a sine decoder reporting another sample rate
+type rateDecoder struct {
+	*sineDecoder
+	rate int
+}
+
+func (d *rateDecoder) Format() decoder.AudioFormat {
+	return decoder.AudioFormat{SampleRate: d.rate, Channels: 2, BitDepth: 24}
+}
+
+func TestLoadResamplesToOutputRate(t *testing.T) {
+	out := &countingOutput{}
+	p := &Player{
+		output:       out,
+		outputFormat: output.Format{SampleRate: 44100, Channels: 2},
+		speed:        1.0,
+		fader:        newFader(),
+		crossfader:   dsp.NewCrossfader(),
+		estimates:    map[string]*domain.ReplayGain{},
+	}
+
+	track := &domain.Track{ID: "hires", ReplayGain: &domain.ReplayGain{TrackGain: -6}}
+	p.mu.Lock()
+	p.loadDecoder(track, &rateDecoder{newSineDecoder(0.5, time.Second), 96000})
+	p.mu.Unlock()
+
+	// The output stays at its rate and the track is resampled to it
+	assert.Zero(t, out.opens)
+	assert.Zero(t, out.closes)
+	assert.Equal(t, 44100, p.outputFormat.SampleRate)
+	require.NotNil(t, p.converter)
+	assert.True(t, p.converter.converts(96000, 44100, 2))
+
+	// A track at the output's rate needs no converter
+	p.mu.Lock()
+	p.loadDecoder(track, newSineDecoder(0.5, time.Second))
+	p.mu.Unlock()
+	assert.Nil(t, p.converter)
+}
@@ -0,0 +1,227 @@
+package audio
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/winramp/winramp/internal/audio/decoder"
+	"github.com/winramp/winramp/internal/audio/output"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// CrossfadeCurves are the volume curves a crossfade can follow
+var CrossfadeCurves = []string{"equal_power", "linear", "logarithmic"}
+
+// maxCrossfade bounds how long tracks overlap
+const maxCrossfade = 20 * time.Second
+
+// crossfadeBlock is how many frames are mixed at each crossfader position,
+// small enough that the volume steps between blocks cannot be heard
+const crossfadeBlock = 256
+
+// crossfade overlaps the end of the current track with the start of the
+// next. The next track is decoded alongside the current one and converted to
+// the output format, so when the current track ends its decoder carries on
+// from where the overlap left it.
+type crossfade struct {
+	decoder   decoder.Decoder // The next track's
+	converter *rateConverter  // Handed on with the decoder when the overlap ends
+	gain      float64         // The next track's ReplayGain or leveling gain
+	frames    int             // Length of the overlap in output frames
+	mixed     int             // Output frames mixed so far
+
+	pending   []float32 // Decoded audio of the next track not yet mixed
+	buffer    []float32
+	mixBuffer []float32
+	out       []float32
+}
+
+// SetCrossfade sets how long crossfade transitions overlap two tracks and
+// the curve their volumes follow, one of CrossfadeCurves
+func (p *Player) SetCrossfade(duration time.Duration, curve string) error {
+	if !slices.Contains(CrossfadeCurves, curve) {
+		return fmt.Errorf("invalid crossfade curve %q", curve)
+	}
+	p.crossfader.SetCurve(curve)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.crossfade = max(min(duration, maxCrossfade), 0)
+	return nil
+}
+
+// startCrossfade begins overlapping the next track once the current one
+// comes within the crossfade duration of its end, when the transition into
+// it is a crossfade. Must be called with p.mu held.
+func (p *Player) startCrossfade() {
+	if p.mixing != nil || p.transition != TransitionCrossfade || p.nextDecoder == nil ||
+		p.crossfade <= 0 || p.duration <= 0 || p.output == nil {
+		return
+	}
+
+	remaining := p.duration - p.position
+	if remaining > p.crossfade {
+		return
+	}
+	format := p.outputFormat
+	frames := int(remaining.Seconds() * float64(format.SampleRate))
+	if frames <= 0 {
+		return
+	}
+
+	gain, _ := p.gainFor(p.nextTrack)
+	xf := &crossfade{
+		decoder: p.nextDecoder,
+		gain:    gain,
+		frames:  frames,
+		buffer:  make([]float32, p.bufferSize),
+	}
+	xf.converter = newTrackConverter(p.nextDecoder.Format().SampleRate, format, p.speed)
+	p.mixing = xf
+
+	logger.Debug("Crossfading into next track",
+		logger.String("track", p.nextTrack.GetDisplayTitle()),
+		logger.Duration("overlap", remaining))
+}
+
+// cancelCrossfade abandons an overlap in progress. The next track has
+// already been partly read, so it is rewound before it plays. Must be called
+// with p.mu held.
+func (p *Player) cancelCrossfade() {
+	if p.mixing == nil {
+		return
+	}
+	p.abandoned = p.mixing.decoder
+	p.mixing = nil
+}
+
+// rewindAbandoned rewinds the next track after a cancelled crossfade. Only
+// called from the playback loop, which is the only reader of the decoders;
+// must be called with p.mu held.
+func (p *Player) rewindAbandoned() {
+	if p.abandoned == nil {
+		return
+	}
+	if p.abandoned == p.nextDecoder {
+		if err := p.nextDecoder.Seek(0); err != nil {
+			logger.Warn("Failed to rewind next track", logger.Error(err))
+		}
+	}
+	p.abandoned = nil
+}
+
+// mix blends the next track into samples of the current one, which are in
+// the output format, returning the mixed audio. The returned slice is reused
+// by the next call.
+func (p *Player) mix(xf *crossfade, samples []float32, format output.Format, speed float64) ([]float32, error) {
+	if err := p.readNext(xf, len(samples), format, speed); err != nil {
+		return samples, err
+	}
+
+	if cap(xf.out) < len(samples) {
+		xf.out = make([]float32, len(samples))
+	}
+	out := xf.out[:len(samples)]
+	next := xf.pending[:len(samples)]
+
+	channels := format.Channels
+	frames := len(samples) / channels
+	for start := 0; start < frames; start += crossfadeBlock {
+		end := min(start+crossfadeBlock, frames)
+		p.crossfader.SetPosition(float64(xf.mixed+start) / float64(xf.frames))
+		p.crossfader.Mix(samples[start*channels:end*channels], next[start*channels:end*channels], out[start*channels:end*channels])
+	}
+	xf.mixed += frames
+
+	xf.pending = xf.pending[:copy(xf.pending, xf.pending[len(samples):])]
+	return out, nil
+}
+
+// readNext decodes the next track until at least n samples are pending in
+// the output format. A next track shorter than the overlap ends in silence.
+func (p *Player) readNext(xf *crossfade, n int, format output.Format, speed float64) error {
+	channels := format.Channels
+	for len(xf.pending) < n {
+		frames, err := xf.decoder.Decode(xf.buffer)
+		if err == decoder.ErrEndOfStream || (err == nil && frames == 0) {
+			xf.pending = append(xf.pending, make([]float32, n-len(xf.pending))...)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		from := xf.decoder.Format().Channels
+		if from <= 0 {
+			from = 2
+		}
+		samples := xf.buffer[:frames*from]
+		if from != channels {
+			xf.mixBuffer = remix(xf.mixBuffer, samples, from, channels)
+			samples = xf.mixBuffer
+		}
+		if xf.converter == nil && speed != 1.0 {
+			xf.converter = newTrackConverter(xf.decoder.Format().SampleRate, format, speed)
+		}
+		if xf.converter != nil {
+			samples = xf.converter.process(samples, speed)
+		}
+		start := len(xf.pending)
+		xf.pending = append(xf.pending, samples...)
+		if xf.gain != 1.0 {
+			output.ApplyVolume(xf.pending[start:], xf.gain)
+		}
+	}
+	return nil
+}
+
+// finishCrossfade hands the overlap over to the next track as it becomes
+// the current one. Its converter carries on where it was, the audio decoded
+// ahead is played first, and if the previous track ended sooner than its
+// duration promised the rest of the fade in is finished by the fader. Must
+// be called with p.mu held.
+func (p *Player) finishCrossfade(xf *crossfade) {
+	p.converter = xf.converter
+	p.carry = xf.pending
+	if remaining := xf.remaining(p.outputFormat.SampleRate); remaining > 0 {
+		p.fader.set(float64(xf.mixed) / float64(xf.frames))
+		p.fader.rampTo(1.0, remaining)
+	}
+}
+
+// playCarry writes the audio of the current track decoded ahead during a
+// crossfade. It returns false if the output has gone.
+func (p *Player) playCarry() bool {
+	p.mu.Lock()
+	carry := p.carry
+	p.carry = nil
+	out := p.output
+	format := p.outputFormat
+	track := p.currentTrack
+	gain := p.trackGain
+	p.mu.Unlock()
+
+	if len(carry) == 0 {
+		return true
+	}
+	if out == nil {
+		return false
+	}
+
+	p.finishOutput(carry, format, track, gain)
+	started := time.Now()
+	if _, err := out.Write(carry); err != nil {
+		logger.ErrorLog("Output error", logger.Error(err))
+	}
+	p.noteWrite(started)
+	return true
+}
+
+// remaining returns how much of the overlap is still to be mixed
+func (xf *crossfade) remaining(sampleRate int) time.Duration {
+	if sampleRate <= 0 || xf.mixed >= xf.frames {
+		return 0
+	}
+	return time.Duration(xf.frames-xf.mixed) * time.Second / time.Duration(sampleRate)
+}
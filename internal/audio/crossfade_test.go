@@ -0,0 +1,107 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/audio/dsp"
+	"github.com/winramp/winramp/internal/audio/output"
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestCrossfadeMix(t *testing.T) {
+	p := &Player{crossfader: dsp.NewCrossfader()}
+	p.crossfader.SetEnabled(true)
+	require.NoError(t, p.SetCrossfade(time.Second, "linear"))
+
+	next := newSineDecoder(0.5, time.Second)
+	xf := &crossfade{decoder: next, gain: 1.0, frames: 4410, buffer: make([]float32, 1000)}
+	format := output.Format{SampleRate: sineRate, Channels: 2}
+
+	// The current track is silent, so only the next one fading in is heard
+	var mixed []float32
+	for i := 0; i < 10; i++ {
+		out, err := p.mix(xf, make([]float32, 441*2), format, 1.0)
+		require.NoError(t, err)
+		mixed = append(mixed, out...)
+	}
+	require.Len(t, mixed, 4410*2)
+
+	for frame := 0; frame < 4410; frame++ {
+		sine := 0.5 * math.Sin(2*math.Pi*1000*float64(frame)/sineRate)
+		position := float64(frame) / 4410
+		assert.InDelta(t, sine*position, mixed[2*frame], 0.5*crossfadeBlock/4410.0)
+	}
+
+	// Audio decoded ahead is kept, so the next track carries on exactly
+	// where the overlap ended
+	assert.EqualValues(t, 4410, next.CurrentSample()-int64(len(xf.pending)/2))
+	assert.Zero(t, xf.remaining(sineRate))
+}
+
+func TestCrossfadeShortNextTrack(t *testing.T) {
+	p := &Player{crossfader: dsp.NewCrossfader()}
+	p.crossfader.SetEnabled(true)
+
+	xf := &crossfade{decoder: newSineDecoder(0.5, 10*time.Millisecond), gain: 1.0, frames: 4410, buffer: make([]float32, 1000)}
+	out, err := p.mix(xf, make([]float32, 4410*2), output.Format{SampleRate: sineRate, Channels: 2}, 1.0)
+	require.NoError(t, err)
+	require.Len(t, out, 4410*2)
+	for _, s := range out[441*2:] {
+		assert.Zero(t, s)
+	}
+}
+
+func TestSetCrossfade(t *testing.T) {
+	p := &Player{crossfader: dsp.NewCrossfader()}
+
+	require.NoError(t, p.SetCrossfade(time.Minute, "equal_power"))
+	assert.Equal(t, maxCrossfade, p.crossfade)
+	require.NoError(t, p.SetCrossfade(-time.Second, "logarithmic"))
+	assert.Zero(t, p.crossfade)
+
+	assert.Error(t, p.SetCrossfade(time.Second, "s-curve"))
+}
+
+func TestPlayCarryEffects(t *testing.T) {
+	out := &countingOutput{}
+	track := &domain.Track{ID: "next"}
+	p := &Player{
+		output:       out,
+		outputFormat: output.Format{SampleRate: sineRate, Channels: 2},
+		currentTrack: track,
+		trackGain:    1.0,
+		fader:        newFader(),
+		clips:        newClipMeter(),
+	}
+	p.newEffects(sineRate)
+	require.NoError(t, p.effects.SetEffectEnabled(EffectLimiter, false))
+	p.SetPreamp(6)
+
+	// A second of the next track decoded during the overlap goes through
+	// the preamp like any other audio, and its clipping is counted against
+	// the track now playing
+	carry := make([]float32, 2*sineRate)
+	for i := 0; i < sineRate; i++ {
+		carry[2*i] = float32(0.8 * math.Sin(2*math.Pi*1000*float64(i)/sineRate))
+	}
+	want := make([]float32, len(carry))
+	copy(want, carry)
+	p.carry = carry
+
+	require.True(t, p.playCarry())
+	require.Len(t, out.written, len(want))
+	for i := 0; i < 1000; i++ {
+		assert.InDelta(t, want[2*i]*1.995, out.written[2*i], 0.002)
+	}
+	assert.Nil(t, p.carry)
+
+	stats := p.ClipStats()
+	require.Len(t, stats.Tracks, 1)
+	assert.Equal(t, "next", stats.Tracks[0].Track.ID)
+	assert.Positive(t, stats.Tracks[0].Clipped)
+}
@@ -21,9 +21,11 @@ func NewDecoderFactory() *DecoderFactory {
 	// Register all available decoders
 	f.RegisterFactory("mp3", &MP3Factory{})
 	f.RegisterFactory("flac", &FLACFactory{})
+	for _, format := range (&WAVFactory{}).SupportedFormats() {
+		f.RegisterFactory(format, &WAVFactory{})
+	}
 	// Future: Add more decoders
 	// f.RegisterFactory("ogg", &OGGFactory{})
-	// f.RegisterFactory("wav", &WAVFactory{})
 	// f.RegisterFactory("aac", &AACFactory{})
 	
 	return f
@@ -91,7 +93,9 @@ func (f *DecoderFactory) SupportedFormats() []string {
 }
 
 func (f *DecoderFactory) contentTypeToFormat(contentType string) string {
-	switch strings.ToLower(contentType) {
+	// Servers may add parameters, as in "audio/mpeg; charset=binary"
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
 	case "audio/mpeg", "audio/mp3":
 		return "mp3"
 	case "audio/flac":
@@ -100,6 +104,8 @@ func (f *DecoderFactory) contentTypeToFormat(contentType string) string {
 		return "ogg"
 	case "audio/wav", "audio/wave":
 		return "wav"
+	case "audio/aiff", "audio/x-aiff":
+		return "aiff"
 	case "audio/aac":
 		return "aac"
 	default:
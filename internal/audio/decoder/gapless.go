@@ -0,0 +1,215 @@
+package decoder
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// gaplessScanSize is how much of the head and tail of a file is searched
+// for encoder delay information
+const gaplessScanSize = 256 * 1024
+
+// mp3DecoderDelay is the delay an MP3 decoder adds to its output, which the
+// LAME header's encoder delay leaves out
+const mp3DecoderDelay = 529
+
+var smpbPattern = regexp.MustCompile(`[0-9A-Fa-f]{8} ([0-9A-Fa-f]{8}) ([0-9A-Fa-f]{8})`)
+
+// GaplessInfo describes how encoder delay and padding can be trimmed from a
+// track so consecutive tracks join without a gap
+type GaplessInfo struct {
+	Source  string // "lame", "itunsmpb", "lossless" or "container"
+	Delay   int    // Encoder delay in samples
+	Padding int    // Trailing padding in samples
+}
+
+// ReadGaplessInfo inspects a file for gapless playback information. Lossless
+// and self-describing container formats always report info; lossy formats
+// return nil when neither a LAME header nor an iTunSMPB tag is present.
+func ReadGaplessInfo(path string) (*GaplessInfo, error) {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	switch ext {
+	case "flac", "wav", "aiff", "aif", "ape", "wv":
+		return &GaplessInfo{Source: "lossless"}, nil
+	case "ogg", "oga", "opus":
+		// Granule positions carry exact sample counts
+		return &GaplessInfo{Source: "container"}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, gaplessScanSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	if ext == "mp3" {
+		if info := parseLAMEHeader(head); info != nil {
+			return info, nil
+		}
+	}
+
+	if info := parseITunSMPB(head); info != nil {
+		return info, nil
+	}
+
+	// MP4 files frequently keep the metadata atom at the end
+	if stat, err := file.Stat(); err == nil && stat.Size() > int64(len(head)) {
+		offset := stat.Size() - gaplessScanSize
+		if offset < int64(len(head)) {
+			offset = int64(len(head))
+		}
+		tail := make([]byte, stat.Size()-offset)
+		if _, err := file.ReadAt(tail, offset); err == nil {
+			if info := parseITunSMPB(tail); info != nil {
+				return info, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// MP3Trim works out which samples an MP3 decoder outputs are music, from
+// the gapless information in the first part of the file. The output starts
+// with the silent frame that carries a Xing or LAME header, then the
+// encoder delay, and ends with the encoder's padding. It returns how many
+// samples to skip and how many follow; a file without gapless information
+// is left whole.
+func MP3Trim(head []byte, total int64) (skip, length int64) {
+	info := parseLAMEHeader(head)
+	if info == nil {
+		info = parseITunSMPB(head)
+	}
+	if info == nil {
+		return 0, total
+	}
+
+	var infoFrame int64
+	if frame, _ := xingFrame(head); frame != nil {
+		infoFrame = mp3FrameSamples(frame)
+	}
+	delay, padding := int64(info.Delay), int64(info.Padding)
+
+	// iTunSMPB counts the decoder's delay in with the encoder's
+	skip = infoFrame + delay
+	if info.Source == "lame" {
+		skip += mp3DecoderDelay
+	}
+	length = min(total-infoFrame-delay-padding, total-skip)
+	if length <= 0 {
+		return 0, total
+	}
+	return skip, length
+}
+
+// mp3FrameSamples returns the samples in an MP3 frame from its header:
+// 1152 for MPEG-1 and 576 for MPEG-2 and 2.5
+func mp3FrameSamples(frame []byte) int64 {
+	if len(frame) >= 2 && (frame[1]>>3)&0x03 == 0x03 {
+		return 1152
+	}
+	return 576
+}
+
+// xingFrame returns the first audio frame of an MP3 file and the offset of
+// the Xing or Info header within it, or nil when there is none
+func xingFrame(data []byte) ([]byte, int) {
+	start := 0
+	if len(data) >= 10 && bytes.Equal(data[:3], []byte("ID3")) {
+		// Skip the ID3v2 tag (synchsafe size)
+		size := int(data[6])<<21 | int(data[7])<<14 | int(data[8])<<7 | int(data[9])
+		start = 10 + size
+	}
+	if start >= len(data) {
+		return nil, -1
+	}
+
+	// The Xing header sits inside the first audio frame
+	frame := data[start:]
+	for i := 0; i+1 < len(frame) && i < 4096; i++ {
+		if frame[i] == 0xFF && frame[i+1]&0xE0 == 0xE0 {
+			frame = frame[i:]
+			break
+		}
+	}
+	if len(frame) > 4096 {
+		frame = frame[:4096]
+	}
+	xing := bytes.Index(frame, []byte("Xing"))
+	if xing < 0 {
+		xing = bytes.Index(frame, []byte("Info"))
+	}
+	if xing < 0 {
+		return nil, -1
+	}
+	return frame, xing
+}
+
+// parseLAMEHeader looks for a Xing/Info frame carrying a LAME extension
+func parseLAMEHeader(data []byte) *GaplessInfo {
+	frame, xing := xingFrame(data)
+	if frame == nil {
+		return nil
+	}
+
+	lame := bytes.Index(frame[xing:], []byte("LAME"))
+	if lame < 0 {
+		return nil
+	}
+	lame += xing
+
+	// Delay and padding are packed as two 12-bit values at offset 21
+	if lame+24 > len(frame) {
+		return nil
+	}
+	b := frame[lame+21 : lame+24]
+	return &GaplessInfo{
+		Source:  "lame",
+		Delay:   int(b[0])<<4 | int(b[1])>>4,
+		Padding: int(b[1]&0x0F)<<8 | int(b[2]),
+	}
+}
+
+// parseITunSMPB looks for an iTunes gapless tag in an ID3 comment or MP4 atom
+func parseITunSMPB(data []byte) *GaplessInfo {
+	idx := bytes.Index(data, []byte("iTunSMPB"))
+	if idx < 0 {
+		return nil
+	}
+
+	end := idx + 128
+	if end > len(data) {
+		end = len(data)
+	}
+	match := smpbPattern.FindSubmatch(data[idx:end])
+	if match == nil {
+		return nil
+	}
+
+	delay, err := strconv.ParseInt(string(match[1]), 16, 64)
+	if err != nil {
+		return nil
+	}
+	padding, err := strconv.ParseInt(string(match[2]), 16, 64)
+	if err != nil {
+		return nil
+	}
+
+	return &GaplessInfo{
+		Source:  "itunsmpb",
+		Delay:   int(delay),
+		Padding: int(padding),
+	}
+}
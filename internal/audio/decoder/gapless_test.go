@@ -0,0 +1,55 @@
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMP3Trim(t *testing.T) {
+	tests := []struct {
+		name   string
+		head   []byte
+		total  int64
+		skip   int64
+		length int64
+	}{
+		{"no gapless info", append(id3Tag(), mp3Frame(0xFB)...), 100000, 0, 100000},
+		{"LAME MPEG-1", append(id3Tag(), lameFrame(0xFB, 576, 1000)...), 100000, 1152 + 576 + mp3DecoderDelay, 100000 - 1152 - 576 - 1000},
+		{"LAME MPEG-2", lameFrame(0xF3, 576, 1000), 100000, 576 + 576 + mp3DecoderDelay, 100000 - 576 - 576 - 1000},
+		{"iTunSMPB", append(id3Tag(), []byte("iTunSMPB\x00 00000000 00000840 000001F0 0000000000017F10")...), 100000, 2112, 100000 - 2112 - 496},
+		{"padding past the end", lameFrame(0xFB, 576, 4000), 5000, 0, 5000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skip, length := MP3Trim(tt.head, tt.total)
+			assert.Equal(t, tt.skip, skip)
+			assert.Equal(t, tt.length, length)
+		})
+	}
+}
+
+// id3Tag returns an empty ID3v2 tag header
+func id3Tag() []byte {
+	return []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 0}
+}
+
+// mp3Frame returns the start of an MP3 frame whose second header byte is b
+func mp3Frame(b byte) []byte {
+	frame := make([]byte, 200)
+	frame[0], frame[1], frame[2] = 0xFF, b, 0x90
+	return frame
+}
+
+// lameFrame returns an Info frame carrying a LAME header with the given
+// encoder delay and padding
+func lameFrame(b byte, delay, padding int) []byte {
+	frame := mp3Frame(b)
+	copy(frame[36:], "Info")
+	copy(frame[156:], "LAME3.100")
+	frame[156+21] = byte(delay >> 4)
+	frame[156+22] = byte(delay<<4) | byte(padding>>8)
+	frame[156+23] = byte(padding)
+	return frame
+}
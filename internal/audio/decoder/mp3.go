@@ -17,6 +17,7 @@ type MP3Decoder struct {
 	decoder    *mp3.Decoder
 	buffer     []byte
 	eof        bool
+	skip       int64 // Encoder delay samples before the music starts
 }
 
 // NewMP3Decoder creates a new MP3 decoder
@@ -38,6 +39,7 @@ func NewMP3Decoder(reader io.ReadSeeker) (*MP3Decoder, error) {
 
 	// Extract metadata
 	metadata := &Metadata{}
+	var head []byte
 	if seeker, ok := reader.(io.ReadSeeker); ok {
 		seeker.Seek(0, io.SeekStart)
 		if m, err := tag.ReadFrom(reader); err == nil {
@@ -63,13 +65,23 @@ func NewMP3Decoder(reader io.ReadSeeker) (*MP3Decoder, error) {
 				metadata.AlbumArtMIME = pic.MIMEType
 			}
 		}
+		// Read the head for the encoder delay and padding
+		seeker.Seek(0, io.SeekStart)
+		head, _ = io.ReadAll(io.LimitReader(reader, gaplessScanSize))
+
 		// Reset reader position
 		seeker.Seek(0, io.SeekStart)
 		decoder, _ = mp3.NewDecoder(reader)
 	}
 
-	// Calculate duration and sample count
-	sampleCount := decoder.Length() / 4 // 2 channels * 2 bytes per sample
+	// Calculate duration and sample count, leaving out the encoder's delay
+	// and padding so tracks join without a gap
+	skip, sampleCount := MP3Trim(head, decoder.Length()/4) // 2 channels * 2 bytes per sample
+	if skip > 0 {
+		if _, err := decoder.Seek(skip*4, io.SeekStart); err != nil {
+			skip, sampleCount = 0, decoder.Length()/4
+		}
+	}
 	duration := time.Duration(sampleCount) * time.Second / time.Duration(format.SampleRate)
 	metadata.Duration = duration
 
@@ -88,6 +100,7 @@ func NewMP3Decoder(reader io.ReadSeeker) (*MP3Decoder, error) {
 		reader:  reader,
 		decoder: decoder,
 		buffer:  make([]byte, initialBufferSize),
+		skip:    skip,
 	}, nil
 }
 
@@ -108,6 +121,12 @@ func (d *MP3Decoder) Decode(buffer []float32) (int, error) {
 		return 0, fmt.Errorf("buffer size exceeds maximum allowed: %d > %d", len(buffer), maxBufferSize/2)
 	}
 
+	buffer = buffer[:d.limit(len(buffer))]
+	if len(buffer) == 0 {
+		d.eof = true
+		return 0, ErrEndOfStream
+	}
+
 	// Calculate bytes needed
 	bytesNeeded := len(buffer) * 2 // 2 bytes per sample (int16)
 	if bytesNeeded > len(d.buffer) {
@@ -166,6 +185,12 @@ func (d *MP3Decoder) DecodeInt16(buffer []int16) (int, error) {
 		return 0, fmt.Errorf("buffer size exceeds maximum allowed: %d > %d", len(buffer), maxBufferSize/2)
 	}
 
+	buffer = buffer[:d.limit(len(buffer))]
+	if len(buffer) == 0 {
+		d.eof = true
+		return 0, ErrEndOfStream
+	}
+
 	// Calculate bytes needed
 	bytesNeeded := len(buffer) * 2
 	if bytesNeeded > len(d.buffer) {
@@ -206,6 +231,19 @@ func (d *MP3Decoder) DecodeInt16(buffer []int16) (int, error) {
 	return samplesRead / d.format.Channels, nil
 }
 
+// limit returns how many of n samples can be decoded before the encoder's
+// padding. Streams have no known length and aren't limited.
+func (d *MP3Decoder) limit(n int) int {
+	if d.sampleCount <= 0 {
+		return n
+	}
+	remaining := (d.sampleCount - d.currentSample) * int64(d.format.Channels)
+	if remaining < int64(n) {
+		return int(max(remaining, 0))
+	}
+	return n
+}
+
 // Seek seeks to the specified position
 func (d *MP3Decoder) Seek(position time.Duration) error {
 	targetSample := int64(position.Seconds() * float64(d.format.SampleRate))
@@ -221,27 +259,15 @@ func (d *MP3Decoder) SeekSample(sample int64) error {
 		return fmt.Errorf("sample position out of range: %d > %d", sample, d.sampleCount)
 	}
 
-	// Calculate byte position (approximate for MP3)
-	bytePosition := sample * 4 // 2 channels * 2 bytes per sample
-	
-	if seeker, ok := d.reader.(io.Seeker); ok {
-		_, err := seeker.Seek(bytePosition, io.SeekStart)
-		if err != nil {
-			return fmt.Errorf("failed to seek: %w", err)
-		}
-		
-		// Recreate decoder at new position
-		d.decoder, err = mp3.NewDecoder(d.reader)
-		if err != nil {
-			return fmt.Errorf("failed to recreate decoder: %w", err)
-		}
-		
-		d.currentSample = sample
-		d.eof = false
-		return nil
+	// The decoder seeks by decoded PCM bytes, past the encoder delay
+	bytePosition := (d.skip + sample) * 4 // 2 channels * 2 bytes per sample
+	if _, err := d.decoder.Seek(bytePosition, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek: %w", err)
 	}
 
-	return ErrSeekNotSupported
+	d.currentSample = sample
+	d.eof = false
+	return nil
 }
 
 // Close closes the decoder
@@ -278,9 +304,75 @@ func (f *MP3Factory) CreateDecoderForFile(path string) (Decoder, error) {
 
 // CreateStreamDecoder creates a decoder for streaming
 func (f *MP3Factory) CreateStreamDecoder(reader io.Reader) (StreamDecoder, error) {
-	// For streaming, we need a reader that supports seeking for metadata
-	// In practice, we might buffer the stream
-	return nil, fmt.Errorf("streaming not yet implemented for MP3")
+	return NewMP3StreamDecoder(reader)
+}
+
+// MP3StreamDecoder decodes MP3 from a stream that can't seek, such as
+// internet radio. It has no length and no tags; stations send what is
+// playing separately.
+type MP3StreamDecoder struct {
+	MP3Decoder
+	source io.Reader
+}
+
+// NewMP3StreamDecoder creates a decoder reading MP3 as it arrives
+func NewMP3StreamDecoder(reader io.Reader) (*MP3StreamDecoder, error) {
+	decoder, err := mp3.NewDecoder(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MP3 decoder: %w", err)
+	}
+
+	return &MP3StreamDecoder{
+		MP3Decoder: MP3Decoder{
+			BaseDecoder: BaseDecoder{
+				format: AudioFormat{
+					SampleRate: decoder.SampleRate(),
+					Channels:   2,
+					BitDepth:   16,
+					Encoding:   "pcm",
+				},
+				metadata: &Metadata{},
+			},
+			decoder: decoder,
+			buffer:  make([]byte, 4096),
+		},
+		source: reader,
+	}, nil
+}
+
+// Seek is not supported on a stream
+func (d *MP3StreamDecoder) Seek(position time.Duration) error {
+	return ErrSeekNotSupported
+}
+
+// SeekSample is not supported on a stream
+func (d *MP3StreamDecoder) SeekSample(sample int64) error {
+	return ErrSeekNotSupported
+}
+
+// SetBufferSize sets the size of the buffer decoded PCM is read into
+func (d *MP3StreamDecoder) SetBufferSize(size int) {
+	if size > 0 {
+		d.buffer = make([]byte, size)
+	}
+}
+
+// Buffered returns 0; read-ahead is left to the stream's reader
+func (d *MP3StreamDecoder) Buffered() int {
+	return 0
+}
+
+// IsStreaming returns true
+func (d *MP3StreamDecoder) IsStreaming() bool {
+	return true
+}
+
+// Close closes the stream
+func (d *MP3StreamDecoder) Close() error {
+	if closer, ok := d.source.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
 }
 
 // SupportsFormat checks if the factory supports the given format
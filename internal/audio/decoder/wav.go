@@ -0,0 +1,568 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhowden/tag"
+)
+
+// WAV format tags
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatFloat      = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+// pcmLayout describes how samples are stored in uncompressed audio
+type pcmLayout struct {
+	order     binary.ByteOrder
+	bits      int  // Bits per sample
+	float     bool // IEEE floating point rather than integer
+	unsigned  bool // 8-bit WAV samples are unsigned
+	blockSize int  // Bytes per frame
+}
+
+// WAVDecoder implements the Decoder interface for uncompressed WAV and AIFF
+// files: 8, 16, 24 and 32-bit integer PCM and 32 and 64-bit float. As every
+// frame is the same size, seeking is exact and immediate.
+type WAVDecoder struct {
+	BaseDecoder
+	reader    io.ReadSeeker
+	layout    pcmLayout
+	dataStart int64 // Offset of the first frame
+	buffer    []byte
+	eof       bool
+}
+
+// NewWAVDecoder creates a decoder for a WAV or AIFF stream, telling them
+// apart by their header
+func NewWAVDecoder(reader io.ReadSeeker) (*WAVDecoder, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+
+	d := &WAVDecoder{
+		BaseDecoder: BaseDecoder{metadata: &Metadata{}},
+		reader:      reader,
+	}
+
+	var err error
+	switch {
+	case string(header[:4]) == "RIFF" && string(header[8:]) == "WAVE":
+		err = d.parseWAV()
+	case string(header[:4]) == "FORM" && (string(header[8:]) == "AIFF" || string(header[8:]) == "AIFC"):
+		err = d.parseAIFF(string(header[8:]) == "AIFC")
+	default:
+		err = fmt.Errorf("%w: not a WAV or AIFF file", ErrUnsupportedFormat)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if d.format.SampleRate <= 0 || d.format.Channels <= 0 || d.layout.blockSize <= 0 {
+		return nil, fmt.Errorf("%w: missing format chunk", ErrInvalidData)
+	}
+
+	d.metadata.Duration = d.Duration()
+	d.metadata.Bitrate = d.format.SampleRate * d.layout.blockSize * 8
+	if _, err := reader.Seek(d.dataStart, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// chunk is the header of a RIFF or IFF chunk
+type chunk struct {
+	id     string
+	size   int64
+	offset int64 // Where the chunk's data starts
+}
+
+// readChunks walks the chunks after the file header, calling fn for each.
+// Chunks are padded to an even length.
+func (d *WAVDecoder) readChunks(order binary.ByteOrder, fn func(c chunk) error) error {
+	offset := int64(12)
+	header := make([]byte, 8)
+	for {
+		if _, err := d.reader.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(d.reader, header); err != nil {
+			return nil // End of file
+		}
+
+		c := chunk{
+			id:     string(header[:4]),
+			size:   int64(order.Uint32(header[4:])),
+			offset: offset + 8,
+		}
+		if err := fn(c); err != nil {
+			return err
+		}
+		offset = c.offset + c.size + c.size%2
+	}
+}
+
+func (d *WAVDecoder) parseWAV() error {
+	var dataSize int64 = -1
+	err := d.readChunks(binary.LittleEndian, func(c chunk) error {
+		switch c.id {
+		case "fmt ":
+			return d.parseWAVFormat(c)
+		case "data":
+			d.dataStart = c.offset
+			dataSize = c.size
+		case "LIST":
+			d.parseInfoList(c)
+		case "id3 ", "ID3 ":
+			d.parseID3(c)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if dataSize < 0 {
+		return fmt.Errorf("%w: no data chunk", ErrInvalidData)
+	}
+	return d.setSampleCount(dataSize)
+}
+
+func (d *WAVDecoder) parseWAVFormat(c chunk) error {
+	if c.size < 16 {
+		return fmt.Errorf("%w: short format chunk", ErrInvalidData)
+	}
+	data := make([]byte, min(c.size, 40))
+	if _, err := io.ReadFull(d.reader, data); err != nil {
+		return err
+	}
+
+	formatTag := binary.LittleEndian.Uint16(data[0:])
+	channels := int(binary.LittleEndian.Uint16(data[2:]))
+	sampleRate := int(binary.LittleEndian.Uint32(data[4:]))
+	blockAlign := int(binary.LittleEndian.Uint16(data[12:]))
+	bits := int(binary.LittleEndian.Uint16(data[14:]))
+
+	// Extensible files keep the real format tag at the start of the
+	// sub-format GUID
+	if formatTag == wavFormatExtensible && len(data) >= 26 {
+		formatTag = binary.LittleEndian.Uint16(data[24:])
+	}
+
+	layout := pcmLayout{order: binary.LittleEndian, bits: bits, blockSize: blockAlign}
+	switch formatTag {
+	case wavFormatPCM:
+		layout.unsigned = bits == 8
+	case wavFormatFloat:
+		layout.float = true
+	default:
+		return fmt.Errorf("%w: WAV format 0x%04x", ErrUnsupportedFormat, formatTag)
+	}
+	return d.setLayout(layout, sampleRate, channels)
+}
+
+func (d *WAVDecoder) parseAIFF(compressed bool) error {
+	var dataSize int64 = -1
+	order := binary.ByteOrder(binary.BigEndian)
+	float := false
+	var channels, bits, sampleRate int
+
+	err := d.readChunks(binary.BigEndian, func(c chunk) error {
+		switch c.id {
+		case "COMM":
+			data := make([]byte, min(c.size, 22))
+			if _, err := io.ReadFull(d.reader, data); err != nil || len(data) < 18 {
+				return fmt.Errorf("%w: short COMM chunk", ErrInvalidData)
+			}
+			channels = int(binary.BigEndian.Uint16(data[0:]))
+			bits = int(binary.BigEndian.Uint16(data[6:]))
+			sampleRate = int(extendedToFloat(data[8:18]))
+
+			if compressed && len(data) >= 22 {
+				switch compression := string(data[18:22]); strings.ToLower(compression) {
+				case "none", "twos":
+				case "sowt":
+					order = binary.LittleEndian
+				case "fl32", "fl64":
+					float = true
+				default:
+					return fmt.Errorf("%w: AIFF-C compression %q", ErrUnsupportedFormat, compression)
+				}
+			}
+		case "SSND":
+			// The sound data starts after an offset and block size field
+			offset := make([]byte, 4)
+			if _, err := io.ReadFull(d.reader, offset); err != nil {
+				return err
+			}
+			skip := int64(binary.BigEndian.Uint32(offset))
+			d.dataStart = c.offset + 8 + skip
+			dataSize = c.size - 8 - skip
+		case "NAME":
+			d.metadata.Title = d.readText(c)
+		case "AUTH":
+			d.metadata.Artist = d.readText(c)
+		case "ANNO":
+			d.metadata.Comment = d.readText(c)
+		case "ID3 ", "id3 ":
+			d.parseID3(c)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if dataSize < 0 {
+		return fmt.Errorf("%w: no sound data chunk", ErrInvalidData)
+	}
+
+	if float && bits != 32 && bits != 64 {
+		bits = 32
+	}
+	layout := pcmLayout{
+		order:     order,
+		bits:      bits,
+		float:     float,
+		blockSize: channels * ((bits + 7) / 8),
+	}
+	if err := d.setLayout(layout, sampleRate, channels); err != nil {
+		return err
+	}
+	return d.setSampleCount(dataSize)
+}
+
+// setLayout checks the sample layout is one the decoder reads and sets
+// the format from it
+func (d *WAVDecoder) setLayout(layout pcmLayout, sampleRate, channels int) error {
+	// Samples are stored in whole bytes, left justified
+	layout.bits = (layout.bits + 7) / 8 * 8
+	valid := layout.bits == 8 || layout.bits == 16 || layout.bits == 24 || layout.bits == 32
+	if layout.float {
+		valid = layout.bits == 32 || layout.bits == 64
+	}
+	if !valid {
+		return fmt.Errorf("%w: %d-bit samples", ErrUnsupportedFormat, layout.bits)
+	}
+	if layout.blockSize < channels*layout.bits/8 {
+		layout.blockSize = channels * layout.bits / 8
+	}
+
+	d.layout = layout
+	d.format = AudioFormat{
+		SampleRate: sampleRate,
+		Channels:   channels,
+		BitDepth:   layout.bits,
+		Float:      layout.float,
+		Encoding:   "pcm",
+	}
+	if layout.float {
+		d.format.Encoding = "float" + strconv.Itoa(layout.bits)
+	}
+	return nil
+}
+
+// setSampleCount works out the length from the data size. Files cut short
+// while recording claim more data than they hold, so the file size caps it.
+func (d *WAVDecoder) setSampleCount(dataSize int64) error {
+	if d.layout.blockSize <= 0 {
+		return fmt.Errorf("%w: data before format", ErrInvalidData)
+	}
+	if end, err := d.reader.Seek(0, io.SeekEnd); err == nil && d.dataStart+dataSize > end {
+		dataSize = max(end-d.dataStart, 0)
+	}
+	d.sampleCount = dataSize / int64(d.layout.blockSize)
+	return nil
+}
+
+// parseInfoList reads a RIFF INFO list, where WAV files keep their tags
+func (d *WAVDecoder) parseInfoList(c chunk) {
+	if c.size < 4 || c.size > 1<<20 {
+		return
+	}
+	data := make([]byte, c.size)
+	if _, err := io.ReadFull(d.reader, data); err != nil || string(data[:4]) != "INFO" {
+		return
+	}
+
+	for data = data[4:]; len(data) >= 8; {
+		id := string(data[:4])
+		size := int(binary.LittleEndian.Uint32(data[4:]))
+		if 8+size > len(data) {
+			return
+		}
+		value := strings.TrimRight(string(data[8:8+size]), "\x00 ")
+		switch id {
+		case "INAM":
+			d.metadata.Title = value
+		case "IART":
+			d.metadata.Artist = value
+		case "IPRD":
+			d.metadata.Album = value
+		case "IGNR":
+			d.metadata.Genre = value
+		case "ICMT":
+			d.metadata.Comment = value
+		case "ICRD":
+			if len(value) >= 4 {
+				d.metadata.Year, _ = strconv.Atoi(value[:4])
+			}
+		case "ITRK", "IPRT":
+			d.metadata.TrackNumber, _ = strconv.Atoi(value)
+		}
+		data = data[min(8+size+size%2, len(data)):]
+	}
+}
+
+// parseID3 reads an ID3v2 tag embedded as a chunk, which takes precedence
+// over the format's own text chunks
+func (d *WAVDecoder) parseID3(c chunk) {
+	if c.size > 16<<20 {
+		return
+	}
+	data := make([]byte, c.size)
+	if _, err := io.ReadFull(d.reader, data); err != nil {
+		return
+	}
+	m, err := tag.ReadID3v2Tags(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+
+	if m.Title() != "" {
+		d.metadata.Title = m.Title()
+	}
+	if m.Artist() != "" {
+		d.metadata.Artist = m.Artist()
+	}
+	if m.Album() != "" {
+		d.metadata.Album = m.Album()
+	}
+	if m.AlbumArtist() != "" {
+		d.metadata.AlbumArtist = m.AlbumArtist()
+	}
+	if m.Genre() != "" {
+		d.metadata.Genre = m.Genre()
+	}
+	if m.Year() > 0 {
+		d.metadata.Year = m.Year()
+	}
+	if track, _ := m.Track(); track > 0 {
+		d.metadata.TrackNumber = track
+	}
+	if disc, _ := m.Disc(); disc > 0 {
+		d.metadata.DiscNumber = disc
+	}
+	if pic := m.Picture(); pic != nil {
+		d.metadata.AlbumArt = pic.Data
+		d.metadata.AlbumArtMIME = pic.MIMEType
+	}
+}
+
+func (d *WAVDecoder) readText(c chunk) string {
+	if c.size > 64*1024 {
+		return ""
+	}
+	data := make([]byte, c.size)
+	if _, err := io.ReadFull(d.reader, data); err != nil {
+		return ""
+	}
+	return strings.TrimRight(string(data), "\x00 ")
+}
+
+// readFrames reads up to frames whole frames into d.buffer, returning how
+// many were read
+func (d *WAVDecoder) readFrames(frames int) (int, error) {
+	if d.eof {
+		return 0, ErrEndOfStream
+	}
+
+	frames = int(min(int64(frames), d.sampleCount-d.currentSample))
+	if frames <= 0 {
+		d.eof = true
+		return 0, ErrEndOfStream
+	}
+
+	size := frames * d.layout.blockSize
+	if cap(d.buffer) < size {
+		d.buffer = make([]byte, size)
+	}
+	d.buffer = d.buffer[:size]
+
+	n, err := io.ReadFull(d.reader, d.buffer)
+	frames = n / d.layout.blockSize
+	if err != nil {
+		if err != io.ErrUnexpectedEOF && err != io.EOF {
+			return 0, fmt.Errorf("failed to read audio data: %w", err)
+		}
+		d.eof = true
+		if frames == 0 {
+			return 0, ErrEndOfStream
+		}
+	}
+
+	d.currentSample += int64(frames)
+	return frames, nil
+}
+
+// sample returns one sample from d.buffer scaled to [-1.0, 1.0]
+func (d *WAVDecoder) sample(frame, channel int) float64 {
+	width := d.layout.bits / 8
+	b := d.buffer[frame*d.layout.blockSize+channel*width:]
+	order := d.layout.order
+
+	if d.layout.float {
+		if width == 8 {
+			return math.Float64frombits(order.Uint64(b))
+		}
+		return float64(math.Float32frombits(order.Uint32(b)))
+	}
+
+	switch width {
+	case 1:
+		if d.layout.unsigned {
+			return (float64(b[0]) - 128) / 128
+		}
+		return float64(int8(b[0])) / 128
+	case 2:
+		return float64(int16(order.Uint16(b))) / 32768
+	case 3:
+		var v int32
+		if order == binary.ByteOrder(binary.LittleEndian) {
+			v = int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+		} else {
+			v = int32(b[2]) | int32(b[1])<<8 | int32(int8(b[0]))<<16
+		}
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(order.Uint32(b))) / (1 << 31)
+	}
+}
+
+// Decode reads and decodes audio data into float32 format
+func (d *WAVDecoder) Decode(buffer []float32) (int, error) {
+	channels := d.format.Channels
+	frames, err := d.readFrames(len(buffer) / channels)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < frames; i++ {
+		for c := 0; c < channels; c++ {
+			buffer[i*channels+c] = float32(d.sample(i, c))
+		}
+	}
+	return frames, nil
+}
+
+// DecodeInt16 reads and decodes audio data into int16 format
+func (d *WAVDecoder) DecodeInt16(buffer []int16) (int, error) {
+	channels := d.format.Channels
+	frames, err := d.readFrames(len(buffer) / channels)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < frames; i++ {
+		for c := 0; c < channels; c++ {
+			s := math.Max(-1, math.Min(1, d.sample(i, c)))
+			buffer[i*channels+c] = int16(s * 32767)
+		}
+	}
+	return frames, nil
+}
+
+// Seek seeks to the specified position
+func (d *WAVDecoder) Seek(position time.Duration) error {
+	targetSample := int64(position.Seconds() * float64(d.format.SampleRate))
+	return d.SeekSample(targetSample)
+}
+
+// SeekSample seeks straight to a sample, as every frame is the same size
+func (d *WAVDecoder) SeekSample(sample int64) error {
+	if sample < 0 || sample > d.sampleCount {
+		return fmt.Errorf("sample position out of range: %d", sample)
+	}
+
+	offset := d.dataStart + sample*int64(d.layout.blockSize)
+	if _, err := d.reader.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek: %w", err)
+	}
+	d.currentSample = sample
+	d.eof = false
+	return nil
+}
+
+// Close closes the decoder
+func (d *WAVDecoder) Close() error {
+	if closer, ok := d.reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// extendedToFloat converts the 80-bit IEEE extended float AIFF stores its
+// sample rate in
+func extendedToFloat(b []byte) float64 {
+	exponent := int(binary.BigEndian.Uint16(b[0:]) & 0x7FFF)
+	mantissa := binary.BigEndian.Uint64(b[2:])
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+	value := math.Ldexp(float64(mantissa), exponent-16383-63)
+	if b[0]&0x80 != 0 {
+		value = -value
+	}
+	return value
+}
+
+// WAVFactory creates decoders for WAV and AIFF files
+type WAVFactory struct{}
+
+// CreateDecoder creates a decoder for the given reader
+func (f *WAVFactory) CreateDecoder(reader io.ReadSeeker) (Decoder, error) {
+	return NewWAVDecoder(reader)
+}
+
+// CreateDecoderForFile creates a decoder for a file
+func (f *WAVFactory) CreateDecoderForFile(path string) (Decoder, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	decoder, err := NewWAVDecoder(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return decoder, nil
+}
+
+// CreateStreamDecoder creates a decoder for streaming
+func (f *WAVFactory) CreateStreamDecoder(reader io.Reader) (StreamDecoder, error) {
+	return nil, fmt.Errorf("streaming not yet implemented for WAV")
+}
+
+// SupportsFormat checks if the factory supports the given format
+func (f *WAVFactory) SupportsFormat(format string) bool {
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "wav", "wave", "aiff", "aif", "aifc", "audio/wav", "audio/wave", "audio/aiff", "audio/x-aiff":
+		return true
+	}
+	return false
+}
+
+// SupportedFormats returns a list of supported formats
+func (f *WAVFactory) SupportedFormats() []string {
+	return []string{"wav", "wave", "aiff", "aif", "aifc"}
+}
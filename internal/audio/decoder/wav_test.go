@@ -0,0 +1,256 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSamples are the stereo frames every test file holds
+var testSamples = []float64{0, 0, 0.5, -0.5, -1, 0.25}
+
+func TestWAVDecoder(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      []byte
+		bitDepth  int
+		encoding  string
+		tolerance float64
+	}{
+		{"WAV 8-bit", wavFile(wavFormatPCM, 8, nil), 8, "pcm", 1.0 / 128},
+		{"WAV 16-bit", wavFile(wavFormatPCM, 16, nil), 16, "pcm", 1.0 / 32768},
+		{"WAV 24-bit", wavFile(wavFormatPCM, 24, nil), 24, "pcm", 1.0 / (1 << 23)},
+		{"WAV 32-bit", wavFile(wavFormatPCM, 32, nil), 32, "pcm", 1.0 / (1 << 31)},
+		{"WAV float", wavFile(wavFormatFloat, 32, nil), 32, "float32", 0},
+		{"WAV extensible", wavFile(wavFormatExtensible, 16, nil), 16, "pcm", 1.0 / 32768},
+		{"AIFF 16-bit", aiffFile("", 16), 16, "pcm", 1.0 / 32768},
+		{"AIFF 24-bit", aiffFile("", 24), 24, "pcm", 1.0 / (1 << 23)},
+		{"AIFF-C sowt", aiffFile("sowt", 16), 16, "pcm", 1.0 / 32768},
+		{"AIFF-C fl32", aiffFile("fl32", 32), 32, "float32", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewWAVDecoder(bytes.NewReader(tt.file))
+			require.NoError(t, err)
+
+			format := d.Format()
+			assert.Equal(t, 44100, format.SampleRate)
+			assert.Equal(t, 2, format.Channels)
+			assert.Equal(t, tt.bitDepth, format.BitDepth)
+			assert.Equal(t, tt.encoding, format.Encoding)
+			assert.EqualValues(t, 3, d.SampleCount())
+
+			buffer := make([]float32, 16)
+			n, err := d.Decode(buffer)
+			require.NoError(t, err)
+			require.Equal(t, 3, n)
+			for i, want := range testSamples {
+				assert.InDelta(t, want, buffer[i], tt.tolerance, "sample %d", i)
+			}
+
+			_, err = d.Decode(buffer)
+			assert.ErrorIs(t, err, ErrEndOfStream)
+
+			// Every frame is the same size, so seeking is exact
+			require.NoError(t, d.SeekSample(2))
+			n, err = d.Decode(buffer)
+			require.NoError(t, err)
+			require.Equal(t, 1, n)
+			assert.InDelta(t, -1, buffer[0], tt.tolerance)
+		})
+	}
+}
+
+func TestWAVDecoderTags(t *testing.T) {
+	info := []byte("INFO")
+	for _, field := range [][2]string{{"INAM", "Song"}, {"IART", "Band"}, {"ICRD", "1999-05-01"}, {"ITRK", "7"}} {
+		info = append(info, field[0]...)
+		info = binary.LittleEndian.AppendUint32(info, uint32(len(field[1])+1))
+		info = append(info, field[1]+"\x00"...)
+		if len(field[1]+"\x00")%2 == 1 {
+			info = append(info, 0)
+		}
+	}
+
+	d, err := NewWAVDecoder(bytes.NewReader(wavFile(wavFormatPCM, 16, riffChunk("LIST", info))))
+	require.NoError(t, err)
+
+	metadata := d.Metadata()
+	assert.Equal(t, "Song", metadata.Title)
+	assert.Equal(t, "Band", metadata.Artist)
+	assert.Equal(t, 1999, metadata.Year)
+	assert.Equal(t, 7, metadata.TrackNumber)
+	assert.Equal(t, 44100*4*8, metadata.Bitrate)
+}
+
+func TestWAVDecoderTruncated(t *testing.T) {
+	// Files cut short while recording claim more data than they hold
+	file := wavFile(wavFormatPCM, 16, nil)
+	d, err := NewWAVDecoder(bytes.NewReader(file[:len(file)-4]))
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, d.SampleCount())
+	assert.Equal(t, 2*time.Second/44100, d.Duration())
+}
+
+func TestWAVDecoderErrors(t *testing.T) {
+	noData := riffChunk("fmt ", wavFormatChunk(wavFormatPCM, 16))
+
+	tests := []struct {
+		name string
+		file []byte
+		err  error
+	}{
+		{"empty", nil, ErrInvalidData},
+		{"not audio", []byte("RIFF\x00\x00\x00\x00AVI "), ErrUnsupportedFormat},
+		{"compressed", wavFile(0x0055, 16, nil), ErrUnsupportedFormat},
+		{"16-bit float", wavFile(wavFormatFloat, 16, nil), ErrUnsupportedFormat},
+		{"no data", append([]byte("RIFF\x00\x00\x00\x00WAVE"), noData...), ErrInvalidData},
+		{"no format", append([]byte("RIFF\x00\x00\x00\x00WAVE"), riffChunk("data", make([]byte, 4))...), ErrInvalidData},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWAVDecoder(bytes.NewReader(tt.file))
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestExtendedToFloat(t *testing.T) {
+	for _, rate := range []float64{8000, 22050, 44100, 48000, 96000, 192000} {
+		assert.Equal(t, rate, extendedToFloat(extendedBytes(rate)))
+	}
+	assert.Zero(t, extendedToFloat(make([]byte, 10)))
+}
+
+// wavFile builds a stereo 44.1 kHz WAV file of testSamples with any extra
+// chunks placed before the data
+func wavFile(formatTag uint16, bitDepth int, extra []byte) []byte {
+	body := []byte("WAVE")
+	body = append(body, riffChunk("fmt ", wavFormatChunk(formatTag, bitDepth))...)
+	body = append(body, extra...)
+
+	layout := pcmLayout{order: binary.LittleEndian, bits: bitDepth, unsigned: bitDepth == 8, float: formatTag == wavFormatFloat}
+	body = append(body, riffChunk("data", encodeSamples(layout))...)
+
+	file := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	return append(file, body...)
+}
+
+func wavFormatChunk(formatTag uint16, bitDepth int) []byte {
+	tag := formatTag
+	if formatTag == wavFormatExtensible {
+		tag = wavFormatPCM
+	}
+
+	b := binary.LittleEndian.AppendUint16(nil, formatTag)
+	b = binary.LittleEndian.AppendUint16(b, 2)
+	b = binary.LittleEndian.AppendUint32(b, 44100)
+	b = binary.LittleEndian.AppendUint32(b, uint32(44100*bitDepth/4))
+	b = binary.LittleEndian.AppendUint16(b, uint16(bitDepth/4))
+	b = binary.LittleEndian.AppendUint16(b, uint16(bitDepth))
+	if formatTag == wavFormatExtensible {
+		b = binary.LittleEndian.AppendUint16(b, 22)
+		b = binary.LittleEndian.AppendUint16(b, uint16(bitDepth))
+		b = binary.LittleEndian.AppendUint32(b, 3) // Front left and right
+		b = binary.LittleEndian.AppendUint16(b, tag)
+		b = append(b, make([]byte, 14)...) // Rest of the GUID
+	}
+	return b
+}
+
+func riffChunk(id string, data []byte) []byte {
+	chunk := append([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// aiffFile builds a stereo 44.1 kHz AIFF file of testSamples, or an AIFF-C
+// file when compression is set
+func aiffFile(compression string, bitDepth int) []byte {
+	comm := binary.BigEndian.AppendUint16(nil, 2)
+	comm = binary.BigEndian.AppendUint32(comm, 3)
+	comm = binary.BigEndian.AppendUint16(comm, uint16(bitDepth))
+	comm = append(comm, extendedBytes(44100)...)
+
+	layout := pcmLayout{order: binary.BigEndian, bits: bitDepth}
+	formType := "AIFF"
+	if compression != "" {
+		formType = "AIFC"
+		comm = append(comm, compression...)
+		comm = append(comm, 0, 0) // Empty compression name
+		switch compression {
+		case "sowt":
+			layout.order = binary.LittleEndian
+		case "fl32":
+			layout.float = true
+		}
+	}
+
+	ssnd := append(make([]byte, 8), encodeSamples(layout)...)
+	body := append([]byte(formType), iffChunk("COMM", comm)...)
+	body = append(body, iffChunk("NAME", []byte("Song"))...)
+	body = append(body, iffChunk("SSND", ssnd)...)
+
+	file := append([]byte("FORM"), binary.BigEndian.AppendUint32(nil, uint32(len(body)))...)
+	return append(file, body...)
+}
+
+func iffChunk(id string, data []byte) []byte {
+	chunk := append([]byte(id), binary.BigEndian.AppendUint32(nil, uint32(len(data)))...)
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// encodeSamples stores testSamples in a layout, clipping full scale
+func encodeSamples(layout pcmLayout) []byte {
+	order := layout.order.(binary.AppendByteOrder)
+	var b []byte
+	for _, s := range testSamples {
+		switch {
+		case layout.float:
+			b = order.AppendUint32(b, math.Float32bits(float32(s)))
+		case layout.bits == 8:
+			v := int(math.Min(s*128, 127))
+			if layout.unsigned {
+				v += 128
+			}
+			b = append(b, byte(v))
+		case layout.bits == 16:
+			b = order.AppendUint16(b, uint16(int16(math.Min(s*32768, 32767))))
+		case layout.bits == 24:
+			v := uint32(int32(math.Min(s*(1<<23), 1<<23-1)))
+			if layout.order == binary.ByteOrder(binary.LittleEndian) {
+				b = append(b, byte(v), byte(v>>8), byte(v>>16))
+			} else {
+				b = append(b, byte(v>>16), byte(v>>8), byte(v))
+			}
+		default:
+			b = order.AppendUint32(b, uint32(int32(math.Min(s*(1<<31), 1<<31-1))))
+		}
+	}
+	return b
+}
+
+// extendedBytes encodes a positive whole number as an 80-bit extended float
+func extendedBytes(value float64) []byte {
+	mantissa := uint64(value)
+	shift := bits.LeadingZeros64(mantissa)
+	exponent := 16383 + 63 - shift
+
+	b := binary.BigEndian.AppendUint16(nil, uint16(exponent))
+	return binary.BigEndian.AppendUint64(b, mantissa<<shift)
+}
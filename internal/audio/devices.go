@@ -0,0 +1,68 @@
+package audio
+
+import (
+	"github.com/winramp/winramp/internal/audio/output"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// DeviceChange is sent with EventDevicesChanged when output devices are
+// plugged in or removed
+type DeviceChange struct {
+	Added   []*output.Device
+	Removed []*output.Device
+	Lost    *output.Device // The device playing, when it was removed
+	Paused  bool           // Playback was paused because its device went
+}
+
+// SetPauseOnDeviceLost sets whether playback pauses when its output device
+// is removed, as when unplugging headphones, rather than carrying on through
+// the default device
+func (p *Player) SetPauseOnDeviceLost(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pauseOnLost = enabled
+}
+
+// onDevicesChanged moves playback to the default device when the device
+// playing is removed, keeping its position
+func (p *Player) onDevicesChanged(added, removed []*output.Device) {
+	change := &DeviceChange{Added: added, Removed: removed}
+
+	p.mu.Lock()
+	if p.output != nil {
+		current := p.output.GetDevice()
+		for _, device := range removed {
+			if current != nil && device.ID == current.ID {
+				change.Lost = device
+			}
+		}
+	}
+	if change.Lost != nil {
+		p.loseDevice(change)
+	}
+	p.mu.Unlock()
+
+	p.notifyListeners(EventDevicesChanged, change)
+}
+
+// loseDevice falls back to the default device, pausing first when set to.
+// Must be called with p.mu held.
+func (p *Player) loseDevice(change *DeviceChange) {
+	logger.Warn("Output device removed, falling back to the default device",
+		logger.String("device", change.Lost.Name))
+
+	if p.pauseOnLost && (p.state == StatePlaying || p.state == StateBuffering) {
+		// The device is gone, so there is nothing to fade out on
+		p.fader.set(1.0)
+		p.setState(StatePaused)
+		change.Paused = true
+	}
+
+	device, err := p.deviceManager.GetDefaultDevice()
+	if err == nil {
+		err = p.switchOutput(device)
+	}
+	if err != nil {
+		logger.ErrorLog("Failed to open the default output device", logger.Error(err))
+	}
+}
@@ -0,0 +1,284 @@
+// Package diagnostics plays test signals through an audio output to check
+// that it works: a tone on each channel in turn, sweeps at each sample rate
+// the device claims, and silence. It is meant for trying out new output
+// backends and for troubleshooting a setup without the user interface.
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/winramp/winramp/internal/audio/output"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// Test is one kind of check
+type Test string
+
+const (
+	TestChannels Test = "channels" // A tone on each channel in turn
+	TestSweep    Test = "sweep"    // A sweep at each sample rate
+	TestSilence  Test = "silence"  // Silence, which should play without noise
+)
+
+// AllTests are the tests run when none are asked for
+var AllTests = []Test{TestChannels, TestSweep, TestSilence}
+
+const (
+	DefaultLevelDB     = -18.0
+	DefaultToneLength  = time.Second
+	DefaultSweepLength = 3 * time.Second
+
+	toneFrequency = 440.0
+	lfeFrequency  = 60.0
+	sweepFrom     = 20.0
+	sweepTo       = 20000.0
+
+	chunkFrames = 1024
+	latency     = 100 * time.Millisecond
+	pause       = 300 * time.Millisecond // Between signals, to tell them apart
+)
+
+// Options choose which tests run and how they sound
+type Options struct {
+	Tests       []Test        // Defaults to AllTests
+	LevelDB     float64       // Level of tones and sweeps in dBFS, defaults to DefaultLevelDB
+	ToneLength  time.Duration // Per channel
+	SweepLength time.Duration // Per sample rate
+	SampleRates []int         // Defaults to the rates the device claims
+}
+
+func (o Options) withDefaults() Options {
+	if len(o.Tests) == 0 {
+		o.Tests = AllTests
+	}
+	if o.LevelDB >= 0 {
+		o.LevelDB = DefaultLevelDB
+	}
+	if o.ToneLength <= 0 {
+		o.ToneLength = DefaultToneLength
+	}
+	if o.SweepLength <= 0 {
+		o.SweepLength = DefaultSweepLength
+	}
+	return o
+}
+
+// Result is the outcome of playing one signal
+type Result struct {
+	Test       Test          `json:"test"`
+	Name       string        `json:"name"` // What played, such as "Left" or "48000 Hz"
+	SampleRate int           `json:"sampleRate"`
+	Channels   int           `json:"channels"`
+	Passed     bool          `json:"passed"`
+	Error      string        `json:"error,omitempty"`
+	Length     time.Duration `json:"length"`  // Length of the signal
+	Elapsed    time.Duration `json:"elapsed"` // How long the output took to accept it
+}
+
+// Report is the outcome of a diagnostics run
+type Report struct {
+	Device    string    `json:"device"`
+	Backend   string    `json:"backend"`
+	StartedAt time.Time `json:"startedAt"`
+	Results   []Result  `json:"results"`
+}
+
+// Passed reports whether every signal played
+func (r *Report) Passed() bool {
+	for _, result := range r.Results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Write prints the report for reading in a terminal
+func (r *Report) Write(w io.Writer) {
+	fmt.Fprintf(w, "Audio diagnostics for %s (%s)\n", r.Device, r.Backend)
+	for _, result := range r.Results {
+		status := "ok"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "  %-4s %-8s %-12s %6d Hz %dch  %v in %v",
+			status, result.Test, result.Name, result.SampleRate, result.Channels,
+			result.Length.Round(time.Millisecond), result.Elapsed.Round(time.Millisecond))
+		if result.Error != "" {
+			fmt.Fprintf(w, "  %s", result.Error)
+		}
+		fmt.Fprintln(w)
+	}
+	if r.Passed() {
+		fmt.Fprintln(w, "All signals played")
+	} else {
+		fmt.Fprintln(w, "Some signals failed")
+	}
+}
+
+// Run plays the test signals on a device, "" or "default" meaning the
+// default device. A signal that fails is recorded and the run goes on; an
+// error is returned only for bad options, a missing device or ctx ending.
+func Run(ctx context.Context, devices output.DeviceManager, deviceID string, opts Options) (*Report, error) {
+	opts = opts.withDefaults()
+	for _, test := range opts.Tests {
+		if !slices.Contains(AllTests, test) {
+			return nil, fmt.Errorf("unknown test %q", test)
+		}
+	}
+
+	var device *output.Device
+	var err error
+	if deviceID == "" || deviceID == "default" {
+		device, err = devices.GetDefaultDevice()
+	} else {
+		device, err = devices.GetDevice(deviceID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device %q: %w", deviceID, err)
+	}
+
+	report := &Report{
+		Device:    device.Name,
+		Backend:   device.Type,
+		StartedAt: time.Now(),
+	}
+	r := &runner{ctx: ctx, devices: devices, device: device, opts: opts, report: report}
+
+	channels := max(device.MaxChannels, 1)
+	rate := preferredRate(device.SampleRates)
+	rates := opts.SampleRates
+	if len(rates) == 0 {
+		rates = device.SampleRates
+	}
+	if len(rates) == 0 {
+		rates = []int{rate}
+	}
+
+	amplitude := dbToAmplitude(opts.LevelDB)
+	for _, test := range opts.Tests {
+		switch test {
+		case TestChannels:
+			for c := 0; c < channels; c++ {
+				freq := toneFrequency
+				if isLFE(c, channels) {
+					freq = lfeFrequency
+				}
+				r.play(test, ChannelName(c, channels), rate, channels,
+					Tone(freq, amplitude, c, channels, rate, opts.ToneLength))
+			}
+		case TestSweep:
+			for _, sweepRate := range rates {
+				top := min(sweepTo, float64(sweepRate)*0.45)
+				r.play(test, fmt.Sprintf("%d Hz", sweepRate), sweepRate, channels,
+					Sweep(sweepFrom, top, amplitude, channels, sweepRate, opts.SweepLength))
+			}
+		case TestSilence:
+			r.play(test, "Silence", rate, channels, Silence(channels, rate, opts.ToneLength))
+		}
+
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+	}
+
+	logger.Info("Audio diagnostics finished",
+		logger.String("device", report.Device),
+		logger.Int("signals", len(report.Results)),
+		logger.Bool("passed", report.Passed()))
+	return report, nil
+}
+
+type runner struct {
+	ctx     context.Context
+	devices output.DeviceManager
+	device  *output.Device
+	opts    Options
+	report  *Report
+}
+
+// play opens the device at a format, plays samples through it and records
+// the result
+func (r *runner) play(test Test, name string, rate, channels int, samples []float32) {
+	if r.ctx.Err() != nil {
+		return
+	}
+
+	result := Result{
+		Test:       test,
+		Name:       name,
+		SampleRate: rate,
+		Channels:   channels,
+		Length:     time.Duration(len(samples)/channels) * time.Second / time.Duration(rate),
+	}
+
+	started := time.Now()
+	err := r.write(rate, channels, samples)
+	result.Elapsed = time.Since(started)
+
+	// An output taking audio far faster than it can play it is dropping it
+	if err == nil && result.Length > 2*latency && result.Elapsed < result.Length/2 {
+		err = errors.New("output accepted audio faster than real time")
+	}
+	if err != nil {
+		result.Error = err.Error()
+		logger.Warn("Audio diagnostic failed",
+			logger.String("test", string(test)),
+			logger.String("signal", name),
+			logger.Int("sample_rate", rate),
+			logger.Int("channels", channels),
+			logger.Error(err))
+	} else {
+		result.Passed = true
+	}
+	r.report.Results = append(r.report.Results, result)
+
+	select {
+	case <-r.ctx.Done():
+	case <-time.After(pause):
+	}
+}
+
+func (r *runner) write(rate, channels int, samples []float32) error {
+	out, err := r.devices.CreateOutput(r.device)
+	if err != nil {
+		return err
+	}
+	format := output.Format{SampleRate: rate, Channels: channels, BitDepth: 16, Latency: latency}
+	if err := out.Open(format); err != nil {
+		return fmt.Errorf("failed to open output: %w", err)
+	}
+	defer out.Close()
+
+	chunk := chunkFrames * channels
+	for start := 0; start < len(samples); start += chunk {
+		if err := r.ctx.Err(); err != nil {
+			return err
+		}
+		end := min(start+chunk, len(samples))
+		if _, err := out.Write(samples[start:end]); err != nil {
+			return err
+		}
+	}
+
+	// Let the last of the signal play out before closing
+	select {
+	case <-r.ctx.Done():
+	case <-time.After(out.GetLatency()):
+	}
+	return nil
+}
+
+// preferredRate picks the rate channel tests run at: CD rate where the
+// device has it, as that is what the player opens with
+func preferredRate(rates []int) int {
+	if len(rates) == 0 || slices.Contains(rates, 44100) {
+		return 44100
+	}
+	return rates[0]
+}
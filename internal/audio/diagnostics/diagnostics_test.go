@@ -0,0 +1,216 @@
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/audio/output"
+)
+
+func TestToneSignal(t *testing.T) {
+	samples := Tone(1000, 0.5, 1, 2, 48000, 100*time.Millisecond)
+	require.Len(t, samples, 4800*2)
+
+	peak := 0.0
+	for i := 0; i < len(samples); i += 2 {
+		assert.Zero(t, samples[i], "left channel should be silent")
+		peak = math.Max(peak, math.Abs(float64(samples[i+1])))
+	}
+	assert.InDelta(t, 0.5, peak, 0.01)
+
+	// Ramped in and out so it doesn't click
+	assert.Zero(t, samples[1])
+	assert.Less(t, math.Abs(float64(samples[len(samples)-1])), 0.01)
+
+	// A channel of -1 plays on all
+	all := Tone(1000, 0.5, -1, 2, 48000, 100*time.Millisecond)
+	for i := 0; i < len(all); i += 2 {
+		assert.Equal(t, all[i], all[i+1])
+	}
+}
+
+func TestSweepSignal(t *testing.T) {
+	samples := Sweep(20, 20000, 0.25, 1, 44100, time.Second)
+	require.Len(t, samples, 44100)
+
+	// The sweep speeds up: the second half crosses zero far more often
+	crossings := func(s []float32) int {
+		n := 0
+		for i := 1; i < len(s); i++ {
+			if (s[i-1] < 0) != (s[i] < 0) {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Greater(t, crossings(samples[22050:]), 10*crossings(samples[:22050]))
+
+	for _, s := range samples {
+		assert.LessOrEqual(t, math.Abs(float64(s)), 0.25+1e-6)
+	}
+	assert.Empty(t, Sweep(20, 20000, 0.25, 2, 44100, 0))
+}
+
+func TestSilenceSignal(t *testing.T) {
+	samples := Silence(2, 44100, 500*time.Millisecond)
+	assert.Len(t, samples, 44100)
+	for _, s := range samples {
+		assert.Zero(t, s)
+	}
+}
+
+func TestChannelName(t *testing.T) {
+	tests := []struct {
+		channel, channels int
+		name              string
+	}{
+		{0, 1, "Mono"},
+		{1, 2, "Right"},
+		{3, 6, "LFE"},
+		{7, 8, "Side right"},
+		{2, 3, "Channel 3"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.name, ChannelName(tt.channel, tt.channels))
+	}
+	assert.True(t, isLFE(3, 6))
+	assert.False(t, isLFE(3, 4))
+}
+
+func TestRun(t *testing.T) {
+	devices := &fakeDevices{device: &output.Device{
+		ID:          "speakers",
+		Name:        "Speakers",
+		Type:        "Test",
+		MaxChannels: 2,
+		SampleRates: []int{44100, 48000, 96000},
+	}, failRate: 96000}
+
+	report, err := Run(context.Background(), devices, "default", Options{
+		ToneLength:  50 * time.Millisecond,
+		SweepLength: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Speakers", report.Device)
+
+	var names []string
+	for _, result := range report.Results {
+		names = append(names, result.Name)
+	}
+	assert.Equal(t, []string{"Left", "Right", "44100 Hz", "48000 Hz", "96000 Hz", "Silence"}, names)
+
+	// A failing signal is recorded and the run goes on
+	assert.False(t, report.Passed())
+	assert.False(t, report.Results[4].Passed)
+	assert.Contains(t, report.Results[4].Error, "unsupported rate")
+	assert.True(t, report.Results[5].Passed)
+
+	var b bytes.Buffer
+	report.Write(&b)
+	assert.Contains(t, b.String(), "FAIL")
+	assert.Contains(t, b.String(), "Some signals failed")
+
+	// Each signal was played in full
+	devices.mu.Lock()
+	assert.Equal(t, 2205*2, devices.written[0])
+	devices.mu.Unlock()
+}
+
+func TestRunDetectsDroppedAudio(t *testing.T) {
+	devices := &fakeDevices{device: &output.Device{ID: "d", Name: "Fast", MaxChannels: 1}}
+
+	// The fake output takes a second of audio instantly
+	report, err := Run(context.Background(), devices, "d", Options{Tests: []Test{TestSilence}, ToneLength: time.Second})
+	require.NoError(t, err)
+	require.Len(t, report.Results, 1)
+	assert.Contains(t, report.Results[0].Error, "faster than real time")
+}
+
+func TestRunErrors(t *testing.T) {
+	devices := &fakeDevices{device: &output.Device{ID: "d"}}
+
+	_, err := Run(context.Background(), devices, "d", Options{Tests: []Test{"loudness"}})
+	assert.ErrorContains(t, err, "unknown test")
+
+	_, err = Run(context.Background(), devices, "missing", Options{})
+	assert.ErrorIs(t, err, output.ErrDeviceNotFound)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := Run(ctx, devices, "d", Options{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, report.Results)
+}
+
+type fakeDevices struct {
+	device   *output.Device
+	failRate int
+
+	mu      sync.Mutex
+	written []int // Samples written to each output
+}
+
+func (f *fakeDevices) EnumerateDevices() ([]*output.Device, error) {
+	return []*output.Device{f.device}, nil
+}
+
+func (f *fakeDevices) GetDefaultDevice() (*output.Device, error) { return f.device, nil }
+
+func (f *fakeDevices) GetDevice(id string) (*output.Device, error) {
+	if id != f.device.ID {
+		return nil, output.ErrDeviceNotFound
+	}
+	return f.device, nil
+}
+
+func (f *fakeDevices) CreateOutput(device *output.Device) (output.Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.written = append(f.written, 0)
+	return &fakeOutput{devices: f, index: len(f.written) - 1}, nil
+}
+
+func (f *fakeDevices) SetDefaultDevice(id string) error { return nil }
+
+func (f *fakeDevices) WatchDevices(callback func(added, removed []*output.Device)) {}
+
+type fakeOutput struct {
+	devices *fakeDevices
+	index   int
+}
+
+func (o *fakeOutput) Open(format output.Format) error {
+	if format.SampleRate == o.devices.failRate {
+		return errors.New("unsupported rate")
+	}
+	return nil
+}
+
+func (o *fakeOutput) Write(samples []float32) (int, error) {
+	o.devices.mu.Lock()
+	defer o.devices.mu.Unlock()
+	o.devices.written[o.index] += len(samples)
+	return len(samples), nil
+}
+
+func (o *fakeOutput) WriteInt16(samples []int16) (int, error) { return len(samples), nil }
+func (o *fakeOutput) Close() error                            { return nil }
+func (o *fakeOutput) Pause() error                            { return nil }
+func (o *fakeOutput) Resume() error                           { return nil }
+func (o *fakeOutput) Flush() error                            { return nil }
+func (o *fakeOutput) GetLatency() time.Duration               { return time.Millisecond }
+func (o *fakeOutput) GetBufferSize() int                      { return 0 }
+func (o *fakeOutput) SetVolume(volume float64) error          { return nil }
+func (o *fakeOutput) GetVolume() float64                      { return 1 }
+func (o *fakeOutput) IsPlaying() bool                         { return true }
+func (o *fakeOutput) GetDevice() *output.Device               { return o.devices.device }
+func (o *fakeOutput) GetPosition() time.Duration              { return 0 }
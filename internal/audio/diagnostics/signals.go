@@ -0,0 +1,109 @@
+package diagnostics
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// rampLength fades test signals in and out so they start and stop without
+// clicks
+const rampLength = 10 * time.Millisecond
+
+// Tone returns a sine tone at freq Hz on one channel of an interleaved
+// buffer, with the other channels silent. A channel of -1 plays on all.
+func Tone(freq float64, amplitude float64, channel, channels, sampleRate int, length time.Duration) []float32 {
+	frames := framesFor(length, sampleRate)
+	samples := make([]float32, frames*channels)
+	step := 2 * math.Pi * freq / float64(sampleRate)
+
+	for i := 0; i < frames; i++ {
+		value := float32(amplitude * ramp(i, frames, sampleRate) * math.Sin(step*float64(i)))
+		for c := 0; c < channels; c++ {
+			if channel < 0 || c == channel {
+				samples[i*channels+c] = value
+			}
+		}
+	}
+	return samples
+}
+
+// Sweep returns a logarithmic sine sweep from one frequency to another on
+// all channels, which exercises the output across the audible range
+func Sweep(from, to float64, amplitude float64, channels, sampleRate int, length time.Duration) []float32 {
+	frames := framesFor(length, sampleRate)
+	samples := make([]float32, frames*channels)
+	if frames == 0 {
+		return samples
+	}
+
+	// Phase of an exponential chirp, so each octave takes the same time
+	seconds := length.Seconds()
+	rate := math.Log(to / from)
+	phase := func(t float64) float64 {
+		return 2 * math.Pi * from * seconds / rate * (math.Exp(t/seconds*rate) - 1)
+	}
+
+	for i := 0; i < frames; i++ {
+		t := float64(i) / float64(sampleRate)
+		value := float32(amplitude * ramp(i, frames, sampleRate) * math.Sin(phase(t)))
+		for c := 0; c < channels; c++ {
+			samples[i*channels+c] = value
+		}
+	}
+	return samples
+}
+
+// Silence returns digital silence on all channels
+func Silence(channels, sampleRate int, length time.Duration) []float32 {
+	return make([]float32, framesFor(length, sampleRate)*channels)
+}
+
+func framesFor(length time.Duration, sampleRate int) int {
+	return int(length.Seconds() * float64(sampleRate))
+}
+
+// ramp returns the fade gain for frame i of frames
+func ramp(i, frames, sampleRate int) float64 {
+	edge := max(framesFor(rampLength, sampleRate), 1)
+	switch {
+	case i < edge:
+		return float64(i) / float64(edge)
+	case frames-i < edge:
+		return float64(frames-i) / float64(edge)
+	}
+	return 1
+}
+
+// dbToAmplitude converts a level in dBFS to a linear amplitude
+func dbToAmplitude(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// ChannelName returns the speaker a channel usually feeds in a layout of
+// the given size
+func ChannelName(channel, channels int) string {
+	var names []string
+	switch channels {
+	case 1:
+		names = []string{"Mono"}
+	case 2:
+		names = []string{"Left", "Right"}
+	case 4:
+		names = []string{"Front left", "Front right", "Rear left", "Rear right"}
+	case 6:
+		names = []string{"Front left", "Front right", "Centre", "LFE", "Rear left", "Rear right"}
+	case 8:
+		names = []string{"Front left", "Front right", "Centre", "LFE", "Rear left", "Rear right", "Side left", "Side right"}
+	}
+	if channel < len(names) {
+		return names[channel]
+	}
+	return "Channel " + strconv.Itoa(channel+1)
+}
+
+// isLFE reports whether a channel is the low frequency effects channel,
+// which only reproduces bass
+func isLFE(channel, channels int) bool {
+	return channel == 3 && (channels == 6 || channels == 8)
+}
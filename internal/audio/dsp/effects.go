@@ -68,6 +68,73 @@ func (c *EffectChain) RemoveEffect(name string) error {
 	return ErrEffectNotFound
 }
 
+// Effect returns the effect with a name
+func (c *EffectChain) Effect(name string) (Effect, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	
+	for _, effect := range c.effects {
+		if effect.GetName() == name {
+			return effect, nil
+		}
+	}
+	return nil, ErrEffectNotFound
+}
+
+// Names returns the effects' names in processing order
+func (c *EffectChain) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	
+	names := make([]string, len(c.effects))
+	for i, effect := range c.effects {
+		names[i] = effect.GetName()
+	}
+	return names
+}
+
+// SetOrder reorders the effects by name. Effects left out follow the named
+// ones in their current order.
+func (c *EffectChain) SetOrder(names []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	ordered := make([]Effect, 0, len(c.effects))
+	placed := make(map[string]bool, len(names))
+	for _, name := range names {
+		found := false
+		for _, effect := range c.effects {
+			if effect.GetName() == name && !placed[name] {
+				ordered = append(ordered, effect)
+				placed[name] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrEffectNotFound
+		}
+	}
+	for _, effect := range c.effects {
+		if !placed[effect.GetName()] {
+			ordered = append(ordered, effect)
+		}
+	}
+	
+	c.effects = ordered
+	return nil
+}
+
+// SetEffectEnabled bypasses an effect, or puts it back in the chain
+func (c *EffectChain) SetEffectEnabled(name string, enabled bool) error {
+	effect, err := c.Effect(name)
+	if err != nil {
+		return err
+	}
+	effect.SetEnabled(enabled)
+	return nil
+}
+
 // Process applies all effects in the chain
 func (c *EffectChain) Process(samples []float32) {
 	c.mu.RLock()
@@ -369,6 +436,80 @@ func (r *ReplayGain) GetName() string {
 	return "ReplayGain"
 }
 
+// Preamp applies a fixed gain ahead of the other effects
+type Preamp struct {
+	gainDB  float64
+	gain    float32 // Linear
+	enabled bool
+	mu      sync.RWMutex
+}
+
+// NewPreamp creates a preamp at 0 dB
+func NewPreamp() *Preamp {
+	return &Preamp{
+		gain:    1.0,
+		enabled: true,
+	}
+}
+
+// SetGainDB sets the preamp gain in dB
+func (p *Preamp) SetGainDB(db float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.gainDB = db
+	p.gain = float32(math.Pow(10, db/20))
+}
+
+// GainDB returns the preamp gain in dB
+func (p *Preamp) GainDB() float64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.gainDB
+}
+
+// Process applies the gain to samples
+func (p *Preamp) Process(samples []float32) {
+	p.mu.RLock()
+	gain := p.gain
+	enabled := p.enabled
+	p.mu.RUnlock()
+	
+	if !enabled || gain == 1.0 {
+		return
+	}
+	for i := range samples {
+		samples[i] *= gain
+	}
+}
+
+// ProcessStereo applies the gain to stereo samples
+func (p *Preamp) ProcessStereo(left, right []float32) {
+	p.Process(left)
+	p.Process(right)
+}
+
+// SetEnabled enables or disables the preamp
+func (p *Preamp) SetEnabled(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.enabled = enabled
+}
+
+// IsEnabled returns whether the preamp is enabled
+func (p *Preamp) IsEnabled() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.enabled
+}
+
+// Reset does nothing; the preamp keeps no state
+func (p *Preamp) Reset() {}
+
+// GetName returns the effect name
+func (p *Preamp) GetName() string {
+	return "Preamp"
+}
+
 // Limiter implements a simple audio limiter
 type Limiter struct {
 	threshold   float64
@@ -397,44 +538,35 @@ func NewLimiter(sampleRate int) *Limiter {
 // Process applies limiting to samples
 func (l *Limiter) Process(samples []float32) {
 	l.mu.RLock()
-	threshold := float32(l.threshold)
-	ratio := float32(l.ratio)
+	threshold := l.threshold
+	ratio := l.ratio
 	enabled := l.enabled
+	sampleRate := float64(l.sampleRate)
 	l.mu.RUnlock()
 	
 	if !enabled {
 		return
 	}
 	
-	attackCoeff := float32(math.Exp(-1.0 / (l.attack * float64(l.sampleRate))))
-	releaseCoeff := float32(math.Exp(-1.0 / (l.release * float64(l.sampleRate))))
+	attackCoeff := math.Exp(-1.0 / (l.attack * sampleRate))
+	releaseCoeff := math.Exp(-1.0 / (l.release * sampleRate))
 	
 	for i := range samples {
-		input := samples[i]
-		absInput := input
-		if absInput < 0 {
-			absInput = -absInput
-		}
-		
-		// Update envelope
-		targetEnv := float32(0.0)
-		if absInput > threshold {
-			targetEnv = absInput - threshold
-		}
+		input := float64(samples[i])
 		
-		var envCoeff float32
-		if targetEnv > l.envelope {
+		// Follow the signal's peak level
+		level := math.Abs(input)
+		envCoeff := releaseCoeff
+		if level > l.envelope {
 			envCoeff = attackCoeff
-		} else {
-			envCoeff = releaseCoeff
 		}
+		l.envelope = level + (l.envelope-level)*envCoeff
 		
-		l.envelope = targetEnv + (l.envelope-targetEnv)*float64(envCoeff)
-		
-		// Apply limiting
-		if l.envelope > 0 {
-			gain := 1.0 - (l.envelope * float64(1.0-1.0/ratio))
-			samples[i] = float32(float64(input) * gain)
+		// Scale the part of the envelope over the threshold down by the
+		// ratio
+		if l.envelope > threshold {
+			limited := threshold + (l.envelope-threshold)/ratio
+			samples[i] = float32(input * limited / l.envelope)
 		}
 	}
 }
@@ -456,6 +588,18 @@ func (l *Limiter) ProcessStereo(left, right []float32) {
 	}
 }
 
+// SetSampleRate sets the rate the attack and release times are timed at
+func (l *Limiter) SetSampleRate(sampleRate int) {
+	if sampleRate <= 0 {
+		return
+	}
+	
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sampleRate = sampleRate
+	l.envelope = 0.0
+}
+
 // SetEnabled enables or disables the limiter
 func (l *Limiter) SetEnabled(enabled bool) {
 	l.mu.Lock()
@@ -133,6 +133,43 @@ func (eq *Equalizer) IsEnabled() bool {
 	return eq.enabled
 }
 
+// SetSampleRate retunes the bands for a new sample rate, as when the
+// output is opened on a device running at another rate
+func (eq *Equalizer) SetSampleRate(sampleRate int) {
+	if sampleRate <= 0 {
+		return
+	}
+	
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+	
+	if sampleRate == eq.sampleRate {
+		return
+	}
+	eq.sampleRate = sampleRate
+	for i := 0; i < 10; i++ {
+		eq.filters[i].setSampleRate(sampleRate)
+		eq.updateFilter(i)
+	}
+}
+
+// GetName returns the effect name
+func (eq *Equalizer) GetName() string {
+	return "Equalizer"
+}
+
+// Frequencies returns the bands' center frequencies in Hz
+func (eq *Equalizer) Frequencies() [10]float64 {
+	eq.mu.RLock()
+	defer eq.mu.RUnlock()
+	
+	var frequencies [10]float64
+	for i := 0; i < 10; i++ {
+		frequencies[i] = eq.bands[i].Frequency
+	}
+	return frequencies
+}
+
 // Process applies equalization to audio samples
 func (eq *Equalizer) Process(samples []float32) {
 	eq.mu.RLock()
@@ -232,6 +269,13 @@ func (eq *Equalizer) updateFilter(band int) {
 	
 	b := eq.bands[band]
 	
+	// A band at or past the Nyquist frequency can't be filtered, and is
+	// left flat
+	if b.Frequency >= float64(eq.sampleRate)*0.45 {
+		eq.filters[band].SetCoefficients(1, 0, 0, 0, 0)
+		return
+	}
+	
 	// Convert gain from dB to linear
 	gain := math.Pow(10, b.Gain/20)
 	
@@ -368,6 +412,15 @@ func (f *BiquadFilter) ProcessStereo(left, right []float32) {
 	f.mu.Unlock()
 }
 
+// setSampleRate sets the rate the filter runs at, clearing its state
+func (f *BiquadFilter) setSampleRate(sampleRate int) {
+	f.Reset()
+	
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sampleRate = sampleRate
+}
+
 // Reset resets the filter state
 func (f *BiquadFilter) Reset() {
 	f.mu.Lock()
@@ -0,0 +1,94 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+const (
+	// responsePoints is how many frequencies, spaced logarithmically across
+	// the audible range, the equalizer's response is measured at
+	responsePoints = 240
+
+	// clipMarginDB is how far over full scale a peak may go before it is
+	// reported, allowing for rounding in stored peaks
+	clipMarginDB = 0.05
+)
+
+// Headroom is the result of checking whether the gain ahead of the output
+// would push a track past full scale. In exclusive mode nothing after the
+// gain stage touches the samples, so the limiter that would otherwise
+// catch overs is bypassed and they clip.
+type Headroom struct {
+	PreampDB float64 `json:"preampDb"`
+	EQGainDB float64 `json:"eqGainDb"` // Largest boost at any frequency
+	PeakDB   float64 `json:"peakDb"`   // Track peak in dBFS, 0 when unknown
+	OverDB   float64 `json:"overDb"`   // How far the boosted peak exceeds full scale
+	// SuggestedPreampDB is the preamp that would just avoid clipping
+	SuggestedPreampDB float64 `json:"suggestedPreampDb"`
+}
+
+// Clips reports whether the gain would push the peak over full scale
+func (h Headroom) Clips() bool {
+	return h.OverDB > clipMarginDB
+}
+
+// CheckHeadroom works out whether a preamp and equalizer would clip a track
+// with a linear sample peak. A peak of 0 means unknown and is taken as full
+// scale, as most modern masters reach it. eq may be nil.
+func CheckHeadroom(preampDB float64, eq *Equalizer, peak float64) Headroom {
+	if peak <= 0 {
+		peak = 1
+	}
+
+	h := Headroom{
+		PreampDB: preampDB,
+		PeakDB:   20 * math.Log10(peak),
+	}
+	if eq != nil {
+		h.EQGainDB = eq.MaxGainDB()
+	}
+
+	over := h.PeakDB + h.PreampDB + h.EQGainDB
+	h.OverDB = math.Max(over, 0)
+	h.SuggestedPreampDB = math.Min(preampDB, math.Floor((preampDB-over)*10)/10)
+	return h
+}
+
+// MaxGainDB returns the largest gain the equalizer applies at any audible
+// frequency, in dB. Neighbouring boosted bands overlap, so this can exceed
+// the largest band gain. A disabled equalizer applies none.
+func (eq *Equalizer) MaxGainDB() float64 {
+	eq.mu.RLock()
+	defer eq.mu.RUnlock()
+
+	if !eq.enabled {
+		return 0
+	}
+
+	nyquist := float64(eq.sampleRate) / 2
+	low, high := 20.0, math.Min(20000, nyquist*0.99)
+	maxGain := 0.0
+	for i := 0; i < responsePoints; i++ {
+		freq := low * math.Pow(high/low, float64(i)/float64(responsePoints-1))
+
+		magnitude := 1.0
+		for _, filter := range eq.filters {
+			magnitude *= filter.response(freq)
+		}
+		maxGain = math.Max(maxGain, 20*math.Log10(magnitude))
+	}
+	return maxGain
+}
+
+// response returns the filter's magnitude response at a frequency
+func (f *BiquadFilter) response(freq float64) float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	z := cmplx.Exp(complex(0, -2*math.Pi*freq/float64(f.sampleRate)))
+	z2 := z * z
+	numerator := complex(f.b0, 0) + complex(f.b1, 0)*z + complex(f.b2, 0)*z2
+	denominator := 1 + complex(f.a1, 0)*z + complex(f.a2, 0)*z2
+	return cmplx.Abs(numerator / denominator)
+}
@@ -0,0 +1,177 @@
+package dsp
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// stretchFrame is the length of the segments audio is cut into, long
+	// enough to hold a couple of pitch periods of a low voice
+	stretchFrame = 30 * time.Millisecond
+
+	// stretchSearch is how far from its nominal position a segment may be
+	// taken to line up with the one before
+	stretchSearch = 10 * time.Millisecond
+
+	// stretchCoarse is the step of the first pass of the search, which the
+	// second refines
+	stretchCoarse = 4
+)
+
+// TimeStretch changes the tempo of audio without changing its pitch, by
+// waveform-similarity overlap-add (WSOLA). Audio is cut into overlapping
+// segments that are laid down at a fixed hop; speeding up takes them from
+// further apart in the input, slowing down from closer together. Each
+// segment is taken from near its nominal position where it best continues
+// the one before, so the joins don't beat or echo.
+type TimeStretch struct {
+	sampleRate int
+	channels   int
+	frame      int // Segment length in frames
+	hop        int // Output frames per segment; segments overlap by half
+	search     int // Frames either side of the nominal position searched
+
+	window  []float32
+	in      []float32 // Input still needed, interleaved
+	nominal float64   // Where in in the next segment nominally starts
+	prev    int       // Where in in the last segment started, which may be before in
+	started bool      // Whether a segment has been laid down
+	tail    []float32 // Windowed second half of the last segment
+	out     []float32
+}
+
+// NewTimeStretch creates a time-stretcher for audio of a sample rate and
+// channel count
+func NewTimeStretch(sampleRate, channels int) *TimeStretch {
+	frame := int(stretchFrame.Seconds()*float64(sampleRate)) &^ 1
+	t := &TimeStretch{
+		sampleRate: sampleRate,
+		channels:   channels,
+		frame:      frame,
+		hop:        frame / 2,
+		search:     int(stretchSearch.Seconds() * float64(sampleRate)),
+		window:     make([]float32, frame),
+		tail:       make([]float32, frame/2*channels),
+	}
+
+	// A periodic Hann window, whose halves sum to one when overlapped by half
+	for i := range t.window {
+		t.window[i] = float32(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(frame)))
+	}
+	t.Reset()
+	return t
+}
+
+// Matches reports whether the stretcher is for a sample rate and channel
+// count
+func (t *TimeStretch) Matches(sampleRate, channels int) bool {
+	return t.sampleRate == sampleRate && t.channels == channels
+}
+
+// Reset discards buffered audio, as after a seek
+func (t *TimeStretch) Reset() {
+	t.in = t.in[:0]
+	t.nominal = 0
+	t.prev = 0
+	t.started = false
+}
+
+// Process stretches interleaved samples to play at speed times their
+// normal tempo. Output lags input by about a segment, held back until more
+// input arrives. The returned slice is reused by the next call.
+func (t *TimeStretch) Process(samples []float32, speed float64) []float32 {
+	channels := t.channels
+	t.in = append(t.in, samples[:len(samples)/channels*channels]...)
+	frames := len(t.in) / channels
+
+	t.out = t.out[:0]
+	for {
+		pos := int(t.nominal)
+		need := pos + t.search + t.frame
+		if t.started {
+			need = max(need, t.prev+t.hop+t.frame)
+		}
+		if frames < need {
+			break
+		}
+
+		start := pos
+		if t.started {
+			start = t.align(max(pos-t.search, 0), pos+t.search)
+		}
+		segment := t.in[start*channels : (start+t.frame)*channels]
+
+		// Overlap the first half with the last segment's second half. The
+		// very first segment starts at full level rather than fading in.
+		for i := 0; i < t.hop; i++ {
+			w := t.window[i]
+			if !t.started {
+				w = 1
+			}
+			for ch := 0; ch < channels; ch++ {
+				t.out = append(t.out, t.tail[i*channels+ch]+w*segment[i*channels+ch])
+			}
+		}
+		for i := t.hop; i < t.frame; i++ {
+			w := t.window[i]
+			for ch := 0; ch < channels; ch++ {
+				t.tail[(i-t.hop)*channels+ch] = w * segment[i*channels+ch]
+			}
+		}
+
+		t.prev = start
+		t.started = true
+		t.nominal += float64(t.hop) * speed
+	}
+
+	// Drop the input no later segment can be taken from
+	if used := min(int(t.nominal)-t.search, t.prev+t.hop); used > 0 {
+		t.in = t.in[:copy(t.in, t.in[used*channels:])]
+		t.nominal -= float64(used)
+		t.prev -= used
+	}
+
+	return t.out
+}
+
+// align returns the start between lo and hi where a segment best continues
+// the last one: where it most resembles the audio that followed the last
+// segment in the input
+func (t *TimeStretch) align(lo, hi int) int {
+	target := t.prev + t.hop
+	best, bestScore := lo, math.Inf(-1)
+	for start := lo; start <= hi; start += stretchCoarse {
+		if score := t.similarity(target, start, 2); score > bestScore {
+			best, bestScore = start, score
+		}
+	}
+
+	coarse := best
+	bestScore = math.Inf(-1)
+	for start := max(coarse-stretchCoarse+1, lo); start <= min(coarse+stretchCoarse-1, hi); start++ {
+		if score := t.similarity(target, start, 1); score > bestScore {
+			best, bestScore = start, score
+		}
+	}
+	return best
+}
+
+// similarity is the normalised cross-correlation of the half segments at
+// two positions, summed across channels, taking every stride'th frame
+func (t *TimeStretch) similarity(a, b, stride int) float64 {
+	channels := t.channels
+	var corr, energy float64
+	for i := 0; i < t.hop; i += stride {
+		x := t.in[(a+i)*channels : (a+i+1)*channels]
+		y := t.in[(b+i)*channels : (b+i+1)*channels]
+		for ch := range x {
+			corr += float64(x[ch]) * float64(y[ch])
+			energy += float64(y[ch]) * float64(y[ch])
+		}
+	}
+	if energy == 0 {
+		return 0
+	}
+	return corr / math.Sqrt(energy)
+}
@@ -0,0 +1,92 @@
+package audio
+
+import (
+	"github.com/winramp/winramp/internal/audio/dsp"
+	"github.com/winramp/winramp/internal/audio/output"
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// Names of the effects in the player's chain
+const (
+	EffectPreamp    = "Preamp"
+	EffectEqualizer = "Equalizer"
+	EffectLimiter   = "Limiter"
+)
+
+// newEffects builds the effect chain playback runs through between decoding
+// and output: preamp, then equalizer, then a limiter to catch what they
+// push over full scale
+func (p *Player) newEffects(sampleRate int) {
+	p.preamp = dsp.NewPreamp()
+	p.equalizer = dsp.NewEqualizer(sampleRate)
+	p.limiter = dsp.NewLimiter(sampleRate)
+
+	p.effects = dsp.NewEffectChain()
+	p.effects.AddEffect(p.preamp)
+	p.effects.AddEffect(p.equalizer)
+	p.effects.AddEffect(p.limiter)
+}
+
+// applyEffects runs the effect chain over interleaved samples. Filters keep
+// separate state for each side, so stereo is split to process; layouts of
+// more than two channels pass through untouched.
+func (p *Player) applyEffects(samples []float32, channels int) {
+	if p.effects == nil || !p.effects.IsEnabled() {
+		return
+	}
+
+	switch channels {
+	case 1:
+		p.effects.Process(samples)
+	case 2:
+		frames := len(samples) / 2
+		if cap(p.left) < frames {
+			p.left = make([]float32, frames)
+			p.right = make([]float32, frames)
+		}
+		left, right := p.left[:frames], p.right[:frames]
+		for i := range left {
+			left[i], right[i] = samples[2*i], samples[2*i+1]
+		}
+		p.effects.ProcessStereo(left, right)
+		for i := range left {
+			samples[2*i], samples[2*i+1] = left[i], right[i]
+		}
+	}
+}
+
+// setEffectsRate retunes the effects for the output's sample rate. Must be
+// called with p.mu held.
+func (p *Player) setEffectsRate(sampleRate int) {
+	if p.effects == nil {
+		return
+	}
+	p.equalizer.SetSampleRate(sampleRate)
+	p.limiter.SetSampleRate(sampleRate)
+}
+
+// Equalizer returns the playback equalizer
+func (p *Player) Equalizer() *dsp.Equalizer {
+	return p.equalizer
+}
+
+// Effects returns the chain of effects playback runs through
+func (p *Player) Effects() *dsp.EffectChain {
+	return p.effects
+}
+
+// SetPreamp sets the gain ahead of the equalizer, in dB
+func (p *Player) SetPreamp(db float64) {
+	p.preamp.SetGainDB(db)
+}
+
+// finishOutput runs audio about to be written through the effects and the
+// fader and measures it for clipping. All audio reaching the output goes
+// through it, so filter state carries on unbroken across tracks.
+func (p *Player) finishOutput(samples []float32, format output.Format, track *domain.Track, gain float64) {
+	p.applyEffects(samples, format.Channels)
+	p.fader.apply(samples, format.Channels, format.SampleRate)
+	if report := p.clips.measure(samples, format.Channels, format.SampleRate, track, gain); report != nil {
+		p.notifyListeners(EventClipping, report)
+	}
+}
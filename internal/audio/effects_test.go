@@ -0,0 +1,114 @@
+package audio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/audio/dsp"
+)
+
+func TestEffectChainOrder(t *testing.T) {
+	p := &Player{}
+	p.newEffects(sineRate)
+	assert.Equal(t, []string{EffectPreamp, EffectEqualizer, EffectLimiter}, p.effects.Names())
+
+	require.NoError(t, p.effects.SetOrder([]string{EffectLimiter}))
+	assert.Equal(t, []string{EffectLimiter, EffectPreamp, EffectEqualizer}, p.effects.Names())
+	assert.Error(t, p.effects.SetOrder([]string{"Reverb"}))
+	assert.Error(t, p.effects.SetEffectEnabled("Reverb", false))
+}
+
+func TestApplyEffects(t *testing.T) {
+	p := &Player{}
+	p.newEffects(sineRate)
+	require.NoError(t, p.effects.SetEffectEnabled(EffectLimiter, false))
+	p.SetPreamp(-6)
+
+	// Left is a tone, right silence; the preamp halves the one and leaves
+	// the other silent, so the channels stay apart
+	samples := make([]float32, 2*1000)
+	for i := 0; i < 1000; i++ {
+		samples[2*i] = float32(0.8 * math.Sin(2*math.Pi*1000*float64(i)/sineRate))
+	}
+	want := make([]float32, len(samples))
+	copy(want, samples)
+
+	p.applyEffects(samples, 2)
+	for i := 0; i < 1000; i++ {
+		assert.InDelta(t, want[2*i]*0.501, samples[2*i], 0.001)
+		assert.Zero(t, samples[2*i+1])
+	}
+
+	// The limiter holds down peaks the preamp pushes past full scale, once
+	// it has caught up with them
+	p.SetPreamp(12)
+	require.NoError(t, p.effects.SetEffectEnabled(EffectLimiter, true))
+	copy(samples, want)
+	p.applyEffects(samples, 2)
+	peak := 0.0
+	for _, s := range samples[2*400:] {
+		peak = max(peak, math.Abs(float64(s)))
+	}
+	assert.Greater(t, peak, 0.95)
+	assert.Less(t, peak, 1.3) // 3.2 unlimited
+}
+
+func TestEqualizerSampleRate(t *testing.T) {
+	p := &Player{}
+	p.newEffects(sineRate)
+	p.equalizer.SetEnabled(true)
+	p.equalizer.SetAllBands([10]float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 12})
+
+	// At 22.05kHz the 16kHz band is past Nyquist and stays flat rather
+	// than making the filter blow up
+	p.setEffectsRate(22050)
+	samples := make([]float32, 2*2205)
+	for i := range samples {
+		samples[i] = float32(0.5 * math.Sin(2*math.Pi*440*float64(i/2)/22050))
+	}
+	p.applyEffects(samples, 2)
+	for _, s := range samples {
+		assert.False(t, math.IsNaN(float64(s)) || math.Abs(float64(s)) > 1)
+	}
+}
+
+func TestTimeStretch(t *testing.T) {
+	const freq = 200.0
+	input := make([]float32, 2*sineRate*2)
+	for i := 0; i < len(input)/2; i++ {
+		v := float32(0.5 * math.Sin(2*math.Pi*freq*float64(i)/sineRate))
+		input[2*i], input[2*i+1] = v, -v
+	}
+
+	for _, speed := range []float64{0.75, 1, 1.25, 1.5, 2} {
+		stretch := dsp.NewTimeStretch(sineRate, 2)
+		var out []float32
+		for start := 0; start < len(input); start += 2 * 1024 {
+			out = append(out, stretch.Process(input[start:min(start+2*1024, len(input))], speed)...)
+		}
+
+		// Tempo changes: the output is shorter or longer by the speed, give
+		// or take the segment held back
+		frames := len(out) / 2
+		assert.InDelta(t, float64(len(input)/2)/speed, frames, 0.05*sineRate, "speed %v", speed)
+
+		// Pitch and level don't: the tone keeps its frequency and its
+		// channels stay apart
+		middle := out[frames/4*2 : frames*3/4*2]
+		crossings := 0
+		var sum float64
+		for i := 2; i < len(middle); i += 2 {
+			if middle[i-2] < 0 && middle[i] >= 0 {
+				crossings++
+			}
+			sum += float64(middle[i]) * float64(middle[i])
+			assert.Equal(t, middle[i], -middle[i+1])
+		}
+		seconds := float64(len(middle)/2) / sineRate
+		assert.InDelta(t, freq, float64(crossings)/seconds, 3, "speed %v", speed)
+		assert.InDelta(t, 0.5/math.Sqrt2, math.Sqrt(sum/float64(len(middle)/2)), 0.03, "speed %v", speed)
+	}
+}
@@ -0,0 +1,62 @@
+package audio
+
+import (
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
+)
+
+// Topics the player publishes to its event bus, one per PlayerEvent
+var (
+	TopicStateChanged    = events.NewTopic[PlayerState]("player:stateChanged")
+	TopicTrackChanged    = events.NewTopic[*domain.Track]("player:trackChanged")
+	TopicPositionChanged = events.NewTopic[time.Duration]("player:positionChanged")
+	TopicVolumeChanged   = events.NewTopic[float64]("player:volumeChanged")
+	TopicTrackFinished   = events.NewTopic[*domain.Track]("player:trackFinished")
+	TopicTrackEnding     = events.NewTopic[*TrackEnding]("player:trackEnding")
+	TopicError           = events.NewTopic[*TrackError]("player:error")
+	TopicBuffering       = events.NewTopic[*BufferProgress]("player:buffering")
+	TopicOutputWarning   = events.NewTopic[*OutputWarning]("player:outputWarning")
+	TopicStreamMetadata  = events.NewTopic[*StreamMetadata]("player:streamMetadata")
+	TopicClipping        = events.NewTopic[*ClipReport]("player:clipping")
+	TopicDevicesChanged  = events.NewTopic[*DeviceChange]("player:devicesChanged")
+)
+
+// SetEventBus sets the bus player events are published to, alongside any
+// listeners added with AddListener
+func (p *Player) SetEventBus(bus *events.Bus) {
+	p.listenerMu.Lock()
+	defer p.listenerMu.Unlock()
+	p.bus = bus
+}
+
+// publish sends an event to the bus under its topic
+func publish(bus *events.Bus, event PlayerEvent, data interface{}) {
+	switch event {
+	case EventStateChanged:
+		events.Publish(bus, TopicStateChanged, data.(PlayerState))
+	case EventTrackChanged:
+		events.Publish(bus, TopicTrackChanged, data.(*domain.Track))
+	case EventPositionChanged:
+		events.Publish(bus, TopicPositionChanged, data.(time.Duration))
+	case EventVolumeChanged:
+		events.Publish(bus, TopicVolumeChanged, data.(float64))
+	case EventTrackFinished:
+		events.Publish(bus, TopicTrackFinished, data.(*domain.Track))
+	case EventTrackEnding:
+		events.Publish(bus, TopicTrackEnding, data.(*TrackEnding))
+	case EventError:
+		events.Publish(bus, TopicError, data.(*TrackError))
+	case EventBuffering:
+		events.Publish(bus, TopicBuffering, data.(*BufferProgress))
+	case EventOutputWarning:
+		events.Publish(bus, TopicOutputWarning, data.(*OutputWarning))
+	case EventStreamMetadata:
+		events.Publish(bus, TopicStreamMetadata, data.(*StreamMetadata))
+	case EventClipping:
+		events.Publish(bus, TopicClipping, data.(*ClipReport))
+	case EventDevicesChanged:
+		events.Publish(bus, TopicDevicesChanged, data.(*DeviceChange))
+	}
+}
@@ -0,0 +1,117 @@
+package audio
+
+import (
+	"sync"
+	"time"
+)
+
+// fadeTimeout is how long past its duration a fade is waited for
+const fadeTimeout = 500 * time.Millisecond
+
+// fader ramps a gain applied to samples on their way to the output. Fades
+// work on the samples rather than the output volume, so they cannot race
+// with volume changes and the volume never has to be restored afterwards.
+type fader struct {
+	gain      float64
+	target    float64
+	perSecond float64   // Gain change per second of audio
+	result    chan bool // Receives true when the ramp completes, false if replaced
+	mu        sync.Mutex
+}
+
+func newFader() *fader {
+	return &fader{
+		gain:   1.0,
+		target: 1.0,
+	}
+}
+
+// rampTo starts a fade from the current gain to target over duration,
+// replacing any fade in progress. The returned channel reports whether the
+// fade completed or was replaced by another.
+func (f *fader) rampTo(target float64, duration time.Duration) <-chan bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.cancel()
+	result := make(chan bool, 1)
+
+	distance := target - f.gain
+	if distance < 0 {
+		distance = -distance
+	}
+	if duration <= 0 || distance == 0 {
+		f.gain = target
+		f.target = target
+		result <- true
+		return result
+	}
+
+	f.target = target
+	f.perSecond = distance / duration.Seconds()
+	f.result = result
+	return result
+}
+
+// set jumps straight to a gain, cancelling any fade in progress
+func (f *fader) set(gain float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.cancel()
+	f.gain = gain
+	f.target = gain
+}
+
+// fadingOut reports whether a fade to silence is still in progress, during
+// which audio must keep flowing even though playback has been paused
+func (f *fader) fadingOut() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.result != nil && f.target == 0
+}
+
+// apply scales interleaved samples by the fade gain, advancing the fade
+func (f *fader) apply(samples []float32, channels, sampleRate int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.result == nil {
+		if f.gain != 1.0 {
+			for i := range samples {
+				samples[i] *= float32(f.gain)
+			}
+		}
+		return
+	}
+
+	if channels <= 0 || sampleRate <= 0 {
+		return
+	}
+	step := f.perSecond / float64(sampleRate)
+
+	for frame := 0; frame*channels < len(samples); frame++ {
+		switch {
+		case f.gain < f.target:
+			f.gain = min(f.gain+step, f.target)
+		case f.gain > f.target:
+			f.gain = max(f.gain-step, f.target)
+		}
+		for c := 0; c < channels && frame*channels+c < len(samples); c++ {
+			samples[frame*channels+c] *= float32(f.gain)
+		}
+	}
+
+	if f.gain == f.target {
+		f.result <- true
+		f.result = nil
+	}
+}
+
+// cancel reports the fade in progress as replaced. Callers must hold f.mu.
+func (f *fader) cancel() {
+	if f.result != nil {
+		f.result <- false
+		f.result = nil
+	}
+}
@@ -0,0 +1,146 @@
+package audio
+
+import (
+	"time"
+
+	"github.com/winramp/winramp/internal/audio/decoder"
+)
+
+const (
+	// gaplessLookahead is how near the end of a track the next one starts
+	// decoding ahead
+	gaplessLookahead = 5 * time.Second
+
+	// gaplessPrebuffer is how much of the next track is decoded ahead, enough
+	// to ride out opening a file on a sleeping disk
+	gaplessPrebuffer = 500 * time.Millisecond
+)
+
+// decodeAhead wraps the next track's decoder and decodes its start into a
+// ring buffer while the current track plays. Playback then carries straight
+// on into buffered audio at the splice instead of waiting on the disk.
+type decodeAhead struct {
+	decoder.Decoder
+	ring     []float32
+	read     int // Index of the first buffered sample
+	count    int // Buffered samples
+	channels int
+	scratch  []float32
+	err      error // Decoding error to return once the ring is drained
+}
+
+// newDecodeAhead wraps a decoder, buffering up to gaplessPrebuffer of it
+func newDecodeAhead(dec decoder.Decoder) *decodeAhead {
+	format := dec.Format()
+	channels := format.Channels
+	if channels <= 0 {
+		channels = 2
+	}
+	frames := int(gaplessPrebuffer.Seconds() * float64(format.SampleRate))
+	return &decodeAhead{
+		Decoder:  dec,
+		ring:     make([]float32, max(frames, 1)*channels),
+		channels: channels,
+	}
+}
+
+// fill decodes up to frames more frames into the ring, stopping when it is
+// full. It reports whether there is room left.
+func (d *decodeAhead) fill(frames int) bool {
+	space := (len(d.ring) - d.count) / d.channels
+	if d.err != nil || space == 0 {
+		return false
+	}
+	frames = min(frames, space)
+	if cap(d.scratch) < frames*d.channels {
+		d.scratch = make([]float32, frames*d.channels)
+	}
+
+	n, err := d.Decoder.Decode(d.scratch[:frames*d.channels])
+	if err != nil {
+		d.err = err
+		return false
+	}
+	samples := d.scratch[:n*d.channels]
+	write := (d.read + d.count) % len(d.ring)
+	copied := copy(d.ring[write:], samples)
+	copy(d.ring, samples[copied:])
+	d.count += len(samples)
+	return d.count < len(d.ring)
+}
+
+// buffered returns how many frames are waiting in the ring
+func (d *decodeAhead) buffered() int {
+	return d.count / d.channels
+}
+
+// Decode drains the ring before decoding more
+func (d *decodeAhead) Decode(buffer []float32) (int, error) {
+	if d.count == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		return d.Decoder.Decode(buffer)
+	}
+
+	n := min(len(buffer)/d.channels*d.channels, d.count)
+	copied := copy(buffer[:n], d.ring[d.read:])
+	copy(buffer[copied:n], d.ring)
+	d.read = (d.read + n) % len(d.ring)
+	d.count -= n
+	return n / d.channels, nil
+}
+
+// Position returns the position of the next frame Decode returns
+func (d *decodeAhead) Position() time.Duration {
+	rate := d.Format().SampleRate
+	if rate <= 0 {
+		return d.Decoder.Position()
+	}
+	return d.Decoder.Position() - time.Duration(d.buffered())*time.Second/time.Duration(rate)
+}
+
+// CurrentSample returns the sample of the next frame Decode returns
+func (d *decodeAhead) CurrentSample() int64 {
+	return d.Decoder.CurrentSample() - int64(d.buffered())
+}
+
+// Seek discards the buffered audio
+func (d *decodeAhead) Seek(position time.Duration) error {
+	d.reset()
+	return d.Decoder.Seek(position)
+}
+
+// SeekSample discards the buffered audio
+func (d *decodeAhead) SeekSample(sample int64) error {
+	d.reset()
+	return d.Decoder.SeekSample(sample)
+}
+
+func (d *decodeAhead) reset() {
+	d.read, d.count, d.err = 0, 0, nil
+}
+
+// decodeNextAhead decodes a block of the next track ahead when the current
+// one is near its end and the two join without a crossfade. A crossfade
+// transition with no crossfade duration joins them gaplessly. Must be
+// called with p.mu held.
+func (p *Player) decodeNextAhead(frames int) {
+	ahead, ok := p.nextDecoder.(*decodeAhead)
+	if !ok || p.mixing != nil || p.transition == TransitionCrossfade && p.crossfade > 0 {
+		return
+	}
+	if p.duration <= 0 || p.duration-p.position > gaplessLookahead {
+		return
+	}
+	ahead.fill(frames)
+}
+
+// SetGapless sets whether the start of the next track is decoded ahead so
+// tracks join seamlessly
+func (p *Player) SetGapless(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.gapless = enabled
+}
@@ -0,0 +1,80 @@
+package audio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/audio/decoder"
+)
+
+func TestDecodeAhead(t *testing.T) {
+	ahead := newDecodeAhead(newSineDecoder(0.5, time.Second))
+	want := newSineDecoder(0.5, time.Second)
+
+	for ahead.fill(1000) {
+	}
+	frames := int(gaplessPrebuffer.Seconds() * sineRate)
+	assert.Equal(t, frames, ahead.buffered())
+	assert.Zero(t, ahead.CurrentSample())
+	assert.Zero(t, ahead.Position())
+
+	// Reading and filling in turn wraps around the ring; the audio comes
+	// out exactly as decoded, sample for sample
+	got := make([]float32, 0, 2*sineRate)
+	buffer := make([]float32, 1234)
+	for {
+		ahead.fill(700)
+		n, err := ahead.Decode(buffer)
+		if err == decoder.ErrEndOfStream {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, buffer[:2*n]...)
+	}
+	expected := make([]float32, 2*sineRate)
+	n, err := want.Decode(expected)
+	require.NoError(t, err)
+	assert.Equal(t, expected[:2*n], got)
+	assert.EqualValues(t, sineRate, ahead.CurrentSample())
+}
+
+func TestDecodeAheadSeek(t *testing.T) {
+	ahead := newDecodeAhead(newSineDecoder(0.5, 10*time.Millisecond))
+	for ahead.fill(100) {
+	}
+	assert.Equal(t, 441, ahead.buffered())
+
+	// Rewinding, as a cancelled crossfade does, drops what was buffered
+	require.NoError(t, ahead.Seek(0))
+	assert.Zero(t, ahead.buffered())
+	buffer := make([]float32, 2000)
+	n, err := ahead.Decode(buffer)
+	require.NoError(t, err)
+	assert.Equal(t, 441, n)
+	_, err = ahead.Decode(buffer)
+	assert.ErrorIs(t, err, decoder.ErrEndOfStream)
+}
+
+func TestDecodeNextAheadCrossfade(t *testing.T) {
+	ahead := newDecodeAhead(newSineDecoder(0.5, time.Second))
+	p := &Player{
+		nextDecoder: ahead,
+		transition:  TransitionCrossfade,
+		crossfade:   5 * time.Second,
+		duration:    time.Minute,
+		position:    time.Minute - time.Second,
+	}
+
+	// The crossfade reads the next track itself
+	p.decodeNextAhead(100)
+	assert.Zero(t, ahead.buffered())
+
+	// Without a crossfade duration the tracks join gaplessly, so the next
+	// one is decoded ahead as for a gapless transition
+	p.crossfade = 0
+	p.decodeNextAhead(100)
+	assert.Equal(t, 100, ahead.buffered())
+}
@@ -0,0 +1,188 @@
+package audio
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/winramp/winramp/internal/audio/decoder"
+	"github.com/winramp/winramp/internal/domain"
+)
+
+const (
+	// levelingReference is the RMS level (dBFS) tracks are levelled towards,
+	// matching the ReplayGain pink-noise reference.
+	levelingReference = -20.0
+	// levelingWindow is how much audio is analysed for a quick estimate
+	levelingWindow = 30 * time.Second
+	// levelingBlock is the RMS block length used by the estimator
+	levelingBlock = 50 * time.Millisecond
+	// maxLevelingGain bounds estimated gain in either direction (dB)
+	maxLevelingGain = 15.0
+)
+
+// WholeStream is an analysis window covering the entire stream
+const WholeStream time.Duration = -1
+
+// LoudnessAnalysis holds the short-term RMS levels and peak of analysed
+// audio. Analyses of an album's tracks can be pooled, so album gain comes
+// from the loudness of the album as a whole, as ReplayGain computes it.
+type LoudnessAnalysis struct {
+	blocks []float64 // RMS level of each block in dBFS, in stream order
+	peak   float64
+}
+
+// AnalyzeLoudness measures the loudness of a decoded stream, reading up to
+// window of audio, or all of it with WholeStream
+func AnalyzeLoudness(dec decoder.Decoder, window time.Duration) (*LoudnessAnalysis, error) {
+	format := dec.Format()
+	if format.SampleRate <= 0 || format.Channels <= 0 {
+		return nil, errors.New("invalid decoder format")
+	}
+
+	if window == 0 {
+		window = levelingWindow
+	}
+
+	blockFrames := int(levelingBlock.Seconds() * float64(format.SampleRate))
+	if blockFrames <= 0 {
+		blockFrames = 1
+	}
+	maxFrames := math.MaxInt
+	if window > 0 {
+		maxFrames = int(window.Seconds() * float64(format.SampleRate))
+	}
+
+	buffer := make([]float32, blockFrames*format.Channels)
+	analysis := &LoudnessAnalysis{}
+	if window > 0 {
+		analysis.blocks = make([]float64, 0, maxFrames/blockFrames+1)
+	}
+	framesRead := 0
+
+	for framesRead < maxFrames {
+		n, err := dec.Decode(buffer)
+		if n > 0 {
+			sum := 0.0
+			samples := buffer[:n*format.Channels]
+			for _, s := range samples {
+				v := float64(s)
+				sum += v * v
+				if abs := math.Abs(v); abs > analysis.peak {
+					analysis.peak = abs
+				}
+			}
+			meanSquare := sum / float64(len(samples))
+			if meanSquare > 0 {
+				analysis.blocks = append(analysis.blocks, 10*math.Log10(meanSquare))
+			}
+			framesRead += n
+		}
+		if err != nil {
+			if errors.Is(err, decoder.ErrEndOfStream) {
+				break
+			}
+			return nil, err
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	if len(analysis.blocks) == 0 {
+		return nil, errors.New("no audio to analyse")
+	}
+
+	return analysis, nil
+}
+
+// Gain returns the gain in dB that brings the audio to the reference level
+func (a *LoudnessAnalysis) Gain() float64 {
+	return levelingGain(a.blocks)
+}
+
+// Peak returns the highest absolute sample value
+func (a *LoudnessAnalysis) Peak() float64 {
+	return a.peak
+}
+
+// EstimateLoudness performs a quick loudness analysis of the first part of a
+// decoded stream and returns ReplayGain-style values marked as estimated.
+// It uses the 95th percentile of short-term RMS blocks, which tracks the
+// loud passages the way ReplayGain does, without the equal-loudness filter.
+func EstimateLoudness(dec decoder.Decoder, window time.Duration) (*domain.ReplayGain, error) {
+	if window <= 0 {
+		window = levelingWindow
+	}
+
+	analysis, err := AnalyzeLoudness(dec, window)
+	if err != nil {
+		return nil, err
+	}
+
+	gain := analysis.Gain()
+	return &domain.ReplayGain{
+		TrackGain: gain,
+		TrackPeak: analysis.peak,
+		AlbumGain: gain,
+		AlbumPeak: analysis.peak,
+		Estimated: true,
+	}, nil
+}
+
+// AlbumReplayGain computes gain values for each of an album's tracks from
+// their analyses. Album gain and peak are shared by every track, computed
+// over the blocks of all tracks together rather than averaged, so quiet
+// interludes don't pull the album louder.
+func AlbumReplayGain(analyses []*LoudnessAnalysis) []*domain.ReplayGain {
+	var blocks []float64
+	albumPeak := 0.0
+	for _, analysis := range analyses {
+		blocks = append(blocks, analysis.blocks...)
+		albumPeak = math.Max(albumPeak, analysis.peak)
+	}
+	albumGain := levelingGain(blocks)
+
+	gains := make([]*domain.ReplayGain, len(analyses))
+	for i, analysis := range analyses {
+		gains[i] = &domain.ReplayGain{
+			TrackGain: analysis.Gain(),
+			TrackPeak: analysis.peak,
+			AlbumGain: albumGain,
+			AlbumPeak: albumPeak,
+		}
+	}
+	return gains
+}
+
+// levelingGain returns the bounded gain for blocks of RMS levels, taken
+// from the 95th percentile so loud passages decide the level
+func levelingGain(blocks []float64) float64 {
+	if len(blocks) == 0 {
+		return 0
+	}
+
+	sorted := make([]float64, len(blocks))
+	copy(sorted, blocks)
+	sort.Float64s(sorted)
+	loudness := sorted[int(float64(len(sorted)-1)*0.95)]
+
+	gain := levelingReference - loudness
+	if gain > maxLevelingGain {
+		gain = maxLevelingGain
+	} else if gain < -maxLevelingGain {
+		gain = -maxLevelingGain
+	}
+	return gain
+}
+
+// gainToLinear converts a gain in dB to a linear multiplier, reduced if
+// necessary so the given peak does not clip.
+func gainToLinear(gain, peak float64) float64 {
+	linear := math.Pow(10, gain/20.0)
+	if peak > 0 && linear*peak > 1.0 {
+		linear = 1.0 / peak
+	}
+	return linear
+}
@@ -0,0 +1,164 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/audio/decoder"
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestEstimateLoudness(t *testing.T) {
+	tests := []struct {
+		name      string
+		amplitude float64
+		gain      float64 // Expected gain in dB
+	}{
+		// A sine's RMS is its amplitude / sqrt(2), so 0.1414 sits at -20 dBFS
+		{"at reference", 0.1414, 0},
+		{"loud", 0.2, -3},
+		{"quiet", 0.01414, 15},  // -40 dBFS wants +20 dB, capped
+		{"very loud", 1.0, -15}, // -3 dBFS wants -17 dB, capped
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := newSineDecoder(tt.amplitude, 10*time.Second)
+
+			rg, err := EstimateLoudness(dec, time.Second)
+			require.NoError(t, err)
+			assert.True(t, rg.Estimated)
+			assert.InDelta(t, tt.gain, rg.TrackGain, 0.1)
+			assert.Equal(t, rg.TrackGain, rg.AlbumGain)
+			assert.InDelta(t, tt.amplitude, rg.TrackPeak, 0.001)
+			assert.Equal(t, rg.TrackPeak, rg.AlbumPeak)
+
+			// Only the window is read
+			assert.InDelta(t, time.Second.Seconds(), dec.Position().Seconds(), 0.06)
+		})
+	}
+}
+
+func TestEstimateLoudnessSilence(t *testing.T) {
+	_, err := EstimateLoudness(newSineDecoder(0, time.Second), time.Second)
+	assert.Error(t, err)
+}
+
+func TestAlbumReplayGain(t *testing.T) {
+	loud, err := AnalyzeLoudness(newSineDecoder(0.2, time.Second), WholeStream)
+	require.NoError(t, err)
+	quiet, err := AnalyzeLoudness(newSineDecoder(0.1414, time.Second), WholeStream)
+	require.NoError(t, err)
+
+	gains := AlbumReplayGain([]*LoudnessAnalysis{loud, quiet})
+	require.Len(t, gains, 2)
+	assert.InDelta(t, -3, gains[0].TrackGain, 0.1)
+	assert.InDelta(t, 0, gains[1].TrackGain, 0.1)
+
+	// The album is as loud as its loud passages
+	for _, rg := range gains {
+		assert.False(t, rg.Estimated)
+		assert.InDelta(t, -3, rg.AlbumGain, 0.1)
+		assert.InDelta(t, 0.2, rg.AlbumPeak, 0.001)
+	}
+}
+
+func TestGainToLinear(t *testing.T) {
+	assert.InDelta(t, 2.0, gainToLinear(6.0206, 0), 0.001)
+	assert.InDelta(t, 0.5, gainToLinear(-6.0206, 0.9), 0.001)
+	// Gain is held back so the peak doesn't clip
+	assert.InDelta(t, 1.25, gainToLinear(6.0206, 0.8), 0.001)
+}
+
+func TestTrackGainEstimates(t *testing.T) {
+	estimate := &domain.ReplayGain{TrackGain: -6.0206, TrackPeak: 0.5, AlbumGain: -6.0206, AlbumPeak: 0.5, Estimated: true}
+
+	tests := []struct {
+		name     string
+		rg       *domain.ReplayGain
+		needs    bool
+		expected float64 // Linear gain applied
+	}{
+		{"no gain", nil, true, 0.5},
+		// Library tracks always have a ReplayGain, empty when untagged
+		{"empty gain", &domain.ReplayGain{}, true, 0.5},
+		{"tagged", &domain.ReplayGain{TrackGain: 6.0206, TrackPeak: 0.25}, false, 1.0},
+		{"saved estimate", &domain.ReplayGain{TrackGain: 3, TrackPeak: 0.5, Estimated: true}, true, 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := &domain.Track{ID: "t1", FilePath: "song.mp3", ReplayGain: tt.rg}
+			var before *domain.ReplayGain
+			if tt.rg != nil {
+				copied := *tt.rg
+				before = &copied
+			}
+
+			p := &Player{leveling: true, estimates: map[string]*domain.ReplayGain{}}
+			assert.Equal(t, tt.needs, p.needsLoudnessEstimate(track))
+
+			p.estimates[track.ID] = estimate
+			p.currentTrack = track
+			p.updateTrackGain()
+			assert.InDelta(t, tt.expected, p.trackGain, 0.001)
+			assert.False(t, p.needsLoudnessEstimate(track))
+
+			// Estimates stay with the player rather than the track
+			assert.Equal(t, before, track.ReplayGain)
+			assert.Equal(t, estimate, p.GainEstimate(track.ID))
+		})
+	}
+}
+
+// sineDecoder decodes a 1 kHz stereo sine wave
+type sineDecoder struct {
+	amplitude float64
+	frames    int64
+	position  int64
+}
+
+const sineRate = 44100
+
+func newSineDecoder(amplitude float64, length time.Duration) *sineDecoder {
+	return &sineDecoder{amplitude: amplitude, frames: int64(length.Seconds() * sineRate)}
+}
+
+func (d *sineDecoder) Decode(buffer []float32) (int, error) {
+	n := 0
+	for ; n < len(buffer)/2 && d.position < d.frames; n++ {
+		v := float32(d.amplitude * math.Sin(2*math.Pi*1000*float64(d.position)/sineRate))
+		buffer[2*n], buffer[2*n+1] = v, v
+		d.position++
+	}
+	if n == 0 {
+		return 0, decoder.ErrEndOfStream
+	}
+	return n, nil
+}
+
+func (d *sineDecoder) DecodeInt16(buffer []int16) (int, error) { return 0, nil }
+
+func (d *sineDecoder) Format() decoder.AudioFormat {
+	return decoder.AudioFormat{SampleRate: sineRate, Channels: 2, BitDepth: 16}
+}
+
+func (d *sineDecoder) Metadata() *decoder.Metadata { return nil }
+func (d *sineDecoder) Duration() time.Duration {
+	return time.Duration(d.frames) * time.Second / sineRate
+}
+func (d *sineDecoder) Position() time.Duration {
+	return time.Duration(d.position) * time.Second / sineRate
+}
+func (d *sineDecoder) Seek(position time.Duration) error {
+	d.position = int64(position.Seconds() * sineRate)
+	return nil
+}
+func (d *sineDecoder) SeekSample(sample int64) error { d.position = sample; return nil }
+func (d *sineDecoder) SampleCount() int64            { return d.frames }
+func (d *sineDecoder) CurrentSample() int64          { return d.position }
+func (d *sineDecoder) Close() error                  { return nil }
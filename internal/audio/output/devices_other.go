@@ -0,0 +1,11 @@
+//go:build !windows
+
+package output
+
+import "errors"
+
+// systemDevices is not supported outside Windows, where only the default
+// device is offered
+func systemDevices() ([]*Device, error) {
+	return nil, errors.New("device enumeration is not supported on this platform")
+}
@@ -0,0 +1,212 @@
+//go:build windows
+
+package output
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	coinitMultithreaded = 0x0        // COINIT_MULTITHREADED
+	clsctxAll           = 0x17       // CLSCTX_ALL
+	rpcEChangedMode     = 0x80010106 // RPC_E_CHANGED_MODE
+	eRender             = 0          // EDataFlow eRender
+	eConsole            = 0          // ERole eConsole
+	deviceStateActive   = 0x1        // DEVICE_STATE_ACTIVE
+	stgmRead            = 0x0        // STGM_READ
+	vtLPWStr            = 31         // VT_LPWSTR
+	hresultNotFound     = 0x80070490 // HRESULT_FROM_WIN32(ERROR_NOT_FOUND)
+)
+
+// Vtable slots, counting IUnknown's QueryInterface, AddRef and Release
+const (
+	methodRelease = 2
+
+	enumeratorEnumAudioEndpoints      = 3
+	enumeratorGetDefaultAudioEndpoint = 4
+
+	collectionGetCount = 3
+	collectionItem     = 4
+
+	deviceOpenPropertyStore = 4
+	deviceGetID             = 5
+
+	propertyStoreGetValue = 5
+)
+
+var (
+	ole32 = syscall.NewLazyDLL("ole32.dll")
+
+	procCoInitializeEx   = ole32.NewProc("CoInitializeEx")
+	procCoUninitialize   = ole32.NewProc("CoUninitialize")
+	procCoCreateInstance = ole32.NewProc("CoCreateInstance")
+	procCoTaskMemFree    = ole32.NewProc("CoTaskMemFree")
+	procPropVariantClear = ole32.NewProc("PropVariantClear")
+
+	clsidMMDeviceEnumerator = guid{0xBCDE0395, 0xE52F, 0x467C, [8]byte{0x8E, 0x3D, 0xC4, 0x57, 0x92, 0x91, 0x69, 0x2E}}
+	iidIMMDeviceEnumerator  = guid{0xA95664D2, 0x9614, 0x4F35, [8]byte{0xA7, 0x46, 0xDE, 0x8D, 0xB6, 0x36, 0x17, 0xE6}}
+
+	pkeyDeviceFriendlyName = propertyKey{
+		fmtid: guid{0xA45C254E, 0xDF1C, 0x4EFD, [8]byte{0x80, 0x20, 0x67, 0xD1, 0x46, 0xA8, 0x50, 0xE0}},
+		pid:   14,
+	}
+)
+
+// guid mirrors GUID
+type guid struct {
+	data1 uint32
+	data2 uint16
+	data3 uint16
+	data4 [8]byte
+}
+
+// propertyKey mirrors PROPERTYKEY
+type propertyKey struct {
+	fmtid guid
+	pid   uint32
+}
+
+// propVariant mirrors PROPVARIANT for the string values read here
+type propVariant struct {
+	vt       uint16
+	reserved [3]uint16
+	value    *uint16
+	_        uintptr
+}
+
+// comObject is a COM interface pointer, whose first field points to its
+// vtable
+type comObject struct {
+	vtbl *[32]uintptr
+}
+
+func (o *comObject) call(method int, args ...uintptr) uint32 {
+	args = append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)
+	hr, _, _ := syscall.SyscallN(o.vtbl[method], args...)
+	return uint32(hr)
+}
+
+func (o *comObject) release() {
+	o.call(methodRelease)
+}
+
+// systemDevices lists the active playback endpoints through Core Audio
+func systemDevices() ([]*Device, error) {
+	// COM is initialised per thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hr, _, _ := procCoInitializeEx.Call(0, coinitMultithreaded)
+	switch uint32(hr) {
+	case 0, 1: // S_OK, S_FALSE
+		defer procCoUninitialize.Call()
+	case rpcEChangedMode:
+		// Already initialised differently on this thread, which works too
+	default:
+		return nil, fmt.Errorf("CoInitializeEx failed: 0x%08X", uint32(hr))
+	}
+
+	var enumerator *comObject
+	hr, _, _ = procCoCreateInstance.Call(
+		uintptr(unsafe.Pointer(&clsidMMDeviceEnumerator)), 0, clsctxAll,
+		uintptr(unsafe.Pointer(&iidIMMDeviceEnumerator)),
+		uintptr(unsafe.Pointer(&enumerator)))
+	if uint32(hr) != 0 {
+		return nil, fmt.Errorf("failed to create device enumerator: 0x%08X", uint32(hr))
+	}
+	defer enumerator.release()
+
+	defaultID := ""
+	var defaultDevice *comObject
+	switch hr := enumerator.call(enumeratorGetDefaultAudioEndpoint, eRender, eConsole, uintptr(unsafe.Pointer(&defaultDevice))); hr {
+	case 0:
+		defaultID = deviceID(defaultDevice)
+		defaultDevice.release()
+	case hresultNotFound:
+		// No playback devices at all
+	default:
+		return nil, fmt.Errorf("failed to get default device: 0x%08X", hr)
+	}
+
+	var collection *comObject
+	if hr := enumerator.call(enumeratorEnumAudioEndpoints, eRender, deviceStateActive, uintptr(unsafe.Pointer(&collection))); hr != 0 {
+		return nil, fmt.Errorf("failed to enumerate devices: 0x%08X", hr)
+	}
+	defer collection.release()
+
+	var count uint32
+	if hr := collection.call(collectionGetCount, uintptr(unsafe.Pointer(&count))); hr != 0 {
+		return nil, fmt.Errorf("failed to count devices: 0x%08X", hr)
+	}
+
+	devices := make([]*Device, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var endpoint *comObject
+		if hr := collection.call(collectionItem, uintptr(i), uintptr(unsafe.Pointer(&endpoint))); hr != 0 {
+			continue
+		}
+
+		id := deviceID(endpoint)
+		name := friendlyName(endpoint)
+		endpoint.release()
+		if id == "" {
+			continue
+		}
+		if name == "" {
+			name = id
+		}
+
+		devices = append(devices, &Device{
+			ID:          id,
+			Name:        name,
+			Type:        "WASAPI",
+			IsDefault:   id == defaultID,
+			MaxChannels: 2,
+			SampleRates: standardSampleRates,
+			Exclusive:   true,
+		})
+	}
+	return devices, nil
+}
+
+func deviceID(device *comObject) string {
+	var id *uint16
+	if hr := device.call(deviceGetID, uintptr(unsafe.Pointer(&id))); hr != 0 || id == nil {
+		return ""
+	}
+	defer procCoTaskMemFree.Call(uintptr(unsafe.Pointer(id)))
+	return utf16PtrToString(id)
+}
+
+func friendlyName(device *comObject) string {
+	var store *comObject
+	if hr := device.call(deviceOpenPropertyStore, stgmRead, uintptr(unsafe.Pointer(&store))); hr != 0 {
+		return ""
+	}
+	defer store.release()
+
+	var value propVariant
+	if hr := store.call(propertyStoreGetValue, uintptr(unsafe.Pointer(&pkeyDeviceFriendlyName)), uintptr(unsafe.Pointer(&value))); hr != 0 {
+		return ""
+	}
+	defer procPropVariantClear.Call(uintptr(unsafe.Pointer(&value)))
+
+	if value.vt != vtLPWStr {
+		return ""
+	}
+	return utf16PtrToString(value.value)
+}
+
+func utf16PtrToString(p *uint16) string {
+	if p == nil {
+		return ""
+	}
+	n := 0
+	for ptr := unsafe.Pointer(p); *(*uint16)(ptr) != 0; n++ {
+		ptr = unsafe.Add(ptr, 2)
+	}
+	return syscall.UTF16ToString(unsafe.Slice(p, n))
+}
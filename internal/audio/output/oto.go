@@ -9,6 +9,41 @@ import (
 	"github.com/hajimehoshi/oto/v3"
 )
 
+// oto allows a single context per process. It is shared by all outputs,
+// each of which plays through its own oto player mixed on that context.
+var (
+	sharedContext *oto.Context
+	sharedFormat  Format
+	contextMu     sync.Mutex
+)
+
+// acquireContext returns the shared oto context, creating it on first use.
+// Later outputs must use the same sample rate and channel count.
+func acquireContext(format Format, options *oto.NewContextOptions) (*oto.Context, error) {
+	contextMu.Lock()
+	defer contextMu.Unlock()
+
+	if sharedContext != nil {
+		if format.SampleRate != sharedFormat.SampleRate || format.Channels != sharedFormat.Channels {
+			return nil, fmt.Errorf("%w: output is running at %d Hz with %d channels",
+				ErrInvalidFormat, sharedFormat.SampleRate, sharedFormat.Channels)
+		}
+		return sharedContext, nil
+	}
+
+	context, ready, err := oto.NewContext(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audio context: %w", err)
+	}
+
+	// Wait for context to be ready
+	<-ready
+
+	sharedContext = context
+	sharedFormat = format
+	return context, nil
+}
+
 // OtoOutput implements Output interface using oto library
 type OtoOutput struct {
 	BaseOutput
@@ -51,15 +86,11 @@ func (o *OtoOutput) Open(format Format) error {
 		options.BufferSize = time.Duration(bufferSamples) * time.Second / time.Duration(samplesPerSecond)
 	}
 
-	// Create context
-	context, ready, err := oto.NewContext(options)
+	context, err := acquireContext(format, options)
 	if err != nil {
-		return fmt.Errorf("failed to create audio context: %w", err)
+		return err
 	}
 
-	// Wait for context to be ready
-	<-ready
-
 	o.context = context
 	o.format = format
 	o.bufferSize = int(options.BufferSize.Seconds() * float64(format.SampleRate))
@@ -143,10 +174,8 @@ func (o *OtoOutput) Close() error {
 		o.player = nil
 	}
 
-	if o.context != nil {
-		// Note: oto v3 doesn't have a Close method for context
-		o.context = nil
-	}
+	// The shared context stays alive for other outputs
+	o.context = nil
 
 	return nil
 }
@@ -194,7 +223,17 @@ func (o *OtoOutput) Flush() error {
 	return nil
 }
 
-// OtoDeviceManager implements DeviceManager using oto
+// deviceWatchInterval is how often WatchDevices checks for devices being
+// plugged in or removed
+const deviceWatchInterval = 2 * time.Second
+
+// standardSampleRates are the rates offered for every device
+var standardSampleRates = []int{22050, 44100, 48000, 88200, 96000, 192000}
+
+// OtoDeviceManager implements DeviceManager using oto. Devices are
+// enumerated through the system where supported, Core Audio on Windows,
+// after a "default" device that follows the system default. oto itself
+// always plays through the system default device.
 type OtoDeviceManager struct {
 	defaultDevice *Device
 	mu            sync.RWMutex
@@ -209,7 +248,7 @@ func NewOtoDeviceManager() *OtoDeviceManager {
 			Type:        "Oto",
 			IsDefault:   true,
 			MaxChannels: 2,
-			SampleRates: []int{22050, 44100, 48000, 88200, 96000, 192000},
+			SampleRates: standardSampleRates,
 		},
 	}
 }
@@ -218,9 +257,13 @@ func NewOtoDeviceManager() *OtoDeviceManager {
 func (m *OtoDeviceManager) EnumerateDevices() ([]*Device, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-
-	// Oto doesn't provide device enumeration, return default device
-	return []*Device{m.defaultDevice}, nil
+	
+	devices, err := systemDevices()
+	if err != nil {
+		// Without enumeration there is still the default device
+		devices = nil
+	}
+	return append([]*Device{m.defaultDevice}, devices...), nil
 }
 
 // GetDefaultDevice returns the default audio device
@@ -233,12 +276,18 @@ func (m *OtoDeviceManager) GetDefaultDevice() (*Device, error) {
 
 // GetDevice returns a specific device by ID
 func (m *OtoDeviceManager) GetDevice(id string) (*Device, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	if id == "default" || id == m.defaultDevice.ID {
+	if id == "" || id == m.defaultDevice.ID {
 		return m.defaultDevice, nil
 	}
+	
+	// Devices come and go, so look it up afresh
+	devices, err := systemDevices()
+	if err != nil {
+		return nil, ErrDeviceNotFound
+	}
+	if device := findDevice(devices, id); device != nil {
+		return device, nil
+	}
 	return nil, ErrDeviceNotFound
 }
 
@@ -252,16 +301,64 @@ func (m *OtoDeviceManager) CreateOutput(device *Device) (Output, error) {
 
 // SetDefaultDevice sets the default audio device
 func (m *OtoDeviceManager) SetDefaultDevice(id string) error {
-	// Oto doesn't support changing devices
+	// The system default is chosen in the system's sound settings
 	if id != "default" && id != m.defaultDevice.ID {
 		return ErrDeviceNotFound
 	}
 	return nil
 }
 
-// WatchDevices watches for device changes
+// WatchDevices calls back with the devices plugged in and removed since the
+// last check, for as long as the process runs. Nothing is watched where
+// devices can't be enumerated.
 func (m *OtoDeviceManager) WatchDevices(callback func(added, removed []*Device)) {
-	// Oto doesn't support device watching
+	previous, err := systemDevices()
+	if err != nil {
+		return
+	}
+	
+	go func() {
+		ticker := time.NewTicker(deviceWatchInterval)
+		defer ticker.Stop()
+		
+		for range ticker.C {
+			current, err := systemDevices()
+			if err != nil {
+				continue
+			}
+			
+			added, removed := diffDevices(previous, current)
+			previous = current
+			if len(added) > 0 || len(removed) > 0 {
+				callback(added, removed)
+			}
+		}
+	}()
+}
+
+// diffDevices returns the devices in current but not previous, and those
+// in previous but not current
+func diffDevices(previous, current []*Device) (added, removed []*Device) {
+	for _, device := range current {
+		if findDevice(previous, device.ID) == nil {
+			added = append(added, device)
+		}
+	}
+	for _, device := range previous {
+		if findDevice(current, device.ID) == nil {
+			removed = append(removed, device)
+		}
+	}
+	return added, removed
+}
+
+func findDevice(devices []*Device, id string) *Device {
+	for _, device := range devices {
+		if device.ID == id {
+			return device
+		}
+	}
+	return nil
 }
 
 // Helper function to convert float32 to uint32
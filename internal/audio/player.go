@@ -1,14 +1,18 @@
 package audio
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/winramp/winramp/internal/audio/decoder"
+	"github.com/winramp/winramp/internal/audio/dsp"
 	"github.com/winramp/winramp/internal/audio/output"
 	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
 	"github.com/winramp/winramp/internal/logger"
 )
 
@@ -56,8 +60,42 @@ const (
 	EventVolumeChanged
 	EventTrackFinished
 	EventError
+	EventTrackEnding // Sent with *TrackEnding shortly before a track finishes
+	EventBuffering   // Sent with *BufferProgress while a stream fills its prebuffer
+	EventOutputWarning // Sent with *OutputWarning after repeated underruns or stalls
+	EventStreamMetadata // Sent with *StreamMetadata when a station says what it is playing
+	EventClipping       // Sent with *ClipReport each second samples clip, and once after
+	EventDevicesChanged // Sent with *DeviceChange when output devices are plugged in or removed
 )
 
+// DefaultTrackEndingNotice is how long before the end of a track
+// EventTrackEnding is sent
+const DefaultTrackEndingNotice = 10 * time.Second
+
+// TrackEnding is sent with EventTrackEnding. Next is the track queued for
+// gapless playback, if any, and Transition how it will follow.
+type TrackEnding struct {
+	Track      *domain.Track
+	Next       *domain.Track
+	Transition Transition
+	Remaining  time.Duration
+}
+
+// TrackError is sent with EventError when a track cannot be loaded or
+// fails while decoding
+type TrackError struct {
+	Track *domain.Track
+	Err   error
+}
+
+func (e *TrackError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Track.FilePath, e.Err)
+}
+
+func (e *TrackError) Unwrap() error {
+	return e.Err
+}
+
 // EventListener is a callback for player events
 type EventListener func(event PlayerEvent, data interface{})
 
@@ -69,36 +107,73 @@ type Player struct {
 	nextTrack     *domain.Track
 	position      time.Duration
 	duration      time.Duration
-	volume        float64
+	volume        float64 // Volume control position, mapped to gain in dB
+	maxVolumeDB   float64
 	speed         float64
+	speedMode     string           // How speed is changed, one of SpeedModes
+	stretcher     *dsp.TimeStretch // Changes tempo in SpeedModeStretch
 	
 	// Audio components
 	decoder       decoder.Decoder
 	nextDecoder   decoder.Decoder // For gapless playback
 	output        output.Output
+	outputFormat  output.Format
+	converter     *rateConverter // Resamples tracks to the output's rate
 	deviceManager output.DeviceManager
+	sources       *SourceResolver
+	previewer     *Previewer
 	
 	// Buffering
 	buffer        []float32
 	bufferSize    int
-	prebuffer     []float32 // For gapless playback
+	bufferFrames  int // Output buffer, which the watchdog grows after underruns
+	watchdog      watchdog
+	clips         *clipMeter
+	syncOffset    time.Duration // Latency past the output buffer, as Bluetooth adds
+	mixBuffer     []float32 // Decoded audio remixed to the output channels
+	carry         []float32 // Audio of the current track decoded during a crossfade, played first
+	left, right   []float32 // Stereo split for the effect chain
 	
 	// Control
 	mu            sync.RWMutex
+	decoding      sync.Mutex // Held while processAudio reads from the decoder
 	playing       chan bool
 	stop          chan bool
 	seekRequest   chan time.Duration
 	
 	// Events
 	listeners     []EventListener
+	bus           *events.Bus
 	listenerMu    sync.RWMutex
 	
 	// Settings
 	crossfade     time.Duration
+	crossfader    *dsp.Crossfader
+	effects       *dsp.EffectChain // Between decoding and output
+	preamp        *dsp.Preamp
+	equalizer     *dsp.Equalizer
+	limiter       *dsp.Limiter
+	mixing        *crossfade      // Overlap into the next track in progress
+	abandoned     decoder.Decoder // Next decoder a cancelled crossfade read from
 	gapless       bool
+	transitions   TransitionPolicy
+	transition    Transition    // Into nextTrack
+	transitionGap time.Duration // Silence a gap transition into nextTrack leaves
+	pendingGap    time.Duration // Silence to play before the current track starts
 	replayGain    bool
+	albumGain     bool // Prefer album gain over track gain
 	fadeOnPause   bool
 	fadeDuration  time.Duration
+	pauseOnLost   bool // Pause when the output device is removed mid-playback
+	fader         *fader
+	endingNotice  time.Duration
+	endingSent    bool
+	
+	// Volume leveling
+	leveling      bool
+	trackGain     float64                       // Linear gain for the current track
+	trackPeak     float64                       // Sample peak of the current track after trackGain, 0 when unknown
+	estimates     map[string]*domain.ReplayGain // Loudness estimates by track ID
 }
 
 // NewPlayer creates a new audio player
@@ -107,19 +182,35 @@ func NewPlayer() *Player {
 		state:         StateStopped,
 		volume:        1.0,
 		speed:         1.0,
+		speedMode:     SpeedModeResample,
 		bufferSize:    8192,
+		bufferFrames:  DefaultBufferFrames,
 		buffer:        make([]float32, 8192),
 		playing:       make(chan bool, 1),
 		stop:          make(chan bool, 1),
 		seekRequest:   make(chan time.Duration, 1),
 		listeners:     make([]EventListener, 0),
 		crossfade:     5 * time.Second,
+		crossfader:    dsp.NewCrossfader(),
 		gapless:       true,
+		transitions:   DefaultTransitionPolicy(),
+		transition:    TransitionGapless,
 		fadeOnPause:   true,
 		fadeDuration:  200 * time.Millisecond,
+		pauseOnLost:   true,
+		fader:         newFader(),
+		clips:         newClipMeter(),
+		endingNotice:  DefaultTrackEndingNotice,
+		trackGain:     1.0,
+		estimates:     make(map[string]*domain.ReplayGain),
 		deviceManager: output.NewOtoDeviceManager(),
+		sources:       NewSourceResolver(nil),
 	}
 	
+	p.crossfader.SetEnabled(true)
+	p.newEffects(44100)
+	p.previewer = NewPreviewer(p.deviceManager, p.sources)
+	
 	// Initialize output device
 	if err := p.initializeOutput(); err != nil {
 		logger.Error("Failed to initialize audio output", logger.Error(err))
@@ -128,6 +219,8 @@ func NewPlayer() *Player {
 	// Start playback loop
 	go p.playbackLoop()
 	
+	p.deviceManager.WatchDevices(p.onDevicesChanged)
+	
 	return p
 }
 
@@ -137,27 +230,63 @@ func (p *Player) initializeOutput() error {
 		return fmt.Errorf("failed to get default device: %w", err)
 	}
 	
-	p.output, err = p.deviceManager.CreateOutput(device)
-	if err != nil {
-		return fmt.Errorf("failed to create output: %w", err)
-	}
-	
+	return p.openOutput(device)
+}
+
+// openOutput creates and opens an output on the given device
+func (p *Player) openOutput(device *output.Device) error {
 	// Open with default format
 	format := output.Format{
 		SampleRate: 44100,
 		Channels:   2,
 		BitDepth:   16,
-		Latency:    50 * time.Millisecond,
+	}
+	format.Latency = framesToLatency(p.bufferFrames, format.SampleRate)
+	
+	return p.openOutputFormat(device, format)
+}
+
+// openOutputFormat creates and opens an output on the given device in a
+// specific format
+func (p *Player) openOutputFormat(device *output.Device, format output.Format) error {
+	var err error
+	p.output, err = p.deviceManager.CreateOutput(device)
+	if err != nil {
+		return fmt.Errorf("failed to create output: %w", err)
 	}
 	
 	if err := p.output.Open(format); err != nil {
+		p.output = nil
 		return fmt.Errorf("failed to open output: %w", err)
 	}
 	
-	p.output.SetVolume(p.volume)
+	p.outputFormat = format
+	p.setEffectsRate(format.SampleRate)
+	p.output.SetVolume(p.outputGain())
 	return nil
 }
 
+// negotiateFormat matches a track to the output by resampling it to the
+// output's rate. The output keeps the rate it was opened at: oto allows one
+// context per process and its rate can't change once created, so reopening
+// at a track's rate is not possible. When restart is set the converter
+// starts afresh, as for a newly loaded track; otherwise a converter already
+// running between the same rates carries on, so tracks joined gaplessly
+// have no seam. Must be called with p.mu held.
+func (p *Player) negotiateFormat(format decoder.AudioFormat, restart bool) {
+	previous := p.converter
+	p.converter = nil
+	if p.output == nil || format.SampleRate <= 0 {
+		return
+	}
+	
+	if !restart && previous != nil && previous.converts(format.SampleRate, p.outputFormat.SampleRate, p.outputFormat.Channels) {
+		p.converter = previous
+		return
+	}
+	p.converter = newTrackConverter(format.SampleRate, p.outputFormat, p.speed)
+}
+
 // Load loads a track for playback
 func (p *Player) Load(track *domain.Track) error {
 	p.mu.Lock()
@@ -174,21 +303,41 @@ func (p *Player) Load(track *domain.Track) error {
 	}
 	
 	// Create new decoder
-	dec, err := decoder.CreateDecoderForFile(track.FilePath)
+	dec, err := p.sources.OpenDecoder(context.Background(), track)
 	if err != nil {
+		p.notifyListeners(EventError, &TrackError{Track: track, Err: err})
 		return fmt.Errorf("failed to create decoder: %w", err)
 	}
 	
+	p.loadDecoder(track, dec)
+	return nil
+}
+
+// loadDecoder makes a newly opened decoder the current one, stopped at the
+// start of its track. Must be called with p.mu held.
+func (p *Player) loadDecoder(track *domain.Track, dec decoder.Decoder) {
 	p.decoder = dec
 	p.currentTrack = track
 	p.position = 0
 	p.duration = dec.Duration()
+	p.endingSent = false
+	p.pendingGap = 0
+	p.carry = nil
+	p.stretcher = nil
+	p.cancelCrossfade()
+	p.fader.set(1.0)
+	p.negotiateFormat(dec.Format(), true)
 	
 	// Update track duration if not set
 	if track.Duration == 0 {
 		track.Duration = p.duration
 	}
 	
+	p.updateTrackGain()
+	if p.needsLoudnessEstimate(track) {
+		go p.estimateLoudness(track)
+	}
+	
 	p.setState(StateStopped)
 	p.notifyListeners(EventTrackChanged, track)
 	
@@ -197,11 +346,10 @@ func (p *Player) Load(track *domain.Track) error {
 		logger.String("artist", track.GetDisplayArtist()),
 		logger.Duration("duration", p.duration),
 	)
-	
-	return nil
 }
 
-// Play starts or resumes playback
+// Play starts or resumes playback. Resuming fades in when fading is
+// enabled, as does starting part way through a track.
 func (p *Player) Play() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -211,17 +359,29 @@ func (p *Player) Play() error {
 	}
 	
 	switch p.state {
-	case StatePlaying:
+	case StatePlaying, StateBuffering:
 		return ErrAlreadyPlaying
 	case StatePaused:
+		if p.fadeOnPause {
+			// Also picks up from part way through a pause fade
+			p.fader.rampTo(1.0, p.fadeDuration)
+		} else {
+			p.fader.set(1.0)
+		}
 		if p.output != nil {
 			p.output.Resume()
 		}
 		p.setState(StatePlaying)
-		p.playing <- true
+		p.startProcessing()
 	case StateStopped:
+		if p.fadeOnPause && p.position > 0 {
+			p.fader.set(0)
+			p.fader.rampTo(1.0, p.fadeDuration)
+		} else {
+			p.fader.set(1.0)
+		}
 		p.setState(StatePlaying)
-		p.playing <- true
+		p.startProcessing()
 		if p.output != nil {
 			p.output.Resume()
 		}
@@ -230,18 +390,31 @@ func (p *Player) Play() error {
 	return nil
 }
 
-// Pause pauses playback
+// Pause pauses playback, fading out first when enabled. The player reports
+// paused straight away; the output is paused once the fade completes.
 func (p *Player) Pause() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	
+	if p.state == StateBuffering {
+		// Nothing is being heard, so there is nothing to fade
+		if p.output != nil {
+			p.output.Pause()
+		}
+		p.setState(StatePaused)
+		return nil
+	}
 	if p.state != StatePlaying {
 		return ErrNotPlaying
 	}
 	
-	if p.fadeOnPause {
-		// Apply fade out
-		go p.fadeOut(p.fadeDuration)
+	if p.fadeOnPause && p.output != nil {
+		result := p.fader.rampTo(0, p.fadeDuration)
+		p.setState(StatePaused)
+		go p.afterFade(result, p.fadeDuration, StatePaused, func() {
+			p.output.Pause()
+		})
+		return nil
 	}
 	
 	if p.output != nil {
@@ -252,7 +425,7 @@ func (p *Player) Pause() error {
 	return nil
 }
 
-// Stop stops playback
+// Stop stops playback, fading out first when enabled
 func (p *Player) Stop() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -261,6 +434,57 @@ func (p *Player) Stop() error {
 		return nil
 	}
 	
+	if p.state == StatePlaying && p.fadeOnPause && p.output != nil {
+		result := p.fader.rampTo(0, p.fadeDuration)
+		p.setState(StateStopped)
+		go p.afterFade(result, p.fadeDuration, StateStopped, p.stopOutput)
+		return nil
+	}
+	
+	p.stopOutput()
+	p.setState(StateStopped)
+	
+	return nil
+}
+
+// Release stops playback without fading when a track is loaded or queued
+// next, and closes its decoder. It returns once the file is closed, so it can
+// then be moved or deleted.
+func (p *Player) Release(trackID string) {
+	p.mu.Lock()
+	current := p.currentTrack != nil && p.currentTrack.ID == trackID
+	if current && p.state != StateStopped {
+		p.fader.set(1.0)
+		p.stopOutput()
+		p.setState(StateStopped)
+	}
+	p.mu.Unlock()
+	
+	// Wait for the playback loop to stop reading
+	p.decoding.Lock()
+	defer p.decoding.Unlock()
+	
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	if current && p.decoder != nil {
+		p.decoder.Close()
+		p.decoder = nil
+		p.carry = nil
+	}
+	if p.nextTrack != nil && p.nextTrack.ID == trackID {
+		p.cancelCrossfade()
+		if p.nextDecoder != nil {
+			p.nextDecoder.Close()
+			p.nextDecoder = nil
+		}
+		p.nextTrack = nil
+		p.transition = TransitionGapless
+	}
+}
+
+// stopOutput ends playback of the current track. Callers must hold p.mu.
+func (p *Player) stopOutput() {
 	select {
 	case p.stop <- true:
 	default:
@@ -272,9 +496,36 @@ func (p *Player) Stop() error {
 	}
 	
 	p.position = 0
-	p.setState(StateStopped)
+	p.carry = nil
+	p.stretcher = nil
+	p.cancelCrossfade()
+}
+
+// afterFade runs action once a fade completes, provided the fade was not
+// replaced and the player is still in state. A fade only advances while
+// audio flows, so it is given up on if audio stops first.
+func (p *Player) afterFade(result <-chan bool, duration time.Duration, state PlayerState, action func()) {
+	completed := true
+	select {
+	case completed = <-result:
+	case <-time.After(duration + fadeTimeout):
+	}
 	
-	return nil
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	if completed && p.state == state {
+		action()
+	}
+}
+
+// startProcessing wakes the playback loop. The loop may still be running a
+// fade out, in which case it simply carries on. Callers must hold p.mu.
+func (p *Player) startProcessing() {
+	select {
+	case p.playing <- true:
+	default:
+	}
 }
 
 // Seek seeks to a position in the track
@@ -298,7 +549,8 @@ func (p *Player) Seek(position time.Duration) error {
 	return nil
 }
 
-// SetVolume sets the playback volume (0.0 to 1.0)
+// SetVolume sets the playback volume (0.0 to 1.0). The volume is a
+// position on a dB scale rather than a linear gain; see VolumeToGain.
 func (p *Player) SetVolume(volume float64) error {
 	if volume < 0.0 || volume > 1.0 {
 		return errors.New("volume must be between 0.0 and 1.0")
@@ -309,13 +561,205 @@ func (p *Player) SetVolume(volume float64) error {
 	
 	p.volume = volume
 	if p.output != nil {
-		p.output.SetVolume(volume)
+		p.output.SetVolume(p.outputGain())
 	}
 	
 	p.notifyListeners(EventVolumeChanged, volume)
 	return nil
 }
 
+// GetVolume returns the playback volume (0.0 to 1.0)
+func (p *Player) GetVolume() float64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.volume
+}
+
+// GetVolumeDB returns the playback level in dB relative to full scale, or
+// negative infinity when muted
+func (p *Player) GetVolumeDB() float64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return VolumeToDB(p.volume, p.maxVolumeDB)
+}
+
+// StepVolume changes the volume by deltaDB decibels, for fine control from
+// hotkeys, and returns the new volume
+func (p *Player) StepVolume(deltaDB float64) float64 {
+	p.mu.RLock()
+	volume := p.volume + deltaDB/volumeRangeDB
+	p.mu.RUnlock()
+	
+	volume = math.Max(0, math.Min(1, volume))
+	p.SetVolume(volume)
+	return volume
+}
+
+// SetMaxVolumeDB sets the level the top of the volume control plays at,
+// between MinMaxVolumeDB and MaxMaxVolumeDB
+func (p *Player) SetMaxVolumeDB(db float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	p.maxVolumeDB = clampMaxVolumeDB(db)
+	if p.output != nil {
+		p.output.SetVolume(p.outputGain())
+	}
+}
+
+// SetTrackEndingNotice sets how long before the end of a track
+// EventTrackEnding is sent. Zero disables the event.
+func (p *Player) SetTrackEndingNotice(notice time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.endingNotice = notice
+}
+
+// SetSourceResolver replaces the resolver used to open tracks, so CD and
+// remote server sources can be added
+func (p *Player) SetSourceResolver(sources *SourceResolver) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	p.sources = sources
+	p.previewer.setSourceResolver(sources)
+}
+
+// SetFade sets whether pausing, resuming and stopping fade, and how long
+// fades take
+func (p *Player) SetFade(enabled bool, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	p.fadeOnPause = enabled
+	if duration > 0 {
+		p.fadeDuration = duration
+	}
+}
+
+// outputGain returns the linear gain for the current volume. Callers must
+// hold p.mu.
+func (p *Player) outputGain() float64 {
+	return VolumeToGain(p.volume, p.maxVolumeDB)
+}
+
+// GetOutputDevices returns the available audio output devices
+func (p *Player) GetOutputDevices() ([]*output.Device, error) {
+	return p.deviceManager.EnumerateDevices()
+}
+
+// Sources returns the resolver the player opens tracks with
+func (p *Player) Sources() *SourceResolver {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.sources
+}
+
+// DeviceManager returns the manager the player opens outputs with
+func (p *Player) DeviceManager() output.DeviceManager {
+	return p.deviceManager
+}
+
+// SetOutputDevice switches playback to another output device. An empty ID
+// or "default" selects the system default device.
+func (p *Player) SetOutputDevice(id string) error {
+	var device *output.Device
+	var err error
+	if id == "" || id == "default" {
+		device, err = p.deviceManager.GetDefaultDevice()
+	} else {
+		device, err = p.deviceManager.GetDevice(id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get device %q: %w", id, err)
+	}
+	
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	return p.switchOutput(device)
+}
+
+// switchOutput moves playback to another device, carrying on from the same
+// position. Must be called with p.mu held.
+func (p *Player) switchOutput(device *output.Device) error {
+	if p.output != nil {
+		p.output.Close()
+		p.output = nil
+	}
+	
+	if err := p.openOutput(device); err != nil {
+		return err
+	}
+	
+	if p.decoder != nil {
+		p.negotiateFormat(p.decoder.Format(), false)
+	}
+	if p.state != StatePlaying && p.state != StateBuffering {
+		p.output.Pause()
+	}
+	return nil
+}
+
+// StartPreview auditions part of a track quietly alongside the main
+// playback. Start is a fraction of the track (0.0-1.0); zero values use the
+// defaults.
+func (p *Player) StartPreview(track *domain.Track, start float64, length time.Duration) error {
+	return p.previewer.Start(track, start, length)
+}
+
+// StopPreview ends any preview that is playing
+func (p *Player) StopPreview() {
+	p.previewer.Stop()
+}
+
+// GetPreviewTrackID returns the ID of the track being previewed, or ""
+func (p *Player) GetPreviewTrackID() string {
+	return p.previewer.Current()
+}
+
+// SetReplayGain enables or disables ReplayGain from track tags
+func (p *Player) SetReplayGain(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	p.replayGain = enabled
+	p.updateTrackGain()
+}
+
+// SetReplayGainMode chooses between "track" gain, which levels every track
+// alike, and "album" gain, which keeps the loudness differences between an
+// album's tracks. Tracks without album gain fall back to track gain.
+func (p *Player) SetReplayGainMode(mode string) error {
+	if mode != "track" && mode != "album" {
+		return fmt.Errorf("unknown ReplayGain mode: %s", mode)
+	}
+	
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	p.albumGain = mode == "album"
+	p.updateTrackGain()
+	return nil
+}
+
+// SetVolumeLeveling enables or disables estimated loudness leveling for
+// tracks without ReplayGain information
+func (p *Player) SetVolumeLeveling(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	p.leveling = enabled
+	p.updateTrackGain()
+}
+
+// IsVolumeLeveling returns whether volume leveling is enabled
+func (p *Player) IsVolumeLeveling() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.leveling
+}
+
 // SetSpeed sets the playback speed (0.5 to 2.0)
 func (p *Player) SetSpeed(speed float64) error {
 	if speed < 0.5 || speed > 2.0 {
@@ -362,8 +806,10 @@ func (p *Player) SetNextTrack(track *domain.Track) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	
+	p.cancelCrossfade()
 	if track == nil {
 		p.nextTrack = nil
+		p.transition = TransitionGapless
 		if p.nextDecoder != nil {
 			p.nextDecoder.Close()
 			p.nextDecoder = nil
@@ -372,22 +818,158 @@ func (p *Player) SetNextTrack(track *domain.Track) error {
 	}
 	
 	// Create decoder for next track
-	dec, err := decoder.CreateDecoderForFile(track.FilePath)
+	dec, err := p.sources.OpenDecoder(context.Background(), track)
 	if err != nil {
 		return fmt.Errorf("failed to create decoder for next track: %w", err)
 	}
 	
+	// Decode the start of the next track ahead so it follows without a gap.
+	// Streams buffer themselves.
+	if _, stream := dec.(*bufferedStream); p.gapless && !stream {
+		dec = newDecodeAhead(dec)
+	}
+	
 	p.nextTrack = track
 	p.nextDecoder = dec
+	choice := p.transitions.Explain(p.currentTrack, track)
+	p.transition, p.transitionGap = choice.Transition, choice.Gap
+	
+	// The output is not reopened between gapless tracks, so a change of
+	// rate is resampled instead
+	if rate := dec.Format().SampleRate; p.output != nil && rate != p.outputFormat.SampleRate {
+		logger.Debug("Next track will be resampled",
+			logger.Int("from", rate),
+			logger.Int("to", p.outputFormat.SampleRate))
+	}
 	
-	// Pre-buffer if gapless is enabled
-	if p.gapless && len(p.prebuffer) > 0 {
-		p.nextDecoder.Decode(p.prebuffer)
+	// Estimate loudness ahead of time so the transition is already levelled
+	if p.needsLoudnessEstimate(track) {
+		go p.estimateLoudness(track)
 	}
 	
 	return nil
 }
 
+// needsLoudnessEstimate reports whether a track lacks gain information and
+// has not been analysed yet. Must be called with p.mu held.
+func (p *Player) needsLoudnessEstimate(track *domain.Track) bool {
+	if !p.leveling || track == nil || hasMeasuredGain(track) || track.IsNetworkPath() {
+		return false
+	}
+	_, exists := p.estimates[track.ID]
+	return !exists
+}
+
+// hasMeasuredGain reports whether a track has ReplayGain from its tags or a
+// full scan. Tracks loaded from the library always have a ReplayGain, left
+// empty when there is none, so a missing one is told apart by its peak.
+func hasMeasuredGain(track *domain.Track) bool {
+	rg := track.ReplayGain
+	return rg != nil && !rg.Estimated && rg.TrackPeak != 0
+}
+
+// GainEstimate returns the loudness estimate made for a track without
+// ReplayGain, or nil when there is none yet. Estimates are kept by the
+// player and never written to the track, so they can't be saved as tags.
+func (p *Player) GainEstimate(trackID string) *domain.ReplayGain {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	
+	estimate := p.estimates[trackID]
+	if estimate == nil {
+		return nil
+	}
+	rg := *estimate
+	return &rg
+}
+
+// estimateLoudness analyses a track on a separate decoder and records the
+// estimated gain for when it becomes the current track
+func (p *Player) estimateLoudness(track *domain.Track) {
+	p.mu.Lock()
+	if _, exists := p.estimates[track.ID]; exists {
+		p.mu.Unlock()
+		return
+	}
+	// Reserve the slot so concurrent requests don't analyse twice
+	p.estimates[track.ID] = nil
+	sources := p.sources
+	p.mu.Unlock()
+	
+	dec, err := sources.OpenDecoder(context.Background(), track)
+	if err != nil {
+		logger.Warn("Failed to open track for loudness estimate",
+			logger.String("path", track.FilePath),
+			logger.Error(err))
+		return
+	}
+	defer dec.Close()
+	
+	estimate, err := EstimateLoudness(dec, levelingWindow)
+	if err != nil {
+		logger.Warn("Failed to estimate loudness",
+			logger.String("path", track.FilePath),
+			logger.Error(err))
+		return
+	}
+	
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	p.estimates[track.ID] = estimate
+	if p.currentTrack != nil && p.currentTrack.ID == track.ID {
+		p.updateTrackGain()
+	}
+	
+	logger.Debug("Estimated track loudness",
+		logger.String("title", track.GetDisplayTitle()),
+		logger.Float64("gain_db", estimate.TrackGain),
+		logger.Float64("peak", estimate.TrackPeak),
+	)
+}
+
+// updateTrackGain recalculates the gain applied to the current track from
+// its ReplayGain tags or, failing that, a loudness estimate from
+// p.estimates. Must be called with p.mu held.
+func (p *Player) updateTrackGain() {
+	p.trackGain, p.trackPeak = p.gainFor(p.currentTrack)
+}
+
+// gainFor returns the linear gain to apply to a track and its sample peak
+// after that gain, 0 when unknown. Must be called with p.mu held.
+func (p *Player) gainFor(track *domain.Track) (gain, peak float64) {
+	if track == nil {
+		return 1.0, 0
+	}
+	
+	rg := track.ReplayGain
+	if !hasMeasuredGain(track) {
+		rg = p.estimates[track.ID]
+	}
+	if rg == nil {
+		return 1.0, 0
+	}
+	
+	db, peak := rg.TrackGain, rg.TrackPeak
+	if p.albumGain && rg.AlbumPeak > 0 {
+		db, peak = rg.AlbumGain, rg.AlbumPeak
+	}
+	
+	gain = 1.0
+	if (rg.Estimated && p.leveling) || (!rg.Estimated && p.replayGain) {
+		gain = gainToLinear(db, peak)
+	}
+	return gain, peak * gain
+}
+
+// CurrentPeak returns the linear sample peak of the current track after the
+// gain applied to it, or 0 when the track's peak is unknown
+func (p *Player) CurrentPeak() float64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.trackPeak
+}
+
 // AddListener adds an event listener
 func (p *Player) AddListener(listener EventListener) {
 	p.listenerMu.Lock()
@@ -420,11 +1002,15 @@ func (p *Player) notifyListeners(event PlayerEvent, data interface{}) {
 	p.listenerMu.RLock()
 	listeners := make([]EventListener, len(p.listeners))
 	copy(listeners, p.listeners)
+	bus := p.bus
 	p.listenerMu.RUnlock()
 	
 	for _, listener := range listeners {
 		go listener(event, data)
 	}
+	if bus != nil {
+		publish(bus, event, data)
+	}
 }
 
 func (p *Player) playbackLoop() {
@@ -451,6 +1037,10 @@ func (p *Player) playbackLoop() {
 					logger.Error("Failed to seek", logger.Error(err))
 				} else {
 					p.position = position
+					p.endingSent = false
+					p.carry = nil
+					p.stretcher = nil
+					p.cancelCrossfade()
 					p.notifyListeners(EventPositionChanged, position)
 				}
 			}
@@ -469,6 +1059,9 @@ func (p *Player) playbackLoop() {
 }
 
 func (p *Player) processAudio() {
+	p.decoding.Lock()
+	defer p.decoding.Unlock()
+	
 	p.mu.RLock()
 	dec := p.decoder
 	out := p.output
@@ -478,8 +1071,25 @@ func (p *Player) processAudio() {
 	if dec == nil || out == nil {
 		return
 	}
+	stream, _ := dec.(*bufferedStream)
+	
+	p.watchdog.start()
+	watching := make(chan struct{})
+	go p.watchForStalls(watching)
+	defer func() {
+		close(watching)
+		p.watchdog.finish()
+	}()
+	
+	if !p.playGap() {
+		return
+	}
+	if !p.playCarry() {
+		return
+	}
 	
-	for p.state == StatePlaying {
+	// Keep going after a pause or stop until any fade out has finished
+	for p.state == StatePlaying || p.fader.fadingOut() {
 		// Check for seek requests
 		select {
 		case position := <-p.seekRequest:
@@ -488,25 +1098,65 @@ func (p *Player) processAudio() {
 				logger.Error("Failed to seek", logger.Error(err))
 			} else {
 				p.position = position
+				p.endingSent = false
+				p.carry = nil
+				p.stretcher = nil
+				p.cancelCrossfade()
 			}
 			p.mu.Unlock()
+			p.watchdog.start()
 			continue
 		case <-p.stop:
 			return
 		default:
 		}
 		
+		// Hold off while a stream fills its prebuffer, at the start and
+		// after running dry
+		if stream != nil && p.state == StatePlaying {
+			if _, ready := stream.buffer.progress(); !ready {
+				p.watchdog.finish()
+				if !p.waitForBuffer(stream.buffer) {
+					return
+				}
+				p.watchdog.start()
+				continue
+			}
+		}
+		
+		// Start overlapping the next track near the end of this one, or
+		// decoding it ahead when it follows straight on
+		p.mu.Lock()
+		p.rewindAbandoned()
+		p.startCrossfade()
+		p.decodeNextAhead(bufSize)
+		xf := p.mixing
+		p.mu.Unlock()
+		
 		// Decode audio
 		n, err := dec.Decode(p.buffer[:bufSize])
 		if err != nil {
 			if err == decoder.ErrEndOfStream {
-				// Track finished
-				p.handleTrackFinished()
-				return
+				// Track finished; carry straight on into the next one so
+				// its first sample follows this one's last
+				if !p.handleTrackFinished() {
+					return
+				}
+				p.mu.RLock()
+				dec = p.decoder
+				p.mu.RUnlock()
+				stream, _ = dec.(*bufferedStream)
+				if !p.playGap() || !p.playCarry() {
+					return
+				}
+				continue
 			}
 			logger.Error("Decode error", logger.Error(err))
 			p.mu.Lock()
 			p.setState(StateError)
+			if p.currentTrack != nil {
+				p.notifyListeners(EventError, &TrackError{Track: p.currentTrack, Err: err})
+			}
 			p.mu.Unlock()
 			return
 		}
@@ -515,14 +1165,63 @@ func (p *Player) processAudio() {
 			continue
 		}
 		
-		// Apply speed adjustment if needed
-		samples := p.buffer[:n*2] // Stereo
-		if p.speed != 1.0 {
-			samples = p.applySpeedChange(samples, p.speed)
+		channels := dec.Format().Channels
+		if channels <= 0 {
+			channels = 2
 		}
 		
+		// Convert to the output layout, rate and speed, then apply
+		// ReplayGain or estimated leveling gain
+		samples := p.buffer[:n*channels]
+		p.mu.Lock()
+		gain := p.trackGain
+		track := p.currentTrack
+		speed := p.speed
+		format := p.outputFormat
+		rateSpeed := speed // The part of the speed resampling makes, shifting pitch
+		var stretcher *dsp.TimeStretch
+		if p.speedMode == SpeedModeStretch {
+			rateSpeed = 1.0
+			stretcher = p.stretcherFor(format, speed)
+		}
+		if p.converter == nil && rateSpeed != 1.0 {
+			p.converter = newTrackConverter(dec.Format().SampleRate, format, rateSpeed)
+		}
+		converter := p.converter
+		out = p.output // Reopened when the format changes
+		p.mu.Unlock()
+		if out == nil {
+			return
+		}
+		if channels != format.Channels {
+			p.mixBuffer = remix(p.mixBuffer, samples, channels, format.Channels)
+			samples = p.mixBuffer
+		}
+		if converter != nil {
+			samples = converter.process(samples, rateSpeed)
+		}
+		if gain != 1.0 {
+			output.ApplyVolume(samples, gain)
+		}
+		if xf != nil {
+			mixed, err := p.mix(xf, samples, format, rateSpeed)
+			if err != nil {
+				logger.ErrorLog("Failed to read next track for crossfade", logger.Error(err))
+				p.mu.Lock()
+				p.cancelCrossfade()
+				p.mu.Unlock()
+			}
+			samples = mixed
+		}
+		if stretcher != nil {
+			samples = stretcher.Process(samples, speed)
+		}
+		p.finishOutput(samples, format, track, gain)
+		
 		// Write to output
+		started := time.Now()
 		_, err = out.Write(samples)
+		p.noteWrite(started)
 		if err != nil {
 			logger.Error("Output error", logger.Error(err))
 			continue
@@ -531,14 +1230,21 @@ func (p *Player) processAudio() {
 		// Update position
 		p.mu.Lock()
 		p.position = dec.Position()
+		p.checkTrackEnding()
 		p.mu.Unlock()
 	}
 }
 
-func (p *Player) handleTrackFinished() {
+// handleTrackFinished moves on to the next track, or stops when there is
+// none. It reports whether playback carries on with the next track.
+func (p *Player) handleTrackFinished() bool {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	
+	p.rewindAbandoned()
+	xf := p.mixing
+	p.mixing = nil
+	
 	// Check for next track (gapless playback)
 	if p.nextDecoder != nil && p.nextTrack != nil {
 		// Switch to next track
@@ -548,86 +1254,69 @@ func (p *Player) handleTrackFinished() {
 		
 		p.decoder = p.nextDecoder
 		p.currentTrack = p.nextTrack
-		p.position = 0
+		p.position = p.decoder.Position() // Past the start after a crossfade
 		p.duration = p.decoder.Duration()
+		p.endingSent = false
 		
 		p.nextDecoder = nil
 		p.nextTrack = nil
-		
-		p.notifyListeners(EventTrackChanged, p.currentTrack)
-		
-		// Continue playing
-		if p.state == StatePlaying {
-			go p.processAudio()
+		if p.transition == TransitionGap {
+			p.pendingGap = p.transitionGap
 		}
-	} else {
-		// No next track, stop
-		p.setState(StateStopped)
-		p.position = 0
-		p.notifyListeners(EventTrackFinished, p.currentTrack)
-	}
-}
-
-func (p *Player) fadeOut(duration time.Duration) {
-	steps := int(duration / (10 * time.Millisecond))
-	if steps <= 0 {
-		steps = 1
-	}
-	
-	startVolume := p.volume
-	volumeStep := startVolume / float64(steps)
-	
-	for i := 0; i < steps; i++ {
-		newVolume := startVolume - (volumeStep * float64(i+1))
-		if newVolume < 0 {
-			newVolume = 0
+		p.transition = TransitionGapless
+		p.updateTrackGain()
+		p.negotiateFormat(p.decoder.Format(), false)
+		if xf != nil && xf.decoder == p.decoder {
+			p.finishCrossfade(xf)
 		}
 		
-		p.mu.Lock()
-		if p.output != nil {
-			p.output.SetVolume(newVolume)
-		}
-		p.mu.Unlock()
+		p.notifyListeners(EventTrackChanged, p.currentTrack)
 		
-		time.Sleep(10 * time.Millisecond)
+		// Continue playing
+		return p.state == StatePlaying
 	}
 	
-	// Restore original volume
-	p.mu.Lock()
-	if p.output != nil {
-		p.output.SetVolume(startVolume)
-	}
-	p.mu.Unlock()
+	// No next track, stop
+	p.setState(StateStopped)
+	p.position = 0
+	p.notifyListeners(EventTrackFinished, p.currentTrack)
+	return false
 }
 
-func (p *Player) applySpeedChange(samples []float32, speed float64) []float32 {
-	// Simple speed change by resampling
-	// This is a basic implementation - production would use a proper resampler
-	if speed == 1.0 {
-		return samples
+// checkTrackEnding sends EventTrackEnding once per track when playback
+// comes within the notice period of the end. Must be called with p.mu held.
+func (p *Player) checkTrackEnding() {
+	if p.endingSent || p.endingNotice <= 0 || p.duration <= 0 || p.currentTrack == nil {
+		return
 	}
 	
-	inputLen := len(samples)
-	outputLen := int(float64(inputLen) / speed)
-	output := make([]float32, outputLen)
-	
-	for i := 0; i < outputLen; i++ {
-		srcIndex := int(float64(i) * speed)
-		if srcIndex < inputLen {
-			output[i] = samples[srcIndex]
-		}
+	remaining := p.duration - p.position
+	if remaining > p.endingNotice {
+		return
 	}
 	
-	return output
+	p.endingSent = true
+	p.notifyListeners(EventTrackEnding, &TrackEnding{
+		Track:      p.currentTrack,
+		Next:       p.nextTrack,
+		Transition: p.transition,
+		Remaining:  remaining,
+	})
 }
 
 // Close closes the player and releases resources
 func (p *Player) Close() error {
-	p.Stop()
+	p.previewer.Stop()
 	
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	
+	// Stop without fading, as everything is about to be released
+	if p.state != StateStopped {
+		p.stopOutput()
+		p.setState(StateStopped)
+	}
+	
 	if p.decoder != nil {
 		p.decoder.Close()
 		p.decoder = nil
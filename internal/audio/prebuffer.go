@@ -0,0 +1,274 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/audio/decoder"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+const (
+	// DefaultStreamPrebuffer is how much of a stream is buffered before it
+	// starts playing, and again after the buffer runs dry
+	DefaultStreamPrebuffer = 2 * time.Second
+
+	// assumedStreamBitrate sizes a time-based prebuffer for streams that
+	// don't announce their bitrate, in kbps
+	assumedStreamBitrate = 128
+
+	// maxStreamPrebuffer caps how far repeated underruns grow the prebuffer
+	maxStreamPrebuffer = 30 * time.Second
+
+	// underrunsBeforeGrowth is how many times a stream may run dry before
+	// its prebuffer is enlarged
+	underrunsBeforeGrowth = 2
+
+	// prebufferGrowth is the factor the prebuffer grows by each time
+	prebufferGrowth = 1.5
+
+	// bufferReportInterval is how often fill progress is sent while a
+	// stream buffers
+	bufferReportInterval = 100 * time.Millisecond
+
+	streamReadSize = 16 * 1024
+)
+
+// BufferProgress is sent with EventBuffering while a stream fills its
+// prebuffer. Percent runs from 0 to 100.
+type BufferProgress struct {
+	Track   *domain.Track
+	Percent float64
+}
+
+// PrebufferSettings sets how much of a stream is buffered before playing.
+// Bytes, when set, takes precedence over Duration, which is converted to
+// bytes at the bitrate the stream announces.
+type PrebufferSettings struct {
+	Duration time.Duration
+	Bytes    int
+}
+
+// size returns the prebuffer in bytes for a stream at a bitrate in kbps,
+// or at an assumed bitrate when it is unknown
+func (s PrebufferSettings) size(bitrate int) int {
+	if s.Bytes > 0 {
+		return s.Bytes
+	}
+	duration := s.Duration
+	if duration <= 0 {
+		duration = DefaultStreamPrebuffer
+	}
+	return durationToBytes(duration, bitrate)
+}
+
+func durationToBytes(duration time.Duration, bitrate int) int {
+	if bitrate <= 0 {
+		bitrate = assumedStreamBitrate
+	}
+	return int(duration.Seconds() * float64(bitrate) * 1000 / 8)
+}
+
+// streamBuffer reads a network stream ahead of its decoder. Playback waits
+// while it fills to its target, both at the start and whenever the stream
+// falls behind and the buffer runs dry. Underruns that keep happening grow
+// the target, up to a limit.
+type streamBuffer struct {
+	src    io.ReadCloser
+	mu     sync.Mutex
+	cond   *sync.Cond
+	data   bytes.Buffer
+	target int
+	limit  int
+	ready  bool  // Filled to the target since the last underrun
+	err    error // Why the source stopped, io.EOF at its end
+	closed bool
+
+	underruns int
+	onGrow    func(target int)
+}
+
+// newStreamBuffer starts reading a stream ahead. onGrow, which may be nil,
+// is told when underruns enlarge the target.
+func newStreamBuffer(src io.ReadCloser, target, limit int, onGrow func(target int)) *streamBuffer {
+	b := &streamBuffer{
+		src:    src,
+		target: min(target, limit),
+		limit:  limit,
+		onGrow: onGrow,
+	}
+	b.cond = sync.NewCond(&b.mu)
+
+	go b.fill()
+	return b
+}
+
+// fill copies the stream into the buffer, holding off while the buffer is
+// well ahead of playback
+func (b *streamBuffer) fill() {
+	chunk := make([]byte, streamReadSize)
+	for {
+		n, err := b.src.Read(chunk)
+
+		b.mu.Lock()
+		b.data.Write(chunk[:n])
+		if !b.ready && b.data.Len() >= b.target {
+			b.ready = true
+		}
+		if err != nil {
+			b.err = err
+		}
+		b.cond.Broadcast()
+
+		// Stay a couple of prebuffers ahead at most
+		for !b.closed && b.err == nil && b.data.Len() >= 2*b.target {
+			b.cond.Wait()
+		}
+		done := b.closed || b.err != nil
+		b.mu.Unlock()
+
+		if done {
+			return
+		}
+	}
+}
+
+// Read reads buffered audio, waiting for more when the buffer is empty
+func (b *streamBuffer) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for b.data.Len() == 0 && b.err == nil && !b.closed {
+		if b.ready {
+			b.underrun()
+		}
+		b.cond.Wait()
+	}
+
+	if b.data.Len() == 0 {
+		if b.closed {
+			return 0, io.ErrClosedPipe
+		}
+		return 0, b.err
+	}
+
+	n, _ := b.data.Read(p)
+	b.cond.Broadcast()
+	return n, nil
+}
+
+// underrun records the buffer running dry. Must be called with b.mu held.
+func (b *streamBuffer) underrun() {
+	b.ready = false
+	b.underruns++
+	if b.underruns < underrunsBeforeGrowth || b.target >= b.limit {
+		return
+	}
+
+	b.underruns = 0
+	b.target = min(int(float64(b.target)*prebufferGrowth), b.limit)
+	logger.Debug("Stream prebuffer enlarged after underruns", logger.Int("bytes", b.target))
+	if b.onGrow != nil {
+		b.onGrow(b.target)
+	}
+}
+
+// progress reports how full the buffer is as a percentage of its target,
+// and whether playback can go on. A stream that has ended is always ready,
+// as no more is coming.
+func (b *streamBuffer) progress() (float64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ready || b.err != nil || b.closed {
+		return 100, true
+	}
+	return min(100, float64(b.data.Len())*100/float64(b.target)), false
+}
+
+// Len returns the number of buffered bytes
+func (b *streamBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data.Len()
+}
+
+// Close stops reading ahead and closes the stream
+func (b *streamBuffer) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	b.cond.Broadcast()
+	b.mu.Unlock()
+
+	return b.src.Close()
+}
+
+// bufferedStream is a stream decoder reading through a streamBuffer, which
+// the player waits on while it fills
+type bufferedStream struct {
+	decoder.StreamDecoder
+	buffer *streamBuffer
+}
+
+// Buffered returns the number of bytes read ahead of the decoder
+func (s *bufferedStream) Buffered() int {
+	return s.buffer.Len()
+}
+
+func (s *bufferedStream) Close() error {
+	err := s.StreamDecoder.Close()
+	s.buffer.Close()
+	return err
+}
+
+// waitForBuffer reports the player as buffering until a stream's buffer
+// has filled, sending its progress as it goes. It returns false if playback
+// was paused or stopped in the meantime.
+func (p *Player) waitForBuffer(buffer *streamBuffer) bool {
+	p.mu.Lock()
+	if p.state != StatePlaying {
+		p.mu.Unlock()
+		return false
+	}
+	track := p.currentTrack
+	p.setState(StateBuffering)
+	p.mu.Unlock()
+
+	ticker := time.NewTicker(bufferReportInterval)
+	defer ticker.Stop()
+
+	for {
+		percent, ready := buffer.progress()
+		p.notifyListeners(EventBuffering, &BufferProgress{Track: track, Percent: percent})
+		if ready {
+			break
+		}
+
+		select {
+		case <-p.stop:
+			return false
+		case <-ticker.C:
+		}
+
+		if p.GetState() != StateBuffering {
+			return false
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state != StateBuffering {
+		return false
+	}
+	p.setState(StatePlaying)
+	return true
+}
+
+// SetStreamPrebuffer sets how much of a network stream is buffered before
+// it plays. It applies to streams opened from now on.
+func (p *Player) SetStreamPrebuffer(settings PrebufferSettings) {
+	p.sources.SetStreamPrebuffer(settings)
+}
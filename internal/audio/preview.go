@@ -0,0 +1,249 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/audio/decoder"
+	"github.com/winramp/winramp/internal/audio/output"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+const (
+	// DefaultPreviewLength is how long a preview plays
+	DefaultPreviewLength = 10 * time.Second
+
+	// DefaultPreviewStart is where a preview starts, as a fraction of the
+	// track, to skip quiet intros
+	DefaultPreviewStart = 0.3
+
+	previewVolume = 0.35
+	previewFade   = 250 * time.Millisecond
+	previewBuffer = 2048 // Frames per decode
+)
+
+// previewFormat must match the main output, as outputs share one device
+// stream
+var previewFormat = output.Format{
+	SampleRate: 44100,
+	Channels:   2,
+	BitDepth:   16,
+	Latency:    50 * time.Millisecond,
+}
+
+// Previewer plays short, quiet auditions of tracks on its own decoder and
+// output so browsing the library never disturbs the main playback
+type Previewer struct {
+	deviceManager output.DeviceManager
+	sources       *SourceResolver
+
+	trackID string
+	stop    chan struct{}
+	done    chan struct{}
+	mu      sync.Mutex
+}
+
+// NewPreviewer creates a previewer playing on the given devices
+func NewPreviewer(deviceManager output.DeviceManager, sources *SourceResolver) *Previewer {
+	return &Previewer{
+		deviceManager: deviceManager,
+		sources:       sources,
+	}
+}
+
+func (pv *Previewer) setSourceResolver(sources *SourceResolver) {
+	pv.mu.Lock()
+	defer pv.mu.Unlock()
+	pv.sources = sources
+}
+
+// Start plays length of a track from start (0.0-1.0 of its duration),
+// replacing any preview already playing
+func (pv *Previewer) Start(track *domain.Track, start float64, length time.Duration) error {
+	if track == nil {
+		return errors.New("track is nil")
+	}
+	if start < 0 || start >= 1 {
+		start = DefaultPreviewStart
+	}
+	if length <= 0 {
+		length = DefaultPreviewLength
+	}
+
+	pv.Stop()
+
+	pv.mu.Lock()
+	sources := pv.sources
+	pv.mu.Unlock()
+
+	dec, err := sources.OpenDecoder(context.Background(), track)
+	if err != nil {
+		return fmt.Errorf("failed to open track for preview: %w", err)
+	}
+
+	if duration := dec.Duration(); duration > 0 {
+		offset := time.Duration(float64(duration) * start)
+		if err := dec.Seek(offset); err != nil && !errors.Is(err, decoder.ErrSeekNotSupported) {
+			dec.Close()
+			return fmt.Errorf("failed to seek preview: %w", err)
+		}
+	}
+
+	device, err := pv.deviceManager.GetDefaultDevice()
+	if err != nil {
+		dec.Close()
+		return err
+	}
+	out, err := pv.deviceManager.CreateOutput(device)
+	if err != nil {
+		dec.Close()
+		return err
+	}
+	if err := out.Open(previewFormat); err != nil {
+		dec.Close()
+		return fmt.Errorf("failed to open preview output: %w", err)
+	}
+	out.SetVolume(previewVolume)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	pv.mu.Lock()
+	pv.trackID = track.ID
+	pv.stop = stop
+	pv.done = done
+	pv.mu.Unlock()
+
+	go pv.play(dec, out, length, stop, done)
+	return nil
+}
+
+// Stop ends the current preview and waits for it to release its output
+func (pv *Previewer) Stop() {
+	pv.mu.Lock()
+	stop, done := pv.stop, pv.done
+	pv.stop = nil
+	pv.done = nil
+	pv.trackID = ""
+	pv.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Current returns the ID of the track being previewed, or "" if none
+func (pv *Previewer) Current() string {
+	pv.mu.Lock()
+	defer pv.mu.Unlock()
+	return pv.trackID
+}
+
+func (pv *Previewer) play(dec decoder.Decoder, out output.Output, length time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	defer dec.Close()
+	defer out.Close()
+
+	format := dec.Format()
+	channels := max(format.Channels, 1)
+	rate := format.SampleRate
+	if rate <= 0 {
+		rate = previewFormat.SampleRate
+	}
+
+	total := int(length.Seconds() * float64(rate))
+	fade := int(previewFade.Seconds() * float64(rate))
+	buffer := make([]float32, previewBuffer*channels)
+
+	for played := 0; played < total; {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		n, err := dec.Decode(buffer)
+		if err != nil {
+			if !errors.Is(err, decoder.ErrEndOfStream) {
+				logger.Debug("Preview decode failed", logger.Error(err))
+			}
+			break
+		}
+		if n == 0 {
+			continue
+		}
+		n = min(n, total-played)
+
+		samples := toPreviewFormat(buffer[:n*channels], channels, rate)
+
+		// Fade in and out to avoid clicks
+		frames := len(samples) / previewFormat.Channels
+		for i := 0; i < frames; i++ {
+			pos := played + i*n/frames
+			gain := 1.0
+			if pos < fade {
+				gain = float64(pos) / float64(fade)
+			} else if total-pos < fade {
+				gain = float64(total-pos) / float64(fade)
+			}
+			if gain < 1 {
+				for c := 0; c < previewFormat.Channels; c++ {
+					samples[i*previewFormat.Channels+c] *= float32(gain)
+				}
+			}
+		}
+
+		if _, err := out.Write(samples); err != nil {
+			logger.Debug("Preview output failed", logger.Error(err))
+			break
+		}
+		played += n
+	}
+
+	pv.mu.Lock()
+	if pv.done == done {
+		pv.trackID = ""
+		pv.stop = nil
+		pv.done = nil
+	}
+	pv.mu.Unlock()
+}
+
+// toPreviewFormat converts interleaved samples to the preview output's
+// channel layout and sample rate, using linear interpolation for rate
+// changes. Previews are short and quiet, so this favours speed over
+// fidelity.
+func toPreviewFormat(samples []float32, channels, rate int) []float32 {
+	outChannels := previewFormat.Channels
+	if channels != outChannels {
+		samples = remix(nil, samples, channels, outChannels)
+		channels = outChannels
+	}
+	frames := len(samples) / channels
+
+	outFrames := frames
+	if rate != previewFormat.SampleRate {
+		outFrames = frames * previewFormat.SampleRate / rate
+	}
+
+	result := make([]float32, outFrames*outChannels)
+	for i := 0; i < outFrames; i++ {
+		src := float64(i) * float64(rate) / float64(previewFormat.SampleRate)
+		i0 := min(int(src), frames-1)
+		i1 := min(i0+1, frames-1)
+		frac := float32(src - float64(i0))
+
+		for c := 0; c < outChannels; c++ {
+			a := samples[i0*channels+c]
+			b := samples[i1*channels+c]
+			result[i*outChannels+c] = a + (b-a)*frac
+		}
+	}
+
+	return result
+}
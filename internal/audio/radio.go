@@ -0,0 +1,205 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/network"
+)
+
+// LoadStream loads an opened network stream, such as an internet radio
+// station, for playback. The stream is buffered ahead like any other HTTP
+// source, so playback reports StateBuffering while it fills. When the
+// station sends what it is playing, the track's title and artist follow
+// along and EventTrackChanged is sent with each change. The player closes
+// the stream when another track is loaded.
+func (p *Player) LoadStream(stream *network.Stream) error {
+	if stream == nil {
+		return errors.New("stream is nil")
+	}
+
+	track, err := domain.NewTransientTrack(domain.Source{Kind: domain.SourceStream, URI: stream.URL}, stream.Name)
+	if err != nil {
+		stream.Close()
+		return err
+	}
+	station := track.GetDisplayTitle()
+	bitrate := stream.Bitrate / 1000 // Announced in bps
+	track.Album = stream.Name
+	track.Bitrate = bitrate
+
+	var src io.ReadCloser = stream
+	if stream.MetaInt > 0 {
+		src = &icyReader{
+			src:       stream,
+			interval:  stream.MetaInt,
+			remaining: stream.MetaInt,
+			onMetadata: func(fields map[string]string) {
+				p.setStreamMetadata(track, station, fields)
+			},
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.decoder != nil {
+		p.decoder.Close()
+		p.decoder = nil
+	}
+
+	buffer := newStreamBuffer(src, p.sources.http.prebufferSize(bitrate), durationToBytes(maxStreamPrebuffer, bitrate), nil)
+	dec, err := p.sources.factory.CreateStreamDecoder(stream.ContentType, buffer)
+	if err != nil {
+		buffer.Close()
+		err = fmt.Errorf("failed to create decoder: %w", err)
+		p.notifyListeners(EventError, &TrackError{Track: track, Err: err})
+		return err
+	}
+
+	p.loadDecoder(track, &bufferedStream{StreamDecoder: dec, buffer: buffer})
+	return nil
+}
+
+// StreamMetadata is what a station says it is playing, sent with
+// EventStreamMetadata. It arrives as the stream is read ahead, so it can
+// lead the audio by up to the prebuffer.
+type StreamMetadata struct {
+	Track   *domain.Track
+	Station string
+	Title   string // StreamTitle as sent, usually "Artist - Title"
+	Artist  string // Split from Title when it has that form
+	Song    string
+	URL     string // StreamUrl, which stations use for a site or cover art
+}
+
+// setStreamMetadata shows what a station is playing on its track, as long
+// as the track is still loaded, and sends it with EventStreamMetadata.
+// Between songs, stations often send an empty title; the station's name
+// stands in.
+func (p *Player) setStreamMetadata(track *domain.Track, station string, fields map[string]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.currentTrack != track {
+		return
+	}
+
+	metadata := &StreamMetadata{
+		Track:   track,
+		Station: station,
+		Title:   strings.TrimSpace(fields["StreamTitle"]),
+		URL:     strings.TrimSpace(fields["StreamUrl"]),
+	}
+	metadata.Artist, metadata.Song = splitStreamTitle(metadata.Title)
+
+	titleChanged := metadata.Artist != track.Artist || metadata.Song != track.Title
+	track.Artist, track.Title = metadata.Artist, metadata.Song
+	if track.Title == "" {
+		track.Title = station
+	}
+
+	p.notifyListeners(EventStreamMetadata, metadata)
+	if titleChanged {
+		p.notifyListeners(EventTrackChanged, track)
+	}
+
+	logger.Debug("Stream metadata changed",
+		logger.String("station", station),
+		logger.String("title", metadata.Title),
+		logger.String("url", metadata.URL))
+}
+
+// splitStreamTitle splits a title in the usual "Artist - Title" form. A
+// title without a separator is all title.
+func splitStreamTitle(title string) (artist, song string) {
+	title = strings.TrimSpace(title)
+	if artist, song, ok := strings.Cut(title, " - "); ok {
+		return strings.TrimSpace(artist), strings.TrimSpace(song)
+	}
+	return "", title
+}
+
+// icyReader strips the metadata SHOUTcast and Icecast servers interleave
+// with the audio when asked to. After every interval bytes of audio comes
+// a length byte and that many 16-byte blocks of text such as
+// "StreamTitle='Artist - Title';StreamUrl='http://...';". Servers repeat
+// the same block until something changes; onMetadata is called when it
+// does.
+type icyReader struct {
+	src        io.ReadCloser
+	interval   int
+	remaining  int // Audio bytes before the next metadata
+	last       string
+	onMetadata func(fields map[string]string)
+}
+
+func (r *icyReader) Read(p []byte) (int, error) {
+	if r.remaining == 0 {
+		if err := r.readMetadata(); err != nil {
+			return 0, err
+		}
+		r.remaining = r.interval
+	}
+
+	if len(p) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.src.Read(p)
+	r.remaining -= n
+	return n, err
+}
+
+func (r *icyReader) Close() error {
+	return r.src.Close()
+}
+
+func (r *icyReader) readMetadata() error {
+	var length [1]byte
+	if _, err := io.ReadFull(r.src, length[:]); err != nil {
+		return err
+	}
+	if length[0] == 0 {
+		return nil
+	}
+
+	block := make([]byte, int(length[0])*16)
+	if _, err := io.ReadFull(r.src, block); err != nil {
+		return err
+	}
+
+	text := string(bytes.TrimRight(block, "\x00"))
+	if text == r.last {
+		return nil
+	}
+	r.last = text
+	if fields := parseICYMetadata(text); len(fields) > 0 {
+		r.onMetadata(fields)
+	}
+	return nil
+}
+
+// parseICYMetadata splits a metadata block into its Key='value'; fields.
+// Values can hold quotes themselves, so each runs to the "';" ending it,
+// or to the last quote for the final field.
+func parseICYMetadata(text string) map[string]string {
+	fields := make(map[string]string)
+	for text != "" {
+		key, rest, ok := strings.Cut(text, "='")
+		if !ok {
+			break
+		}
+		value, next, ok := strings.Cut(rest, "';")
+		if !ok {
+			value = strings.TrimSuffix(strings.TrimRight(rest, " ;"), "'")
+		}
+		fields[strings.TrimSpace(key)] = value
+		text = next
+	}
+	return fields
+}
@@ -0,0 +1,101 @@
+package audio
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/winramp/winramp/internal/audio/decoder"
+	"github.com/winramp/winramp/internal/domain"
+)
+
+const (
+	// silenceThreshold is the block RMS level (dBFS) treated as silence
+	silenceThreshold = -50.0
+	// minSilenceDuration is the shortest quiet passage reported as a region
+	minSilenceDuration = 2 * time.Second
+	// silenceBlock is the analysis block length
+	silenceBlock = 100 * time.Millisecond
+)
+
+// DetectSilence scans a decoded stream for silent passages and returns them
+// as silence region markers for the given track. The decoder is read to the
+// end, so callers should pass a decoder dedicated to the analysis.
+func DetectSilence(dec decoder.Decoder, trackID string) ([]*domain.TrackMarker, error) {
+	format := dec.Format()
+	if format.SampleRate <= 0 || format.Channels <= 0 {
+		return nil, errors.New("invalid decoder format")
+	}
+
+	blockFrames := int(silenceBlock.Seconds() * float64(format.SampleRate))
+	if blockFrames <= 0 {
+		blockFrames = 1
+	}
+
+	buffer := make([]float32, blockFrames*format.Channels)
+	markers := make([]*domain.TrackMarker, 0)
+	framesRead := 0
+	silenceStart := -1
+
+	framesToDuration := func(frames int) time.Duration {
+		return time.Duration(float64(frames) / float64(format.SampleRate) * float64(time.Second))
+	}
+
+	closeRegion := func(end int) error {
+		if silenceStart < 0 {
+			return nil
+		}
+		start, stop := framesToDuration(silenceStart), framesToDuration(end)
+		silenceStart = -1
+		if stop-start < minSilenceDuration {
+			return nil
+		}
+		marker, err := domain.NewTrackMarker(trackID, domain.MarkerTypeSilence, start, "")
+		if err != nil {
+			return err
+		}
+		marker.End = stop
+		markers = append(markers, marker)
+		return nil
+	}
+
+	for {
+		n, err := dec.Decode(buffer)
+		if n > 0 {
+			sum := 0.0
+			samples := buffer[:n*format.Channels]
+			for _, s := range samples {
+				sum += float64(s) * float64(s)
+			}
+
+			level := math.Inf(-1)
+			if meanSquare := sum / float64(len(samples)); meanSquare > 0 {
+				level = 10 * math.Log10(meanSquare)
+			}
+
+			if level < silenceThreshold {
+				if silenceStart < 0 {
+					silenceStart = framesRead
+				}
+			} else if cerr := closeRegion(framesRead); cerr != nil {
+				return nil, cerr
+			}
+			framesRead += n
+		}
+		if err != nil {
+			if errors.Is(err, decoder.ErrEndOfStream) {
+				break
+			}
+			return nil, err
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	if err := closeRegion(framesRead); err != nil {
+		return nil, err
+	}
+
+	return markers, nil
+}
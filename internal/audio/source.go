@@ -0,0 +1,259 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/archive"
+	"github.com/winramp/winramp/internal/audio/decoder"
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// ResolvedSource is an opened track source. Seekable sources such as files
+// come back as a reader for the decoder factory to pick a decoder for by
+// name; live sources come back as a decoder that is already streaming.
+type ResolvedSource struct {
+	Name   string // File name or URL path, used to pick a decoder for Reader
+	Reader io.ReadSeeker
+	Stream decoder.StreamDecoder
+}
+
+// SourceOpener opens sources of one kind
+type SourceOpener interface {
+	Open(ctx context.Context, source domain.Source) (*ResolvedSource, error)
+}
+
+// CredentialLookup returns the username and password stored under a
+// credentials reference
+type CredentialLookup func(ref string) (username, password string, err error)
+
+// SourceResolver opens track sources with the opener registered for their
+// kind. Files, zip archive entries and HTTP streams are supported out of
+// the box; CD and remote server sources are registered by their
+// integrations.
+type SourceResolver struct {
+	openers map[domain.SourceKind]SourceOpener
+	factory *decoder.DecoderFactory
+	http    *httpOpener
+	mu      sync.RWMutex
+}
+
+// NewSourceResolver creates a resolver for files, archive entries and HTTP
+// streams. lookup
+// provides credentials for sources that reference them and may be nil.
+func NewSourceResolver(lookup CredentialLookup) *SourceResolver {
+	r := &SourceResolver{
+		openers: make(map[domain.SourceKind]SourceOpener),
+		factory: decoder.GetDecoderFactory(),
+	}
+	r.http = newHTTPOpener(r.factory, lookup)
+
+	r.Register(domain.SourceFile, fileOpener{})
+	r.Register(domain.SourceArchive, archiveOpener{})
+	r.Register(domain.SourceStream, r.http)
+
+	return r
+}
+
+// Register sets the opener for a kind of source, replacing any before it
+func (r *SourceResolver) Register(kind domain.SourceKind, opener SourceOpener) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.openers[kind] = opener
+}
+
+// Supports reports whether sources of a kind can be opened
+func (r *SourceResolver) Supports(kind domain.SourceKind) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.openers[kind]
+	return ok
+}
+
+// SetStreamPrebuffer sets how much of an HTTP stream is buffered before it
+// plays
+func (r *SourceResolver) SetStreamPrebuffer(settings PrebufferSettings) {
+	r.http.setPrebuffer(settings)
+}
+
+// Resolve opens a source with the opener for its kind
+func (r *SourceResolver) Resolve(ctx context.Context, source domain.Source) (*ResolvedSource, error) {
+	if err := source.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	opener, ok := r.openers[source.Kind]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", domain.ErrUnsupportedSource, source.Kind)
+	}
+
+	return opener.Open(ctx, source)
+}
+
+// OpenDecoder opens a track's source and returns a decoder for its audio
+func (r *SourceResolver) OpenDecoder(ctx context.Context, track *domain.Track) (decoder.Decoder, error) {
+	resolved, err := r.Resolve(ctx, track.GetSource())
+	if err != nil {
+		return nil, err
+	}
+	if resolved.Stream != nil {
+		return resolved.Stream, nil
+	}
+
+	dec, err := r.factory.CreateDecoder(resolved.Name, resolved.Reader)
+	if err != nil {
+		if closer, ok := resolved.Reader.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, err
+	}
+
+	return dec, nil
+}
+
+// fileOpener opens local and network-share files
+type fileOpener struct{}
+
+func (fileOpener) Open(ctx context.Context, source domain.Source) (*ResolvedSource, error) {
+	file, err := os.Open(source.URI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	return &ResolvedSource{
+		Name:   source.URI,
+		Reader: file,
+	}, nil
+}
+
+// archiveOpener opens files inside zip archives
+type archiveOpener struct{}
+
+func (archiveOpener) Open(ctx context.Context, source domain.Source) (*ResolvedSource, error) {
+	archivePath, entry, err := source.ArchiveEntry()
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := archive.Open(archivePath, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResolvedSource{
+		Name:   entry,
+		Reader: reader,
+	}, nil
+}
+
+// httpOpener opens HTTP(S) streams and decodes them as they arrive,
+// reading ahead into a prebuffer
+type httpOpener struct {
+	client  *http.Client
+	factory *decoder.DecoderFactory
+	lookup  CredentialLookup
+
+	mu        sync.Mutex
+	prebuffer PrebufferSettings
+	grown     map[string]int // Prebuffers enlarged by underruns, by URL
+}
+
+func newHTTPOpener(factory *decoder.DecoderFactory, lookup CredentialLookup) *httpOpener {
+	return &httpOpener{
+		// No overall timeout: the body is read for as long as the stream plays
+		client: &http.Client{
+			Transport: &http.Transport{
+				ResponseHeaderTimeout: 15 * time.Second,
+				IdleConnTimeout:       90 * time.Second,
+			},
+		},
+		factory:   factory,
+		lookup:    lookup,
+		prebuffer: PrebufferSettings{Duration: DefaultStreamPrebuffer},
+		grown:     make(map[string]int),
+	}
+}
+
+func (o *httpOpener) setPrebuffer(settings PrebufferSettings) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.prebuffer = settings
+	o.grown = make(map[string]int)
+}
+
+// newBuffer wraps a stream's body in a prebuffer. A station that needed a
+// larger prebuffer before starts with it when reconnected.
+func (o *httpOpener) newBuffer(uri string, resp *http.Response) *streamBuffer {
+	bitrate, _ := strconv.Atoi(resp.Header.Get("icy-br"))
+
+	o.mu.Lock()
+	target := o.prebuffer.size(bitrate)
+	if grown := o.grown[uri]; grown > target {
+		target = grown
+	}
+	o.mu.Unlock()
+
+	limit := max(target, durationToBytes(maxStreamPrebuffer, bitrate))
+	return newStreamBuffer(resp.Body, target, limit, func(target int) {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.grown[uri] = target
+	})
+}
+
+// prebufferSize returns the prebuffer in bytes for a stream at a bitrate
+// in kbps
+func (o *httpOpener) prebufferSize(bitrate int) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.prebuffer.size(bitrate)
+}
+
+func (o *httpOpener) Open(ctx context.Context, source domain.Source) (*ResolvedSource, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidSource, err)
+	}
+	req.Header.Set("User-Agent", "WinRamp/1.0")
+	req.Header.Set("Accept", "audio/*")
+
+	if source.CredentialsRef != "" {
+		if o.lookup == nil {
+			return nil, fmt.Errorf("%w: no credential store for %s", domain.ErrInvalidSource, source.CredentialsRef)
+		}
+		username, password, err := o.lookup(source.CredentialsRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up credentials: %w", err)
+		}
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("stream returned status %d", resp.StatusCode)
+	}
+
+	buffer := o.newBuffer(source.URI, resp)
+	stream, err := o.factory.CreateStreamDecoder(resp.Header.Get("Content-Type"), buffer)
+	if err != nil {
+		buffer.Close()
+		return nil, err
+	}
+
+	return &ResolvedSource{
+		Name:   source.Name(),
+		Stream: &bufferedStream{StreamDecoder: stream, buffer: buffer},
+	}, nil
+}
@@ -0,0 +1,110 @@
+package audio
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/archive"
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestSourceResolverFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tone.wav")
+	require.NoError(t, os.WriteFile(path, wavBytes(44100), 0o644))
+
+	r := NewSourceResolver(nil)
+	assert.True(t, r.Supports(domain.SourceFile))
+	assert.True(t, r.Supports(domain.SourceStream))
+	assert.False(t, r.Supports(domain.SourceCD))
+
+	dec, err := r.OpenDecoder(context.Background(), &domain.Track{FilePath: path})
+	require.NoError(t, err)
+	defer dec.Close()
+	assert.Equal(t, 44100, dec.Format().SampleRate)
+	assert.EqualValues(t, 44100, dec.SampleCount())
+
+	_, err = r.OpenDecoder(context.Background(), &domain.Track{FilePath: filepath.Join(t.TempDir(), "gone.wav")})
+	assert.Error(t, err)
+}
+
+func TestSourceResolverArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mix.zip")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	w := zip.NewWriter(file)
+	entry, err := w.Create("Mix/01 Tone.wav")
+	require.NoError(t, err)
+	_, err = entry.Write(wavBytes(22050))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, file.Close())
+
+	r := NewSourceResolver(nil)
+	assert.True(t, r.Supports(domain.SourceArchive))
+
+	track, err := domain.NewArchiveTrack(path, "Mix/01 Tone.wav")
+	require.NoError(t, err)
+	dec, err := r.OpenDecoder(context.Background(), track)
+	require.NoError(t, err)
+	assert.EqualValues(t, 22050, dec.SampleCount())
+	require.NoError(t, dec.Close())
+
+	_, err = r.Resolve(context.Background(), domain.ArchiveSource(path, "Mix/02 Missing.wav"))
+	assert.ErrorIs(t, err, archive.ErrEntryNotFound)
+}
+
+func TestSourceResolverKinds(t *testing.T) {
+	r := NewSourceResolver(nil)
+	cd := &domain.Track{FilePath: "cdda://D:/3"}
+
+	_, err := r.OpenDecoder(context.Background(), cd)
+	assert.ErrorIs(t, err, domain.ErrUnsupportedSource)
+
+	_, err = r.Resolve(context.Background(), domain.Source{Kind: domain.SourceFile})
+	assert.ErrorIs(t, err, domain.ErrInvalidSource)
+
+	// Integrations register openers for the kinds they add
+	opener := &fakeOpener{data: wavBytes(4410)}
+	r.Register(domain.SourceCD, opener)
+	dec, err := r.OpenDecoder(context.Background(), cd)
+	require.NoError(t, err)
+	defer dec.Close()
+	assert.EqualValues(t, 4410, dec.SampleCount())
+	assert.Equal(t, "cdda://D:/3", opener.opened)
+}
+
+type fakeOpener struct {
+	data   []byte
+	opened string
+}
+
+func (o *fakeOpener) Open(ctx context.Context, source domain.Source) (*ResolvedSource, error) {
+	o.opened = source.URI
+	return &ResolvedSource{Name: "track.wav", Reader: bytes.NewReader(o.data)}, nil
+}
+
+// wavBytes returns a silent 16-bit stereo 44.1 kHz WAV file
+func wavBytes(frames int) []byte {
+	data := make([]byte, frames*4)
+	b := []byte("RIFF")
+	b = binary.LittleEndian.AppendUint32(b, uint32(36+len(data)))
+	b = append(b, "WAVEfmt "...)
+	b = binary.LittleEndian.AppendUint32(b, 16)
+	b = binary.LittleEndian.AppendUint16(b, 1) // PCM
+	b = binary.LittleEndian.AppendUint16(b, 2)
+	b = binary.LittleEndian.AppendUint32(b, 44100)
+	b = binary.LittleEndian.AppendUint32(b, 44100*4)
+	b = binary.LittleEndian.AppendUint16(b, 4)
+	b = binary.LittleEndian.AppendUint16(b, 16)
+	b = append(b, "data"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	return append(b, data...)
+}
@@ -0,0 +1,57 @@
+package audio
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/winramp/winramp/internal/audio/dsp"
+	"github.com/winramp/winramp/internal/audio/output"
+)
+
+// Ways playback speed can be changed
+const (
+	// SpeedModeResample plays faster or slower by resampling, which shifts
+	// the pitch with the tempo as a tape would
+	SpeedModeResample = "resample"
+	// SpeedModeStretch changes the tempo and keeps the pitch, for speech
+	SpeedModeStretch = "time-stretch"
+)
+
+// SpeedModes are the ways playback speed can be changed
+var SpeedModes = []string{SpeedModeResample, SpeedModeStretch}
+
+// SetSpeedMode sets how playback speed is changed, one of SpeedModes
+func (p *Player) SetSpeedMode(mode string) error {
+	if !slices.Contains(SpeedModes, mode) {
+		return fmt.Errorf("invalid speed mode %q", mode)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.speedMode = mode
+	if mode != SpeedModeStretch {
+		p.stretcher = nil
+	}
+	return nil
+}
+
+// GetSpeed returns the playback speed and how it is changed
+func (p *Player) GetSpeed() (float64, string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.speed, p.speedMode
+}
+
+// stretcherFor returns the time-stretcher for output in a format, creating
+// one when the speed needs it. Once running it carries on at normal speed
+// rather than drop the audio it holds. Must be called with p.mu held.
+func (p *Player) stretcherFor(format output.Format, speed float64) *dsp.TimeStretch {
+	if p.stretcher != nil && p.stretcher.Matches(format.SampleRate, format.Channels) {
+		return p.stretcher
+	}
+	p.stretcher = nil
+	if speed != 1.0 && format.SampleRate > 0 && format.Channels > 0 {
+		p.stretcher = dsp.NewTimeStretch(format.SampleRate, format.Channels)
+	}
+	return p.stretcher
+}
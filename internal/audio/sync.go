@@ -0,0 +1,31 @@
+package audio
+
+import "time"
+
+// maxSyncOffset bounds the extra latency, well past the worst Bluetooth
+// devices
+const maxSyncOffset = 2 * time.Second
+
+// SetSyncOffset sets the latency past the output buffer before audio is
+// heard, such as a Bluetooth link adds. Lyrics and visualization run this
+// much behind the playback position to match what is heard.
+func (p *Player) SetSyncOffset(offset time.Duration) {
+	offset = max(min(offset, maxSyncOffset), -maxSyncOffset)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.syncOffset = offset
+}
+
+// SyncOffset returns how far what is heard lags the playback position: the
+// output buffer plus the offset set with SetSyncOffset
+func (p *Player) SyncOffset() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return max(p.outputFormat.Latency+p.syncOffset, 0)
+}
+
+// HeardPosition returns the position of the audio reaching the listener
+func (p *Player) HeardPosition() time.Duration {
+	return max(p.GetPosition()-p.SyncOffset(), 0)
+}
@@ -0,0 +1,181 @@
+package audio
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// Transition is how one track leads into the next
+type Transition string
+
+const (
+	TransitionGapless   Transition = "gapless"   // Straight on, sample for sample
+	TransitionCrossfade Transition = "crossfade" // Overlapping the end of one with the start of the next
+	TransitionGap       Transition = "gap"       // A short silence between them
+)
+
+// DefaultTransitionGap is the silence TransitionGap leaves
+const DefaultTransitionGap = 2 * time.Second
+
+// maxTransitionGap bounds the silence between tracks
+const maxTransitionGap = 30 * time.Second
+
+// ParseTransition checks a transition name
+func ParseTransition(name string) (Transition, error) {
+	switch t := Transition(name); t {
+	case TransitionGapless, TransitionCrossfade, TransitionGap:
+		return t, nil
+	}
+	return "", fmt.Errorf("invalid transition %q", name)
+}
+
+// TransitionRule sets the transition between two tracks that both meet
+// its rules, such as gapless for classical movements or a short gap on
+// pop compilations
+type TransitionRule struct {
+	Name       string            `json:"name"`
+	Rules      domain.SmartRules `json:"rules"` // Conditions only; order and limit are ignored
+	Transition Transition        `json:"transition"`
+	Gap        time.Duration     `json:"gap"` // Silence left by TransitionGap; zero uses the policy's
+}
+
+// TransitionPolicy picks the transition between two tracks by whether they
+// come from the same album, so albums play as mastered while unrelated
+// tracks blend or get room to breathe. Rules take precedence, the first
+// both tracks meet deciding.
+type TransitionPolicy struct {
+	SameAlbum       Transition
+	DifferentAlbum  Transition
+	Gap             time.Duration    // Silence left by TransitionGap
+	CrossfadeAlbums []string         // Album keys that crossfade even within the album
+	Rules           []TransitionRule // Checked with SmartRules.Validate
+}
+
+// DefaultTransitionPolicy plays albums gaplessly and crossfades between
+// anything else
+func DefaultTransitionPolicy() TransitionPolicy {
+	return TransitionPolicy{
+		SameAlbum:      TransitionGapless,
+		DifferentAlbum: TransitionCrossfade,
+		Gap:            DefaultTransitionGap,
+	}
+}
+
+// TransitionChoice is the transition a policy picks between two tracks
+// and why
+type TransitionChoice struct {
+	Transition Transition    `json:"transition"`
+	Gap        time.Duration `json:"gap"`  // Silence before the next track; zero unless Transition is TransitionGap
+	Rule       string        `json:"rule"` // Name of the rule that decided, if one did
+}
+
+// Choose returns the transition from current to next
+func (tp TransitionPolicy) Choose(current, next *domain.Track) Transition {
+	return tp.Explain(current, next).Transition
+}
+
+// Explain returns the transition from current to next with the silence a
+// gap transition leaves and the rule behind it. An album chosen to
+// crossfade does so whatever the rules say, as it was picked out by hand.
+func (tp TransitionPolicy) Explain(current, next *domain.Track) TransitionChoice {
+	if current == nil || next == nil {
+		return TransitionChoice{Transition: TransitionGapless}
+	}
+	if current.IsSameAlbum(next) && slices.Contains(tp.CrossfadeAlbums, current.AlbumKey()) {
+		return TransitionChoice{Transition: TransitionCrossfade}
+	}
+
+	choice := TransitionChoice{Transition: orGapless(tp.SameAlbum), Gap: tp.Gap}
+	if !current.IsSameAlbum(next) {
+		choice.Transition = orGapless(tp.DifferentAlbum)
+	}
+	now := time.Now()
+	for _, rule := range tp.Rules {
+		if rule.Rules.Matches(current, now) && rule.Rules.Matches(next, now) {
+			choice = TransitionChoice{Transition: orGapless(rule.Transition), Gap: tp.Gap, Rule: rule.Name}
+			if rule.Gap > 0 {
+				choice.Gap = min(rule.Gap, maxTransitionGap)
+			}
+			break
+		}
+	}
+	if choice.Transition != TransitionGap {
+		choice.Gap = 0
+	}
+	return choice
+}
+
+func orGapless(t Transition) Transition {
+	if t == "" {
+		return TransitionGapless
+	}
+	return t
+}
+
+// SetTransitionPolicy sets how tracks lead into each other. The transition
+// to a track already queued is chosen again.
+func (p *Player) SetTransitionPolicy(policy TransitionPolicy) {
+	policy.Gap = max(min(policy.Gap, maxTransitionGap), 0)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.transitions = policy
+	choice := policy.Explain(p.currentTrack, p.nextTrack)
+	p.transition, p.transitionGap = choice.Transition, choice.Gap
+}
+
+// NextTransition returns the transition into the queued next track
+func (p *Player) NextTransition() Transition {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.transition
+}
+
+// playGap writes the silence a gap transition leaves before the track
+// starts. It returns false if playback stopped meanwhile; when paused, the
+// rest of the gap is kept for when playback resumes.
+func (p *Player) playGap() bool {
+	p.mu.Lock()
+	gap := p.pendingGap
+	p.pendingGap = 0
+	format := p.outputFormat
+	p.mu.Unlock()
+
+	if gap <= 0 || format.SampleRate <= 0 {
+		return true
+	}
+
+	chunk := make([]float32, format.SampleRate/10*format.Channels)
+	for written := time.Duration(0); written < gap; written += 100 * time.Millisecond {
+		select {
+		case <-p.stop:
+			return false
+		default:
+		}
+		p.mu.Lock()
+		playing := p.state == StatePlaying
+		if !playing && p.state == StatePaused {
+			p.pendingGap = gap - written
+		}
+		p.mu.Unlock()
+		if !playing {
+			return false
+		}
+
+		p.mu.RLock()
+		out := p.output
+		p.mu.RUnlock()
+		if out == nil {
+			return false
+		}
+		started := time.Now()
+		if _, err := out.Write(chunk); err != nil {
+			return false
+		}
+		p.noteWrite(started)
+	}
+	return true
+}
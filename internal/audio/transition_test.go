@@ -0,0 +1,69 @@
+package audio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestTransitionPolicyRules(t *testing.T) {
+	classical := domain.SmartRules{Conditions: []domain.RuleCondition{
+		{Field: "genre", Operator: domain.OperatorContains, Value: "classical"},
+	}}
+	compilation := domain.SmartRules{Conditions: []domain.RuleCondition{
+		{Field: "album_artist", Operator: domain.OperatorEquals, Value: "Various Artists"},
+	}}
+	policy := TransitionPolicy{
+		SameAlbum:       TransitionGapless,
+		DifferentAlbum:  TransitionCrossfade,
+		Gap:             DefaultTransitionGap,
+		CrossfadeAlbums: []string{(&domain.Track{Album: "Requiem", AlbumArtist: "Mozart"}).AlbumKey()},
+		Rules: []TransitionRule{
+			{Name: "Classical", Rules: classical, Transition: TransitionGapless},
+			{Name: "Compilations", Rules: compilation, Transition: TransitionGap, Gap: time.Second},
+		},
+	}
+
+	symphony := &domain.Track{Album: "Symphony No. 9", AlbumArtist: "Beethoven", Genre: "Classical"}
+	requiem := &domain.Track{Album: "Requiem", AlbumArtist: "Mozart", Genre: "Classical"}
+	hits := &domain.Track{Album: "Now 42", AlbumArtist: "Various Artists", Genre: "Pop"}
+	morehits := &domain.Track{Album: "Now 43", AlbumArtist: "Various Artists", Genre: "Pop"}
+	rock := &domain.Track{Album: "Rumours", AlbumArtist: "Fleetwood Mac", Genre: "Rock"}
+
+	tests := []struct {
+		name    string
+		current *domain.Track
+		next    *domain.Track
+		want    TransitionChoice
+	}{
+		{"rule across albums", symphony, requiem, TransitionChoice{Transition: TransitionGapless, Rule: "Classical"}},
+		{"rule gap", hits, morehits, TransitionChoice{Transition: TransitionGap, Gap: time.Second, Rule: "Compilations"}},
+		{"both tracks must match", hits, rock, TransitionChoice{Transition: TransitionCrossfade}},
+		{"crossfade album wins", requiem, requiem, TransitionChoice{Transition: TransitionCrossfade}},
+		{"no next track", rock, nil, TransitionChoice{Transition: TransitionGapless}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.Explain(tt.current, tt.next))
+		})
+	}
+
+	// A rule gap falls back to the policy's and is dropped for other transitions
+	policy.Rules[1].Gap = 0
+	assert.Equal(t, DefaultTransitionGap, policy.Explain(hits, morehits).Gap)
+	policy.Rules[1].Transition = TransitionGapless
+	assert.Zero(t, policy.Explain(hits, morehits).Gap)
+}
+
+func TestDefaultTransitionPolicy(t *testing.T) {
+	policy := DefaultTransitionPolicy()
+	first := &domain.Track{Album: "Rumours", AlbumArtist: "Fleetwood Mac"}
+	second := &domain.Track{Album: "Rumours", AlbumArtist: "Fleetwood Mac"}
+	other := &domain.Track{Album: "Tusk", AlbumArtist: "Fleetwood Mac"}
+
+	assert.Equal(t, TransitionGapless, policy.Choose(first, second))
+	assert.Equal(t, TransitionCrossfade, policy.Choose(second, other))
+}
@@ -0,0 +1,55 @@
+package audio
+
+import (
+	"math"
+)
+
+const (
+	// volumeRangeDB is the span of the volume control below its maximum.
+	// The bottom of the range is silence rather than the floor itself.
+	volumeRangeDB = 50.0
+
+	// MinMaxVolumeDB and MaxMaxVolumeDB bound the configurable maximum
+	// volume. The maximum never exceeds full scale so it cannot clip.
+	MinMaxVolumeDB = -30.0
+	MaxMaxVolumeDB = 0.0
+
+	// DefaultVolumeStepDB is the volume change per hotkey press
+	DefaultVolumeStepDB = 1.0
+)
+
+// VolumeToGain maps a volume position (0.0 to 1.0) to a linear gain. The
+// position is spread evenly over decibels, so equal movements of the
+// control sound like equal changes in loudness, instead of the top half of
+// a linear control doing almost nothing and the bottom falling off a cliff.
+// Position 1.0 plays at maxDB.
+func VolumeToGain(position, maxDB float64) float64 {
+	if position <= 0 {
+		return 0
+	}
+	return math.Pow(10, VolumeToDB(position, maxDB)/20)
+}
+
+// VolumeToDB returns the attenuation in dB for a volume position, or
+// negative infinity for silence
+func VolumeToDB(position, maxDB float64) float64 {
+	if position <= 0 {
+		return math.Inf(-1)
+	}
+	if position > 1 {
+		position = 1
+	}
+	return maxDB - volumeRangeDB*(1-position)
+}
+
+// DBToVolume returns the volume position that plays at db. Levels below the
+// range map to silence.
+func DBToVolume(db, maxDB float64) float64 {
+	position := 1 - (maxDB-db)/volumeRangeDB
+	return math.Max(0, math.Min(1, position))
+}
+
+// clampMaxVolumeDB keeps a configured maximum volume within bounds
+func clampMaxVolumeDB(db float64) float64 {
+	return math.Max(MinMaxVolumeDB, math.Min(MaxMaxVolumeDB, db))
+}
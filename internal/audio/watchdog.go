@@ -0,0 +1,260 @@
+package audio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/audio/output"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+const (
+	// DefaultBufferFrames is the output buffer size, in frames, until set
+	DefaultBufferFrames = 2048
+
+	// maxBufferFrames caps how far the watchdog grows the output buffer
+	maxBufferFrames = 16384
+
+	// bufferGrowth is the factor the output buffer grows by each time
+	bufferGrowth = 1.5
+
+	// stallTimeout is how long playback may go without writing to the
+	// output before it counts as stalled
+	stallTimeout = 2 * time.Second
+
+	watchdogInterval = 250 * time.Millisecond
+
+	// troubleWindow and troubleThreshold: this many underruns or stalls
+	// within the window grow the buffer and warn the user
+	troubleWindow    = 5 * time.Minute
+	troubleThreshold = 3
+)
+
+// OutputWarning is sent with EventOutputWarning after repeated underruns or
+// stalls. The output buffer has been enlarged to BufferFrames.
+type OutputWarning struct {
+	Underruns    int
+	Stalls       int
+	BufferFrames int
+	Latency      time.Duration
+	Device       string
+}
+
+// watchdog watches the output for underruns, where writes fall so far
+// behind that the device runs out of audio, and stalls, where writes stop
+// altogether while playing
+type watchdog struct {
+	mu        sync.Mutex
+	lastWrite time.Time   // End of the last write, zero outside playback
+	stalled   bool        // The current stall has been reported
+	recent    []time.Time // Underruns and stalls within troubleWindow
+	underruns int         // Totals since the last warning
+	stalls    int
+}
+
+// start begins timing writes for a run of playback
+func (w *watchdog) start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastWrite = time.Now()
+	w.stalled = false
+}
+
+// finish stops timing writes, as when playback pauses or a stream buffers
+func (w *watchdog) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastWrite = time.Time{}
+}
+
+// wrote records a finished write begun at started. It reports an underrun
+// when the gap since the previous write was longer than the output buffer,
+// which must then have run dry.
+func (w *watchdog) wrote(started time.Time, latency time.Duration) (gap time.Duration, underrun bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	previous := w.lastWrite
+	w.lastWrite = time.Now()
+	w.stalled = false
+	if previous.IsZero() || latency <= 0 {
+		return 0, false
+	}
+
+	gap = started.Sub(previous)
+	if gap <= latency {
+		return gap, false
+	}
+	w.underruns++
+	return gap, true
+}
+
+// checkStall reports a stall once per stretch without writes
+func (w *watchdog) checkStall(now time.Time) (time.Duration, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.lastWrite.IsZero() || w.stalled {
+		return 0, false
+	}
+	since := now.Sub(w.lastWrite)
+	if since < stallTimeout {
+		return 0, false
+	}
+	w.stalled = true
+	w.stalls++
+	return since, true
+}
+
+// trouble records an underrun or stall, returning whether enough have
+// happened recently to act on. Acting resets the count.
+func (w *watchdog) trouble(now time.Time) (underruns, stalls int, repeated bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	kept := w.recent[:0]
+	for _, at := range w.recent {
+		if now.Sub(at) < troubleWindow {
+			kept = append(kept, at)
+		}
+	}
+	w.recent = append(kept, now)
+	if len(w.recent) < troubleThreshold {
+		return 0, 0, false
+	}
+
+	underruns, stalls = w.underruns, w.stalls
+	w.recent = nil
+	w.underruns, w.stalls = 0, 0
+	return underruns, stalls, true
+}
+
+// watchForStalls checks for stalls until done is closed
+func (p *Player) watchForStalls(done <-chan struct{}) {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			if p.GetState() != StatePlaying {
+				continue
+			}
+			if since, stalled := p.watchdog.checkStall(now); stalled {
+				p.reportOutputTrouble("stall", since)
+			}
+		}
+	}
+}
+
+// noteWrite times a write to the output that began at started
+func (p *Player) noteWrite(started time.Time) {
+	p.mu.RLock()
+	latency := p.outputFormat.Latency
+	p.mu.RUnlock()
+
+	if gap, underrun := p.watchdog.wrote(started, latency); underrun {
+		p.reportOutputTrouble("underrun", gap)
+	}
+}
+
+// reportOutputTrouble logs an underrun or stall with the output's setup.
+// After repeated trouble the output buffer grows and EventOutputWarning
+// is sent.
+func (p *Player) reportOutputTrouble(kind string, gap time.Duration) {
+	p.mu.RLock()
+	format := p.outputFormat
+	frames := p.bufferFrames
+	device := outputDeviceName(p.output)
+	p.mu.RUnlock()
+
+	logger.Warn("Audio output "+kind,
+		logger.Duration("gap", gap),
+		logger.Int("buffer_frames", frames),
+		logger.Duration("latency", format.Latency),
+		logger.String("device", device),
+		logger.Int("sample_rate", format.SampleRate),
+		logger.Int("channels", format.Channels))
+
+	underruns, stalls, repeated := p.watchdog.trouble(time.Now())
+	if !repeated {
+		return
+	}
+
+	p.mu.Lock()
+	frames = p.growOutputBuffer()
+	latency := p.outputFormat.Latency
+	p.mu.Unlock()
+
+	p.notifyListeners(EventOutputWarning, &OutputWarning{
+		Underruns:    underruns,
+		Stalls:       stalls,
+		BufferFrames: frames,
+		Latency:      latency,
+		Device:       device,
+	})
+}
+
+// growOutputBuffer enlarges the output buffer and reopens the output with
+// it, returning the new size in frames. Must be called with p.mu held.
+func (p *Player) growOutputBuffer() int {
+	if p.bufferFrames >= maxBufferFrames {
+		return p.bufferFrames
+	}
+	p.bufferFrames = min(int(float64(p.bufferFrames)*bufferGrowth), maxBufferFrames)
+	logger.Info("Enlarged audio output buffer", logger.Int("buffer_frames", p.bufferFrames))
+
+	p.reopenOutputBuffer()
+	return p.bufferFrames
+}
+
+// reopenOutputBuffer reopens the output with the current buffer size. Must
+// be called with p.mu held.
+func (p *Player) reopenOutputBuffer() {
+	if p.output == nil {
+		return
+	}
+	format := p.outputFormat
+	format.Latency = framesToLatency(p.bufferFrames, format.SampleRate)
+	if format.Latency == p.outputFormat.Latency {
+		return
+	}
+
+	device := p.output.GetDevice()
+	p.output.Close()
+	if err := p.openOutputFormat(device, format); err != nil {
+		logger.ErrorLog("Failed to reopen audio output", logger.Error(err))
+	}
+}
+
+// SetBufferFrames sets the output buffer size in frames. Larger buffers
+// ride out hiccups at the cost of latency.
+func (p *Player) SetBufferFrames(frames int) {
+	if frames <= 0 {
+		frames = DefaultBufferFrames
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bufferFrames = min(frames, maxBufferFrames)
+	p.reopenOutputBuffer()
+}
+
+func framesToLatency(frames, sampleRate int) time.Duration {
+	if sampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(frames) * time.Second / time.Duration(sampleRate)
+}
+
+func outputDeviceName(out output.Output) string {
+	if out == nil {
+		return ""
+	}
+	if device := out.GetDevice(); device != nil {
+		return device.Name
+	}
+	return ""
+}
@@ -40,6 +40,11 @@ type AppConfig struct {
 	CheckForUpdates bool   `mapstructure:"check_for_updates"`
 	Language        string `mapstructure:"language"`
 	Theme           string `mapstructure:"theme"`
+	FirstRunComplete bool  `mapstructure:"first_run_complete"`
+	ResumePlayback  bool   `mapstructure:"resume_playback"` // Start playing on launch if the last run exited playing
+	FamilyMode      bool   `mapstructure:"family_mode"`     // Keep explicit tracks out of shuffle and party mode
+	ShuffleLive     bool   `mapstructure:"shuffle_live"`    // Shuffle plays live recordings
+	SuggestLive     bool   `mapstructure:"suggest_live"`    // Similar tracks and the auto-DJ may be live recordings
 }
 
 type AudioConfig struct {
@@ -49,15 +54,65 @@ type AudioConfig struct {
 	BufferSize        int           `mapstructure:"buffer_size"`
 	SampleRate        int           `mapstructure:"sample_rate"`
 	BitDepth          int           `mapstructure:"bit_depth"`
-	Volume            float64       `mapstructure:"volume"`
+	Volume            float64       `mapstructure:"volume"`         // Last volume, restored on startup
+	MaxVolumeDB       float64       `mapstructure:"max_volume_db"`  // Level at the top of the volume control
+	VolumeStepDB      float64       `mapstructure:"volume_step_db"` // Volume change per hotkey press
 	CrossfadeDuration time.Duration `mapstructure:"crossfade_duration"`
+	CrossfadeCurve    string        `mapstructure:"crossfade_curve"` // equal_power, linear, logarithmic
 	ReplayGain        bool          `mapstructure:"replay_gain"`
 	ReplayGainMode    string        `mapstructure:"replay_gain_mode"` // track, album
+	ReplayGainScan    bool          `mapstructure:"replay_gain_scan"`       // Measure gain of tracks new scans find
+	ReplayGainWriteTags bool        `mapstructure:"replay_gain_write_tags"` // Also write measured gain into files
+	VolumeLeveling    bool          `mapstructure:"volume_leveling"`  // Estimate gain for untagged tracks
 	PreAmp            float64       `mapstructure:"preamp"`
 	Equalizer         EqualizerConfig `mapstructure:"equalizer"`
+	Effects           EffectsConfig `mapstructure:"effects"`
 	GaplessPlayback   bool          `mapstructure:"gapless_playback"`
+	GaplessHints      bool          `mapstructure:"gapless_hints"`          // Warn when album tracks lack gapless info
+	HintedAlbums      []string      `mapstructure:"gapless_hinted_albums"`  // Albums already warned about
+	CrossfadeAlbums   []string      `mapstructure:"crossfade_albums"`       // Albums that crossfade instead of gapless
+	Transitions       TransitionConfig `mapstructure:"transitions"`
 	FadeOnPause       bool          `mapstructure:"fade_on_pause"`
+	SpeedMode         string        `mapstructure:"speed_mode"` // resample, time-stretch
 	FadeDuration      time.Duration `mapstructure:"fade_duration"`
+	PauseOnDeviceLost bool          `mapstructure:"pause_on_device_lost"` // Pause when the output device is unplugged
+	TrackEndingNotice time.Duration `mapstructure:"track_ending_notice"` // When the UI is told a track is about to end
+	SyncOffset        time.Duration `mapstructure:"sync_offset"`          // Extra latency lyrics and visualization allow for
+	DeviceLatency     map[string]time.Duration `mapstructure:"device_latency"` // Measured extra latency by device ID
+	Idle              IdleConfig    `mapstructure:"idle"`
+}
+
+// TransitionConfig chooses how tracks lead into each other: gapless,
+// crossfade or gap
+type TransitionConfig struct {
+	SameAlbum      string        `mapstructure:"same_album"`
+	DifferentAlbum string        `mapstructure:"different_album"`
+	Gap            time.Duration `mapstructure:"gap"` // Silence left by the gap transition
+	Rules          []TransitionRuleConfig `mapstructure:"rules"` // Override the album transitions for tracks they match
+}
+
+// TransitionRuleConfig sets the transition between tracks that both meet
+// its conditions, which are written as smart playlist conditions
+type TransitionRuleConfig struct {
+	Name       string                `mapstructure:"name" json:"name"`
+	Conditions []RuleConditionConfig `mapstructure:"conditions" json:"conditions"`
+	Transition string                `mapstructure:"transition" json:"transition"` // gapless, crossfade or gap
+	GapSeconds float64               `mapstructure:"gap_seconds" json:"gapSeconds"` // 0 uses the gap setting
+}
+
+// RuleConditionConfig is one smart playlist condition, as stored in the
+// config
+type RuleConditionConfig struct {
+	Field    string      `mapstructure:"field" json:"field"`
+	Operator string      `mapstructure:"operator" json:"operator"`
+	Value    interface{} `mapstructure:"value" json:"value"`
+	AndOr    string      `mapstructure:"and_or" json:"and_or"`
+}
+
+type IdleConfig struct {
+	PauseOnLock    bool          `mapstructure:"pause_on_lock"`
+	ResumeOnUnlock bool          `mapstructure:"resume_on_unlock"` // Only resumes playback the lock paused
+	StopAfter      time.Duration `mapstructure:"stop_after"`       // Continuous playback before asking if anyone is listening; 0 never asks
 }
 
 type EqualizerConfig struct {
@@ -66,21 +121,60 @@ type EqualizerConfig struct {
 	Bands   [10]float64 `mapstructure:"bands"` // -12 to +12 dB
 }
 
+// EffectsConfig orders the effects playback runs through and which are
+// bypassed; the equalizer is switched on and off by its own setting
+type EffectsConfig struct {
+	Order  []string `mapstructure:"order"`
+	Bypass []string `mapstructure:"bypass"`
+}
+
 type LibraryConfig struct {
 	WatchFolders      []string      `mapstructure:"watch_folders"`
+	WatchForChanges   bool          `mapstructure:"watch_for_changes"` // Pick up file changes in watch folders as they happen
 	AutoScan          bool          `mapstructure:"auto_scan"`
 	ScanInterval      time.Duration `mapstructure:"scan_interval"`
+	PauseScanWhilePlaying bool      `mapstructure:"pause_scan_while_playing"` // Scheduled scans wait for playback to stop
 	ExtractMetadata   bool          `mapstructure:"extract_metadata"`
 	ExtractAlbumArt   bool          `mapstructure:"extract_album_art"`
 	AlbumArtMaxSize   int           `mapstructure:"album_art_max_size"`
+	FetchAlbumArt     bool          `mapstructure:"fetch_album_art"` // Look covers missing from files and folders up online
 	SkipDuplicates    bool          `mapstructure:"skip_duplicates"`
+	DuplicatePolicy   string        `mapstructure:"duplicate_policy"`  // skip, update_in_place, keep_both, prefer_higher_quality
+	RemoveMissing     bool          `mapstructure:"remove_missing"`    // Scans delete tracks whose files are gone instead of flagging them
+	WriteRatingTags   bool          `mapstructure:"write_rating_tags"` // Also write ratings into files for other players
+	FormatPreference  []string      `mapstructure:"format_preference"` // Formats to show first when an album exists in several, e.g. ["flac", "mp3"]
 	MinTrackDuration  time.Duration `mapstructure:"min_track_duration"`
 	MaxTrackDuration  time.Duration `mapstructure:"max_track_duration"`
 	FilePatterns      []string      `mapstructure:"file_patterns"`
 	ExcludePatterns   []string      `mapstructure:"exclude_patterns"`
+	LocalIOWorkers    int           `mapstructure:"local_io_workers"`   // Concurrent file reads on local disks
+	NetworkIOWorkers  int           `mapstructure:"network_io_workers"` // Concurrent file reads on network shares
+	CPUWorkers        int           `mapstructure:"cpu_workers"`        // Concurrent full decodes
 	DatabasePath      string        `mapstructure:"database_path"`
 	BackupDatabase    bool          `mapstructure:"backup_database"`
 	BackupInterval    time.Duration `mapstructure:"backup_interval"`
+	Normalization     NormalizationConfig `mapstructure:"normalization"`
+	Enrichment        EnrichmentConfig    `mapstructure:"enrichment"`
+}
+
+// EnrichmentConfig chooses where missing track metadata is looked up
+type EnrichmentConfig struct {
+	Providers        []string `mapstructure:"providers"`         // In priority order: tags, filename, musicbrainz, discogs, acoustid
+	Merge            string   `mapstructure:"merge"`             // fill keeps existing values; replace prefers the providers'
+	OnScan           bool     `mapstructure:"on_scan"`           // Also enrich files as they are scanned, not only on demand
+	FilenamePatterns []string `mapstructure:"filename_patterns"` // Tried in order by the filename provider, e.g. "{artist}/{album}/{track} - {title}"
+}
+
+// NormalizationConfig controls how tag values are cleaned up on import and
+// when edited
+type NormalizationConfig struct {
+	Enabled        bool              `mapstructure:"enabled"`
+	TrimWhitespace bool              `mapstructure:"trim_whitespace"`
+	FeatFormat     string            `mapstructure:"feat_format"`    // e.g. "feat."; empty leaves credits alone
+	FixTitleCase   bool              `mapstructure:"fix_title_case"` // Only all-caps or all-lowercase values
+	MoveArticles   bool              `mapstructure:"move_articles"`  // "Beatles, The" -> "The Beatles"
+	GenreAliases   map[string]string `mapstructure:"genre_aliases"`
+	ArtistAliases  map[string]string `mapstructure:"artist_aliases"`
 }
 
 type UIConfig struct {
@@ -112,6 +206,8 @@ type NetworkConfig struct {
 	EnableStreaming   bool          `mapstructure:"enable_streaming"`
 	StreamingPort     int           `mapstructure:"streaming_port"`
 	BufferSize        int           `mapstructure:"buffer_size"`
+	StreamPrebuffer   time.Duration `mapstructure:"stream_prebuffer"`    // Buffered before a radio stream plays
+	StreamPrebufferKB int           `mapstructure:"stream_prebuffer_kb"` // Overrides stream_prebuffer when set
 	Timeout           time.Duration `mapstructure:"timeout"`
 	MaxConnections    int           `mapstructure:"max_connections"`
 	ProxyEnabled      bool          `mapstructure:"proxy_enabled"`
@@ -119,6 +215,25 @@ type NetworkConfig struct {
 	CacheEnabled      bool          `mapstructure:"cache_enabled"`
 	CacheSize         int64         `mapstructure:"cache_size"` // in MB
 	CachePath         string        `mapstructure:"cache_path"`
+	FetchContext      bool          `mapstructure:"fetch_context"`  // Artist and album info for the current track
+	LastFMAPIKey      string        `mapstructure:"lastfm_api_key"`
+	FanartAPIKey      string        `mapstructure:"fanart_api_key"` // Artist images from fanart.tv
+	RemoteEnabled     bool          `mapstructure:"remote_enabled"` // Remote control API for scripts
+	RemoteAddress     string        `mapstructure:"remote_address"`
+	RemoteGRPCAddress string        `mapstructure:"remote_grpc_address"` // gRPC service, "" for none
+	RemoteTLS         bool          `mapstructure:"remote_tls"`         // Self-signed certificate for the remote API
+	RemoteRateLimit   float64       `mapstructure:"remote_rate_limit"`  // Requests per second per client, 0 for none
+	MaxStreams        int           `mapstructure:"max_streams"`        // Library tracks streamed at once
+	Discovery         bool          `mapstructure:"discovery"`          // Advertise the remote API over mDNS
+	OfflineMode       bool          `mapstructure:"offline_mode"`       // No internet requests at all
+	ConnectivityProbe string        `mapstructure:"connectivity_probe"` // Host and port dialled to detect a lost connection
+	DiscogsKey        string        `mapstructure:"discogs_key"`        // Discogs application consumer key
+	DiscogsSecret     string        `mapstructure:"discogs_secret"`
+	DiscogsToken      string        `mapstructure:"discogs_token"`     // Personal access token, instead of connecting an account
+	AcoustIDKey       string        `mapstructure:"acoustid_key"`      // AcoustID application key, for identifying untagged tracks
+	AcoustIDUserKey   string        `mapstructure:"acoustid_user_key"` // The user's own key, for submitting fingerprints
+	AcoustIDSubmit    bool          `mapstructure:"acoustid_submit"`   // Contribute fingerprints of tagged tracks when identifying
+	FpcalcPath        string        `mapstructure:"fpcalc_path"`       // Chromaprint's fpcalc, "" to look in PATH
 }
 
 type ShortcutsConfig struct {
@@ -139,6 +254,28 @@ type AdvancedConfig struct {
 	ProfilePort       int           `mapstructure:"profile_port"`
 	DebugMode         bool          `mapstructure:"debug_mode"`
 	ExperimentalFeatures []string   `mapstructure:"experimental_features"`
+	Hooks             []HookConfig  `mapstructure:"hooks"` // Commands and webhooks run on playback events
+	NowPlaying        NowPlayingConfig `mapstructure:"now_playing"`
+}
+
+// NowPlayingConfig keeps a text file holding the current track, rendered
+// through a template, for streaming overlays to read
+type NowPlayingConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Path     string `mapstructure:"path"`
+	Template string `mapstructure:"template"`  // Such as "{{.Artist}} - {{.Title}}"
+	IdleText string `mapstructure:"idle_text"` // Written while nothing plays
+}
+
+// HookConfig runs a command with templated arguments, or posts to a
+// webhook, when a playback event happens: track_changed, play, pause or
+// stop
+type HookConfig struct {
+	Event   string   `mapstructure:"event" json:"event"`
+	Command string   `mapstructure:"command" json:"command"`
+	Args    []string `mapstructure:"args" json:"args"`
+	URL     string   `mapstructure:"url" json:"url"`
+	Enabled bool     `mapstructure:"enabled" json:"enabled"`
 }
 
 func Get() *Config {
@@ -205,6 +342,11 @@ func (c *Config) setDefaults() {
 	c.v.SetDefault("app.check_for_updates", true)
 	c.v.SetDefault("app.language", "en")
 	c.v.SetDefault("app.theme", "dark")
+	c.v.SetDefault("app.first_run_complete", false)
+	c.v.SetDefault("app.resume_playback", false)
+	c.v.SetDefault("app.family_mode", false)
+	c.v.SetDefault("app.shuffle_live", true)
+	c.v.SetDefault("app.suggest_live", true)
 	
 	// Audio defaults
 	c.v.SetDefault("audio.output_device", "default")
@@ -214,32 +356,99 @@ func (c *Config) setDefaults() {
 	c.v.SetDefault("audio.sample_rate", 44100)
 	c.v.SetDefault("audio.bit_depth", 16)
 	c.v.SetDefault("audio.volume", 0.8)
+	c.v.SetDefault("audio.max_volume_db", 0.0)
+	c.v.SetDefault("audio.volume_step_db", 1.0)
 	c.v.SetDefault("audio.crossfade_duration", 5*time.Second)
+	c.v.SetDefault("audio.crossfade_curve", "equal_power")
 	c.v.SetDefault("audio.replay_gain", true)
 	c.v.SetDefault("audio.replay_gain_mode", "track")
+	c.v.SetDefault("audio.replay_gain_scan", true)
+	c.v.SetDefault("audio.speed_mode", "time-stretch")
+	c.v.SetDefault("audio.replay_gain_write_tags", false)
+	c.v.SetDefault("audio.volume_leveling", true)
 	c.v.SetDefault("audio.preamp", 0.0)
 	c.v.SetDefault("audio.equalizer.enabled", false)
 	c.v.SetDefault("audio.equalizer.preset", "flat")
 	c.v.SetDefault("audio.equalizer.bands", [10]float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	c.v.SetDefault("audio.effects.order", []string{"Preamp", "Equalizer", "Limiter"})
+	c.v.SetDefault("audio.effects.bypass", []string{})
 	c.v.SetDefault("audio.gapless_playback", true)
+	c.v.SetDefault("audio.gapless_hints", true)
+	c.v.SetDefault("audio.gapless_hinted_albums", []string{})
+	c.v.SetDefault("audio.crossfade_albums", []string{})
+	c.v.SetDefault("audio.transitions.same_album", "gapless")
+	c.v.SetDefault("audio.transitions.different_album", "crossfade")
+	c.v.SetDefault("audio.transitions.gap", 2*time.Second)
+	c.v.SetDefault("audio.transitions.rules", []map[string]interface{}{
+		{"name": "Classical", "transition": "gapless", "conditions": []map[string]interface{}{
+			{"field": "genre", "operator": "contains", "value": "classical"},
+		}},
+		{"name": "Live", "transition": "gapless", "conditions": []map[string]interface{}{
+			{"field": "live", "operator": "equals", "value": true},
+		}},
+		{"name": "Compilations", "transition": "gap", "gap_seconds": 1, "conditions": []map[string]interface{}{
+			{"field": "album_artist", "operator": "equals", "value": "Various Artists"},
+		}},
+	})
 	c.v.SetDefault("audio.fade_on_pause", true)
 	c.v.SetDefault("audio.fade_duration", 200*time.Millisecond)
+	c.v.SetDefault("audio.pause_on_device_lost", true)
+	c.v.SetDefault("audio.track_ending_notice", 10*time.Second)
+	c.v.SetDefault("audio.sync_offset", time.Duration(0))
+	c.v.SetDefault("audio.device_latency", map[string]time.Duration{})
+	c.v.SetDefault("audio.idle.pause_on_lock", false)
+	c.v.SetDefault("audio.idle.resume_on_unlock", true)
+	c.v.SetDefault("audio.idle.stop_after", time.Duration(0))
 	
 	// Library defaults
 	c.v.SetDefault("library.watch_folders", []string{})
+	c.v.SetDefault("library.watch_for_changes", true)
 	c.v.SetDefault("library.auto_scan", true)
 	c.v.SetDefault("library.scan_interval", 1*time.Hour)
+	c.v.SetDefault("library.pause_scan_while_playing", true)
 	c.v.SetDefault("library.extract_metadata", true)
 	c.v.SetDefault("library.extract_album_art", true)
 	c.v.SetDefault("library.album_art_max_size", 1024)
+	c.v.SetDefault("library.fetch_album_art", true)
 	c.v.SetDefault("library.skip_duplicates", true)
+	c.v.SetDefault("library.duplicate_policy", "skip")
+	c.v.SetDefault("library.remove_missing", false)
+	c.v.SetDefault("library.write_rating_tags", false)
+	c.v.SetDefault("library.format_preference", []string{})
 	c.v.SetDefault("library.min_track_duration", 10*time.Second)
 	c.v.SetDefault("library.max_track_duration", 10*time.Hour)
-	c.v.SetDefault("library.file_patterns", []string{"*.mp3", "*.flac", "*.ogg", "*.wav", "*.aac", "*.wma", "*.m4a"})
+	c.v.SetDefault("library.file_patterns", []string{"*.mp3", "*.flac", "*.ogg", "*.wav", "*.aiff", "*.aif", "*.aac", "*.wma", "*.m4a"})
 	c.v.SetDefault("library.exclude_patterns", []string{"*.tmp", "*.temp", "*.partial"})
+	c.v.SetDefault("library.local_io_workers", 4)
+	c.v.SetDefault("library.network_io_workers", 1)
+	c.v.SetDefault("library.cpu_workers", runtime.NumCPU())
 	c.v.SetDefault("library.database_path", filepath.Join(c.getDataDir(), "library.db"))
 	c.v.SetDefault("library.backup_database", true)
 	c.v.SetDefault("library.backup_interval", 24*time.Hour)
+	c.v.SetDefault("library.enrichment.providers", []string{"tags", "musicbrainz"})
+	c.v.SetDefault("library.enrichment.merge", "fill")
+	c.v.SetDefault("library.enrichment.on_scan", false)
+	c.v.SetDefault("library.enrichment.filename_patterns", []string{
+		"{artist}/{album}/{track} - {title}",
+		"{track} - {title}",
+		"{artist} - {title}",
+	})
+	c.v.SetDefault("library.normalization.enabled", true)
+	c.v.SetDefault("library.normalization.trim_whitespace", true)
+	c.v.SetDefault("library.normalization.feat_format", "feat.")
+	c.v.SetDefault("library.normalization.fix_title_case", false)
+	c.v.SetDefault("library.normalization.move_articles", true)
+	c.v.SetDefault("library.normalization.genre_aliases", map[string]string{
+		"hip hop":        "Hip-Hop",
+		"hiphop":         "Hip-Hop",
+		"rnb":            "R&B",
+		"r'n'b":          "R&B",
+		"rhythm & blues": "R&B",
+		"drum n bass":    "Drum & Bass",
+		"drum and bass":  "Drum & Bass",
+		"electronica":    "Electronic",
+	})
+	c.v.SetDefault("library.normalization.artist_aliases", map[string]string{})
 	
 	// UI defaults
 	c.v.SetDefault("ui.window_mode", "modern")
@@ -261,12 +470,33 @@ func (c *Config) setDefaults() {
 	c.v.SetDefault("network.enable_streaming", true)
 	c.v.SetDefault("network.streaming_port", 8080)
 	c.v.SetDefault("network.buffer_size", 65536)
+	c.v.SetDefault("network.stream_prebuffer", 2*time.Second)
+	c.v.SetDefault("network.stream_prebuffer_kb", 0)
 	c.v.SetDefault("network.timeout", 30*time.Second)
 	c.v.SetDefault("network.max_connections", 10)
 	c.v.SetDefault("network.proxy_enabled", false)
 	c.v.SetDefault("network.cache_enabled", true)
 	c.v.SetDefault("network.cache_size", 500) // MB
 	c.v.SetDefault("network.cache_path", filepath.Join(c.getDataDir(), "cache", "network"))
+	c.v.SetDefault("network.fetch_context", true)
+	c.v.SetDefault("network.lastfm_api_key", "")
+	c.v.SetDefault("network.fanart_api_key", "")
+	c.v.SetDefault("network.remote_enabled", false)
+	c.v.SetDefault("network.remote_address", "127.0.0.1:8765")
+	c.v.SetDefault("network.remote_grpc_address", "127.0.0.1:8766")
+	c.v.SetDefault("network.remote_tls", true)
+	c.v.SetDefault("network.remote_rate_limit", 20.0)
+	c.v.SetDefault("network.max_streams", 4)
+	c.v.SetDefault("network.discovery", true)
+	c.v.SetDefault("network.offline_mode", false)
+	c.v.SetDefault("network.connectivity_probe", "musicbrainz.org:443")
+	c.v.SetDefault("network.discogs_key", "")
+	c.v.SetDefault("network.discogs_secret", "")
+	c.v.SetDefault("network.discogs_token", "")
+	c.v.SetDefault("network.acoustid_key", "")
+	c.v.SetDefault("network.acoustid_user_key", "")
+	c.v.SetDefault("network.acoustid_submit", false)
+	c.v.SetDefault("network.fpcalc_path", "")
 	
 	// Shortcuts defaults
 	c.v.SetDefault("shortcuts.global", map[string]string{
@@ -289,6 +519,11 @@ func (c *Config) setDefaults() {
 	c.v.SetDefault("advanced.profile_port", 6060)
 	c.v.SetDefault("advanced.debug_mode", false)
 	c.v.SetDefault("advanced.experimental_features", []string{})
+	c.v.SetDefault("advanced.hooks", []HookConfig{})
+	c.v.SetDefault("advanced.now_playing.enabled", false)
+	c.v.SetDefault("advanced.now_playing.path", filepath.Join(c.getDataDir(), "nowplaying.txt"))
+	c.v.SetDefault("advanced.now_playing.template", "{{.Artist}} - {{.Title}}")
+	c.v.SetDefault("advanced.now_playing.idle_text", "")
 }
 
 func (c *Config) getUserConfigDir() string {
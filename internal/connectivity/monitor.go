@@ -0,0 +1,223 @@
+// Package connectivity tracks whether WinRamp may use the internet: the
+// user can switch to offline mode, and lost connections are detected by
+// probing. Network features check it before making requests, and work put
+// off while offline runs once the connection is back.
+package connectivity
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/events"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+const (
+	// DefaultProbeAddress is dialled to check the connection. It is one of
+	// the services the metadata lookups use.
+	DefaultProbeAddress = "musicbrainz.org:443"
+
+	probeTimeout = 5 * time.Second
+
+	// onlineProbeInterval and offlineProbeInterval are how often the
+	// connection is checked while up and while down
+	onlineProbeInterval  = 2 * time.Minute
+	offlineProbeInterval = 15 * time.Second
+)
+
+// ErrOffline is returned by network features while offline
+var ErrOffline = errors.New("offline")
+
+// Status is the state of the connection
+type Status struct {
+	Online      bool `json:"online"`      // Network features may be used
+	OfflineMode bool `json:"offlineMode"` // The user switched to offline mode
+	Reachable   bool `json:"reachable"`   // The last probe got through
+}
+
+// TopicChanged is published when the status changes
+var TopicChanged = events.NewTopic[Status]("network:connectivityChanged")
+
+// Monitor tracks connectivity. A nil *Monitor is always online.
+type Monitor struct {
+	probeAddress string
+	bus          *events.Bus
+	dial         func(ctx context.Context, network, address string) (net.Conn, error)
+
+	mu          sync.Mutex
+	offlineMode bool
+	reachable   bool
+	pending     map[string]func() // Work waiting to go online, by key
+	order       []string
+	probeNow    chan struct{}
+}
+
+// NewMonitor creates a monitor probing address, publishing changes to bus.
+// It assumes the connection is up until a probe says otherwise.
+func NewMonitor(address string, bus *events.Bus) *Monitor {
+	if address == "" {
+		address = DefaultProbeAddress
+	}
+	dialer := &net.Dialer{Timeout: probeTimeout}
+	return &Monitor{
+		probeAddress: address,
+		bus:          bus,
+		dial:         dialer.DialContext,
+		reachable:    true,
+		pending:      make(map[string]func()),
+		probeNow:     make(chan struct{}, 1),
+	}
+}
+
+// Start probes the connection in the background until ctx ends
+func (m *Monitor) Start(ctx context.Context) {
+	go m.run(ctx)
+}
+
+func (m *Monitor) run(ctx context.Context) {
+	for {
+		m.probe(ctx)
+
+		interval := onlineProbeInterval
+		if !m.Status().Reachable {
+			interval = offlineProbeInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.probeNow:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// probe checks the connection, unless offline mode makes it moot
+func (m *Monitor) probe(ctx context.Context) {
+	m.mu.Lock()
+	offlineMode := m.offlineMode
+	m.mu.Unlock()
+	if offlineMode {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	conn, err := m.dial(ctx, "tcp", m.probeAddress)
+	if err == nil {
+		conn.Close()
+	} else if ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return // Shutting down
+	}
+	m.update(func() { m.reachable = err == nil })
+}
+
+// Online reports whether network features may be used
+func (m *Monitor) Online() bool {
+	if m == nil {
+		return true
+	}
+	return m.Status().Online
+}
+
+// Status returns the current status
+func (m *Monitor) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status()
+}
+
+// status must be called with m.mu held
+func (m *Monitor) status() Status {
+	return Status{
+		Online:      !m.offlineMode && m.reachable,
+		OfflineMode: m.offlineMode,
+		Reachable:   m.reachable,
+	}
+}
+
+// SetOfflineMode switches offline mode on or off. Turning it off checks the
+// connection straight away.
+func (m *Monitor) SetOfflineMode(enabled bool) {
+	m.update(func() { m.offlineMode = enabled })
+	if !enabled {
+		m.ReportFailure()
+	}
+}
+
+// ReportFailure tells the monitor a request failed in a way that suggests
+// the connection is down, so it checks without waiting
+func (m *Monitor) ReportFailure() {
+	if m == nil {
+		return
+	}
+	select {
+	case m.probeNow <- struct{}{}:
+	default:
+	}
+}
+
+// WhenOnline runs fn now if online, or else once the connection is back.
+// Work queued under a key replaces earlier work under the same key, so only
+// the latest request for a thing is kept.
+func (m *Monitor) WhenOnline(key string, fn func()) {
+	if m == nil {
+		go fn()
+		return
+	}
+
+	m.mu.Lock()
+	if m.status().Online {
+		m.mu.Unlock()
+		go fn()
+		return
+	}
+	if _, queued := m.pending[key]; !queued {
+		m.order = append(m.order, key)
+	}
+	m.pending[key] = fn
+	m.mu.Unlock()
+
+	logger.Debug("Queued work until online", logger.String("key", key))
+}
+
+// update applies a change and, if the status changed, publishes it and runs
+// any work waiting to go online
+func (m *Monitor) update(change func()) {
+	m.mu.Lock()
+	before := m.status()
+	change()
+	after := m.status()
+
+	var ready []func()
+	if after.Online && !before.Online {
+		for _, key := range m.order {
+			ready = append(ready, m.pending[key])
+		}
+		m.pending = make(map[string]func())
+		m.order = nil
+	}
+	m.mu.Unlock()
+
+	if before == after {
+		return
+	}
+
+	logger.Info("Connectivity changed",
+		logger.Bool("online", after.Online),
+		logger.Bool("offlineMode", after.OfflineMode),
+		logger.Bool("reachable", after.Reachable))
+	events.Publish(m.bus, TopicChanged, after)
+
+	if len(ready) > 0 {
+		go func() {
+			for _, fn := range ready {
+				fn()
+			}
+		}()
+	}
+}
@@ -0,0 +1,398 @@
+// Package discovery advertises WinRamp on the local network over multicast
+// DNS and finds other instances doing the same
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/logger"
+)
+
+const (
+	// ServiceType is the DNS-SD service WinRamp instances advertise
+	ServiceType = "_winramp._tcp.local."
+
+	servicesQuery = "_services._dns-sd._udp.local."
+
+	// recordTTL is how long others may cache the advertised records, in
+	// seconds
+	recordTTL = 120
+
+	// DefaultBrowseTimeout is how long Browse waits for answers
+	DefaultBrowseTimeout = 2 * time.Second
+)
+
+var (
+	mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+	ErrAlreadyAdvertising = errors.New("already advertising")
+)
+
+// Peer is another WinRamp instance found on the network
+type Peer struct {
+	Name   string            `json:"name"`
+	Host   string            `json:"host"`
+	Port   int               `json:"port"`
+	Addrs  []string          `json:"addrs"`
+	Text   map[string]string `json:"text"` // Service details, such as the API version
+	SeenAt time.Time         `json:"seenAt"`
+}
+
+// Address returns the peer's first address with its port, or "" when its
+// address is unknown
+func (p *Peer) Address() string {
+	if len(p.Addrs) == 0 {
+		return ""
+	}
+	return net.JoinHostPort(p.Addrs[0], strconv.Itoa(p.Port))
+}
+
+// Advertiser answers mDNS queries for a WinRamp service on this machine
+type Advertiser struct {
+	instance string // Fully qualified instance name
+	host     string // Fully qualified host name
+	port     int
+	text     map[string]string
+
+	mu     sync.Mutex
+	conn   *net.UDPConn
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewAdvertiser creates an advertiser for a service on port. The instance
+// name defaults to one based on the computer's name.
+func NewAdvertiser(name string, port int, text map[string]string) *Advertiser {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "winramp"
+	}
+	hostname = dnsLabel(strings.SplitN(hostname, ".", 2)[0])
+
+	if name == "" {
+		name = "WinRamp on " + hostname
+	}
+	return &Advertiser{
+		instance: dnsLabel(name) + "." + ServiceType,
+		host:     hostname + ".local.",
+		port:     port,
+		text:     text,
+	}
+}
+
+// Instance returns the advertised instance name
+func (a *Advertiser) Instance() string {
+	return a.instance
+}
+
+// Start joins the mDNS group, announces the service and answers queries
+// for it until Stop or ctx ends
+func (a *Advertiser) Start(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.conn != nil {
+		return ErrAlreadyAdvertising
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return fmt.Errorf("failed to join mDNS group: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	a.conn = conn
+	a.cancel = cancel
+	a.done = make(chan struct{})
+
+	go a.serve(ctx, conn, a.done)
+	a.announce(conn, recordTTL)
+
+	logger.Info("Advertising on the local network",
+		logger.String("instance", a.instance),
+		logger.Int("port", a.port))
+	return nil
+}
+
+// Stop says goodbye, so others drop the service straight away, and stops
+// answering queries
+func (a *Advertiser) Stop() {
+	a.mu.Lock()
+	conn, cancel, done := a.conn, a.cancel, a.done
+	a.conn = nil
+	a.mu.Unlock()
+
+	if conn == nil {
+		return
+	}
+	a.announce(conn, 0)
+	cancel()
+	conn.Close()
+	<-done
+}
+
+func (a *Advertiser) serve(ctx context.Context, conn *net.UDPConn, done chan struct{}) {
+	defer close(done)
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				logger.Debug("mDNS read failed", logger.Error(err))
+			}
+			return
+		}
+
+		query, err := unpack(buf[:n])
+		if err != nil || query.response {
+			continue
+		}
+		a.answer(conn, query, from)
+	}
+}
+
+// answer responds to the questions about our service. Queries from a port
+// other than 5353 come from simple resolvers, which get a direct reply
+// echoing their question.
+func (a *Advertiser) answer(conn *net.UDPConn, query *message, from *net.UDPAddr) {
+	var answers []record
+	for _, q := range query.questions {
+		answers = append(answers, a.recordsFor(q)...)
+	}
+	if len(answers) == 0 {
+		return
+	}
+
+	reply := &message{response: true, records: answers}
+	to := mdnsGroup
+	if from.Port != mdnsGroup.Port {
+		reply.id = query.id
+		reply.questions = query.questions
+		to = from
+	}
+
+	if _, err := conn.WriteToUDP(reply.pack(), to); err != nil {
+		logger.Debug("mDNS reply failed", logger.Error(err))
+	}
+}
+
+// recordsFor returns the records answering a question, with the records a
+// browser needs next added on
+func (a *Advertiser) recordsFor(q question) []record {
+	switch {
+	case strings.EqualFold(q.name, servicesQuery) && matches(q.qtype, typePTR):
+		return []record{{name: servicesQuery, rtype: typePTR, class: classIN, ttl: recordTTL, target: ServiceType}}
+	case strings.EqualFold(q.name, ServiceType) && matches(q.qtype, typePTR):
+		return a.records(recordTTL)
+	case strings.EqualFold(q.name, a.instance) && (matches(q.qtype, typeSRV) || matches(q.qtype, typeTXT)):
+		return a.records(recordTTL)[1:]
+	case strings.EqualFold(q.name, a.host) && matches(q.qtype, typeA):
+		return a.addressRecords(recordTTL)
+	}
+	return nil
+}
+
+func matches(qtype, want uint16) bool {
+	return qtype == want || qtype == typeANY
+}
+
+// records returns the service's PTR, SRV, TXT and address records
+func (a *Advertiser) records(ttl uint32) []record {
+	records := []record{
+		{name: ServiceType, rtype: typePTR, class: classIN, ttl: ttl, target: a.instance},
+		{name: a.instance, rtype: typeSRV, class: classIN | classCacheFlush, ttl: ttl, target: a.host, port: uint16(a.port)},
+		{name: a.instance, rtype: typeTXT, class: classIN | classCacheFlush, ttl: ttl, text: a.text},
+	}
+	return append(records, a.addressRecords(ttl)...)
+}
+
+func (a *Advertiser) addressRecords(ttl uint32) []record {
+	var records []record
+	for _, ip := range localAddresses() {
+		records = append(records, record{name: a.host, rtype: typeA, class: classIN | classCacheFlush, ttl: ttl, ip: ip})
+	}
+	return records
+}
+
+// announce sends the service's records unprompted, or with a TTL of zero,
+// withdraws them
+func (a *Advertiser) announce(conn *net.UDPConn, ttl uint32) {
+	announcement := &message{response: true, records: a.records(ttl)}
+	if _, err := conn.WriteToUDP(announcement.pack(), mdnsGroup); err != nil {
+		logger.Debug("mDNS announcement failed", logger.Error(err))
+	}
+}
+
+// Browse asks the network for WinRamp instances and returns those that
+// answer within timeout, leaving out the instance named self
+func Browse(ctx context.Context, timeout time.Duration, self string) ([]*Peer, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mDNS socket: %w", err)
+	}
+	defer conn.Close()
+
+	query := &message{id: uint16(time.Now().UnixNano()), questions: []question{{name: ServiceType, qtype: typePTR}}}
+	if _, err := conn.WriteToUDP(query.pack(), mdnsGroup); err != nil {
+		return nil, fmt.Errorf("failed to send mDNS query: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+
+	collected := newCollector()
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		reply, err := unpack(buf[:n])
+		if err != nil || !reply.response {
+			continue
+		}
+		collected.add(reply, from.IP)
+	}
+
+	return collected.peers(self), nil
+}
+
+// collector assembles peers from the records in mDNS replies
+type collector struct {
+	instances map[string]string // Names as given, by lowercase name
+	services  map[string]record // SRV by instance
+	texts     map[string]map[string]string
+	addrs     map[string][]string // By host
+	sources   map[string]string   // Where each instance's reply came from
+}
+
+func newCollector() *collector {
+	return &collector{
+		instances: make(map[string]string),
+		services:  make(map[string]record),
+		texts:     make(map[string]map[string]string),
+		addrs:     make(map[string][]string),
+		sources:   make(map[string]string),
+	}
+}
+
+func (c *collector) add(reply *message, from net.IP) {
+	for _, r := range reply.records {
+		name := strings.ToLower(r.name)
+		switch r.rtype {
+		case typePTR:
+			if name == ServiceType && r.ttl > 0 {
+				target := strings.ToLower(r.target)
+				c.instances[target] = r.target
+				c.sources[target] = from.String()
+			}
+		case typeSRV:
+			c.services[name] = r
+		case typeTXT:
+			c.texts[name] = r.text
+		case typeA:
+			c.addrs[name] = appendUnique(c.addrs[name], r.ip.String())
+		}
+	}
+}
+
+func (c *collector) peers(self string) []*Peer {
+	now := time.Now()
+	var peers []*Peer
+	for instance, given := range c.instances {
+		service, ok := c.services[instance]
+		if !ok || strings.EqualFold(instance, self) {
+			continue
+		}
+
+		host := strings.ToLower(service.target)
+		addrs := c.addrs[host]
+		if len(addrs) == 0 {
+			addrs = []string{c.sources[instance]}
+		}
+
+		peers = append(peers, &Peer{
+			Name:   instanceName(given),
+			Host:   strings.TrimSuffix(service.target, "."),
+			Port:   int(service.port),
+			Addrs:  addrs,
+			Text:   c.texts[instance],
+			SeenAt: now,
+		})
+	}
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+	return peers
+}
+
+// instanceName returns the readable part of a fully qualified instance name
+func instanceName(instance string) string {
+	if len(instance) > len(ServiceType) && strings.EqualFold(instance[len(instance)-len(ServiceType):], ServiceType) {
+		instance = instance[:len(instance)-len(ServiceType)]
+	}
+	return strings.TrimSuffix(instance, ".")
+}
+
+// dnsLabel makes a name usable as a single DNS label
+func dnsLabel(name string) string {
+	name = strings.ReplaceAll(strings.TrimSpace(name), ".", "-")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// localAddresses returns this machine's IPv4 addresses other than loopback
+func localAddresses() []net.IP {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+
+	var ips []net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() {
+			continue
+		}
+		if ip := ipNet.IP.To4(); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, existing := range list {
+		if existing == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,248 @@
+package discovery
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+)
+
+// DNS record types and classes used by mDNS service discovery
+const (
+	typeA   uint16 = 1
+	typePTR uint16 = 12
+	typeTXT uint16 = 16
+	typeSRV uint16 = 33
+	typeANY uint16 = 255
+
+	classIN uint16 = 1
+	// classCacheFlush marks a record as the only one of its name and type
+	classCacheFlush uint16 = 0x8000
+
+	flagResponse uint16 = 0x8400 // QR and AA
+)
+
+var errMalformed = errors.New("malformed DNS message")
+
+type question struct {
+	name  string
+	qtype uint16
+}
+
+type record struct {
+	name  string
+	rtype uint16
+	class uint16
+	ttl   uint32
+
+	target string            // PTR and SRV
+	port   uint16            // SRV
+	text   map[string]string // TXT
+	ip     net.IP            // A
+}
+
+type message struct {
+	id        uint16
+	response  bool
+	questions []question
+	records   []record // Answers and additional records together
+}
+
+// pack encodes a message. Names are written in full, as messages are small
+// enough not to need compression.
+func (m *message) pack() []byte {
+	b := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(b[0:], m.id)
+	if m.response {
+		binary.BigEndian.PutUint16(b[2:], flagResponse)
+	}
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.records)))
+
+	for _, q := range m.questions {
+		b = appendName(b, q.name)
+		b = binary.BigEndian.AppendUint16(b, q.qtype)
+		b = binary.BigEndian.AppendUint16(b, classIN)
+	}
+
+	for _, r := range m.records {
+		b = appendName(b, r.name)
+		b = binary.BigEndian.AppendUint16(b, r.rtype)
+		b = binary.BigEndian.AppendUint16(b, r.class)
+		b = binary.BigEndian.AppendUint32(b, r.ttl)
+
+		lengthAt := len(b)
+		b = append(b, 0, 0)
+		switch r.rtype {
+		case typePTR:
+			b = appendName(b, r.target)
+		case typeSRV:
+			b = binary.BigEndian.AppendUint16(b, 0) // Priority
+			b = binary.BigEndian.AppendUint16(b, 0) // Weight
+			b = binary.BigEndian.AppendUint16(b, r.port)
+			b = appendName(b, r.target)
+		case typeTXT:
+			b = appendText(b, r.text)
+		case typeA:
+			b = append(b, r.ip.To4()...)
+		}
+		binary.BigEndian.PutUint16(b[lengthAt:], uint16(len(b)-lengthAt-2))
+	}
+
+	return b
+}
+
+func appendName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		label = label[:min(len(label), 63)]
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+func appendText(b []byte, text map[string]string) []byte {
+	if len(text) == 0 {
+		return append(b, 0)
+	}
+	for key, value := range text {
+		entry := key + "=" + value
+		entry = entry[:min(len(entry), 255)]
+		b = append(b, byte(len(entry)))
+		b = append(b, entry...)
+	}
+	return b
+}
+
+// unpack decodes a message, skipping records of types it doesn't use
+func unpack(b []byte) (*message, error) {
+	if len(b) < 12 {
+		return nil, errMalformed
+	}
+
+	m := &message{
+		id:       binary.BigEndian.Uint16(b[0:]),
+		response: binary.BigEndian.Uint16(b[2:])&0x8000 != 0,
+	}
+	questions := int(binary.BigEndian.Uint16(b[4:]))
+	records := int(binary.BigEndian.Uint16(b[6:])) +
+		int(binary.BigEndian.Uint16(b[8:])) +
+		int(binary.BigEndian.Uint16(b[10:]))
+
+	off := 12
+	for i := 0; i < questions; i++ {
+		name, next, err := readName(b, off)
+		if err != nil || next+4 > len(b) {
+			return nil, errMalformed
+		}
+		m.questions = append(m.questions, question{
+			name:  name,
+			qtype: binary.BigEndian.Uint16(b[next:]),
+		})
+		off = next + 4
+	}
+
+	for i := 0; i < records; i++ {
+		name, next, err := readName(b, off)
+		if err != nil || next+10 > len(b) {
+			return nil, errMalformed
+		}
+		r := record{
+			name:  name,
+			rtype: binary.BigEndian.Uint16(b[next:]),
+			class: binary.BigEndian.Uint16(b[next+2:]),
+			ttl:   binary.BigEndian.Uint32(b[next+4:]),
+		}
+		length := int(binary.BigEndian.Uint16(b[next+8:]))
+		start := next + 10
+		end := start + length
+		if end > len(b) {
+			return nil, errMalformed
+		}
+
+		switch r.rtype {
+		case typePTR:
+			if r.target, _, err = readName(b, start); err != nil {
+				return nil, err
+			}
+		case typeSRV:
+			if length < 7 {
+				return nil, errMalformed
+			}
+			r.port = binary.BigEndian.Uint16(b[start+4:])
+			if r.target, _, err = readName(b, start+6); err != nil {
+				return nil, err
+			}
+		case typeTXT:
+			r.text = readText(b[start:end])
+		case typeA:
+			if length == net.IPv4len {
+				r.ip = net.IP(append([]byte(nil), b[start:end]...))
+			}
+		}
+
+		m.records = append(m.records, r)
+		off = end
+	}
+
+	return m, nil
+}
+
+// readName reads a possibly compressed name at off, returning it with a
+// trailing dot and the offset just past it
+func readName(b []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if off >= len(b) {
+			return "", 0, errMalformed
+		}
+		length := int(b[off])
+
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+
+		case length&0xC0 == 0xC0:
+			if off+1 >= len(b) {
+				return "", 0, errMalformed
+			}
+			if jumps++; jumps > 16 {
+				return "", 0, errMalformed
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(b[off:]) & 0x3FFF)
+
+		default:
+			if off+1+length > len(b) {
+				return "", 0, errMalformed
+			}
+			labels = append(labels, string(b[off+1:off+1+length]))
+			off += 1 + length
+		}
+	}
+}
+
+func readText(b []byte) map[string]string {
+	text := make(map[string]string)
+	for len(b) > 0 {
+		length := int(b[0])
+		if 1+length > len(b) {
+			break
+		}
+		key, value, _ := strings.Cut(string(b[1:1+length]), "=")
+		if key != "" {
+			text[strings.ToLower(key)] = value
+		}
+		b = b[1+length:]
+	}
+	return text
+}
@@ -0,0 +1,175 @@
+package discovery
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackUnpack(t *testing.T) {
+	instance := "Kitchen." + ServiceType
+	original := &message{
+		id:        42,
+		response:  true,
+		questions: []question{{name: ServiceType, qtype: typePTR}},
+		records: []record{
+			{name: ServiceType, rtype: typePTR, class: classIN, ttl: 120, target: instance},
+			{name: instance, rtype: typeSRV, class: classIN | classCacheFlush, ttl: 120, target: "kitchen.local.", port: 8765},
+			{name: instance, rtype: typeTXT, class: classIN | classCacheFlush, ttl: 120, text: map[string]string{"api": "1", "version": "2.0"}},
+			{name: "kitchen.local.", rtype: typeA, class: classIN | classCacheFlush, ttl: 120, ip: net.IPv4(192, 168, 1, 20).To4()},
+		},
+	}
+
+	m, err := unpack(original.pack())
+	require.NoError(t, err)
+	assert.Equal(t, original, m)
+}
+
+func TestUnpackEmptyText(t *testing.T) {
+	// A TXT record with no entries still holds one empty string
+	original := &message{records: []record{{name: "a.local.", rtype: typeTXT, class: classIN, text: nil}}}
+	m, err := unpack(original.pack())
+	require.NoError(t, err)
+	require.Len(t, m.records, 1)
+	assert.Empty(t, m.records[0].text)
+}
+
+func TestReadName(t *testing.T) {
+	// "local." at 12, then "_winramp._tcp" pointing back to it at 19
+	b := make([]byte, 12)
+	b = append(b, 5, 'l', 'o', 'c', 'a', 'l', 0)
+	b = append(b, 8, '_', 'w', 'i', 'n', 'r', 'a', 'm', 'p', 4, '_', 't', 'c', 'p', 0xC0, 12)
+
+	tests := []struct {
+		name string
+		b    []byte
+		off  int
+		want string
+		next int
+		err  bool
+	}{
+		{"plain", b, 12, "local.", 19, false},
+		{"compressed", b, 19, "_winramp._tcp.local.", len(b), false},
+		{"root", []byte{0}, 0, ".", 1, false},
+		{"past the end", b, len(b), "", 0, true},
+		{"label overruns", []byte{5, 'a', 'b'}, 0, "", 0, true},
+		{"truncated pointer", []byte{0xC0}, 0, "", 0, true},
+		{"pointer loop", []byte{0xC0, 0}, 0, "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, next, err := readName(tt.b, tt.off)
+			if tt.err {
+				assert.ErrorIs(t, err, errMalformed)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, name)
+			assert.Equal(t, tt.next, next)
+		})
+	}
+}
+
+func TestUnpackMalformed(t *testing.T) {
+	valid := (&message{
+		response: true,
+		records:  []record{{name: "host.local.", rtype: typeSRV, class: classIN, target: "host.local.", port: 80}},
+	}).pack()
+
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"short header", []byte{0, 1, 2}},
+		{"missing question", []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0}},
+		{"missing record", []byte{0, 0, 0x84, 0, 0, 0, 0, 1, 0, 0, 0, 0}},
+		{"truncated record", valid[:len(valid)-3]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := unpack(tt.b)
+			assert.ErrorIs(t, err, errMalformed)
+		})
+	}
+}
+
+func TestAppendName(t *testing.T) {
+	long := make([]byte, 70)
+	for i := range long {
+		long[i] = 'a'
+	}
+
+	// Labels are capped at 63 bytes and empty labels dropped
+	b := appendName(nil, string(long)+"..local.")
+	assert.Equal(t, byte(63), b[0])
+	name, _, err := readName(b, 0)
+	require.NoError(t, err)
+	assert.Equal(t, string(long[:63])+".local.", name)
+}
+
+func TestAdvertiserRecords(t *testing.T) {
+	a := &Advertiser{instance: "Kitchen." + ServiceType, host: "kitchen.local.", port: 8765, text: map[string]string{"api": "1"}}
+
+	tests := []struct {
+		name  string
+		q     question
+		types []uint16
+	}{
+		{"services", question{servicesQuery, typePTR}, []uint16{typePTR}},
+		{"service", question{ServiceType, typePTR}, []uint16{typePTR, typeSRV, typeTXT}},
+		{"instance", question{"kitchen." + ServiceType, typeANY}, []uint16{typeSRV, typeTXT}},
+		{"instance TXT", question{a.instance, typeTXT}, []uint16{typeSRV, typeTXT}},
+		{"wrong type", question{ServiceType, typeSRV}, nil},
+		{"other service", question{"_http._tcp.local.", typePTR}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var types []uint16
+			for _, r := range a.recordsFor(tt.q) {
+				if r.rtype != typeA { // Depends on this machine's interfaces
+					types = append(types, r.rtype)
+				}
+			}
+			assert.Equal(t, tt.types, types)
+		})
+	}
+}
+
+func TestCollectorPeers(t *testing.T) {
+	kitchen := &Advertiser{instance: "Kitchen." + ServiceType, host: "kitchen.local.", port: 8765, text: map[string]string{"api": "1"}}
+	office := &Advertiser{instance: "Office." + ServiceType, host: "office.local.", port: 9000}
+
+	c := newCollector()
+	reply, err := unpack((&message{response: true, records: append(kitchen.records(recordTTL)[:3],
+		record{name: "kitchen.local.", rtype: typeA, class: classIN, ttl: recordTTL, ip: net.IPv4(10, 0, 0, 5).To4()})}).pack())
+	require.NoError(t, err)
+	c.add(reply, net.IPv4(10, 0, 0, 5))
+
+	// Without an address record the reply's source is used
+	reply, err = unpack((&message{response: true, records: office.records(recordTTL)[:3]}).pack())
+	require.NoError(t, err)
+	c.add(reply, net.IPv4(10, 0, 0, 9))
+
+	// Withdrawn services are ignored
+	withdrawn := &Advertiser{instance: "Gone." + ServiceType, host: "gone.local.", port: 1}
+	reply, err = unpack((&message{response: true, records: withdrawn.records(0)[:3]}).pack())
+	require.NoError(t, err)
+	c.add(reply, net.IPv4(10, 0, 0, 7))
+
+	peers := c.peers("office." + ServiceType)
+	require.Len(t, peers, 1)
+	assert.Equal(t, "Kitchen", peers[0].Name)
+	assert.Equal(t, "kitchen.local", peers[0].Host)
+	assert.Equal(t, "10.0.0.5:8765", peers[0].Address())
+	assert.Equal(t, map[string]string{"api": "1"}, peers[0].Text)
+
+	peers = c.peers("")
+	require.Len(t, peers, 2)
+	assert.Equal(t, "Office", peers[1].Name)
+	assert.Equal(t, "10.0.0.9:9000", peers[1].Address())
+}
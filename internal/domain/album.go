@@ -0,0 +1,314 @@
+package domain
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AlbumDisc is one disc of an album, with its tracks in play order
+type AlbumDisc struct {
+	Number   int      `json:"number"`
+	Subtitle string   `json:"subtitle,omitempty"` // e.g. "Live at Wembley"
+	Tracks   []*Track `json:"tracks"`
+}
+
+// AlbumGroup gathers the tracks of one album release, split into discs.
+// Tracks are grouped by album and album artist, so compilations and
+// same-named albums by different artists stay apart.
+type AlbumGroup struct {
+	Key      string        `json:"key"`
+	Title    string        `json:"title"`
+	Artist   string        `json:"artist"`
+	Year     int           `json:"year"`
+	Discs    []*AlbumDisc  `json:"discs"`
+	Duration time.Duration `json:"duration"`
+
+	// OtherVersions holds copies of the album in less preferred formats,
+	// set by PreferVersions
+	OtherVersions []*AlbumVersion `json:"other_versions,omitempty"`
+}
+
+// AlbumVersion is one copy of an album in a single format, such as the MP3
+// rip kept alongside a FLAC one
+type AlbumVersion struct {
+	Format AudioFormat `json:"format"`
+	Tracks []*Track    `json:"tracks"` // In play order
+}
+
+// FormatPreference ranks audio formats, most preferred first, for choosing
+// which copy of an album to show when the library holds several
+type FormatPreference []AudioFormat
+
+// ParseFormatPreference builds a preference from format names such as
+// "flac" or ".mp3"
+func ParseFormatPreference(names []string) (FormatPreference, error) {
+	pref := make(FormatPreference, 0, len(names))
+	seen := make(map[AudioFormat]bool)
+	for _, name := range names {
+		format := detectFormat("x." + strings.TrimPrefix(strings.TrimSpace(name), "."))
+		if format == "" {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, name)
+		}
+		if !seen[format] {
+			seen[format] = true
+			pref = append(pref, format)
+		}
+	}
+	return pref, nil
+}
+
+// Rank returns a format's position in the preference, lower being better.
+// Unlisted formats rank after all listed ones.
+func (p FormatPreference) Rank(format AudioFormat) int {
+	for i, f := range p {
+		if f == format {
+			return i
+		}
+	}
+	return len(p)
+}
+
+// GroupAlbums groups tracks into albums, ordered by artist and title, with
+// each album's discs and tracks in play order. Tracks without an album are
+// left out.
+func GroupAlbums(tracks []*Track) []*AlbumGroup {
+	groups := make(map[string]*AlbumGroup)
+	discs := make(map[string]map[int]*AlbumDisc)
+
+	for _, track := range tracks {
+		key := track.AlbumKey()
+		if key == "" {
+			continue
+		}
+
+		group, ok := groups[key]
+		if !ok {
+			artist := track.AlbumArtist
+			if artist == "" {
+				artist = track.Artist
+			}
+			group = &AlbumGroup{
+				Key:    key,
+				Title:  track.Album,
+				Artist: artist,
+			}
+			groups[key] = group
+			discs[key] = make(map[int]*AlbumDisc)
+		}
+		if group.Year == 0 {
+			group.Year = track.Year
+		}
+		group.Duration += track.Duration
+
+		number := discNumber(track)
+		disc, ok := discs[key][number]
+		if !ok {
+			disc = &AlbumDisc{Number: number}
+			discs[key][number] = disc
+			group.Discs = append(group.Discs, disc)
+		}
+		if disc.Subtitle == "" {
+			disc.Subtitle = track.DiscSubtitle
+		}
+		disc.Tracks = append(disc.Tracks, track)
+	}
+
+	result := make([]*AlbumGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Slice(group.Discs, func(i, j int) bool {
+			return group.Discs[i].Number < group.Discs[j].Number
+		})
+		for _, disc := range group.Discs {
+			SortAlbumOrder(disc.Tracks)
+		}
+		result = append(result, group)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := FoldText(result[i].Artist), FoldText(result[j].Artist)
+		if a != b {
+			return a < b
+		}
+		return FoldText(result[i].Title) < FoldText(result[j].Title)
+	})
+
+	return result
+}
+
+// SortByRelease orders albums by year, oldest first, with undated albums
+// last. Albums of the same year keep their order.
+func SortByRelease(groups []*AlbumGroup) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i].Year, groups[j].Year
+		if a == 0 || b == 0 {
+			return a != 0 && b == 0
+		}
+		return a < b
+	})
+}
+
+// Tracks returns all of the album's tracks in play order
+func (g *AlbumGroup) Tracks() []*Track {
+	var tracks []*Track
+	for _, disc := range g.Discs {
+		tracks = append(tracks, disc.Tracks...)
+	}
+	return tracks
+}
+
+// TrackCount returns the number of tracks on all discs
+func (g *AlbumGroup) TrackCount() int {
+	count := 0
+	for _, disc := range g.Discs {
+		count += len(disc.Tracks)
+	}
+	return count
+}
+
+// IsMultiDisc reports whether the album spans more than one disc
+func (g *AlbumGroup) IsMultiDisc() bool {
+	return len(g.Discs) > 1
+}
+
+// NeedsAlbumGain reports whether any of the album's tracks lacks album
+// gain from a full analysis, so the album must be measured as a whole
+func (g *AlbumGroup) NeedsAlbumGain() bool {
+	for _, track := range g.Tracks() {
+		if rg := track.ReplayGain; rg == nil || rg.Estimated || rg.AlbumPeak == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// HiddenTracks returns the tracks of the album's other versions
+func (g *AlbumGroup) HiddenTracks() []*Track {
+	var tracks []*Track
+	for _, version := range g.OtherVersions {
+		tracks = append(tracks, version.Tracks...)
+	}
+	return tracks
+}
+
+// PreferVersions keeps only the preferred copy of albums that exist in more
+// than one format, moving the others to OtherVersions. A format counts as a
+// copy only when it repeats tracks of the preferred one, so a bonus track in
+// a different format stays on the album. Groups are left unchanged when the
+// preference is empty.
+func PreferVersions(groups []*AlbumGroup, pref FormatPreference) {
+	if len(pref) == 0 {
+		return
+	}
+
+	for _, group := range groups {
+		group.preferVersion(pref)
+	}
+}
+
+func (g *AlbumGroup) preferVersion(pref FormatPreference) {
+	byFormat := make(map[AudioFormat][]*Track)
+	var formats []AudioFormat
+	for _, track := range g.Tracks() {
+		format := trackFormat(track)
+		if _, ok := byFormat[format]; !ok {
+			formats = append(formats, format)
+		}
+		byFormat[format] = append(byFormat[format], track)
+	}
+	if len(formats) < 2 {
+		return
+	}
+
+	// Best ranked first; among unlisted formats the most complete copy wins
+	sort.Slice(formats, func(i, j int) bool {
+		a, b := formats[i], formats[j]
+		if ra, rb := pref.Rank(a), pref.Rank(b); ra != rb {
+			return ra < rb
+		}
+		if na, nb := len(byFormat[a]), len(byFormat[b]); na != nb {
+			return na > nb
+		}
+		return a < b
+	})
+
+	slots := make(map[string]bool)
+	for _, track := range byFormat[formats[0]] {
+		slots[albumSlot(track)] = true
+	}
+
+	kept := byFormat[formats[0]]
+	var others []*AlbumVersion
+	for _, format := range formats[1:] {
+		tracks := byFormat[format]
+		isCopy := false
+		for _, track := range tracks {
+			if slots[albumSlot(track)] {
+				isCopy = true
+				break
+			}
+		}
+		if !isCopy {
+			kept = append(kept, tracks...)
+			continue
+		}
+		SortAlbumOrder(tracks)
+		others = append(others, &AlbumVersion{Format: format, Tracks: tracks})
+	}
+	if len(others) == 0 {
+		return
+	}
+
+	preferred := GroupAlbums(kept)[0]
+	preferred.OtherVersions = others
+	*g = *preferred
+}
+
+// albumSlot identifies a track's place on an album, so copies of the same
+// song in different formats can be matched
+func albumSlot(track *Track) string {
+	if track.TrackNumber > 0 {
+		return fmt.Sprintf("%d/%d", discNumber(track), track.TrackNumber)
+	}
+	title := track.Title
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(track.FilePath), filepath.Ext(track.FilePath))
+	}
+	return FoldText(title)
+}
+
+func trackFormat(track *Track) AudioFormat {
+	if track.Format != "" {
+		return track.Format
+	}
+	return detectFormat(track.FilePath)
+}
+
+// SortAlbumOrder sorts tracks into album play order: by disc, then track
+// number, then file name for untagged tracks
+func SortAlbumOrder(tracks []*Track) {
+	sort.SliceStable(tracks, func(i, j int) bool {
+		a, b := tracks[i], tracks[j]
+		if da, db := discNumber(a), discNumber(b); da != db {
+			return da < db
+		}
+		if a.TrackNumber != b.TrackNumber {
+			// Untagged tracks go after numbered ones
+			if a.TrackNumber == 0 || b.TrackNumber == 0 {
+				return b.TrackNumber == 0
+			}
+			return a.TrackNumber < b.TrackNumber
+		}
+		return strings.ToLower(a.FilePath) < strings.ToLower(b.FilePath)
+	})
+}
+
+// discNumber treats an untagged disc as disc 1
+func discNumber(track *Track) int {
+	if track.DiscNumber <= 0 {
+		return 1
+	}
+	return track.DiscNumber
+}
@@ -0,0 +1,159 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupAlbums(t *testing.T) {
+	tracks := []*Track{
+		{ID: "d2t1", FilePath: "/m/a/2-01.flac", Album: "Live", AlbumArtist: "Band", DiscNumber: 2, TrackNumber: 1, DiscSubtitle: "Encore"},
+		{ID: "d1t2", FilePath: "/m/a/1-02.flac", Album: "Live", AlbumArtist: "Band", DiscNumber: 1, TrackNumber: 2},
+		{ID: "d1t1", FilePath: "/m/a/1-01.flac", Album: "Live", Artist: "Band", TrackNumber: 1},
+		{ID: "other", FilePath: "/m/b/01.mp3", Album: "Live", AlbumArtist: "Other Band", TrackNumber: 1},
+		{ID: "single", FilePath: "/m/single.mp3", Artist: "Band"},
+	}
+
+	albums := GroupAlbums(tracks)
+	require.Len(t, albums, 2)
+
+	live := albums[0]
+	assert.Equal(t, "Band", live.Artist)
+	assert.True(t, live.IsMultiDisc())
+	assert.Equal(t, 3, live.TrackCount())
+	require.Len(t, live.Discs, 2)
+	assert.Equal(t, 1, live.Discs[0].Number)
+	assert.Equal(t, "Encore", live.Discs[1].Subtitle)
+
+	var order []string
+	for _, track := range live.Tracks() {
+		order = append(order, track.ID)
+	}
+	assert.Equal(t, []string{"d1t1", "d1t2", "d2t1"}, order)
+
+	assert.Equal(t, "Other Band", albums[1].Artist)
+	assert.False(t, albums[1].IsMultiDisc())
+}
+
+func TestSortByRelease(t *testing.T) {
+	groups := []*AlbumGroup{
+		{Title: "Undated"},
+		{Title: "Third", Year: 2001},
+		{Title: "First", Year: 1994},
+		{Title: "Second", Year: 1997},
+		{Title: "Also 1994", Year: 1994},
+	}
+
+	SortByRelease(groups)
+
+	var order []string
+	for _, group := range groups {
+		order = append(order, group.Title)
+	}
+	assert.Equal(t, []string{"First", "Also 1994", "Second", "Third", "Undated"}, order)
+}
+
+func TestSortAlbumOrder(t *testing.T) {
+	tracks := []*Track{
+		{ID: "untagged-b", FilePath: "/m/b.mp3"},
+		{ID: "two", FilePath: "/m/z.mp3", TrackNumber: 2},
+		{ID: "untagged-a", FilePath: "/m/a.mp3"},
+		{ID: "one", FilePath: "/m/y.mp3", TrackNumber: 1},
+	}
+
+	SortAlbumOrder(tracks)
+
+	var order []string
+	for _, track := range tracks {
+		order = append(order, track.ID)
+	}
+	assert.Equal(t, []string{"one", "two", "untagged-a", "untagged-b"}, order)
+}
+
+func TestPreferVersions(t *testing.T) {
+	tracks := []*Track{
+		{ID: "flac1", FilePath: "/m/flac/01.flac", Album: "Blue", Artist: "Band", TrackNumber: 1},
+		{ID: "flac2", FilePath: "/m/flac/02.flac", Album: "Blue", Artist: "Band", TrackNumber: 2},
+		{ID: "mp31", FilePath: "/m/mp3/01.mp3", Album: "Blue", Artist: "Band", TrackNumber: 1},
+		{ID: "mp32", FilePath: "/m/mp3/02.mp3", Album: "Blue", Artist: "Band", TrackNumber: 2},
+		{ID: "bonus", FilePath: "/m/flac/03.m4a", Album: "Blue", Artist: "Band", TrackNumber: 3},
+		{ID: "solo", FilePath: "/m/solo/01.mp3", Album: "Red", Artist: "Band", TrackNumber: 1},
+	}
+
+	trackIDs := func(tracks []*Track) []string {
+		var ids []string
+		for _, track := range tracks {
+			ids = append(ids, track.ID)
+		}
+		return ids
+	}
+
+	t.Run("empty preference keeps every copy", func(t *testing.T) {
+		albums := GroupAlbums(tracks)
+		PreferVersions(albums, nil)
+		assert.Equal(t, 5, albums[0].TrackCount())
+		assert.Empty(t, albums[0].OtherVersions)
+	})
+
+	t.Run("preferred format is shown", func(t *testing.T) {
+		albums := GroupAlbums(tracks)
+		PreferVersions(albums, FormatPreference{FormatFLAC, FormatMP3})
+		require.Len(t, albums, 2)
+
+		blue := albums[0]
+		assert.Equal(t, []string{"flac1", "flac2", "bonus"}, trackIDs(blue.Tracks()))
+		require.Len(t, blue.OtherVersions, 1)
+		assert.Equal(t, FormatMP3, blue.OtherVersions[0].Format)
+		assert.Equal(t, []string{"mp31", "mp32"}, trackIDs(blue.HiddenTracks()))
+
+		assert.Equal(t, 1, albums[1].TrackCount())
+		assert.Empty(t, albums[1].OtherVersions)
+	})
+
+	t.Run("preference order decides", func(t *testing.T) {
+		albums := GroupAlbums(tracks)
+		PreferVersions(albums, FormatPreference{FormatMP3})
+		assert.Equal(t, []string{"mp31", "mp32", "bonus"}, trackIDs(albums[0].Tracks()))
+		assert.Equal(t, []string{"flac1", "flac2"}, trackIDs(albums[0].HiddenTracks()))
+	})
+}
+
+func TestParseFormatPreference(t *testing.T) {
+	pref, err := ParseFormatPreference([]string{"FLAC", ".m4a", "mp3", "flac"})
+	require.NoError(t, err)
+	assert.Equal(t, FormatPreference{FormatFLAC, FormatM4A, FormatMP3}, pref)
+	assert.Equal(t, 1, pref.Rank(FormatM4A))
+	assert.Equal(t, 3, pref.Rank(FormatOGG))
+
+	_, err = ParseFormatPreference([]string{"xyz"})
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestAlbumGroupNeedsAlbumGain(t *testing.T) {
+	analysed := &ReplayGain{TrackGain: -3, TrackPeak: 0.9, AlbumGain: -4, AlbumPeak: 0.95}
+	estimated := &ReplayGain{TrackGain: -3, TrackPeak: 0.9, AlbumGain: -3, AlbumPeak: 0.9, Estimated: true}
+
+	tests := []struct {
+		name     string
+		gains    []*ReplayGain
+		expected bool
+	}{
+		{"all analysed", []*ReplayGain{analysed, analysed}, false},
+		{"new track", []*ReplayGain{analysed, nil}, true},
+		{"estimate only", []*ReplayGain{analysed, estimated}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tracks []*Track
+			for i, gain := range tt.gains {
+				tracks = append(tracks, &Track{Album: "Blue", Artist: "Band", TrackNumber: i + 1, ReplayGain: gain})
+			}
+			albums := GroupAlbums(tracks)
+			require.Len(t, albums, 1)
+			assert.Equal(t, tt.expected, albums[0].NeedsAlbumGain())
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Album is an album release as the library holds it, summed up from its
+// tracks. Albums are told apart by Track.AlbumKey, so the same title by
+// two artists makes two albums.
+type Album struct {
+	Key         string        `json:"key"`
+	Title       string        `json:"title"`
+	AlbumArtist string        `json:"album_artist"` // The album artist, or the track artist when untagged
+	SortTitle   string        `json:"sort_title"`
+	SortArtist  string        `json:"sort_artist"`
+	Year        int           `json:"year"`
+	Genre       string        `json:"genre"`
+	TrackCount  int           `json:"track_count"`
+	DiscCount   int           `json:"disc_count"`
+	Duration    time.Duration `json:"duration"`
+	ArtPath     string        `json:"art_path"`
+	DateAdded   time.Time     `json:"date_added"` // When its newest track was added
+	IsLive      bool          `json:"is_live"`
+}
+
+// Artist is an artist the library has albums or tracks by, listed under
+// the name albums credit them by
+type Artist struct {
+	Name       string `json:"name"`
+	SortName   string `json:"sort_name"`
+	ArtistMBID string `json:"artist_mbid,omitempty"`
+	AlbumCount int    `json:"album_count"`
+	TrackCount int    `json:"track_count"`
+	ArtPath    string `json:"art_path"` // Art of their newest album, until an artist image is fetched
+}
+
+// browseArtist returns the artist a track is listed under when browsing:
+// its album artist, or its own artist
+func (t *Track) browseArtist() string {
+	if t.AlbumArtist != "" {
+		return t.AlbumArtist
+	}
+	return t.Artist
+}
+
+// SummarizeAlbums sums tracks up into albums, ordered by artist and title.
+// Tracks without an album are left out.
+func SummarizeAlbums(tracks []*Track) []*Album {
+	albums := make(map[string]*Album)
+	discs := make(map[string]map[int]bool)
+	live := make(map[string]int)
+
+	for _, track := range tracks {
+		key := track.AlbumKey()
+		if key == "" {
+			continue
+		}
+
+		album, ok := albums[key]
+		if !ok {
+			album = &Album{
+				Key:         key,
+				Title:       track.Album,
+				AlbumArtist: track.browseArtist(),
+				SortTitle:   FoldText(track.Album),
+				SortArtist:  FoldText(track.browseArtist()),
+			}
+			albums[key] = album
+			discs[key] = make(map[int]bool)
+		}
+		if album.Year == 0 {
+			album.Year = track.Year
+		}
+		if album.Genre == "" {
+			album.Genre = track.Genre
+		}
+		if album.ArtPath == "" {
+			album.ArtPath = track.AlbumArtPath
+		}
+		if track.DateAdded.After(album.DateAdded) {
+			album.DateAdded = track.DateAdded
+		}
+		if track.IsLive {
+			live[key]++
+		}
+		album.TrackCount++
+		album.Duration += track.Duration
+		discs[key][discNumber(track)] = true
+	}
+
+	result := make([]*Album, 0, len(albums))
+	for key, album := range albums {
+		album.DiscCount = len(discs[key])
+		album.IsLive = live[key]*2 > album.TrackCount
+		result = append(result, album)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SortArtist != result[j].SortArtist {
+			return result[i].SortArtist < result[j].SortArtist
+		}
+		if result[i].SortTitle != result[j].SortTitle {
+			return result[i].SortTitle < result[j].SortTitle
+		}
+		return result[i].Key < result[j].Key
+	})
+
+	return result
+}
+
+// SummarizeArtists sums tracks up into the artists they are listed under,
+// ordered by name. Names differing only in case are one artist.
+func SummarizeArtists(tracks []*Track) []*Artist {
+	artists := make(map[string]*Artist)
+	albums := make(map[string]map[string]bool)
+	newestYear := make(map[string]int)
+
+	for _, track := range tracks {
+		name := track.browseArtist()
+		if name == "" {
+			continue
+		}
+		key := strings.ToLower(name)
+
+		artist, ok := artists[key]
+		if !ok {
+			artist = &Artist{Name: name, SortName: FoldText(name)}
+			artists[key] = artist
+			albums[key] = make(map[string]bool)
+		}
+		if artist.ArtistMBID == "" && strings.EqualFold(track.Artist, name) {
+			artist.ArtistMBID = track.ArtistMBID
+		}
+		if albumKey := track.AlbumKey(); albumKey != "" {
+			albums[key][albumKey] = true
+			if track.AlbumArtPath != "" && (artist.ArtPath == "" || track.Year > newestYear[key]) {
+				artist.ArtPath = track.AlbumArtPath
+				newestYear[key] = track.Year
+			}
+		}
+		artist.TrackCount++
+	}
+
+	result := make([]*Artist, 0, len(artists))
+	for key, artist := range artists {
+		artist.AlbumCount = len(albums[key])
+		result = append(result, artist)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SortName != result[j].SortName {
+			return result[i].SortName < result[j].SortName
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
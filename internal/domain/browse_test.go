@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeAlbums(t *testing.T) {
+	added := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	tracks := []*Track{
+		{ID: "1", Album: "Post", Artist: "Björk", Year: 1995, Duration: 4 * time.Minute, DateAdded: added},
+		{ID: "2", Album: "Post", Artist: "björk", Year: 1995, Duration: 5 * time.Minute, DateAdded: added.AddDate(0, 1, 0), AlbumArtPath: "art/post.jpg"},
+		{ID: "3", Album: "Hits", AlbumArtist: "Various Artists", Artist: "Björk", DiscNumber: 1},
+		{ID: "4", Album: "Hits", AlbumArtist: "Various Artists", Artist: "Moby", DiscNumber: 2},
+		{ID: "5", Title: "Loose track", Artist: "Björk"},
+	}
+
+	albums := SummarizeAlbums(tracks)
+	require.Len(t, albums, 2)
+
+	post := albums[0]
+	assert.Equal(t, "Post", post.Title)
+	assert.Equal(t, "Björk", post.AlbumArtist)
+	assert.Equal(t, "bjork", post.SortArtist)
+	assert.Equal(t, 2, post.TrackCount)
+	assert.Equal(t, 1, post.DiscCount)
+	assert.Equal(t, 9*time.Minute, post.Duration)
+	assert.Equal(t, "art/post.jpg", post.ArtPath)
+	assert.Equal(t, added.AddDate(0, 1, 0), post.DateAdded)
+	assert.Equal(t, tracks[0].AlbumKey(), post.Key)
+
+	hits := albums[1]
+	assert.Equal(t, "Various Artists", hits.AlbumArtist)
+	assert.Equal(t, 2, hits.DiscCount)
+}
+
+func TestSummarizeArtists(t *testing.T) {
+	tracks := []*Track{
+		{ID: "1", Album: "Debut", Artist: "Björk", ArtistMBID: "mbid", Year: 1993, AlbumArtPath: "art/debut.jpg"},
+		{ID: "2", Album: "Post", Artist: "BJÖRK", Year: 1995, AlbumArtPath: "art/post.jpg"},
+		{ID: "3", Album: "Hits", AlbumArtist: "Various Artists", Artist: "Björk"},
+		{ID: "4", Title: "Loose track", Artist: "Aphex Twin"},
+		{ID: "5", Title: "Untagged"},
+	}
+
+	artists := SummarizeArtists(tracks)
+	require.Len(t, artists, 3)
+	assert.Equal(t, "Aphex Twin", artists[0].Name)
+	assert.Zero(t, artists[0].AlbumCount)
+
+	bjork := artists[1]
+	assert.Equal(t, "Björk", bjork.Name)
+	assert.Equal(t, "mbid", bjork.ArtistMBID)
+	assert.Equal(t, 2, bjork.TrackCount)
+	assert.Equal(t, 2, bjork.AlbumCount)
+	assert.Equal(t, "art/post.jpg", bjork.ArtPath, "newest album's art")
+
+	assert.Equal(t, "Various Artists", artists[2].Name)
+}
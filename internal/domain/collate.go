@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// foldSpecial covers letters that don't decompose into a base letter and
+// a combining mark
+var foldSpecial = map[rune]string{
+	'ß': "ss",
+	'æ': "ae", 'Æ': "ae",
+	'œ': "oe", 'Œ': "oe",
+	'ø': "o", 'Ø': "o",
+	'đ': "d", 'Đ': "d",
+	'ł': "l", 'Ł': "l",
+	'þ': "th", 'Þ': "th",
+	'ı': "i",
+}
+
+// FoldText folds case and diacritics so text sorts and matches the way it
+// reads: "Björk", "BJORK" and "bjork" all fold to "bjork"
+func FoldText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue // Combining accent
+		}
+		if folded, ok := foldSpecial[r]; ok {
+			b.WriteString(folded)
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// UpdateSortKeys recomputes the folded columns used for ordering and search.
+// Repositories call it before saving a track.
+func (t *Track) UpdateSortKeys() {
+	t.SortTitle = FoldText(t.GetDisplayTitle())
+	t.SortArtist = FoldText(t.Artist)
+	t.SortAlbum = FoldText(t.Album)
+
+	// Fields are kept on separate lines so a search cannot match across two
+	t.SearchText = FoldText(strings.Join([]string{
+		t.GetDisplayTitle(),
+		t.Artist,
+		t.AlbumArtist,
+		t.Album,
+		t.Genre,
+		t.Composer,
+		t.Publisher,
+	}, "\n"))
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFoldText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"plain", "Radiohead", "radiohead"},
+		{"diacritics", "Björk", "bjork"},
+		{"upper case accents", "ÉDITH PIAF", "edith piaf"},
+		{"special letters", "Sigur Rós, Mötley Crüe, Straße, Ørsted", "sigur ros, motley crue, strasse, orsted"},
+		{"non-latin kept", "坂本龍一", "坂本龍一"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FoldText(tt.input))
+		})
+	}
+}
+
+func TestTrack_UpdateSortKeys(t *testing.T) {
+	track := &Track{FilePath: "/music/song.mp3", Title: "Jóga", Artist: "Björk", Album: "Homogenic"}
+	track.UpdateSortKeys()
+
+	assert.Equal(t, "joga", track.SortTitle)
+	assert.Equal(t, "bjork", track.SortArtist)
+	assert.Equal(t, "homogenic", track.SortAlbum)
+	assert.Contains(t, track.SearchText, "bjork")
+	assert.NotContains(t, track.SearchText, "joga bjork")
+}
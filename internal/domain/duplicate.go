@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidDuplicatePolicy = errors.New("invalid duplicate policy")
+
+// DuplicatePolicy decides what an import does with a file that is already
+// in the library, either at the same path or as a copy elsewhere
+type DuplicatePolicy string
+
+const (
+	// DuplicateSkip re-reads library files only when they have changed and
+	// does not import copies of library tracks
+	DuplicateSkip DuplicatePolicy = "skip"
+	// DuplicateUpdateInPlace re-reads library files even when unchanged, to
+	// pick up tag edits made elsewhere, and does not import copies
+	DuplicateUpdateInPlace DuplicatePolicy = "update_in_place"
+	// DuplicateKeepBoth imports copies as tracks of their own
+	DuplicateKeepBoth DuplicatePolicy = "keep_both"
+	// DuplicatePreferQuality keeps the better of two copies. When the new
+	// file is better the library track moves to it, keeping its ratings and
+	// history; otherwise the new file is not imported.
+	DuplicatePreferQuality DuplicatePolicy = "prefer_higher_quality"
+)
+
+// duplicateDurationSlack is how far apart the lengths of two copies of a
+// recording may be, as encoders pad differently
+const duplicateDurationSlack = 2 * time.Second
+
+// ParseDuplicatePolicy parses a policy name, with an empty name meaning
+// DuplicateSkip
+func ParseDuplicatePolicy(name string) (DuplicatePolicy, error) {
+	switch policy := DuplicatePolicy(strings.ToLower(strings.TrimSpace(name))); policy {
+	case "":
+		return DuplicateSkip, nil
+	case DuplicateSkip, DuplicateUpdateInPlace, DuplicateKeepBoth, DuplicatePreferQuality:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidDuplicatePolicy, name)
+	}
+}
+
+// IsLossless reports whether the format stores audio without lossy
+// compression
+func (f AudioFormat) IsLossless() bool {
+	return f == FormatFLAC || f == FormatWAV || f == FormatAIFF
+}
+
+// IsDuplicateOf reports whether two tracks at different paths are copies of
+// the same recording: identical audio, or the same title, artist and album
+// with lengths that nearly match
+func (t *Track) IsDuplicateOf(other *Track) bool {
+	if other == nil || t.FilePath == other.FilePath {
+		return false
+	}
+	if t.Checksum != "" && t.Checksum == other.Checksum {
+		return true
+	}
+	if t.Title == "" || t.Artist == "" {
+		return false
+	}
+
+	if FoldText(t.Title) != FoldText(other.Title) ||
+		FoldText(t.Artist) != FoldText(other.Artist) ||
+		FoldText(t.Album) != FoldText(other.Album) {
+		return false
+	}
+
+	diff := t.Duration - other.Duration
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= duplicateDurationSlack
+}
+
+// IsMoveOf reports whether the track's file could be another track's file
+// at a new path: the same size, length and audio. Acoustic fingerprints
+// must agree too when both tracks have one.
+func (t *Track) IsMoveOf(other *Track) bool {
+	if other == nil || t.FilePath == other.FilePath || t.Checksum == "" {
+		return false
+	}
+	if t.Checksum != other.Checksum || t.FileSize != other.FileSize {
+		return false
+	}
+	if t.Fingerprint != "" && other.Fingerprint != "" && t.Fingerprint != other.Fingerprint {
+		return false
+	}
+
+	diff := t.Duration - other.Duration
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= duplicateDurationSlack
+}
+
+// SoundsLike reports whether the track's audio fingerprint and length
+// match another track's at a different path. Files whose checksum covers
+// their tags get a new checksum when retagged, but keep their fingerprint.
+func (t *Track) SoundsLike(other *Track) bool {
+	if other == nil || t.FilePath == other.FilePath || t.Fingerprint == "" || t.Fingerprint != other.Fingerprint {
+		return false
+	}
+
+	diff := t.Duration - other.Duration
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= duplicateDurationSlack
+}
+
+// BetterQualityThan reports whether the track is a better copy than
+// another: lossless beats lossy, then the higher bitrate wins, then the
+// higher sample rate
+func (t *Track) BetterQualityThan(other *Track) bool {
+	if a, b := t.Format.IsLossless(), other.Format.IsLossless(); a != b {
+		return a
+	}
+	if t.Bitrate != other.Bitrate {
+		return t.Bitrate > other.Bitrate
+	}
+	return t.SampleRate > other.SampleRate
+}
+
+// TakeFileFrom points the track at another copy of the same recording,
+// keeping the track's identity, tags and play history
+func (t *Track) TakeFileFrom(other *Track) {
+	t.SetFilePath(other.FilePath)
+	t.Format = other.Format
+	t.FileSize = other.FileSize
+	t.FileModTime = other.FileModTime
+	t.Checksum = other.Checksum
+	t.Duration = other.Duration
+	t.Bitrate = other.Bitrate
+	t.SampleRate = other.SampleRate
+	t.Channels = other.Channels
+	if other.AlbumArtPath != "" {
+		t.AlbumArtPath = other.AlbumArtPath
+	}
+	t.MarkValid()
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected DuplicatePolicy
+		wantErr  bool
+	}{
+		{"", DuplicateSkip, false},
+		{"skip", DuplicateSkip, false},
+		{" Keep_Both ", DuplicateKeepBoth, false},
+		{"prefer_higher_quality", DuplicatePreferQuality, false},
+		{"overwrite", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParseDuplicatePolicy(tt.name)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidDuplicatePolicy)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, policy)
+		})
+	}
+}
+
+func TestTrackIsDuplicateOf(t *testing.T) {
+	original := &Track{
+		FilePath: "/music/a/song.mp3",
+		Title:    "Song",
+		Artist:   "Artist",
+		Album:    "Album",
+		Duration: 200 * time.Second,
+		Checksum: "abc",
+	}
+
+	tests := []struct {
+		name     string
+		other    *Track
+		expected bool
+	}{
+		{"same checksum", &Track{FilePath: "/music/b/x.flac", Checksum: "abc"}, true},
+		{"same tags", &Track{FilePath: "/music/b/song.flac", Title: "song", Artist: "ARTIST", Album: "Album", Duration: 201 * time.Second}, true},
+		{"different length", &Track{FilePath: "/music/b/song.flac", Title: "Song", Artist: "Artist", Album: "Album", Duration: 260 * time.Second}, false},
+		{"different album", &Track{FilePath: "/music/b/song.flac", Title: "Song", Artist: "Artist", Album: "Live", Duration: 200 * time.Second}, false},
+		{"same path", &Track{FilePath: "/music/a/song.mp3", Checksum: "abc"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, original.IsDuplicateOf(tt.other))
+		})
+	}
+}
+
+func TestTrackBetterQualityThan(t *testing.T) {
+	mp3 := &Track{Format: FormatMP3, Bitrate: 320, SampleRate: 44100}
+	lowMP3 := &Track{Format: FormatMP3, Bitrate: 128, SampleRate: 44100}
+	flac := &Track{Format: FormatFLAC, Bitrate: 900, SampleRate: 44100}
+
+	assert.True(t, flac.BetterQualityThan(mp3))
+	assert.False(t, mp3.BetterQualityThan(flac))
+	assert.True(t, mp3.BetterQualityThan(lowMP3))
+	assert.False(t, mp3.BetterQualityThan(mp3))
+}
+
+func TestTrackIsMoveOf(t *testing.T) {
+	moved := &Track{FilePath: "/music/new/song.mp3", FileSize: 4000, Checksum: "abc", Duration: 200 * time.Second}
+
+	tests := []struct {
+		name     string
+		other    *Track
+		expected bool
+	}{
+		{"same file", &Track{FilePath: "/music/old/song.mp3", FileSize: 4000, Checksum: "abc", Duration: 200 * time.Second}, true},
+		{"retagged", &Track{FilePath: "/music/old/song.mp3", FileSize: 4100, Checksum: "abc", Duration: 200 * time.Second}, false},
+		{"other audio", &Track{FilePath: "/music/old/song.mp3", FileSize: 4000, Checksum: "def", Duration: 200 * time.Second}, false},
+		{"fingerprint on one side only", &Track{FilePath: "/music/old/song.mp3", FileSize: 4000, Checksum: "abc", Duration: 200 * time.Second, Fingerprint: "x"}, true},
+		{"same path", &Track{FilePath: "/music/new/song.mp3", FileSize: 4000, Checksum: "abc", Duration: 200 * time.Second}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, moved.IsMoveOf(tt.other))
+		})
+	}
+
+	moved.Fingerprint = "y"
+	assert.False(t, moved.IsMoveOf(tests[3].other), "fingerprints must agree when both are known")
+}
+
+func TestTrackSoundsLike(t *testing.T) {
+	moved := &Track{FilePath: "/music/new/song.m4a", Checksum: "abc", Fingerprint: "AQAD", Duration: 200 * time.Second}
+
+	assert.True(t, moved.SoundsLike(&Track{FilePath: "/music/old/song.m4a", Checksum: "def", Fingerprint: "AQAD", Duration: 201 * time.Second}))
+	assert.False(t, moved.SoundsLike(&Track{FilePath: "/music/old/song.m4a", Fingerprint: "AQAE", Duration: 200 * time.Second}))
+	assert.False(t, moved.SoundsLike(&Track{FilePath: "/music/old/song.m4a", Fingerprint: "AQAD", Duration: 230 * time.Second}))
+	assert.False(t, moved.SoundsLike(&Track{FilePath: "/music/new/song.m4a", Fingerprint: "AQAD", Duration: 200 * time.Second}))
+	assert.False(t, (&Track{FilePath: "/a.mp3"}).SoundsLike(&Track{FilePath: "/b.mp3"}), "unknown fingerprints don't match")
+}
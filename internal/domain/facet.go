@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Facet is a property the library view can be narrowed by, shown as a row
+// of filter chips with how many tracks each would leave
+type Facet string
+
+const (
+	FacetGenre    Facet = "genre"
+	FacetDecade   Facet = "decade"
+	FacetFormat   Facet = "format"
+	FacetRating   Facet = "rating"
+	FacetLanguage Facet = "language"
+)
+
+// Facets lists the facets in the order the library view shows them
+var Facets = []Facet{FacetGenre, FacetDecade, FacetFormat, FacetRating, FacetLanguage}
+
+// TrackFilter narrows the library to tracks matching every facet it sets,
+// and any of the values set for a facet. An empty filter matches every
+// track.
+type TrackFilter struct {
+	Query   string        `json:"query"`   // Matched like a search
+	Genres  []string      `json:"genres"`  // Compared ignoring case
+	Decades []int         `json:"decades"` // By first year, so 1990 for the 1990s
+	Formats []AudioFormat `json:"formats"`
+	Ratings []int         `json:"ratings"` // 0 for unrated
+
+	Languages    []string `json:"languages"`    // Compared ignoring case
+	HideExplicit bool     `json:"hideExplicit"` // Family mode
+}
+
+// Validate checks the filter's values are ones tracks can have
+func (f TrackFilter) Validate() error {
+	for _, decade := range f.Decades {
+		if decade <= 0 || decade%10 != 0 {
+			return fmt.Errorf("%w: decade %d must be a year ending in 0", ErrInvalidInput, decade)
+		}
+	}
+	for _, format := range f.Formats {
+		if !slices.Contains(GetSupportedFormats(), format) {
+			return fmt.Errorf("%w: unknown format %q", ErrInvalidInput, format)
+		}
+	}
+	for _, rating := range f.Ratings {
+		if rating < 0 || rating > 5 {
+			return fmt.Errorf("%w: rating %d must be 0-5", ErrInvalidInput, rating)
+		}
+	}
+	return nil
+}
+
+// IsEmpty reports whether the filter matches every track
+func (f TrackFilter) IsEmpty() bool {
+	return strings.TrimSpace(f.Query) == "" && len(f.Genres) == 0 && len(f.Decades) == 0 &&
+		len(f.Formats) == 0 && len(f.Ratings) == 0 && len(f.Languages) == 0 && !f.HideExplicit
+}
+
+// FacetCount is one value of a facet and how many tracks have it
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// FacetCounts are the values of each facet among the tracks a filter
+// matches. A facet's own values in the filter are left out when counting
+// it, so its other chips show what choosing them as well would add.
+type FacetCounts map[Facet][]FacetCount
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackFilterValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter TrackFilter
+		valid  bool
+	}{
+		{"empty", TrackFilter{}, true},
+		{"every facet", TrackFilter{Genres: []string{"Jazz"}, Decades: []int{1960}, Formats: []AudioFormat{FormatFLAC}, Ratings: []int{0, 5}}, true},
+		{"odd decade", TrackFilter{Decades: []int{1965}}, false},
+		{"unknown format", TrackFilter{Formats: []AudioFormat{"tape"}}, false},
+		{"rating too high", TrackFilter{Ratings: []int{6}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.Validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidInput)
+			}
+		})
+	}
+
+	assert.True(t, TrackFilter{Query: "  "}.IsEmpty())
+	assert.False(t, TrackFilter{Ratings: []int{0}}.IsEmpty())
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// PlayHistoryEntry records one playback of a track. The sequence of entries
+// drives recommendations through co-play statistics.
+type PlayHistoryEntry struct {
+	ID       string    `json:"id" gorm:"primaryKey"`
+	TrackID  string    `json:"track_id" gorm:"index;not null"`
+	PlayedAt time.Time `json:"played_at" gorm:"index"`
+}
+
+func NewPlayHistoryEntry(trackID string) *PlayHistoryEntry {
+	return &PlayHistoryEntry{
+		ID:       generateHistoryID(),
+		TrackID:  trackID,
+		PlayedAt: time.Now(),
+	}
+}
+
+func generateHistoryID() string {
+	return fmt.Sprintf("play_%d_%d", time.Now().UnixNano(), randomInt())
+}
+
+type PlayHistoryRepository interface {
+	Record(entry *PlayHistoryEntry) error
+	FindSince(since time.Time) ([]*PlayHistoryEntry, error)
+	FindBetween(from, to time.Time) ([]*PlayHistoryEntry, error)
+	FindRecent(limit int) ([]*PlayHistoryEntry, error)
+	FindByTrack(trackID string, limit int) ([]*PlayHistoryEntry, error)
+}
@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidImportRule  = errors.New("invalid import rule")
+	ErrImportRuleNotFound = errors.New("import rule not found")
+)
+
+// ImportAction is what an import rule does to a newly imported track
+type ImportAction string
+
+const (
+	ImportAddToPlaylist ImportAction = "add_to_playlist" // Value is the playlist ID
+	ImportAddTag        ImportAction = "add_tag"         // Value is the user tag name
+	ImportSetGenre      ImportAction = "set_genre"       // Value is the genre
+	ImportMarkAudiobook ImportAction = "mark_audiobook"  // Value is unused
+)
+
+// ImportRule is attached to a watch folder and applied by the scanner to
+// each new file found under it, e.g. to drop downloads into an "Inbox"
+// playlist. Rules apply to subfolders too.
+type ImportRule struct {
+	ID        string       `json:"id" gorm:"primaryKey"`
+	Folder    string       `json:"folder" gorm:"index;not null"`
+	Action    ImportAction `json:"action" gorm:"not null"`
+	Value     string       `json:"value"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+func NewImportRule(folder string, action ImportAction, value string) (*ImportRule, error) {
+	rule := &ImportRule{
+		ID:        generateImportRuleID(),
+		Folder:    filepath.Clean(folder),
+		Action:    action,
+		Value:     strings.TrimSpace(value),
+		CreatedAt: time.Now(),
+	}
+
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (r *ImportRule) Validate() error {
+	if r.Folder == "" || r.Folder == "." {
+		return fmt.Errorf("%w: folder is required", ErrInvalidImportRule)
+	}
+
+	switch r.Action {
+	case ImportAddToPlaylist, ImportAddTag, ImportSetGenre:
+		if r.Value == "" {
+			return fmt.Errorf("%w: %s needs a value", ErrInvalidImportRule, r.Action)
+		}
+	case ImportMarkAudiobook:
+	default:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidImportRule, r.Action)
+	}
+
+	return nil
+}
+
+// AppliesTo reports whether a file is inside the rule's folder, ignoring
+// case since watch folders are usually on Windows drives
+func (r *ImportRule) AppliesTo(path string) bool {
+	rel, err := filepath.Rel(strings.ToLower(r.Folder), strings.ToLower(filepath.Clean(path)))
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ApplyToTrack applies rules that change the track itself, returning false
+// for actions that need other parts of the library, such as playlists
+func (r *ImportRule) ApplyToTrack(track *Track) bool {
+	switch r.Action {
+	case ImportSetGenre:
+		track.Genre = r.Value
+	case ImportMarkAudiobook:
+		track.IsAudiobook = true
+	default:
+		return false
+	}
+	return true
+}
+
+func generateImportRuleID() string {
+	return fmt.Sprintf("rule_%d_%d", time.Now().UnixNano(), randomInt())
+}
+
+type ImportRuleRepository interface {
+	Create(rule *ImportRule) error
+	Delete(id string) error
+	FindAll() ([]*ImportRule, error)
+	FindByFolder(folder string) ([]*ImportRule, error)
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewImportRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		folder  string
+		action  ImportAction
+		value   string
+		wantErr bool
+	}{
+		{"playlist", "/downloads", ImportAddToPlaylist, "pl_1", false},
+		{"genre", "/downloads", ImportSetGenre, " Ambient ", false},
+		{"audiobook needs no value", "/books", ImportMarkAudiobook, "", false},
+		{"missing folder", "", ImportSetGenre, "Rock", true},
+		{"missing value", "/downloads", ImportAddTag, "", true},
+		{"unknown action", "/downloads", ImportAction("delete"), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := NewImportRule(tt.folder, tt.action, tt.value)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidImportRule)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, rule.ID)
+		})
+	}
+}
+
+func TestImportRuleAppliesTo(t *testing.T) {
+	rule, err := NewImportRule(filepath.FromSlash("/music/Downloads"), ImportSetGenre, "New")
+	require.NoError(t, err)
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/music/Downloads/song.mp3", true},
+		{"/music/downloads/album/song.flac", true},
+		{"/music/Downloads2/song.mp3", false},
+		{"/music/song.mp3", false},
+		{"/music/Downloads", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.expected, rule.AppliesTo(filepath.FromSlash(tt.path)))
+		})
+	}
+}
+
+func TestImportRuleApplyToTrack(t *testing.T) {
+	track := &Track{Genre: "Rock"}
+
+	genre := &ImportRule{Action: ImportSetGenre, Value: "Podcast"}
+	assert.True(t, genre.ApplyToTrack(track))
+	assert.Equal(t, "Podcast", track.Genre)
+
+	book := &ImportRule{Action: ImportMarkAudiobook}
+	assert.True(t, book.ApplyToTrack(track))
+	assert.True(t, track.IsAudiobook)
+
+	playlist := &ImportRule{Action: ImportAddToPlaylist, Value: "pl_1"}
+	assert.False(t, playlist.ApplyToTrack(track))
+}
@@ -12,6 +12,7 @@ var (
 	ErrLibraryNotInitialized = errors.New("library not initialized")
 	ErrDuplicateLibraryPath  = errors.New("path already exists in library")
 	ErrInvalidLibraryPath    = errors.New("invalid library path")
+	ErrWatchFolderNotFound   = errors.New("watch folder not found")
 )
 
 type Library struct {
@@ -43,8 +44,9 @@ type WatchFolder struct {
 	IsRecursive  bool      `json:"is_recursive" gorm:"default:true"`
 	IsEnabled    bool      `json:"is_enabled" gorm:"default:true"`
 	IncludeHidden bool     `json:"include_hidden" gorm:"default:false"`
-	FilePatterns []string  `json:"file_patterns" gorm:"type:json"` // e.g., ["*.mp3", "*.flac"]
-	ExcludePatterns []string `json:"exclude_patterns" gorm:"type:json"`
+	FilePatterns []string  `json:"file_patterns" gorm:"type:json;serializer:json"` // e.g., ["*.mp3", "*.flac"]
+	ExcludePatterns []string `json:"exclude_patterns" gorm:"type:json;serializer:json"`
+	DuplicatePolicy DuplicatePolicy `json:"duplicate_policy"` // Empty uses the library default
 	LastScanned  *time.Time `json:"last_scanned"`
 	CreatedAt    time.Time `json:"created_at"`
 }
@@ -113,6 +115,18 @@ func DefaultLibrarySettings() LibrarySettings {
 	}
 }
 
+// NewWatchFolder creates settings for a watch folder, which follow the
+// scanner's defaults until changed
+func NewWatchFolder(path string) *WatchFolder {
+	return &WatchFolder{
+		ID:          generateWatchFolderID(),
+		Path:        filepath.Clean(path),
+		IsRecursive: true,
+		IsEnabled:   true,
+		CreatedAt:   time.Now(),
+	}
+}
+
 func (l *Library) AddWatchFolder(path string, recursive bool) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -137,7 +151,7 @@ func (l *Library) AddWatchFolder(path string, recursive bool) error {
 		IsRecursive: recursive,
 		IsEnabled:   true,
 		CreatedAt:   time.Now(),
-		FilePatterns: []string{"*.mp3", "*.flac", "*.ogg", "*.wav", "*.aac", "*.wma", "*.m4a", "*.opus"},
+		FilePatterns: []string{"*.mp3", "*.flac", "*.ogg", "*.wav", "*.aiff", "*.aif", "*.aac", "*.wma", "*.m4a", "*.opus"},
 	}
 
 	l.WatchFolders = append(l.WatchFolders, watchFolder)
@@ -380,4 +394,9 @@ type LibraryRepository interface {
 	GetDefault() (*Library, error)
 	SetDefault(id string) error
 	UpdateStatistics(library *Library) error
+}
+
+type WatchFolderRepository interface {
+	Save(folder *WatchFolder) error
+	FindByPath(path string) (*WatchFolder, error)
 }
\ No newline at end of file
@@ -0,0 +1,55 @@
+package domain
+
+import "strings"
+
+// liveMarkers are folded phrases in titles and album names that mark a
+// recording as live, such as "Song (Live)" or "Live at Wembley"
+var liveMarkers = []string{
+	"(live", "[live", "- live", "live at ", "live in ", "live from ",
+	"live on ", "unplugged", "in concert",
+}
+
+// LooksLive reports whether a track's title, album, disc subtitle or genre
+// mark it as a live recording
+func (t *Track) LooksLive() bool {
+	if FoldText(strings.TrimSpace(t.Genre)) == "live" {
+		return true
+	}
+	for _, text := range []string{t.Title, t.Album, t.DiscSubtitle} {
+		folded := FoldText(strings.TrimSpace(text))
+		if folded == "live" {
+			return true
+		}
+		for _, marker := range liveMarkers {
+			if strings.Contains(folded, marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsLive reports whether most of the album's tracks are live recordings,
+// so an album with a live bonus track still counts as a studio album
+func (g *AlbumGroup) IsLive() bool {
+	tracks := g.Tracks()
+	live := 0
+	for _, track := range tracks {
+		if track.IsLive {
+			live++
+		}
+	}
+	return live > 0 && live*2 > len(tracks)
+}
+
+// SplitLive separates live albums from the others, keeping their order
+func SplitLive(groups []*AlbumGroup) (studio, live []*AlbumGroup) {
+	for _, group := range groups {
+		if group.IsLive() {
+			live = append(live, group)
+		} else {
+			studio = append(studio, group)
+		}
+	}
+	return studio, live
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackLooksLive(t *testing.T) {
+	tests := []struct {
+		name  string
+		track Track
+		want  bool
+	}{
+		{"title suffix", Track{Title: "Alive (Live)"}, true},
+		{"bracketed", Track{Title: "Jeremy [Live in Seattle]"}, true},
+		{"dash suffix", Track{Title: "Creep - Live"}, true},
+		{"album", Track{Title: "Lithium", Album: "Live at Reading"}, true},
+		{"album named live", Track{Title: "Roxanne", Album: "Live"}, true},
+		{"unplugged", Track{Title: "About a Girl", Album: "MTV Unplugged in New York"}, true},
+		{"disc subtitle", Track{Title: "One", DiscSubtitle: "In Concert, 1992"}, true},
+		{"genre", Track{Title: "Song", Genre: "Live"}, true},
+		{"word inside another", Track{Title: "Alive", Album: "Delivery"}, false},
+		{"live as a verb", Track{Title: "Live and Let Die"}, false},
+		{"studio", Track{Title: "Song", Album: "Album"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.track.LooksLive())
+		})
+	}
+}
+
+func TestSplitLive(t *testing.T) {
+	groups := GroupAlbums([]*Track{
+		{ID: "1", Album: "Studio", Artist: "Band", TrackNumber: 1},
+		{ID: "2", Album: "Studio", Artist: "Band", TrackNumber: 2, IsLive: true},
+		{ID: "3", Album: "Concert", Artist: "Band", TrackNumber: 1, IsLive: true},
+		{ID: "4", Album: "Concert", Artist: "Band", TrackNumber: 2, IsLive: true},
+	})
+
+	studio, live := SplitLive(groups)
+	assert.Len(t, studio, 1)
+	assert.Equal(t, "Studio", studio[0].Title, "a live bonus track doesn't make a live album")
+	assert.Len(t, live, 1)
+	assert.Equal(t, "Concert", live[0].Title)
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidMarker  = errors.New("invalid marker")
+	ErrMarkerNotFound = errors.New("marker not found")
+)
+
+type MarkerType string
+
+const (
+	MarkerTypeChapter  MarkerType = "chapter"
+	MarkerTypeBookmark MarkerType = "bookmark"
+	MarkerTypeSilence  MarkerType = "silence"
+)
+
+// TrackMarker is a point or region of interest within a track, used by the
+// seek bar to render chapter ticks, user bookmarks and silent passages
+type TrackMarker struct {
+	ID        string        `json:"id" gorm:"primaryKey"`
+	TrackID   string        `json:"track_id" gorm:"index;not null"`
+	Type      MarkerType    `json:"type" gorm:"index"`
+	Label     string        `json:"label"`
+	Position  time.Duration `json:"position"`
+	End       time.Duration `json:"end"` // Zero for point markers
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+func NewTrackMarker(trackID string, markerType MarkerType, position time.Duration, label string) (*TrackMarker, error) {
+	marker := &TrackMarker{
+		ID:        generateMarkerID(),
+		TrackID:   trackID,
+		Type:      markerType,
+		Label:     label,
+		Position:  position,
+		CreatedAt: time.Now(),
+	}
+
+	if err := marker.Validate(); err != nil {
+		return nil, err
+	}
+
+	return marker, nil
+}
+
+func (m *TrackMarker) Validate() error {
+	if m.TrackID == "" {
+		return fmt.Errorf("%w: track ID is required", ErrInvalidMarker)
+	}
+
+	switch m.Type {
+	case MarkerTypeChapter, MarkerTypeBookmark, MarkerTypeSilence:
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidMarker, m.Type)
+	}
+
+	if m.Position < 0 {
+		return fmt.Errorf("%w: position cannot be negative", ErrInvalidMarker)
+	}
+
+	if m.End != 0 && m.End < m.Position {
+		return fmt.Errorf("%w: end is before position", ErrInvalidMarker)
+	}
+
+	return nil
+}
+
+// IsRegion returns true if the marker spans a range rather than a point
+func (m *TrackMarker) IsRegion() bool {
+	return m.End > m.Position
+}
+
+func generateMarkerID() string {
+	return fmt.Sprintf("marker_%d_%d", time.Now().UnixNano(), randomInt())
+}
+
+type MarkerRepository interface {
+	Create(marker *TrackMarker) error
+	Delete(id string) error
+	FindByTrack(trackID string) ([]*TrackMarker, error)
+	DeleteByTrack(trackID string, markerType MarkerType) error
+}
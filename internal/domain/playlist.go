@@ -1,8 +1,11 @@
 package domain
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -31,6 +34,7 @@ type Playlist struct {
 	TrackIDs    []string     `json:"track_ids" gorm:"-"` // For efficient storage
 	TrackOrder  string       `json:"track_order" gorm:"type:text"` // Comma-separated track IDs for order
 	Rules       *SmartRules  `json:"rules,omitempty" gorm:"embedded"` // For smart playlists
+	Generator   string       `json:"generator,omitempty"`             // Builds a smart playlist from listening history instead of rules
 	IsPublic    bool         `json:"is_public" gorm:"default:false"`
 	IsFavorite  bool         `json:"is_favorite" gorm:"default:false"`
 	ImagePath   string       `json:"image_path"`
@@ -54,7 +58,7 @@ type SmartRules struct {
 }
 
 type RuleCondition struct {
-	Field    string      `json:"field"`    // artist, album, genre, year, rating, etc.
+	Field    string      `json:"field"`    // artist, album, genre, composer, publisher, tag, year, rating, etc.; see Track.FieldValue
 	Operator string      `json:"operator"` // equals, contains, greater, less, between
 	Value    interface{} `json:"value"`
 	AndOr    string      `json:"and_or"` // AND or OR for combining conditions
@@ -96,8 +100,8 @@ func (p *Playlist) Validate() error {
 		p.Type = PlaylistTypeStatic
 	}
 
-	if p.Type == PlaylistTypeSmart && p.Rules == nil {
-		return fmt.Errorf("%w: smart playlist requires rules", ErrInvalidPlaylist)
+	if p.Type == PlaylistTypeSmart && p.Rules == nil && p.Generator == "" {
+		return fmt.Errorf("%w: smart playlist requires rules or a generator", ErrInvalidPlaylist)
 	}
 
 	return nil
@@ -222,6 +226,30 @@ func (p *Playlist) Clear() {
 	p.incrementVersion()
 }
 
+// SetTracks replaces the playlist's tracks, as when a smart playlist is
+// refreshed
+func (p *Playlist) SetTracks(tracks []*Track) {
+	p.Tracks = make([]*Track, len(tracks))
+	p.TrackIDs = make([]string, len(tracks))
+	for i, track := range tracks {
+		p.Tracks[i] = track
+		p.TrackIDs[i] = track.ID
+	}
+	p.updateMetadata()
+	p.incrementVersion()
+}
+
+// IsGenerated returns true if the playlist's tracks come from a generator
+func (p *Playlist) IsGenerated() bool {
+	return p.Type == PlaylistTypeSmart && p.Generator != ""
+}
+
+// IsRuleBased returns true if the playlist's tracks are those meeting its
+// rules
+func (p *Playlist) IsRuleBased() bool {
+	return p.Type == PlaylistTypeSmart && p.Generator == "" && p.Rules != nil
+}
+
 func (p *Playlist) GetTrackAt(position int) (*Track, error) {
 	if position < 0 || position >= len(p.Tracks) {
 		return nil, fmt.Errorf("%w: position %d out of range", ErrInvalidPosition, position)
@@ -251,10 +279,61 @@ func (p *Playlist) Shuffle() {
 	p.incrementVersion()
 }
 
-func (p *Playlist) Sort(field string, descending bool) {
-	// Implementation would sort tracks based on field
-	// This is a placeholder - actual implementation would use sort.Slice
+// Sort orders the playlist's tracks by a field: title, artist, album,
+// duration, year, rating, play_count or date_added. Text is compared
+// ignoring case and accents. Tracks that tie stay in album order for
+// artist and album, and otherwise keep their places.
+func (p *Playlist) Sort(field string, descending bool) error {
+	field = strings.ToLower(field)
+	compare, ok := playlistSortFields[field]
+	if !ok {
+		return fmt.Errorf("%w: cannot sort by %q", ErrInvalidInput, field)
+	}
+	
+	slices.SortStableFunc(p.Tracks, func(a, b *Track) int {
+		order := compare(a, b)
+		if descending {
+			order = -order
+		}
+		if order == 0 && (field == "artist" || field == "album") {
+			order = compareAlbumOrder(a, b)
+		}
+		return order
+	})
+	
+	p.TrackIDs = make([]string, len(p.Tracks))
+	for i, track := range p.Tracks {
+		p.TrackIDs[i] = track.ID
+	}
 	p.incrementVersion()
+	return nil
+}
+
+// playlistSortFields compares tracks by each field a playlist sorts by
+var playlistSortFields = map[string]func(a, b *Track) int{
+	"title": func(a, b *Track) int {
+		return strings.Compare(FoldText(a.GetDisplayTitle()), FoldText(b.GetDisplayTitle()))
+	},
+	"artist": func(a, b *Track) int {
+		return strings.Compare(FoldText(a.Artist), FoldText(b.Artist))
+	},
+	"album": func(a, b *Track) int {
+		return strings.Compare(FoldText(a.Album), FoldText(b.Album))
+	},
+	"duration":   func(a, b *Track) int { return cmp.Compare(a.Duration, b.Duration) },
+	"year":       func(a, b *Track) int { return cmp.Compare(a.Year, b.Year) },
+	"rating":     func(a, b *Track) int { return cmp.Compare(a.Rating, b.Rating) },
+	"play_count": func(a, b *Track) int { return cmp.Compare(a.PlayCount, b.PlayCount) },
+	"date_added": func(a, b *Track) int { return a.DateAdded.Compare(b.DateAdded) },
+}
+
+// compareAlbumOrder orders tracks by album, then as they play on it
+func compareAlbumOrder(a, b *Track) int {
+	return cmp.Or(
+		strings.Compare(FoldText(a.Album), FoldText(b.Album)),
+		cmp.Compare(a.DiscNumber, b.DiscNumber),
+		cmp.Compare(a.TrackNumber, b.TrackNumber),
+	)
 }
 
 func (p *Playlist) Clone() *Playlist {
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaylist_Sort(t *testing.T) {
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracks := []*Track{
+		{ID: "b2", Title: "Zebra", Artist: "Björk", Album: "Post", TrackNumber: 2, Year: 1995, Rating: 3, Duration: 200 * time.Second, DateAdded: day},
+		{ID: "a", Title: "apple", Artist: "ABBA", Album: "Gold", TrackNumber: 1, Year: 1992, Rating: 5, PlayCount: 7, Duration: 180 * time.Second, DateAdded: day.AddDate(0, 0, 2)},
+		{ID: "b1", Title: "Éclair", Artist: "bjork", Album: "Post", TrackNumber: 1, Year: 1995, Rating: 3, PlayCount: 2, Duration: 240 * time.Second, DateAdded: day.AddDate(0, 0, 1)},
+	}
+
+	tests := []struct {
+		field      string
+		descending bool
+		want       []string
+	}{
+		{"title", false, []string{"a", "b1", "b2"}},
+		{"title", true, []string{"b2", "b1", "a"}},
+		{"artist", false, []string{"a", "b1", "b2"}}, // Björk ties with bjork, then track order
+		{"artist", true, []string{"b1", "b2", "a"}},
+		{"album", false, []string{"a", "b1", "b2"}},
+		{"duration", false, []string{"a", "b2", "b1"}},
+		{"year", true, []string{"b2", "b1", "a"}}, // Ties keep their places
+		{"rating", true, []string{"a", "b2", "b1"}},
+		{"play_count", true, []string{"a", "b1", "b2"}},
+		{"Date_Added", false, []string{"b2", "b1", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			playlist, err := NewPlaylist("Mix", PlaylistTypeStatic)
+			require.NoError(t, err)
+			playlist.SetTracks(tracks)
+			version := playlist.Version
+
+			require.NoError(t, playlist.Sort(tt.field, tt.descending))
+			ids := make([]string, len(playlist.Tracks))
+			for i, track := range playlist.Tracks {
+				ids[i] = track.ID
+			}
+			assert.Equal(t, tt.want, ids)
+			assert.Equal(t, tt.want, playlist.TrackIDs)
+			assert.Equal(t, version+1, playlist.Version)
+		})
+	}
+
+	playlist, err := NewPlaylist("Mix", PlaylistTypeStatic)
+	require.NoError(t, err)
+	assert.ErrorIs(t, playlist.Sort("mood", false), ErrInvalidInput)
+	assert.Equal(t, 1, playlist.Version)
+}
@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSkipRule = errors.New("invalid skip rule")
+
+// EpisodePlayedThreshold is how far through an episode counts as having
+// played it, as outros and ads at the end are often skipped
+const EpisodePlayedThreshold = 0.95
+
+// IsPodcastEpisode reports whether the track is a podcast episode, which by
+// the usual tagging convention has the genre "Podcast"
+func (t *Track) IsPodcastEpisode() bool {
+	return FoldText(t.Genre) == "podcast"
+}
+
+// PodcastFeed returns the key of the podcast an episode belongs to, taken
+// from its album, which podcast clients set to the show's name. It is empty
+// for tracks that aren't episodes.
+func (t *Track) PodcastFeed() string {
+	if !t.IsPodcastEpisode() {
+		return ""
+	}
+	for _, name := range []string{t.Album, t.AlbumArtist, t.Artist} {
+		if feed := FoldText(strings.TrimSpace(name)); feed != "" {
+			return feed
+		}
+	}
+	return ""
+}
+
+// EpisodeProgress is how far the user has got through a podcast episode
+type EpisodeProgress struct {
+	TrackID   string        `json:"track_id" gorm:"primaryKey"`
+	Position  time.Duration `json:"position"`
+	Played    bool          `json:"played" gorm:"index"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+func NewEpisodeProgress(trackID string) *EpisodeProgress {
+	return &EpisodeProgress{
+		TrackID:   trackID,
+		UpdatedAt: time.Now(),
+	}
+}
+
+// Update records the position reached in an episode of the given length,
+// marking the episode played once past EpisodePlayedThreshold. It reports
+// whether this update marked it played.
+func (p *EpisodeProgress) Update(position, duration time.Duration) bool {
+	p.Position = max(position, 0)
+	p.UpdatedAt = time.Now()
+
+	if p.Played || duration <= 0 || float64(position) < float64(duration)*EpisodePlayedThreshold {
+		return false
+	}
+	p.Played = true
+	return true
+}
+
+// ResumeAt returns where playback of the episode should pick up. Played
+// episodes start again from the beginning.
+func (p *EpisodeProgress) ResumeAt() time.Duration {
+	if p.Played {
+		return 0
+	}
+	return p.Position
+}
+
+// SetPlayed marks the episode played or unplayed, forgetting the position
+func (p *EpisodeProgress) SetPlayed(played bool) {
+	p.Played = played
+	p.Position = 0
+	p.UpdatedAt = time.Now()
+}
+
+// FeedSkipRule skips the intro and outro of every episode of a podcast
+type FeedSkipRule struct {
+	Feed      string        `json:"feed" gorm:"primaryKey"` // See Track.PodcastFeed
+	Name      string        `json:"name"`
+	SkipIntro time.Duration `json:"skip_intro"`
+	SkipOutro time.Duration `json:"skip_outro"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+func NewFeedSkipRule(name string, skipIntro, skipOutro time.Duration) (*FeedSkipRule, error) {
+	rule := &FeedSkipRule{
+		Feed:      FoldText(strings.TrimSpace(name)),
+		Name:      strings.TrimSpace(name),
+		SkipIntro: skipIntro,
+		SkipOutro: skipOutro,
+		UpdatedAt: time.Now(),
+	}
+
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (r *FeedSkipRule) Validate() error {
+	if r.Feed == "" {
+		return fmt.Errorf("%w: podcast name is required", ErrInvalidSkipRule)
+	}
+	if r.SkipIntro < 0 || r.SkipOutro < 0 {
+		return fmt.Errorf("%w: skip lengths can't be negative", ErrInvalidSkipRule)
+	}
+	return nil
+}
+
+// PlayWindow returns the part of an episode of the given length that is
+// played. Episodes too short for the rule are played in full.
+func (r *FeedSkipRule) PlayWindow(duration time.Duration) (start, end time.Duration) {
+	if duration <= 0 || r.SkipIntro+r.SkipOutro >= duration {
+		return 0, duration
+	}
+	return r.SkipIntro, duration - r.SkipOutro
+}
+
+type EpisodeProgressRepository interface {
+	Save(progress *EpisodeProgress) error
+	FindByTrack(trackID string) (*EpisodeProgress, error) // nil when the episode hasn't been started
+}
+
+type FeedSkipRuleRepository interface {
+	Save(rule *FeedSkipRule) error
+	Delete(feed string) error
+	FindAll() ([]*FeedSkipRule, error)
+	FindByFeed(feed string) (*FeedSkipRule, error) // nil when the feed has no rule
+}
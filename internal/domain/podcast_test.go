@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackPodcastFeed(t *testing.T) {
+	tests := []struct {
+		name     string
+		track    Track
+		expected string
+	}{
+		{"album", Track{Genre: "Podcast", Album: "The Show", Artist: "Host"}, "the show"},
+		{"falls back to artist", Track{Genre: "podcast", Artist: "Host"}, "host"},
+		{"not an episode", Track{Genre: "Rock", Album: "The Show"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.track.PodcastFeed())
+		})
+	}
+}
+
+func TestEpisodeProgressUpdate(t *testing.T) {
+	progress := NewEpisodeProgress("track_1")
+	duration := 100 * time.Minute
+
+	assert.False(t, progress.Update(30*time.Minute, duration))
+	assert.Equal(t, 30*time.Minute, progress.ResumeAt())
+
+	assert.True(t, progress.Update(95*time.Minute, duration))
+	assert.False(t, progress.Update(97*time.Minute, duration), "already played")
+	assert.True(t, progress.Played)
+	assert.Zero(t, progress.ResumeAt())
+
+	progress.SetPlayed(false)
+	assert.False(t, progress.Played)
+	assert.Zero(t, progress.Position)
+}
+
+func TestFeedSkipRulePlayWindow(t *testing.T) {
+	rule, err := NewFeedSkipRule(" The Show ", 30*time.Second, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "the show", rule.Feed)
+
+	start, end := rule.PlayWindow(10 * time.Minute)
+	assert.Equal(t, 30*time.Second, start)
+	assert.Equal(t, 9*time.Minute, end)
+
+	start, end = rule.PlayWindow(time.Minute)
+	assert.Zero(t, start)
+	assert.Equal(t, time.Minute, end)
+
+	_, err = NewFeedSkipRule("The Show", -time.Second, 0)
+	assert.ErrorIs(t, err, ErrInvalidSkipRule)
+	_, err = NewFeedSkipRule("  ", 0, 0)
+	assert.ErrorIs(t, err, ErrInvalidSkipRule)
+}
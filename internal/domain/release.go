@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrReleaseNotFound = errors.New("album release not found")
+
+// ReleaseCredit is someone credited on a release for a role, such as a
+// producer or a session musician
+type ReleaseCredit struct {
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	Tracks string `json:"tracks,omitempty"` // e.g. "A1, B2"; empty for the whole release
+}
+
+// AlbumRelease is what an online database knows about the release an
+// album was ripped from: which pressing it is, who put it out and who
+// worked on it. Tracks don't carry these details, so they are kept per
+// album.
+type AlbumRelease struct {
+	AlbumKey      string          `json:"album_key" gorm:"primaryKey"` // Track.AlbumKey of the album
+	Source        string          `json:"source" gorm:"not null"`      // e.g. "discogs"
+	ReleaseID     string          `json:"release_id" gorm:"not null"`
+	URL           string          `json:"url"`
+	Title         string          `json:"title"`
+	Artist        string          `json:"artist"`
+	Label         string          `json:"label"`
+	CatalogNumber string          `json:"catalog_number"`
+	Country       string          `json:"country"`
+	Year          int             `json:"year"`
+	Genres        []string        `json:"genres" gorm:"serializer:json"`
+	Styles        []string        `json:"styles" gorm:"serializer:json"`
+	Credits       []ReleaseCredit `json:"credits" gorm:"serializer:json"`
+	FetchedAt     time.Time       `json:"fetched_at"`
+}
+
+func (r *AlbumRelease) Validate() error {
+	if r.AlbumKey == "" {
+		return fmt.Errorf("%w: album key is required", ErrInvalidInput)
+	}
+	if r.Source == "" || r.ReleaseID == "" {
+		return fmt.Errorf("%w: source and release ID are required", ErrInvalidInput)
+	}
+	return nil
+}
+
+type AlbumReleaseRepository interface {
+	Save(release *AlbumRelease) error
+	FindByAlbumKey(albumKey string) (*AlbumRelease, error)
+	Delete(albumKey string) error
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidRemoteClient  = errors.New("invalid remote client")
+	ErrRemoteClientNotFound = errors.New("remote client not found")
+)
+
+// ClientScope is what a paired remote client may do. Each scope includes
+// the ones below it.
+type ClientScope string
+
+const (
+	ScopeRead    ClientScope = "read"    // Player state, queue, library and events
+	ScopeControl ClientScope = "control" // Also playback and queue changes
+	ScopeAdmin   ClientScope = "admin"   // Also managing paired clients
+)
+
+var scopeRanks = map[ClientScope]int{
+	ScopeRead:    1,
+	ScopeControl: 2,
+	ScopeAdmin:   3,
+}
+
+// ParseClientScope parses a scope name, with an empty name meaning
+// ScopeControl
+func ParseClientScope(name string) (ClientScope, error) {
+	scope := ClientScope(strings.ToLower(strings.TrimSpace(name)))
+	if scope == "" {
+		return ScopeControl, nil
+	}
+	if _, ok := scopeRanks[scope]; !ok {
+		return "", fmt.Errorf("%w: unknown scope %q", ErrInvalidRemoteClient, name)
+	}
+	return scope, nil
+}
+
+// Allows reports whether the scope covers a required one
+func (s ClientScope) Allows(required ClientScope) bool {
+	rank, ok := scopeRanks[s]
+	return ok && rank >= scopeRanks[required]
+}
+
+// RemoteClient is a program paired with the remote control API. Only a
+// hash of its token is kept, so a leaked database doesn't leak access.
+type RemoteClient struct {
+	ID         string      `json:"id" gorm:"primaryKey"`
+	Name       string      `json:"name"`
+	Scope      ClientScope `json:"scope" gorm:"not null"`
+	TokenHash  string      `json:"-" gorm:"uniqueIndex;not null"`
+	CreatedAt  time.Time   `json:"created_at"`
+	LastSeenAt *time.Time  `json:"last_seen_at"`
+}
+
+// NewRemoteClient creates a client and the token it authenticates with.
+// The token is returned only here.
+func NewRemoteClient(name string, scope ClientScope) (*RemoteClient, string, error) {
+	token, err := newClientToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	client := &RemoteClient{
+		ID:        generateRemoteClientID(),
+		Name:      strings.TrimSpace(name),
+		Scope:     scope,
+		TokenHash: HashClientToken(token),
+		CreatedAt: time.Now(),
+	}
+
+	if err := client.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	return client, token, nil
+}
+
+func (c *RemoteClient) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRemoteClient)
+	}
+	if _, ok := scopeRanks[c.Scope]; !ok {
+		return fmt.Errorf("%w: unknown scope %q", ErrInvalidRemoteClient, c.Scope)
+	}
+	if c.TokenHash == "" {
+		return fmt.Errorf("%w: token is required", ErrInvalidRemoteClient)
+	}
+	return nil
+}
+
+// HashClientToken returns the form a client token is stored and looked up
+// in
+func HashClientToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newClientToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func generateRemoteClientID() string {
+	return fmt.Sprintf("client_%d_%d", time.Now().UnixNano(), randomInt())
+}
+
+type RemoteClientRepository interface {
+	Create(client *RemoteClient) error
+	Delete(id string) error
+	FindAll() ([]*RemoteClient, error)
+	FindByTokenHash(hash string) (*RemoteClient, error)
+	UpdateLastSeen(id string, at time.Time) error
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientScopeAllows(t *testing.T) {
+	tests := []struct {
+		scope    ClientScope
+		required ClientScope
+		expected bool
+	}{
+		{ScopeRead, ScopeRead, true},
+		{ScopeRead, ScopeControl, false},
+		{ScopeControl, ScopeRead, true},
+		{ScopeControl, ScopeAdmin, false},
+		{ScopeAdmin, ScopeControl, true},
+		{ClientScope("owner"), ScopeRead, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.scope)+"/"+string(tt.required), func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.scope.Allows(tt.required))
+		})
+	}
+}
+
+func TestNewRemoteClient(t *testing.T) {
+	client, token, err := NewRemoteClient(" Stream Deck ", ScopeControl)
+	require.NoError(t, err)
+	assert.Equal(t, "Stream Deck", client.Name)
+	assert.Len(t, token, 64)
+	assert.Equal(t, HashClientToken(token), client.TokenHash)
+	assert.NotContains(t, client.TokenHash, token)
+
+	_, other, err := NewRemoteClient("Script", ScopeRead)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+
+	_, _, err = NewRemoteClient("", ScopeRead)
+	assert.ErrorIs(t, err, ErrInvalidRemoteClient)
+
+	scope, err := ParseClientScope("")
+	require.NoError(t, err)
+	assert.Equal(t, ScopeControl, scope)
+	_, err = ParseClientScope("root")
+	assert.ErrorIs(t, err, ErrInvalidRemoteClient)
+}
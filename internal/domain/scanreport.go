@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrScanReportNotFound = errors.New("scan report not found")
+
+type ScanChange string
+
+const (
+	ScanChangeAdded   ScanChange = "added"
+	ScanChangeUpdated ScanChange = "updated" // File changed on disk and was re-read
+	ScanChangeRemoved ScanChange = "removed" // File disappeared; the track is flagged, not deleted
+	ScanChangeMoved   ScanChange = "moved"   // File turned up at a new path; the track followed it
+)
+
+// ScanReport records what one scan run changed in the library, so users can
+// see exactly which tracks came, went or changed
+type ScanReport struct {
+	ID         string       `json:"id" gorm:"primaryKey"`
+	Root       string       `json:"root"`
+	StartedAt  time.Time    `json:"started_at" gorm:"index"`
+	FinishedAt time.Time    `json:"finished_at"`
+	Added      int          `json:"added"`
+	Updated    int          `json:"updated"`
+	Removed    int          `json:"removed"`
+	Moved      int          `json:"moved"`
+	Failed     int          `json:"failed"`
+	Cancelled  bool         `json:"cancelled"`
+	NewAlbums  []ScanAlbum  `json:"new_albums" gorm:"serializer:json"`
+	Entries    []*ScanEntry `json:"entries,omitempty" gorm:"-"`
+}
+
+// ScanAlbum is an album that first appeared in the library during a scan
+type ScanAlbum struct {
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+	Tracks int    `json:"tracks"`
+}
+
+// ScanEntry is one track changed by a scan. Track details are copied so
+// the report still reads correctly after the track is edited or removed.
+type ScanEntry struct {
+	ID       uint       `json:"-" gorm:"primaryKey;autoIncrement"`
+	ReportID string     `json:"report_id" gorm:"index;not null"`
+	Change   ScanChange `json:"change"`
+	TrackID  string     `json:"track_id"`
+	Path     string     `json:"path"`
+	Title    string     `json:"title"`
+	Artist   string     `json:"artist"`
+	Album    string     `json:"album"`
+}
+
+func NewScanReport(root string) *ScanReport {
+	return &ScanReport{
+		ID:        generateScanReportID(),
+		Root:      root,
+		StartedAt: time.Now(),
+	}
+}
+
+// Record adds a changed track to the report
+func (r *ScanReport) Record(change ScanChange, track *Track) {
+	switch change {
+	case ScanChangeAdded:
+		r.Added++
+	case ScanChangeUpdated:
+		r.Updated++
+	case ScanChangeRemoved:
+		r.Removed++
+	case ScanChangeMoved:
+		r.Moved++
+	}
+
+	r.Entries = append(r.Entries, &ScanEntry{
+		ReportID: r.ID,
+		Change:   change,
+		TrackID:  track.ID,
+		Path:     track.FilePath,
+		Title:    track.GetDisplayTitle(),
+		Artist:   track.GetDisplayArtist(),
+		Album:    track.Album,
+	})
+}
+
+// HasChanges returns true if the scan changed anything in the library
+func (r *ScanReport) HasChanges() bool {
+	return r.Added+r.Updated+r.Removed+r.Moved > 0
+}
+
+func generateScanReportID() string {
+	return fmt.Sprintf("scan_%d_%d", time.Now().UnixNano(), randomInt())
+}
+
+type ScanReportRepository interface {
+	Create(report *ScanReport) error
+	FindByID(id string) (*ScanReport, error)
+	FindLatest() (*ScanReport, error)
+	FindRecent(limit int) ([]*ScanReport, error)
+	Prune(keep int) error
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanReport_Record(t *testing.T) {
+	report := NewScanReport("/music")
+	assert.False(t, report.HasChanges())
+
+	added := &Track{ID: "a", FilePath: "/music/a.mp3", Title: "Song", Artist: "Band", Album: "Record"}
+	removed := &Track{ID: "b", FilePath: "/music/b.mp3"}
+	moved := &Track{ID: "c", FilePath: "/music/new/c.mp3"}
+	report.Record(ScanChangeAdded, added)
+	report.Record(ScanChangeRemoved, removed)
+	report.Record(ScanChangeMoved, moved)
+
+	assert.True(t, report.HasChanges())
+	assert.Equal(t, 1, report.Added)
+	assert.Equal(t, 1, report.Removed)
+	assert.Equal(t, 1, report.Moved)
+	assert.Equal(t, 0, report.Updated)
+
+	require.Len(t, report.Entries, 3)
+	assert.Equal(t, report.ID, report.Entries[0].ReportID)
+	assert.Equal(t, "Song", report.Entries[0].Title)
+	assert.Equal(t, "b.mp3", report.Entries[1].Title, "untitled tracks fall back to the file name")
+}
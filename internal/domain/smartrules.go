@@ -0,0 +1,394 @@
+package domain
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Operators smart playlist conditions compare with
+const (
+	OperatorEquals      = "equals"
+	OperatorNotEquals   = "not_equals"
+	OperatorContains    = "contains"
+	OperatorNotContains = "not_contains"
+	OperatorStartsWith  = "starts_with"
+	OperatorGreater     = "greater"
+	OperatorLess        = "less"
+	OperatorBetween     = "between"
+	OperatorInLast      = "in_last" // Dates within the last Value days
+)
+
+// ruleKind is the type of value a rule field holds, which decides the
+// operators it takes
+type ruleKind int
+
+const (
+	ruleText ruleKind = iota
+	ruleNumber
+	ruleDuration // Compared in seconds
+	ruleDate     // Compared to "2006-01-02" or RFC 3339 values
+	ruleList
+	ruleBool
+)
+
+var ruleOperators = map[ruleKind][]string{
+	ruleText:     {OperatorEquals, OperatorNotEquals, OperatorContains, OperatorNotContains, OperatorStartsWith},
+	ruleNumber:   {OperatorEquals, OperatorNotEquals, OperatorGreater, OperatorLess, OperatorBetween},
+	ruleDuration: {OperatorGreater, OperatorLess, OperatorBetween},
+	ruleDate:     {OperatorGreater, OperatorLess, OperatorBetween, OperatorInLast},
+	ruleList:     {OperatorContains, OperatorNotContains},
+	ruleBool:     {OperatorEquals},
+}
+
+// ruleFields are the fields smart playlist rules can test, as named by
+// Track.FieldValue
+var ruleFields = map[string]ruleKind{
+	"title":        ruleText,
+	"artist":       ruleText,
+	"album":        ruleText,
+	"album_artist": ruleText,
+	"genre":        ruleText,
+	"composer":     ruleText,
+	"publisher":    ruleText,
+	"label":        ruleText,
+	"format":       ruleText,
+	"year":         ruleNumber,
+	"rating":       ruleNumber,
+	"play_count":   ruleNumber,
+	"bpm":          ruleNumber,
+	"duration":     ruleDuration,
+	"date_added":   ruleDate,
+	"tag":          ruleList,
+	"tags":         ruleList,
+	"audiobook":    ruleBool,
+	"language":     ruleText,
+	"explicit":     ruleBool,
+	"clean":        ruleBool,
+	"live":         ruleBool,
+}
+
+// RuleFields returns the fields smart playlist rules can test, with the
+// operators each takes
+func RuleFields() map[string][]string {
+	fields := make(map[string][]string, len(ruleFields))
+	for field, kind := range ruleFields {
+		fields[field] = ruleOperators[kind]
+	}
+	return fields
+}
+
+// RuleError describes a smart playlist condition that can't be evaluated.
+// Index is the condition's position, or -1 for the ordering and limit.
+type RuleError struct {
+	Index   int    `json:"index"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *RuleError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("%s: %s", e.Field, e.Message)
+	}
+	return fmt.Sprintf("condition %d (%s): %s", e.Index+1, e.Field, e.Message)
+}
+
+func (e *RuleError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+// Check returns every problem with the rules, so an editor can point at
+// each one. Rules without conditions match every track.
+func (r *SmartRules) Check() []*RuleError {
+	var errs []*RuleError
+	for i, c := range r.Conditions {
+		if msg := c.check(); msg != "" {
+			errs = append(errs, &RuleError{Index: i, Field: c.Field, Message: msg})
+		}
+	}
+	if r.OrderBy != "" {
+		if _, ok := ruleFields[strings.ToLower(r.OrderBy)]; !ok {
+			errs = append(errs, &RuleError{Index: -1, Field: "order_by", Message: fmt.Sprintf("unknown field %q", r.OrderBy)})
+		}
+	}
+	if r.Limit < 0 {
+		errs = append(errs, &RuleError{Index: -1, Field: "limit", Message: "must not be negative"})
+	}
+	return errs
+}
+
+// Validate returns the first problem with the rules, if any
+func (r *SmartRules) Validate() error {
+	if errs := r.Check(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// check returns what is wrong with a condition, or an empty string
+func (c RuleCondition) check() string {
+	kind, ok := ruleFields[strings.ToLower(c.Field)]
+	if !ok {
+		return fmt.Sprintf("unknown field %q", c.Field)
+	}
+	if !slices.Contains(ruleOperators[kind], c.Operator) {
+		return fmt.Sprintf("%q can't be used with %s", c.Operator, c.Field)
+	}
+	if andOr := strings.ToUpper(c.AndOr); andOr != "" && andOr != "AND" && andOr != "OR" {
+		return fmt.Sprintf("must be joined with AND or OR, not %q", c.AndOr)
+	}
+
+	values := []interface{}{c.Value}
+	if c.Operator == OperatorBetween {
+		pair, ok := c.Value.([]interface{})
+		if !ok || len(pair) != 2 {
+			return "between needs two values"
+		}
+		values = pair
+	}
+	for _, value := range values {
+		if msg := checkRuleValue(kind, c.Operator, value); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+// checkRuleValue returns what is wrong with a value for a kind of field
+func checkRuleValue(kind ruleKind, operator string, value interface{}) string {
+	switch {
+	case kind == ruleDate && operator == OperatorInLast, kind == ruleNumber, kind == ruleDuration:
+		if _, ok := ruleNumberValue(value); !ok {
+			return "value must be a number"
+		}
+	case kind == ruleDate:
+		if _, ok := ruleDateValue(value); !ok {
+			return "value must be a date"
+		}
+	case kind == ruleBool:
+		if _, ok := value.(bool); !ok {
+			return "value must be true or false"
+		}
+	default:
+		if _, ok := value.(string); !ok {
+			return "value must be text"
+		}
+	}
+	return ""
+}
+
+// Matches reports whether a track meets the rules. Conditions joined by
+// AND bind tighter than OR, so "a AND b OR c" is "(a AND b) OR c". The
+// rules must have been checked.
+func (r *SmartRules) Matches(track *Track, now time.Time) bool {
+	if len(r.Conditions) == 0 {
+		return true
+	}
+
+	group := true
+	for i, c := range r.Conditions {
+		if i > 0 && strings.EqualFold(c.AndOr, "OR") {
+			if group {
+				return true
+			}
+			group = true
+		}
+		group = group && c.matches(track, now)
+	}
+	return group
+}
+
+// Apply returns the tracks that meet the rules, ordered and limited as
+// they say
+func (r *SmartRules) Apply(tracks []*Track, now time.Time) []*Track {
+	var matches []*Track
+	for _, track := range tracks {
+		if r.Matches(track, now) {
+			matches = append(matches, track)
+		}
+	}
+
+	if r.OrderBy != "" {
+		slices.SortStableFunc(matches, func(a, b *Track) int {
+			order := compareRuleValues(a, b, r.OrderBy)
+			if r.OrderDesc {
+				return -order
+			}
+			return order
+		})
+	}
+	if r.Limit > 0 && len(matches) > r.Limit {
+		matches = matches[:r.Limit]
+	}
+	return matches
+}
+
+// Values returns a checked condition's values as its field reads them:
+// one, or two for between. Numbers are float64, with durations in seconds
+// and in_last in days; dates are time.Time, text and tags string and flags
+// bool.
+func (c RuleCondition) Values() []interface{} {
+	kind := ruleFields[strings.ToLower(c.Field)]
+	raw := []interface{}{c.Value}
+	if c.Operator == OperatorBetween {
+		raw, _ = c.Value.([]interface{})
+	}
+
+	values := make([]interface{}, len(raw))
+	for i, value := range raw {
+		switch {
+		case kind == ruleDate && c.Operator != OperatorInLast:
+			values[i], _ = ruleDateValue(value)
+		case kind == ruleNumber, kind == ruleDuration, kind == ruleDate:
+			values[i], _ = ruleNumberValue(value)
+		default:
+			values[i] = value
+		}
+	}
+	return values
+}
+
+// matches reports whether a track meets one condition
+func (c RuleCondition) matches(track *Track, now time.Time) bool {
+	field, ok := track.FieldValue(c.Field)
+	if !ok {
+		return false
+	}
+
+	switch value := field.(type) {
+	case string:
+		return matchText(value, c.Operator, c.Value)
+	case int:
+		return matchNumber(float64(value), c.Operator, c.Value)
+	case time.Duration:
+		return matchNumber(value.Seconds(), c.Operator, c.Value)
+	case time.Time:
+		return matchDate(value, c.Operator, c.Value, now)
+	case []string:
+		text, _ := c.Value.(string)
+		contains := slices.ContainsFunc(value, func(s string) bool { return strings.EqualFold(s, text) })
+		return contains == (c.Operator == OperatorContains)
+	case bool:
+		want, _ := c.Value.(bool)
+		return value == want
+	default:
+		return false
+	}
+}
+
+func matchText(value, operator string, want interface{}) bool {
+	text, _ := want.(string)
+	value, text = strings.ToLower(value), strings.ToLower(text)
+	switch operator {
+	case OperatorEquals:
+		return value == text
+	case OperatorNotEquals:
+		return value != text
+	case OperatorContains:
+		return strings.Contains(value, text)
+	case OperatorNotContains:
+		return !strings.Contains(value, text)
+	case OperatorStartsWith:
+		return strings.HasPrefix(value, text)
+	default:
+		return false
+	}
+}
+
+func matchNumber(value float64, operator string, want interface{}) bool {
+	if operator == OperatorBetween {
+		pair, _ := want.([]interface{})
+		if len(pair) != 2 {
+			return false
+		}
+		low, _ := ruleNumberValue(pair[0])
+		high, _ := ruleNumberValue(pair[1])
+		return value >= low && value <= high
+	}
+
+	number, _ := ruleNumberValue(want)
+	switch operator {
+	case OperatorEquals:
+		return value == number
+	case OperatorNotEquals:
+		return value != number
+	case OperatorGreater:
+		return value > number
+	case OperatorLess:
+		return value < number
+	default:
+		return false
+	}
+}
+
+func matchDate(value time.Time, operator string, want interface{}, now time.Time) bool {
+	switch operator {
+	case OperatorInLast:
+		days, _ := ruleNumberValue(want)
+		return value.After(now.Add(-time.Duration(days * float64(24*time.Hour))))
+	case OperatorBetween:
+		pair, _ := want.([]interface{})
+		if len(pair) != 2 {
+			return false
+		}
+		from, _ := ruleDateValue(pair[0])
+		to, _ := ruleDateValue(pair[1])
+		return !value.Before(from) && value.Before(to.AddDate(0, 0, 1))
+	}
+
+	date, _ := ruleDateValue(want)
+	switch operator {
+	case OperatorGreater:
+		return !value.Before(date.AddDate(0, 0, 1))
+	case OperatorLess:
+		return value.Before(date)
+	default:
+		return false
+	}
+}
+
+// compareRuleValues orders two tracks by a rule field
+func compareRuleValues(a, b *Track, field string) int {
+	x, _ := a.FieldValue(field)
+	y, _ := b.FieldValue(field)
+	switch x := x.(type) {
+	case string:
+		return strings.Compare(strings.ToLower(x), strings.ToLower(y.(string)))
+	case int:
+		return cmp.Compare(x, y.(int))
+	case time.Duration:
+		return cmp.Compare(x, y.(time.Duration))
+	case time.Time:
+		return x.Compare(y.(time.Time))
+	default:
+		return 0
+	}
+}
+
+// ruleNumberValue reads a number as JSON decodes it, or as Go code sets it
+func ruleNumberValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// ruleDateValue reads a date given as "2006-01-02" or RFC 3339
+func ruleDateValue(value interface{}) (time.Time, bool) {
+	text, ok := value.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if date, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSmartRules_Check(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     SmartRules
+		wantField []string
+	}{
+		{"no conditions", SmartRules{}, nil},
+		{"valid", SmartRules{Conditions: []RuleCondition{
+			{Field: "genre", Operator: OperatorEquals, Value: "Jazz"},
+			{Field: "year", Operator: OperatorBetween, Value: []interface{}{1950.0, 1969.0}, AndOr: "AND"},
+			{Field: "date_added", Operator: OperatorInLast, Value: 30.0, AndOr: "or"},
+			{Field: "audiobook", Operator: OperatorEquals, Value: false},
+		}, OrderBy: "rating", Limit: 25}, nil},
+		{"unknown field", SmartRules{Conditions: []RuleCondition{{Field: "mood", Operator: OperatorEquals, Value: "happy"}}}, []string{"mood"}},
+		{"operator for another kind", SmartRules{Conditions: []RuleCondition{{Field: "artist", Operator: OperatorGreater, Value: "M"}}}, []string{"artist"}},
+		{"text for a number", SmartRules{Conditions: []RuleCondition{{Field: "rating", Operator: OperatorGreater, Value: "four"}}}, []string{"rating"}},
+		{"between one value", SmartRules{Conditions: []RuleCondition{{Field: "bpm", Operator: OperatorBetween, Value: 120.0}}}, []string{"bpm"}},
+		{"bad date", SmartRules{Conditions: []RuleCondition{{Field: "date_added", Operator: OperatorLess, Value: "last week"}}}, []string{"date_added"}},
+		{"bad join", SmartRules{Conditions: []RuleCondition{{Field: "genre", Operator: OperatorEquals, Value: "Rock", AndOr: "XOR"}}}, []string{"genre"}},
+		{"order and limit", SmartRules{OrderBy: "mood", Limit: -1}, []string{"order_by", "limit"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.rules.Check()
+			var fields []string
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+				assert.True(t, errors.Is(err, ErrInvalidInput))
+			}
+			assert.Equal(t, tt.wantField, fields)
+			if tt.wantField == nil {
+				assert.NoError(t, tt.rules.Validate())
+			} else {
+				assert.Error(t, tt.rules.Validate())
+			}
+		})
+	}
+}
+
+func TestSmartRules_Apply(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.Local)
+	tracks := []*Track{
+		{ID: "a", Genre: "Jazz", Year: 1959, Rating: 5, Duration: 9 * time.Minute, DateAdded: now.AddDate(-2, 0, 0)},
+		{ID: "b", Genre: "jazz", Year: 1972, Rating: 3, Duration: 4 * time.Minute, DateAdded: now.AddDate(0, 0, -3)},
+		{ID: "c", Genre: "Rock", Year: 1969, Rating: 4, Duration: 3 * time.Minute, DateAdded: now.AddDate(0, 0, -10), UserTags: []string{"Road Trip"}},
+		{ID: "d", Genre: "Jazz", Year: 1961, Rating: 4, Duration: 6 * time.Minute, DateAdded: now.AddDate(-1, 0, 0)},
+	}
+
+	tests := []struct {
+		name  string
+		rules SmartRules
+		want  []string
+	}{
+		{"everything", SmartRules{}, []string{"a", "b", "c", "d"}},
+		{"text ignores case", SmartRules{Conditions: []RuleCondition{{Field: "genre", Operator: OperatorEquals, Value: "JAZZ"}}}, []string{"a", "b", "d"}},
+		{"and", SmartRules{Conditions: []RuleCondition{
+			{Field: "genre", Operator: OperatorEquals, Value: "jazz"},
+			{Field: "year", Operator: OperatorBetween, Value: []interface{}{1950.0, 1969.0}, AndOr: "AND"},
+		}}, []string{"a", "d"}},
+		{"and binds tighter than or", SmartRules{Conditions: []RuleCondition{
+			{Field: "genre", Operator: OperatorEquals, Value: "rock"},
+			{Field: "rating", Operator: OperatorGreater, Value: 4.0, AndOr: "AND"},
+			{Field: "duration", Operator: OperatorGreater, Value: 480.0, AndOr: "OR"},
+		}}, []string{"a"}},
+		{"recently added", SmartRules{Conditions: []RuleCondition{{Field: "date_added", Operator: OperatorInLast, Value: 30.0}}}, []string{"b", "c"}},
+		{"before a date", SmartRules{Conditions: []RuleCondition{{Field: "date_added", Operator: OperatorLess, Value: "2025-01-01"}}}, []string{"a"}},
+		{"tag", SmartRules{Conditions: []RuleCondition{{Field: "tag", Operator: OperatorContains, Value: "road trip"}}}, []string{"c"}},
+		{"ordered and limited", SmartRules{OrderBy: "rating", OrderDesc: true, Limit: 3}, []string{"a", "c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.rules.Validate())
+			var ids []string
+			for _, track := range tt.rules.Apply(tracks, now) {
+				ids = append(ids, track.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestRuleFieldsAreTrackFields(t *testing.T) {
+	track := &Track{}
+	for field := range RuleFields() {
+		_, ok := track.FieldValue(field)
+		assert.True(t, ok, field)
+	}
+}
+
+func TestSmartRules_Advisory(t *testing.T) {
+	tracks := []*Track{
+		{ID: "a", Language: "eng", Advisory: AdvisoryExplicit},
+		{ID: "b", Language: "fra", Advisory: AdvisoryClean},
+		{ID: "c", Language: "eng"},
+	}
+	tests := []struct {
+		name  string
+		rules SmartRules
+		want  []string
+	}{
+		{"explicit", SmartRules{Conditions: []RuleCondition{{Field: "explicit", Operator: OperatorEquals, Value: true}}}, []string{"a"}},
+		{"not explicit", SmartRules{Conditions: []RuleCondition{{Field: "explicit", Operator: OperatorEquals, Value: false}}}, []string{"b", "c"}},
+		{"clean", SmartRules{Conditions: []RuleCondition{{Field: "clean", Operator: OperatorEquals, Value: true}}}, []string{"b"}},
+		{"language", SmartRules{Conditions: []RuleCondition{{Field: "language", Operator: OperatorEquals, Value: "ENG"}}}, []string{"a", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.rules.Validate())
+			var ids []string
+			for _, track := range tt.rules.Apply(tracks, time.Now()) {
+				ids = append(ids, track.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+
+	_, err := ParseAdvisory("parental")
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrInvalidSource     = errors.New("invalid source")
+	ErrUnsupportedSource = errors.New("unsupported source kind")
+)
+
+// SourceKind says where a track's audio comes from
+type SourceKind string
+
+const (
+	SourceFile    SourceKind = "file"    // Local or network-share file
+	SourceStream  SourceKind = "stream"  // HTTP(S) stream or remote file fetched by URL
+	SourceCD      SourceKind = "cd"      // Audio CD track, e.g. cdda://D:/3
+	SourceRemote  SourceKind = "remote"  // Track on a remote media server
+	SourceArchive SourceKind = "archive" // File inside a zip archive, e.g. zip://C:/Mixes/set.zip!/01 Intro.mp3
+)
+
+const (
+	archiveScheme    = "zip://"
+	archiveSeparator = "!/" // Between the archive path and the entry name
+)
+
+// Source locates a track's audio. URI is a path for files and a URL for
+// everything else. Credentials are never stored on the track; CredentialsRef
+// names the entry in the credential store a resolver should use.
+type Source struct {
+	Kind           SourceKind `json:"kind" gorm:"index"`
+	URI            string     `json:"uri"`
+	CredentialsRef string     `json:"credentials_ref,omitempty"`
+}
+
+// SourceFromPath derives a source from a file path or URL, as stored in
+// Track.FilePath before sources existed
+func SourceFromPath(path string) Source {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasPrefix(lower, "http://"), strings.HasPrefix(lower, "https://"):
+		return Source{Kind: SourceStream, URI: path}
+	case strings.HasPrefix(lower, "cdda://"):
+		return Source{Kind: SourceCD, URI: path}
+	case strings.HasPrefix(lower, archiveScheme):
+		return Source{Kind: SourceArchive, URI: path}
+	default:
+		return Source{Kind: SourceFile, URI: filepath.Clean(path)}
+	}
+}
+
+// Validate checks that the source has a known kind and a usable URI
+func (s Source) Validate() error {
+	if s.URI == "" {
+		return fmt.Errorf("%w: URI is required", ErrInvalidSource)
+	}
+
+	switch s.Kind {
+	case SourceFile:
+		return nil
+	case SourceArchive:
+		_, _, err := s.ArchiveEntry()
+		return err
+	case SourceStream, SourceCD, SourceRemote:
+		if _, err := url.Parse(s.URI); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSource, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedSource, s.Kind)
+	}
+}
+
+// IsLocal reports whether the source is a file that can be read directly
+func (s Source) IsLocal() bool {
+	return s.Kind == SourceFile
+}
+
+// Name returns the part of the URI that identifies the audio format, such as
+// the file name or the last URL path segment
+func (s Source) Name() string {
+	if s.Kind == SourceFile {
+		return filepath.Base(s.URI)
+	}
+	if _, entry, err := s.ArchiveEntry(); err == nil {
+		return path.Base(entry)
+	}
+	if u, err := url.Parse(s.URI); err == nil && u.Path != "" {
+		return u.Path[strings.LastIndex(u.Path, "/")+1:]
+	}
+	return s.URI
+}
+
+// ArchiveSource returns the source of a file inside a zip archive. The
+// entry name uses forward slashes, as zip archives store it.
+func ArchiveSource(archivePath, entry string) Source {
+	return Source{
+		Kind: SourceArchive,
+		URI:  archiveScheme + filepath.Clean(archivePath) + archiveSeparator + entry,
+	}
+}
+
+// ArchiveEntry splits an archive source into the path of the archive and
+// the name of the file inside it
+func (s Source) ArchiveEntry() (archivePath, entry string, err error) {
+	if s.Kind != SourceArchive || !strings.HasPrefix(strings.ToLower(s.URI), archiveScheme) {
+		return "", "", fmt.Errorf("%w: %q is not an archive entry", ErrInvalidSource, s.URI)
+	}
+
+	// Split after the archive's extension, as entry names may themselves
+	// hold the separator
+	rest := s.URI[len(archiveScheme):]
+	i := strings.Index(strings.ToLower(rest), ".zip"+archiveSeparator)
+	if i < 0 {
+		return "", "", fmt.Errorf("%w: %q names no file in a zip archive", ErrInvalidSource, s.URI)
+	}
+	archivePath, entry = rest[:i+len(".zip")], rest[i+len(".zip"+archiveSeparator):]
+	if entry == "" {
+		return "", "", fmt.Errorf("%w: %q names no file in a zip archive", ErrInvalidSource, s.URI)
+	}
+	return archivePath, entry, nil
+}
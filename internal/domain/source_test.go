@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceFromPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		kind SourceKind
+		uri  string
+	}{
+		{"file", "/music/song.mp3", SourceFile, filepath.Clean("/music/song.mp3")},
+		{"untidy file", "/music/../music/song.mp3", SourceFile, filepath.Clean("/music/song.mp3")},
+		{"stream", "http://radio.example.com/live", SourceStream, "http://radio.example.com/live"},
+		{"secure stream", "HTTPS://radio.example.com/live.mp3", SourceStream, "HTTPS://radio.example.com/live.mp3"},
+		{"CD", "cdda://D:/3", SourceCD, "cdda://D:/3"},
+		{"archive", "zip://C:/Mixes/set.zip!/01 Intro.mp3", SourceArchive, "zip://C:/Mixes/set.zip!/01 Intro.mp3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := SourceFromPath(tt.path)
+			assert.Equal(t, tt.kind, source.Kind)
+			assert.Equal(t, tt.uri, source.URI)
+			assert.NoError(t, source.Validate())
+		})
+	}
+}
+
+func TestSourceValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		source Source
+		err    error
+	}{
+		{"file", Source{Kind: SourceFile, URI: "/music/song.mp3"}, nil},
+		{"remote", Source{Kind: SourceRemote, URI: "jellyfin://server/items/42"}, nil},
+		{"no URI", Source{Kind: SourceFile}, ErrInvalidSource},
+		{"bad URL", Source{Kind: SourceStream, URI: "http://[::1"}, ErrInvalidSource},
+		{"unknown kind", Source{Kind: "ftp", URI: "ftp://host/song.mp3"}, ErrUnsupportedSource},
+		{"archive without entry", Source{Kind: SourceArchive, URI: "zip:///mixes/set.zip!/"}, ErrInvalidSource},
+		{"archive without separator", Source{Kind: SourceArchive, URI: "zip:///mixes/set.zip"}, ErrInvalidSource},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.source.Validate()
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSourceName(t *testing.T) {
+	assert.Equal(t, "song.flac", Source{Kind: SourceFile, URI: filepath.Join("music", "song.flac")}.Name())
+	assert.Equal(t, "live.mp3", Source{Kind: SourceStream, URI: "http://radio.example.com/streams/live.mp3?sid=1"}.Name())
+	assert.Equal(t, "http://radio.example.com", Source{Kind: SourceStream, URI: "http://radio.example.com"}.Name())
+	assert.Equal(t, "02 Outro.flac", ArchiveSource("/mixes/set.zip", "Set/02 Outro.flac").Name())
+}
+
+func TestArchiveEntry(t *testing.T) {
+	source := ArchiveSource(filepath.Join("mixes", "Live!/Set.ZIP"), "Set/Track!/01.mp3")
+	archivePath, entry, err := source.ArchiveEntry()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("mixes", "Live!/Set.ZIP"), archivePath)
+	assert.Equal(t, "Set/Track!/01.mp3", entry)
+	assert.Equal(t, SourceArchive, SourceFromPath(source.URI).Kind)
+
+	_, _, err = Source{Kind: SourceFile, URI: "/music/song.mp3"}.ArchiveEntry()
+	assert.ErrorIs(t, err, ErrInvalidSource)
+}
+
+func TestTrackGetSource(t *testing.T) {
+	// Tracks from before sources existed derive theirs from the path
+	track := &Track{FilePath: "http://radio.example.com/live"}
+	assert.Equal(t, SourceStream, track.GetSource().Kind)
+	assert.True(t, track.IsNetworkPath())
+
+	track = &Track{FilePath: "/music/song.mp3", Source: Source{Kind: SourceRemote, URI: "remote://server/42"}}
+	assert.Equal(t, SourceRemote, track.GetSource().Kind)
+	assert.False(t, track.GetSource().IsLocal())
+	assert.True(t, track.IsNetworkPath())
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrStreamNotFound = errors.New("stream not found")
+
+// StreamStation is a network stream the user has listened to or marked as
+// a favorite, keyed by its URL. Streams aren't library tracks, so their
+// history is kept here rather than in play history.
+type StreamStation struct {
+	URL          string        `json:"url" gorm:"primaryKey"`
+	Name         string        `json:"name"`
+	Favorite     bool          `json:"favorite" gorm:"index"`
+	PlayCount    int           `json:"play_count"`
+	ListenTime   time.Duration `json:"listen_time"` // Total time spent playing the stream
+	LastPlayedAt *time.Time    `json:"last_played_at" gorm:"index"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+func NewStreamStation(url, name string) (*StreamStation, error) {
+	station := &StreamStation{
+		URL:       strings.TrimSpace(url),
+		Name:      strings.TrimSpace(name),
+		CreatedAt: time.Now(),
+	}
+
+	if err := station.Validate(); err != nil {
+		return nil, err
+	}
+
+	return station, nil
+}
+
+func (s *StreamStation) Validate() error {
+	source := SourceFromPath(s.URL)
+	if source.Kind != SourceStream {
+		return fmt.Errorf("%w: %q is not a stream URL", ErrInvalidSource, s.URL)
+	}
+	return source.Validate()
+}
+
+// RecordListen adds a listening session that began at started and played
+// for listened
+func (s *StreamStation) RecordListen(started time.Time, listened time.Duration) {
+	s.PlayCount++
+	s.ListenTime += max(listened, 0)
+	if s.LastPlayedAt == nil || started.After(*s.LastPlayedAt) {
+		s.LastPlayedAt = &started
+	}
+}
+
+// GetDisplayName returns the station's name, or its URL when it has none
+func (s *StreamStation) GetDisplayName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.URL
+}
+
+type StreamStationRepository interface {
+	Save(station *StreamStation) error
+	Delete(url string) error
+	FindByURL(url string) (*StreamStation, error)
+	FindRecent(limit int) ([]*StreamStation, error)
+	FindFavorites() ([]*StreamStation, error)
+}
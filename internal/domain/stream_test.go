@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStreamStation(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{"http", "http://radio.example/stream", false},
+		{"https", " https://radio.example/live.aac ", false},
+		{"file path", "/music/song.mp3", true},
+		{"cd", "cdda://1", true},
+		{"empty", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			station, err := NewStreamStation(tt.url, "Radio")
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidSource)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotContains(t, station.URL, " ")
+		})
+	}
+}
+
+func TestStreamStationRecordListen(t *testing.T) {
+	station, err := NewStreamStation("http://radio.example/stream", "")
+	require.NoError(t, err)
+	assert.Equal(t, "http://radio.example/stream", station.GetDisplayName())
+
+	first := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	station.RecordListen(second, 10*time.Minute)
+	station.RecordListen(first, 5*time.Minute)
+	station.RecordListen(second, -time.Minute)
+
+	assert.Equal(t, 3, station.PlayCount)
+	assert.Equal(t, 15*time.Minute, station.ListenTime)
+	require.NotNil(t, station.LastPlayedAt)
+	assert.Equal(t, second, *station.LastPlayedAt)
+}
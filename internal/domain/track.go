@@ -22,46 +22,89 @@ const (
 	FormatFLAC AudioFormat = "flac"
 	FormatOGG  AudioFormat = "ogg"
 	FormatWAV  AudioFormat = "wav"
+	FormatAIFF AudioFormat = "aiff"
 	FormatAAC  AudioFormat = "aac"
 	FormatWMA  AudioFormat = "wma"
 	FormatM4A  AudioFormat = "m4a"
 	FormatOPUS AudioFormat = "opus"
 )
 
+// Advisory is a track's parental advisory rating
+type Advisory string
+
+const (
+	AdvisoryNone     Advisory = "" // Not rated
+	AdvisoryExplicit Advisory = "explicit"
+	AdvisoryClean    Advisory = "clean" // Edited version of an explicit track
+)
+
+// ParseAdvisory parses an advisory rating name, "" for none
+func ParseAdvisory(s string) (Advisory, error) {
+	switch advisory := Advisory(strings.ToLower(strings.TrimSpace(s))); advisory {
+	case AdvisoryNone, AdvisoryExplicit, AdvisoryClean:
+		return advisory, nil
+	default:
+		return AdvisoryNone, fmt.Errorf("%w: unknown advisory %q", ErrInvalidInput, s)
+	}
+}
+
 type Track struct {
 	ID           string        `json:"id" gorm:"primaryKey"`
 	FilePath     string        `json:"file_path" gorm:"uniqueIndex;not null"`
+	Source       Source        `json:"source" gorm:"embedded;embeddedPrefix:source_"`
 	Title        string        `json:"title"`
 	Artist       string        `json:"artist" gorm:"index"`
+	ArtistMBID   string        `json:"artist_mbid"` // MusicBrainz artist ID, from tags when present
 	Album        string        `json:"album" gorm:"index"`
 	AlbumArtist  string        `json:"album_artist"`
 	Genre        string        `json:"genre" gorm:"index"`
 	Year         int           `json:"year" gorm:"index"`
 	TrackNumber  int           `json:"track_number"`
 	DiscNumber   int           `json:"disc_number"`
+	DiscSubtitle string        `json:"disc_subtitle"` // Title of the disc in a multi-disc set
+	Language     string        `json:"language" gorm:"index"` // ISO 639-2 code of the lyrics, e.g. "eng"
+	Advisory     Advisory      `json:"advisory" gorm:"index"`
 	Duration     time.Duration `json:"duration"`
 	Bitrate      int           `json:"bitrate"`
 	SampleRate   int           `json:"sample_rate"`
 	Channels     int           `json:"channels"`
+	BitDepth     int           `json:"bit_depth"`
 	Format       AudioFormat   `json:"format"`
 	FileSize     int64         `json:"file_size"`
+	FileModTime  time.Time     `json:"file_mod_time"` // When the file was last modified, as of its last read
 	DateAdded    time.Time     `json:"date_added" gorm:"index"`
 	LastPlayed   *time.Time    `json:"last_played"`
 	PlayCount    int           `json:"play_count" gorm:"default:0"`
 	Rating       int           `json:"rating" gorm:"default:0"` // 0-5 stars
+	Favorite     bool          `json:"favorite" gorm:"default:false;index"`
+	IsAudiobook  bool          `json:"is_audiobook" gorm:"default:false;index"`
+	IsLive       bool          `json:"is_live" gorm:"default:false;index"` // Concert recording, from tags or LooksLive
 	BPM          int           `json:"bpm"`
 	Comment      string        `json:"comment"`
-	Composer     string        `json:"composer"`
-	Publisher    string        `json:"publisher"`
+	Composer     string        `json:"composer" gorm:"index"`
+	Publisher    string        `json:"publisher" gorm:"index"` // Publisher or record label
 	Lyrics       string        `json:"lyrics" gorm:"type:text"`
 	AlbumArtPath string        `json:"album_art_path"`
 	ReplayGain   *ReplayGain   `json:"replay_gain" gorm:"embedded"`
-	Fingerprint  string        `json:"fingerprint"` // Acoustic fingerprint for duplicate detection
-	Checksum     string        `json:"checksum"`    // File checksum for integrity
+	Fingerprint  string        `json:"fingerprint" gorm:"index"` // Acoustic fingerprint for duplicate and move detection
+	Checksum     string        `json:"checksum" gorm:"index"` // File checksum for integrity and duplicate detection
 	IsValid      bool          `json:"is_valid" gorm:"default:true"`
 	Error        string        `json:"error,omitempty"`
 	UpdatedAt    time.Time     `json:"updated_at"`
 	CreatedAt    time.Time     `json:"created_at"`
+
+	// Case- and accent-folded copies for collation; see UpdateSortKeys
+	SortTitle  string `json:"-" gorm:"index"`
+	SortArtist string `json:"-" gorm:"index"`
+	SortAlbum  string `json:"-" gorm:"index"`
+	SearchText string `json:"-" gorm:"type:text"`
+
+	// Names of the user tags on the track; see UserTagRepository.LoadForTracks
+	UserTags []string `json:"user_tags,omitempty" gorm:"-"`
+
+	// Transient tracks are queued from outside the library, such as a
+	// one-off file or a radio stream, and are never stored
+	Transient bool `json:"transient,omitempty" gorm:"-"`
 }
 
 type ReplayGain struct {
@@ -69,6 +112,9 @@ type ReplayGain struct {
 	TrackPeak float64 `json:"track_peak"`
 	AlbumGain float64 `json:"album_gain"`
 	AlbumPeak float64 `json:"album_peak"`
+	// Estimated marks values derived from a quick playback-time analysis
+	// rather than tags or a full loudness scan.
+	Estimated bool `json:"estimated" gorm:"column:replay_gain_estimated"`
 }
 
 func NewTrack(filePath string) (*Track, error) {
@@ -85,6 +131,7 @@ func NewTrack(filePath string) (*Track, error) {
 	return &Track{
 		ID:        generateTrackID(),
 		FilePath:  filepath.Clean(filePath),
+		Source:    SourceFromPath(filePath),
 		Format:    format,
 		DateAdded: now,
 		CreatedAt: now,
@@ -94,6 +141,60 @@ func NewTrack(filePath string) (*Track, error) {
 	}, nil
 }
 
+// NewTransientTrack creates a track for a file or stream that is played
+// without being added to the library. The title defaults to the file or
+// stream name.
+func NewTransientTrack(source Source, title string) (*Track, error) {
+	if err := source.Validate(); err != nil {
+		return nil, err
+	}
+	if source.Kind == SourceFile {
+		source.URI = filepath.Clean(source.URI)
+	}
+
+	now := time.Now()
+	return &Track{
+		ID:        generateTransientTrackID(),
+		FilePath:  source.URI,
+		Source:    source,
+		Title:     strings.TrimSpace(title),
+		Format:    detectFormat(source.Name()),
+		DateAdded: now,
+		CreatedAt: now,
+		UpdatedAt: now,
+		IsValid:   true,
+		Channels:  2,
+		Transient: true,
+	}, nil
+}
+
+// NewArchiveTrack creates a library track for an audio file inside a zip
+// archive, played from the archive without unpacking it
+func NewArchiveTrack(archivePath, entry string) (*Track, error) {
+	source := ArchiveSource(archivePath, entry)
+	if err := source.Validate(); err != nil {
+		return nil, err
+	}
+
+	format := detectFormat(entry)
+	if format == "" {
+		return nil, ErrUnsupportedFormat
+	}
+
+	now := time.Now()
+	return &Track{
+		ID:        generateTrackID(),
+		FilePath:  source.URI,
+		Source:    source,
+		Format:    format,
+		DateAdded: now,
+		CreatedAt: now,
+		UpdatedAt: now,
+		IsValid:   true,
+		Channels:  2,
+	}, nil
+}
+
 func (t *Track) Validate() error {
 	if t.FilePath == "" {
 		return fmt.Errorf("%w: file path is required", ErrInvalidTrack)
@@ -114,6 +215,13 @@ func (t *Track) Validate() error {
 		}
 	}
 
+	if t.Source.Kind == "" {
+		t.Source = SourceFromPath(t.FilePath)
+	}
+	if err := t.Source.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTrack, err)
+	}
+
 	return nil
 }
 
@@ -133,6 +241,26 @@ func (t *Track) SetRating(rating int) error {
 	return nil
 }
 
+// SetFavorite marks or unmarks the track as a favorite
+func (t *Track) SetFavorite(favorite bool) {
+	t.Favorite = favorite
+	t.UpdatedAt = time.Now()
+}
+
+// MarkInvalid flags the track as unplayable with the given reason
+func (t *Track) MarkInvalid(reason string) {
+	t.IsValid = false
+	t.Error = reason
+	t.UpdatedAt = time.Now()
+}
+
+// MarkValid clears any previous validation error
+func (t *Track) MarkValid() {
+	t.IsValid = true
+	t.Error = ""
+	t.UpdatedAt = time.Now()
+}
+
 func (t *Track) GetDisplayTitle() string {
 	if t.Title != "" {
 		return t.Title
@@ -150,6 +278,61 @@ func (t *Track) GetDisplayArtist() string {
 	return "Unknown Artist"
 }
 
+// FieldValue returns the value of a track field by the name used in smart
+// playlist rules, and whether the name is known
+func (t *Track) FieldValue(field string) (interface{}, bool) {
+	switch strings.ToLower(field) {
+	case "title":
+		return t.GetDisplayTitle(), true
+	case "artist":
+		return t.Artist, true
+	case "album":
+		return t.Album, true
+	case "album_artist":
+		return t.AlbumArtist, true
+	case "genre":
+		return t.Genre, true
+	case "composer":
+		return t.Composer, true
+	case "publisher", "label":
+		return t.Publisher, true
+	case "year":
+		return t.Year, true
+	case "rating":
+		return t.Rating, true
+	case "play_count":
+		return t.PlayCount, true
+	case "bpm":
+		return t.BPM, true
+	case "duration":
+		return t.Duration, true
+	case "date_added":
+		return t.DateAdded, true
+	case "format":
+		return string(t.Format), true
+	case "tag", "tags":
+		return t.UserTags, true
+	case "audiobook":
+		return t.IsAudiobook, true
+	case "language":
+		return t.Language, true
+	case "explicit":
+		return t.IsExplicit(), true
+	case "clean":
+		return t.Advisory == AdvisoryClean, true
+	case "live":
+		return t.IsLive, true
+	default:
+		return nil, false
+	}
+}
+
+// IsExplicit reports whether the track is rated explicit, which family
+// mode keeps out of shuffle and the auto-DJ
+func (t *Track) IsExplicit() bool {
+	return t.Advisory == AdvisoryExplicit
+}
+
 func (t *Track) GetSortKey() string {
 	artist := strings.ToLower(t.GetDisplayArtist())
 	album := strings.ToLower(t.Album)
@@ -157,7 +340,47 @@ func (t *Track) GetSortKey() string {
 	return fmt.Sprintf("%s-%s-%s-%s", artist, album, track, t.ID)
 }
 
+// AlbumKey returns a key identifying the album release the track belongs
+// to, or an empty string if the track has no album
+func (t *Track) AlbumKey() string {
+	if t.Album == "" {
+		return ""
+	}
+	artist := t.AlbumArtist
+	if artist == "" {
+		artist = t.Artist
+	}
+	return strings.ToLower(artist) + "\x00" + strings.ToLower(t.Album)
+}
+
+// IsSameAlbum returns true if both tracks belong to the same album release
+func (t *Track) IsSameAlbum(other *Track) bool {
+	if other == nil {
+		return false
+	}
+	key := t.AlbumKey()
+	return key != "" && key == other.AlbumKey()
+}
+
+// SetFilePath points the track at a new file, keeping its source in step
+func (t *Track) SetFilePath(path string) {
+	t.FilePath = path
+	t.Source = SourceFromPath(path)
+}
+
+// GetSource returns where the track's audio comes from. Tracks stored before
+// sources existed derive it from the file path.
+func (t *Track) GetSource() Source {
+	if t.Source.Kind == "" {
+		return SourceFromPath(t.FilePath)
+	}
+	return t.Source
+}
+
 func (t *Track) IsNetworkPath() bool {
+	if t.Source.Kind != "" && !t.Source.IsLocal() {
+		return true
+	}
 	return strings.HasPrefix(t.FilePath, "\\\\") || 
 		   strings.HasPrefix(t.FilePath, "//") ||
 		   strings.HasPrefix(t.FilePath, "smb://") ||
@@ -189,6 +412,8 @@ func detectFormat(filePath string) AudioFormat {
 		return FormatOGG
 	case "wav":
 		return FormatWAV
+	case "aiff", "aif", "aifc":
+		return FormatAIFF
 	case "aac":
 		return FormatAAC
 	case "wma":
@@ -206,6 +431,10 @@ func generateTrackID() string {
 	return fmt.Sprintf("track_%d_%d", time.Now().UnixNano(), randomInt())
 }
 
+func generateTransientTrackID() string {
+	return fmt.Sprintf("transient_%d_%d", time.Now().UnixNano(), randomInt())
+}
+
 func randomInt() int {
 	return int(time.Now().UnixNano() % 1000000)
 }
@@ -220,6 +449,7 @@ func GetSupportedFormats() []AudioFormat {
 		FormatFLAC,
 		FormatOGG,
 		FormatWAV,
+		FormatAIFF,
 		FormatAAC,
 		FormatWMA,
 		FormatM4A,
@@ -227,6 +457,14 @@ func GetSupportedFormats() []AudioFormat {
 	}
 }
 
+// Subfolder is a folder directly inside another, with how many tracks are
+// in it and its own subfolders
+type Subfolder struct {
+	Name          string `json:"name"`
+	TrackCount    int    `json:"trackCount"`
+	HasSubfolders bool   `json:"hasSubfolders"`
+}
+
 type TrackRepository interface {
 	Create(track *Track) error
 	Update(track *Track) error
@@ -236,10 +474,33 @@ type TrackRepository interface {
 	FindAll() ([]*Track, error)
 	FindByArtist(artist string) ([]*Track, error)
 	FindByAlbum(album string) ([]*Track, error)
+	FindByAlbumKey(albumKey string) ([]*Track, error)
+	FindAlbums(artist string) ([]*Album, error)
+	FindArtists() ([]*Artist, error)
 	FindByGenre(genre string) ([]*Track, error)
+	FindByComposer(composer string) ([]*Track, error)
+	FindByPublisher(publisher string) ([]*Track, error)
+	GetComposers() ([]string, error)
+	GetPublishers() ([]string, error)
 	Search(query string) ([]*Track, error)
 	GetRecentlyPlayed(limit int) ([]*Track, error)
 	GetMostPlayed(limit int) ([]*Track, error)
 	GetRecentlyAdded(limit int) ([]*Track, error)
+	FindInvalid() ([]*Track, error)
+	CountByAlbumArt(path string) (int64, error)
+	UpdateStatus(track *Track) error
+	UpdateRating(track *Track) error
+	UpdateTags(track *Track) error
+	UpdateReplayGain(track *Track) error
+	FindFavorites() ([]*Track, error)
+	CountUnder(dir string) (int64, error)
+	FindSubfolders(dir string) ([]*Subfolder, error)
+	FindInFolder(dir string, offset, limit int) ([]*Track, int64, error)
+	FindUnder(dir string) ([]*Track, error)
+	FindDuplicates(track *Track) ([]*Track, error)
+	FindByFingerprint(fingerprint string) ([]*Track, error)
+	FindBySmartRules(rules *SmartRules, now time.Time) ([]*Track, error)
+	FindByFilter(filter TrackFilter) ([]*Track, error)
+	FacetCounts(filter TrackFilter) (FacetCounts, error)
 	Count() (int64, error)
 }
\ No newline at end of file
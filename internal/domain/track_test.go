@@ -61,6 +61,23 @@ func TestNewTrack(t *testing.T) {
 	}
 }
 
+func TestNewTransientTrack(t *testing.T) {
+	stream, err := NewTransientTrack(SourceFromPath("https://radio.example.com/live.mp3"), " Live Radio ")
+	require.NoError(t, err)
+	assert.True(t, stream.Transient)
+	assert.Equal(t, SourceStream, stream.Source.Kind)
+	assert.Equal(t, "Live Radio", stream.Title)
+	assert.Equal(t, FormatMP3, stream.Format)
+
+	file, err := NewTransientTrack(SourceFromPath("/downloads/song.flac"), "")
+	require.NoError(t, err)
+	assert.Equal(t, "song.flac", file.GetDisplayTitle())
+	assert.Equal(t, FormatFLAC, file.Format)
+
+	_, err = NewTransientTrack(Source{}, "Nothing")
+	assert.ErrorIs(t, err, ErrInvalidSource)
+}
+
 func TestTrack_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -300,6 +317,52 @@ func TestTrack_IsNetworkPath(t *testing.T) {
 	}
 }
 
+func TestTrack_IsSameAlbum(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        *Track
+		b        *Track
+		expected bool
+	}{
+		{
+			name:     "Same album and artist",
+			a:        &Track{Artist: "Artist", Album: "Album"},
+			b:        &Track{Artist: "artist", Album: "ALBUM"},
+			expected: true,
+		},
+		{
+			name:     "Album artist overrides track artist",
+			a:        &Track{Artist: "Guest", AlbumArtist: "Various", Album: "Compilation"},
+			b:        &Track{Artist: "Other", AlbumArtist: "Various", Album: "Compilation"},
+			expected: true,
+		},
+		{
+			name:     "Same title by different artists",
+			a:        &Track{Artist: "Artist A", Album: "Greatest Hits"},
+			b:        &Track{Artist: "Artist B", Album: "Greatest Hits"},
+			expected: false,
+		},
+		{
+			name:     "Missing album",
+			a:        &Track{Artist: "Artist"},
+			b:        &Track{Artist: "Artist"},
+			expected: false,
+		},
+		{
+			name:     "Nil other",
+			a:        &Track{Artist: "Artist", Album: "Album"},
+			b:        nil,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.a.IsSameAlbum(tt.b))
+		})
+	}
+}
+
 func TestTrack_Clone(t *testing.T) {
 	now := time.Now()
 	original := &Track{
@@ -352,6 +415,8 @@ func TestDetectFormat(t *testing.T) {
 		{"/music/song.ogg", FormatOGG},
 		{"/music/song.oga", FormatOGG},
 		{"/music/song.wav", FormatWAV},
+		{"/music/song.aiff", FormatAIFF},
+		{"/music/song.aif", FormatAIFF},
 		{"/music/song.aac", FormatAAC},
 		{"/music/song.wma", FormatWMA},
 		{"/music/song.m4a", FormatM4A},
@@ -394,6 +459,7 @@ func TestGetSupportedFormats(t *testing.T) {
 	assert.Contains(t, formats, FormatFLAC)
 	assert.Contains(t, formats, FormatOGG)
 	assert.Contains(t, formats, FormatWAV)
+	assert.Contains(t, formats, FormatAIFF)
 	assert.Contains(t, formats, FormatAAC)
 	assert.Contains(t, formats, FormatWMA)
 	assert.Contains(t, formats, FormatM4A)
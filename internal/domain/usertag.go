@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidUserTag  = errors.New("invalid user tag")
+	ErrUserTagNotFound = errors.New("user tag not found")
+)
+
+// maxUserTagName bounds tag names so they fit on a chip in the UI
+const maxUserTagName = 64
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// UserTag is a free-form label such as "workout" or "rainy day" that the
+// user attaches to tracks. Unlike genre it is never read from or written to
+// files, and a track can carry any number of them.
+type UserTag struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null"`
+	Color     string    `json:"color"` // "#rrggbb", or empty for the default
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewUserTag(name, color string) (*UserTag, error) {
+	tag := &UserTag{
+		ID:        generateUserTagID(),
+		Name:      strings.TrimSpace(name),
+		Color:     color,
+		CreatedAt: time.Now(),
+	}
+
+	if err := tag.Validate(); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (t *UserTag) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidUserTag)
+	}
+
+	if len([]rune(t.Name)) > maxUserTagName {
+		return fmt.Errorf("%w: name is longer than %d characters", ErrInvalidUserTag, maxUserTagName)
+	}
+
+	if t.Color != "" && !tagColorPattern.MatchString(t.Color) {
+		return fmt.Errorf("%w: color must be #rrggbb", ErrInvalidUserTag)
+	}
+
+	return nil
+}
+
+// HasUserTag reports whether the track carries a tag, ignoring case. The
+// track's tags must have been loaded with UserTagRepository.LoadForTracks.
+func (t *Track) HasUserTag(name string) bool {
+	for _, tag := range t.UserTags {
+		if strings.EqualFold(tag, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func generateUserTagID() string {
+	return fmt.Sprintf("tag_%d_%d", time.Now().UnixNano(), randomInt())
+}
+
+type UserTagRepository interface {
+	Create(tag *UserTag) error
+	Update(tag *UserTag) error
+	Delete(id string) error
+	FindByID(id string) (*UserTag, error)
+	FindByName(name string) (*UserTag, error)
+	FindAll() ([]*UserTag, error)
+	CountTracks() (map[string]int64, error)
+	AddToTracks(tagID string, trackIDs []string) error
+	RemoveFromTracks(tagID string, trackIDs []string) error
+	FindByTrack(trackID string) ([]*UserTag, error)
+	FindTrackIDs(tagID string) ([]string, error)
+	LoadForTracks(tracks []*Track) error
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUserTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		tagName string
+		color   string
+		wantErr bool
+	}{
+		{"plain", "workout", "", false},
+		{"with color", "rainy day", "#3366cc", false},
+		{"trimmed", "  chill  ", "", false},
+		{"empty name", "   ", "", true},
+		{"name too long", strings.Repeat("a", maxUserTagName+1), "", true},
+		{"bad color", "focus", "blue", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := NewUserTag(tt.tagName, tt.color)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidUserTag)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, strings.TrimSpace(tt.tagName), tag.Name)
+			assert.NotEmpty(t, tag.ID)
+		})
+	}
+}
+
+func TestTrack_HasUserTag(t *testing.T) {
+	track := &Track{UserTags: []string{"Workout", "rainy day"}}
+
+	assert.True(t, track.HasUserTag("workout"))
+	assert.True(t, track.HasUserTag("Rainy Day"))
+	assert.False(t, track.HasUserTag("chill"))
+
+	value, ok := track.FieldValue("tag")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"Workout", "rainy day"}, value)
+}
@@ -0,0 +1,198 @@
+// Package events is an in-process publish/subscribe bus that lets backend
+// subsystems announce what happened without knowing who is listening. The
+// player, scanner and playlist manager publish to it; the App forwards
+// events to the frontend, and integrations such as scrobblers or media
+// controls subscribe to the same topics.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// Topic names a kind of event and the payload type it carries. Topics are
+// declared by the package that publishes them.
+type Topic[T any] struct {
+	name string
+}
+
+// NewTopic declares a topic. Names follow the frontend's "area:event" form
+// so events can be forwarded under the same name.
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{name: name}
+}
+
+// Name returns the topic's name
+func (t Topic[T]) Name() string {
+	return t.name
+}
+
+// Event is a published event as seen by SubscribeAll
+type Event struct {
+	Topic   string
+	Payload interface{}
+	Time    time.Time
+}
+
+// Bus delivers published events to subscribers. Each subscriber gets
+// events in the order they were published, on a goroutine of its own, so
+// publishers never wait on slow or re-entrant handlers. A nil *Bus accepts
+// publishes and drops them.
+type Bus struct {
+	subscribers map[uint64]*subscriber
+	nextID      uint64
+	closed      bool
+	mu          sync.RWMutex
+}
+
+// NewBus creates an event bus
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[uint64]*subscriber)}
+}
+
+// Publish sends an event to the topic's subscribers
+func Publish[T any](b *Bus, topic Topic[T], payload T) {
+	if b == nil {
+		return
+	}
+	b.publish(Event{Topic: topic.name, Payload: payload, Time: time.Now()})
+}
+
+// Subscribe calls handler for each event published to a topic, until the
+// returned function is called
+func Subscribe[T any](b *Bus, topic Topic[T], handler func(T)) (unsubscribe func()) {
+	return b.subscribe(topic.name, func(event Event) {
+		handler(event.Payload.(T))
+	})
+}
+
+// SubscribeAll calls handler for every event published to the bus, such as
+// to log or forward them
+func (b *Bus) SubscribeAll(handler func(Event)) (unsubscribe func()) {
+	return b.subscribe("", handler)
+}
+
+// Close stops delivery to all subscribers. Events still queued are
+// dropped and later publishes are ignored.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for id, sub := range b.subscribers {
+		sub.stop()
+		delete(b.subscribers, id)
+	}
+}
+
+func (b *Bus) subscribe(topic string, handler func(Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return func() {}
+	}
+
+	b.nextID++
+	id := b.nextID
+	sub := newSubscriber(topic, handler)
+	b.subscribers[id] = sub
+	go sub.run()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if sub, ok := b.subscribers[id]; ok {
+			sub.stop()
+			delete(b.subscribers, id)
+		}
+	}
+}
+
+func (b *Bus) publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return
+	}
+	for _, sub := range b.subscribers {
+		if sub.topic == "" || sub.topic == event.Topic {
+			sub.enqueue(event)
+		}
+	}
+}
+
+// subscriber queues events for one handler. The queue is unbounded so a
+// publisher holding its own locks never blocks on delivery.
+type subscriber struct {
+	topic   string // Empty for all topics
+	handler func(Event)
+	queue   []Event
+	wake    chan struct{}
+	done    chan struct{}
+	mu      sync.Mutex
+}
+
+func newSubscriber(topic string, handler func(Event)) *subscriber {
+	return &subscriber{
+		topic:   topic,
+		handler: handler,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+}
+
+func (s *subscriber) enqueue(event Event) {
+	s.mu.Lock()
+	s.queue = append(s.queue, event)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber) stop() {
+	close(s.done)
+}
+
+func (s *subscriber) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.wake:
+		}
+
+		s.mu.Lock()
+		pending := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+
+		for _, event := range pending {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			s.deliver(event)
+		}
+	}
+}
+
+// deliver runs the handler, keeping a panicking handler from taking down
+// the subscription
+func (s *subscriber) deliver(event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorLog("Event handler panicked",
+				logger.String("topic", event.Topic),
+				logger.Any("panic", r))
+		}
+	}()
+	s.handler(event)
+}
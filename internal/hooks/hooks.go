@@ -0,0 +1,262 @@
+// Package hooks runs user-defined commands and webhooks when playback
+// events happen, such as a track change, so the now playing track can feed
+// a stream overlay or a custom log without writing a plugin. Arguments and
+// webhook URLs are templates filled in with the track's metadata. It also
+// keeps a now playing text file up to date for overlays to read.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// Event is a playback event hooks can run on
+type Event string
+
+const (
+	EventTrackChanged Event = "track_changed"
+	EventPlay         Event = "play"
+	EventPause        Event = "pause"
+	EventStop         Event = "stop"
+)
+
+// Events are the events hooks can run on
+var Events = []Event{EventTrackChanged, EventPlay, EventPause, EventStop}
+
+// runTimeout bounds how long a command or webhook may take
+const runTimeout = 30 * time.Second
+
+var ErrInvalidHook = errors.New("invalid hook")
+
+// Hook runs a command or calls a webhook on an event. Command is run
+// directly, not through a shell, with Args filled in from Data, so
+// metadata can't break out of its argument. URL, also a template, is sent
+// Data as JSON in a POST.
+type Hook struct {
+	Event   Event
+	Command string
+	Args    []string
+	URL     string
+}
+
+// Data is what a hook is told about an event, and what its templates can
+// use, as in {{.Artist}} - {{.Title}}
+type Data struct {
+	Event       Event     `json:"event"`
+	Time        time.Time `json:"time"`
+	TrackID     string    `json:"trackId"`
+	Title       string    `json:"title"`
+	Artist      string    `json:"artist"`
+	Album       string    `json:"album"`
+	AlbumArtist string    `json:"albumArtist"`
+	Genre       string    `json:"genre"`
+	Year        int       `json:"year"`
+	TrackNumber int       `json:"trackNumber"`
+	Path        string    `json:"path"`
+	Duration    float64   `json:"duration"` // In seconds
+}
+
+// NewData describes an event on a track, which may be nil
+func NewData(event Event, track *domain.Track) Data {
+	data := Data{Event: event, Time: time.Now()}
+	if track == nil {
+		return data
+	}
+	data.TrackID = track.ID
+	data.Title = track.GetDisplayTitle()
+	data.Artist = track.Artist
+	data.Album = track.Album
+	data.AlbumArtist = track.AlbumArtist
+	data.Genre = track.Genre
+	data.Year = track.Year
+	data.TrackNumber = track.TrackNumber
+	data.Path = track.FilePath
+	data.Duration = track.Duration.Seconds()
+	return data
+}
+
+// compiled is a hook with its templates parsed
+type compiled struct {
+	Hook
+	args []*template.Template
+	url  *template.Template
+}
+
+// compile checks a hook and parses its templates
+func compile(hook Hook) (*compiled, error) {
+	if !slices.Contains(Events, hook.Event) {
+		return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidHook, hook.Event)
+	}
+	hook.Command = strings.TrimSpace(hook.Command)
+	hook.URL = strings.TrimSpace(hook.URL)
+	if (hook.Command == "") == (hook.URL == "") {
+		return nil, fmt.Errorf("%w: needs either a command or a URL", ErrInvalidHook)
+	}
+
+	c := &compiled{Hook: hook}
+	for _, arg := range hook.Args {
+		tmpl, err := parseTemplate("arg", arg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidHook, err)
+		}
+		c.args = append(c.args, tmpl)
+	}
+	if hook.URL != "" {
+		tmpl, err := parseTemplate("url", hook.URL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidHook, err)
+		}
+		c.url = tmpl
+	}
+	return c, nil
+}
+
+// Runner runs the hooks for each event as it is fired
+type Runner struct {
+	client *http.Client
+
+	mu    sync.RWMutex
+	hooks []*compiled
+}
+
+// NewRunner creates a runner with no hooks
+func NewRunner() *Runner {
+	return &Runner{client: &http.Client{Timeout: runTimeout}}
+}
+
+// Set replaces the hooks. None are changed if any is invalid.
+func (r *Runner) Set(hooks []Hook) error {
+	compiledHooks := make([]*compiled, 0, len(hooks))
+	for i, hook := range hooks {
+		c, err := compile(hook)
+		if err != nil {
+			return fmt.Errorf("hook %d: %w", i+1, err)
+		}
+		compiledHooks = append(compiledHooks, c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = compiledHooks
+	return nil
+}
+
+// Fire runs the hooks for an event in the background. Failures are logged.
+func (r *Runner) Fire(data Data) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, hook := range r.hooks {
+		if hook.Event == data.Event {
+			go r.run(hook, data)
+		}
+	}
+}
+
+// Test runs a hook once on sample data and returns how it went, for trying
+// out a hook before saving it
+func (r *Runner) Test(hook Hook, data Data) error {
+	c, err := compile(hook)
+	if err != nil {
+		return err
+	}
+	return r.execute(c, data)
+}
+
+func (r *Runner) run(hook *compiled, data Data) {
+	if err := r.execute(hook, data); err != nil {
+		logger.Warn("Hook failed",
+			logger.String("event", string(hook.Event)),
+			logger.String("command", hook.Command),
+			logger.String("url", hook.URL),
+			logger.Error(err))
+	}
+}
+
+func (r *Runner) execute(hook *compiled, data Data) error {
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+	defer cancel()
+
+	if hook.Command != "" {
+		args := make([]string, len(hook.args))
+		for i, tmpl := range hook.args {
+			arg, err := render(tmpl, data)
+			if err != nil {
+				return err
+			}
+			args[i] = arg
+		}
+
+		output, err := exec.CommandContext(ctx, hook.Command, args...).CombinedOutput()
+		if message := strings.TrimSpace(string(output)); err != nil && message != "" {
+			return fmt.Errorf("%w: %s", err, message)
+		}
+		return err
+	}
+
+	url, err := render(hook.url, data)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "WinRamp/1.0")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// parseTemplate parses a template and tries it on empty data, which
+// catches fields Data doesn't have
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, Data{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func render(tmpl *template.Template, data Data) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Validate checks a hook without running it
+func Validate(hook Hook) error {
+	_, err := compile(hook)
+	return err
+}
@@ -0,0 +1,157 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// TestHelperProcess is run as a hook's command by the tests below. It
+// writes its arguments to the file named by WINRAMP_HOOK_OUTPUT, or fails
+// when asked to.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("WINRAMP_HOOK_HELPER") != "1" {
+		return
+	}
+	args := os.Args
+	for i, arg := range args {
+		if arg == "--" {
+			args = args[i+1:]
+			break
+		}
+	}
+	if len(args) > 0 && args[0] == "fail" {
+		fmt.Fprintln(os.Stderr, "something broke")
+		os.Exit(3)
+	}
+	if err := os.WriteFile(os.Getenv("WINRAMP_HOOK_OUTPUT"), []byte(strings.Join(args, "|")), 0o644); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		hook Hook
+		ok   bool
+	}{
+		{"command", Hook{Event: EventPlay, Command: "notify", Args: []string{"{{.Artist}} - {{.Title}}"}}, true},
+		{"webhook", Hook{Event: EventTrackChanged, URL: "http://localhost/{{.TrackID}}"}, true},
+		{"unknown event", Hook{Event: "seek", Command: "notify"}, false},
+		{"neither", Hook{Event: EventStop, Command: "  "}, false},
+		{"both", Hook{Event: EventStop, Command: "notify", URL: "http://localhost"}, false},
+		{"bad template", Hook{Event: EventPlay, Command: "notify", Args: []string{"{{.Title"}}, false},
+		{"unknown field", Hook{Event: EventPlay, URL: "http://localhost/{{.Rating}}"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.hook)
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidHook)
+			}
+		})
+	}
+}
+
+func TestSetKeepsHooksOnError(t *testing.T) {
+	r := NewRunner()
+	require.NoError(t, r.Set([]Hook{{Event: EventPlay, Command: "notify"}}))
+
+	err := r.Set([]Hook{{Event: EventPlay, Command: "notify"}, {Event: "seek", Command: "notify"}})
+	assert.ErrorIs(t, err, ErrInvalidHook)
+	assert.Contains(t, err.Error(), "hook 2")
+	assert.Len(t, r.hooks, 1)
+}
+
+func TestCommandHook(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "args.txt")
+	t.Setenv("WINRAMP_HOOK_HELPER", "1")
+	t.Setenv("WINRAMP_HOOK_OUTPUT", output)
+	r := NewRunner()
+	data := NewData(EventTrackChanged, testTrack())
+
+	// Metadata stays within its argument, with no shell to interpret it
+	err := r.Test(Hook{
+		Event:   EventTrackChanged,
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperProcess", "--", "{{.Artist}} - {{.Title}}", "{{.Year}}"},
+	}, data)
+	require.NoError(t, err)
+	args, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "Band; rm -rf / - Song|1999", string(args))
+
+	err = r.Test(Hook{Event: EventTrackChanged, Command: os.Args[0], Args: []string{"-test.run=TestHelperProcess", "--", "fail"}}, data)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "something broke")
+}
+
+func TestWebhook(t *testing.T) {
+	received := make(chan Data, 1)
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var data Data
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.Equal(t, "/played/t1", req.URL.Path)
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&data))
+		w.WriteHeader(status)
+		received <- data
+	}))
+	defer server.Close()
+
+	r := NewRunner()
+	require.NoError(t, r.Set([]Hook{
+		{Event: EventPlay, URL: server.URL + "/played/{{.TrackID}}"},
+		{Event: EventStop, URL: server.URL + "/stopped"},
+	}))
+
+	// Only the hooks for the event run
+	r.Fire(NewData(EventPlay, testTrack()))
+	select {
+	case data := <-received:
+		assert.Equal(t, EventPlay, data.Event)
+		assert.Equal(t, "Song", data.Title)
+		assert.Equal(t, 245.0, data.Duration)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not called")
+	}
+
+	status = http.StatusInternalServerError
+	err := r.Test(Hook{Event: EventPlay, URL: server.URL + "/played/{{.TrackID}}"}, NewData(EventPlay, testTrack()))
+	assert.ErrorContains(t, err, "status 500")
+	<-received
+}
+
+func TestNewDataWithoutTrack(t *testing.T) {
+	data := NewData(EventStop, nil)
+	assert.Equal(t, EventStop, data.Event)
+	assert.Empty(t, data.TrackID)
+	assert.False(t, data.Time.IsZero())
+}
+
+func testTrack() *domain.Track {
+	return &domain.Track{
+		ID:       "t1",
+		Title:    "Song",
+		Artist:   "Band; rm -rf /",
+		Year:     1999,
+		FilePath: "song.mp3",
+		Duration: 245 * time.Second,
+	}
+}
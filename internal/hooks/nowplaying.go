@@ -0,0 +1,126 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// DefaultNowPlayingTemplate is written for the current track until another
+// template is set
+const DefaultNowPlayingTemplate = "{{.Artist}} - {{.Title}}"
+
+// NowPlaying renders the current track through a template, for writing to
+// a text file that stream overlays such as OBS read, or for copying. The
+// file is replaced in one go so readers never see it half written.
+type NowPlaying struct {
+	mu       sync.Mutex
+	tmpl     *template.Template
+	path     string // "" writes no file
+	idleText string // Written while nothing plays
+	last     string
+}
+
+// NewNowPlaying creates a now playing output using the default template
+// and writing no file
+func NewNowPlaying() *NowPlaying {
+	tmpl, _ := parseNowPlaying(DefaultNowPlayingTemplate)
+	return &NowPlaying{tmpl: tmpl}
+}
+
+// Configure sets the template, the file written, which may be "" for none,
+// and the text written while nothing plays. An empty template uses the
+// default.
+func (n *NowPlaying) Configure(text, path, idleText string) error {
+	if strings.TrimSpace(text) == "" {
+		text = DefaultNowPlayingTemplate
+	}
+	tmpl, err := parseNowPlaying(text)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.tmpl = tmpl
+	n.path = strings.TrimSpace(path)
+	n.idleText = idleText
+	n.last = ""
+	return nil
+}
+
+// Render fills in the template for an event's track. Without one it
+// returns the idle text.
+func (n *NowPlaying) Render(data Data) (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.render(data)
+}
+
+// Must be called with n.mu held
+func (n *NowPlaying) render(data Data) (string, error) {
+	if data.TrackID == "" {
+		return n.idleText, nil
+	}
+	return render(n.tmpl, data)
+}
+
+// Update writes the text for an event's track to the file, when there is
+// one and the text has changed
+func (n *NowPlaying) Update(data Data) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.path == "" {
+		return nil
+	}
+	text, err := n.render(data)
+	if err != nil {
+		return err
+	}
+	if text == n.last {
+		return nil
+	}
+	if err := writeFileAtomic(n.path, text); err != nil {
+		return err
+	}
+	n.last = text
+	return nil
+}
+
+func parseNowPlaying(text string) (*template.Template, error) {
+	tmpl, err := parseTemplate("nowPlaying", text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid now playing template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// writeFileAtomic writes a file through a temporary file renamed over it
+func writeFileAtomic(path, text string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".nowplaying-*")
+	if err != nil {
+		return fmt.Errorf("failed to write now playing file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(text); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write now playing file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write now playing file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write now playing file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// browseColumns are the track columns albums and artists are summed up
+// from, so browsing doesn't load lyrics and the like for every track.
+// Albums and artists are not stored but derived from the tracks on each
+// call, grouped by Track.AlbumKey, which folds text in ways SQL can't.
+var browseColumns = []string{
+	"id", "artist", "artist_mbid", "album", "album_artist", "genre", "year",
+	"disc_number", "duration", "album_art_path", "date_added", "is_live",
+}
+
+// FindAlbums returns the library's albums, or only those listed under an
+// artist when one is given, ordered by artist and title
+func (r *TrackRepository) FindAlbums(artist string) ([]*domain.Album, error) {
+	query := r.db.Select(browseColumns).Where("album <> ''")
+	if artist != "" {
+		query = query.Where("LOWER(album_artist) = LOWER(?) OR (album_artist = '' AND LOWER(artist) = LOWER(?))", artist, artist)
+	}
+
+	var tracks []*domain.Track
+	if err := query.Find(&tracks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find albums: %w", err)
+	}
+
+	albums := domain.SummarizeAlbums(tracks)
+	if artist == "" {
+		return albums, nil
+	}
+	// SQLite only lowers ASCII, so the query can match too much
+	listed := albums[:0]
+	for _, album := range albums {
+		if strings.EqualFold(album.AlbumArtist, artist) {
+			listed = append(listed, album)
+		}
+	}
+	return listed, nil
+}
+
+// FindArtists returns the artists the library's tracks are listed under,
+// ordered by name
+func (r *TrackRepository) FindArtists() ([]*domain.Artist, error) {
+	var tracks []*domain.Track
+	if err := r.db.Select(browseColumns).
+		Where("artist <> '' OR album_artist <> ''").
+		Find(&tracks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find artists: %w", err)
+	}
+
+	return domain.SummarizeArtists(tracks), nil
+}
+
+// FindByAlbumKey returns the tracks of the album with the given
+// Track.AlbumKey, every version of it included, in disc and track order
+func (r *TrackRepository) FindByAlbumKey(albumKey string) ([]*domain.Track, error) {
+	_, album, ok := strings.Cut(albumKey, "\x00")
+	if !ok || album == "" {
+		return nil, fmt.Errorf("%w: album key %q", domain.ErrInvalidInput, albumKey)
+	}
+
+	// sort_album is folded, so this finds every spelling of the album; the
+	// key then picks out the artist's
+	var tracks []*domain.Track
+	if err := r.db.Where("sort_album = ?", domain.FoldText(album)).
+		Order("disc_number, track_number").
+		Find(&tracks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find tracks by album: %w", err)
+	}
+
+	found := tracks[:0]
+	for _, track := range tracks {
+		if track.AlbumKey() == albumKey {
+			found = append(found, track)
+		}
+	}
+	return found, nil
+}
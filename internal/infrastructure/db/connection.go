@@ -127,7 +127,7 @@ func (d *Database) Initialize(cfg Config) error {
 	d.db = db
 
 	// Run migrations
-	if err := d.Migrate(); err != nil {
+	if err := d.migrate(); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
@@ -139,6 +139,11 @@ func (d *Database) Migrate() error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	return d.migrate()
+}
+
+// migrate runs the migrations; callers must hold d.mu
+func (d *Database) migrate() error {
 	if d.db == nil {
 		return fmt.Errorf("database not initialized")
 	}
@@ -150,7 +155,19 @@ func (d *Database) Migrate() error {
 		&domain.Library{},
 		&domain.WatchFolder{},
 		&domain.PlaylistVersion{},
+		&domain.TrackMarker{},
+		&domain.PlayHistoryEntry{},
+		&domain.UserTag{},
+		&domain.ScanReport{},
+		&domain.ScanEntry{},
+		&domain.ImportRule{},
+		&domain.StreamStation{},
+		&domain.EpisodeProgress{},
+		&domain.FeedSkipRule{},
+		&domain.RemoteClient{},
+		&domain.AlbumRelease{},
 		&PlaylistTrack{}, // Junction table for playlist-track many-to-many
+		&TrackTag{},      // Junction table for track-user tag many-to-many
 	}
 
 	for _, model := range models {
@@ -159,6 +176,16 @@ func (d *Database) Migrate() error {
 		}
 	}
 
+	// Fill in sources for tracks stored before they existed
+	if err := d.migrateTrackSources(); err != nil {
+		return fmt.Errorf("failed to migrate track sources: %w", err)
+	}
+	
+	// Compute sort and search columns for tracks stored before they existed
+	if err := d.migrateSortKeys(); err != nil {
+		return fmt.Errorf("failed to migrate sort keys: %w", err)
+	}
+
 	// Create indexes
 	if err := d.createIndexes(); err != nil {
 		return fmt.Errorf("failed to create indexes: %w", err)
@@ -168,6 +195,65 @@ func (d *Database) Migrate() error {
 	return nil
 }
 
+// migrateTrackSources derives the source of each track that has none from its
+// file path: URLs become streams and everything else a file
+func (d *Database) migrateTrackSources() error {
+	result := d.db.Exec(`UPDATE tracks SET
+		source_kind = CASE
+			WHEN lower(file_path) LIKE 'http://%' OR lower(file_path) LIKE 'https://%' THEN ?
+			WHEN lower(file_path) LIKE 'cdda://%' THEN ?
+			ELSE ?
+		END,
+		source_uri = file_path
+		WHERE source_kind IS NULL OR source_kind = ''`,
+		domain.SourceStream, domain.SourceCD, domain.SourceFile)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		logger.Info("Migrated track sources", logger.Int64("tracks", result.RowsAffected))
+	}
+	return nil
+}
+
+// migrateSortKeys fills the folded sort and search columns of tracks that
+// lack them. Folding needs Unicode tables, so it is done here rather than in
+// SQL.
+func (d *Database) migrateSortKeys() error {
+	var tracks []*domain.Track
+	migrated := 0
+	
+	err := d.db.Where("search_text IS NULL OR search_text = ''").
+		FindInBatches(&tracks, 500, func(batch *gorm.DB, _ int) error {
+			return d.db.Transaction(func(tx *gorm.DB) error {
+				for _, track := range tracks {
+					track.UpdateSortKeys()
+					if err := tx.Model(&domain.Track{}).
+						Where("id = ?", track.ID).
+						UpdateColumns(map[string]interface{}{
+							"sort_title":  track.SortTitle,
+							"sort_artist": track.SortArtist,
+							"sort_album":  track.SortAlbum,
+							"search_text": track.SearchText,
+						}).Error; err != nil {
+						return err
+					}
+				}
+				migrated += len(tracks)
+				return nil
+			})
+		}).Error
+	if err != nil {
+		return err
+	}
+	
+	if migrated > 0 {
+		logger.Info("Computed track sort keys", logger.Int("tracks", migrated))
+	}
+	return nil
+}
+
 func (d *Database) createIndexes() error {
 	indexes := []struct {
 		Table   string
@@ -182,6 +268,7 @@ func (d *Database) createIndexes() error {
 		{"tracks", "idx_tracks_last_played", []string{"last_played"}},
 		{"tracks", "idx_tracks_play_count", []string{"play_count"}},
 		{"tracks", "idx_tracks_rating", []string{"rating"}},
+		{"tracks", "idx_tracks_sort_artist_album", []string{"sort_artist", "sort_album"}},
 		
 		// Playlist indexes
 		{"playlists", "idx_playlists_type", []string{"type"}},
@@ -390,4 +477,11 @@ type PlaylistTrack struct {
 	TrackID    string `gorm:"primaryKey"`
 	Position   int    `gorm:"not null"`
 	AddedAt    time.Time
+}
+
+// TrackTag represents the junction table for the track-user tag many-to-many relationship
+type TrackTag struct {
+	TrackID string `gorm:"primaryKey"`
+	TagID   string `gorm:"primaryKey;index"`
+	AddedAt time.Time
 }
\ No newline at end of file
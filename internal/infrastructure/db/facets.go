@@ -0,0 +1,122 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// decadeColumn is the first year of a track's decade
+const decadeColumn = "(year / 10) * 10"
+
+// facetQueries say how each facet's values are read from the tracks table:
+// the value to show, what to group by, which tracks have a value at all,
+// if not every one does, and the order the chips come in
+var facetQueries = map[domain.Facet]struct {
+	value, group, present, order string
+}{
+	// Genres are grouped ignoring case, showing one of the spellings
+	domain.FacetGenre:    {"MIN(genre)", "LOWER(genre)", "genre <> ''", "count DESC, LOWER(genre)"},
+	domain.FacetDecade:   {"CAST(" + decadeColumn + " AS TEXT)", decadeColumn, "year > 0", decadeColumn},
+	domain.FacetFormat:   {"format", "format", "format <> ''", "count DESC, format"},
+	domain.FacetRating:   {"CAST(rating AS TEXT)", "rating", "", "rating DESC"},
+	domain.FacetLanguage: {"MIN(language)", "LOWER(language)", "language <> ''", "count DESC, LOWER(language)"},
+}
+
+// FacetCounts counts the values of each facet among the tracks a filter
+// matches, leaving a facet's own values out of the filter when counting it
+func (r *TrackRepository) FacetCounts(filter domain.TrackFilter) (domain.FacetCounts, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	counts := make(domain.FacetCounts, len(domain.Facets))
+	for _, facet := range domain.Facets {
+		spec := facetQueries[facet]
+		query := r.db.Model(&domain.Track{})
+		if spec.present != "" {
+			query = query.Where(spec.present)
+		}
+		if where, args := trackFilterWhere(filter, facet); where != "" {
+			query = query.Where(where, args...)
+		}
+
+		values := make([]domain.FacetCount, 0)
+		if err := query.Select(spec.value + " AS value, COUNT(*) AS count").
+			Group(spec.group).
+			Order(spec.order).
+			Scan(&values).Error; err != nil {
+			return nil, fmt.Errorf("failed to count %s facet: %w", facet, err)
+		}
+		counts[facet] = values
+	}
+
+	return counts, nil
+}
+
+// FindByFilter returns the tracks a filter matches
+func (r *TrackRepository) FindByFilter(filter domain.TrackFilter) ([]*domain.Track, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	query := r.db
+	if where, args := trackFilterWhere(filter, ""); where != "" {
+		query = query.Where(where, args...)
+	}
+
+	var tracks []*domain.Track
+	if err := query.Order("sort_artist, sort_album, disc_number, track_number").
+		Find(&tracks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find tracks by filter: %w", err)
+	}
+
+	return tracks, nil
+}
+
+// trackFilterWhere translates a checked filter into a WHERE clause and its
+// arguments, leaving out the skip facet
+func trackFilterWhere(filter domain.TrackFilter, skip domain.Facet) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if query := sanitizeSearchQuery(strings.TrimSpace(filter.Query)); query != "" {
+		conditions = append(conditions, "search_text LIKE ? ESCAPE '!'")
+		args = append(args, "%"+escapeLike(domain.FoldText(query))+"%")
+	}
+	if len(filter.Genres) > 0 && skip != domain.FacetGenre {
+		genres := make([]string, len(filter.Genres))
+		for i, genre := range filter.Genres {
+			genres[i] = strings.ToLower(genre)
+		}
+		conditions = append(conditions, "LOWER(genre) IN ?")
+		args = append(args, genres)
+	}
+	if len(filter.Decades) > 0 && skip != domain.FacetDecade {
+		conditions = append(conditions, decadeColumn+" IN ?")
+		args = append(args, filter.Decades)
+	}
+	if len(filter.Formats) > 0 && skip != domain.FacetFormat {
+		conditions = append(conditions, "format IN ?")
+		args = append(args, filter.Formats)
+	}
+	if len(filter.Ratings) > 0 && skip != domain.FacetRating {
+		conditions = append(conditions, "rating IN ?")
+		args = append(args, filter.Ratings)
+	}
+	if len(filter.Languages) > 0 && skip != domain.FacetLanguage {
+		languages := make([]string, len(filter.Languages))
+		for i, language := range filter.Languages {
+			languages[i] = strings.ToLower(language)
+		}
+		conditions = append(conditions, "LOWER(language) IN ?")
+		args = append(args, languages)
+	}
+	if filter.HideExplicit {
+		conditions = append(conditions, "advisory <> ?")
+		args = append(args, domain.AdvisoryExplicit)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
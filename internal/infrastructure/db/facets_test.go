@@ -0,0 +1,38 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestTrackFilterWhere(t *testing.T) {
+	filter := domain.TrackFilter{
+		Query:   "Björk",
+		Genres:  []string{"Trip-Hop", "Electronic"},
+		Decades: []int{1990},
+		Formats: []domain.AudioFormat{domain.FormatFLAC},
+		Ratings: []int{4, 5},
+	}
+
+	where, args := trackFilterWhere(filter, "")
+	assert.Equal(t, "search_text LIKE ? ESCAPE '!' AND LOWER(genre) IN ? AND (year / 10) * 10 IN ? AND format IN ? AND rating IN ?", where)
+	assert.Equal(t, []interface{}{
+		"%bjork%",
+		[]string{"trip-hop", "electronic"},
+		[]int{1990},
+		[]domain.AudioFormat{domain.FormatFLAC},
+		[]int{4, 5},
+	}, args)
+
+	// A facet is counted without its own values, so its chips can be added to
+	where, args = trackFilterWhere(filter, domain.FacetGenre)
+	assert.NotContains(t, where, "genre")
+	assert.Len(t, args, 4)
+
+	where, args = trackFilterWhere(domain.TrackFilter{Genres: []string{"Jazz"}}, domain.FacetGenre)
+	assert.Empty(t, where)
+	assert.Empty(t, args)
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"gorm.io/gorm"
+)
+
+type PlayHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewPlayHistoryRepository(database *Database) domain.PlayHistoryRepository {
+	return &PlayHistoryRepository{
+		db: database.DB(),
+	}
+}
+
+func (r *PlayHistoryRepository) Record(entry *domain.PlayHistoryEntry) error {
+	if entry.TrackID == "" {
+		return fmt.Errorf("%w: track ID is required", domain.ErrInvalidInput)
+	}
+
+	if err := r.db.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to record play: %w", err)
+	}
+
+	return nil
+}
+
+// FindSince returns plays after the given time, oldest first
+func (r *PlayHistoryRepository) FindSince(since time.Time) ([]*domain.PlayHistoryEntry, error) {
+	var entries []*domain.PlayHistoryEntry
+	if err := r.db.Where("played_at >= ?", since).
+		Order("played_at").
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to find play history: %w", err)
+	}
+
+	return entries, nil
+}
+
+// FindBetween returns plays from one time up to but not including another,
+// oldest first
+func (r *PlayHistoryRepository) FindBetween(from, to time.Time) ([]*domain.PlayHistoryEntry, error) {
+	var entries []*domain.PlayHistoryEntry
+	if err := r.db.Where("played_at >= ? AND played_at < ?", from, to).
+		Order("played_at").
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to find play history: %w", err)
+	}
+
+	return entries, nil
+}
+
+// FindRecent returns the latest plays, newest first
+func (r *PlayHistoryRepository) FindRecent(limit int) ([]*domain.PlayHistoryEntry, error) {
+	var entries []*domain.PlayHistoryEntry
+	if err := r.db.Order("played_at DESC").
+		Limit(limit).
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to find play history: %w", err)
+	}
+
+	return entries, nil
+}
+
+// FindByTrack returns the latest plays of a track, newest first
+func (r *PlayHistoryRepository) FindByTrack(trackID string, limit int) ([]*domain.PlayHistoryEntry, error) {
+	var entries []*domain.PlayHistoryEntry
+	if err := r.db.Where("track_id = ?", trackID).
+		Order("played_at DESC").
+		Limit(limit).
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to find play history: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/winramp/winramp/internal/domain"
+	"gorm.io/gorm"
+)
+
+type ImportRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewImportRuleRepository(database *Database) domain.ImportRuleRepository {
+	return &ImportRuleRepository{
+		db: database.DB(),
+	}
+}
+
+func (r *ImportRuleRepository) Create(rule *domain.ImportRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.db.Create(rule).Error; err != nil {
+		return fmt.Errorf("failed to create import rule: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ImportRuleRepository) Delete(id string) error {
+	result := r.db.Delete(&domain.ImportRule{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete import rule: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return domain.ErrImportRuleNotFound
+	}
+
+	return nil
+}
+
+func (r *ImportRuleRepository) FindAll() ([]*domain.ImportRule, error) {
+	var rules []*domain.ImportRule
+	if err := r.db.Order("folder, created_at").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to find import rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// FindByFolder returns the rules attached to a folder itself, ignoring case
+func (r *ImportRuleRepository) FindByFolder(folder string) ([]*domain.ImportRule, error) {
+	var rules []*domain.ImportRule
+	if err := r.db.Where("folder = ? COLLATE NOCASE", filepath.Clean(folder)).
+		Order("created_at").
+		Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to find import rules: %w", err)
+	}
+
+	return rules, nil
+}
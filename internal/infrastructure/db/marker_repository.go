@@ -0,0 +1,63 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/winramp/winramp/internal/domain"
+	"gorm.io/gorm"
+)
+
+type MarkerRepository struct {
+	db *gorm.DB
+}
+
+func NewMarkerRepository(database *Database) domain.MarkerRepository {
+	return &MarkerRepository{
+		db: database.DB(),
+	}
+}
+
+func (r *MarkerRepository) Create(marker *domain.TrackMarker) error {
+	if err := marker.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.db.Create(marker).Error; err != nil {
+		return fmt.Errorf("failed to create marker: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MarkerRepository) Delete(id string) error {
+	result := r.db.Delete(&domain.TrackMarker{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete marker: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return domain.ErrMarkerNotFound
+	}
+
+	return nil
+}
+
+func (r *MarkerRepository) FindByTrack(trackID string) ([]*domain.TrackMarker, error) {
+	var markers []*domain.TrackMarker
+	if err := r.db.Where("track_id = ?", trackID).
+		Order("position").
+		Find(&markers).Error; err != nil {
+		return nil, fmt.Errorf("failed to find markers: %w", err)
+	}
+
+	return markers, nil
+}
+
+func (r *MarkerRepository) DeleteByTrack(trackID string, markerType domain.MarkerType) error {
+	if err := r.db.Where("track_id = ? AND type = ?", trackID, markerType).
+		Delete(&domain.TrackMarker{}).Error; err != nil {
+		return fmt.Errorf("failed to delete markers: %w", err)
+	}
+
+	return nil
+}
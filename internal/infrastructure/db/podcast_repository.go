@@ -0,0 +1,96 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/winramp/winramp/internal/domain"
+	"gorm.io/gorm"
+)
+
+type EpisodeProgressRepository struct {
+	db *gorm.DB
+}
+
+func NewEpisodeProgressRepository(database *Database) domain.EpisodeProgressRepository {
+	return &EpisodeProgressRepository{
+		db: database.DB(),
+	}
+}
+
+// Save creates or replaces an episode's progress, including a position
+// reset to the start
+func (r *EpisodeProgressRepository) Save(progress *domain.EpisodeProgress) error {
+	if progress.TrackID == "" {
+		return fmt.Errorf("%w: track ID is required", domain.ErrInvalidInput)
+	}
+
+	if err := r.db.Save(progress).Error; err != nil {
+		return fmt.Errorf("failed to save episode progress: %w", err)
+	}
+
+	return nil
+}
+
+func (r *EpisodeProgressRepository) FindByTrack(trackID string) (*domain.EpisodeProgress, error) {
+	var progress domain.EpisodeProgress
+	if err := r.db.First(&progress, "track_id = ?", trackID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find episode progress: %w", err)
+	}
+
+	return &progress, nil
+}
+
+type FeedSkipRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewFeedSkipRuleRepository(database *Database) domain.FeedSkipRuleRepository {
+	return &FeedSkipRuleRepository{
+		db: database.DB(),
+	}
+}
+
+func (r *FeedSkipRuleRepository) Save(rule *domain.FeedSkipRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.db.Save(rule).Error; err != nil {
+		return fmt.Errorf("failed to save skip rule: %w", err)
+	}
+
+	return nil
+}
+
+func (r *FeedSkipRuleRepository) Delete(feed string) error {
+	if err := r.db.Delete(&domain.FeedSkipRule{}, "feed = ?", feed).Error; err != nil {
+		return fmt.Errorf("failed to delete skip rule: %w", err)
+	}
+
+	return nil
+}
+
+func (r *FeedSkipRuleRepository) FindAll() ([]*domain.FeedSkipRule, error) {
+	var rules []*domain.FeedSkipRule
+	if err := r.db.Order("name COLLATE NOCASE").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to find skip rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (r *FeedSkipRuleRepository) FindByFeed(feed string) (*domain.FeedSkipRule, error) {
+	var rule domain.FeedSkipRule
+	if err := r.db.First(&rule, "feed = ?", feed).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find skip rule: %w", err)
+	}
+
+	return &rule, nil
+}
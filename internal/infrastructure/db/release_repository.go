@@ -0,0 +1,56 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/winramp/winramp/internal/domain"
+	"gorm.io/gorm"
+)
+
+type AlbumReleaseRepository struct {
+	db *gorm.DB
+}
+
+func NewAlbumReleaseRepository(database *Database) domain.AlbumReleaseRepository {
+	return &AlbumReleaseRepository{
+		db: database.DB(),
+	}
+}
+
+// Save stores an album's release, replacing any fetched before
+func (r *AlbumReleaseRepository) Save(release *domain.AlbumRelease) error {
+	if err := release.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.db.Save(release).Error; err != nil {
+		return fmt.Errorf("failed to save album release: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AlbumReleaseRepository) FindByAlbumKey(albumKey string) (*domain.AlbumRelease, error) {
+	var release domain.AlbumRelease
+	if err := r.db.First(&release, "album_key = ?", albumKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrReleaseNotFound
+		}
+		return nil, fmt.Errorf("failed to find album release: %w", err)
+	}
+
+	return &release, nil
+}
+
+func (r *AlbumReleaseRepository) Delete(albumKey string) error {
+	result := r.db.Delete(&domain.AlbumRelease{}, "album_key = ?", albumKey)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete album release: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrReleaseNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"gorm.io/gorm"
+)
+
+type RemoteClientRepository struct {
+	db *gorm.DB
+}
+
+func NewRemoteClientRepository(database *Database) domain.RemoteClientRepository {
+	return &RemoteClientRepository{
+		db: database.DB(),
+	}
+}
+
+func (r *RemoteClientRepository) Create(client *domain.RemoteClient) error {
+	if err := client.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.db.Create(client).Error; err != nil {
+		return fmt.Errorf("failed to create remote client: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RemoteClientRepository) Delete(id string) error {
+	result := r.db.Delete(&domain.RemoteClient{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete remote client: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrRemoteClientNotFound
+	}
+
+	return nil
+}
+
+func (r *RemoteClientRepository) FindAll() ([]*domain.RemoteClient, error) {
+	var clients []*domain.RemoteClient
+	if err := r.db.Order("created_at").Find(&clients).Error; err != nil {
+		return nil, fmt.Errorf("failed to find remote clients: %w", err)
+	}
+
+	return clients, nil
+}
+
+func (r *RemoteClientRepository) FindByTokenHash(hash string) (*domain.RemoteClient, error) {
+	var client domain.RemoteClient
+	if err := r.db.First(&client, "token_hash = ?", hash).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrRemoteClientNotFound
+		}
+		return nil, fmt.Errorf("failed to find remote client: %w", err)
+	}
+
+	return &client, nil
+}
+
+func (r *RemoteClientRepository) UpdateLastSeen(id string, at time.Time) error {
+	if err := r.db.Model(&domain.RemoteClient{}).Where("id = ?", id).
+		Update("last_seen_at", at).Error; err != nil {
+		return fmt.Errorf("failed to update remote client: %w", err)
+	}
+
+	return nil
+}
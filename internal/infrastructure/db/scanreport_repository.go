@@ -0,0 +1,108 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/winramp/winramp/internal/domain"
+	"gorm.io/gorm"
+)
+
+type ScanReportRepository struct {
+	db *gorm.DB
+}
+
+func NewScanReportRepository(database *Database) domain.ScanReportRepository {
+	return &ScanReportRepository{
+		db: database.DB(),
+	}
+}
+
+// Create saves a report together with its entries
+func (r *ScanReportRepository) Create(report *domain.ScanReport) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(report).Error; err != nil {
+			return fmt.Errorf("failed to create scan report: %w", err)
+		}
+
+		if len(report.Entries) > 0 {
+			if err := tx.CreateInBatches(report.Entries, 500).Error; err != nil {
+				return fmt.Errorf("failed to create scan report entries: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// FindByID returns a report with its entries
+func (r *ScanReportRepository) FindByID(id string) (*domain.ScanReport, error) {
+	var report domain.ScanReport
+	if err := r.db.First(&report, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrScanReportNotFound
+		}
+		return nil, fmt.Errorf("failed to find scan report: %w", err)
+	}
+
+	return &report, r.loadEntries(&report)
+}
+
+// FindLatest returns the most recent report with its entries
+func (r *ScanReportRepository) FindLatest() (*domain.ScanReport, error) {
+	var report domain.ScanReport
+	if err := r.db.Order("started_at DESC").First(&report).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrScanReportNotFound
+		}
+		return nil, fmt.Errorf("failed to find scan report: %w", err)
+	}
+
+	return &report, r.loadEntries(&report)
+}
+
+// FindRecent returns report summaries, newest first, without their entries
+func (r *ScanReportRepository) FindRecent(limit int) ([]*domain.ScanReport, error) {
+	var reports []*domain.ScanReport
+	if err := r.db.Order("started_at DESC").
+		Limit(limit).
+		Find(&reports).Error; err != nil {
+		return nil, fmt.Errorf("failed to find scan reports: %w", err)
+	}
+
+	return reports, nil
+}
+
+// Prune deletes all but the newest reports
+func (r *ScanReportRepository) Prune(keep int) error {
+	var ids []string
+	if err := r.db.Model(&domain.ScanReport{}).
+		Order("started_at DESC").
+		Offset(keep).
+		Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("failed to find old scan reports: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("report_id IN ?", ids).Delete(&domain.ScanEntry{}).Error; err != nil {
+			return fmt.Errorf("failed to delete scan report entries: %w", err)
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&domain.ScanReport{}).Error; err != nil {
+			return fmt.Errorf("failed to delete scan reports: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *ScanReportRepository) loadEntries(report *domain.ScanReport) error {
+	if err := r.db.Where("report_id = ?", report.ID).
+		Order("change, path").
+		Find(&report.Entries).Error; err != nil {
+		return fmt.Errorf("failed to load scan report entries: %w", err)
+	}
+
+	return nil
+}
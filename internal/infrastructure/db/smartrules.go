@@ -0,0 +1,195 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// smartRuleColumns maps smart playlist rule fields to the track columns
+// holding them. Tags live in their own tables and are handled apart.
+var smartRuleColumns = map[string]string{
+	"title":        "sort_title",
+	"artist":       "sort_artist",
+	"album":        "sort_album",
+	"album_artist": "album_artist",
+	"genre":        "genre",
+	"composer":     "composer",
+	"publisher":    "publisher",
+	"label":        "publisher",
+	"format":       "format",
+	"year":         "year",
+	"rating":       "rating",
+	"play_count":   "play_count",
+	"bpm":          "bpm",
+	"duration":     "duration",
+	"date_added":   "date_added",
+	"audiobook":    "is_audiobook",
+	"language":     "language",
+	"explicit":     "(advisory = 'explicit')",
+	"clean":        "(advisory = 'clean')",
+	"live":         "is_live",
+}
+
+// foldedColumns hold text folded with domain.FoldText, so rules on them
+// ignore accents as well as case, the way the library sorts and searches
+var foldedColumns = map[string]bool{
+	"sort_title":  true,
+	"sort_artist": true,
+	"sort_album":  true,
+}
+
+// tagCondition matches tracks carrying a user tag by name
+const tagCondition = "EXISTS (SELECT 1 FROM track_tags JOIN user_tags ON user_tags.id = track_tags.tag_id " +
+	"WHERE track_tags.track_id = tracks.id AND user_tags.name = ? COLLATE NOCASE)"
+
+// FindBySmartRules returns the tracks that meet smart playlist rules,
+// ordered and limited as they say. The rules are translated into a single
+// query rather than tested against every track.
+func (r *TrackRepository) FindBySmartRules(rules *domain.SmartRules, now time.Time) ([]*domain.Track, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	query := r.db
+	if where, args := smartRulesWhere(rules, now); where != "" {
+		query = query.Where(where, args...)
+	}
+	if order := smartRulesOrder(rules); order != "" {
+		query = query.Order(order)
+	}
+	if rules.Limit > 0 {
+		query = query.Limit(rules.Limit)
+	}
+
+	var tracks []*domain.Track
+	if err := query.Find(&tracks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find tracks by rules: %w", err)
+	}
+
+	return tracks, nil
+}
+
+// smartRulesWhere translates checked rules into a WHERE clause and its
+// arguments. Conditions joined by AND bind tighter than OR, as in
+// SmartRules.Matches.
+func smartRulesWhere(rules *domain.SmartRules, now time.Time) (string, []interface{}) {
+	if len(rules.Conditions) == 0 {
+		return "", nil
+	}
+
+	var groups, group []string
+	var args []interface{}
+	for i, c := range rules.Conditions {
+		if i > 0 && strings.EqualFold(c.AndOr, "OR") {
+			groups = append(groups, "("+strings.Join(group, " AND ")+")")
+			group = nil
+		}
+		clause, clauseArgs := smartCondition(c, now)
+		group = append(group, clause)
+		args = append(args, clauseArgs...)
+	}
+	groups = append(groups, "("+strings.Join(group, " AND ")+")")
+
+	return strings.Join(groups, " OR "), args
+}
+
+// smartCondition translates one checked condition
+func smartCondition(c domain.RuleCondition, now time.Time) (string, []interface{}) {
+	field := strings.ToLower(c.Field)
+	values := c.Values()
+
+	switch field {
+	case "tag", "tags":
+		if c.Operator == domain.OperatorNotContains {
+			return "NOT " + tagCondition, values
+		}
+		return tagCondition, values
+	case "date_added":
+		return dateCondition("date_added", c.Operator, values, now)
+	case "duration":
+		// Stored in nanoseconds, compared in seconds
+		for i, value := range values {
+			values[i] = int64(value.(float64) * float64(time.Second))
+		}
+	}
+
+	column := smartRuleColumns[field]
+	if text, ok := values[0].(string); ok {
+		return textCondition(column, c.Operator, text)
+	}
+
+	switch c.Operator {
+	case domain.OperatorNotEquals:
+		return column + " <> ?", values
+	case domain.OperatorGreater:
+		return column + " > ?", values
+	case domain.OperatorLess:
+		return column + " < ?", values
+	case domain.OperatorBetween:
+		return column + " BETWEEN ? AND ?", values
+	default:
+		return column + " = ?", values
+	}
+}
+
+// textCondition compares a text column ignoring case
+func textCondition(column, operator, text string) (string, []interface{}) {
+	if foldedColumns[column] {
+		text = domain.FoldText(text)
+	} else {
+		column = "LOWER(" + column + ")"
+		text = strings.ToLower(text)
+	}
+
+	switch operator {
+	case domain.OperatorNotEquals:
+		return column + " <> ?", []interface{}{text}
+	case domain.OperatorContains:
+		return column + " LIKE ? ESCAPE '!'", []interface{}{"%" + escapeLike(text) + "%"}
+	case domain.OperatorNotContains:
+		return column + " NOT LIKE ? ESCAPE '!'", []interface{}{"%" + escapeLike(text) + "%"}
+	case domain.OperatorStartsWith:
+		return column + " LIKE ? ESCAPE '!'", []interface{}{escapeLike(text) + "%"}
+	default:
+		return column + " = ?", []interface{}{text}
+	}
+}
+
+// dateCondition compares a date column by whole days: after a date means
+// from the next day on, and between takes in both days
+func dateCondition(column, operator string, values []interface{}, now time.Time) (string, []interface{}) {
+	switch operator {
+	case domain.OperatorInLast:
+		days := values[0].(float64)
+		return column + " > ?", []interface{}{now.Add(-time.Duration(days * float64(24*time.Hour)))}
+	case domain.OperatorBetween:
+		from, to := values[0].(time.Time), values[1].(time.Time)
+		return column + " >= ? AND " + column + " < ?", []interface{}{from, to.AddDate(0, 0, 1)}
+	case domain.OperatorGreater:
+		return column + " >= ?", []interface{}{values[0].(time.Time).AddDate(0, 0, 1)}
+	default:
+		return column + " < ?", values
+	}
+}
+
+// smartRulesOrder translates the rules' ordering into an ORDER BY clause.
+// Tags and flags have no order, as in SmartRules.Apply.
+func smartRulesOrder(rules *domain.SmartRules) string {
+	field := strings.ToLower(rules.OrderBy)
+	column, ok := smartRuleColumns[field]
+	if !ok || field == "audiobook" || field == "explicit" || field == "clean" || field == "live" {
+		return ""
+	}
+
+	switch field {
+	case "album_artist", "genre", "composer", "publisher", "label", "format", "language":
+		column = "LOWER(" + column + ")"
+	}
+	if rules.OrderDesc {
+		return column + " DESC"
+	}
+	return column
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestSmartRulesWhere(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.Local)
+	day := func(d int) time.Time { return time.Date(2026, 6, d, 0, 0, 0, 0, time.Local) }
+
+	tests := []struct {
+		name      string
+		condition domain.RuleCondition
+		where     string
+		args      []interface{}
+	}{
+		{"folded text", domain.RuleCondition{Field: "Artist", Operator: domain.OperatorEquals, Value: "Björk"},
+			"(sort_artist = ?)", []interface{}{"bjork"}},
+		{"text", domain.RuleCondition{Field: "genre", Operator: domain.OperatorContains, Value: "Hip_Hop"},
+			"(LOWER(genre) LIKE ? ESCAPE '!')", []interface{}{"%hip!_hop%"}},
+		{"starts with", domain.RuleCondition{Field: "label", Operator: domain.OperatorStartsWith, Value: "Warp"},
+			"(LOWER(publisher) LIKE ? ESCAPE '!')", []interface{}{"warp%"}},
+		{"number", domain.RuleCondition{Field: "year", Operator: domain.OperatorBetween, Value: []interface{}{1990.0, 1999.0}},
+			"(year BETWEEN ? AND ?)", []interface{}{1990.0, 1999.0}},
+		{"duration", domain.RuleCondition{Field: "duration", Operator: domain.OperatorGreater, Value: 300.0},
+			"(duration > ?)", []interface{}{int64(300 * time.Second)}},
+		{"in last", domain.RuleCondition{Field: "date_added", Operator: domain.OperatorInLast, Value: 7.0},
+			"(date_added > ?)", []interface{}{now.AddDate(0, 0, -7)}},
+		{"between dates", domain.RuleCondition{Field: "date_added", Operator: domain.OperatorBetween, Value: []interface{}{"2026-06-01", "2026-06-10"}},
+			"(date_added >= ? AND date_added < ?)", []interface{}{day(1), day(11)}},
+		{"after date", domain.RuleCondition{Field: "date_added", Operator: domain.OperatorGreater, Value: "2026-06-01"},
+			"(date_added >= ?)", []interface{}{day(2)}},
+		{"tag", domain.RuleCondition{Field: "tag", Operator: domain.OperatorNotContains, Value: "Skip"},
+			"(NOT " + tagCondition + ")", []interface{}{"Skip"}},
+		{"flag", domain.RuleCondition{Field: "audiobook", Operator: domain.OperatorEquals, Value: true},
+			"(is_audiobook = ?)", []interface{}{true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := &domain.SmartRules{Conditions: []domain.RuleCondition{tt.condition}}
+			assert.NoError(t, rules.Validate())
+			where, args := smartRulesWhere(rules, now)
+			assert.Equal(t, tt.where, where)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestSmartRulesWhereGrouping(t *testing.T) {
+	rules := &domain.SmartRules{
+		Conditions: []domain.RuleCondition{
+			{Field: "genre", Operator: domain.OperatorEquals, Value: "jazz"},
+			{Field: "rating", Operator: domain.OperatorGreater, Value: 3.0, AndOr: "AND"},
+			{Field: "year", Operator: domain.OperatorLess, Value: 1960.0, AndOr: "or"},
+		},
+		OrderBy:   "genre",
+		OrderDesc: true,
+	}
+
+	where, args := smartRulesWhere(rules, time.Now())
+	assert.Equal(t, "(LOWER(genre) = ? AND rating > ?) OR (year < ?)", where)
+	assert.Equal(t, []interface{}{"jazz", 3.0, 1960.0}, args)
+	assert.Equal(t, "LOWER(genre) DESC", smartRulesOrder(rules))
+
+	where, args = smartRulesWhere(&domain.SmartRules{}, time.Now())
+	assert.Empty(t, where)
+	assert.Empty(t, args)
+}
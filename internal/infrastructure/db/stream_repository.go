@@ -0,0 +1,82 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/winramp/winramp/internal/domain"
+	"gorm.io/gorm"
+)
+
+type StreamStationRepository struct {
+	db *gorm.DB
+}
+
+func NewStreamStationRepository(database *Database) domain.StreamStationRepository {
+	return &StreamStationRepository{
+		db: database.DB(),
+	}
+}
+
+// Save creates or replaces a station, including cleared fields such as an
+// unset favorite
+func (r *StreamStationRepository) Save(station *domain.StreamStation) error {
+	if err := station.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.db.Save(station).Error; err != nil {
+		return fmt.Errorf("failed to save stream: %w", err)
+	}
+
+	return nil
+}
+
+func (r *StreamStationRepository) Delete(url string) error {
+	result := r.db.Delete(&domain.StreamStation{}, "url = ?", url)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete stream: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrStreamNotFound
+	}
+
+	return nil
+}
+
+func (r *StreamStationRepository) FindByURL(url string) (*domain.StreamStation, error) {
+	var station domain.StreamStation
+	if err := r.db.First(&station, "url = ?", url).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrStreamNotFound
+		}
+		return nil, fmt.Errorf("failed to find stream: %w", err)
+	}
+
+	return &station, nil
+}
+
+// FindRecent returns the streams played most recently, newest first
+func (r *StreamStationRepository) FindRecent(limit int) ([]*domain.StreamStation, error) {
+	var stations []*domain.StreamStation
+	if err := r.db.Where("last_played_at IS NOT NULL").
+		Order("last_played_at DESC").
+		Limit(limit).
+		Find(&stations).Error; err != nil {
+		return nil, fmt.Errorf("failed to find stream history: %w", err)
+	}
+
+	return stations, nil
+}
+
+// FindFavorites returns the favorite streams by name
+func (r *StreamStationRepository) FindFavorites() ([]*domain.StreamStation, error) {
+	var stations []*domain.StreamStation
+	if err := r.db.Where("favorite = ?", true).
+		Order("name COLLATE NOCASE, url").
+		Find(&stations).Error; err != nil {
+		return nil, fmt.Errorf("failed to find favorite streams: %w", err)
+	}
+
+	return stations, nil
+}
@@ -2,10 +2,14 @@ package db
 
 import (
 	"fmt"
+	"path/filepath"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/winramp/winramp/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TrackRepository struct {
@@ -22,6 +26,7 @@ func (r *TrackRepository) Create(track *domain.Track) error {
 	if err := track.Validate(); err != nil {
 		return err
 	}
+	track.UpdateSortKeys()
 	
 	if err := r.db.Create(track).Error; err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint") {
@@ -37,6 +42,7 @@ func (r *TrackRepository) Update(track *domain.Track) error {
 	if err := track.Validate(); err != nil {
 		return err
 	}
+	track.UpdateSortKeys()
 	
 	result := r.db.Model(track).Updates(track)
 	if result.Error != nil {
@@ -51,16 +57,22 @@ func (r *TrackRepository) Update(track *domain.Track) error {
 }
 
 func (r *TrackRepository) Delete(id string) error {
-	result := r.db.Delete(&domain.Track{}, "id = ?", id)
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete track: %w", result.Error)
-	}
-	
-	if result.RowsAffected == 0 {
-		return domain.ErrTrackNotFound
-	}
-	
-	return nil
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&domain.Track{}, "id = ?", id)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete track: %w", result.Error)
+		}
+		
+		if result.RowsAffected == 0 {
+			return domain.ErrTrackNotFound
+		}
+		
+		if err := tx.Delete(&TrackTag{}, "track_id = ?", id).Error; err != nil {
+			return fmt.Errorf("failed to delete track tags: %w", err)
+		}
+		
+		return nil
+	})
 }
 
 func (r *TrackRepository) FindByID(id string) (*domain.Track, error) {
@@ -99,7 +111,7 @@ func (r *TrackRepository) FindAll() ([]*domain.Track, error) {
 func (r *TrackRepository) FindByArtist(artist string) ([]*domain.Track, error) {
 	var tracks []*domain.Track
 	if err := r.db.Where("artist = ? OR album_artist = ?", artist, artist).
-		Order("album, disc_number, track_number").
+		Order("sort_album, disc_number, track_number").
 		Find(&tracks).Error; err != nil {
 		return nil, fmt.Errorf("failed to find tracks by artist: %w", err)
 	}
@@ -121,7 +133,7 @@ func (r *TrackRepository) FindByAlbum(album string) ([]*domain.Track, error) {
 func (r *TrackRepository) FindByGenre(genre string) ([]*domain.Track, error) {
 	var tracks []*domain.Track
 	if err := r.db.Where("genre = ?", genre).
-		Order("artist, album, track_number").
+		Order("sort_artist, sort_album, disc_number, track_number").
 		Find(&tracks).Error; err != nil {
 		return nil, fmt.Errorf("failed to find tracks by genre: %w", err)
 	}
@@ -129,6 +141,61 @@ func (r *TrackRepository) FindByGenre(genre string) ([]*domain.Track, error) {
 	return tracks, nil
 }
 
+func (r *TrackRepository) FindByComposer(composer string) ([]*domain.Track, error) {
+	var tracks []*domain.Track
+	if err := r.db.Where("composer = ?", composer).
+		Order("sort_album, disc_number, track_number").
+		Find(&tracks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find tracks by composer: %w", err)
+	}
+	
+	return tracks, nil
+}
+
+func (r *TrackRepository) FindByPublisher(publisher string) ([]*domain.Track, error) {
+	var tracks []*domain.Track
+	if err := r.db.Where("publisher = ?", publisher).
+		Order("sort_artist, sort_album, disc_number, track_number").
+		Find(&tracks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find tracks by publisher: %w", err)
+	}
+	
+	return tracks, nil
+}
+
+// GetComposers returns the distinct composers in the library, sorted
+func (r *TrackRepository) GetComposers() ([]string, error) {
+	return r.distinct("composer")
+}
+
+// GetPublishers returns the distinct publishers and labels in the library,
+// sorted
+func (r *TrackRepository) GetPublishers() ([]string, error) {
+	return r.distinct("publisher")
+}
+
+// distinct returns the distinct non-empty values of a column, in folded
+// order so accented names sort with their plain spellings
+func (r *TrackRepository) distinct(column string) ([]string, error) {
+	var values []string
+	if err := r.db.Model(&domain.Track{}).
+		Where(column + " <> ''").
+		Distinct(column).
+		Pluck(column, &values).Error; err != nil {
+		return nil, fmt.Errorf("failed to list %s values: %w", column, err)
+	}
+	
+	keys := make(map[string]string, len(values))
+	for _, value := range values {
+		keys[value] = domain.FoldText(value)
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return keys[values[i]] < keys[values[j]]
+	})
+	
+	return values, nil
+}
+
 func (r *TrackRepository) Search(query string) ([]*domain.Track, error) {
 	var tracks []*domain.Track
 	
@@ -148,14 +215,23 @@ func (r *TrackRepository) Search(query string) ([]*domain.Track, error) {
 	// Even though GORM parameterizes, this adds defense in depth
 	query = sanitizeSearchQuery(query)
 	
-	// Build search query with wildcards
-	searchPattern := "%" + strings.ToLower(query) + "%"
+	// Match against the folded search column, so "bjork" finds "Björk"
+	searchPattern := "%" + escapeLike(domain.FoldText(query)) + "%"
+	
+	// Title matches come first, then artist and album ones, so the cap
+	// keeps the likeliest results for callers to rank
+	relevance := clause.OrderBy{Expression: clause.Expr{
+		SQL: "CASE WHEN sort_title LIKE ? ESCAPE '!' THEN 0 WHEN sort_artist LIKE ? ESCAPE '!' THEN 1 " +
+			"WHEN sort_album LIKE ? ESCAPE '!' THEN 2 ELSE 3 END, play_count DESC, " +
+			"sort_artist, sort_album, disc_number, track_number",
+		Vars:               []interface{}{searchPattern, searchPattern, searchPattern},
+		WithoutParentheses: true,
+	}}
 	
 	// Use parameterized query through GORM (already safe)
-	if err := r.db.Where(
-		"LOWER(title) LIKE ? OR LOWER(artist) LIKE ? OR LOWER(album) LIKE ? OR LOWER(genre) LIKE ?",
-		searchPattern, searchPattern, searchPattern, searchPattern,
-	).Limit(1000).Find(&tracks).Error; err != nil {
+	if err := r.db.Where("search_text LIKE ? ESCAPE '!'", searchPattern).
+		Clauses(relevance).
+		Limit(1000).Find(&tracks).Error; err != nil {
 		return nil, fmt.Errorf("failed to search tracks: %w", err)
 	}
 	
@@ -214,6 +290,82 @@ func (r *TrackRepository) GetRecentlyAdded(limit int) ([]*domain.Track, error) {
 	return tracks, nil
 }
 
+// FindInvalid returns tracks that failed decoding or verification
+func (r *TrackRepository) FindInvalid() ([]*domain.Track, error) {
+	var tracks []*domain.Track
+	if err := r.db.Where("is_valid = ?", false).
+		Order("updated_at DESC").
+		Find(&tracks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find invalid tracks: %w", err)
+	}
+	
+	return tracks, nil
+}
+
+// CountByAlbumArt returns how many tracks reference an album art file
+func (r *TrackRepository) CountByAlbumArt(path string) (int64, error) {
+	var count int64
+	if err := r.db.Model(&domain.Track{}).
+		Where("album_art_path = ?", path).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count album art references: %w", err)
+	}
+	
+	return count, nil
+}
+
+// UpdateStatus persists the integrity fields of a track. Unlike Update it
+// writes zero values, so a track can be marked invalid or have its error
+// cleared.
+func (r *TrackRepository) UpdateStatus(track *domain.Track) error {
+	result := r.db.Model(&domain.Track{}).
+		Where("id = ?", track.ID).
+		Updates(map[string]interface{}{
+			"is_valid":   track.IsValid,
+			"error":      track.Error,
+			"checksum":   track.Checksum,
+			"updated_at": track.UpdatedAt,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update track status: %w", result.Error)
+	}
+	
+	if result.RowsAffected == 0 {
+		return domain.ErrTrackNotFound
+	}
+	
+	return nil
+}
+
+// UpdateReplayGain stores a track's gain and peak values, including zero
+// gains, which Update would skip
+func (r *TrackRepository) UpdateReplayGain(track *domain.Track) error {
+	rg := track.ReplayGain
+	if rg == nil {
+		rg = &domain.ReplayGain{}
+	}
+	
+	result := r.db.Model(&domain.Track{}).
+		Where("id = ?", track.ID).
+		Updates(map[string]interface{}{
+			"track_gain":            rg.TrackGain,
+			"track_peak":            rg.TrackPeak,
+			"album_gain":            rg.AlbumGain,
+			"album_peak":            rg.AlbumPeak,
+			"replay_gain_estimated": rg.Estimated,
+			"updated_at":            track.UpdatedAt,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update track gain: %w", result.Error)
+	}
+	
+	if result.RowsAffected == 0 {
+		return domain.ErrTrackNotFound
+	}
+	
+	return nil
+}
+
 func (r *TrackRepository) Count() (int64, error) {
 	var count int64
 	if err := r.db.Model(&domain.Track{}).Count(&count).Error; err != nil {
@@ -228,7 +380,7 @@ func (r *TrackRepository) Count() (int64, error) {
 func (r *TrackRepository) FindByYear(year int) ([]*domain.Track, error) {
 	var tracks []*domain.Track
 	if err := r.db.Where("year = ?", year).
-		Order("artist, album, track_number").
+		Order("sort_artist, sort_album, disc_number, track_number").
 		Find(&tracks).Error; err != nil {
 		return nil, fmt.Errorf("failed to find tracks by year: %w", err)
 	}
@@ -236,10 +388,201 @@ func (r *TrackRepository) FindByYear(year int) ([]*domain.Track, error) {
 	return tracks, nil
 }
 
+// UpdateRating persists a track's rating and favorite flag. Like
+// UpdateStatus it writes zero values, so ratings can be cleared.
+func (r *TrackRepository) UpdateRating(track *domain.Track) error {
+	if err := track.Validate(); err != nil {
+		return err
+	}
+	
+	result := r.db.Model(&domain.Track{}).
+		Where("id = ?", track.ID).
+		Updates(map[string]interface{}{
+			"rating":     track.Rating,
+			"favorite":   track.Favorite,
+			"updated_at": track.UpdatedAt,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update track rating: %w", result.Error)
+	}
+	
+	if result.RowsAffected == 0 {
+		return domain.ErrTrackNotFound
+	}
+	
+	return nil
+}
+
+// UpdateTags saves the editable tag fields of a track, including fields that
+// were cleared
+func (r *TrackRepository) UpdateTags(track *domain.Track) error {
+	if err := track.Validate(); err != nil {
+		return err
+	}
+	track.UpdateSortKeys()
+	
+	result := r.db.Model(&domain.Track{}).
+		Where("id = ?", track.ID).
+		Updates(map[string]interface{}{
+			"title":         track.Title,
+			"artist":        track.Artist,
+			"album":         track.Album,
+			"album_artist":  track.AlbumArtist,
+			"genre":         track.Genre,
+			"year":          track.Year,
+			"track_number":  track.TrackNumber,
+			"disc_number":   track.DiscNumber,
+			"disc_subtitle": track.DiscSubtitle,
+			"composer":      track.Composer,
+			"publisher":     track.Publisher,
+			"artist_mbid":   track.ArtistMBID,
+			"language":      track.Language,
+			"advisory":      track.Advisory,
+			"is_live":       track.IsLive,
+			"comment":       track.Comment,
+			"sort_title":    track.SortTitle,
+			"sort_artist":   track.SortArtist,
+			"sort_album":    track.SortAlbum,
+			"search_text":   track.SearchText,
+			"updated_at":    track.UpdatedAt,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update track tags: %w", result.Error)
+	}
+	
+	if result.RowsAffected == 0 {
+		return domain.ErrTrackNotFound
+	}
+	
+	return nil
+}
+
+func (r *TrackRepository) FindFavorites() ([]*domain.Track, error) {
+	var tracks []*domain.Track
+	if err := r.db.Where("favorite = ?", true).
+		Order("sort_artist, sort_album, disc_number, track_number").
+		Find(&tracks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find favorite tracks: %w", err)
+	}
+	
+	return tracks, nil
+}
+
+// CountUnder returns how many tracks are in dir and its subfolders
+func (r *TrackRepository) CountUnder(dir string) (int64, error) {
+	var count int64
+	if err := r.db.Model(&domain.Track{}).
+		Where("file_path LIKE ? ESCAPE '!'", folderPattern(dir)).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count tracks under folder: %w", err)
+	}
+	
+	return count, nil
+}
+
+// FindSubfolders returns the folders directly inside dir that hold tracks,
+// grouping the paths under dir on their next segment
+func (r *TrackRepository) FindSubfolders(dir string) ([]*domain.Subfolder, error) {
+	sep := string(filepath.Separator)
+	prefix := strings.TrimRight(filepath.Clean(dir), sep) + sep
+	
+	// The rest of each path below dir; SQLite counts characters, not bytes
+	rest := fmt.Sprintf("substr(file_path, %d)", utf8.RuneCountInString(prefix)+1)
+	var folders []*domain.Subfolder
+	if err := r.db.Table("(?) AS under", r.db.Model(&domain.Track{}).
+			Select(rest+" AS rest").
+			Where("file_path LIKE ? ESCAPE '!'", folderPattern(dir))).
+		Select("substr(rest, 1, instr(rest, ?) - 1) AS name, COUNT(*) AS track_count, "+
+			"MAX(instr(substr(rest, instr(rest, ?) + 1), ?) > 0) AS has_subfolders", sep, sep, sep).
+		Where("instr(rest, ?) > 0", sep).
+		Group("name").
+		Scan(&folders).Error; err != nil {
+		return nil, fmt.Errorf("failed to find subfolders: %w", err)
+	}
+	
+	return folders, nil
+}
+
+// FindInFolder returns a page of the tracks directly inside dir, in path
+// order, and the total number of such tracks
+func (r *TrackRepository) FindInFolder(dir string, offset, limit int) ([]*domain.Track, int64, error) {
+	// Tracks inside dir with no further separator in their path
+	direct := func() *gorm.DB {
+		return r.db.Model(&domain.Track{}).
+			Where("file_path LIKE ? ESCAPE '!'", folderPattern(dir)).
+			Where("file_path NOT LIKE ? ESCAPE '!'", folderPattern(dir)+escapeLike(string(filepath.Separator))+"%")
+	}
+	
+	var total int64
+	if err := direct().Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count folder tracks: %w", err)
+	}
+	
+	var tracks []*domain.Track
+	if err := direct().Order("file_path").Offset(offset).Limit(limit).Find(&tracks).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to find folder tracks: %w", err)
+	}
+	
+	return tracks, total, nil
+}
+
+// FindDuplicates returns tracks at other paths that may be copies of a
+// track: the same audio checksum, or the same title, artist and album.
+// Callers confirm matches with Track.IsDuplicateOf.
+func (r *TrackRepository) FindDuplicates(track *domain.Track) ([]*domain.Track, error) {
+	track.UpdateSortKeys()
+	
+	query := r.db.Where("file_path <> ?", track.FilePath)
+	switch {
+	case track.Checksum != "" && track.Title != "" && track.Artist != "":
+		query = query.Where("checksum = ? OR (sort_title = ? AND sort_artist = ? AND sort_album = ?)",
+			track.Checksum, track.SortTitle, track.SortArtist, track.SortAlbum)
+	case track.Checksum != "":
+		query = query.Where("checksum = ?", track.Checksum)
+	case track.Title != "" && track.Artist != "":
+		query = query.Where("sort_title = ? AND sort_artist = ? AND sort_album = ?",
+			track.SortTitle, track.SortArtist, track.SortAlbum)
+	default:
+		return nil, nil
+	}
+	
+	var tracks []*domain.Track
+	if err := query.Find(&tracks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find duplicate tracks: %w", err)
+	}
+	
+	return tracks, nil
+}
+
+// FindByFingerprint returns the tracks with an acoustic fingerprint
+func (r *TrackRepository) FindByFingerprint(fingerprint string) ([]*domain.Track, error) {
+	var tracks []*domain.Track
+	if fingerprint == "" {
+		return tracks, nil
+	}
+	if err := r.db.Where("fingerprint = ?", fingerprint).Find(&tracks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find tracks by fingerprint: %w", err)
+	}
+	
+	return tracks, nil
+}
+
+// FindUnder returns all tracks in dir and its subfolders, in path order
+func (r *TrackRepository) FindUnder(dir string) ([]*domain.Track, error) {
+	var tracks []*domain.Track
+	if err := r.db.Where("file_path LIKE ? ESCAPE '!'", folderPattern(dir)).
+		Order("file_path").
+		Find(&tracks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find tracks under folder: %w", err)
+	}
+	
+	return tracks, nil
+}
+
 func (r *TrackRepository) FindByRating(rating int) ([]*domain.Track, error) {
 	var tracks []*domain.Track
 	if err := r.db.Where("rating = ?", rating).
-		Order("artist, album, track_number").
+		Order("sort_artist, sort_album, disc_number, track_number").
 		Find(&tracks).Error; err != nil {
 		return nil, fmt.Errorf("failed to find tracks by rating: %w", err)
 	}
@@ -250,7 +593,7 @@ func (r *TrackRepository) FindByRating(rating int) ([]*domain.Track, error) {
 func (r *TrackRepository) FindByFormat(format domain.AudioFormat) ([]*domain.Track, error) {
 	var tracks []*domain.Track
 	if err := r.db.Where("format = ?", format).
-		Order("artist, album, track_number").
+		Order("sort_artist, sort_album, disc_number, track_number").
 		Find(&tracks).Error; err != nil {
 		return nil, fmt.Errorf("failed to find tracks by format: %w", err)
 	}
@@ -296,16 +639,22 @@ func (r *TrackRepository) BatchCreate(tracks []*domain.Track) error {
 }
 
 func (r *TrackRepository) DeleteByPath(path string) error {
-	result := r.db.Delete(&domain.Track{}, "file_path = ?", path)
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete track by path: %w", result.Error)
-	}
-	
-	if result.RowsAffected == 0 {
-		return domain.ErrTrackNotFound
-	}
-	
-	return nil
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM track_tags WHERE track_id IN (SELECT id FROM tracks WHERE file_path = ?)", path).Error; err != nil {
+			return fmt.Errorf("failed to delete track tags: %w", err)
+		}
+		
+		result := tx.Delete(&domain.Track{}, "file_path = ?", path)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete track by path: %w", result.Error)
+		}
+		
+		if result.RowsAffected == 0 {
+			return domain.ErrTrackNotFound
+		}
+		
+		return nil
+	})
 }
 
 func (r *TrackRepository) GetStatistics() (map[string]interface{}, error) {
@@ -355,4 +704,17 @@ func (r *TrackRepository) GetStatistics() (map[string]interface{}, error) {
 	}
 	
 	return stats, nil
-}
\ No newline at end of file
+}
+
+// folderPattern returns a LIKE pattern, escaped with '!', matching paths
+// inside dir
+func folderPattern(dir string) string {
+	dir = strings.TrimRight(filepath.Clean(dir), string(filepath.Separator))
+	return escapeLike(dir+string(filepath.Separator)) + "%"
+}
+
+// escapeLike escapes LIKE wildcards using '!' as the escape character,
+// since the path separator may be a backslash
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
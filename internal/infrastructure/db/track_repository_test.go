@@ -0,0 +1,68 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func testTrackRepository(t *testing.T, paths ...string) *TrackRepository {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&domain.Track{}))
+
+	for i, path := range paths {
+		track := &domain.Track{ID: string(rune('a' + i)), FilePath: filepath.FromSlash(path), IsValid: true}
+		require.NoError(t, db.Create(track).Error)
+	}
+	return &TrackRepository{db: db}
+}
+
+func TestFindSubfolders(t *testing.T) {
+	repo := testTrackRepository(t,
+		"/music/loose.mp3",
+		"/music/Björk/Post/01.flac",
+		"/music/Björk/Post/02.flac",
+		"/music/Björk/hidden.mp3",
+		"/music/Air/Moon Safari/01.mp3",
+		"/music/Air/02.mp3",
+		"/music/Zappa/01.mp3",
+		"/other/01.mp3",
+	)
+	music := filepath.FromSlash("/music")
+
+	count, err := repo.CountUnder(music)
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, count)
+
+	folders, err := repo.FindSubfolders(music)
+	require.NoError(t, err)
+	byName := make(map[string]domain.Subfolder)
+	for _, folder := range folders {
+		byName[folder.Name] = *folder
+	}
+	assert.Equal(t, map[string]domain.Subfolder{
+		"Air":   {Name: "Air", TrackCount: 2, HasSubfolders: true},
+		"Björk": {Name: "Björk", TrackCount: 3, HasSubfolders: true},
+		"Zappa": {Name: "Zappa", TrackCount: 1},
+	}, byName)
+
+	// Folder names after multibyte characters are cut in the right place
+	folders, err = repo.FindSubfolders(filepath.FromSlash("/music/Björk/"))
+	require.NoError(t, err)
+	require.Len(t, folders, 1)
+	assert.Equal(t, domain.Subfolder{Name: "Post", TrackCount: 2}, *folders[0])
+
+	folders, err = repo.FindSubfolders(filepath.FromSlash("/music/Zappa"))
+	require.NoError(t, err)
+	assert.Empty(t, folders)
+}
@@ -0,0 +1,240 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserTagRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTagRepository(database *Database) domain.UserTagRepository {
+	return &UserTagRepository{
+		db: database.DB(),
+	}
+}
+
+func (r *UserTagRepository) Create(tag *domain.UserTag) error {
+	if err := tag.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := r.FindByName(tag.Name); err == nil {
+		return fmt.Errorf("%w: tag %q", domain.ErrAlreadyExists, tag.Name)
+	}
+
+	if err := r.db.Create(tag).Error; err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return nil
+}
+
+// Update saves a tag's name and color, including a cleared color
+func (r *UserTagRepository) Update(tag *domain.UserTag) error {
+	if err := tag.Validate(); err != nil {
+		return err
+	}
+
+	if existing, err := r.FindByName(tag.Name); err == nil && existing.ID != tag.ID {
+		return fmt.Errorf("%w: tag %q", domain.ErrAlreadyExists, tag.Name)
+	}
+
+	result := r.db.Model(&domain.UserTag{}).
+		Where("id = ?", tag.ID).
+		Updates(map[string]interface{}{
+			"name":  tag.Name,
+			"color": tag.Color,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update tag: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return domain.ErrUserTagNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a tag and takes it off every track
+func (r *UserTagRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&domain.UserTag{}, "id = ?", id)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete tag: %w", result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			return domain.ErrUserTagNotFound
+		}
+
+		if err := tx.Delete(&TrackTag{}, "tag_id = ?", id).Error; err != nil {
+			return fmt.Errorf("failed to delete track tags: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *UserTagRepository) FindByID(id string) (*domain.UserTag, error) {
+	var tag domain.UserTag
+	if err := r.db.First(&tag, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrUserTagNotFound
+		}
+		return nil, fmt.Errorf("failed to find tag: %w", err)
+	}
+
+	return &tag, nil
+}
+
+// FindByName finds a tag by name, ignoring case
+func (r *UserTagRepository) FindByName(name string) (*domain.UserTag, error) {
+	var tag domain.UserTag
+	if err := r.db.First(&tag, "name = ? COLLATE NOCASE", name).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrUserTagNotFound
+		}
+		return nil, fmt.Errorf("failed to find tag: %w", err)
+	}
+
+	return &tag, nil
+}
+
+func (r *UserTagRepository) FindAll() ([]*domain.UserTag, error) {
+	var tags []*domain.UserTag
+	if err := r.db.Order("name COLLATE NOCASE").Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("failed to find tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// CountTracks returns the number of tracks carrying each tag, by tag ID
+func (r *UserTagRepository) CountTracks() (map[string]int64, error) {
+	var rows []struct {
+		TagID string
+		Count int64
+	}
+	if err := r.db.Model(&TrackTag{}).
+		Select("tag_id, COUNT(*) AS count").
+		Group("tag_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count tagged tracks: %w", err)
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.TagID] = row.Count
+	}
+	return counts, nil
+}
+
+// AddToTracks tags every track in the list. Tracks that already carry the
+// tag are left as they are.
+func (r *UserTagRepository) AddToTracks(tagID string, trackIDs []string) error {
+	if len(trackIDs) == 0 {
+		return nil
+	}
+
+	if _, err := r.FindByID(tagID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	links := make([]TrackTag, len(trackIDs))
+	for i, trackID := range trackIDs {
+		links[i] = TrackTag{TrackID: trackID, TagID: tagID, AddedAt: now}
+	}
+
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(links, 500).Error; err != nil {
+		return fmt.Errorf("failed to tag tracks: %w", err)
+	}
+
+	return nil
+}
+
+func (r *UserTagRepository) RemoveFromTracks(tagID string, trackIDs []string) error {
+	if len(trackIDs) == 0 {
+		return nil
+	}
+
+	if err := r.db.Where("tag_id = ? AND track_id IN ?", tagID, trackIDs).
+		Delete(&TrackTag{}).Error; err != nil {
+		return fmt.Errorf("failed to untag tracks: %w", err)
+	}
+
+	return nil
+}
+
+func (r *UserTagRepository) FindByTrack(trackID string) ([]*domain.UserTag, error) {
+	var tags []*domain.UserTag
+	if err := r.db.Joins("JOIN track_tags ON track_tags.tag_id = user_tags.id").
+		Where("track_tags.track_id = ?", trackID).
+		Order("user_tags.name COLLATE NOCASE").
+		Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("failed to find track tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+func (r *UserTagRepository) FindTrackIDs(tagID string) ([]string, error) {
+	var ids []string
+	if err := r.db.Model(&TrackTag{}).
+		Where("tag_id = ?", tagID).
+		Pluck("track_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to find tagged tracks: %w", err)
+	}
+
+	return ids, nil
+}
+
+// LoadForTracks fills in the UserTags of each track
+func (r *UserTagRepository) LoadForTracks(tracks []*domain.Track) error {
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*domain.Track, len(tracks))
+	ids := make([]string, 0, len(tracks))
+	for _, track := range tracks {
+		track.UserTags = nil
+		byID[track.ID] = track
+		ids = append(ids, track.ID)
+	}
+
+	// Chunked to stay under SQLite's bound variable limit
+	const chunk = 500
+	for start := 0; start < len(ids); start += chunk {
+		end := min(start+chunk, len(ids))
+
+		var rows []struct {
+			TrackID string
+			Name    string
+		}
+		if err := r.db.Model(&TrackTag{}).
+			Select("track_tags.track_id, user_tags.name").
+			Joins("JOIN user_tags ON user_tags.id = track_tags.tag_id").
+			Where("track_tags.track_id IN ?", ids[start:end]).
+			Order("user_tags.name COLLATE NOCASE").
+			Scan(&rows).Error; err != nil {
+			return fmt.Errorf("failed to load track tags: %w", err)
+		}
+
+		for _, row := range rows {
+			track := byID[row.TrackID]
+			track.UserTags = append(track.UserTags, row.Name)
+		}
+	}
+
+	return nil
+}
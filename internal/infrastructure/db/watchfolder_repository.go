@@ -0,0 +1,46 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/winramp/winramp/internal/domain"
+	"gorm.io/gorm"
+)
+
+type WatchFolderRepository struct {
+	db *gorm.DB
+}
+
+func NewWatchFolderRepository(database *Database) domain.WatchFolderRepository {
+	return &WatchFolderRepository{
+		db: database.DB(),
+	}
+}
+
+// Save creates or replaces a folder's settings, including cleared ones
+func (r *WatchFolderRepository) Save(folder *domain.WatchFolder) error {
+	if folder.Path == "" {
+		return domain.ErrInvalidLibraryPath
+	}
+
+	if err := r.db.Save(folder).Error; err != nil {
+		return fmt.Errorf("failed to save watch folder: %w", err)
+	}
+
+	return nil
+}
+
+// FindByPath returns a folder's settings, ignoring case
+func (r *WatchFolderRepository) FindByPath(path string) (*domain.WatchFolder, error) {
+	var folder domain.WatchFolder
+	if err := r.db.First(&folder, "path = ? COLLATE NOCASE", filepath.Clean(path)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrWatchFolderNotFound
+		}
+		return nil, fmt.Errorf("failed to find watch folder: %w", err)
+	}
+
+	return &folder, nil
+}
@@ -0,0 +1,268 @@
+package library
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Register GIF decoder
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// artJPEGQuality is used when resized art is re-encoded as JPEG
+const artJPEGQuality = 90
+
+// ArtStore keeps album art in the cache directory, named by content hash so
+// identical images embedded in many tracks are stored once. Files are
+// removed when no track references them any more.
+type ArtStore struct {
+	dir       string
+	maxSize   int // Longest edge in pixels, 0 for no limit
+	trackRepo domain.TrackRepository
+
+	mu     sync.Mutex
+	finder AlbumArtFinder
+	misses map[string]time.Time // Album keys no finder had art for, and when
+}
+
+// NewArtStore creates an album art store under cacheDir
+func NewArtStore(cacheDir string, maxSize int, trackRepo domain.TrackRepository) *ArtStore {
+	return &ArtStore{
+		dir:       filepath.Join(cacheDir, "albumart"),
+		maxSize:   maxSize,
+		trackRepo: trackRepo,
+	}
+}
+
+// Dir returns the directory art is stored in
+func (a *ArtStore) Dir() string {
+	return a.dir
+}
+
+// Save stores image data, resizing it to fit the configured maximum, and
+// returns the path of the stored file. Saving the same image twice returns
+// the existing file, its modification time brought up to date so Cleanup
+// sees it as new.
+func (a *ArtStore) Save(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", errors.New("empty image data")
+	}
+
+	sum := sha1.Sum(data)
+	hash := hex.EncodeToString(sum[:])
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Deduplicate by content hash
+	for _, ext := range []string{".jpg", ".png"} {
+		path := filepath.Join(a.dir, hash+ext)
+		if _, err := os.Stat(path); err == nil {
+			now := time.Now()
+			if err := os.Chtimes(path, now, now); err != nil {
+				logger.Debug("Failed to touch album art", logger.String("path", path), logger.Error(err))
+			}
+			return path, nil
+		}
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode album art: %w", err)
+	}
+
+	if err := os.MkdirAll(a.dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create album art directory: %w", err)
+	}
+
+	bounds := img.Bounds()
+	needsResize := a.maxSize > 0 && (bounds.Dx() > a.maxSize || bounds.Dy() > a.maxSize)
+
+	// Keep the original bytes when no processing is needed
+	if !needsResize && (format == "jpeg" || format == "png") {
+		ext := ".jpg"
+		if format == "png" {
+			ext = ".png"
+		}
+		path := filepath.Join(a.dir, hash+ext)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return "", fmt.Errorf("failed to save album art: %w", err)
+		}
+		return path, nil
+	}
+
+	if needsResize {
+		img = resizeToFit(img, a.maxSize)
+	}
+
+	var buf bytes.Buffer
+	ext := ".jpg"
+	if hasAlpha(img) {
+		ext = ".png"
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: artJPEGQuality})
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to encode album art: %w", err)
+	}
+
+	path := filepath.Join(a.dir, hash+ext)
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return "", fmt.Errorf("failed to save album art: %w", err)
+	}
+
+	return path, nil
+}
+
+// Release removes an art file if no track references it any more. It should
+// be called after a track is deleted or its art replaced.
+func (a *ArtStore) Release(path string) error {
+	return a.release(path, time.Time{})
+}
+
+// release removes an unreferenced art file, unless it was saved at or after
+// the given time. A zero time removes it regardless.
+func (a *ArtStore) release(path string, before time.Time) error {
+	if path == "" || !a.owns(path) {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !before.IsZero() {
+		info, err := os.Stat(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !info.ModTime().Before(before) {
+			return nil
+		}
+	}
+
+	count, err := a.trackRepo.CountByAlbumArt(path)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	logger.Debug("Removed unreferenced album art", logger.String("path", path))
+	return nil
+}
+
+// Cleanup removes the art files no track references and returns the number
+// of files removed. Only files saved before the given time are considered:
+// a scan saves a track's art before the track itself, so art from a scan
+// still running looks unreferenced.
+func (a *ArtStore) Cleanup(before time.Time) (int, error) {
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(a.dir, entry.Name())
+		if err := a.release(path, before); err != nil {
+			logger.Warn("Failed to release album art", logger.String("path", path), logger.Error(err))
+			continue
+		}
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// owns reports whether a path is inside the store directory
+func (a *ArtStore) owns(path string) bool {
+	rel, err := filepath.Rel(a.dir, filepath.Clean(path))
+	return err == nil && !strings.HasPrefix(rel, "..") && !filepath.IsAbs(rel)
+}
+
+// resizeToFit scales an image down so its longest edge is maxSize, averaging
+// the source pixels covered by each destination pixel
+func resizeToFit(src image.Image, maxSize int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	scale := float64(maxSize) / float64(width)
+	if height > width {
+		scale = float64(maxSize) / float64(height)
+	}
+	dstWidth := max(int(float64(width)*scale), 1)
+	dstHeight := max(int(float64(height)*scale), 1)
+
+	// Work on RGBA for fast pixel access
+	rgba := image.NewRGBA(bounds)
+	draw.Draw(rgba, bounds, src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := y * height / dstHeight
+		y1 := max((y+1)*height/dstHeight, y0+1)
+		for x := 0; x < dstWidth; x++ {
+			x0 := x * width / dstWidth
+			x1 := max((x+1)*width/dstWidth, x0+1)
+
+			var r, g, b, alpha, n uint32
+			for sy := y0; sy < y1; sy++ {
+				offset := rgba.PixOffset(bounds.Min.X+x0, bounds.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(rgba.Pix[offset])
+					g += uint32(rgba.Pix[offset+1])
+					b += uint32(rgba.Pix[offset+2])
+					alpha += uint32(rgba.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n),
+				G: uint8(g / n),
+				B: uint8(b / n),
+				A: uint8(alpha / n),
+			})
+		}
+	}
+
+	return dst
+}
+
+// hasAlpha reports whether an image has any transparent pixels
+func hasAlpha(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return !opaque.Opaque()
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package library
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// unreferencedArtRepo has no track referencing any art
+type unreferencedArtRepo struct {
+	domain.TrackRepository
+}
+
+func (r *unreferencedArtRepo) CountByAlbumArt(path string) (int64, error) {
+	return 0, nil
+}
+
+func TestArtCleanupKeepsNewArt(t *testing.T) {
+	store := NewArtStore(t.TempDir(), 0, &unreferencedArtRepo{})
+
+	stale, err := store.Save(testPNG(t, 4))
+	require.NoError(t, err)
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(stale, old, old))
+
+	// Art saved by a scan after cleanup started has no track yet, but is
+	// kept all the same; so is old art a scan saves again
+	cutoff := time.Now().Add(-time.Minute)
+	fresh, err := store.Save(testPNG(t, 8))
+	require.NoError(t, err)
+	reused, err := store.Save(testPNG(t, 16))
+	require.NoError(t, err)
+	require.NoError(t, os.Chtimes(reused, old, old))
+	_, err = store.Save(testPNG(t, 16))
+	require.NoError(t, err)
+
+	removed, err := store.Cleanup(cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.NoFileExists(t, stale)
+	assert.FileExists(t, fresh)
+	assert.FileExists(t, reused)
+}
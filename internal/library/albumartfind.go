@@ -0,0 +1,197 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dhowden/tag"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/metadata"
+)
+
+// artMissTTL is how long an album no source had a cover for is left before
+// it is looked up online again
+const artMissTTL = 24 * time.Hour
+
+// folderArtNames are the image files taken as the cover of the album in
+// their folder, in order of preference
+var folderArtNames = []string{"cover", "folder", "front", "album", "albumart"}
+
+// folderArtExts are the formats folder art is read in
+var folderArtExts = []string{".jpg", ".jpeg", ".png"}
+
+// AlbumArtFinder looks album art up online. FetchAlbumArt returns
+// metadata.ErrNoAlbumArt when no source has a cover.
+type AlbumArtFinder interface {
+	FetchAlbumArt(ctx context.Context, artist, album string) ([]byte, error)
+}
+
+// SetFinder sets where art missing from an album's files and folder is
+// looked up. nil looks nowhere else.
+func (a *ArtStore) SetFinder(finder AlbumArtFinder) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.finder = finder
+}
+
+// FolderArt returns the path of the cover image in a folder, or "" when it
+// has none. Names match regardless of case, as Folder.jpg is as common as
+// folder.jpg.
+func FolderArt(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	names := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names[strings.ToLower(entry.Name())] = entry.Name()
+		}
+	}
+	for _, name := range folderArtNames {
+		for _, ext := range folderArtExts {
+			if found, ok := names[name+ext]; ok {
+				return filepath.Join(dir, found)
+			}
+		}
+	}
+	return ""
+}
+
+// SaveFolderArt stores the cover image in the folder of a file, returning
+// "" when the folder has none
+func (a *ArtStore) SaveFolderArt(filePath string) (string, error) {
+	path := FolderArt(filepath.Dir(filePath))
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return a.Save(data)
+}
+
+// AlbumArt returns the stored art of an album, given its tracks. It looks
+// in turn at the art a track already has, the pictures embedded in their
+// files, the cover images in their folders and, with a finder set, online.
+// Tracks without the art found are given it. It returns
+// metadata.ErrNoAlbumArt when there is none.
+func (a *ArtStore) AlbumArt(ctx context.Context, tracks []*domain.Track) (string, error) {
+	if len(tracks) == 0 {
+		return "", metadata.ErrNoAlbumArt
+	}
+
+	path, err := a.findArt(ctx, tracks)
+	if err != nil {
+		return "", err
+	}
+
+	for _, track := range tracks {
+		if track.AlbumArtPath == path {
+			continue
+		}
+		oldArt := track.AlbumArtPath
+		track.AlbumArtPath = path
+		if err := a.trackRepo.Update(track); err != nil {
+			return "", err
+		}
+		if err := a.Release(oldArt); err != nil {
+			logger.Warn("Failed to release album art", logger.String("path", oldArt), logger.Error(err))
+		}
+	}
+	return path, nil
+}
+
+// findArt looks for an album's art in the order AlbumArt describes
+func (a *ArtStore) findArt(ctx context.Context, tracks []*domain.Track) (string, error) {
+	for _, track := range tracks {
+		if track.AlbumArtPath == "" {
+			continue
+		}
+		if _, err := os.Stat(track.AlbumArtPath); err == nil {
+			return track.AlbumArtPath, nil
+		}
+	}
+
+	for _, track := range tracks {
+		if data := readEmbeddedArt(track.FilePath); len(data) > 0 {
+			if path, err := a.Save(data); err == nil {
+				return path, nil
+			}
+		}
+	}
+
+	dirs := make(map[string]bool)
+	for _, track := range tracks {
+		dir := filepath.Dir(track.FilePath)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		if path, err := a.SaveFolderArt(track.FilePath); err == nil && path != "" {
+			return path, nil
+		}
+	}
+
+	return a.fetchArt(ctx, tracks[0])
+}
+
+// fetchArt looks an album's cover up online, remembering for a while the
+// albums no source had one for
+func (a *ArtStore) fetchArt(ctx context.Context, track *domain.Track) (string, error) {
+	key := track.AlbumKey()
+	a.mu.Lock()
+	finder := a.finder
+	missed := time.Since(a.misses[key]) < artMissTTL
+	a.mu.Unlock()
+	if finder == nil || key == "" || missed {
+		return "", metadata.ErrNoAlbumArt
+	}
+
+	artist := track.AlbumArtist
+	if artist == "" {
+		artist = track.Artist
+	}
+	data, err := finder.FetchAlbumArt(ctx, artist, track.Album)
+	if errors.Is(err, metadata.ErrNoAlbumArt) {
+		a.mu.Lock()
+		if a.misses == nil {
+			a.misses = make(map[string]time.Time)
+		}
+		a.misses[key] = time.Now()
+		a.mu.Unlock()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	path, err := a.Save(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to save fetched album art: %w", err)
+	}
+	return path, nil
+}
+
+// readEmbeddedArt returns the picture embedded in a file's tags, if any
+func readEmbeddedArt(path string) []byte {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	m, err := tag.ReadFrom(file)
+	if err != nil || m.Picture() == nil {
+		return nil
+	}
+	return m.Picture().Data
+}
@@ -0,0 +1,113 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/metadata"
+)
+
+// artTrackRepo records the tracks given art
+type artTrackRepo struct {
+	domain.TrackRepository
+	updated []*domain.Track
+}
+
+func (r *artTrackRepo) Update(track *domain.Track) error {
+	r.updated = append(r.updated, track)
+	return nil
+}
+
+func (r *artTrackRepo) CountByAlbumArt(path string) (int64, error) {
+	return 1, nil
+}
+
+// artFinder serves one cover, counting lookups
+type artFinder struct {
+	cover   []byte
+	lookups int
+}
+
+func (f *artFinder) FetchAlbumArt(ctx context.Context, artist, album string) ([]byte, error) {
+	f.lookups++
+	if f.cover == nil {
+		return nil, metadata.ErrNoAlbumArt
+	}
+	return f.cover, nil
+}
+
+func testPNG(t *testing.T, size int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, size, size))))
+	return buf.Bytes()
+}
+
+func TestFolderArt(t *testing.T) {
+	dir := t.TempDir()
+	assert.Empty(t, FolderArt(dir))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Front.JPG"), nil, 0600))
+	assert.Equal(t, filepath.Join(dir, "Front.JPG"), FolderArt(dir))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Folder.png"), nil, 0600))
+	assert.Equal(t, filepath.Join(dir, "Folder.png"), FolderArt(dir), "folder art is preferred to front")
+}
+
+func TestAlbumArt(t *testing.T) {
+	music := t.TempDir()
+	repo := &artTrackRepo{}
+	store := NewArtStore(t.TempDir(), 64, repo)
+	finder := &artFinder{}
+	store.SetFinder(finder)
+
+	tracks := []*domain.Track{
+		{Album: "Post", AlbumArtist: "Björk", FilePath: filepath.Join(music, "01.mp3")},
+		{Album: "Post", AlbumArtist: "Björk", FilePath: filepath.Join(music, "02.mp3")},
+	}
+
+	// Nothing anywhere, and the miss is remembered
+	_, err := store.AlbumArt(context.Background(), tracks)
+	assert.ErrorIs(t, err, metadata.ErrNoAlbumArt)
+	_, err = store.AlbumArt(context.Background(), tracks)
+	assert.ErrorIs(t, err, metadata.ErrNoAlbumArt)
+	assert.Equal(t, 1, finder.lookups)
+
+	// The folder image is found, resized and given to every track
+	require.NoError(t, os.WriteFile(filepath.Join(music, "cover.png"), testPNG(t, 128), 0600))
+	path, err := store.AlbumArt(context.Background(), tracks)
+	require.NoError(t, err)
+	assert.Len(t, repo.updated, 2)
+	for _, track := range tracks {
+		assert.Equal(t, path, track.AlbumArtPath)
+	}
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	config, _, err := image.DecodeConfig(file)
+	require.NoError(t, err)
+	assert.Equal(t, 64, config.Width)
+
+	// Art a track has is used as it is
+	repo.updated = nil
+	again, err := store.AlbumArt(context.Background(), tracks)
+	require.NoError(t, err)
+	assert.Equal(t, path, again)
+	assert.Empty(t, repo.updated)
+
+	// Online is the last resort
+	finder.cover = testPNG(t, 32)
+	other := []*domain.Track{{Album: "Homogenic", AlbumArtist: "Björk", FilePath: filepath.Join(t.TempDir(), "01.mp3")}}
+	_, err = store.AlbumArt(context.Background(), other)
+	require.NoError(t, err)
+	assert.Equal(t, 2, finder.lookups)
+	assert.NotEmpty(t, other[0].AlbumArtPath)
+}
@@ -0,0 +1,121 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/winramp/winramp/internal/archive"
+	"github.com/winramp/winramp/internal/audio/decoder"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// ArchiveAudio returns the audio files in a zip archive, in the order they
+// are stored
+func ArchiveAudio(archivePath string) ([]archive.Entry, error) {
+	entries, err := archive.List(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	audio := entries[:0]
+	for _, entry := range entries {
+		if decoder.SupportsFile(entry.Name) && !strings.HasPrefix(path.Base(entry.Name), ".") {
+			audio = append(audio, entry)
+		}
+	}
+	return audio, nil
+}
+
+// ImportArchive adds the audio files in a zip archive to the library as
+// tracks played from inside it, reading their tags and duration as a scan
+// would. Files already imported are returned as they are; files that
+// cannot be read are left out.
+func (s *Scanner) ImportArchive(ctx context.Context, archivePath string) ([]*domain.Track, error) {
+	entries, err := ArchiveAudio(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := make([]*domain.Track, 0, len(entries))
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return tracks, err
+		}
+
+		source := domain.ArchiveSource(archivePath, entry.Name)
+		if existing, err := s.trackRepo.FindByPath(source.URI); err == nil {
+			tracks = append(tracks, existing)
+			continue
+		}
+
+		track, err := s.readArchiveEntry(ctx, archivePath, entry)
+		if err == nil {
+			err = s.trackRepo.Create(track)
+		}
+		if err != nil {
+			logger.Warn("Failed to import archive entry",
+				logger.String("archive", archivePath),
+				logger.String("entry", entry.Name),
+				logger.Error(err))
+			continue
+		}
+		tracks = append(tracks, track)
+	}
+
+	logger.Info("Archive imported",
+		logger.String("archive", archivePath),
+		logger.Int("tracks", len(tracks)))
+	return tracks, nil
+}
+
+// readArchiveEntry creates a track for a file in an archive, reading its
+// tags and stream details from one opening of the entry
+func (s *Scanner) readArchiveEntry(ctx context.Context, archivePath string, entry archive.Entry) (*domain.Track, error) {
+	track, err := domain.NewArchiveTrack(archivePath, entry.Name)
+	if err != nil {
+		return nil, err
+	}
+	track.FileSize = entry.Size
+	track.FileModTime = entry.Modified
+
+	reader, err := archive.Open(archivePath, entry.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.extractMetadata {
+		if err := s.readTagsFrom(track, reader); err != nil {
+			logger.Debug("Failed to extract archive entry metadata",
+				logger.String("entry", entry.Name),
+				logger.Error(err))
+		}
+		s.enrich(ctx, track)
+	}
+	if track.Title == "" {
+		track.Title = strings.TrimSuffix(path.Base(entry.Name), path.Ext(entry.Name))
+	}
+
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		reader.Close()
+		return nil, err
+	}
+	dec, err := decoder.GetDecoderFactory().CreateDecoder(entry.Name, reader)
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("%w: %v", domain.ErrUnsupportedFormat, err)
+	}
+	defer dec.Close()
+
+	format := dec.Format()
+	applyStreamInfo(track, &StreamInfo{
+		Duration:   dec.Duration(),
+		SampleRate: format.SampleRate,
+		Channels:   format.Channels,
+		BitDepth:   format.BitDepth,
+	})
+	return track, s.checkDuration(track)
+}
@@ -0,0 +1,94 @@
+package library
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// archiveTrackRepo keeps created tracks by path
+type archiveTrackRepo struct {
+	domain.TrackRepository
+	tracks map[string]*domain.Track
+}
+
+func (r *archiveTrackRepo) FindByPath(path string) (*domain.Track, error) {
+	if track, ok := r.tracks[path]; ok {
+		return track, nil
+	}
+	return nil, domain.ErrTrackNotFound
+}
+
+func (r *archiveTrackRepo) Create(track *domain.Track) error {
+	r.tracks[track.FilePath] = track
+	return nil
+}
+
+// silentWAV returns a second of silent 16-bit mono 8 kHz audio
+func silentWAV() []byte {
+	data := make([]byte, 8000*2)
+	b := []byte("RIFF")
+	b = binary.LittleEndian.AppendUint32(b, uint32(36+len(data)))
+	b = append(b, "WAVEfmt "...)
+	b = binary.LittleEndian.AppendUint32(b, 16)
+	b = binary.LittleEndian.AppendUint16(b, 1) // PCM
+	b = binary.LittleEndian.AppendUint16(b, 1)
+	b = binary.LittleEndian.AppendUint32(b, 8000)
+	b = binary.LittleEndian.AppendUint32(b, 8000*2)
+	b = binary.LittleEndian.AppendUint16(b, 2)
+	b = binary.LittleEndian.AppendUint16(b, 16)
+	b = append(b, "data"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	return append(b, data...)
+}
+
+func TestImportArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mix.zip")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	w := zip.NewWriter(file)
+	for name, data := range map[string][]byte{
+		"Mix/01 Intro.wav":   silentWAV(),
+		"Mix/tracklist.txt":  []byte("01 Intro"),
+		"Mix/._01 Intro.wav": nil,
+	} {
+		entry, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = entry.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, file.Close())
+
+	entries, err := ArchiveAudio(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "other files and resource forks are left out")
+
+	repo := &archiveTrackRepo{tracks: make(map[string]*domain.Track)}
+	scanner := NewScanner(repo, nil)
+	scanner.minDuration = 0
+
+	tracks, err := scanner.ImportArchive(context.Background(), path)
+	require.NoError(t, err)
+	require.Len(t, tracks, 1)
+	track := tracks[0]
+	assert.Equal(t, domain.SourceArchive, track.GetSource().Kind)
+	assert.Equal(t, "01 Intro", track.Title)
+	assert.Equal(t, time.Second, track.Duration)
+	assert.Equal(t, 8000, track.SampleRate)
+
+	// Importing again finds the tracks already imported
+	again, err := scanner.ImportArchive(context.Background(), path)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, track.ID, again[0].ID)
+}
@@ -0,0 +1,351 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// ErrArtEmbedUnsupported is returned for formats cover art cannot be
+// embedded into
+var ErrArtEmbedUnsupported = errors.New("embedding art is not supported for this format")
+
+// ArtEmbedOptions controls how a cover image is prepared before it is
+// written into files
+type ArtEmbedOptions struct {
+	MaxSize int    // Longest edge in pixels, 0 to keep the original size
+	Format  string // "jpeg" or "png"; empty keeps JPEG and PNG images as they are
+}
+
+// ArtEmbedResult summarises an embedding job
+type ArtEmbedResult struct {
+	Embedded int
+	Skipped  int               // Files in formats that cannot hold art
+	Failed   map[string]string // File path to error
+	Duration time.Duration
+}
+
+// embeddedArt is a cover image ready to be written into a file
+type embeddedArt struct {
+	data   []byte
+	mime   string
+	width  int
+	height int
+	depth  int // Bits per pixel
+}
+
+// artWriter embeds a cover into a file, reading the original from src and
+// writing the complete new file to dst
+type artWriter func(src *os.File, size int64, dst io.Writer, art *embeddedArt) error
+
+var artWriters = map[string]artWriter{
+	".mp3":  embedID3Art,
+	".flac": embedFLACArt,
+	".m4a":  embedMP4Art,
+	".mp4":  embedMP4Art,
+	".ogg":  embedOggArt,
+	".opus": embedOggArt,
+}
+
+// CanEmbedArt reports whether cover art can be embedded into a file
+func CanEmbedArt(path string) bool {
+	_, ok := artWriters[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// ArtEmbedder writes a cover image into the audio files of an album as a
+// single job and updates the library to match
+type ArtEmbedder struct {
+	trackRepo domain.TrackRepository
+	artStore  *ArtStore
+
+	isRunning  bool
+	cancelFunc context.CancelFunc
+	progress   float64
+
+	mu sync.RWMutex
+}
+
+// NewArtEmbedder creates a new art embedder
+func NewArtEmbedder(trackRepo domain.TrackRepository, artStore *ArtStore) *ArtEmbedder {
+	return &ArtEmbedder{
+		trackRepo: trackRepo,
+		artStore:  artStore,
+	}
+}
+
+// Embed writes image into every track's file. A file that cannot be
+// written is recorded in the result and the job carries on with the rest.
+func (e *ArtEmbedder) Embed(ctx context.Context, tracks []*domain.Track, image []byte, opts ArtEmbedOptions) (*ArtEmbedResult, error) {
+	art, err := prepareEmbeddedArt(image, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	if e.isRunning {
+		e.mu.Unlock()
+		return nil, fmt.Errorf("art embedding already in progress")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	e.isRunning = true
+	e.cancelFunc = cancel
+	e.progress = 0
+	e.mu.Unlock()
+
+	defer func() {
+		cancel()
+		e.mu.Lock()
+		e.isRunning = false
+		e.cancelFunc = nil
+		e.progress = 100
+		e.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	result := &ArtEmbedResult{Failed: make(map[string]string)}
+
+	// Store the cover once so every track can point at it
+	artPath := ""
+	if e.artStore != nil {
+		if artPath, err = e.artStore.Save(art.data); err != nil {
+			logger.Warn("Failed to store embedded art", logger.Error(err))
+		}
+	}
+
+	for i, track := range tracks {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		err := e.embedTrack(track, art, artPath)
+		switch {
+		case errors.Is(err, ErrArtEmbedUnsupported):
+			result.Skipped++
+		case err != nil:
+			result.Failed[track.FilePath] = err.Error()
+			logger.Warn("Failed to embed album art",
+				logger.String("path", track.FilePath),
+				logger.Error(err))
+		default:
+			result.Embedded++
+		}
+
+		e.mu.Lock()
+		e.progress = float64(i+1) / float64(len(tracks)) * 100
+		e.mu.Unlock()
+	}
+
+	result.Duration = time.Since(startTime)
+
+	logger.Info("Album art embedded",
+		logger.Int("embedded", result.Embedded),
+		logger.Int("skipped", result.Skipped),
+		logger.Int("failed", len(result.Failed)),
+		logger.Duration("duration", result.Duration),
+	)
+
+	return result, nil
+}
+
+// embedTrack writes the cover into one file and records the new file size,
+// checksum and art on the track
+func (e *ArtEmbedder) embedTrack(track *domain.Track, art *embeddedArt, artPath string) error {
+	if track.GetSource().Kind != domain.SourceFile {
+		return ErrArtEmbedUnsupported
+	}
+	writer, ok := artWriters[strings.ToLower(filepath.Ext(track.FilePath))]
+	if !ok {
+		return ErrArtEmbedUnsupported
+	}
+
+	if err := rewriteFile(track.FilePath, func(src *os.File, size int64, dst io.Writer) error {
+		return writer(src, size, dst, art)
+	}); err != nil {
+		return err
+	}
+
+	syncFileInfo(track)
+
+	oldArt := track.AlbumArtPath
+	if artPath != "" {
+		track.AlbumArtPath = artPath
+	}
+	if err := e.trackRepo.Update(track); err != nil {
+		return err
+	}
+
+	if e.artStore != nil && oldArt != "" && oldArt != track.AlbumArtPath {
+		if err := e.artStore.Release(oldArt); err != nil {
+			logger.Warn("Failed to release album art", logger.String("path", oldArt), logger.Error(err))
+		}
+	}
+	return nil
+}
+
+// Cancel cancels a running job. Files already written keep their new art.
+func (e *ArtEmbedder) Cancel() {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.cancelFunc != nil {
+		e.cancelFunc()
+	}
+}
+
+// IsRunning returns whether a job is in progress
+func (e *ArtEmbedder) IsRunning() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.isRunning
+}
+
+// GetProgress returns the job progress (0-100)
+func (e *ArtEmbedder) GetProgress() float64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.progress
+}
+
+// prepareEmbeddedArt resizes and converts an image as the options ask.
+// JPEG and PNG images that need no changes are embedded byte for byte.
+func prepareEmbeddedArt(data []byte, opts ArtEmbedOptions) (*embeddedArt, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty image data", domain.ErrInvalidInput)
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decode image: %v", domain.ErrInvalidInput, err)
+	}
+
+	target := strings.ToLower(opts.Format)
+	switch target {
+	case "", "jpeg", "png":
+	case "jpg":
+		target = "jpeg"
+	default:
+		return nil, fmt.Errorf("%w: unsupported image format %q", domain.ErrInvalidInput, opts.Format)
+	}
+
+	bounds := img.Bounds()
+	needsResize := opts.MaxSize > 0 && (bounds.Dx() > opts.MaxSize || bounds.Dy() > opts.MaxSize)
+	if target == "" {
+		target = format
+		if format != "jpeg" && format != "png" {
+			target = "jpeg"
+			if hasAlpha(img) {
+				target = "png"
+			}
+		}
+	}
+
+	if !needsResize && target == format {
+		return newEmbeddedArt(data, format, img), nil
+	}
+
+	if needsResize {
+		img = resizeToFit(img, opts.MaxSize)
+	}
+
+	var buf bytes.Buffer
+	if target == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: artJPEGQuality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return newEmbeddedArt(buf.Bytes(), target, img), nil
+}
+
+func newEmbeddedArt(data []byte, format string, img image.Image) *embeddedArt {
+	art := &embeddedArt{
+		data:   data,
+		mime:   "image/" + format,
+		width:  img.Bounds().Dx(),
+		height: img.Bounds().Dy(),
+		depth:  24,
+	}
+	if format == "png" && hasAlpha(img) {
+		art.depth = 32
+	}
+	return art
+}
+
+// rewriteFile writes a new version of a file next to it and swaps it in, so
+// a failure part way leaves the original untouched
+func rewriteFile(path string, write func(src *os.File, size int64, dst io.Writer) error) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".winramp-*.tmp")
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrFileAccessDenied, err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op once renamed
+
+	if err := write(src, info.Size(), tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	// Windows cannot replace a file that is still open
+	src.Close()
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// syncFileInfo updates the size and checksum of a track after its file was
+// rewritten
+func syncFileInfo(track *domain.Track) {
+	if info, err := os.Stat(track.FilePath); err == nil {
+		track.FileSize = info.Size()
+	}
+
+	// Formats without a separable tag area checksum the whole file, so the
+	// stored checksum would no longer match
+	if track.Checksum != "" {
+		if checksum, err := ComputeChecksum(track.FilePath); err == nil {
+			track.Checksum = checksum
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/metadata"
+)
+
+// ArtistImageSize names one of the stored sizes of an artist image
+type ArtistImageSize string
+
+const (
+	ArtistImageThumb  ArtistImageSize = "thumb"  // Browse grids and lists
+	ArtistImageMedium ArtistImageSize = "medium" // Now Playing screen
+	ArtistImageLarge  ArtistImageSize = "large"  // Artist page header
+)
+
+// artistImageSizes maps each size to the longest edge in pixels
+var artistImageSizes = map[ArtistImageSize]int{
+	ArtistImageThumb:  150,
+	ArtistImageMedium: 500,
+	ArtistImageLarge:  1000,
+}
+
+const (
+	// artistImageTTL is how long a stored image is used before refreshing
+	artistImageTTL = 30 * 24 * time.Hour
+
+	// artistImageMissTTL is how long to wait before asking again for an
+	// artist no source had an image of
+	artistImageMissTTL = 7 * 24 * time.Hour
+
+	// missingMarker records that no image was found for an artist
+	missingMarker = "missing"
+)
+
+// mbidPattern matches a MusicBrainz ID, which also makes it safe as a
+// folder name
+var mbidPattern = regexp.MustCompile(`^[0-9a-fA-F-]{36}$`)
+
+// ArtistImageFinder looks artists up online. FetchArtistImage returns
+// metadata.ErrNoArtistImage when no source has an image.
+type ArtistImageFinder interface {
+	LookupArtistID(ctx context.Context, name string) (string, error)
+	FetchArtistImage(ctx context.Context, mbid string) ([]byte, error)
+}
+
+// ArtistImage is a stored artist image
+type ArtistImage struct {
+	ArtistID string          `json:"artistId"`
+	Path     string          `json:"path"`
+	Size     ArtistImageSize `json:"size"`
+	Stale    bool            `json:"stale"` // Could not be refreshed; an older copy or other size
+}
+
+// ArtistImageStore keeps artist images in the cache directory, one folder
+// per MusicBrainz artist ID with a file for each size. Images are fetched
+// when missing or expired; when offline, whatever is stored is used.
+type ArtistImageStore struct {
+	dir    string
+	finder ArtistImageFinder
+
+	mu      sync.Mutex
+	pending map[string]*sync.Mutex // Per-artist fetch locks
+}
+
+// NewArtistImageStore creates an artist image store under cacheDir
+func NewArtistImageStore(cacheDir string, finder ArtistImageFinder) *ArtistImageStore {
+	return &ArtistImageStore{
+		dir:     filepath.Join(cacheDir, "artists"),
+		finder:  finder,
+		pending: make(map[string]*sync.Mutex),
+	}
+}
+
+// Get returns an image of an artist at the given size. The MusicBrainz ID is
+// looked up from the name when not known.
+func (s *ArtistImageStore) Get(ctx context.Context, artistID, name string, size ArtistImageSize) (*ArtistImage, error) {
+	if _, ok := artistImageSizes[size]; !ok {
+		size = ArtistImageMedium
+	}
+
+	if artistID == "" {
+		artistID = s.knownID(name)
+	}
+	if artistID == "" {
+		id, err := s.finder.LookupArtistID(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", metadata.ErrNoArtistImage, name)
+		}
+		artistID = id
+		s.rememberID(name, id)
+	}
+	if !mbidPattern.MatchString(artistID) {
+		return nil, fmt.Errorf("%w: invalid artist ID %q", metadata.ErrNoArtistImage, artistID)
+	}
+
+	// One fetch per artist at a time; others wait and use its result
+	lock := s.artistLock(artistID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	path := s.path(artistID, size)
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < artistImageTTL {
+		return &ArtistImage{ArtistID: artistID, Path: path, Size: size}, nil
+	}
+	if info, err := os.Stat(filepath.Join(s.dir, artistID, missingMarker)); err == nil && time.Since(info.ModTime()) < artistImageMissTTL {
+		return nil, fmt.Errorf("%w: %s", metadata.ErrNoArtistImage, artistID)
+	}
+
+	err := s.fetch(ctx, artistID)
+	if err == nil {
+		return &ArtistImage{ArtistID: artistID, Path: path, Size: size}, nil
+	}
+	if errors.Is(err, metadata.ErrNoArtistImage) {
+		return nil, err
+	}
+
+	// Offline or the source failed: fall back to anything stored
+	if fallback := s.stored(artistID, size); fallback != nil {
+		logger.Debug("Using stored artist image",
+			logger.String("artist", artistID),
+			logger.Error(err))
+		return fallback, nil
+	}
+	return nil, err
+}
+
+// Cached returns the stored image of an artist closest to the given size
+// without going online, or nil if none is stored. The MusicBrainz ID is
+// taken from an earlier lookup of the name when not known.
+func (s *ArtistImageStore) Cached(artistID, name string, size ArtistImageSize) *ArtistImage {
+	if artistID == "" {
+		artistID = s.knownID(name)
+	}
+	if !mbidPattern.MatchString(artistID) {
+		return nil
+	}
+
+	image := s.stored(artistID, size)
+	if image != nil && image.Size == size {
+		if info, err := os.Stat(image.Path); err == nil && time.Since(info.ModTime()) < artistImageTTL {
+			image.Stale = false
+		}
+	}
+	return image
+}
+
+// fetch downloads an artist's image and stores it at every size
+func (s *ArtistImageStore) fetch(ctx context.Context, artistID string) error {
+	dir := filepath.Join(s.dir, artistID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create artist image directory: %w", err)
+	}
+
+	data, err := s.finder.FetchArtistImage(ctx, artistID)
+	if err != nil {
+		if errors.Is(err, metadata.ErrNoArtistImage) {
+			if err := os.WriteFile(filepath.Join(dir, missingMarker), nil, 0600); err != nil {
+				logger.Warn("Failed to record missing artist image", logger.Error(err))
+			}
+			return fmt.Errorf("%w: %s", metadata.ErrNoArtistImage, artistID)
+		}
+		return err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decode artist image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	for size, edge := range artistImageSizes {
+		variant := img
+		if bounds.Dx() > edge || bounds.Dy() > edge {
+			variant = resizeToFit(img, edge)
+		}
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, variant, &jpeg.Options{Quality: artJPEGQuality}); err != nil {
+			return fmt.Errorf("failed to encode artist image: %w", err)
+		}
+		if err := os.WriteFile(s.path(artistID, size), buf.Bytes(), 0600); err != nil {
+			return fmt.Errorf("failed to save artist image: %w", err)
+		}
+	}
+
+	os.Remove(filepath.Join(dir, missingMarker))
+	return nil
+}
+
+// stored returns the stored image closest to the wanted size, preferring
+// larger sizes, or nil if none is stored
+func (s *ArtistImageStore) stored(artistID string, size ArtistImageSize) *ArtistImage {
+	order := []ArtistImageSize{size, ArtistImageLarge, ArtistImageMedium, ArtistImageThumb}
+	for _, candidate := range order {
+		path := s.path(artistID, candidate)
+		if _, err := os.Stat(path); err == nil {
+			return &ArtistImage{ArtistID: artistID, Path: path, Size: candidate, Stale: true}
+		}
+	}
+	return nil
+}
+
+// knownID returns the artist ID previously looked up for a name, so stored
+// images can be found without going online
+func (s *ArtistImageStore) knownID(name string) string {
+	data, err := os.ReadFile(s.namePath(name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func (s *ArtistImageStore) rememberID(name, artistID string) {
+	path := s.namePath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	if err := os.WriteFile(path, []byte(artistID), 0600); err != nil {
+		logger.Debug("Failed to remember artist ID", logger.String("artist", name), logger.Error(err))
+	}
+}
+
+func (s *ArtistImageStore) namePath(name string) string {
+	sum := sha1.Sum([]byte(strings.ToLower(strings.TrimSpace(name))))
+	return filepath.Join(s.dir, "names", hex.EncodeToString(sum[:]))
+}
+
+func (s *ArtistImageStore) path(artistID string, size ArtistImageSize) string {
+	return filepath.Join(s.dir, artistID, string(size)+".jpg")
+}
+
+func (s *ArtistImageStore) artistLock(artistID string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, ok := s.pending[artistID]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.pending[artistID] = lock
+	}
+	return lock
+}
@@ -0,0 +1,40 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtistImageCached(t *testing.T) {
+	const mbid = "87c5dedd-371d-4a53-9f7f-80522fb7f3cb"
+	store := NewArtistImageStore(t.TempDir(), nil)
+	assert.Nil(t, store.Cached(mbid, "Björk", ArtistImageThumb))
+
+	// Another size stands in until the wanted one is stored
+	large := store.path(mbid, ArtistImageLarge)
+	require.NoError(t, os.MkdirAll(filepath.Dir(large), 0o700))
+	require.NoError(t, os.WriteFile(large, []byte("jpeg"), 0o600))
+	image := store.Cached(mbid, "Björk", ArtistImageThumb)
+	require.NotNil(t, image)
+	assert.Equal(t, large, image.Path)
+	assert.True(t, image.Stale)
+
+	thumb := store.path(mbid, ArtistImageThumb)
+	require.NoError(t, os.WriteFile(thumb, []byte("jpeg"), 0o600))
+	image = store.Cached(mbid, "Björk", ArtistImageThumb)
+	require.NotNil(t, image)
+	assert.Equal(t, thumb, image.Path)
+	assert.False(t, image.Stale)
+
+	// Artists without an ID are found by a name looked up before, never
+	// by going online
+	assert.Nil(t, store.Cached("", "Björk", ArtistImageThumb))
+	store.rememberID("Björk", mbid)
+	image = store.Cached("", "björk", ArtistImageThumb)
+	require.NotNil(t, image)
+	assert.Equal(t, thumb, image.Path)
+}
@@ -0,0 +1,839 @@
+package library
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// Picture types shared by ID3 APIC frames and FLAC picture blocks. Both are
+// replaced, since older taggers store covers as "other".
+const (
+	pictureTypeOther      = 0
+	pictureTypeFrontCover = 3
+)
+
+// tagPadding is left after rewritten tags so later edits fit in place
+const tagPadding = 2048
+
+func isCoverPicture(pictureType uint32) bool {
+	return pictureType == pictureTypeOther || pictureType == pictureTypeFrontCover
+}
+
+// embedID3Art writes the cover as an APIC frame in the file's ID3v2 tag,
+// adding an ID3v2.3 tag if there is none
+func embedID3Art(src *os.File, size int64, dst io.Writer, art *embeddedArt) error {
+	keep := func(id string, body []byte) bool {
+		return id != "APIC" || !isCoverPicture(uint32(apicPictureType(body)))
+	}
+	return rewriteID3(src, size, dst, keep, func(version byte) []byte {
+		var apic bytes.Buffer
+		apic.WriteByte(0) // ISO-8859-1 text
+		apic.WriteString(art.mime)
+		apic.WriteByte(0)
+		apic.WriteByte(pictureTypeFrontCover)
+		apic.WriteByte(0) // Empty description
+		apic.Write(art.data)
+		return id3Frame("APIC", apic.Bytes(), version)
+	})
+}
+
+// rewriteID3 writes the file with a new ID3v2 tag holding the frames of the
+// old tag that keep accepts, followed by the frames added returns for the
+// tag's version. A file without a tag gets an ID3v2.3 one.
+func rewriteID3(src *os.File, size int64, dst io.Writer, keep func(id string, body []byte) bool, added func(version byte) []byte) error {
+	version := byte(3)
+	var frames []byte
+	audioStart := int64(0)
+
+	header := make([]byte, 10)
+	if _, err := src.ReadAt(header, 0); err == nil && string(header[:3]) == "ID3" {
+		version = header[3]
+		flags := header[5]
+		tagSize := int64(syncsafe(header[6:10]))
+
+		if version < 3 || version > 4 {
+			return fmt.Errorf("%w: ID3v2.%d tag", domain.ErrUnsupportedFormat, version)
+		}
+		if flags&0x80 != 0 {
+			return fmt.Errorf("%w: unsynchronised ID3 tag", domain.ErrUnsupportedFormat)
+		}
+		if 10+tagSize > size {
+			return fmt.Errorf("%w: ID3 tag runs past end of file", domain.ErrTrackCorrupted)
+		}
+
+		audioStart = 10 + tagSize
+		if flags&0x10 != 0 {
+			audioStart += 10 // Footer
+		}
+
+		body := make([]byte, tagSize)
+		if _, err := src.ReadAt(body, 10); err != nil {
+			return err
+		}
+		if flags&0x40 != 0 {
+			body = skipID3ExtendedHeader(body, version)
+		}
+
+		var err error
+		if frames, err = filterID3Frames(body, version, keep); err != nil {
+			return err
+		}
+	}
+	frames = append(frames, added(version)...)
+
+	tag := []byte{'I', 'D', '3', version, 0, 0}
+	tag = append(tag, toSyncsafe(len(frames)+tagPadding)...)
+	tag = append(tag, frames...)
+	tag = append(tag, make([]byte, tagPadding)...)
+
+	if _, err := dst.Write(tag); err != nil {
+		return err
+	}
+	_, err := io.Copy(dst, io.NewSectionReader(src, audioStart, size-audioStart))
+	return err
+}
+
+// filterID3Frames returns the raw frames of a tag body that keep accepts
+func filterID3Frames(body []byte, version byte, keep func(id string, body []byte) bool) ([]byte, error) {
+	var kept []byte
+	for pos := 0; pos+10 <= len(body); {
+		if body[pos] == 0 {
+			break // Padding
+		}
+
+		id := string(body[pos : pos+4])
+		var frameSize int
+		if version == 4 {
+			frameSize = syncsafe(body[pos+4 : pos+8])
+		} else {
+			frameSize = int(binary.BigEndian.Uint32(body[pos+4 : pos+8]))
+		}
+		end := pos + 10 + frameSize
+		if frameSize < 0 || end > len(body) {
+			return nil, fmt.Errorf("%w: ID3 frame %q runs past end of tag", domain.ErrTrackCorrupted, id)
+		}
+
+		if keep(id, body[pos+10:end]) {
+			kept = append(kept, body[pos:end]...)
+		}
+		pos = end
+	}
+	return kept, nil
+}
+
+// apicPictureType reads the picture type of an APIC frame body, or returns
+// the front cover type if it cannot be read so the frame is replaced
+func apicPictureType(frame []byte) byte {
+	if len(frame) < 2 {
+		return pictureTypeFrontCover
+	}
+	mimeEnd := bytes.IndexByte(frame[1:], 0)
+	if mimeEnd < 0 || 1+mimeEnd+1 >= len(frame) {
+		return pictureTypeFrontCover
+	}
+	return frame[1+mimeEnd+1]
+}
+
+func skipID3ExtendedHeader(body []byte, version byte) []byte {
+	if len(body) < 4 {
+		return nil
+	}
+	// The v2.3 size excludes its own four bytes; the v2.4 size includes them
+	n := int(binary.BigEndian.Uint32(body[:4])) + 4
+	if version == 4 {
+		n = syncsafe(body[:4])
+	}
+	if n > len(body) {
+		return nil
+	}
+	return body[n:]
+}
+
+func id3Frame(id string, body []byte, version byte) []byte {
+	frame := []byte(id)
+	if version == 4 {
+		frame = append(frame, toSyncsafe(len(body))...)
+	} else {
+		frame = binary.BigEndian.AppendUint32(frame, uint32(len(body)))
+	}
+	frame = append(frame, 0, 0) // Flags
+	return append(frame, body...)
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+func toSyncsafe(n int) []byte {
+	return []byte{byte(n>>21) & 0x7F, byte(n>>14) & 0x7F, byte(n>>7) & 0x7F, byte(n) & 0x7F}
+}
+
+// embedFLACArt writes the cover as a PICTURE metadata block
+func embedFLACArt(src *os.File, size int64, dst io.Writer, art *embeddedArt) error {
+	return rewriteFLAC(src, size, dst, func(blocks []flacBlock) ([]flacBlock, error) {
+		kept := blocks[:0]
+		for _, b := range blocks {
+			if b.kind != flacPictureBlock || len(b.data) < 4 || !isCoverPicture(binary.BigEndian.Uint32(b.data)) {
+				kept = append(kept, b)
+			}
+		}
+
+		picture := flacPicture(art)
+		if len(picture) >= 1<<24 {
+			return nil, fmt.Errorf("%w: image too large for a FLAC picture block", domain.ErrInvalidInput)
+		}
+		return append(kept, flacBlock{flacPictureBlock, picture}), nil
+	})
+}
+
+// FLAC metadata block types
+const (
+	flacStreamInfoBlock = 0
+	flacPaddingBlock    = 1
+	flacCommentBlock    = 4
+	flacPictureBlock    = 6
+)
+
+// flacBlock is a FLAC metadata block
+type flacBlock struct {
+	kind byte
+	data []byte
+}
+
+// rewriteFLAC writes the file with its metadata blocks passed through edit.
+// Padding is taken out before edit sees the blocks and added back at the
+// end.
+func rewriteFLAC(src *os.File, size int64, dst io.Writer, edit func([]flacBlock) ([]flacBlock, error)) error {
+	// Some encoders put an ID3 tag in front of the stream; keep it as is
+	start := int64(0)
+	header := make([]byte, 10)
+	if _, err := src.ReadAt(header, 0); err == nil && string(header[:3]) == "ID3" {
+		start = 10 + int64(syncsafe(header[6:10]))
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(src, start, size-start))
+	marker := make([]byte, 4)
+	if _, err := io.ReadFull(r, marker); err != nil || string(marker) != "fLaC" {
+		return fmt.Errorf("%w: missing FLAC stream marker", domain.ErrTrackCorrupted)
+	}
+
+	var blocks []flacBlock
+	for last := false; !last; {
+		blockHeader := make([]byte, 4)
+		if _, err := io.ReadFull(r, blockHeader); err != nil {
+			return fmt.Errorf("%w: truncated FLAC metadata", domain.ErrTrackCorrupted)
+		}
+		last = blockHeader[0]&0x80 != 0
+		kind := blockHeader[0] & 0x7F
+		data := make([]byte, int(blockHeader[1])<<16|int(blockHeader[2])<<8|int(blockHeader[3]))
+		if _, err := io.ReadFull(r, data); err != nil {
+			return fmt.Errorf("%w: truncated FLAC metadata", domain.ErrTrackCorrupted)
+		}
+		if kind != flacPaddingBlock {
+			blocks = append(blocks, flacBlock{kind, data})
+		}
+	}
+	if len(blocks) == 0 || blocks[0].kind != flacStreamInfoBlock {
+		return fmt.Errorf("%w: FLAC stream does not start with STREAMINFO", domain.ErrTrackCorrupted)
+	}
+
+	blocks, err := edit(blocks)
+	if err != nil {
+		return err
+	}
+	blocks = append(blocks, flacBlock{flacPaddingBlock, make([]byte, tagPadding)})
+
+	if _, err := io.Copy(dst, io.NewSectionReader(src, 0, start)); err != nil {
+		return err
+	}
+	if _, err := dst.Write(marker); err != nil {
+		return err
+	}
+	for i, b := range blocks {
+		kind := b.kind
+		if i == len(blocks)-1 {
+			kind |= 0x80
+		}
+		n := len(b.data)
+		if _, err := dst.Write([]byte{kind, byte(n >> 16), byte(n >> 8), byte(n)}); err != nil {
+			return err
+		}
+		if _, err := dst.Write(b.data); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.Copy(dst, r)
+	return err
+}
+
+// flacPicture builds the body of a FLAC picture block, which is also the
+// payload of an Ogg METADATA_BLOCK_PICTURE comment
+func flacPicture(art *embeddedArt) []byte {
+	b := binary.BigEndian.AppendUint32(nil, pictureTypeFrontCover)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(art.mime)))
+	b = append(b, art.mime...)
+	b = binary.BigEndian.AppendUint32(b, 0) // Empty description
+	b = binary.BigEndian.AppendUint32(b, uint32(art.width))
+	b = binary.BigEndian.AppendUint32(b, uint32(art.height))
+	b = binary.BigEndian.AppendUint32(b, uint32(art.depth))
+	b = binary.BigEndian.AppendUint32(b, 0) // Not an indexed image
+	b = binary.BigEndian.AppendUint32(b, uint32(len(art.data)))
+	return append(b, art.data...)
+}
+
+// mp4Atom is a parsed MP4 box. Containers hold children; other boxes keep
+// their payload as is.
+type mp4Atom struct {
+	kind     string
+	prefix   []byte // Version and flags of full boxes that hold children
+	data     []byte
+	children []*mp4Atom
+}
+
+// mp4Containers are the boxes descended into on the way to the tag list and
+// the chunk offset tables
+var mp4Containers = map[string]bool{
+	"moov": true, "trak": true, "mdia": true, "minf": true, "stbl": true,
+	"udta": true, "meta": true, "ilst": true, "edts": true,
+}
+
+// embedMP4Art writes the cover as the covr item of the iTunes tag list
+func embedMP4Art(src *os.File, size int64, dst io.Writer, art *embeddedArt) error {
+	return rewriteMP4Tags(src, size, dst, func(ilst *mp4Atom) {
+		items := ilst.children[:0]
+		for _, item := range ilst.children {
+			if item.kind != "covr" {
+				items = append(items, item)
+			}
+		}
+		imageType := uint32(13) // JPEG
+		if art.mime == "image/png" {
+			imageType = 14
+		}
+		ilst.children = append(items, &mp4Atom{kind: "covr", data: mp4Data(imageType, art.data)})
+	})
+}
+
+// rewriteMP4Tags writes the file with the iTunes tag list in
+// moov/udta/meta/ilst passed through edit, adding the list if there is
+// none. When the movie box comes before the media data, the chunk offsets
+// are shifted by the change in its size.
+func rewriteMP4Tags(src *os.File, size int64, dst io.Writer, edit func(ilst *mp4Atom)) error {
+	moovStart, moovSize := int64(-1), int64(0)
+	for pos := int64(0); pos+8 <= size; {
+		header := make([]byte, 16)
+		n, _ := src.ReadAt(header, pos)
+		if n < 8 {
+			break
+		}
+
+		atomSize := int64(binary.BigEndian.Uint32(header[:4]))
+		kind := string(header[4:8])
+		switch atomSize {
+		case 0:
+			atomSize = size - pos
+		case 1:
+			if n < 16 {
+				return fmt.Errorf("%w: truncated MP4 box", domain.ErrTrackCorrupted)
+			}
+			atomSize = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+		if atomSize < 8 || pos+atomSize > size {
+			return fmt.Errorf("%w: MP4 box %q runs past end of file", domain.ErrTrackCorrupted, kind)
+		}
+
+		switch kind {
+		case "moov":
+			moovStart, moovSize = pos, atomSize
+		case "moof":
+			return fmt.Errorf("%w: fragmented MP4", domain.ErrUnsupportedFormat)
+		}
+		pos += atomSize
+	}
+	if moovStart < 0 {
+		return fmt.Errorf("%w: no MP4 movie box", domain.ErrTrackCorrupted)
+	}
+
+	raw := make([]byte, moovSize)
+	if _, err := src.ReadAt(raw, moovStart); err != nil {
+		return err
+	}
+	atoms, err := parseMP4Atoms(raw)
+	if err != nil || len(atoms) != 1 {
+		return fmt.Errorf("%w: invalid MP4 movie box", domain.ErrTrackCorrupted)
+	}
+	moov := atoms[0]
+
+	udta := moov.child("udta", nil)
+	meta := udta.child("meta", []byte{0, 0, 0, 0})
+	if meta.find("hdlr") == nil {
+		hdlr := &mp4Atom{kind: "hdlr", data: append(make([]byte, 8), "mdirappl\x00\x00\x00\x00\x00\x00\x00\x00\x00"...)}
+		meta.children = append([]*mp4Atom{hdlr}, meta.children...)
+	}
+	edit(meta.child("ilst", nil))
+
+	// Chunk offsets pointing past the movie box move with its new size
+	delta := int64(len(moov.bytes())) - moovSize
+	if delta != 0 {
+		if err := moov.shiftChunkOffsets(moovStart, delta); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.Copy(dst, io.NewSectionReader(src, 0, moovStart)); err != nil {
+		return err
+	}
+	if _, err := dst.Write(moov.bytes()); err != nil {
+		return err
+	}
+	rest := moovStart + moovSize
+	_, err = io.Copy(dst, io.NewSectionReader(src, rest, size-rest))
+	return err
+}
+
+func parseMP4Atoms(b []byte) ([]*mp4Atom, error) {
+	var atoms []*mp4Atom
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return nil, fmt.Errorf("%w: truncated MP4 box", domain.ErrTrackCorrupted)
+		}
+
+		atomSize := uint64(binary.BigEndian.Uint32(b[:4]))
+		headerSize := uint64(8)
+		switch atomSize {
+		case 0:
+			atomSize = uint64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return nil, fmt.Errorf("%w: truncated MP4 box", domain.ErrTrackCorrupted)
+			}
+			atomSize = binary.BigEndian.Uint64(b[8:16])
+			headerSize = 16
+		}
+		if atomSize < headerSize || atomSize > uint64(len(b)) {
+			return nil, fmt.Errorf("%w: MP4 box size out of range", domain.ErrTrackCorrupted)
+		}
+
+		atom := &mp4Atom{kind: string(b[4:8])}
+		payload := b[headerSize:atomSize]
+		if mp4Containers[atom.kind] {
+			// iTunes meta boxes are full boxes; QuickTime ones are not
+			if atom.kind == "meta" && len(payload) >= 8 && string(payload[4:8]) != "hdlr" {
+				atom.prefix, payload = payload[:4], payload[4:]
+			}
+			children, err := parseMP4Atoms(payload)
+			if err != nil {
+				return nil, err
+			}
+			atom.children = children
+		} else {
+			atom.data = payload
+		}
+
+		atoms = append(atoms, atom)
+		b = b[atomSize:]
+	}
+	return atoms, nil
+}
+
+// find returns the first child of a kind, or nil
+func (a *mp4Atom) find(kind string) *mp4Atom {
+	for _, child := range a.children {
+		if child.kind == kind {
+			return child
+		}
+	}
+	return nil
+}
+
+// child returns the first child of a kind, adding an empty one if missing
+func (a *mp4Atom) child(kind string, prefix []byte) *mp4Atom {
+	if found := a.find(kind); found != nil {
+		return found
+	}
+	created := &mp4Atom{kind: kind, prefix: prefix}
+	a.children = append(a.children, created)
+	return created
+}
+
+func (a *mp4Atom) bytes() []byte {
+	payload := append([]byte(nil), a.prefix...)
+	if a.children != nil || mp4Containers[a.kind] {
+		for _, child := range a.children {
+			payload = append(payload, child.bytes()...)
+		}
+	} else {
+		payload = append(payload, a.data...)
+	}
+
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+	b = append(b, a.kind...)
+	return append(b, payload...)
+}
+
+// shiftChunkOffsets moves every stco and co64 offset after the movie box
+func (a *mp4Atom) shiftChunkOffsets(moovStart, delta int64) error {
+	for _, child := range a.children {
+		if err := child.shiftChunkOffsets(moovStart, delta); err != nil {
+			return err
+		}
+	}
+
+	switch a.kind {
+	case "stco":
+		if len(a.data) < 8 {
+			return nil
+		}
+		for i := 8; i+4 <= len(a.data); i += 4 {
+			offset := int64(binary.BigEndian.Uint32(a.data[i:]))
+			if offset <= moovStart {
+				continue
+			}
+			offset += delta
+			if offset > 0xFFFFFFFF {
+				return fmt.Errorf("%w: chunk offsets overflow 32 bits", domain.ErrUnsupportedFormat)
+			}
+			binary.BigEndian.PutUint32(a.data[i:], uint32(offset))
+		}
+	case "co64":
+		for i := 8; i+8 <= len(a.data); i += 8 {
+			offset := int64(binary.BigEndian.Uint64(a.data[i:]))
+			if offset > moovStart {
+				binary.BigEndian.PutUint64(a.data[i:], uint64(offset+delta))
+			}
+		}
+	}
+	return nil
+}
+
+// oggPage is a page of an Ogg bitstream
+type oggPage struct {
+	headerType byte
+	granule    uint64
+	serial     uint32
+	sequence   uint32
+	lacing     []byte
+	data       []byte
+}
+
+// embedOggArt writes the cover as a METADATA_BLOCK_PICTURE comment in a
+// Vorbis or Opus stream
+func embedOggArt(src *os.File, size int64, dst io.Writer, art *embeddedArt) error {
+	return rewriteOggComments(src, size, dst, func(packet []byte) ([]byte, error) {
+		return editOggComments(packet, func(comments [][]byte) [][]byte {
+			kept := comments[:0]
+			for _, comment := range comments {
+				if !isOggCoverComment(comment) {
+					kept = append(kept, comment)
+				}
+			}
+			return append(kept, []byte("METADATA_BLOCK_PICTURE="+base64.StdEncoding.EncodeToString(flacPicture(art))))
+		})
+	})
+}
+
+// rewriteFLACComments writes the file with its Vorbis comments passed
+// through edit, adding a comment block after STREAMINFO if there is none
+func rewriteFLACComments(src *os.File, size int64, dst io.Writer, edit func([][]byte) [][]byte) error {
+	return rewriteFLAC(src, size, dst, func(blocks []flacBlock) ([]flacBlock, error) {
+		for i, b := range blocks {
+			if b.kind != flacCommentBlock {
+				continue
+			}
+			data, err := editVorbisComments(b.data, edit)
+			if err != nil {
+				return nil, err
+			}
+			blocks[i].data = data
+			return blocks, nil
+		}
+
+		// An empty block: no vendor and no comments
+		data, err := editVorbisComments(make([]byte, 8), edit)
+		if err != nil {
+			return nil, err
+		}
+		comment := flacBlock{flacCommentBlock, data}
+		return append(blocks[:1], append([]flacBlock{comment}, blocks[1:]...)...), nil
+	})
+}
+
+// rewriteOggComments writes a Vorbis or Opus stream with its comment packet
+// passed through edit, repaging the header packets and renumbering the
+// pages after them
+func rewriteOggComments(src *os.File, size int64, dst io.Writer, edit func(packet []byte) ([]byte, error)) error {
+	r := bufio.NewReader(io.NewSectionReader(src, 0, size))
+
+	var pages []*oggPage
+	var packets [][]byte
+	var partial []byte
+	headerPackets := 0
+	for headerPackets == 0 || len(packets) < headerPackets {
+		page, err := readOggPage(r)
+		if err != nil {
+			return fmt.Errorf("%w: %v", domain.ErrTrackCorrupted, err)
+		}
+		if len(pages) > 0 && page.serial != pages[0].serial {
+			return fmt.Errorf("%w: multiplexed Ogg stream", domain.ErrUnsupportedFormat)
+		}
+		pages = append(pages, page)
+
+		offset := 0
+		for i, lace := range page.lacing {
+			if headerPackets > 0 && len(packets) == headerPackets {
+				return fmt.Errorf("%w: Ogg headers share a page with audio", domain.ErrUnsupportedFormat)
+			}
+			partial = append(partial, page.data[offset:offset+int(lace)]...)
+			offset += int(lace)
+			if lace == 255 {
+				continue
+			}
+
+			packets = append(packets, partial)
+			partial = nil
+			if len(packets) == 1 {
+				// The identification header must be alone on the first page
+				if i != len(page.lacing)-1 {
+					return fmt.Errorf("%w: unexpected first Ogg page", domain.ErrUnsupportedFormat)
+				}
+				switch {
+				case bytes.HasPrefix(packets[0], []byte("\x01vorbis")):
+					headerPackets = 3
+				case bytes.HasPrefix(packets[0], []byte("OpusHead")):
+					headerPackets = 2
+				default:
+					return fmt.Errorf("%w: Ogg codec", domain.ErrUnsupportedFormat)
+				}
+			}
+		}
+	}
+
+	comments, err := edit(packets[1])
+	if err != nil {
+		return err
+	}
+	packets[1] = comments
+
+	headerPages := paginateOgg(packets[1:], pages[0].serial, 1)
+	delta := uint32(len(headerPages) - (len(pages) - 1))
+
+	if _, err := dst.Write(pages[0].bytes()); err != nil {
+		return err
+	}
+	for _, page := range headerPages {
+		if _, err := dst.Write(page.bytes()); err != nil {
+			return err
+		}
+	}
+	if delta == 0 {
+		_, err := io.Copy(dst, r)
+		return err
+	}
+
+	for {
+		page, err := readOggPage(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", domain.ErrTrackCorrupted, err)
+		}
+		if page.serial == pages[0].serial {
+			page.sequence += delta
+		}
+		if _, err := dst.Write(page.bytes()); err != nil {
+			return err
+		}
+	}
+}
+
+// editOggComments passes the comments of a Vorbis or Opus comment packet
+// through edit
+func editOggComments(packet []byte, edit func([][]byte) [][]byte) ([]byte, error) {
+	var magic []byte
+	switch {
+	case bytes.HasPrefix(packet, []byte("\x03vorbis")):
+		magic = packet[:7]
+	case bytes.HasPrefix(packet, []byte("OpusTags")):
+		magic = packet[:8]
+	default:
+		return nil, fmt.Errorf("%w: missing Ogg comment header", domain.ErrTrackCorrupted)
+	}
+
+	comments, err := editVorbisComments(packet[len(magic):], edit)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte(nil), magic...), comments...), nil
+}
+
+// editVorbisComments passes the comments of a Vorbis comment block, as
+// found in Ogg comment packets and FLAC metadata, through edit. Anything
+// after the comments, such as the Vorbis framing bit or Opus padding, is
+// kept.
+func editVorbisComments(b []byte, edit func([][]byte) [][]byte) ([]byte, error) {
+	readField := func() ([]byte, bool) {
+		if len(b) < 4 {
+			return nil, false
+		}
+		n := binary.LittleEndian.Uint32(b)
+		if uint64(n) > uint64(len(b)-4) {
+			return nil, false
+		}
+		field := b[4 : 4+n]
+		b = b[4+n:]
+		return field, true
+	}
+
+	vendor, ok := readField()
+	if !ok || len(b) < 4 {
+		return nil, fmt.Errorf("%w: invalid Vorbis comment header", domain.ErrTrackCorrupted)
+	}
+	count := binary.LittleEndian.Uint32(b)
+	b = b[4:]
+
+	var comments [][]byte
+	for i := uint32(0); i < count; i++ {
+		comment, ok := readField()
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid Vorbis comment header", domain.ErrTrackCorrupted)
+		}
+		comments = append(comments, comment)
+	}
+	comments = edit(comments)
+
+	var out []byte
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(vendor)))
+	out = append(out, vendor...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(comments)))
+	for _, comment := range comments {
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(comment)))
+		out = append(out, comment...)
+	}
+	return append(out, b...), nil
+}
+
+// isOggCoverComment reports whether a comment holds a cover image, in the
+// standard picture block form or the older COVERART form
+func isOggCoverComment(comment []byte) bool {
+	key, value, _ := strings.Cut(string(comment), "=")
+	switch strings.ToUpper(key) {
+	case "COVERART", "COVERARTMIME":
+		return true
+	case "METADATA_BLOCK_PICTURE":
+		picture, err := base64.StdEncoding.DecodeString(value)
+		return err != nil || len(picture) < 4 || isCoverPicture(binary.BigEndian.Uint32(picture))
+	}
+	return false
+}
+
+// paginateOgg lays packets out on pages, the last packet ending its page
+func paginateOgg(packets [][]byte, serial, sequence uint32) []*oggPage {
+	var pages []*oggPage
+	var page *oggPage
+	for _, packet := range packets {
+		remaining := packet
+		for first := true; ; first = false {
+			if page == nil || len(page.lacing) == 255 {
+				if page != nil {
+					pages = append(pages, page)
+				}
+				page = &oggPage{serial: serial, sequence: sequence + uint32(len(pages)), granule: ^uint64(0)}
+				if !first {
+					page.headerType = 0x01 // Continues a packet
+				}
+			}
+
+			// A packet of exactly n*255 bytes ends with an empty segment
+			n := min(len(remaining), 255)
+			page.lacing = append(page.lacing, byte(n))
+			page.data = append(page.data, remaining[:n]...)
+			remaining = remaining[n:]
+			if n < 255 {
+				page.granule = 0 // Header packets have no position
+				break
+			}
+		}
+	}
+	return append(pages, page)
+}
+
+func readOggPage(r *bufio.Reader) (*oggPage, error) {
+	header := make([]byte, 27)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("truncated Ogg page")
+		}
+		return nil, err
+	}
+	if string(header[:4]) != "OggS" {
+		return nil, fmt.Errorf("missing Ogg page marker")
+	}
+
+	page := &oggPage{
+		headerType: header[5],
+		granule:    binary.LittleEndian.Uint64(header[6:14]),
+		serial:     binary.LittleEndian.Uint32(header[14:18]),
+		sequence:   binary.LittleEndian.Uint32(header[18:22]),
+		lacing:     make([]byte, header[26]),
+	}
+	if _, err := io.ReadFull(r, page.lacing); err != nil {
+		return nil, fmt.Errorf("truncated Ogg page")
+	}
+
+	n := 0
+	for _, lace := range page.lacing {
+		n += int(lace)
+	}
+	page.data = make([]byte, n)
+	if _, err := io.ReadFull(r, page.data); err != nil {
+		return nil, fmt.Errorf("truncated Ogg page")
+	}
+	return page, nil
+}
+
+func (p *oggPage) bytes() []byte {
+	b := append([]byte("OggS"), 0, p.headerType)
+	b = binary.LittleEndian.AppendUint64(b, p.granule)
+	b = binary.LittleEndian.AppendUint32(b, p.serial)
+	b = binary.LittleEndian.AppendUint32(b, p.sequence)
+	b = append(b, 0, 0, 0, 0) // Checksum, filled in below
+	b = append(b, byte(len(p.lacing)))
+	b = append(b, p.lacing...)
+	b = append(b, p.data...)
+
+	binary.LittleEndian.PutUint32(b[22:26], oggCRC(b))
+	return b
+}
+
+var oggCRCTable = func() (table [256]uint32) {
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04C11DB7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+func oggCRC(b []byte) uint32 {
+	var crc uint32
+	for _, v := range b {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^v]
+	}
+	return crc
+}
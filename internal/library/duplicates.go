@@ -0,0 +1,152 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// ErrDuplicateSkipped is returned when an imported file is not added
+// because the library already holds a copy of it
+var ErrDuplicateSkipped = errors.New("file is a copy of a library track")
+
+// SetDuplicatePolicy sets how scans treat files already in the library.
+// Watch folders with a policy of their own override it.
+func (s *Scanner) SetDuplicatePolicy(policy domain.DuplicatePolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.duplicatePolicy = policy
+}
+
+// findCopies returns the library tracks at other paths that may hold the
+// same recording as a new file
+func (s *Scanner) findCopies(track *domain.Track) []*domain.Track {
+	candidates, err := s.trackRepo.FindDuplicates(track)
+	if err != nil {
+		logger.Warn("Failed to look for duplicates",
+			logger.String("path", track.FilePath),
+			logger.Error(err))
+		return nil
+	}
+	return candidates
+}
+
+// resolveDuplicate picks the library track that a new file is a copy of
+// from the candidates found by findCopies. It returns nil when the file
+// should be imported; otherwise replace says whether the library track
+// should move to the new file. Copies whose own file is missing don't
+// count, as those are left to findMoved.
+func (s *Scanner) resolveDuplicate(track *domain.Track, candidates []*domain.Track, policy domain.DuplicatePolicy) (*domain.Track, bool) {
+	if policy == domain.DuplicateKeepBoth {
+		return nil, false
+	}
+
+	for _, candidate := range candidates {
+		if !track.IsDuplicateOf(candidate) {
+			continue
+		}
+		if _, err := os.Stat(candidate.FilePath); err != nil {
+			continue
+		}
+
+		logger.Debug("Found duplicate",
+			logger.String("path", track.FilePath),
+			logger.String("existing", candidate.FilePath))
+		return candidate, policy == domain.DuplicatePreferQuality && track.BetterQualityThan(candidate)
+	}
+
+	return nil, false
+}
+
+// moveToCopy points a library track at another file holding its recording
+func (s *Scanner) moveToCopy(existing, track *domain.Track) error {
+	existing.TakeFileFrom(track)
+	if err := s.trackRepo.Update(existing); err != nil {
+		return err
+	}
+
+	// Update skips zero values, so a cleared error needs its own write
+	return s.trackRepo.UpdateStatus(existing)
+}
+
+// replaceDuplicate moves a library track to a better copy found by a scan
+func (s *Scanner) replaceDuplicate(existing, track *domain.Track, result *ScanResult) {
+	previous := existing.FilePath
+	if err := s.moveToCopy(existing, track); err != nil {
+		result.FailedFiles++
+		result.Errors = append(result.Errors, err)
+		logger.Warn("Failed to move track to better copy",
+			logger.String("path", track.FilePath),
+			logger.Error(err))
+		return
+	}
+
+	result.ScannedFiles++
+	s.report.Record(domain.ScanChangeUpdated, existing)
+	logger.Info("Replaced track with better copy",
+		logger.String("from", previous),
+		logger.String("to", existing.FilePath))
+}
+
+// ImportFile imports a single file outside of a folder scan, applying a
+// duplicate policy. It returns the library track for the file, which for
+// an unchanged file already in the library is the existing track. Files
+// skipped as copies of a library track return that track together with
+// ErrDuplicateSkipped.
+func (s *Scanner) ImportFile(ctx context.Context, path string, policy domain.DuplicatePolicy) (*domain.Track, error) {
+	existing, err := s.trackRepo.FindByPath(path)
+	if err != nil && !errors.Is(err, domain.ErrTrackNotFound) {
+		return nil, err
+	}
+
+	track, needsDecode, err := s.scanFile(ctx, path, existing, policy == domain.DuplicateUpdateInPlace)
+	if err != nil {
+		return nil, err
+	}
+	if track == nil {
+		return existing, nil
+	}
+
+	if needsDecode {
+		if err := s.readStreamInfo(track); err != nil {
+			logger.Warn("Failed to read stream info", logger.String("path", path), logger.Error(err))
+		}
+		if err := s.checkDuration(track); err != nil {
+			return nil, err
+		}
+	}
+
+	if existing != nil {
+		if err := s.saveChangedTrack(track); err != nil {
+			return nil, err
+		}
+		return track, nil
+	}
+
+	candidates := s.findCopies(track)
+	if moved := findMoved(track, candidates); moved != nil {
+		if err := s.moveToCopy(moved, track); err != nil {
+			return nil, err
+		}
+		return moved, nil
+	}
+
+	if duplicate, replace := s.resolveDuplicate(track, candidates, policy); duplicate != nil {
+		if !replace {
+			return duplicate, fmt.Errorf("%w: %s", ErrDuplicateSkipped, duplicate.FilePath)
+		}
+		if err := s.moveToCopy(duplicate, track); err != nil {
+			return nil, err
+		}
+		return duplicate, nil
+	}
+
+	if err := s.trackRepo.Create(track); err != nil {
+		return nil, err
+	}
+	return track, nil
+}
@@ -0,0 +1,63 @@
+package library
+
+import (
+	"context"
+	"os"
+
+	"github.com/dhowden/tag"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/metadata"
+)
+
+// tagProvider supplies metadata from the tags in a track's own file
+type tagProvider struct{}
+
+// NewTagProvider returns the metadata provider reading a file's own tags.
+// It is usually first in a chain, so online sources only fill what the
+// tags leave out.
+func NewTagProvider() metadata.Provider {
+	return tagProvider{}
+}
+
+func (tagProvider) Name() string {
+	return "tags"
+}
+
+func (tagProvider) Lookup(ctx context.Context, track *domain.Track) (*metadata.Fields, error) {
+	if track.FilePath == "" || track.Source.Kind != "" && !track.Source.IsLocal() {
+		return nil, metadata.ErrNoMatch
+	}
+
+	file, err := os.Open(track.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	m, err := tag.ReadFrom(file)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := &metadata.Fields{
+		Title:       m.Title(),
+		Artist:      m.Artist(),
+		Album:       m.Album(),
+		AlbumArtist: m.AlbumArtist(),
+		Genre:       m.Genre(),
+		Composer:    m.Composer(),
+		Publisher:   rawTagText(m.Raw(), publisherTags...),
+		Year:        m.Year(),
+		ArtistMBID:  firstValue(rawTagText(m.Raw(), musicBrainzArtistTags...)),
+		Language:    tagLanguage(m.Raw()),
+		Advisory:    string(tagAdvisory(m.Raw())),
+	}
+	if fields.ArtistMBID == "" {
+		fields.ArtistMBID = firstValue(userTagText(m.Raw(), "MusicBrainz Artist Id"))
+	}
+	fields.TrackNumber, _ = m.Track()
+	fields.DiscNumber, _ = m.Disc()
+
+	return fields, nil
+}
@@ -0,0 +1,42 @@
+package library
+
+import (
+	"github.com/winramp/winramp/internal/events"
+)
+
+// Topics the scanners publish to their event bus
+var (
+	// TopicScanStarted carries the folder being scanned
+	TopicScanStarted = events.NewTopic[string]("library:scanStarted")
+	// TopicScanProgress carries how far a scan is, a few times a second
+	TopicScanProgress = events.NewTopic[*ScanProgress]("library:scanProgress")
+	// TopicScanFinished carries the result of every scan, including
+	// cancelled ones, once its changes are saved
+	TopicScanFinished = events.NewTopic[*ScanResult]("library:scanFinished")
+
+	// TopicGainProgress carries each track a ReplayGain scan measures
+	TopicGainProgress = events.NewTopic[*GainProgress]("library:gainProgress")
+	// TopicGainFinished carries the result of every ReplayGain scan,
+	// including cancelled ones
+	TopicGainFinished = events.NewTopic[*GainScanResult]("library:gainFinished")
+
+	// TopicIdentifyProgress carries each track an identification looks up
+	TopicIdentifyProgress = events.NewTopic[*IdentifyProgress]("library:identifyProgress")
+	// TopicIdentifyFinished carries the result of every identification,
+	// including cancelled ones
+	TopicIdentifyFinished = events.NewTopic[*IdentifyResult]("library:identifyFinished")
+)
+
+// SetEventBus sets the bus scan events are published to
+func (s *Scanner) SetEventBus(bus *events.Bus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bus = bus
+}
+
+// eventBus returns the bus set with SetEventBus, or nil
+func (s *Scanner) eventBus() *events.Bus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.bus
+}
@@ -0,0 +1,164 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/metadata"
+)
+
+// filenameFields are the placeholders a filename pattern may use, with
+// what each matches. Numbers match digits only, so "{track} - {title}"
+// doesn't take a title for a track number.
+var filenameFields = map[string]string{
+	"artist":      `[^/]+?`,
+	"albumartist": `[^/]+?`,
+	"album":       `[^/]+?`,
+	"title":       `[^/]+?`,
+	"genre":       `[^/]+?`,
+	"year":        `\d{4}`,
+	"track":       `\d{1,3}`,
+	"disc":        `\d{1,2}`,
+	"*":           `[^/]*?`, // Anything, ignored
+}
+
+// filenamePlaceholder finds the placeholders in a pattern
+var filenamePlaceholder = regexp.MustCompile(`\{([a-z*]+)\}`)
+
+// FilenamePattern reads tags from where a file is and what it is called,
+// for rips that have none. Patterns such as "{artist}/{album}/{track} -
+// {title}" are matched against the end of the path, without the file's
+// extension; "/" separates folders on every platform.
+type FilenamePattern struct {
+	pattern string
+	re      *regexp.Regexp
+	fields  []string // Placeholder of each group of re
+}
+
+// ParseFilenamePattern compiles a pattern. Placeholders are {artist},
+// {albumartist}, {album}, {title}, {genre}, {year}, {track}, {disc} and
+// {*} for text to skip; anything else matches itself.
+func ParseFilenamePattern(pattern string) (*FilenamePattern, error) {
+	trimmed := strings.Trim(filepath.ToSlash(strings.TrimSpace(pattern)), "/")
+	if trimmed == "" {
+		return nil, fmt.Errorf("%w: empty filename pattern", domain.ErrInvalidInput)
+	}
+
+	var b strings.Builder
+	var fields []string
+	b.WriteString(`(?:^|/)`)
+	last := 0
+	for _, loc := range filenamePlaceholder.FindAllStringSubmatchIndex(trimmed, -1) {
+		name := trimmed[loc[2]:loc[3]]
+		expr, ok := filenameFields[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown placeholder {%s} in filename pattern", domain.ErrInvalidInput, name)
+		}
+		b.WriteString(regexp.QuoteMeta(trimmed[last:loc[0]]))
+		b.WriteString("(" + expr + ")")
+		fields = append(fields, name)
+		last = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(trimmed[last:]))
+	b.WriteString(`$`)
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: filename pattern %q has no placeholders", domain.ErrInvalidInput, pattern)
+	}
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: filename pattern %q: %v", domain.ErrInvalidInput, pattern, err)
+	}
+	return &FilenamePattern{pattern: pattern, re: re, fields: fields}, nil
+}
+
+// String returns the pattern as it was written
+func (p *FilenamePattern) String() string {
+	return p.pattern
+}
+
+// Match reads the fields a path gives under the pattern, reporting
+// whether it matched
+func (p *FilenamePattern) Match(path string) (*metadata.Fields, bool) {
+	path = filepath.ToSlash(path)
+	path = strings.TrimSuffix(path, filepath.Ext(path))
+
+	groups := p.re.FindStringSubmatch(path)
+	if groups == nil {
+		return nil, false
+	}
+
+	fields := &metadata.Fields{}
+	for i, name := range p.fields {
+		value := strings.TrimSpace(groups[i+1])
+		if value == "" {
+			continue
+		}
+		switch name {
+		case "artist":
+			fields.Artist = value
+		case "albumartist":
+			fields.AlbumArtist = value
+		case "album":
+			fields.Album = value
+		case "title":
+			fields.Title = value
+		case "genre":
+			fields.Genre = value
+		case "year":
+			fields.Year, _ = strconv.Atoi(value)
+		case "track":
+			fields.TrackNumber, _ = strconv.Atoi(value)
+		case "disc":
+			fields.DiscNumber, _ = strconv.Atoi(value)
+		}
+	}
+	return fields, true
+}
+
+// ParseFilenamePatterns compiles patterns, keeping their order
+func ParseFilenamePatterns(patterns []string) ([]*FilenamePattern, error) {
+	parsed := make([]*FilenamePattern, len(patterns))
+	for i, pattern := range patterns {
+		p, err := ParseFilenamePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = p
+	}
+	return parsed, nil
+}
+
+// filenameProvider supplies metadata from a file's path
+type filenameProvider struct {
+	patterns []*FilenamePattern
+}
+
+// NewFilenameProvider returns the metadata provider reading fields from a
+// file's path with the first of the patterns it matches. It belongs after
+// the tag provider, so paths only fill in what the tags leave out.
+func NewFilenameProvider(patterns []*FilenamePattern) metadata.Provider {
+	return &filenameProvider{patterns: patterns}
+}
+
+func (p *filenameProvider) Name() string {
+	return "filename"
+}
+
+func (p *filenameProvider) Lookup(ctx context.Context, track *domain.Track) (*metadata.Fields, error) {
+	if track.FilePath == "" || track.Source.Kind != "" && !track.Source.IsLocal() {
+		return nil, metadata.ErrNoMatch
+	}
+
+	for _, pattern := range p.patterns {
+		if fields, ok := pattern.Match(track.FilePath); ok {
+			return fields, nil
+		}
+	}
+	return nil, metadata.ErrNoMatch
+}
@@ -0,0 +1,69 @@
+package library
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/metadata"
+)
+
+func TestFilenamePatternMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		want    *metadata.Fields
+	}{
+		{"folders", "{artist}/{album}/{track} - {title}", "/music/Björk/Post/02 - Army of Me.mp3",
+			&metadata.Fields{Artist: "Björk", Album: "Post", TrackNumber: 2, Title: "Army of Me"}},
+		{"year and disc", "{albumartist}/{year} - {album}/{disc}-{track} {title}", "/m/Can/1971 - Tago Mago/1-03 Oh Yeah.flac",
+			&metadata.Fields{AlbumArtist: "Can", Year: 1971, Album: "Tago Mago", DiscNumber: 1, TrackNumber: 3, Title: "Oh Yeah"}},
+		{"skipped text", "{artist} - {*}/{track}. {title}", "/m/Low - Things We Lost [FLAC]/7. Closer.flac",
+			&metadata.Fields{Artist: "Low", TrackNumber: 7, Title: "Closer"}},
+		{"dashes in the title", "{track} - {title}", "/m/01 - Re-Make - Re-Model.mp3",
+			&metadata.Fields{TrackNumber: 1, Title: "Re-Make - Re-Model"}},
+		{"track must be a number", "{track} - {title}", "/m/Artist - Song.mp3", nil},
+		{"too few folders", "{artist}/{album}/{track} - {title}", "01 - Song.mp3", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, err := ParseFilenamePattern(tt.pattern)
+			require.NoError(t, err)
+			fields, ok := pattern.Match(tt.path)
+			assert.Equal(t, tt.want != nil, ok)
+			assert.Equal(t, tt.want, fields)
+		})
+	}
+}
+
+func TestParseFilenamePatternErrors(t *testing.T) {
+	for _, pattern := range []string{"", "/", "{mood} - {title}", "Artist - Title"} {
+		_, err := ParseFilenamePattern(pattern)
+		assert.ErrorIs(t, err, domain.ErrInvalidInput, pattern)
+	}
+}
+
+func TestFilenameProvider(t *testing.T) {
+	patterns, err := ParseFilenamePatterns([]string{"{artist}/{album}/{track} - {title}", "{artist} - {title}"})
+	require.NoError(t, err)
+
+	// Tags win; the path fills in the rest
+	track := &domain.Track{FilePath: "/music/Low/Secret Name/03 - Weight of Water.mp3", Artist: "LOW"}
+	chain := metadata.NewChain(metadata.MergeFill, NewFilenameProvider(patterns))
+	changed, err := chain.Enrich(context.Background(), track)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"album": "filename", "title": "filename", "trackNumber": "filename"}, changed)
+	assert.Equal(t, "LOW", track.Artist)
+	assert.Equal(t, "Weight of Water", track.Title)
+
+	_, err = NewFilenameProvider(patterns).Lookup(context.Background(), &domain.Track{
+		FilePath: "https://example.com/stream.mp3",
+		Source:   domain.Source{Kind: domain.SourceStream, URI: "https://example.com/stream.mp3"},
+	})
+	assert.ErrorIs(t, err, metadata.ErrNoMatch)
+}
@@ -0,0 +1,171 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// ErrRecycleBinUnavailable is returned where files cannot be sent to a
+// recycle bin or trash
+var ErrRecycleBinUnavailable = errors.New("recycle bin is not available")
+
+// FileAttributes describes a track's file as the file system sees it
+type FileAttributes struct {
+	Size     int64
+	Modified time.Time
+	ReadOnly bool
+	Hidden   bool
+	Remote   bool // On a network drive
+}
+
+// StatFile reads the attributes of a file
+func StatFile(path string) (*FileAttributes, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", domain.ErrFileNotFound, path)
+		}
+		return nil, err
+	}
+
+	return &FileAttributes{
+		Size:     info.Size(),
+		Modified: info.ModTime(),
+		ReadOnly: info.Mode().Perm()&0o200 == 0,
+		Hidden:   strings.HasPrefix(info.Name(), ".") || hasHiddenAttribute(path),
+		Remote:   isRemoteDrive(path),
+	}, nil
+}
+
+// FileOps performs file operations on library tracks and keeps the library
+// in step with the file system
+type FileOps struct {
+	trackRepo  domain.TrackRepository
+	markerRepo domain.MarkerRepository
+	artStore   *ArtStore
+}
+
+// NewFileOps creates a new file operations helper
+func NewFileOps(trackRepo domain.TrackRepository, markerRepo domain.MarkerRepository, artStore *ArtStore) *FileOps {
+	return &FileOps{
+		trackRepo:  trackRepo,
+		markerRepo: markerRepo,
+		artStore:   artStore,
+	}
+}
+
+// Reveal opens the folder holding a track with the file selected
+func (f *FileOps) Reveal(id string) error {
+	track, err := f.trackRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(track.FilePath); err != nil {
+		return fmt.Errorf("%w: %s", domain.ErrFileNotFound, track.FilePath)
+	}
+
+	return RevealInExplorer(track.FilePath)
+}
+
+// Recycle sends a track's file to the Recycle Bin and removes the track,
+// its markers and any album art only it used from the library
+func (f *FileOps) Recycle(id string) (*domain.Track, error) {
+	track, err := f.trackRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := RecycleFile(track.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to delete %s: %w", track.FilePath, err)
+	}
+
+	if err := f.trackRepo.Delete(track.ID); err != nil {
+		return nil, err
+	}
+
+	if f.markerRepo != nil {
+		for _, markerType := range []domain.MarkerType{domain.MarkerTypeChapter, domain.MarkerTypeBookmark, domain.MarkerTypeSilence} {
+			if err := f.markerRepo.DeleteByTrack(track.ID, markerType); err != nil {
+				logger.Warn("Failed to delete track markers", logger.String("id", track.ID), logger.Error(err))
+			}
+		}
+	}
+
+	if f.artStore != nil {
+		if err := f.artStore.Release(track.AlbumArtPath); err != nil {
+			logger.Warn("Failed to release album art", logger.String("path", track.AlbumArtPath), logger.Error(err))
+		}
+	}
+
+	logger.Info("Track file sent to Recycle Bin", logger.String("path", track.FilePath))
+	return track, nil
+}
+
+// Move moves a track's file into destDir, which must lie inside one of the
+// watch folders, and updates the library to point at the new location
+func (f *FileOps) Move(id string, destDir string, watchFolders []string) (*domain.Track, error) {
+	track, err := f.trackRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	destDir = filepath.Clean(destDir)
+	if !isWithinAny(destDir, watchFolders) {
+		return nil, fmt.Errorf("%w: %s is not inside a watch folder", domain.ErrInvalidInput, destDir)
+	}
+
+	info, err := os.Stat(destDir)
+	if err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%w: %s", domain.ErrPathNotAccessible, destDir)
+	}
+
+	dest := filepath.Join(destDir, filepath.Base(track.FilePath))
+	if strings.EqualFold(dest, filepath.Clean(track.FilePath)) {
+		return track, nil
+	}
+	if _, err := os.Stat(dest); err == nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrAlreadyExists, dest)
+	}
+
+	if err := moveFile(track.FilePath, dest); err != nil {
+		return nil, fmt.Errorf("failed to move %s: %w", track.FilePath, err)
+	}
+
+	source := track.FilePath
+	track.SetFilePath(dest)
+	if err := f.trackRepo.Update(track); err != nil {
+		// Put the file back so the library stays consistent
+		if undoErr := moveFile(dest, source); undoErr != nil {
+			logger.ErrorLog("Failed to undo file move",
+				logger.String("from", dest),
+				logger.String("to", source),
+				logger.Error(undoErr))
+		}
+		return nil, err
+	}
+
+	logger.Info("Track file moved", logger.String("from", source), logger.String("to", dest))
+	return track, nil
+}
+
+// isWithinAny reports whether path is one of the roots or inside one
+func isWithinAny(path string, roots []string) bool {
+	for _, root := range roots {
+		rel, err := filepath.Rel(filepath.Clean(root), path)
+		if err != nil {
+			continue
+		}
+		if rel == "." || (!strings.HasPrefix(rel, "..") && !filepath.IsAbs(rel)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package library
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// ErrFingerprinterMissing is returned when Chromaprint's fpcalc cannot be
+// found
+var ErrFingerprinterMissing = errors.New("fpcalc was not found; install Chromaprint or set its path")
+
+// fingerprintLength is how much of each file is fingerprinted, enough for
+// AcoustID to identify it
+const fingerprintLength = 120 * time.Second
+
+// Fingerprinter computes the Chromaprint fingerprints AcoustID identifies
+// recordings by, with the fpcalc tool
+type Fingerprinter struct {
+	fpcalc string
+}
+
+// NewFingerprinter creates a fingerprinter running fpcalc from the given
+// path, or from PATH when it is empty
+func NewFingerprinter(fpcalc string) *Fingerprinter {
+	if fpcalc == "" {
+		fpcalc = "fpcalc"
+	}
+	return &Fingerprinter{fpcalc: fpcalc}
+}
+
+// Available reports whether fpcalc can be run
+func (f *Fingerprinter) Available() bool {
+	_, err := exec.LookPath(f.fpcalc)
+	return err == nil
+}
+
+// Fingerprint computes a file's fingerprint, returning it with the
+// duration fpcalc measured
+func (f *Fingerprinter) Fingerprint(ctx context.Context, path string) (string, time.Duration, error) {
+	if !f.Available() {
+		return "", 0, ErrFingerprinterMissing
+	}
+
+	cmd := exec.CommandContext(ctx, f.fpcalc, "-json", "-length", fmt.Sprint(int(fingerprintLength.Seconds())), path)
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", 0, fmt.Errorf("fpcalc failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", 0, fmt.Errorf("fpcalc failed: %w", err)
+	}
+	return parseFpcalc(output)
+}
+
+// parseFpcalc reads fpcalc's JSON output
+func parseFpcalc(output []byte) (string, time.Duration, error) {
+	var result struct {
+		Duration    float64 `json:"duration"`
+		Fingerprint string  `json:"fingerprint"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return "", 0, fmt.Errorf("failed to read fpcalc output: %w", err)
+	}
+	if result.Fingerprint == "" {
+		return "", 0, errors.New("fpcalc returned no fingerprint")
+	}
+	return result.Fingerprint, time.Duration(result.Duration * float64(time.Second)), nil
+}
+
+// ForTrack fingerprints a local track's file, for metadata providers that
+// identify tracks by their audio
+func (f *Fingerprinter) ForTrack(ctx context.Context, track *domain.Track) (string, error) {
+	if !track.GetSource().IsLocal() {
+		return "", fmt.Errorf("%w: only local files can be fingerprinted", domain.ErrInvalidInput)
+	}
+	fingerprint, _, err := f.Fingerprint(ctx, track.FilePath)
+	return fingerprint, err
+}
@@ -0,0 +1,29 @@
+package library
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFpcalc(t *testing.T) {
+	fingerprint, duration, err := parseFpcalc([]byte(`{"duration": 236.45, "fingerprint": "AQADtEmUaEkSRZEG"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "AQADtEmUaEkSRZEG", fingerprint)
+	assert.Equal(t, 236450*time.Millisecond, duration)
+
+	_, _, err = parseFpcalc([]byte(`{"duration": 10}`))
+	assert.Error(t, err)
+	_, _, err = parseFpcalc([]byte(`ERROR: unable to open file`))
+	assert.Error(t, err)
+}
+
+func TestFingerprinterMissing(t *testing.T) {
+	f := NewFingerprinter("/nonexistent/fpcalc")
+	assert.False(t, f.Available())
+	_, _, err := f.Fingerprint(context.Background(), "song.mp3")
+	assert.ErrorIs(t, err, ErrFingerprinterMissing)
+}
@@ -0,0 +1,348 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/metadata"
+)
+
+// folderExportMarker marks the desktop.ini and album.nfo files WinRamp
+// wrote, which later exports may replace
+const folderExportMarker = "Written by WinRamp"
+
+const (
+	folderCoverName = "folder.jpg"
+	desktopININame  = "desktop.ini"
+	albumNFOName    = "album.nfo"
+)
+
+// FolderExportOptions chooses what is written into album folders so
+// Explorer and other players show them without reading WinRamp's library
+type FolderExportOptions struct {
+	Cover      bool `json:"cover"`      // folder.jpg, Explorer's folder thumbnail
+	DesktopINI bool `json:"desktopIni"` // desktop.ini setting the folder up as music
+	NFO        bool `json:"nfo"`        // album.nfo summing up the album
+	Overwrite  bool `json:"overwrite"`  // Replace files WinRamp did not write
+}
+
+// FolderExportResult summarises an export job
+type FolderExportResult struct {
+	Written  int               // Folders at least one file was written into
+	Skipped  int               // Folders holding several albums, or already done
+	Failed   map[string]string // Folder to error
+	Duration time.Duration
+}
+
+// FolderExporter writes album covers and summaries into the folders of
+// albums as a single job
+type FolderExporter struct {
+	artStore *ArtStore
+
+	isRunning  bool
+	cancelFunc context.CancelFunc
+	progress   float64
+
+	mu sync.RWMutex
+}
+
+// NewFolderExporter creates a new folder exporter
+func NewFolderExporter(artStore *ArtStore) *FolderExporter {
+	return &FolderExporter{artStore: artStore}
+}
+
+// Export writes the chosen files into the folder of each album among the
+// tracks. Folders holding tracks of several albums are left alone, as no
+// one cover fits them. A folder that cannot be written is recorded in the
+// result and the job carries on with the rest.
+func (e *FolderExporter) Export(ctx context.Context, tracks []*domain.Track, opts FolderExportOptions) (*FolderExportResult, error) {
+	if !opts.Cover && !opts.DesktopINI && !opts.NFO {
+		return nil, fmt.Errorf("%w: nothing to export", domain.ErrInvalidInput)
+	}
+
+	e.mu.Lock()
+	if e.isRunning {
+		e.mu.Unlock()
+		return nil, fmt.Errorf("folder export already in progress")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	e.isRunning = true
+	e.cancelFunc = cancel
+	e.progress = 0
+	e.mu.Unlock()
+
+	defer func() {
+		cancel()
+		e.mu.Lock()
+		e.isRunning = false
+		e.cancelFunc = nil
+		e.progress = 100
+		e.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	result := &FolderExportResult{Failed: make(map[string]string)}
+
+	folders := albumFolders(tracks)
+	dirs := make([]string, 0, len(folders))
+	for dir := range folders {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for i, dir := range dirs {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		switch written, err := e.exportFolder(ctx, dir, folders[dir], opts); {
+		case err != nil:
+			result.Failed[dir] = err.Error()
+			logger.Warn("Failed to export album folder",
+				logger.String("folder", dir),
+				logger.Error(err))
+		case written:
+			result.Written++
+		default:
+			result.Skipped++
+		}
+
+		e.mu.Lock()
+		e.progress = float64(i+1) / float64(len(dirs)) * 100
+		e.mu.Unlock()
+	}
+
+	result.Duration = time.Since(startTime)
+
+	logger.Info("Album folders exported",
+		logger.Int("written", result.Written),
+		logger.Int("skipped", result.Skipped),
+		logger.Int("failed", len(result.Failed)),
+		logger.Duration("duration", result.Duration),
+	)
+
+	return result, nil
+}
+
+// albumFolders groups tracks by the folder their file is in, leaving out
+// tracks that are not local files and folders holding several albums
+func albumFolders(tracks []*domain.Track) map[string][]*domain.Track {
+	folders := make(map[string][]*domain.Track)
+	for _, track := range tracks {
+		if track.GetSource().Kind != domain.SourceFile || track.AlbumKey() == "" {
+			continue
+		}
+		dir := filepath.Dir(track.FilePath)
+		folders[dir] = append(folders[dir], track)
+	}
+
+	for dir, album := range folders {
+		for _, track := range album[1:] {
+			if track.AlbumKey() != album[0].AlbumKey() {
+				folders[dir] = nil
+				break
+			}
+		}
+	}
+	return folders
+}
+
+// exportFolder writes the chosen files into one album folder, reporting
+// whether any were written
+func (e *FolderExporter) exportFolder(ctx context.Context, dir string, tracks []*domain.Track, opts FolderExportOptions) (bool, error) {
+	if len(tracks) == 0 {
+		return false, nil
+	}
+	written := false
+
+	if opts.Cover && (opts.Overwrite || !fileExists(filepath.Join(dir, folderCoverName))) {
+		cover, err := e.folderCover(ctx, tracks)
+		switch {
+		case errors.Is(err, metadata.ErrNoAlbumArt):
+		case err != nil:
+			return written, err
+		default:
+			if err := os.WriteFile(filepath.Join(dir, folderCoverName), cover, 0644); err != nil {
+				return written, err
+			}
+			written = true
+		}
+	}
+
+	if opts.DesktopINI && (opts.Overwrite || canReplaceExport(filepath.Join(dir, desktopININame))) {
+		if err := writeDesktopINI(dir); err != nil {
+			return written, err
+		}
+		written = true
+	}
+
+	if opts.NFO && (opts.Overwrite || canReplaceExport(filepath.Join(dir, albumNFOName))) {
+		data, err := albumNFO(tracks)
+		if err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, albumNFOName), data, 0644); err != nil {
+			return written, err
+		}
+		written = true
+	}
+
+	return written, nil
+}
+
+// folderCover returns an album's art as the JPEG Explorer reads as a
+// folder thumbnail
+func (e *FolderExporter) folderCover(ctx context.Context, tracks []*domain.Track) ([]byte, error) {
+	if e.artStore == nil {
+		return nil, metadata.ErrNoAlbumArt
+	}
+	path, err := e.artStore.AlbumArt(ctx, tracks)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	art, err := prepareEmbeddedArt(data, ArtEmbedOptions{Format: "jpeg"})
+	if err != nil {
+		return nil, err
+	}
+	return art.data, nil
+}
+
+// writeDesktopINI sets a folder up for Explorer to show as music, with
+// its folder.jpg as the thumbnail. Explorer only reads desktop.ini while
+// it is hidden and the folder read-only.
+func writeDesktopINI(dir string) error {
+	path := filepath.Join(dir, desktopININame)
+	content := "; " + folderExportMarker + "\r\n" +
+		"[.ShellClassInfo]\r\n" +
+		"ConfirmFileOp=0\r\n" +
+		"[ViewState]\r\n" +
+		"Mode=\r\n" +
+		"Vid=\r\n" +
+		"FolderType=Music\r\n"
+
+	// A hidden system file cannot be opened for writing, only removed
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+	if err := hideShellFile(path); err != nil {
+		return err
+	}
+	return markCustomFolder(dir)
+}
+
+// nfoAlbum is the album.nfo format Kodi and other media centres read
+type nfoAlbum struct {
+	XMLName xml.Name   `xml:"album"`
+	Title   string     `xml:"title"`
+	Artist  string     `xml:"artist"`
+	Genre   string     `xml:"genre,omitempty"`
+	Year    int        `xml:"year,omitempty"`
+	Label   string     `xml:"label,omitempty"`
+	Tracks  []nfoTrack `xml:"track"`
+}
+
+type nfoTrack struct {
+	Disc     int    `xml:"disc,omitempty"`
+	Position int    `xml:"position,omitempty"`
+	Title    string `xml:"title"`
+	Duration string `xml:"duration"`
+}
+
+// albumNFO sums an album's tracks up as an album.nfo
+func albumNFO(tracks []*domain.Track) ([]byte, error) {
+	groups := domain.GroupAlbums(tracks)
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("%w: no album", domain.ErrInvalidInput)
+	}
+	group := groups[0]
+
+	album := nfoAlbum{
+		Title:  group.Title,
+		Artist: group.Artist,
+		Year:   group.Year,
+		Genre:  tracks[0].Genre,
+		Label:  tracks[0].Publisher,
+	}
+	for _, disc := range group.Discs {
+		for _, track := range disc.Tracks {
+			seconds := int(track.Duration.Seconds())
+			album.Tracks = append(album.Tracks, nfoTrack{
+				Disc:     track.DiscNumber,
+				Position: track.TrackNumber,
+				Title:    track.Title,
+				Duration: fmt.Sprintf("%d:%02d", seconds/60, seconds%60),
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString("<!-- " + folderExportMarker + " -->\n")
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(album); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// fileExists reports whether a path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// canReplaceExport reports whether a file is missing or was written by an
+// earlier export
+func canReplaceExport(path string) bool {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+	return err == nil && strings.Contains(string(data), folderExportMarker)
+}
+
+// Cancel cancels a running job. Folders already written keep their files.
+func (e *FolderExporter) Cancel() {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.cancelFunc != nil {
+		e.cancelFunc()
+	}
+}
+
+// IsRunning returns whether a job is in progress
+func (e *FolderExporter) IsRunning() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.isRunning
+}
+
+// GetProgress returns the job progress (0-100)
+func (e *FolderExporter) GetProgress() float64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.progress
+}
@@ -0,0 +1,64 @@
+package library
+
+import (
+	"context"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestFolderExport(t *testing.T) {
+	album := t.TempDir()
+	mixed := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(album, "cover.png"), testPNG(t, 16), 0600))
+
+	tracks := []*domain.Track{
+		{Title: "Army of Me", Album: "Post", AlbumArtist: "Björk", Year: 1995, TrackNumber: 1, Duration: 234 * time.Second, FilePath: filepath.Join(album, "01.mp3")},
+		{Title: "Hyperballad", Album: "Post", AlbumArtist: "Björk", Year: 1995, TrackNumber: 2, Duration: 321 * time.Second, FilePath: filepath.Join(album, "02.mp3")},
+		{Title: "Joga", Album: "Homogenic", AlbumArtist: "Björk", FilePath: filepath.Join(mixed, "01.mp3")},
+		{Title: "Hunter", Album: "Vespertine", AlbumArtist: "Björk", FilePath: filepath.Join(mixed, "02.mp3")},
+	}
+
+	exporter := NewFolderExporter(NewArtStore(t.TempDir(), 0, &artTrackRepo{}))
+	result, err := exporter.Export(context.Background(), tracks, FolderExportOptions{Cover: true, DesktopINI: true, NFO: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Written)
+	assert.Equal(t, 1, result.Skipped, "a folder of several albums is left alone")
+	assert.Empty(t, result.Failed)
+
+	file, err := os.Open(filepath.Join(album, folderCoverName))
+	require.NoError(t, err)
+	defer file.Close()
+	_, format, err := image.DecodeConfig(file)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+
+	ini, err := os.ReadFile(filepath.Join(album, desktopININame))
+	require.NoError(t, err)
+	assert.Contains(t, string(ini), "FolderType=Music")
+
+	nfo, err := os.ReadFile(filepath.Join(album, albumNFOName))
+	require.NoError(t, err)
+	assert.Contains(t, string(nfo), "<title>Post</title>")
+	assert.Contains(t, string(nfo), "<duration>5:21</duration>")
+	assert.NoFileExists(t, filepath.Join(mixed, albumNFOName))
+
+	// Files WinRamp did not write are kept unless overwriting
+	require.NoError(t, os.WriteFile(filepath.Join(album, albumNFOName), []byte("<album/>"), 0600))
+	result, err = exporter.Export(context.Background(), tracks[:2], FolderExportOptions{NFO: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Skipped)
+	nfo, err = os.ReadFile(filepath.Join(album, albumNFOName))
+	require.NoError(t, err)
+	assert.Equal(t, "<album/>", string(nfo))
+
+	_, err = exporter.Export(context.Background(), tracks, FolderExportOptions{})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
@@ -0,0 +1,144 @@
+package library
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// DefaultFolderPageSize is how many tracks a folder listing returns at once
+// when no limit is given. Larger folders are loaded page by page.
+const DefaultFolderPageSize = 500
+
+// FolderNode is a folder in the library folder tree
+type FolderNode struct {
+	Path          string `json:"path"`
+	Name          string `json:"name"`
+	TrackCount    int    `json:"trackCount"` // Including subfolders
+	HasSubfolders bool   `json:"hasSubfolders"`
+}
+
+// FolderListing is the content of one folder: its subfolders and a page of
+// the tracks directly inside it
+type FolderListing struct {
+	Path        string          `json:"path"`
+	Folders     []FolderNode    `json:"folders"`
+	Tracks      []*domain.Track `json:"tracks"`
+	TotalTracks int64           `json:"totalTracks"`
+	Offset      int             `json:"offset"`
+}
+
+// FolderBrowser presents the library as a folder tree rooted at the watch
+// folders, for users who organise music by folder rather than by tag
+type FolderBrowser struct {
+	trackRepo domain.TrackRepository
+}
+
+// NewFolderBrowser creates a folder browser over the library
+func NewFolderBrowser(trackRepo domain.TrackRepository) *FolderBrowser {
+	return &FolderBrowser{
+		trackRepo: trackRepo,
+	}
+}
+
+// Roots returns a node for each watch folder
+func (b *FolderBrowser) Roots(watchFolders []string) ([]FolderNode, error) {
+	roots := make([]FolderNode, 0, len(watchFolders))
+	for _, folder := range watchFolders {
+		folder = filepath.Clean(folder)
+		count, err := b.trackRepo.CountUnder(folder)
+		if err != nil {
+			return nil, err
+		}
+		folders, err := b.trackRepo.FindSubfolders(folder)
+		if err != nil {
+			return nil, err
+		}
+
+		roots = append(roots, FolderNode{
+			Path:          folder,
+			Name:          folder,
+			TrackCount:    int(count),
+			HasSubfolders: len(folders) > 0,
+		})
+	}
+
+	return roots, nil
+}
+
+// List returns the subfolders of dir and up to limit of the tracks directly
+// inside it, starting at offset. dir must be inside a watch folder.
+func (b *FolderBrowser) List(dir string, watchFolders []string, offset, limit int) (*FolderListing, error) {
+	dir = filepath.Clean(dir)
+	if !isWithinAny(dir, watchFolders) {
+		return nil, fmt.Errorf("%w: %s is not inside a watch folder", domain.ErrInvalidInput, dir)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = DefaultFolderPageSize
+	}
+
+	listing := &FolderListing{
+		Path:   dir,
+		Offset: offset,
+	}
+
+	// Subfolders are only listed with the first page
+	if offset == 0 {
+		folders, err := b.subfolders(dir)
+		if err != nil {
+			return nil, err
+		}
+		listing.Folders = folders
+	}
+
+	tracks, total, err := b.trackRepo.FindInFolder(dir, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	listing.Tracks = tracks
+	listing.TotalTracks = total
+
+	return listing, nil
+}
+
+// Tracks returns every track in dir and its subfolders in path order, for
+// playing or queueing a whole folder
+func (b *FolderBrowser) Tracks(dir string, watchFolders []string) ([]*domain.Track, error) {
+	dir = filepath.Clean(dir)
+	if !isWithinAny(dir, watchFolders) {
+		return nil, fmt.Errorf("%w: %s is not inside a watch folder", domain.ErrInvalidInput, dir)
+	}
+
+	return b.trackRepo.FindUnder(dir)
+}
+
+// subfolders returns the folders directly inside dir that hold tracks, in
+// name order
+func (b *FolderBrowser) subfolders(dir string) ([]FolderNode, error) {
+	folders, err := b.trackRepo.FindSubfolders(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.TrimRight(dir, string(filepath.Separator)) + string(filepath.Separator)
+	result := make([]FolderNode, len(folders))
+	for i, folder := range folders {
+		result[i] = FolderNode{
+			Path:          prefix + folder.Name,
+			Name:          folder.Name,
+			TrackCount:    folder.TrackCount,
+			HasSubfolders: folder.HasSubfolders,
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+
+	return result, nil
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package library
+
+// hasHiddenAttribute always returns false; dot files are handled by isHidden
+func hasHiddenAttribute(path string) bool {
+	return false
+}
+
+// isRemoteDrive always returns false; network mounts are not detected
+func isRemoteDrive(path string) bool {
+	return false
+}
+
+// hideShellFile does nothing; only Explorer reads desktop.ini
+func hideShellFile(path string) error {
+	return nil
+}
+
+// markCustomFolder does nothing; only Explorer reads desktop.ini
+func markCustomFolder(dir string) error {
+	return nil
+}
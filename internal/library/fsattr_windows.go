@@ -0,0 +1,65 @@
+//go:build windows
+
+package library
+
+import (
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const driveRemote = 4 // DRIVE_REMOTE
+
+var procGetDriveType = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDriveTypeW")
+
+// hasHiddenAttribute checks the Windows hidden file attribute
+func hasHiddenAttribute(path string) bool {
+	ptr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return false
+	}
+	attrs, err := syscall.GetFileAttributes(ptr)
+	if err != nil {
+		return false
+	}
+	return attrs&syscall.FILE_ATTRIBUTE_HIDDEN != 0
+}
+
+// isRemoteDrive reports whether a path is on a mapped network drive
+func isRemoteDrive(path string) bool {
+	volume := filepath.VolumeName(path)
+	if volume == "" {
+		return false
+	}
+	ptr, err := syscall.UTF16PtrFromString(volume + `\`)
+	if err != nil {
+		return false
+	}
+	driveType, _, _ := procGetDriveType.Call(uintptr(unsafe.Pointer(ptr)))
+	return driveType == driveRemote
+}
+
+// hideShellFile gives a file Explorer reads, such as desktop.ini, the
+// hidden and system attributes it expects them to have
+func hideShellFile(path string) error {
+	ptr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	return syscall.SetFileAttributes(ptr, syscall.FILE_ATTRIBUTE_HIDDEN|syscall.FILE_ATTRIBUTE_SYSTEM)
+}
+
+// markCustomFolder sets the read-only attribute Explorer checks before
+// reading a folder's desktop.ini. On folders it stops nothing being
+// written to them.
+func markCustomFolder(dir string) error {
+	ptr, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return err
+	}
+	attrs, err := syscall.GetFileAttributes(ptr)
+	if err != nil {
+		return err
+	}
+	return syscall.SetFileAttributes(ptr, attrs|syscall.FILE_ATTRIBUTE_READONLY)
+}
@@ -0,0 +1,215 @@
+package library
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// HealthIssue is a kind of problem the library health report looks for
+type HealthIssue string
+
+const (
+	HealthMissingTags       HealthIssue = "missing_tags"
+	HealthMissingArt        HealthIssue = "missing_art"
+	HealthNoReplayGain      HealthIssue = "no_replay_gain"
+	HealthZeroDuration      HealthIssue = "zero_duration"
+	HealthSuspiciousBitrate HealthIssue = "suspicious_bitrate"
+	HealthUnreadable        HealthIssue = "unreadable"
+)
+
+// HealthIssues lists the issues in the order the report groups them
+var HealthIssues = []HealthIssue{
+	HealthUnreadable,
+	HealthZeroDuration,
+	HealthSuspiciousBitrate,
+	HealthMissingTags,
+	HealthMissingArt,
+	HealthNoReplayGain,
+}
+
+// HealthFix names the action that resolves an issue for the tracks listed
+// under it
+type HealthFix string
+
+const (
+	HealthFixReplayGain HealthFix = "scan_replay_gain" // Measure the tracks' loudness
+	HealthFixRescan     HealthFix = "rescan"           // Re-read size, duration and checksum
+	HealthFixRetry      HealthFix = "retry"            // Verify the files again
+)
+
+// healthFixes maps the issues that can be fixed in one click to their fix.
+// Tags, art and bitrates need the user's judgement.
+var healthFixes = map[HealthIssue]HealthFix{
+	HealthUnreadable:   HealthFixRetry,
+	HealthZeroDuration: HealthFixRescan,
+	HealthNoReplayGain: HealthFixReplayGain,
+}
+
+// FixFor returns the one-click fix for an issue, if it has one
+func FixFor(issue HealthIssue) (HealthFix, bool) {
+	fix, ok := healthFixes[issue]
+	return fix, ok
+}
+
+const (
+	// minLossyBitrate is the bitrate below which lossy audio is audibly
+	// degraded, and minOpusBitrate the same for Opus, which holds up lower
+	minLossyBitrate = 96000
+	minOpusBitrate  = 48000
+
+	// maxMP3Bitrate is the highest bitrate the MP3 standard allows
+	maxMP3Bitrate = 320000
+
+	// truncatedRatio is how much smaller than its bitrate and duration
+	// imply a file may be before it looks cut short
+	truncatedRatio = 0.5
+)
+
+// lossyFormats are the formats whose bitrate says something of their quality
+var lossyFormats = map[domain.AudioFormat]bool{
+	domain.FormatMP3:  true,
+	domain.FormatOGG:  true,
+	domain.FormatAAC:  true,
+	domain.FormatWMA:  true,
+	domain.FormatM4A:  true,
+	domain.FormatOPUS: true,
+}
+
+// HealthEntry is a track with an issue and what exactly is wrong with it
+type HealthEntry struct {
+	Track  *domain.Track
+	Detail string
+}
+
+// HealthGroup lists the tracks with one issue
+type HealthGroup struct {
+	Issue   HealthIssue
+	Fix     HealthFix // Empty if the issue has no one-click fix
+	Entries []HealthEntry
+}
+
+// HealthReport is the outcome of checking the library's tracks
+type HealthReport struct {
+	Checked   int
+	Groups    []*HealthGroup // Only issues some track has, in HealthIssues order
+	Generated time.Time
+}
+
+// Healthy reports whether no track has any issue
+func (r *HealthReport) Healthy() bool {
+	return len(r.Groups) == 0
+}
+
+// CheckHealth checks tracks for missing tags, missing art, missing
+// ReplayGain, zero durations, suspicious bitrates and unreadable files.
+// Only file tracks are checked, and an unreadable track is reported as
+// that alone since the rest of its details can't be trusted.
+func CheckHealth(tracks []*domain.Track) *HealthReport {
+	found := make(map[HealthIssue][]HealthEntry)
+	add := func(issue HealthIssue, track *domain.Track, detail string) {
+		found[issue] = append(found[issue], HealthEntry{Track: track, Detail: detail})
+	}
+
+	report := &HealthReport{Generated: time.Now()}
+	for _, track := range tracks {
+		if track.GetSource().Kind != domain.SourceFile {
+			continue
+		}
+		report.Checked++
+
+		if !track.IsValid {
+			add(HealthUnreadable, track, track.Error)
+			continue
+		}
+		if track.Duration <= 0 {
+			add(HealthZeroDuration, track, "")
+		}
+		if detail := bitrateProblem(track); detail != "" {
+			add(HealthSuspiciousBitrate, track, detail)
+		}
+		if missing := missingTags(track); len(missing) > 0 {
+			add(HealthMissingTags, track, strings.Join(missing, ", "))
+		}
+		if track.AlbumArtPath == "" {
+			add(HealthMissingArt, track, "")
+		}
+		if rg := track.ReplayGain; rg == nil || rg.Estimated {
+			detail := ""
+			if rg != nil {
+				detail = "estimated during playback"
+			}
+			add(HealthNoReplayGain, track, detail)
+		}
+	}
+
+	for _, issue := range HealthIssues {
+		entries := found[issue]
+		if len(entries) == 0 {
+			continue
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Track.FilePath < entries[j].Track.FilePath
+		})
+		report.Groups = append(report.Groups, &HealthGroup{
+			Issue:   issue,
+			Fix:     healthFixes[issue],
+			Entries: entries,
+		})
+	}
+
+	return report
+}
+
+// missingTags names the basic tags a track lacks
+func missingTags(track *domain.Track) []string {
+	var missing []string
+	if strings.TrimSpace(track.Title) == "" {
+		missing = append(missing, "title")
+	}
+	if strings.TrimSpace(track.Artist) == "" {
+		missing = append(missing, "artist")
+	}
+	if strings.TrimSpace(track.Album) == "" {
+		missing = append(missing, "album")
+	}
+	return missing
+}
+
+// bitrateProblem describes what is suspicious about a track's bitrate, or
+// returns "" if nothing is
+func bitrateProblem(track *domain.Track) string {
+	if track.Bitrate <= 0 {
+		return ""
+	}
+	kbps := track.Bitrate / 1000
+
+	format := track.Format
+	if format == domain.FormatMP3 && track.Bitrate > maxMP3Bitrate {
+		return fmt.Sprintf("%d kbps is more than MP3 allows", kbps)
+	}
+	if lossyFormats[format] {
+		minimum := minLossyBitrate
+		if format == domain.FormatOPUS {
+			minimum = minOpusBitrate
+		}
+		if track.Bitrate < minimum {
+			return fmt.Sprintf("%d kbps is low for %s", kbps, strings.ToUpper(string(format)))
+		}
+	}
+
+	// Tags and art only make a file bigger, so a file much smaller than its
+	// bitrate and duration imply has lost audio
+	if track.Duration > 0 && track.FileSize > 0 {
+		expected := float64(track.Bitrate) / 8 * track.Duration.Seconds()
+		if float64(track.FileSize) < expected*truncatedRatio {
+			return fmt.Sprintf("file is %d%% of the size %d kbps implies, it may be truncated",
+				int(100*float64(track.FileSize)/expected), kbps)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,109 @@
+package library
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestCheckHealth(t *testing.T) {
+	gain := &domain.ReplayGain{TrackGain: -6, TrackPeak: 0.9, AlbumGain: -6, AlbumPeak: 0.9}
+	healthy := func(path string) *domain.Track {
+		return &domain.Track{
+			FilePath:     path,
+			Title:        "Title",
+			Artist:       "Artist",
+			Album:        "Album",
+			Format:       domain.FormatMP3,
+			Duration:     4 * time.Minute,
+			Bitrate:      256000,
+			FileSize:     256000 / 8 * 240,
+			AlbumArtPath: "art.jpg",
+			ReplayGain:   gain,
+			IsValid:      true,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(*domain.Track)
+		want   map[HealthIssue]string // Issue to the detail reported
+	}{
+		{"healthy", func(*domain.Track) {}, nil},
+		{"missing tags", func(t *domain.Track) { t.Artist, t.Album = "", " " },
+			map[HealthIssue]string{HealthMissingTags: "artist, album"}},
+		{"missing art", func(t *domain.Track) { t.AlbumArtPath = "" },
+			map[HealthIssue]string{HealthMissingArt: ""}},
+		{"no ReplayGain", func(t *domain.Track) { t.ReplayGain = nil },
+			map[HealthIssue]string{HealthNoReplayGain: ""}},
+		{"estimated ReplayGain", func(t *domain.Track) { t.ReplayGain = &domain.ReplayGain{Estimated: true} },
+			map[HealthIssue]string{HealthNoReplayGain: "estimated during playback"}},
+		{"zero duration", func(t *domain.Track) { t.Duration = 0 },
+			map[HealthIssue]string{HealthZeroDuration: ""}},
+		{"low bitrate", func(t *domain.Track) { t.Bitrate, t.FileSize = 64000, 64000/8*240 },
+			map[HealthIssue]string{HealthSuspiciousBitrate: "64 kbps is low for MP3"}},
+		{"low bitrate for Opus is fine", func(t *domain.Track) {
+			t.Format, t.Bitrate, t.FileSize = domain.FormatOPUS, 64000, 64000/8*240
+		}, nil},
+		{"impossible MP3 bitrate", func(t *domain.Track) { t.Bitrate, t.FileSize = 1411000, 1411000/8*240 },
+			map[HealthIssue]string{HealthSuspiciousBitrate: "1411 kbps is more than MP3 allows"}},
+		{"truncated", func(t *domain.Track) { t.FileSize /= 4 },
+			map[HealthIssue]string{HealthSuspiciousBitrate: "file is 25% of the size 256 kbps implies, it may be truncated"}},
+		{"unreadable hides the rest", func(t *domain.Track) { t.MarkInvalid("bad frame"); t.Title = "" },
+			map[HealthIssue]string{HealthUnreadable: "bad frame"}},
+		{"streams aren't checked", func(t *domain.Track) { t.SetFilePath("https://example.com/live"); t.Title = "" }, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := healthy("song.mp3")
+			tt.change(track)
+			report := CheckHealth([]*domain.Track{track})
+
+			got := make(map[HealthIssue]string)
+			for _, group := range report.Groups {
+				require.Len(t, group.Entries, 1)
+				assert.Same(t, track, group.Entries[0].Track)
+				got[group.Issue] = group.Entries[0].Detail
+			}
+			if tt.want == nil {
+				assert.True(t, report.Healthy())
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckHealthGroups(t *testing.T) {
+	tracks := []*domain.Track{
+		{FilePath: "b.flac", Format: domain.FormatFLAC, Duration: time.Minute, IsValid: true},
+		{FilePath: "a.flac", Format: domain.FormatFLAC, Title: "A", Artist: "A", Album: "A", IsValid: true},
+		{FilePath: "c.flac", Format: domain.FormatFLAC, IsValid: false, Error: "missing"},
+	}
+
+	report := CheckHealth(tracks)
+	assert.Equal(t, 3, report.Checked)
+
+	var issues []HealthIssue
+	for _, group := range report.Groups {
+		issues = append(issues, group.Issue)
+	}
+	assert.Equal(t, []HealthIssue{HealthUnreadable, HealthZeroDuration, HealthMissingTags, HealthMissingArt, HealthNoReplayGain}, issues)
+
+	// Fixes are offered only where one click can fix the issue
+	assert.Equal(t, HealthFixRetry, report.Groups[0].Fix)
+	assert.Equal(t, HealthFixRescan, report.Groups[1].Fix)
+	assert.Empty(t, report.Groups[2].Fix)
+	assert.Equal(t, HealthFixReplayGain, report.Groups[4].Fix)
+
+	// Tracks are listed by path
+	missingArt := report.Groups[3].Entries
+	require.Len(t, missingArt, 2)
+	assert.Equal(t, "a.flac", missingArt[0].Track.FilePath)
+	assert.Equal(t, "b.flac", missingArt[1].Track.FilePath)
+}
@@ -0,0 +1,278 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
+	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/metadata"
+)
+
+// IdentifyResult summarises a batch identification
+type IdentifyResult struct {
+	Identified int                          // Tracks whose missing tags were filled in
+	Unmatched  int                          // Untagged tracks AcoustID didn't know
+	Submitted  int                          // Fingerprints contributed to AcoustID
+	Skipped    int                          // Tracks that aren't local files
+	Changes    map[string]map[string]string // By track ID, the fields filled in
+	Failed     map[string]string            // File path to error
+	Duration   time.Duration
+}
+
+// IdentifyProgress is published as each track of an identification is
+// fingerprinted and looked up
+type IdentifyProgress struct {
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+	Path  string `json:"path"`
+}
+
+// Identifier recognises tracks by their audio as a single background job.
+// Tracks missing their title or artist are looked up with AcoustID and
+// get the missing tags filled in; tagged tracks can have their
+// fingerprints submitted so others' untagged copies can be recognised.
+type Identifier struct {
+	trackRepo     domain.TrackRepository
+	fingerprinter *Fingerprinter
+	client        *metadata.AcoustIDClient
+	normalizer    *Normalizer
+	bus           *events.Bus
+
+	isRunning  bool
+	cancelFunc context.CancelFunc
+	progress   float64
+
+	mu sync.RWMutex
+}
+
+// NewIdentifier creates an identifier fingerprinting with fpcalc and
+// looking tracks up with the AcoustID client
+func NewIdentifier(trackRepo domain.TrackRepository, fingerprinter *Fingerprinter, client *metadata.AcoustIDClient) *Identifier {
+	return &Identifier{
+		trackRepo:     trackRepo,
+		fingerprinter: fingerprinter,
+		client:        client,
+	}
+}
+
+// SetEventBus sets the bus progress is published to
+func (i *Identifier) SetEventBus(bus *events.Bus) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.bus = bus
+}
+
+// SetNormalizer sets the rules identified tags are tidied with, as
+// scanned ones are
+func (i *Identifier) SetNormalizer(normalizer *Normalizer) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.normalizer = normalizer
+}
+
+// NeedsIdentifying reports whether a track lacks the tags that say what
+// it is
+func NeedsIdentifying(track *domain.Track) bool {
+	return track.Title == "" || track.Artist == ""
+}
+
+// Identify fingerprints tracks, storing each fingerprint, and fills in the
+// missing tags of those that need identifying from the best AcoustID
+// match. With submit set, the fingerprints of tracks with a title and
+// artist are contributed to AcoustID afterwards.
+func (i *Identifier) Identify(ctx context.Context, tracks []*domain.Track, submit bool) (*IdentifyResult, error) {
+	if !i.client.Configured() {
+		return nil, metadata.ErrAcoustIDNotConfigured
+	}
+	if submit && !i.client.CanSubmit() {
+		return nil, metadata.ErrAcoustIDNoUserKey
+	}
+	if !i.fingerprinter.Available() {
+		return nil, ErrFingerprinterMissing
+	}
+
+	i.mu.Lock()
+	if i.isRunning {
+		i.mu.Unlock()
+		return nil, fmt.Errorf("identification already in progress")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	i.isRunning = true
+	i.cancelFunc = cancel
+	i.progress = 0
+	bus := i.bus
+	i.mu.Unlock()
+
+	startTime := time.Now()
+	result := &IdentifyResult{
+		Changes: make(map[string]map[string]string),
+		Failed:  make(map[string]string),
+	}
+
+	defer func() {
+		cancel()
+		i.mu.Lock()
+		i.isRunning = false
+		i.cancelFunc = nil
+		i.progress = 100
+		i.mu.Unlock()
+
+		result.Duration = time.Since(startTime)
+		events.Publish(bus, TopicIdentifyFinished, result)
+	}()
+
+	var submissions []metadata.AcoustIDSubmission
+	for done, track := range tracks {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		if !track.GetSource().IsLocal() {
+			result.Skipped++
+		} else if err := i.identify(ctx, track, result); err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			result.Failed[track.FilePath] = err.Error()
+			logger.Warn("Failed to identify track",
+				logger.String("path", track.FilePath),
+				logger.Error(err))
+		} else if submit && !NeedsIdentifying(track) {
+			submissions = append(submissions, submissionFor(track))
+		}
+
+		i.mu.Lock()
+		i.progress = float64(done+1) / float64(len(tracks)) * 100
+		i.mu.Unlock()
+		events.Publish(bus, TopicIdentifyProgress, &IdentifyProgress{
+			Done:  done + 1,
+			Total: len(tracks),
+			Path:  track.FilePath,
+		})
+	}
+
+	if len(submissions) > 0 {
+		if err := i.client.Submit(ctx, submissions); err != nil {
+			logger.Warn("Failed to submit fingerprints to AcoustID", logger.Error(err))
+		} else {
+			result.Submitted = len(submissions)
+		}
+	}
+
+	logger.Info("Identification finished",
+		logger.Int("identified", result.Identified),
+		logger.Int("unmatched", result.Unmatched),
+		logger.Int("submitted", result.Submitted),
+		logger.Int("failed", len(result.Failed)),
+		logger.Duration("duration", time.Since(startTime)),
+	)
+
+	return result, nil
+}
+
+// identify fingerprints one track and, if it needs identifying, fills in
+// its missing tags
+func (i *Identifier) identify(ctx context.Context, track *domain.Track, result *IdentifyResult) error {
+	if track.Fingerprint == "" {
+		fingerprint, duration, err := i.fingerprinter.Fingerprint(ctx, track.FilePath)
+		if err != nil {
+			return err
+		}
+		track.Fingerprint = fingerprint
+		if track.Duration <= 0 {
+			track.Duration = duration
+		}
+		if err := i.trackRepo.Update(track); err != nil {
+			return fmt.Errorf("failed to save fingerprint: %w", err)
+		}
+	}
+	if !NeedsIdentifying(track) {
+		return nil
+	}
+
+	found, err := i.client.Provider(i.fingerprinter.ForTrack).Lookup(ctx, track)
+	if errors.Is(err, metadata.ErrNoMatch) {
+		result.Unmatched++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	changed, err := metadata.NewChain(metadata.MergeFill, identified{found}).Enrich(ctx, track)
+	if err != nil || len(changed) == 0 {
+		return err
+	}
+
+	i.mu.RLock()
+	normalizer := i.normalizer
+	i.mu.RUnlock()
+	if normalizer != nil {
+		normalizer.Apply(track)
+	}
+	track.UpdatedAt = time.Now()
+	if err := i.trackRepo.UpdateTags(track); err != nil {
+		return fmt.Errorf("failed to save identified tags: %w", err)
+	}
+	result.Identified++
+	result.Changes[track.ID] = changed
+	return nil
+}
+
+// submissionFor describes a tagged track's recording for AcoustID
+func submissionFor(track *domain.Track) metadata.AcoustIDSubmission {
+	return metadata.AcoustIDSubmission{
+		Fingerprint: track.Fingerprint,
+		Duration:    track.Duration,
+		Title:       track.Title,
+		Artist:      track.Artist,
+		Album:       track.Album,
+		AlbumArtist: track.AlbumArtist,
+		Year:        track.Year,
+		TrackNumber: track.TrackNumber,
+	}
+}
+
+// identified supplies the fields of an AcoustID match, so they are merged
+// into a track the way enrichment merges them
+type identified struct {
+	fields *metadata.Fields
+}
+
+func (p identified) Name() string {
+	return "acoustid"
+}
+
+func (p identified) Lookup(ctx context.Context, track *domain.Track) (*metadata.Fields, error) {
+	return p.fields, nil
+}
+
+// Cancel cancels a running identification. Tracks already identified keep
+// their tags.
+func (i *Identifier) Cancel() {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if i.cancelFunc != nil {
+		i.cancelFunc()
+	}
+}
+
+// IsRunning returns whether an identification is in progress
+func (i *Identifier) IsRunning() bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.isRunning
+}
+
+// GetProgress returns the identification progress (0-100)
+func (i *Identifier) GetProgress() float64 {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.progress
+}
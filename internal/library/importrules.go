@@ -0,0 +1,73 @@
+package library
+
+import (
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// ImportRuleHandler carries out an import rule the scanner cannot apply to
+// a track by itself, such as adding it to a playlist. It is called after
+// the track has been saved.
+type ImportRuleHandler func(track *domain.Track, rule *domain.ImportRule) error
+
+// SetImportRules sets where watch folder import rules are loaded from and
+// the handler for rules that reach beyond the track. No rules are applied
+// when no repository is set.
+func (s *Scanner) SetImportRules(repo domain.ImportRuleRepository, handler ImportRuleHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ruleRepo = repo
+	s.ruleHandler = handler
+}
+
+// loadImportRules reads the rules for a scan. A failure is logged and the
+// scan goes ahead without rules rather than not importing at all.
+func (s *Scanner) loadImportRules() []*domain.ImportRule {
+	s.mu.RLock()
+	repo := s.ruleRepo
+	s.mu.RUnlock()
+	if repo == nil {
+		return nil
+	}
+
+	rules, err := repo.FindAll()
+	if err != nil {
+		logger.Warn("Failed to load import rules", logger.Error(err))
+		return nil
+	}
+	return rules
+}
+
+// applyImportRules applies the rules for a new track's folder to the track
+// before it is saved, returning the rules left for the handler
+func (s *Scanner) applyImportRules(track *domain.Track) []*domain.ImportRule {
+	var deferred []*domain.ImportRule
+	for _, rule := range s.rules {
+		if !rule.AppliesTo(track.FilePath) {
+			continue
+		}
+		if !rule.ApplyToTrack(track) {
+			deferred = append(deferred, rule)
+		}
+	}
+	return deferred
+}
+
+// runImportRules hands deferred rules for a saved track to the handler
+func (s *Scanner) runImportRules(track *domain.Track, rules []*domain.ImportRule) {
+	s.mu.RLock()
+	handler := s.ruleHandler
+	s.mu.RUnlock()
+	if handler == nil {
+		return
+	}
+
+	for _, rule := range rules {
+		if err := handler(track, rule); err != nil {
+			logger.Warn("Failed to apply import rule",
+				logger.String("path", track.FilePath),
+				logger.String("action", string(rule.Action)),
+				logger.Error(err))
+		}
+	}
+}
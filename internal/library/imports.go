@@ -0,0 +1,351 @@
+package library
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Import source kinds
+const (
+	ImportSourceITunes = "itunes"
+	ImportSourceWMP    = "wmp"
+)
+
+// ErrUnknownImportSource is returned for import sources of an unknown kind
+var ErrUnknownImportSource = errors.New("unknown import source")
+
+// ImportSource is a library from another player that can be imported
+type ImportSource struct {
+	Kind string
+	Name string
+	Path string // Library file for iTunes, playlist folder for WMP
+}
+
+// ExternalTrack is a track entry read from another player's library
+type ExternalTrack struct {
+	Path       string
+	Rating     int // 0-5 stars
+	PlayCount  int
+	LastPlayed *time.Time
+}
+
+// ExternalPlaylist is a playlist read from another player's library
+type ExternalPlaylist struct {
+	Name  string
+	Paths []string
+}
+
+// ExternalLibrary holds everything read from an import source
+type ExternalLibrary struct {
+	Tracks    []ExternalTrack
+	Playlists []ExternalPlaylist
+}
+
+// DetectImportSources looks for iTunes libraries and Windows Media Player
+// playlists inside the given music folders
+func DetectImportSources(musicDirs []string) []ImportSource {
+	sources := make([]ImportSource, 0)
+	for _, dir := range musicDirs {
+		for _, name := range []string{"iTunes Music Library.xml", "iTunes Library.xml"} {
+			path := filepath.Join(dir, "iTunes", name)
+			if _, err := os.Stat(path); err == nil {
+				sources = append(sources, ImportSource{Kind: ImportSourceITunes, Name: "iTunes", Path: path})
+				break
+			}
+		}
+
+		playlists := filepath.Join(dir, "Playlists")
+		if matches, _ := filepath.Glob(filepath.Join(playlists, "*.wpl")); len(matches) > 0 {
+			sources = append(sources, ImportSource{Kind: ImportSourceWMP, Name: "Windows Media Player", Path: playlists})
+		}
+	}
+	return sources
+}
+
+// ReadImportSource reads tracks and playlists from an import source
+func ReadImportSource(source ImportSource) (*ExternalLibrary, error) {
+	switch source.Kind {
+	case ImportSourceITunes:
+		return readITunesLibrary(source.Path)
+	case ImportSourceWMP:
+		return readWMPPlaylists(source.Path)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownImportSource, source.Kind)
+	}
+}
+
+// readITunesLibrary parses an iTunes library XML export
+func readITunesLibrary(path string) (*ExternalLibrary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	root, err := decodePlist(xml.NewDecoder(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse iTunes library: %w", err)
+	}
+	dict, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("failed to parse iTunes library: unexpected root element")
+	}
+
+	lib := &ExternalLibrary{}
+
+	// Track entries are keyed by iTunes track ID, which playlists refer to
+	pathsByID := make(map[int64]string)
+	tracks, _ := dict["Tracks"].(map[string]interface{})
+	for _, entry := range tracks {
+		track, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		location, _ := track["Location"].(string)
+		trackPath, ok := fileURLToPath(location)
+		if !ok {
+			continue
+		}
+
+		ext := ExternalTrack{Path: trackPath}
+		if rating, ok := track["Rating"].(int64); ok {
+			ext.Rating = int(rating / 20) // iTunes stores 0-100
+		}
+		if count, ok := track["Play Count"].(int64); ok {
+			ext.PlayCount = int(count)
+		}
+		if played, ok := track["Play Date UTC"].(time.Time); ok {
+			ext.LastPlayed = &played
+		}
+		lib.Tracks = append(lib.Tracks, ext)
+
+		if id, ok := track["Track ID"].(int64); ok {
+			pathsByID[id] = trackPath
+		}
+	}
+
+	playlists, _ := dict["Playlists"].([]interface{})
+	for _, entry := range playlists {
+		playlist, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// Skip built-in and smart playlists
+		if visible, ok := playlist["Visible"].(bool); ok && !visible {
+			continue
+		}
+		if isTrue(playlist["Master"]) || playlist["Distinguished Kind"] != nil || playlist["Smart Info"] != nil {
+			continue
+		}
+
+		name, _ := playlist["Name"].(string)
+		ext := ExternalPlaylist{Name: name}
+		items, _ := playlist["Playlist Items"].([]interface{})
+		for _, item := range items {
+			fields, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id, _ := fields["Track ID"].(int64)
+			if trackPath, ok := pathsByID[id]; ok {
+				ext.Paths = append(ext.Paths, trackPath)
+			}
+		}
+		if name != "" && len(ext.Paths) > 0 {
+			lib.Playlists = append(lib.Playlists, ext)
+		}
+	}
+
+	return lib, nil
+}
+
+// readWMPPlaylists reads all Windows Media Player playlists in a folder
+func readWMPPlaylists(dir string) (*ExternalLibrary, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.wpl"))
+	if err != nil {
+		return nil, err
+	}
+
+	lib := &ExternalLibrary{}
+	seen := make(map[string]bool)
+	for _, path := range matches {
+		playlist, err := readWPL(path)
+		if err != nil {
+			continue
+		}
+		for _, trackPath := range playlist.Paths {
+			if !seen[trackPath] {
+				seen[trackPath] = true
+				lib.Tracks = append(lib.Tracks, ExternalTrack{Path: trackPath})
+			}
+		}
+		if len(playlist.Paths) > 0 {
+			lib.Playlists = append(lib.Playlists, *playlist)
+		}
+	}
+
+	return lib, nil
+}
+
+// readWPL parses a Windows Media Player playlist
+func readWPL(path string) (*ExternalPlaylist, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		Title string `xml:"head>title"`
+		Media []struct {
+			Src string `xml:"src,attr"`
+		} `xml:"body>seq>media"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse playlist %s: %w", path, err)
+	}
+
+	playlist := &ExternalPlaylist{Name: doc.Title}
+	if playlist.Name == "" {
+		playlist.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	base := filepath.Dir(path)
+	for _, media := range doc.Media {
+		src := filepath.FromSlash(strings.ReplaceAll(media.Src, `\`, "/"))
+		if src == "" {
+			continue
+		}
+		if !filepath.IsAbs(src) && !strings.HasPrefix(media.Src, `\\`) {
+			src = filepath.Join(base, src)
+		}
+		playlist.Paths = append(playlist.Paths, filepath.Clean(src))
+	}
+
+	return playlist, nil
+}
+
+// fileURLToPath converts an iTunes file:// location to a local path
+func fileURLToPath(location string) (string, bool) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+
+	path := u.Path
+	if u.Host != "" && u.Host != "localhost" {
+		// Network share
+		path = "//" + u.Host + path
+	} else if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		// Drive letter, e.g. /C:/Music
+		path = path[1:]
+	}
+
+	return filepath.FromSlash(path), path != ""
+}
+
+func isTrue(v interface{}) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
+
+// decodePlist reads the next property list value from an XML decoder.
+// Dicts become maps, arrays slices, integers int64, reals float64 and
+// dates time.Time.
+func decodePlist(d *xml.Decoder) (interface{}, error) {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local == "plist" {
+			continue
+		}
+		return decodePlistValue(d, start)
+	}
+}
+
+func decodePlistValue(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]interface{})
+		var key string
+		for {
+			token, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if err := d.DecodeElement(&key, &t); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				value, err := decodePlistValue(d, t)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+			case xml.EndElement:
+				return dict, nil
+			}
+		}
+
+	case "array":
+		array := make([]interface{}, 0)
+		for {
+			token, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				value, err := decodePlistValue(d, t)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			case xml.EndElement:
+				return array, nil
+			}
+		}
+
+	case "true", "false":
+		if err := d.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	var text string
+	if err := d.DecodeElement(&text, &start); err != nil {
+		return nil, err
+	}
+
+	switch start.Name.Local {
+	case "integer":
+		var n int64
+		_, err := fmt.Sscan(text, &n)
+		return n, err
+	case "real":
+		var f float64
+		_, err := fmt.Sscan(text, &f)
+		return f, err
+	case "date":
+		return time.Parse(time.RFC3339, text)
+	default:
+		// string, data
+		return text, nil
+	}
+}
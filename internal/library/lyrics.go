@@ -0,0 +1,224 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhowden/tag"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// ErrNoLyrics is returned when no source has lyrics for a track
+var ErrNoLyrics = errors.New("no lyrics found")
+
+// Where lyrics were found
+const (
+	LyricsSidecar  = "sidecar"
+	LyricsEmbedded = "embedded"
+	LyricsOnline   = "online"
+)
+
+// LyricLine is one line of lyrics and, in synced lyrics, when it is sung
+type LyricLine struct {
+	Time time.Duration
+	Text string
+}
+
+// Lyrics are the lines of a track's lyrics. Synced lyrics have a time on
+// every line, in order; plain lyrics have none.
+type Lyrics struct {
+	Lines  []LyricLine
+	Synced bool
+	Source string
+}
+
+var (
+	// lrcTimestamp matches [mm:ss], [mm:ss.xx] and the [mm:ss:xx] some
+	// editors write
+	lrcTimestamp = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+
+	// lrcTag matches ID tags such as [ar:Artist] and [offset:+250]
+	lrcTag = regexp.MustCompile(`^\[([a-zA-Z#]+):(.*)\]$`)
+
+	// lrcWordTime matches the per-word times of enhanced LRC, <mm:ss.xx>
+	lrcWordTime = regexp.MustCompile(`<\d+:\d{1,2}(?:[.:]\d{1,3})?>`)
+)
+
+// ParseLyrics reads lyrics from text, taking them as synced when lines
+// carry LRC timestamps. A line may have several timestamps, for a chorus
+// sung more than once, and the [offset:] tag shifts every line.
+func ParseLyrics(text string) *Lyrics {
+	var synced, plain []LyricLine
+	var offset time.Duration
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+
+		var times []time.Duration
+		for {
+			match := lrcTimestamp.FindStringSubmatch(line)
+			if match == nil {
+				break
+			}
+			times = append(times, lrcTime(match[1], match[2], match[3]))
+			line = line[len(match[0]):]
+		}
+		if len(times) > 0 {
+			text := strings.TrimSpace(lrcWordTime.ReplaceAllString(line, ""))
+			for _, t := range times {
+				synced = append(synced, LyricLine{Time: t, Text: text})
+			}
+			continue
+		}
+
+		if match := lrcTag.FindStringSubmatch(line); match != nil {
+			if strings.EqualFold(match[1], "offset") {
+				// A positive offset shows lines sooner
+				ms, _ := strconv.Atoi(strings.TrimSpace(match[2]))
+				offset = time.Duration(ms) * time.Millisecond
+			}
+			continue
+		}
+		plain = append(plain, LyricLine{Text: line})
+	}
+
+	if len(synced) == 0 {
+		// Blank lines at the ends are left over from the tag or file
+		for len(plain) > 0 && plain[0].Text == "" {
+			plain = plain[1:]
+		}
+		for len(plain) > 0 && plain[len(plain)-1].Text == "" {
+			plain = plain[:len(plain)-1]
+		}
+		return &Lyrics{Lines: plain}
+	}
+
+	for i := range synced {
+		synced[i].Time = max(synced[i].Time-offset, 0)
+	}
+	sort.SliceStable(synced, func(i, j int) bool { return synced[i].Time < synced[j].Time })
+	return &Lyrics{Lines: synced, Synced: true}
+}
+
+// lrcTime converts the parts of an LRC timestamp, whose fraction may be in
+// tenths, hundredths or thousandths
+func lrcTime(minutes, seconds, fraction string) time.Duration {
+	m, _ := strconv.Atoi(minutes)
+	s, _ := strconv.Atoi(seconds)
+	t := time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	if fraction != "" {
+		f, _ := strconv.Atoi(fraction)
+		for i := len(fraction); i < 3; i++ {
+			f *= 10
+		}
+		t += time.Duration(f) * time.Millisecond
+	}
+	return t
+}
+
+// LineAt returns the index of the line being sung at a position, or -1
+// before the first line and for plain lyrics
+func (l *Lyrics) LineAt(position time.Duration) int {
+	if !l.Synced {
+		return -1
+	}
+	return sort.Search(len(l.Lines), func(i int) bool { return l.Lines[i].Time > position }) - 1
+}
+
+// LyricsProvider looks lyrics up online, as plain text or LRC.
+// FetchLyrics returns ErrNoLyrics when it has none for the track.
+type LyricsProvider interface {
+	FetchLyrics(ctx context.Context, track *domain.Track) (string, error)
+}
+
+// LyricsService finds the lyrics of tracks
+type LyricsService struct {
+	mu       sync.RWMutex
+	provider LyricsProvider
+}
+
+// NewLyricsService creates a lyrics service that looks in files only until
+// a provider is set
+func NewLyricsService() *LyricsService {
+	return &LyricsService{}
+}
+
+// SetProvider sets where lyrics missing from a track's files are looked up.
+// nil looks nowhere else.
+func (s *LyricsService) SetProvider(provider LyricsProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.provider = provider
+}
+
+// Load returns the lyrics of a track. It looks in turn at an .lrc file
+// beside it, the lyrics in its tags and, with a provider set, online. It
+// returns ErrNoLyrics when there are none.
+func (s *LyricsService) Load(ctx context.Context, track *domain.Track) (*Lyrics, error) {
+	local := track.GetSource().Kind == domain.SourceFile
+	if local {
+		if data, err := os.ReadFile(SidecarLyricsPath(track.FilePath)); err == nil {
+			if lyrics := ParseLyrics(string(data)); len(lyrics.Lines) > 0 {
+				lyrics.Source = LyricsSidecar
+				return lyrics, nil
+			}
+		}
+	}
+
+	text := track.Lyrics
+	if text == "" && local {
+		// Tracks imported before lyrics were read have them only in the file
+		text = readEmbeddedLyrics(track.FilePath)
+	}
+	if lyrics := ParseLyrics(text); len(lyrics.Lines) > 0 {
+		lyrics.Source = LyricsEmbedded
+		return lyrics, nil
+	}
+
+	s.mu.RLock()
+	provider := s.provider
+	s.mu.RUnlock()
+	if provider == nil || track.Title == "" {
+		return nil, ErrNoLyrics
+	}
+	text, err := provider.FetchLyrics(ctx, track)
+	if err != nil {
+		return nil, err
+	}
+	lyrics := ParseLyrics(text)
+	if len(lyrics.Lines) == 0 {
+		return nil, ErrNoLyrics
+	}
+	lyrics.Source = LyricsOnline
+	return lyrics, nil
+}
+
+// SidecarLyricsPath returns where the .lrc file of an audio file is kept:
+// beside it, under the same name
+func SidecarLyricsPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".lrc"
+}
+
+// readEmbeddedLyrics returns the lyrics in a file's tags, if any
+func readEmbeddedLyrics(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	m, err := tag.ReadFrom(file)
+	if err != nil {
+		return ""
+	}
+	return m.Lyrics()
+}
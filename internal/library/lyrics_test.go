@@ -0,0 +1,95 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// lyricsProvider serves one text, counting lookups
+type lyricsProvider struct {
+	text    string
+	lookups int
+}
+
+func (p *lyricsProvider) FetchLyrics(ctx context.Context, track *domain.Track) (string, error) {
+	p.lookups++
+	if p.text == "" {
+		return "", ErrNoLyrics
+	}
+	return p.text, nil
+}
+
+func TestParseLyrics(t *testing.T) {
+	t.Run("synced", func(t *testing.T) {
+		lyrics := ParseLyrics("[ar:Someone]\r\n[offset:+500]\r\n[00:12.5]First\r\n" +
+			"[00:20.00][01:05.120]<00:20.00>Chorus <00:21.30>line\r\n[00:30:05]Third\r\n")
+
+		require.True(t, lyrics.Synced)
+		assert.Equal(t, []LyricLine{
+			{Time: 12 * time.Second, Text: "First"},
+			{Time: 19500 * time.Millisecond, Text: "Chorus line"},
+			{Time: 29550 * time.Millisecond, Text: "Third"},
+			{Time: 64620 * time.Millisecond, Text: "Chorus line"},
+		}, lyrics.Lines)
+
+		assert.Equal(t, -1, lyrics.LineAt(5*time.Second))
+		assert.Equal(t, 0, lyrics.LineAt(12*time.Second))
+		assert.Equal(t, 1, lyrics.LineAt(25*time.Second))
+		assert.Equal(t, 3, lyrics.LineAt(5*time.Minute))
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		lyrics := ParseLyrics("\nFirst line\n\n[Chorus]\nSecond line\n\n")
+
+		assert.False(t, lyrics.Synced)
+		assert.Equal(t, []LyricLine{{Text: "First line"}, {Text: ""}, {Text: "[Chorus]"}, {Text: "Second line"}}, lyrics.Lines)
+		assert.Equal(t, -1, lyrics.LineAt(time.Minute))
+	})
+
+	assert.Empty(t, ParseLyrics("").Lines)
+}
+
+func TestLyricsServiceLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "song.mp3")
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+	track := &domain.Track{FilePath: path, Title: "Song", Lyrics: "Embedded line"}
+
+	svc := NewLyricsService()
+	provider := &lyricsProvider{text: "[00:01.00]Online line"}
+	svc.SetProvider(provider)
+
+	// Embedded lyrics come before the provider
+	lyrics, err := svc.Load(context.Background(), track)
+	require.NoError(t, err)
+	assert.Equal(t, LyricsEmbedded, lyrics.Source)
+	assert.Equal(t, "Embedded line", lyrics.Lines[0].Text)
+
+	// A sidecar .lrc comes before both
+	require.NoError(t, os.WriteFile(SidecarLyricsPath(path), []byte("[00:02.00]Sidecar line"), 0o644))
+	lyrics, err = svc.Load(context.Background(), track)
+	require.NoError(t, err)
+	assert.Equal(t, LyricsSidecar, lyrics.Source)
+	assert.True(t, lyrics.Synced)
+	assert.Equal(t, 0, provider.lookups)
+
+	// Only tracks with nothing local are looked up
+	other := &domain.Track{FilePath: filepath.Join(dir, "other.mp3"), Title: "Other"}
+	lyrics, err = svc.Load(context.Background(), other)
+	require.NoError(t, err)
+	assert.Equal(t, LyricsOnline, lyrics.Source)
+	assert.Equal(t, time.Second, lyrics.Lines[0].Time)
+	assert.Equal(t, 1, provider.lookups)
+
+	svc.SetProvider(nil)
+	_, err = svc.Load(context.Background(), other)
+	assert.ErrorIs(t, err, ErrNoLyrics)
+}
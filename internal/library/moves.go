@@ -0,0 +1,130 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// SetFingerprinter sets the fingerprinter that recognises files moved
+// after their checksum changed, as retagging changes the checksum of
+// formats whose tags can't be skipped. New files are only fingerprinted
+// while the library has fingerprinted tracks whose files are gone.
+func (s *Scanner) SetFingerprinter(fingerprinter *Fingerprinter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fingerprinter = fingerprinter
+}
+
+// findMoved picks the library track that a new file was moved from out of
+// the candidates found by findCopies: one whose file is gone and whose
+// size, length and audio match the new file
+func findMoved(track *domain.Track, candidates []*domain.Track) *domain.Track {
+	for _, candidate := range candidates {
+		if !track.IsMoveOf(candidate) {
+			continue
+		}
+		if _, err := os.Stat(candidate.FilePath); !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		return candidate
+	}
+	return nil
+}
+
+// lostFingerprints returns the fingerprints of library tracks whose files
+// are gone, flagged by earlier scans or about to be by this one
+func (s *Scanner) lostFingerprints() map[string]bool {
+	s.mu.RLock()
+	fingerprinter := s.fingerprinter
+	s.mu.RUnlock()
+	if fingerprinter == nil || !fingerprinter.Available() {
+		return nil
+	}
+
+	lost := make(map[string]bool)
+	if invalid, err := s.trackRepo.FindInvalid(); err == nil {
+		for _, track := range invalid {
+			if track.Fingerprint != "" && track.Error == domain.ErrFileNotFound.Error() {
+				lost[track.Fingerprint] = true
+			}
+		}
+	} else {
+		logger.Warn("Failed to load missing tracks", logger.Error(err))
+	}
+	for _, track := range s.known {
+		if track.Fingerprint == "" || lost[track.Fingerprint] {
+			continue
+		}
+		if _, err := os.Stat(track.FilePath); errors.Is(err, os.ErrNotExist) {
+			lost[track.Fingerprint] = true
+		}
+	}
+	return lost
+}
+
+// findMovedBySound finds the library track a new file was moved from by
+// its fingerprint, for files whose checksum changed along the way. The
+// file's fingerprint is kept on the track either way.
+func (s *Scanner) findMovedBySound(ctx context.Context, track *domain.Track) *domain.Track {
+	s.mu.RLock()
+	fingerprinter := s.fingerprinter
+	s.mu.RUnlock()
+	if len(s.lost) == 0 || fingerprinter == nil {
+		return nil
+	}
+
+	fingerprint, _, err := fingerprinter.Fingerprint(ctx, track.FilePath)
+	if err != nil {
+		logger.Debug("Failed to fingerprint file",
+			logger.String("path", track.FilePath),
+			logger.Error(err))
+		return nil
+	}
+	track.Fingerprint = fingerprint
+	if !s.lost[fingerprint] {
+		return nil
+	}
+
+	candidates, err := s.trackRepo.FindByFingerprint(fingerprint)
+	if err != nil {
+		logger.Warn("Failed to look up tracks by fingerprint",
+			logger.String("path", track.FilePath),
+			logger.Error(err))
+		return nil
+	}
+	for _, candidate := range candidates {
+		if !track.SoundsLike(candidate) {
+			continue
+		}
+		if _, err := os.Stat(candidate.FilePath); errors.Is(err, os.ErrNotExist) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// recordMove points a library track at the new path of its file, instead
+// of importing the file as a new track
+func (s *Scanner) recordMove(existing, track *domain.Track, result *ScanResult) {
+	previous := existing.FilePath
+	if err := s.moveToCopy(existing, track); err != nil {
+		result.FailedFiles++
+		result.Errors = append(result.Errors, err)
+		logger.Warn("Failed to move track to its new path",
+			logger.String("path", track.FilePath),
+			logger.Error(err))
+		return
+	}
+
+	// The old path may still be in this scan's snapshot, and must not be
+	// flagged as missing once the walk is done
+	s.moved[existing.ID] = true
+	s.report.Record(domain.ScanChangeMoved, existing)
+	logger.Info("Track moved",
+		logger.String("from", previous),
+		logger.String("to", existing.FilePath))
+}
@@ -0,0 +1,157 @@
+package library
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// NormalizeRules controls how tag values are cleaned up on import and when
+// edited. Alias keys are matched case-insensitively.
+type NormalizeRules struct {
+	TrimWhitespace bool
+	FeatFormat     string            // e.g. "feat."; empty leaves featuring credits alone
+	FixTitleCase   bool              // Title-case values that are all upper or all lower case
+	MoveArticles   bool              // "Beatles, The" becomes "The Beatles"
+	GenreAliases   map[string]string // e.g. "hip hop" -> "Hip-Hop"
+	ArtistAliases  map[string]string // e.g. "beatles" -> "The Beatles"
+}
+
+var (
+	featPattern    = regexp.MustCompile(`(?i)\b(featuring|feat\.?|ft\.?)\s+`)
+	spacePattern   = regexp.MustCompile(`\s+`)
+	articleSuffix  = regexp.MustCompile(`(?i)^(.+),\s*(the|a|an)$`)
+	titleCaseSmall = map[string]bool{
+		"a": true, "an": true, "and": true, "as": true, "at": true, "but": true,
+		"by": true, "for": true, "in": true, "of": true, "on": true, "or": true,
+		"the": true, "to": true, "vs": true, "vs.": true, "with": true,
+		"feat.": true, "ft.": true,
+	}
+)
+
+// Normalizer applies normalization rules to track metadata
+type Normalizer struct {
+	rules         NormalizeRules
+	genreAliases  map[string]string
+	artistAliases map[string]string
+}
+
+// NewNormalizer creates a normalizer for the given rules
+func NewNormalizer(rules NormalizeRules) *Normalizer {
+	return &Normalizer{
+		rules:         rules,
+		genreAliases:  lowerKeys(rules.GenreAliases),
+		artistAliases: lowerKeys(rules.ArtistAliases),
+	}
+}
+
+// Apply normalizes the text fields of a track in place
+func (n *Normalizer) Apply(track *domain.Track) {
+	track.Title = n.Title(track.Title)
+	track.Artist = n.Artist(track.Artist)
+	track.AlbumArtist = n.Artist(track.AlbumArtist)
+	track.Album = n.Title(track.Album)
+	track.Genre = n.Genre(track.Genre)
+	track.Composer = n.Artist(track.Composer)
+	track.Publisher = n.text(track.Publisher)
+	track.DiscSubtitle = n.Title(track.DiscSubtitle)
+	track.Comment = n.text(track.Comment)
+}
+
+// Title normalizes a title-like value: a track, album or disc title
+func (n *Normalizer) Title(value string) string {
+	value = n.text(value)
+	if n.rules.FixTitleCase {
+		value = titleCase(value)
+	}
+	return n.feat(value)
+}
+
+// Artist normalizes an artist name and maps it to its canonical spelling
+func (n *Normalizer) Artist(value string) string {
+	value = n.text(value)
+	if value == "" {
+		return value
+	}
+
+	if n.rules.FixTitleCase {
+		value = titleCase(value)
+	}
+	if n.rules.MoveArticles {
+		if m := articleSuffix.FindStringSubmatch(value); m != nil {
+			value = capitalize(m[2]) + " " + strings.TrimSpace(m[1])
+		}
+	}
+	if alias, ok := n.artistAliases[strings.ToLower(value)]; ok {
+		return alias
+	}
+	return n.feat(value)
+}
+
+// Genre normalizes a genre and maps it to its canonical spelling
+func (n *Normalizer) Genre(value string) string {
+	value = n.text(value)
+	if alias, ok := n.genreAliases[strings.ToLower(value)]; ok {
+		return alias
+	}
+	if n.rules.FixTitleCase {
+		value = titleCase(value)
+	}
+	return value
+}
+
+// text trims and collapses whitespace when enabled
+func (n *Normalizer) text(value string) string {
+	if !n.rules.TrimWhitespace {
+		return value
+	}
+	return spacePattern.ReplaceAllString(strings.TrimSpace(value), " ")
+}
+
+// feat rewrites featuring credits ("ft.", "Featuring") to the configured form
+func (n *Normalizer) feat(value string) string {
+	if n.rules.FeatFormat == "" {
+		return value
+	}
+	return featPattern.ReplaceAllString(value, n.rules.FeatFormat+" ")
+}
+
+// titleCase capitalizes the words of a value that is entirely upper or lower
+// case. Mixed-case values are assumed to be deliberate and left alone.
+func titleCase(value string) string {
+	if value == "" || (value != strings.ToLower(value) && value != strings.ToUpper(value)) {
+		return value
+	}
+
+	words := strings.Fields(strings.ToLower(value))
+	for i, word := range words {
+		if i > 0 && i < len(words)-1 && titleCaseSmall[word] {
+			continue
+		}
+		words[i] = capitalize(word)
+	}
+	return strings.Join(words, " ")
+}
+
+// capitalize upper-cases the first letter of a word, after any leading
+// punctuation such as an opening bracket
+func capitalize(word string) string {
+	runes := []rune(word)
+	for i, r := range runes {
+		if unicode.IsLetter(r) {
+			runes[i] = unicode.ToUpper(r)
+			break
+		}
+	}
+	return string(runes)
+}
+
+func lowerKeys(aliases map[string]string) map[string]string {
+	result := make(map[string]string, len(aliases))
+	for from, to := range aliases {
+		result[strings.ToLower(strings.TrimSpace(from))] = to
+	}
+	return result
+}
@@ -0,0 +1,199 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// PreviewAction is what a scan would do with a file
+type PreviewAction string
+
+const (
+	PreviewImport  PreviewAction = "import"
+	PreviewUpdate  PreviewAction = "update"  // Already in the library but changed on disk
+	PreviewSkip    PreviewAction = "skip"    // Considered and turned down
+	PreviewExclude PreviewAction = "exclude" // Never considered because of the folder's rules
+)
+
+// PreviewReason explains why a file would be skipped or excluded
+type PreviewReason string
+
+const (
+	ReasonPatternMismatch PreviewReason = "pattern mismatch"
+	ReasonExcluded        PreviewReason = "excluded by pattern"
+	ReasonHidden          PreviewReason = "hidden"
+	ReasonLink            PreviewReason = "link not followed"
+	ReasonNotRecursive    PreviewReason = "subfolder not scanned"
+	ReasonDuplicate       PreviewReason = "duplicate"
+	ReasonTooShort        PreviewReason = "too short"
+	ReasonTooLong         PreviewReason = "too long"
+	ReasonUnreadable      PreviewReason = "unreadable"
+
+	// ReasonDurationUnknown marks imports whose length can only be learned
+	// by a full decode, which a dry run doesn't do
+	ReasonDurationUnknown PreviewReason = "duration not checked"
+)
+
+// maxPreviewEntries caps the files listed per action so previewing a huge
+// folder stays cheap; counts always cover every file
+const maxPreviewEntries = 1000
+
+// PreviewEntry is one file or folder in a dry run
+type PreviewEntry struct {
+	Path   string
+	Action PreviewAction
+	Reason PreviewReason
+}
+
+// ScanPreview reports what a scan of a folder would do, without reading
+// tags or writing anything to the library
+type ScanPreview struct {
+	Root      string
+	Counts    map[PreviewAction]int
+	Reasons   map[PreviewReason]int
+	Entries   []PreviewEntry // Up to maxPreviewEntries per action
+	Truncated bool           // Some entries were left out of Entries
+	Duration  time.Duration
+
+	mu sync.Mutex
+}
+
+func (p *ScanPreview) add(path string, action PreviewAction, reason PreviewReason) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.Counts[action]++
+	if reason != "" {
+		p.Reasons[reason]++
+	}
+
+	if p.Counts[action] > maxPreviewEntries {
+		p.Truncated = true
+		return
+	}
+	p.Entries = append(p.Entries, PreviewEntry{Path: path, Action: action, Reason: reason})
+}
+
+// PreviewFolder reports what ScanFolder would do with a folder
+func (s *Scanner) PreviewFolder(ctx context.Context, path string) (*ScanPreview, error) {
+	return s.preview(ctx, path, s.defaultOptions())
+}
+
+// PreviewWatchFolder reports what ScanWatchFolder would do with a watch
+// folder, so its patterns can be tuned before a large import. The folder
+// does not need to be enabled.
+func (s *Scanner) PreviewWatchFolder(ctx context.Context, folder *domain.WatchFolder) (*ScanPreview, error) {
+	if folder == nil || folder.Path == "" {
+		return nil, domain.ErrInvalidLibraryPath
+	}
+
+	return s.preview(ctx, folder.Path, s.watchFolderOptions(folder))
+}
+
+// preview walks a folder with the same rules as a scan. Files are judged
+// from the library and their headers only, so a dry run of a large folder
+// takes a fraction of the time of the import it previews.
+func (s *Scanner) preview(ctx context.Context, path string, opts scanOptions) (*ScanPreview, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("%w: %s", domain.ErrInvalidLibraryPath, path)
+	}
+
+	startTime := time.Now()
+	preview := &ScanPreview{
+		Root:    path,
+		Counts:  make(map[PreviewAction]int),
+		Reasons: make(map[PreviewReason]int),
+	}
+
+	known := make(map[string]*domain.Track)
+	existing, err := s.trackRepo.FindUnder(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing tracks: %w", err)
+	}
+	for _, track := range existing {
+		known[pathKey(track.FilePath)] = track
+	}
+
+	s.mu.RLock()
+	workers := s.localIOWorkers
+	if isNetworkStorage(path) {
+		workers = s.networkIOWorkers
+	}
+	s.mu.RUnlock()
+
+	files := make(chan string, 100)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range files {
+				if ctx.Err() != nil {
+					continue
+				}
+				action, reason := s.previewFile(file, known, opts.duplicates)
+				preview.add(file, action, reason)
+			}
+		}()
+	}
+
+	walk := newWalkState(opts, files)
+	walk.preview = preview
+	err = s.walkDir(ctx, path, 0, walk)
+	close(files)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	preview.Duration = time.Since(startTime)
+
+	logger.Info("Scan preview completed",
+		logger.String("path", path),
+		logger.Int("import", preview.Counts[PreviewImport]),
+		logger.Int("update", preview.Counts[PreviewUpdate]),
+		logger.Int("skip", preview.Counts[PreviewSkip]),
+		logger.Int("exclude", preview.Counts[PreviewExclude]),
+		logger.Duration("duration", preview.Duration))
+
+	return preview, nil
+}
+
+// previewFile decides what a scan would do with a matching file, following
+// the same checks as scanFile. Copies of library tracks at other paths are
+// not detected, as that needs each file's tags.
+func (s *Scanner) previewFile(path string, known map[string]*domain.Track, policy domain.DuplicatePolicy) (PreviewAction, PreviewReason) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return PreviewSkip, ReasonUnreadable
+	}
+
+	if track := known[pathKey(path)]; track != nil {
+		if policy != domain.DuplicateUpdateInPlace && !fileChanged(track, info) {
+			return PreviewSkip, ReasonDuplicate
+		}
+		return PreviewUpdate, ""
+	}
+
+	// Like a scan, files whose headers don't give a duration are not
+	// turned down here; the scan would decode them instead
+	streamInfo, err := EstimateStreamInfo(path)
+	if err != nil {
+		return PreviewImport, ReasonDurationUnknown
+	}
+
+	switch {
+	case s.minDuration > 0 && streamInfo.Duration < s.minDuration:
+		return PreviewSkip, ReasonTooShort
+	case s.maxDuration > 0 && streamInfo.Duration > s.maxDuration:
+		return PreviewSkip, ReasonTooLong
+	}
+
+	return PreviewImport, ""
+}
@@ -0,0 +1,167 @@
+package library
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/winramp/winramp/internal/audio/decoder"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// ProblemFiles manages tracks that failed to decode or verify. Problem
+// tracks stay in the library, flagged through Track.IsValid and Track.Error,
+// until they are retried, re-scanned, relocated or removed.
+type ProblemFiles struct {
+	trackRepo domain.TrackRepository
+	verifier  *Verifier
+	artStore  *ArtStore
+}
+
+// NewProblemFiles creates a new problem file manager
+func NewProblemFiles(trackRepo domain.TrackRepository, verifier *Verifier, artStore *ArtStore) *ProblemFiles {
+	return &ProblemFiles{
+		trackRepo: trackRepo,
+		verifier:  verifier,
+		artStore:  artStore,
+	}
+}
+
+// List returns all tracks currently flagged as invalid
+func (p *ProblemFiles) List() ([]*domain.Track, error) {
+	return p.trackRepo.FindInvalid()
+}
+
+// Report records a decode or validation failure on a track
+func (p *ProblemFiles) Report(track *domain.Track, cause error) error {
+	if track == nil || track.ID == "" || cause == nil {
+		return nil
+	}
+
+	track.MarkInvalid(cause.Error())
+	if err := p.trackRepo.UpdateStatus(track); err != nil {
+		return err
+	}
+
+	logger.Warn("Track moved to problem files",
+		logger.String("path", track.FilePath),
+		logger.Error(cause))
+
+	return nil
+}
+
+// Retry verifies a problem track again against its stored checksum
+func (p *ProblemFiles) Retry(id string) (*domain.Track, error) {
+	track, err := p.trackRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return track, p.recheck(track)
+}
+
+// Rescan refreshes the file details and accepts the file's current content
+// as the new reference, for files that were intentionally replaced
+func (p *ProblemFiles) Rescan(id string) (*domain.Track, error) {
+	track, err := p.trackRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.refresh(track); err != nil {
+		track.MarkInvalid(err.Error())
+		return track, p.trackRepo.UpdateStatus(track)
+	}
+
+	if err := p.trackRepo.Update(track); err != nil {
+		return nil, err
+	}
+
+	return track, p.recheck(track)
+}
+
+// Relocate points a problem track at a replacement file, keeping its play
+// history, rating and playlist membership
+func (p *ProblemFiles) Relocate(id string, newPath string) (*domain.Track, error) {
+	track, err := p.trackRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	newPath = filepath.Clean(newPath)
+	if !domain.IsAudioFile(newPath) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrUnsupportedFormat, newPath)
+	}
+	if existing, _ := p.trackRepo.FindByPath(newPath); existing != nil && existing.ID != track.ID {
+		return nil, fmt.Errorf("%w: %s is already in the library", domain.ErrAlreadyExists, newPath)
+	}
+
+	track.SetFilePath(newPath)
+	if err := p.refresh(track); err != nil {
+		return nil, err
+	}
+
+	if err := p.trackRepo.Update(track); err != nil {
+		return nil, err
+	}
+
+	return track, p.recheck(track)
+}
+
+// Remove deletes a problem track from the library. The file itself is left
+// untouched.
+func (p *ProblemFiles) Remove(id string) error {
+	track, err := p.trackRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	if err := p.trackRepo.Delete(id); err != nil {
+		return err
+	}
+
+	if p.artStore != nil {
+		if err := p.artStore.Release(track.AlbumArtPath); err != nil {
+			logger.Warn("Failed to release album art", logger.String("path", track.AlbumArtPath), logger.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// refresh re-reads size, duration and checksum so the file becomes the new
+// reference
+func (p *ProblemFiles) refresh(track *domain.Track) error {
+	info, err := os.Stat(track.FilePath)
+	if err != nil {
+		return err
+	}
+
+	checksum, err := ComputeChecksum(track.FilePath)
+	if err != nil {
+		return err
+	}
+
+	dec, err := decoder.CreateDecoderForFile(track.FilePath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrTrackCorrupted, err)
+	}
+	defer dec.Close()
+
+	track.FileSize = info.Size()
+	track.Checksum = checksum
+	track.Duration = dec.Duration()
+	return nil
+}
+
+// recheck verifies the track and stores the outcome
+func (p *ProblemFiles) recheck(track *domain.Track) error {
+	if err := p.verifier.VerifyTrack(track); err != nil {
+		track.MarkInvalid(err.Error())
+	} else {
+		track.MarkValid()
+	}
+
+	return p.trackRepo.UpdateStatus(track)
+}
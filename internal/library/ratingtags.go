@@ -0,0 +1,105 @@
+package library
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// ratingEmail names the ID3 popularimeter ratings are written to. Windows
+// Media Player's is the one most other players read.
+const ratingEmail = "Windows Media Player 9 Series"
+
+// popmRatings maps star ratings to the popularimeter values Windows Media
+// Player uses for them
+var popmRatings = [6]byte{0, 1, 64, 128, 196, 255}
+
+// ratingWriter writes a track's rating into a file, reading the original
+// from src and writing the complete new file to dst
+type ratingWriter func(src *os.File, size int64, dst io.Writer, rating int) error
+
+var ratingWriters = map[string]ratingWriter{
+	".mp3":  writeID3Rating,
+	".flac": writeFLACRating,
+	".ogg":  writeOggRating,
+	".opus": writeOggRating,
+}
+
+// CanWriteRating reports whether a rating can be written into a track's
+// file. MP4 has no rating item other players agree on.
+func CanWriteRating(track *domain.Track) bool {
+	_, ok := ratingWriters[strings.ToLower(filepath.Ext(track.FilePath))]
+	return ok && track.GetSource().Kind == domain.SourceFile
+}
+
+// WriteRating writes a track's rating into its file for other players to
+// read: as a popularimeter (POPM) frame in ID3 tags and a RATING comment
+// of 0-100 in Vorbis comments. Unrated tracks have the rating removed. The
+// track's file size and checksum are updated to match the new file.
+func WriteRating(track *domain.Track) error {
+	if !CanWriteRating(track) {
+		return ErrTagWriteUnsupported
+	}
+	writer := ratingWriters[strings.ToLower(filepath.Ext(track.FilePath))]
+	rating := min(max(track.Rating, 0), 5)
+	if err := rewriteFile(track.FilePath, func(src *os.File, size int64, dst io.Writer) error {
+		return writer(src, size, dst, rating)
+	}); err != nil {
+		return err
+	}
+	syncFileInfo(track)
+	return nil
+}
+
+// writeID3Rating replaces the Windows Media Player popularimeter, leaving
+// those of other players alone
+func writeID3Rating(src *os.File, size int64, dst io.Writer, rating int) error {
+	keep := func(id string, body []byte) bool {
+		email, _, _ := bytes.Cut(body, []byte{0})
+		return id != "POPM" || string(email) != ratingEmail
+	}
+	return rewriteID3(src, size, dst, keep, func(version byte) []byte {
+		if rating == 0 {
+			return nil
+		}
+		body := append([]byte(ratingEmail), 0, popmRatings[rating])
+		return id3Frame("POPM", body, version)
+	})
+}
+
+// writeFLACRating writes the rating as a Vorbis comment, adding a comment
+// block after STREAMINFO if there is none
+func writeFLACRating(src *os.File, size int64, dst io.Writer, rating int) error {
+	return rewriteFLACComments(src, size, dst, withRatingComment(rating))
+}
+
+// writeOggRating writes the rating as a comment of an Ogg Vorbis or Opus
+// stream
+func writeOggRating(src *os.File, size int64, dst io.Writer, rating int) error {
+	return rewriteOggComments(src, size, dst, func(packet []byte) ([]byte, error) {
+		return editOggComments(packet, withRatingComment(rating))
+	})
+}
+
+// withRatingComment returns an edit replacing the RATING comment of a
+// Vorbis comment block
+func withRatingComment(rating int) func([][]byte) [][]byte {
+	return func(comments [][]byte) [][]byte {
+		kept := comments[:0]
+		for _, comment := range comments {
+			key, _, _ := strings.Cut(string(comment), "=")
+			if !strings.EqualFold(key, "RATING") {
+				kept = append(kept, comment)
+			}
+		}
+		if rating > 0 {
+			kept = append(kept, []byte("RATING="+strconv.Itoa(rating*20)))
+		}
+		return kept
+	}
+}
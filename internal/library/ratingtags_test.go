@@ -0,0 +1,110 @@
+package library
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// vorbisComments builds a Vorbis comment block with no vendor
+func vorbisComments(comments ...string) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, 0)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(comments)))
+	for _, c := range comments {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(c)))
+		b = append(b, c...)
+	}
+	return b
+}
+
+// flacFile builds a FLAC stream with a STREAMINFO block and a last block
+// of Vorbis comments, followed by audio
+func flacFile(audio []byte, comments ...string) []byte {
+	block := vorbisComments(comments...)
+	b := append([]byte("fLaC\x00\x00\x00\x22"), make([]byte, 34)...)
+	b = append(b, 0x84, byte(len(block)>>16), byte(len(block)>>8), byte(len(block)))
+	return append(append(b, block...), audio...)
+}
+
+// oggVorbisFile builds an Ogg Vorbis stream with the given comments,
+// followed by audio
+func oggVorbisFile(audio []byte, comments ...string) []byte {
+	comment := append([]byte("\x03vorbis"), vorbisComments(comments...)...)
+	comment = append(comment, 1) // Framing bit
+
+	var file []byte
+	for _, page := range paginateOgg([][]byte{append([]byte("\x01vorbis"), make([]byte, 23)...)}, 1, 0) {
+		page.headerType = 0x02 // Beginning of stream
+		file = append(file, page.bytes()...)
+	}
+	for _, page := range paginateOgg([][]byte{comment, []byte("\x05vorbis")}, 1, 1) {
+		file = append(file, page.bytes()...)
+	}
+	return append(file, audio...)
+}
+
+func TestWriteRating(t *testing.T) {
+	dir := t.TempDir()
+	audio := []byte{0xFF, 0xFB, 0x90, 0x00, 1, 2, 3, 4}
+	otherPOPM := id3Frame("POPM", []byte("other@example.com\x00\x80\x00\x00\x00\x05"), 3)
+	id3v23 := append([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, byte(len(otherPOPM))}, otherPOPM...)
+	oggAudio := paginateOgg([][]byte{audio}, 1, 2)[0].bytes()
+
+	tests := []struct {
+		name   string
+		ext    string
+		file   []byte
+		rating string // Four stars as the format stores them
+		kept   string // Tag text that must survive
+	}{
+		{"ID3 without tag", ".mp3", audio, ratingEmail + "\x00\xc4", ""},
+		{"ID3 with another player's rating", ".mp3", append(id3v23, audio...), ratingEmail + "\x00\xc4", "other@example.com\x00\x80"},
+		{"FLAC", ".flac", flacFile(audio, "RATING=20", "A=Who"), "RATING=80", "A=Who"},
+		{"Ogg Vorbis", ".ogg", oggVorbisFile(oggAudio, "rating=60", "ARTIST=Who"), "RATING=80", "ARTIST=Who"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "song"+tt.ext)
+			require.NoError(t, os.WriteFile(path, tt.file, 0o644))
+			suffix := audio
+			if tt.ext == ".ogg" {
+				suffix = oggAudio
+			}
+
+			// Writing twice replaces the first rating rather than adding
+			// another
+			track := &domain.Track{FilePath: path, Rating: 2}
+			require.NoError(t, WriteRating(track))
+			track.Rating = 4
+			require.NoError(t, WriteRating(track))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.True(t, bytes.HasSuffix(data, suffix))
+			assert.Equal(t, 1, bytes.Count(bytes.ToUpper(data), bytes.ToUpper([]byte(tt.rating))))
+			assert.Contains(t, string(data), tt.kept)
+			assert.EqualValues(t, len(data), track.FileSize)
+
+			// Unrating removes it
+			track.Rating = 0
+			require.NoError(t, WriteRating(track))
+			data, err = os.ReadFile(path)
+			require.NoError(t, err)
+			assert.NotContains(t, string(data), ratingEmail)
+			assert.NotContains(t, string(bytes.ToUpper(data)), "RATING=")
+			assert.Contains(t, string(data), tt.kept)
+		})
+	}
+
+	track := &domain.Track{FilePath: filepath.Join(dir, "song.m4a"), Rating: 3}
+	assert.False(t, CanWriteRating(track))
+	assert.ErrorIs(t, WriteRating(track), ErrTagWriteUnsupported)
+}
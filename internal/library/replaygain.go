@@ -0,0 +1,418 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/winramp/winramp/internal/audio/analysis"
+	"github.com/winramp/winramp/internal/audio/decoder"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// ErrGainTagsUnsupported is returned for formats ReplayGain tags cannot be
+// written to
+var ErrGainTagsUnsupported = errors.New("writing ReplayGain tags is not supported for this format")
+
+// GainScanResult summarises a ReplayGain scan
+type GainScanResult struct {
+	Analysed    int
+	Skipped     int               // Tracks that already had gain from a full scan, or aren't local files
+	TagsWritten int               // Files the gain was also written into
+	Failed      map[string]string // File path to error
+	Duration    time.Duration
+}
+
+// GainProgress is published as each track of a ReplayGain scan is measured
+type GainProgress struct {
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+	Path  string `json:"path"`
+	Album string `json:"album"`
+}
+
+// gainTagWriter writes ReplayGain into a file, reading the original from
+// src and writing the complete new file to dst
+type gainTagWriter func(src *os.File, size int64, dst io.Writer, rg *domain.ReplayGain) error
+
+var gainTagWriters = map[string]gainTagWriter{
+	".mp3":  writeID3Gain,
+	".flac": writeFLACGain,
+	".ogg":  writeOggGain,
+}
+
+// GainScanner measures the loudness of tracks with EBU R128 and stores
+// ReplayGain 2.0 track and album gain as a single background job
+type GainScanner struct {
+	trackRepo domain.TrackRepository
+	writeTags bool
+	bus       *events.Bus
+
+	isRunning  bool
+	cancelFunc context.CancelFunc
+	progress   float64
+
+	mu sync.RWMutex
+}
+
+// NewGainScanner creates a new ReplayGain scanner
+func NewGainScanner(trackRepo domain.TrackRepository) *GainScanner {
+	return &GainScanner{trackRepo: trackRepo}
+}
+
+// SetWriteTags sets whether measured gain is also written into the files'
+// tags, for other players to use
+func (g *GainScanner) SetWriteTags(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.writeTags = enabled
+}
+
+// SetEventBus sets the bus scan progress is published to
+func (g *GainScanner) SetEventBus(bus *events.Bus) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.bus = bus
+}
+
+// Scan measures tracks and stores their gain. Tracks of the same album are
+// measured together so they share album gain; tracks without an album get
+// their track gain as album gain. Albums whose tracks all have gain from a
+// full scan are skipped unless force is set.
+func (g *GainScanner) Scan(ctx context.Context, tracks []*domain.Track, force bool) (*GainScanResult, error) {
+	g.mu.Lock()
+	if g.isRunning {
+		g.mu.Unlock()
+		return nil, fmt.Errorf("ReplayGain scan already in progress")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	g.isRunning = true
+	g.cancelFunc = cancel
+	g.progress = 0
+	writeTags, bus := g.writeTags, g.bus
+	g.mu.Unlock()
+
+	startTime := time.Now()
+	result := &GainScanResult{Failed: make(map[string]string)}
+
+	defer func() {
+		cancel()
+		g.mu.Lock()
+		g.isRunning = false
+		g.cancelFunc = nil
+		g.progress = 100
+		g.mu.Unlock()
+
+		result.Duration = time.Since(startTime)
+		events.Publish(bus, TopicGainFinished, result)
+	}()
+
+	var groups [][]*domain.Track
+	for _, group := range groupByAlbum(tracks) {
+		if force || needsGain(group) {
+			groups = append(groups, group)
+		} else {
+			result.Skipped += len(group)
+		}
+	}
+
+	total := 0
+	for _, group := range groups {
+		total += len(group)
+	}
+
+	done := 0
+	for _, group := range groups {
+		var measured []*domain.Track
+		var loudness []*analysis.Loudness
+		for _, track := range group {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+
+			if track.GetSource().IsLocal() {
+				l, err := measureFile(track.FilePath)
+				if err != nil {
+					result.Failed[track.FilePath] = err.Error()
+					logger.Warn("Failed to measure track loudness",
+						logger.String("path", track.FilePath),
+						logger.Error(err))
+				} else {
+					measured = append(measured, track)
+					loudness = append(loudness, l)
+				}
+			} else {
+				result.Skipped++
+			}
+
+			done++
+			g.mu.Lock()
+			g.progress = float64(done) / float64(total) * 100
+			g.mu.Unlock()
+			events.Publish(bus, TopicGainProgress, &GainProgress{
+				Done:  done,
+				Total: total,
+				Path:  track.FilePath,
+				Album: track.Album,
+			})
+		}
+		if len(measured) == 0 {
+			continue
+		}
+
+		album := analysis.Combine(loudness)
+		now := time.Now()
+		for i, track := range measured {
+			track.ReplayGain = &domain.ReplayGain{
+				TrackGain: loudness[i].Gain(),
+				TrackPeak: loudness[i].Peak(),
+				AlbumGain: album.Gain(),
+				AlbumPeak: album.Peak(),
+			}
+			track.UpdatedAt = now
+
+			written, err := g.storeGain(track, writeTags)
+			if err != nil {
+				result.Failed[track.FilePath] = err.Error()
+				logger.Warn("Failed to store track gain",
+					logger.String("path", track.FilePath),
+					logger.Error(err))
+				continue
+			}
+			result.Analysed++
+			if written {
+				result.TagsWritten++
+			}
+		}
+	}
+
+	logger.Info("ReplayGain scan finished",
+		logger.Int("analysed", result.Analysed),
+		logger.Int("skipped", result.Skipped),
+		logger.Int("tags_written", result.TagsWritten),
+		logger.Int("failed", len(result.Failed)),
+		logger.Duration("duration", time.Since(startTime)),
+	)
+
+	return result, nil
+}
+
+// storeGain saves a track's gain and, when asked, writes it into the file.
+// It reports whether the file was written.
+func (g *GainScanner) storeGain(track *domain.Track, writeTags bool) (bool, error) {
+	if !writeTags {
+		return false, g.trackRepo.UpdateReplayGain(track)
+	}
+
+	err := WriteGainTags(track.FilePath, track.ReplayGain)
+	if errors.Is(err, ErrGainTagsUnsupported) {
+		return false, g.trackRepo.UpdateReplayGain(track)
+	}
+	if err != nil {
+		// The measurement is still good even if the file can't be written
+		if updateErr := g.trackRepo.UpdateReplayGain(track); updateErr != nil {
+			return false, updateErr
+		}
+		return false, err
+	}
+
+	if info, err := os.Stat(track.FilePath); err == nil {
+		track.FileSize = info.Size()
+	}
+	// Formats without a separable tag area checksum the whole file
+	if track.Checksum != "" {
+		if checksum, err := ComputeChecksum(track.FilePath); err == nil {
+			track.Checksum = checksum
+		}
+	}
+	return true, g.trackRepo.Update(track)
+}
+
+// Cancel cancels a running scan. Tracks already measured keep their gain.
+func (g *GainScanner) Cancel() {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.cancelFunc != nil {
+		g.cancelFunc()
+	}
+}
+
+// IsRunning returns whether a scan is in progress
+func (g *GainScanner) IsRunning() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.isRunning
+}
+
+// GetProgress returns the scan progress (0-100)
+func (g *GainScanner) GetProgress() float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.progress
+}
+
+// groupByAlbum splits tracks into albums, in the order they first appear.
+// Each track without an album is a group of its own.
+func groupByAlbum(tracks []*domain.Track) [][]*domain.Track {
+	var groups [][]*domain.Track
+	index := make(map[string]int)
+	for _, track := range tracks {
+		key := track.AlbumKey()
+		if key == "" {
+			groups = append(groups, []*domain.Track{track})
+			continue
+		}
+		if i, ok := index[key]; ok {
+			groups[i] = append(groups[i], track)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, []*domain.Track{track})
+	}
+	return groups
+}
+
+// needsGain reports whether any track of a group lacks gain from a full
+// scan, so the group must be measured as a whole
+func needsGain(group []*domain.Track) bool {
+	for _, track := range group {
+		if rg := track.ReplayGain; rg == nil || rg.Estimated || rg.AlbumPeak == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func measureFile(path string) (*analysis.Loudness, error) {
+	dec, err := decoder.CreateDecoderForFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+
+	return analysis.Measure(dec)
+}
+
+// WriteGainTags writes ReplayGain into a file's tags in the
+// REPLAYGAIN_TRACK_GAIN form other players read, replacing any already
+// there
+func WriteGainTags(path string, rg *domain.ReplayGain) error {
+	writer, ok := gainTagWriters[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return ErrGainTagsUnsupported
+	}
+	return rewriteFile(path, func(src *os.File, size int64, dst io.Writer) error {
+		return writer(src, size, dst, rg)
+	})
+}
+
+// gainTags returns the ReplayGain tags for a gain, in a fixed order
+func gainTags(rg *domain.ReplayGain) [][2]string {
+	return [][2]string{
+		{"REPLAYGAIN_TRACK_GAIN", fmt.Sprintf("%.2f dB", rg.TrackGain)},
+		{"REPLAYGAIN_TRACK_PEAK", fmt.Sprintf("%.6f", rg.TrackPeak)},
+		{"REPLAYGAIN_ALBUM_GAIN", fmt.Sprintf("%.2f dB", rg.AlbumGain)},
+		{"REPLAYGAIN_ALBUM_PEAK", fmt.Sprintf("%.6f", rg.AlbumPeak)},
+	}
+}
+
+func isGainTag(key string) bool {
+	return strings.HasPrefix(strings.ToUpper(key), "REPLAYGAIN_")
+}
+
+// writeID3Gain writes the gain as TXXX frames in the file's ID3v2 tag
+func writeID3Gain(src *os.File, size int64, dst io.Writer, rg *domain.ReplayGain) error {
+	keep := func(id string, body []byte) bool {
+		return id != "TXXX" || !isGainTag(txxxDescription(body))
+	}
+	return rewriteID3(src, size, dst, keep, func(version byte) []byte {
+		var frames []byte
+		for _, tag := range gainTags(rg) {
+			body := append([]byte{0}, tag[0]...) // ISO-8859-1 text
+			body = append(body, 0)
+			body = append(body, tag[1]...)
+			frames = append(frames, id3Frame("TXXX", body, version)...)
+		}
+		return frames
+	})
+}
+
+// txxxDescription reads the description of a TXXX frame
+func txxxDescription(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	text := body[1:]
+	switch body[0] {
+	case 1, 2: // UTF-16, with or without a byte order mark
+		bigEndian := body[0] == 2
+		if len(text) >= 2 && text[0] == 0xFE && text[1] == 0xFF {
+			bigEndian, text = true, text[2:]
+		} else if len(text) >= 2 && text[0] == 0xFF && text[1] == 0xFE {
+			bigEndian, text = false, text[2:]
+		}
+		var units []uint16
+		for i := 0; i+1 < len(text); i += 2 {
+			unit := uint16(text[i]) | uint16(text[i+1])<<8
+			if bigEndian {
+				unit = uint16(text[i])<<8 | uint16(text[i+1])
+			}
+			if unit == 0 {
+				break
+			}
+			units = append(units, unit)
+		}
+		return string(utf16.Decode(units))
+	default:
+		if end := bytes.IndexByte(text, 0); end >= 0 {
+			text = text[:end]
+		}
+		return string(text)
+	}
+}
+
+// writeFLACGain writes the gain as Vorbis comments, adding a comment block
+// after STREAMINFO if there is none
+func writeFLACGain(src *os.File, size int64, dst io.Writer, rg *domain.ReplayGain) error {
+	return rewriteFLACComments(src, size, dst, withGainComments(rg))
+}
+
+// writeOggGain writes the gain as Vorbis comments. Opus carries gain in
+// its own R128 form, so Opus streams aren't written.
+func writeOggGain(src *os.File, size int64, dst io.Writer, rg *domain.ReplayGain) error {
+	return rewriteOggComments(src, size, dst, func(packet []byte) ([]byte, error) {
+		if bytes.HasPrefix(packet, []byte("OpusTags")) {
+			return nil, ErrGainTagsUnsupported
+		}
+		return editOggComments(packet, withGainComments(rg))
+	})
+}
+
+// withGainComments returns an edit replacing the ReplayGain comments of a
+// Vorbis comment block
+func withGainComments(rg *domain.ReplayGain) func([][]byte) [][]byte {
+	return func(comments [][]byte) [][]byte {
+		kept := comments[:0]
+		for _, comment := range comments {
+			key, _, _ := strings.Cut(string(comment), "=")
+			if !isGainTag(key) {
+				kept = append(kept, comment)
+			}
+		}
+		for _, tag := range gainTags(rg) {
+			kept = append(kept, []byte(tag[0]+"="+tag[1]))
+		}
+		return kept
+	}
+}
@@ -0,0 +1,139 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// gainRepo records the tracks whose gain is saved
+type gainRepo struct {
+	domain.TrackRepository
+	saved map[string]domain.ReplayGain
+}
+
+func (r *gainRepo) UpdateReplayGain(track *domain.Track) error {
+	r.saved[track.FilePath] = *track.ReplayGain
+	return nil
+}
+
+func TestGainScannerScan(t *testing.T) {
+	dir := t.TempDir()
+	loud := filepath.Join(dir, "loud.wav")
+	quiet := filepath.Join(dir, "quiet.wav")
+	single := filepath.Join(dir, "single.wav")
+	writeToneWAV(t, loud, 0.5)
+	writeToneWAV(t, quiet, 0.05)
+	writeToneWAV(t, single, 0.05)
+
+	measured := &domain.ReplayGain{TrackGain: -3, TrackPeak: 0.9, AlbumGain: -3, AlbumPeak: 0.9}
+	tracks := []*domain.Track{
+		{FilePath: loud, Album: "Album", AlbumArtist: "Artist"},
+		{FilePath: quiet, Album: "Album", AlbumArtist: "Artist"},
+		{FilePath: single},
+		{FilePath: filepath.Join(dir, "done.wav"), Album: "Other", AlbumArtist: "Artist", ReplayGain: measured},
+	}
+
+	repo := &gainRepo{saved: make(map[string]domain.ReplayGain)}
+	result, err := NewGainScanner(repo).Scan(context.Background(), tracks, false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Analysed)
+	assert.Equal(t, 1, result.Skipped)
+	assert.Empty(t, result.Failed)
+
+	// A 1 kHz sine at half scale is -6 LUFS, and one at a twentieth 20 LU
+	// quieter
+	loudGain, quietGain := repo.saved[loud], repo.saved[quiet]
+	assert.InDelta(t, -12, loudGain.TrackGain, 0.1)
+	assert.InDelta(t, 8, quietGain.TrackGain, 0.1)
+	assert.InDelta(t, 0.5, loudGain.TrackPeak, 0.01)
+
+	// The quiet track falls below the album's relative gate, so the album
+	// is as loud as the loud track
+	assert.Equal(t, loudGain.AlbumGain, quietGain.AlbumGain)
+	assert.InDelta(t, -12, loudGain.AlbumGain, 0.1)
+	assert.Equal(t, loudGain.TrackPeak, quietGain.AlbumPeak)
+
+	// A track without an album is its own album
+	singleGain := repo.saved[single]
+	assert.Equal(t, singleGain.TrackGain, singleGain.AlbumGain)
+	assert.NotContains(t, repo.saved, tracks[3].FilePath)
+}
+
+func TestWriteGainTags(t *testing.T) {
+	dir := t.TempDir()
+	audio := []byte{0xFF, 0xFB, 0x90, 0x00, 1, 2, 3, 4}
+	streamInfo := append([]byte{0, 0, 0, 34}, make([]byte, 34)...)
+
+	tests := []struct {
+		name string
+		ext  string
+		file []byte
+		kept string // Tag text that must survive
+	}{
+		{"ID3 without tag", ".mp3", nil, ""},
+		{"ID3 with tag", ".mp3", append([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 16},
+			id3Frame("TIT2", []byte("\x00Title"), 3)...), "Title"},
+		{"FLAC without comments", ".flac", append(append([]byte("fLaC"), 0x80|streamInfo[0]), streamInfo[1:]...), ""},
+		{"FLAC with comments", ".flac", append(append(append([]byte("fLaC"), streamInfo...), 0x84, 0, 0, 37),
+			[]byte("\x00\x00\x00\x00\x02\x00\x00\x00\x0b\x00\x00\x00TITLE=Title\x0a\x00\x00\x00ARTIST=Who")...), "ARTIST=Who"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "song"+tt.ext)
+			require.NoError(t, os.WriteFile(path, append(tt.file, audio...), 0o644))
+
+			// Writing twice replaces the first gain rather than adding to it
+			require.NoError(t, WriteGainTags(path, &domain.ReplayGain{TrackGain: 1}))
+			require.NoError(t, WriteGainTags(path, &domain.ReplayGain{TrackGain: -7.5, TrackPeak: 0.98, AlbumGain: -6.25, AlbumPeak: 1}))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.True(t, bytes.HasSuffix(data, audio))
+			assert.Equal(t, 1, bytes.Count(data, []byte("REPLAYGAIN_TRACK_GAIN")))
+			assert.Contains(t, string(data), "-7.50 dB")
+			assert.Contains(t, string(data), "0.980000")
+			assert.Contains(t, string(data), "-6.25 dB")
+			assert.NotContains(t, string(data), "1.00 dB")
+			assert.Contains(t, string(data), tt.kept)
+		})
+	}
+
+	assert.ErrorIs(t, WriteGainTags(filepath.Join(dir, "song.wav"), &domain.ReplayGain{}), ErrGainTagsUnsupported)
+}
+
+// writeToneWAV writes two seconds of a 1 kHz sine of an amplitude as 16-bit
+// stereo 48 kHz WAV
+func writeToneWAV(t *testing.T, path string, amplitude float64) {
+	const rate, frames = 48000, 2 * 48000
+	var data []byte
+	for i := 0; i < frames; i++ {
+		v := int16(amplitude * 32767 * math.Sin(2*math.Pi*1000*float64(i)/rate))
+		data = binary.LittleEndian.AppendUint16(data, uint16(v))
+		data = binary.LittleEndian.AppendUint16(data, uint16(v))
+	}
+
+	b := []byte("RIFF")
+	b = binary.LittleEndian.AppendUint32(b, uint32(36+len(data)))
+	b = append(b, "WAVEfmt "...)
+	b = binary.LittleEndian.AppendUint32(b, 16)
+	b = binary.LittleEndian.AppendUint16(b, 1) // PCM
+	b = binary.LittleEndian.AppendUint16(b, 2)
+	b = binary.LittleEndian.AppendUint32(b, rate)
+	b = binary.LittleEndian.AppendUint32(b, rate*4)
+	b = binary.LittleEndian.AppendUint16(b, 4)
+	b = binary.LittleEndian.AppendUint16(b, 16)
+	b = append(b, "data"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	require.NoError(t, os.WriteFile(path, append(b, data...), 0o644))
+}
@@ -2,10 +2,14 @@ package library
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -13,9 +17,21 @@ import (
 	"github.com/dhowden/tag"
 	"github.com/winramp/winramp/internal/audio/decoder"
 	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
 	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/metadata"
 )
 
+// scanProgressInterval is how often a scan publishes its progress
+const scanProgressInterval = 250 * time.Millisecond
+
+// ScanProgress is published as a scan works through the files it found
+type ScanProgress struct {
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+	Path  string `json:"path"`
+}
+
 // ScanResult represents the result of a scan operation
 type ScanResult struct {
 	TotalFiles      int
@@ -25,38 +41,66 @@ type ScanResult struct {
 	SkippedFiles    int
 	Duration        time.Duration
 	Errors          []error
+	Report          *domain.ScanReport // What the scan changed in the library
+	Albums          []*domain.AlbumGroup // Albums that gained or changed tracks, with all their tracks
 }
 
+// maxScanReports is how many scan reports are kept
+const maxScanReports = 20
+
 // Scanner scans directories for audio files
 type Scanner struct {
 	trackRepo     domain.TrackRepository
 	libraryRepo   domain.LibraryRepository
 	library       *domain.Library
+	artStore      *ArtStore
+	normalizer    *Normalizer
+	enricher      *metadata.Chain // Fills in what tags leave out; nil leaves them as read
+	reportRepo    domain.ScanReportRepository
+	ruleRepo      domain.ImportRuleRepository
+	ruleHandler   ImportRuleHandler
+	bus           *events.Bus
 	
 	// Scan state
 	isScanning    bool
 	cancelFunc    context.CancelFunc
 	progress      float64
 	currentFile   string
+	published     time.Time // When progress was last published
+	known         map[string]*domain.Track // Tracks under the scan root before it started, by pathKey
+	report        *domain.ScanReport
+	rules         []*domain.ImportRule // Watch folder rules for new files
+	duplicates    domain.DuplicatePolicy
+	moved         map[string]bool // Tracks that followed their file to a new path, by ID
+	lost          map[string]bool // Fingerprints of tracks whose files are gone
 	
 	// Configuration
 	recursive     bool
 	followSymlinks bool
-	skipDuplicates bool
+	duplicatePolicy domain.DuplicatePolicy
+	removeMissing bool // Delete tracks whose files are gone instead of flagging them
+	fingerprinter *Fingerprinter // Recognises moved files by their audio; nil for checksums only
 	extractMetadata bool
 	minDuration   time.Duration
 	maxDuration   time.Duration
 	filePatterns  []string
 	excludePatterns []string
 	
-	// Concurrency
-	workerCount   int
+	// Concurrency. IO workers read headers and tags and are limited per
+	// storage type so network shares aren't saturated; CPU workers handle
+	// files that must be fully decoded to learn their duration.
+	localIOWorkers   int
+	networkIOWorkers int
+	cpuWorkers       int
+	backgroundCPU    int // Percentage of processors background scans decode on
 	fileChan      chan string
+	decodeChan    chan *domain.Track
 	resultChan    chan *domain.Track
 	errorChan     chan error
 	
 	mu            sync.RWMutex
-	wg            sync.WaitGroup
+	wg            sync.WaitGroup // IO workers
+	cpuWg         sync.WaitGroup // CPU workers
 }
 
 // NewScanner creates a new library scanner
@@ -66,18 +110,146 @@ func NewScanner(trackRepo domain.TrackRepository, libraryRepo domain.LibraryRepo
 		libraryRepo:     libraryRepo,
 		recursive:       true,
 		followSymlinks:  false,
-		skipDuplicates:  true,
+		duplicatePolicy: domain.DuplicateSkip,
 		extractMetadata: true,
 		minDuration:     10 * time.Second,
 		maxDuration:     10 * time.Hour,
-		workerCount:     4,
-		filePatterns:    []string{"*.mp3", "*.flac", "*.ogg", "*.wav", "*.aac", "*.wma", "*.m4a"},
+		localIOWorkers:   4,
+		networkIOWorkers: 1,
+		cpuWorkers:       runtime.NumCPU(),
+		filePatterns:    []string{"*.mp3", "*.flac", "*.ogg", "*.wav", "*.aiff", "*.aif", "*.aac", "*.wma", "*.m4a"},
 		excludePatterns: []string{"*.tmp", "*.temp", "*.partial"},
 	}
 }
 
-// ScanFolder scans a folder for audio files
+// scanOptions holds the rules applied while walking a single folder
+type scanOptions struct {
+	recursive       bool
+	includeHidden   bool
+	filePatterns    []string
+	excludePatterns []string
+	duplicates      domain.DuplicatePolicy
+	background      bool // Hold decode workers to the background CPU limit
+}
+
+// SetArtStore sets where extracted album art is stored. Art is not
+// extracted when no store is set.
+func (s *Scanner) SetArtStore(store *ArtStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.artStore = store
+}
+
+// SetNormalizer sets the rules applied to tags as they are read. Tags are
+// stored as found when no normalizer is set.
+func (s *Scanner) SetNormalizer(normalizer *Normalizer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.normalizer = normalizer
+}
+
+// SetEnricher sets the metadata providers asked to fill in what a file's
+// tags leave out as it is scanned. Online providers slow scans down to
+// their rate limits.
+func (s *Scanner) SetEnricher(chain *metadata.Chain) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enricher = chain
+}
+
+// SetReportRepository sets where a report of each scan's changes is saved.
+// Reports are still returned in ScanResult when no repository is set.
+func (s *Scanner) SetReportRepository(repo domain.ScanReportRepository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reportRepo = repo
+}
+
+// SetConcurrency sets the number of IO workers used for local and network
+// storage and the number of CPU workers used for full decodes
+func (s *Scanner) SetConcurrency(localIO, networkIO, cpu int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if localIO > 0 {
+		s.localIOWorkers = localIO
+	}
+	if networkIO > 0 {
+		s.networkIOWorkers = networkIO
+	}
+	if cpu > 0 {
+		s.cpuWorkers = cpu
+	}
+}
+
+// ScanFolder scans a folder for audio files using the scanner's global
+// patterns
 func (s *Scanner) ScanFolder(ctx context.Context, path string) (*ScanResult, error) {
+	return s.scan(ctx, path, s.defaultOptions())
+}
+
+// ScanWatchFolder scans a watch folder honoring its own patterns, recursion
+// and hidden-file settings
+func (s *Scanner) ScanWatchFolder(ctx context.Context, folder *domain.WatchFolder) (*ScanResult, error) {
+	return s.scanWatchFolder(ctx, folder, false)
+}
+
+func (s *Scanner) scanWatchFolder(ctx context.Context, folder *domain.WatchFolder, background bool) (*ScanResult, error) {
+	if folder == nil || folder.Path == "" {
+		return nil, domain.ErrInvalidLibraryPath
+	}
+	if !folder.IsEnabled {
+		return nil, fmt.Errorf("watch folder is disabled: %s", folder.Path)
+	}
+	
+	opts := s.watchFolderOptions(folder)
+	opts.background = background
+	result, err := s.scan(ctx, folder.Path, opts)
+	if err != nil {
+		return nil, err
+	}
+	
+	now := time.Now()
+	folder.LastScanned = &now
+	
+	return result, nil
+}
+
+func (s *Scanner) defaultOptions() scanOptions {
+	return scanOptions{
+		recursive:       s.recursive,
+		includeHidden:   false,
+		filePatterns:    s.filePatterns,
+		excludePatterns: s.excludePatterns,
+		duplicates:      s.duplicatePolicy,
+	}
+}
+
+// watchFolderOptions builds scan rules for a watch folder. Folder patterns
+// replace the global ones when set; exclusions are combined so global
+// exclusions like partial downloads always apply.
+func (s *Scanner) watchFolderOptions(folder *domain.WatchFolder) scanOptions {
+	opts := s.defaultOptions()
+	opts.recursive = folder.IsRecursive
+	opts.includeHidden = folder.IncludeHidden
+	
+	if len(folder.FilePatterns) > 0 {
+		opts.filePatterns = folder.FilePatterns
+	}
+	if len(folder.ExcludePatterns) > 0 {
+		excludes := make([]string, 0, len(s.excludePatterns)+len(folder.ExcludePatterns))
+		excludes = append(excludes, s.excludePatterns...)
+		excludes = append(excludes, folder.ExcludePatterns...)
+		opts.excludePatterns = excludes
+	}
+	if folder.DuplicatePolicy != "" {
+		opts.duplicates = folder.DuplicatePolicy
+	}
+	
+	return opts
+}
+
+func (s *Scanner) scan(ctx context.Context, path string, opts scanOptions) (*ScanResult, error) {
 	s.mu.Lock()
 	if s.isScanning {
 		s.mu.Unlock()
@@ -111,6 +283,22 @@ func (s *Scanner) ScanFolder(ctx context.Context, path string) (*ScanResult, err
 	}
 	s.library = library
 	
+	// Snapshot the tracks already under the root so the scan can tell new
+	// files from changed and vanished ones
+	s.report = domain.NewScanReport(path)
+	s.known = make(map[string]*domain.Track)
+	if existing, err := s.trackRepo.FindUnder(path); err == nil {
+		for _, track := range existing {
+			s.known[pathKey(track.FilePath)] = track
+		}
+	} else {
+		logger.Warn("Failed to load existing tracks", logger.String("path", path), logger.Error(err))
+	}
+	s.rules = s.loadImportRules()
+	s.duplicates = opts.duplicates
+	s.moved = make(map[string]bool)
+	s.lost = s.lostFingerprints()
+	
 	// Mark scan start
 	s.library.StartScan()
 	if s.libraryRepo != nil {
@@ -119,34 +307,68 @@ func (s *Scanner) ScanFolder(ctx context.Context, path string) (*ScanResult, err
 	
 	// Initialize channels
 	s.fileChan = make(chan string, 100)
+	s.decodeChan = make(chan *domain.Track, 100)
 	s.resultChan = make(chan *domain.Track, 100)
 	s.errorChan = make(chan error, 100)
 	
+	// Limit IO concurrency on network shares
+	s.mu.RLock()
+	ioWorkers := s.localIOWorkers
+	if isNetworkStorage(path) {
+		ioWorkers = s.networkIOWorkers
+	}
+	cpuWorkers := s.cpuWorkers
+	if opts.background {
+		cpuWorkers = backgroundWorkers(cpuWorkers, s.backgroundCPU)
+	}
+	s.mu.RUnlock()
+	
 	// Start workers
-	for i := 0; i < s.workerCount; i++ {
+	for i := 0; i < ioWorkers; i++ {
 		s.wg.Add(1)
 		go s.scanWorker(ctx)
 	}
+	for i := 0; i < cpuWorkers; i++ {
+		s.cpuWg.Add(1)
+		go s.decodeWorker(ctx)
+	}
 	
 	// Start result processor
-	s.wg.Add(1)
-	go s.processResults(ctx, result)
+	processed := make(chan struct{})
+	go func() {
+		s.processResults(ctx, result)
+		close(processed)
+	}()
 	
 	// Walk directory
-	logger.Info("Starting scan", logger.String("path", path))
+	events.Publish(s.eventBus(), TopicScanStarted, path)
+	logger.Info("Starting scan",
+		logger.String("path", path),
+		logger.Int("io_workers", ioWorkers),
+		logger.Int("cpu_workers", cpuWorkers))
 	
-	err = s.walkDirectory(ctx, path)
+	walk := newWalkState(opts, s.fileChan)
+	err = s.walkDir(ctx, path, 0, walk)
 	if err != nil && err != context.Canceled {
 		result.Errors = append(result.Errors, err)
 	}
 	
-	// Close file channel and wait for workers
+	// Close each stage's input once its producers are done
 	close(s.fileChan)
 	s.wg.Wait()
+	close(s.decodeChan)
+	s.cpuWg.Wait()
 	
-	// Close result channels
+	// Close result channels and wait for the processor to drain them
 	close(s.resultChan)
 	close(s.errorChan)
+	<-processed
+	
+	if ctx.Err() == nil {
+		s.flagVanished(path, walk.seen)
+		result.Albums = s.groupScannedAlbums()
+	}
+	s.finishReport(result, ctx.Err() != nil)
 	
 	// Mark scan complete
 	s.library.StopScan()
@@ -159,15 +381,78 @@ func (s *Scanner) ScanFolder(ctx context.Context, path string) (*ScanResult, err
 	logger.Info("Scan completed",
 		logger.Int("total_files", result.TotalFiles),
 		logger.Int("imported", result.ImportedTracks),
+		logger.Int("updated", result.Report.Updated),
+		logger.Int("removed", result.Report.Removed),
 		logger.Int("failed", result.FailedFiles),
 		logger.Duration("duration", result.Duration),
 	)
+	events.Publish(s.eventBus(), TopicScanFinished, result)
 	
 	return result, nil
 }
 
-func (s *Scanner) walkDirectory(ctx context.Context, root string) error {
-	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+// maxScanDepth bounds folder nesting as a backstop against cycles that
+// real path tracking cannot detect
+const maxScanDepth = 64
+
+// walkState tracks folders visited and files found during a single walk
+type walkState struct {
+	opts    scanOptions
+	files   chan<- string   // Receives each matching file
+	visited map[string]bool // Resolved real paths
+	seen    map[string]bool // Audio files found, by pathKey
+	preview *ScanPreview    // Collects what was left out, for dry runs only
+}
+
+func newWalkState(opts scanOptions, files chan<- string) *walkState {
+	return &walkState{
+		opts:    opts,
+		files:   files,
+		visited: make(map[string]bool),
+		seen:    make(map[string]bool),
+	}
+}
+
+// exclude notes a file or folder the walk left out. Only dry runs record
+// them, and only audio files among the files, so covers and playlists sitting
+// next to the music don't bury the ones that matter.
+func (w *walkState) exclude(path string, isDir bool, reason PreviewReason) {
+	if w.preview == nil || (!isDir && !domain.IsAudioFile(path)) {
+		return
+	}
+	w.preview.add(path, PreviewExclude, reason)
+}
+
+// walkDir walks a folder, following symlinks and junctions only when
+// configured. Each folder is entered at most once by its resolved real
+// path, so links pointing back up the tree cannot cause infinite walks.
+func (s *Scanner) walkDir(ctx context.Context, dir string, depth int, state *walkState) error {
+	if depth > maxScanDepth {
+		logger.Warn("Maximum folder depth reached", logger.String("path", dir))
+		return nil
+	}
+	
+	realPath, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		logger.Warn("Error resolving path", logger.String("path", dir), logger.Error(err))
+		return nil
+	}
+	key := pathKey(realPath)
+	if state.visited[key] {
+		logger.Debug("Skipping already visited folder",
+			logger.String("path", dir),
+			logger.String("target", realPath))
+		return nil
+	}
+	state.visited[key] = true
+	
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		logger.Warn("Error accessing path", logger.String("path", dir), logger.Error(err))
+		return nil // Continue walking
+	}
+	
+	for _, entry := range entries {
 		// Check context cancellation
 		select {
 		case <-ctx.Done():
@@ -175,35 +460,71 @@ func (s *Scanner) walkDirectory(ctx context.Context, root string) error {
 		default:
 		}
 		
-		if err != nil {
-			logger.Warn("Error accessing path", logger.String("path", path), logger.Error(err))
-			return nil // Continue walking
+		path := filepath.Join(dir, entry.Name())
+		
+		isDir := entry.IsDir()
+		
+		// Skip hidden files and folders unless included
+		if !state.opts.includeHidden && isHidden(path, entry) {
+			state.exclude(path, isDir, ReasonHidden)
+			continue
 		}
 		
-		// Skip directories if not recursive
-		if d.IsDir() && path != root && !s.recursive {
-			return fs.SkipDir
+		// Symlinks and junctions (reported as irregular on Windows) are only
+		// resolved when following links is enabled
+		if entry.Type()&(os.ModeSymlink|os.ModeIrregular) != 0 {
+			if !s.followSymlinks {
+				state.exclude(path, true, ReasonLink)
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				logger.Warn("Broken link", logger.String("path", path), logger.Error(err))
+				continue
+			}
+			isDir = info.IsDir()
 		}
 		
-		// Skip symlinks if configured
-		if !s.followSymlinks && d.Type()&os.ModeSymlink != 0 {
-			return nil
+		if isDir {
+			// Skip directories if not recursive, and excluded folders entirely
+			if !state.opts.recursive {
+				state.exclude(path, true, ReasonNotRecursive)
+				continue
+			}
+			if matchesAny(path, state.opts.excludePatterns) {
+				state.exclude(path, true, ReasonExcluded)
+				continue
+			}
+			if err := s.walkDir(ctx, path, depth+1, state); err != nil {
+				return err
+			}
+			continue
 		}
 		
 		// Check if file matches patterns
-		if !d.IsDir() && s.matchesPattern(path) && !s.isExcluded(path) {
-			select {
-			case <-ctx.Done():
-				return context.Canceled
-			case s.fileChan <- path:
+		if !matchesAny(path, state.opts.filePatterns) {
+			state.exclude(path, false, ReasonPatternMismatch)
+			continue
+		}
+		if matchesAny(path, state.opts.excludePatterns) {
+			state.exclude(path, false, ReasonExcluded)
+			continue
+		}
+		
+		select {
+		case <-ctx.Done():
+			return context.Canceled
+		case state.files <- path:
+			state.seen[pathKey(path)] = true
+			if state.preview == nil {
 				s.mu.Lock()
 				s.currentFile = path
 				s.mu.Unlock()
 			}
 		}
-		
-		return nil
-	})
+	}
+	
+	return nil
 }
 
 func (s *Scanner) scanWorker(ctx context.Context) {
@@ -218,7 +539,9 @@ func (s *Scanner) scanWorker(ctx context.Context) {
 				return
 			}
 			
-			track, err := s.scanFile(ctx, path)
+			existing := s.known[pathKey(path)]
+			reread := s.duplicates == domain.DuplicateUpdateInPlace
+			track, needsDecode, err := s.scanFile(ctx, path, existing, reread)
 			if err != nil {
 				select {
 				case s.errorChan <- fmt.Errorf("%s: %w", path, err):
@@ -228,173 +551,326 @@ func (s *Scanner) scanWorker(ctx context.Context) {
 				continue
 			}
 			
-			if track != nil {
+			if track == nil {
+				continue
+			}
+			
+			// Hand files without a fast duration estimate to the CPU pool
+			out := s.resultChan
+			if needsDecode {
+				out = s.decodeChan
+			}
+			select {
+			case out <- track:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// decodeWorker fills in stream details by opening a decoder, for formats
+// whose headers don't describe the duration
+func (s *Scanner) decodeWorker(ctx context.Context) {
+	defer s.cpuWg.Done()
+	
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case track, ok := <-s.decodeChan:
+			if !ok {
+				return
+			}
+			
+			if err := s.readStreamInfo(track); err != nil {
+				logger.Warn("Failed to read stream info",
+					logger.String("path", track.FilePath),
+					logger.Error(err))
+			}
+			
+			if err := s.checkDuration(track); err != nil {
 				select {
-				case s.resultChan <- track:
+				case s.errorChan <- fmt.Errorf("%s: %w", track.FilePath, err):
 				case <-ctx.Done():
 					return
 				}
+				continue
+			}
+			
+			select {
+			case s.resultChan <- track:
+			case <-ctx.Done():
+				return
 			}
 		}
 	}
 }
 
-func (s *Scanner) scanFile(ctx context.Context, path string) (*domain.Track, error) {
-	// Check if file already exists in database
-	if s.skipDuplicates {
-		existing, _ := s.trackRepo.FindByPath(path)
-		if existing != nil {
-			return nil, nil // Skip duplicate
-		}
-	}
-	
-	// Create track
-	track, err := domain.NewTrack(path)
-	if err != nil {
-		return nil, err
-	}
-	
+// scanFile performs the IO stage for a file, given the library track
+// already at its path if any. Unchanged library files are skipped unless
+// reread is set. It returns needsDecode when the duration could not be read
+// from headers and a full decode is needed.
+func (s *Scanner) scanFile(ctx context.Context, path string, existing *domain.Track, reread bool) (*domain.Track, bool, error) {
 	// Get file info
 	info, err := os.Stat(path)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	
+	// Files already in the library are re-read only when they have changed
+	// or come back after going missing
+	track := existing
+	changed := true
+	if track != nil {
+		changed = fileChanged(track, info)
+		if !reread && !changed {
+			s.noteModTime(track, info)
+			return nil, false, nil
+		}
+	} else if track, err = domain.NewTrack(path); err != nil {
+		return nil, false, err
 	}
 	track.FileSize = info.Size()
+	track.FileModTime = info.ModTime()
+	
+	// Checksum the audio for move detection and integrity checks. Reading
+	// the whole file is costly, so a forced re-read of a file whose size
+	// hasn't changed keeps the checksum it has.
+	if changed || track.Checksum == "" {
+		checksum, err := ComputeChecksum(path)
+		if err != nil {
+			return nil, false, err
+		}
+		track.Checksum = checksum
+	}
 	
 	// Extract metadata if enabled
 	if s.extractMetadata {
-		if err := s.extractMetadata(track); err != nil {
+		if err := s.readTags(track); err != nil {
 			logger.Warn("Failed to extract metadata", 
 				logger.String("path", path),
 				logger.Error(err))
 		}
+		s.enrich(ctx, track)
 	}
 	
-	// Validate duration
+	// Read duration from headers where the format allows
+	streamInfo, err := EstimateStreamInfo(path)
+	if err != nil {
+		if !errors.Is(err, ErrNoFastPath) {
+			logger.Debug("Fast duration estimate failed",
+				logger.String("path", path),
+				logger.Error(err))
+		}
+		return track, true, nil
+	}
+	applyStreamInfo(track, streamInfo)
+	
+	if err := s.checkDuration(track); err != nil {
+		return nil, false, err
+	}
+	
+	return track, false, nil
+}
+
+// checkDuration rejects tracks outside the configured duration limits
+func (s *Scanner) checkDuration(track *domain.Track) error {
 	if s.minDuration > 0 && track.Duration < s.minDuration {
-		return nil, fmt.Errorf("track too short: %v", track.Duration)
+		return fmt.Errorf("track too short: %v", track.Duration)
 	}
 	if s.maxDuration > 0 && track.Duration > s.maxDuration {
-		return nil, fmt.Errorf("track too long: %v", track.Duration)
+		return fmt.Errorf("track too long: %v", track.Duration)
 	}
+	return nil
+}
+
+func applyStreamInfo(track *domain.Track, info *StreamInfo) {
+	track.Duration = info.Duration
+	track.SampleRate = info.SampleRate
+	track.Channels = info.Channels
+	track.BitDepth = info.BitDepth
+	track.Bitrate = info.Bitrate
 	
-	return track, nil
+	// Calculate bitrate if not set
+	if track.Bitrate == 0 && track.Duration > 0 {
+		track.Bitrate = int(float64(track.FileSize*8) / track.Duration.Seconds())
+	}
 }
 
-func (s *Scanner) extractMetadata(track *domain.Track) error {
+func (s *Scanner) readTags(track *domain.Track) error {
 	file, err := os.Open(track.FilePath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 	
-	// Try to extract tags
-	m, err := tag.ReadFrom(file)
-	if err == nil {
-		track.Title = m.Title()
-		track.Artist = m.Artist()
-		track.Album = m.Album()
-		track.AlbumArtist = m.AlbumArtist()
-		track.Genre = m.Genre()
-		track.Year = m.Year()
-		track.Comment = m.Comment()
-		
-		if trackNum, _ := m.Track(); trackNum > 0 {
-			track.TrackNumber = trackNum
-		}
-		if discNum, _ := m.Disc(); discNum > 0 {
-			track.DiscNumber = discNum
-		}
-		
-		// Extract album art
-		if pic := m.Picture(); pic != nil && len(pic.Data) > 0 {
-			// Save album art to cache
-			artPath := s.saveAlbumArt(track, pic.Data, pic.Ext)
-			if artPath != "" {
-				track.AlbumArtPath = artPath
-			}
-		}
+	return s.readTagsFrom(track, file)
+}
+
+// readTagsFrom fills a track in from the tags at the start of its audio,
+// however it was opened
+func (s *Scanner) readTagsFrom(track *domain.Track, r io.ReadSeeker) error {
+	m, err := tag.ReadFrom(r)
+	if err != nil {
+		return err
 	}
 	
-	// Try to get duration from decoder
-	file.Seek(0, 0)
-	if dec, err := decoder.CreateDecoderForFile(track.FilePath); err == nil {
-		defer dec.Close()
-		track.Duration = dec.Duration()
-		
-		format := dec.Format()
-		track.SampleRate = format.SampleRate
-		track.Channels = format.Channels
-		track.BitDepth = format.BitDepth
-		
-		// Calculate bitrate if not set
-		if track.Bitrate == 0 && track.Duration > 0 {
-			track.Bitrate = int((track.FileSize * 8) / int64(track.Duration.Seconds()))
+	track.Title = m.Title()
+	track.Artist = m.Artist()
+	track.Album = m.Album()
+	track.AlbumArtist = m.AlbumArtist()
+	track.Genre = m.Genre()
+	track.Year = m.Year()
+	track.Comment = m.Comment()
+	track.Composer = m.Composer()
+	track.Lyrics = m.Lyrics()
+	track.Publisher = rawTagText(m.Raw(), publisherTags...)
+	track.DiscSubtitle = rawTagText(m.Raw(), discSubtitleTags...)
+	track.ArtistMBID = firstValue(rawTagText(m.Raw(), musicBrainzArtistTags...))
+	if track.ArtistMBID == "" {
+		track.ArtistMBID = firstValue(userTagText(m.Raw(), "MusicBrainz Artist Id"))
+	}
+	track.Language = tagLanguage(m.Raw())
+	track.Advisory = tagAdvisory(m.Raw())
+	track.IsLive = tagLive(m.Raw())
+	
+	if trackNum, _ := m.Track(); trackNum > 0 {
+		track.TrackNumber = trackNum
+	}
+	if discNum, _ := m.Disc(); discNum > 0 {
+		track.DiscNumber = discNum
+	}
+	
+	if s.normalizer != nil {
+		s.normalizer.Apply(track)
+	}
+	track.IsLive = track.IsLive || track.LooksLive()
+	
+	// Extract album art, or take the cover image in the track's folder
+	if pic := m.Picture(); pic != nil && len(pic.Data) > 0 && s.artStore != nil {
+		artPath, err := s.artStore.Save(pic.Data)
+		if err != nil {
+			logger.Warn("Failed to save album art",
+				logger.String("path", track.FilePath),
+				logger.Error(err))
+		} else {
+			track.AlbumArtPath = artPath
+		}
+	} else if s.artStore != nil {
+		artPath, err := s.artStore.SaveFolderArt(track.FilePath)
+		if err != nil {
+			logger.Warn("Failed to save folder art",
+				logger.String("path", track.FilePath),
+				logger.Error(err))
+		} else if artPath != "" {
+			track.AlbumArtPath = artPath
 		}
 	}
 	
 	return nil
 }
 
-func (s *Scanner) saveAlbumArt(track *domain.Track, data []byte, ext string) string {
-	// Create album art cache directory with secure permissions
-	cacheDir := filepath.Join(os.TempDir(), "winramp", "albumart")
-	if err := os.MkdirAll(cacheDir, 0700); err != nil {
-		logger.Warn("Failed to create album art directory", logger.Error(err))
-		return ""
+// enrich fills in what a track's tags leave out from the scanner's
+// metadata providers, if it has any
+func (s *Scanner) enrich(ctx context.Context, track *domain.Track) {
+	s.mu.RLock()
+	enricher, normalizer := s.enricher, s.normalizer
+	s.mu.RUnlock()
+	if enricher == nil {
+		return
 	}
 	
-	// Sanitize inputs BEFORE using them
-	safeArtist := sanitizeFilename(track.Artist)
-	safeAlbum := sanitizeFilename(track.Album)
-	safeExt := sanitizeFilename(ext)
-	
-	// Additional validation for extension
-	if safeExt == "" || len(safeExt) > 5 {
-		safeExt = "jpg"
+	changed, err := enricher.Enrich(ctx, track)
+	if err != nil {
+		logger.Debug("Metadata enrichment stopped",
+			logger.String("path", track.FilePath),
+			logger.Error(err))
+		return
 	}
-	
-	// Generate filename with sanitized inputs
-	filename := fmt.Sprintf("%s_%s.%s", safeArtist, safeAlbum, safeExt)
-	
-	// Construct path and validate it's within cache directory
-	path := filepath.Join(cacheDir, filename)
-	cleanedPath := filepath.Clean(path)
-	
-	// Verify the final path is within our cache directory
-	relPath, err := filepath.Rel(cacheDir, cleanedPath)
-	if err != nil || strings.HasPrefix(relPath, "..") || filepath.IsAbs(relPath) {
-		logger.Warn("Invalid path detected: potential traversal attempt",
-			logger.String("path", cleanedPath),
-			logger.String("cacheDir", cacheDir))
-		return ""
+	if len(changed) > 0 && normalizer != nil {
+		normalizer.Apply(track)
 	}
-	
-	// Save file with secure permissions
-	if err := os.WriteFile(cleanedPath, data, 0600); err != nil {
-		logger.Warn("Failed to save album art", logger.Error(err))
-		return ""
+}
+
+// readStreamInfo opens a decoder to read duration and format details
+func (s *Scanner) readStreamInfo(track *domain.Track) error {
+	dec, err := decoder.CreateDecoderForFile(track.FilePath)
+	if err != nil {
+		return err
 	}
+	defer dec.Close()
 	
-	return cleanedPath
+	format := dec.Format()
+	applyStreamInfo(track, &StreamInfo{
+		Duration:   dec.Duration(),
+		SampleRate: format.SampleRate,
+		Channels:   format.Channels,
+		BitDepth:   format.BitDepth,
+	})
+	
+	return nil
 }
 
+// processResults saves scanned tracks until both result channels are closed
 func (s *Scanner) processResults(ctx context.Context, result *ScanResult) {
-	defer s.wg.Done()
+	resultChan, errorChan := s.resultChan, s.errorChan
 	
-	for {
+	for resultChan != nil || errorChan != nil {
 		select {
 		case <-ctx.Done():
 			return
 			
-		case track, ok := <-s.resultChan:
+		case track, ok := <-resultChan:
 			if !ok {
-				return
+				resultChan = nil
+				continue
 			}
 			
 			result.ScannedFiles++
 			
-			// Save to database
+			// Changed files update their existing track
+			if s.known[pathKey(track.FilePath)] != nil {
+				if err := s.saveChangedTrack(track); err != nil {
+					result.FailedFiles++
+					result.Errors = append(result.Errors, err)
+					logger.Warn("Failed to update track",
+						logger.String("path", track.FilePath),
+						logger.Error(err))
+				} else {
+					s.report.Record(domain.ScanChangeUpdated, track)
+				}
+				s.updateProgress(result)
+				continue
+			}
+			
+			// Files moved outside WinRamp take their track along, so ratings
+			// and history survive reorganizing folders
+			candidates := s.findCopies(track)
+			moved := findMoved(track, candidates)
+			if moved == nil {
+				moved = s.findMovedBySound(ctx, track)
+			}
+			if moved != nil {
+				s.recordMove(moved, track, result)
+				s.updateProgress(result)
+				continue
+			}
+			
+			// Copies of library tracks are handled by the duplicate policy
+			if duplicate, replace := s.resolveDuplicate(track, candidates, s.duplicates); duplicate != nil {
+				if replace {
+					s.replaceDuplicate(duplicate, track, result)
+				} else {
+					result.SkippedFiles++
+				}
+				s.updateProgress(result)
+				continue
+			}
+			
+			// Save to database, after the watch folder's rules have had
+			// their say
+			deferred := s.applyImportRules(track)
 			if err := s.trackRepo.Create(track); err != nil {
 				result.FailedFiles++
 				result.Errors = append(result.Errors, err)
@@ -403,6 +879,8 @@ func (s *Scanner) processResults(ctx context.Context, result *ScanResult) {
 					logger.Error(err))
 			} else {
 				result.ImportedTracks++
+				s.report.Record(domain.ScanChangeAdded, track)
+				s.runImportRules(track, deferred)
 				
 				// Add to library
 				if s.library != nil {
@@ -413,19 +891,19 @@ func (s *Scanner) processResults(ctx context.Context, result *ScanResult) {
 			// Update progress
 			s.updateProgress(result)
 			
-		case err := <-s.errorChan:
-			if err != nil {
-				result.FailedFiles++
-				result.Errors = append(result.Errors, err)
+		case err, ok := <-errorChan:
+			if !ok {
+				errorChan = nil
+				continue
 			}
+			result.FailedFiles++
+			result.Errors = append(result.Errors, err)
 		}
 	}
 }
 
 func (s *Scanner) updateProgress(result *ScanResult) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	
 	if result.TotalFiles > 0 {
 		s.progress = float64(result.ScannedFiles) / float64(result.TotalFiles) * 100
 	}
@@ -433,11 +911,30 @@ func (s *Scanner) updateProgress(result *ScanResult) {
 	if s.library != nil {
 		s.library.UpdateScanProgress(s.progress)
 	}
+	
+	// Published at most every scanProgressInterval, as large libraries
+	// would otherwise flood the frontend
+	publish := time.Since(s.published) >= scanProgressInterval
+	if publish {
+		s.published = time.Now()
+	}
+	progress := &ScanProgress{
+		Done:  result.ScannedFiles,
+		Total: result.TotalFiles,
+		Path:  s.currentFile,
+	}
+	bus := s.bus
+	s.mu.Unlock()
+	
+	if publish {
+		events.Publish(bus, TopicScanProgress, progress)
+	}
 }
 
-func (s *Scanner) matchesPattern(path string) bool {
+// matchesAny reports whether the file or folder name matches any pattern
+func matchesAny(path string, patterns []string) bool {
 	name := strings.ToLower(filepath.Base(path))
-	for _, pattern := range s.filePatterns {
+	for _, pattern := range patterns {
 		if matched, _ := filepath.Match(strings.ToLower(pattern), name); matched {
 			return true
 		}
@@ -445,14 +942,22 @@ func (s *Scanner) matchesPattern(path string) bool {
 	return false
 }
 
-func (s *Scanner) isExcluded(path string) bool {
-	name := strings.ToLower(filepath.Base(path))
-	for _, pattern := range s.excludePatterns {
-		if matched, _ := filepath.Match(strings.ToLower(pattern), name); matched {
-			return true
-		}
+// isNetworkStorage reports whether a path is on a network share or mapped
+// network drive
+func isNetworkStorage(path string) bool {
+	if strings.HasPrefix(path, `\\`) || strings.HasPrefix(path, "//") {
+		return true
 	}
-	return false
+	return isRemoteDrive(path)
+}
+
+// isHidden reports whether a file or folder is hidden, either by a leading
+// dot or by the platform's hidden attribute
+func isHidden(path string, d fs.DirEntry) bool {
+	if strings.HasPrefix(d.Name(), ".") {
+		return true
+	}
+	return hasHiddenAttribute(path)
 }
 
 func (s *Scanner) getOrCreateLibrary() (*domain.Library, error) {
@@ -507,12 +1012,121 @@ func (s *Scanner) GetCurrentFile() string {
 	return s.currentFile
 }
 
-func sanitizeFilename(s string) string {
-	// Remove invalid filename characters
-	invalid := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
-	result := s
-	for _, char := range invalid {
-		result = strings.ReplaceAll(result, char, "_")
+// publisherTags are the raw tag names holding the publisher or label:
+// ID3v2.3/4, ID3v2.2, then Vorbis comments and MP4 freeform atoms
+var publisherTags = []string{"TPUB", "TPB", "label", "publisher", "organization"}
+
+// discSubtitleTags are the raw tag names holding a disc's own title
+var discSubtitleTags = []string{"TSST", "discsubtitle", "setsubtitle"}
+
+// musicBrainzArtistTags are the raw tag names holding the MusicBrainz artist
+// ID in Vorbis comments and MP4 freeform atoms. ID3v2 keeps it in a TXXX
+// frame, which userTagText reads.
+var musicBrainzArtistTags = []string{"musicbrainz_artistid", "MusicBrainz Artist Id"}
+
+// languageTags are the raw tag names holding the language sung in
+var languageTags = []string{"TLAN", "language"}
+
+// advisoryTags are the raw tag names holding the parental advisory: the MP4
+// rating atom and the iTunes name used in Vorbis comments and TXXX frames
+var advisoryTags = []string{"rtng", "ITUNESADVISORY"}
+
+// releaseTypeTags are the raw tag names holding the MusicBrainz release
+// type, such as "album; live", in Vorbis comments and MP4 freeform atoms.
+// ID3v2 keeps it in a TXXX frame.
+var releaseTypeTags = []string{"releasetype", "MusicBrainz Album Type"}
+
+// tagLive reports whether a track's tags say it is from a live release
+func tagLive(raw map[string]interface{}) bool {
+	releaseType := rawTagText(raw, releaseTypeTags...)
+	if releaseType == "" {
+		releaseType = userTagText(raw, "MusicBrainz Album Type")
+	}
+	return strings.Contains(strings.ToLower(releaseType), "live")
+}
+
+// tagLanguage returns the first language a track's tags name, lower-cased
+// as the ISO 639 codes they hold are compared
+func tagLanguage(raw map[string]interface{}) string {
+	return strings.ToLower(firstValue(rawTagText(raw, languageTags...)))
+}
+
+// tagAdvisory reads the parental advisory from a track's tags. iTunes
+// stores 1 (or 4) for explicit and 2 for clean, as a number in MP4 files
+// and as text elsewhere.
+func tagAdvisory(raw map[string]interface{}) domain.Advisory {
+	var value string
+	for _, name := range advisoryTags {
+		for key, v := range raw {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+			switch v := v.(type) {
+			case string:
+				value = v
+			case int:
+				value = strconv.Itoa(v)
+			case uint8:
+				value = strconv.Itoa(int(v))
+			case []byte:
+				if len(v) > 0 {
+					value = strconv.Itoa(int(v[len(v)-1]))
+				}
+			}
+		}
+		if value != "" {
+			break
+		}
 	}
-	return result
-}
\ No newline at end of file
+	if value == "" {
+		value = userTagText(raw, "ITUNESADVISORY")
+	}
+	
+	switch strings.TrimSpace(value) {
+	case "1", "4":
+		return domain.AdvisoryExplicit
+	case "2":
+		return domain.AdvisoryClean
+	}
+	advisory, _ := domain.ParseAdvisory(value)
+	return advisory
+}
+
+// rawTagText returns the first non-empty text value among the named raw
+// tags. Names are matched without regard to case, as Vorbis comment names
+// are case-insensitive.
+func rawTagText(raw map[string]interface{}, names ...string) string {
+	for _, name := range names {
+		for key, value := range raw {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+			if text, ok := value.(string); ok {
+				if text = strings.TrimSpace(text); text != "" {
+					return text
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// userTagText returns the text of the ID3v2 user-defined text frame (TXXX)
+// with the given description
+func userTagText(raw map[string]interface{}, description string) string {
+	for _, value := range raw {
+		if comm, ok := value.(*tag.Comm); ok && strings.EqualFold(comm.Description, description) {
+			return strings.TrimSpace(comm.Text)
+		}
+	}
+	return ""
+}
+
+// firstValue returns the first of several values joined in one tag, as
+// multi-artist tracks store one ID per artist
+func firstValue(text string) string {
+	if i := strings.IndexAny(text, "/;\x00"); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,29 @@
+package library
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanFileStreamInfo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tone.wav")
+	writeToneWAV(t, path, 0.5)
+
+	scanner := NewScanner(nil, nil)
+	scanner.extractMetadata = false
+	scanner.minDuration = 0
+	track, needsDecode, err := scanner.scanFile(context.Background(), path, nil, false)
+	require.NoError(t, err)
+
+	// The header has everything, so the file is not decoded
+	assert.False(t, needsDecode)
+	assert.Equal(t, 2*time.Second, track.Duration)
+	assert.Equal(t, 48000, track.SampleRate)
+	assert.Equal(t, 2, track.Channels)
+	assert.Equal(t, 16, track.BitDepth)
+}
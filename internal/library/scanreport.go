@@ -0,0 +1,200 @@
+package library
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// pathKey normalizes a path for comparison, ignoring case on Windows
+func pathKey(path string) string {
+	key := filepath.Clean(path)
+	if runtime.GOOS == "windows" {
+		key = strings.ToLower(key)
+	}
+	return key
+}
+
+// fileChanged reports whether a library track's file differs from when it
+// was last read, or has come back after being flagged as missing. Tags
+// rewritten in place keep the size, so the modification time counts too
+// once it is known.
+func fileChanged(track *domain.Track, info os.FileInfo) bool {
+	if info.Size() != track.FileSize {
+		return true
+	}
+	if !track.FileModTime.IsZero() && !info.ModTime().Equal(track.FileModTime) {
+		return true
+	}
+	return !track.IsValid && track.Error == domain.ErrFileNotFound.Error()
+}
+
+// saveChangedTrack stores a re-read track, clearing a missing-file flag
+func (s *Scanner) saveChangedTrack(track *domain.Track) error {
+	wasMissing := !track.IsValid && track.Error == domain.ErrFileNotFound.Error()
+	track.UpdatedAt = time.Now()
+
+	if err := s.trackRepo.Update(track); err != nil {
+		return err
+	}
+
+	// Update skips zero values, so the cleared error needs its own write
+	if wasMissing {
+		track.MarkValid()
+		return s.trackRepo.UpdateStatus(track)
+	}
+	return nil
+}
+
+// SetRemoveMissing sets whether scans delete the tracks of files that are
+// gone, with their ratings and history, instead of flagging them as
+// missing
+func (s *Scanner) SetRemoveMissing(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeMissing = enabled
+}
+
+// flagVanished marks tracks whose files no longer exist as problem files.
+// Tracks are kept so ratings and history survive a file that comes back,
+// unless the scanner removes missing tracks. Nothing is flagged when the
+// scan root itself is unreachable.
+func (s *Scanner) flagVanished(root string, seen map[string]bool) {
+	if _, err := os.Stat(root); err != nil {
+		return
+	}
+
+	s.mu.RLock()
+	remove := s.removeMissing
+	s.mu.RUnlock()
+
+	for key, track := range s.known {
+		if seen[key] || !track.IsValid || s.moved[track.ID] {
+			continue
+		}
+		if _, err := os.Stat(track.FilePath); !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if remove {
+			if err := s.trackRepo.Delete(track.ID); err != nil {
+				logger.Warn("Failed to remove missing track",
+					logger.String("path", track.FilePath),
+					logger.Error(err))
+				continue
+			}
+			s.report.Record(domain.ScanChangeRemoved, track)
+			continue
+		}
+
+		track.MarkInvalid(domain.ErrFileNotFound.Error())
+		if err := s.trackRepo.UpdateStatus(track); err != nil {
+			logger.Warn("Failed to flag missing track",
+				logger.String("path", track.FilePath),
+				logger.Error(err))
+			continue
+		}
+		s.report.Record(domain.ScanChangeRemoved, track)
+	}
+}
+
+// groupScannedAlbums groups the albums that gained or changed tracks in
+// the scan, with all of their tracks, and lists the albums that had no
+// tracks in the library before the scan in the report
+func (s *Scanner) groupScannedAlbums() []*domain.AlbumGroup {
+	touched := make(map[string]bool)
+	added := make(map[string]bool)
+	titles := make(map[string]bool)
+	var tracks []*domain.Track
+	for _, entry := range s.report.Entries {
+		switch entry.Change {
+		case domain.ScanChangeAdded:
+			added[entry.TrackID] = true
+		case domain.ScanChangeUpdated:
+		default:
+			continue
+		}
+		touched[entry.TrackID] = true
+
+		if entry.Album == "" || titles[entry.Album] {
+			continue
+		}
+		titles[entry.Album] = true
+
+		found, err := s.trackRepo.FindByAlbum(entry.Album)
+		if err != nil {
+			logger.Warn("Failed to look up album", logger.String("album", entry.Album), logger.Error(err))
+			continue
+		}
+		tracks = append(tracks, found...)
+	}
+
+	// Same-titled albums by other artists come back from the lookup too
+	var albums []*domain.AlbumGroup
+	for _, group := range domain.GroupAlbums(tracks) {
+		isTouched, isNew := false, true
+		for _, track := range group.Tracks() {
+			isTouched = isTouched || touched[track.ID]
+			isNew = isNew && added[track.ID]
+		}
+		if !isTouched {
+			continue
+		}
+		albums = append(albums, group)
+
+		if isNew {
+			s.report.NewAlbums = append(s.report.NewAlbums, domain.ScanAlbum{
+				Title:  group.Title,
+				Artist: group.Artist,
+				Tracks: group.TrackCount(),
+			})
+		}
+	}
+	return albums
+}
+
+// finishReport completes the scan report, attaches it to the result and
+// saves it when a repository is set. Scans that changed nothing are not
+// saved, so the latest report stays a useful one.
+func (s *Scanner) finishReport(result *ScanResult, cancelled bool) {
+	report := s.report
+	report.FinishedAt = time.Now()
+	report.Failed = result.FailedFiles
+	report.Cancelled = cancelled
+	result.Report = report
+
+	s.mu.RLock()
+	repo := s.reportRepo
+	s.mu.RUnlock()
+	if repo == nil || (!report.HasChanges() && report.Failed == 0) {
+		return
+	}
+
+	if err := repo.Create(report); err != nil {
+		logger.Warn("Failed to save scan report", logger.Error(err))
+		return
+	}
+	if err := repo.Prune(maxScanReports); err != nil {
+		logger.Warn("Failed to prune scan reports", logger.Error(err))
+	}
+}
+
+// noteModTime records the modification time of an unchanged file read
+// before times were kept, so later changes to its tags are noticed
+func (s *Scanner) noteModTime(track *domain.Track, info os.FileInfo) {
+	if !track.FileModTime.IsZero() {
+		return
+	}
+	track.FileModTime = info.ModTime()
+	if err := s.trackRepo.Update(track); err != nil {
+		logger.Warn("Failed to record file modification time",
+			logger.String("path", track.FilePath),
+			logger.Error(err))
+	}
+}
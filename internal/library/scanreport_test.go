@@ -0,0 +1,40 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestFileChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song.mp3")
+	require.NoError(t, os.WriteFile(path, []byte("audio"), 0o644))
+	modTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		track    *domain.Track
+		expected bool
+	}{
+		{"unchanged", &domain.Track{FileSize: 5, FileModTime: modTime, IsValid: true}, false},
+		{"resized", &domain.Track{FileSize: 9, FileModTime: modTime, IsValid: true}, true},
+		{"retagged in place", &domain.Track{FileSize: 5, FileModTime: modTime.Add(-time.Hour), IsValid: true}, true},
+		{"read before times were kept", &domain.Track{FileSize: 5, IsValid: true}, false},
+		{"back after going missing", &domain.Track{FileSize: 5, FileModTime: modTime, Error: domain.ErrFileNotFound.Error()}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, fileChanged(tt.track, info))
+		})
+	}
+}
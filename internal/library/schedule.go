@@ -0,0 +1,168 @@
+package library
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// scheduleBusyRetry is how long a due scan waits before checking again
+// whether it may start
+const scheduleBusyRetry = time.Minute
+
+// ScanScheduler runs a scan of the library every interval while the app
+// runs. A scan that falls due while the scheduler is told it is busy, such
+// as while music plays, waits until it isn't.
+type ScanScheduler struct {
+	scan func(ctx context.Context)
+	busy func() bool
+
+	mu       sync.Mutex
+	interval time.Duration
+	lastScan time.Time
+	nextScan time.Time
+	reset    chan struct{}
+	retry    time.Duration
+}
+
+// NewScanScheduler creates a scheduler calling scan every interval. An
+// interval of zero or less schedules nothing.
+func NewScanScheduler(interval time.Duration, scan func(ctx context.Context)) *ScanScheduler {
+	return &ScanScheduler{
+		scan:     scan,
+		interval: interval,
+		reset:    make(chan struct{}, 1),
+		retry:    scheduleBusyRetry,
+	}
+}
+
+// SetBusy sets the check a due scan waits on, returning true while the
+// scan should not start
+func (s *ScanScheduler) SetBusy(busy func() bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.busy = busy
+}
+
+// SetInterval changes how often scans run, counted from the last scan, or
+// from now if none has run. Zero or less stops scheduling them.
+func (s *ScanScheduler) SetInterval(interval time.Duration) {
+	s.mu.Lock()
+	s.interval = interval
+	s.mu.Unlock()
+
+	select {
+	case s.reset <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs scans on schedule until the context is done. The first scan
+// is one interval away.
+func (s *ScanScheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.lastScan = time.Now()
+	s.mu.Unlock()
+
+	go s.run(ctx)
+}
+
+// run waits for each scan to fall due and runs it
+func (s *ScanScheduler) run(ctx context.Context) {
+	timer := time.NewTimer(s.schedule())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.reset:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		case <-timer.C:
+			if s.isBusy() {
+				s.mu.Lock()
+				s.nextScan = time.Now().Add(s.retry)
+				retry := s.retry
+				s.mu.Unlock()
+				timer.Reset(retry)
+				continue
+			}
+			s.scan(ctx)
+			s.mu.Lock()
+			s.lastScan = time.Now()
+			s.mu.Unlock()
+		}
+		timer.Reset(s.schedule())
+	}
+}
+
+// schedule works out when the next scan is due and returns how long that
+// is from now. With no interval it returns a wait long enough to mean
+// never; SetInterval wakes the scheduler to work it out again.
+func (s *ScanScheduler) schedule() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.interval <= 0 {
+		s.nextScan = time.Time{}
+		return time.Duration(1<<63 - 1)
+	}
+	s.nextScan = s.lastScan.Add(s.interval)
+	return max(time.Until(s.nextScan), 0)
+}
+
+// isBusy reports whether a due scan has to wait
+func (s *ScanScheduler) isBusy() bool {
+	s.mu.Lock()
+	busy := s.busy
+	s.mu.Unlock()
+	return busy != nil && busy()
+}
+
+// LastScan returns when the last scheduled scan finished, or when the
+// scheduler started if none has run
+func (s *ScanScheduler) LastScan() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastScan
+}
+
+// NextScan returns when the next scan is due, or the zero time when none
+// is scheduled
+func (s *ScanScheduler) NextScan() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextScan
+}
+
+// SetBackgroundCPULimit limits the decode workers of background scans to
+// a percentage of the processors, at least one. Zero or less, or 100 and
+// over, leaves them unlimited.
+func (s *Scanner) SetBackgroundCPULimit(percent int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backgroundCPU = percent
+}
+
+// BackgroundScanWatchFolder scans a watch folder as ScanWatchFolder does,
+// holding its decode workers to the background CPU limit
+func (s *Scanner) BackgroundScanWatchFolder(ctx context.Context, folder *domain.WatchFolder) (*ScanResult, error) {
+	return s.scanWatchFolder(ctx, folder, true)
+}
+
+// backgroundWorkers limits a number of decode workers to a percentage of
+// the processors
+func backgroundWorkers(workers, percent int) int {
+	if percent <= 0 || percent >= 100 {
+		return workers
+	}
+	return max(min(workers, runtime.NumCPU()*percent/100), 1)
+}
@@ -0,0 +1,45 @@
+package library
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanScheduler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var scans, busy atomic.Int32
+	busy.Store(1)
+	s := NewScanScheduler(20*time.Millisecond, func(context.Context) { scans.Add(1) })
+	s.retry = 10 * time.Millisecond
+	s.SetBusy(func() bool { return busy.Load() == 1 })
+	s.Start(ctx)
+
+	// Due scans wait while busy
+	time.Sleep(60 * time.Millisecond)
+	assert.Zero(t, scans.Load())
+	assert.False(t, s.NextScan().IsZero())
+
+	busy.Store(0)
+	assert.Eventually(t, func() bool { return scans.Load() >= 1 }, time.Second, 5*time.Millisecond)
+
+	// No interval, no scans
+	s.SetInterval(0)
+	assert.Eventually(t, func() bool { return s.NextScan().IsZero() }, time.Second, 5*time.Millisecond)
+	done := scans.Load()
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, done, scans.Load())
+}
+
+func TestBackgroundWorkers(t *testing.T) {
+	assert.Equal(t, 8, backgroundWorkers(8, 0))
+	assert.Equal(t, 8, backgroundWorkers(8, 100))
+	assert.Equal(t, 1, backgroundWorkers(8, 1))
+	assert.Equal(t, min(8, max(runtime.NumCPU()/2, 1)), backgroundWorkers(8, 50))
+}
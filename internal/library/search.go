@@ -0,0 +1,172 @@
+package library
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+const (
+	// searchExactBoost and searchWordBoost multiply a field's weight when
+	// the query is the whole field or starts one of its words
+	searchExactBoost = 2.0
+	searchWordBoost  = 1.5
+
+	// searchPlayWeight scales the boost from a track's play count, which
+	// grows with its logarithm so heavy rotation doesn't drown out relevance
+	searchPlayWeight = 0.5
+
+	// searchRecentWeight is the boost for a track played just now, which
+	// halves every searchRecentHalfLife
+	searchRecentWeight   = 2.0
+	searchRecentHalfLife = 30 * 24 * time.Hour
+)
+
+// searchFields are the fields a search matches, by the names the frontend
+// knows them by, with how much a match in each counts. Title outranks
+// artist, which outranks album.
+var searchFields = []struct {
+	name   string
+	weight float64
+	value  func(*domain.Track) string
+}{
+	{"title", 3, (*domain.Track).GetDisplayTitle},
+	{"artist", 2, func(t *domain.Track) string { return t.Artist }},
+	{"album", 1.5, func(t *domain.Track) string { return t.Album }},
+	{"albumArtist", 1, func(t *domain.Track) string { return t.AlbumArtist }},
+	{"genre", 0.5, func(t *domain.Track) string { return t.Genre }},
+	{"composer", 0.5, func(t *domain.Track) string { return t.Composer }},
+	{"publisher", 0.5, func(t *domain.Track) string { return t.Publisher }},
+}
+
+// SearchMatch is where a search matched in a field, in UTF-16 code units
+// as JavaScript indexes strings. End is exclusive.
+type SearchMatch struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// SearchResult is a track found by a search, with how relevant it is and
+// where the query matched it
+type SearchResult struct {
+	Track      *domain.Track
+	Score      float64
+	Highlights map[string][]SearchMatch // By field name
+}
+
+// RankSearch scores tracks against a query and returns them most relevant
+// first. A match counts for more in the title than the artist, and in the
+// artist than the album, and more again when it is the whole field or
+// starts a word. Often and recently played tracks are boosted. Matching
+// ignores case and accents, as the library's search does; tracks that
+// score the same keep their order.
+func RankSearch(tracks []*domain.Track, query string, now time.Time) []SearchResult {
+	needle := []rune(domain.FoldText(strings.TrimSpace(query)))
+
+	results := make([]SearchResult, len(tracks))
+	for i, track := range tracks {
+		result := SearchResult{Track: track, Highlights: make(map[string][]SearchMatch)}
+		for _, field := range searchFields {
+			text := foldWithOffsets(field.value(track))
+			matches := text.find(needle)
+			if len(matches) == 0 {
+				continue
+			}
+			result.Highlights[field.name] = matches
+			result.Score += field.weight * text.quality(needle)
+		}
+		result.Score += searchBoost(track, now)
+		results[i] = result
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
+// searchBoost is what a track's listening history adds to its score
+func searchBoost(track *domain.Track, now time.Time) float64 {
+	boost := searchPlayWeight * math.Log1p(float64(max(track.PlayCount, 0)))
+	if track.LastPlayed != nil {
+		age := max(now.Sub(*track.LastPlayed), 0)
+		boost += searchRecentWeight * math.Exp2(-float64(age)/float64(searchRecentHalfLife))
+	}
+	return boost
+}
+
+// foldedText is text folded as domain.FoldText folds it, remembering the
+// span of the original each folded rune came from
+type foldedText struct {
+	runes  []rune
+	starts []int // UTF-16 offsets into the original
+	ends   []int
+}
+
+// foldWithOffsets folds text a rune at a time, which folds it the same as
+// folding it whole since combining marks are dropped either way
+func foldWithOffsets(s string) foldedText {
+	var f foldedText
+	offset := 0
+	for _, r := range s {
+		width := 1
+		if r > 0xFFFF {
+			width = 2 // Surrogate pair
+		}
+		for _, folded := range domain.FoldText(string(r)) {
+			f.runes = append(f.runes, folded)
+			f.starts = append(f.starts, offset)
+			f.ends = append(f.ends, offset+width)
+		}
+		offset += width
+	}
+	return f
+}
+
+// find returns where needle occurs in the text, without overlaps
+func (f foldedText) find(needle []rune) []SearchMatch {
+	if len(needle) == 0 {
+		return nil
+	}
+
+	var matches []SearchMatch
+	for i := 0; i+len(needle) <= len(f.runes); i++ {
+		if !f.matchesAt(needle, i) {
+			continue
+		}
+		matches = append(matches, SearchMatch{Start: f.starts[i], End: f.ends[i+len(needle)-1]})
+		i += len(needle) - 1
+	}
+	return matches
+}
+
+// quality is how well needle matches the text, which must contain it: as
+// the whole text, at the start of a word, or anywhere
+func (f foldedText) quality(needle []rune) float64 {
+	if len(needle) == len(f.runes) {
+		return searchExactBoost
+	}
+	for i := 0; i+len(needle) <= len(f.runes); i++ {
+		if f.matchesAt(needle, i) && (i == 0 || !isWordRune(f.runes[i-1])) {
+			return searchWordBoost
+		}
+	}
+	return 1
+}
+
+func (f foldedText) matchesAt(needle []rune, i int) bool {
+	for j, r := range needle {
+		if f.runes[i+j] != r {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,67 @@
+package library
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestRankSearch(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	yesterday := now.AddDate(0, 0, -1)
+
+	tracks := []*domain.Track{
+		{ID: "album", Title: "Intro", Artist: "Someone", Album: "Blue Skies"},
+		{ID: "artist", Title: "Intro", Artist: "Blue"},
+		{ID: "title", Title: "Feeling Blue", Artist: "Someone"},
+		{ID: "exact", Title: "Blue", Artist: "Someone"},
+		{ID: "inside", Title: "Skyblue", Artist: "Someone"},
+		{ID: "genre", Title: "Intro", Artist: "Someone", Genre: "Blues"},
+	}
+
+	ids := func(results []SearchResult) []string {
+		ids := make([]string, len(results))
+		for i, result := range results {
+			ids[i] = result.Track.ID
+		}
+		return ids
+	}
+
+	results := RankSearch(tracks, "blue", now)
+	assert.Equal(t, []string{"exact", "title", "artist", "inside", "album", "genre"}, ids(results))
+
+	// Listening lifts a weaker match above a stronger one
+	tracks[0].PlayCount = 50
+	tracks[0].LastPlayed = &yesterday
+	results = RankSearch(tracks, "blue", now)
+	assert.Equal(t, "album", results[0].Track.ID)
+}
+
+func TestRankSearchHighlights(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		query string
+		want  []SearchMatch
+	}{
+		{"every match", "Blue on blue", "blue", []SearchMatch{{0, 4}, {8, 12}}},
+		{"accents and case", "Björk", "BJORK", []SearchMatch{{0, 5}}},
+		{"decomposed accent", "Björk", "bjork", []SearchMatch{{0, 6}}},
+		{"expanded letter", "Straße", "strasse", []SearchMatch{{0, 6}}},
+		{"surrogate pair", "🎵 Song", "song", []SearchMatch{{3, 7}}},
+		{"no overlaps", "aaaa", "aa", []SearchMatch{{0, 2}, {2, 4}}},
+		{"no match", "Song", "blue", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := RankSearch([]*domain.Track{{Title: tt.title}}, tt.query, time.Now())
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.want, results[0].Highlights["title"])
+		})
+	}
+}
@@ -0,0 +1,62 @@
+//go:build !windows
+
+package library
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+)
+
+// RevealInExplorer opens the file manager on the file's folder
+func RevealInExplorer(path string) error {
+	if runtime.GOOS == "darwin" {
+		return exec.Command("open", "-R", path).Start()
+	}
+	return exec.Command("xdg-open", filepath.Dir(path)).Start()
+}
+
+// RecycleFile is not supported outside Windows
+func RecycleFile(path string) error {
+	return ErrRecycleBinUnavailable
+}
+
+// moveFile renames a file, copying it when source and destination are on
+// different file systems
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	return os.Remove(src)
+}
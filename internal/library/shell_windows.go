@@ -0,0 +1,125 @@
+//go:build windows
+
+package library
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	foMove   = 0x0001 // FO_MOVE
+	foDelete = 0x0003 // FO_DELETE
+
+	fofSilent               = 0x0004 // FOF_SILENT
+	fofNoConfirmation       = 0x0010 // FOF_NOCONFIRMATION
+	fofAllowUndo            = 0x0040 // FOF_ALLOWUNDO
+	fofNoConfirmMkdir       = 0x0200 // FOF_NOCONFIRMMKDIR
+	fofNoErrorUI            = 0x0400 // FOF_NOERRORUI
+	coinitApartmentThreaded = 0x2    // COINIT_APARTMENTTHREADED
+)
+
+var (
+	shell32 = syscall.NewLazyDLL("shell32.dll")
+	ole32   = syscall.NewLazyDLL("ole32.dll")
+
+	procSHFileOperation            = shell32.NewProc("SHFileOperationW")
+	procILCreateFromPath           = shell32.NewProc("ILCreateFromPathW")
+	procILFree                     = shell32.NewProc("ILFree")
+	procSHOpenFolderAndSelectItems = shell32.NewProc("SHOpenFolderAndSelectItems")
+	procCoInitializeEx             = ole32.NewProc("CoInitializeEx")
+	procCoUninitialize             = ole32.NewProc("CoUninitialize")
+)
+
+// shFileOpStruct mirrors SHFILEOPSTRUCTW
+type shFileOpStruct struct {
+	hwnd                  uintptr
+	wFunc                 uint32
+	pFrom                 *uint16
+	pTo                   *uint16
+	fFlags                uint16
+	fAnyOperationsAborted int32
+	hNameMappings         uintptr
+	lpszProgressTitle     *uint16
+}
+
+// RevealInExplorer opens an Explorer window on the file's folder with the
+// file selected
+func RevealInExplorer(path string) error {
+	// Shell calls need COM initialised on the calling thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if hr, _, _ := procCoInitializeEx.Call(0, coinitApartmentThreaded); int32(hr) >= 0 {
+		defer procCoUninitialize.Call()
+	}
+
+	ptr, err := syscall.UTF16PtrFromString(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	pidl, _, _ := procILCreateFromPath.Call(uintptr(unsafe.Pointer(ptr)))
+	if pidl == 0 {
+		return fmt.Errorf("failed to resolve %s", path)
+	}
+	defer procILFree.Call(pidl)
+
+	if hr, _, _ := procSHOpenFolderAndSelectItems.Call(pidl, 0, 0, 0); int32(hr) < 0 {
+		return fmt.Errorf("failed to reveal %s: HRESULT 0x%08X", path, uint32(hr))
+	}
+
+	return nil
+}
+
+// RecycleFile sends a file to the Recycle Bin
+func RecycleFile(path string) error {
+	return shellFileOperation(foDelete, path, "", fofAllowUndo|fofNoConfirmation|fofSilent|fofNoErrorUI)
+}
+
+// moveFile moves a file through the shell, which handles moves across
+// volumes and keeps file attributes and security descriptors
+func moveFile(src, dst string) error {
+	return shellFileOperation(foMove, src, dst, fofNoConfirmation|fofSilent|fofNoErrorUI|fofNoConfirmMkdir)
+}
+
+func shellFileOperation(op uint32, from, to string, flags uint16) error {
+	pFrom, err := doubleNullTerminated(filepath.Clean(from))
+	if err != nil {
+		return err
+	}
+
+	fileOp := shFileOpStruct{
+		wFunc:  op,
+		pFrom:  pFrom,
+		fFlags: flags,
+	}
+	if to != "" {
+		if fileOp.pTo, err = doubleNullTerminated(filepath.Clean(to)); err != nil {
+			return err
+		}
+	}
+
+	if ret, _, _ := procSHFileOperation.Call(uintptr(unsafe.Pointer(&fileOp))); ret != 0 {
+		return fmt.Errorf("shell file operation failed with code 0x%X", ret)
+	}
+	if fileOp.fAnyOperationsAborted != 0 {
+		return errors.New("file operation was cancelled")
+	}
+
+	return nil
+}
+
+// doubleNullTerminated encodes a path list as SHFileOperation expects
+func doubleNullTerminated(path string) (*uint16, error) {
+	encoded, err := syscall.UTF16FromString(path)
+	if err != nil {
+		return nil, err
+	}
+	encoded = append(encoded, 0)
+	return &encoded[0], nil
+}
@@ -0,0 +1,303 @@
+package library
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNoFastPath is returned when a format has no header-only estimator and
+// the file must be decoded to learn its duration
+var ErrNoFastPath = errors.New("no fast duration estimate for format")
+
+// mp3HeaderScan bounds how far past the ID3 tag the first frame is searched
+const mp3HeaderScan = 64 * 1024
+
+// StreamInfo holds technical details read from file headers
+type StreamInfo struct {
+	Duration   time.Duration
+	SampleRate int
+	Channels   int
+	BitDepth   int
+	Bitrate    int // bits per second
+}
+
+// EstimateStreamInfo reads duration and format details from frame headers
+// or stream info blocks without decoding audio. Only a few kilobytes are
+// read, which keeps scans of network shares cheap.
+func EstimateStreamInfo(path string) (*StreamInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		return mp3StreamInfo(file, stat.Size())
+	case ".flac":
+		return flacStreamInfo(file)
+	case ".wav":
+		return wavStreamInfo(file)
+	default:
+		return nil, ErrNoFastPath
+	}
+}
+
+var (
+	mp3Bitrates = map[[2]int][]int{
+		{1, 1}: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{1, 2}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{1, 3}: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		{2, 1}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{2, 2}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{2, 3}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	}
+	mp3SampleRates = []int{44100, 48000, 32000}
+)
+
+// mp3Frame is a parsed MPEG audio frame header
+type mp3Frame struct {
+	version         int // 1 = MPEG-1, 2 = MPEG-2, 3 = MPEG-2.5
+	layer           int
+	bitrate         int // kbps
+	sampleRate      int
+	channels        int
+	samplesPerFrame int
+	length          int // bytes
+}
+
+func parseMP3Frame(h []byte) (*mp3Frame, bool) {
+	if len(h) < 4 || h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return nil, false
+	}
+
+	frame := &mp3Frame{}
+	switch (h[1] >> 3) & 0x03 {
+	case 3:
+		frame.version = 1
+	case 2:
+		frame.version = 2
+	case 0:
+		frame.version = 3
+	default:
+		return nil, false
+	}
+
+	layerBits := (h[1] >> 1) & 0x03
+	if layerBits == 0 {
+		return nil, false
+	}
+	frame.layer = 4 - int(layerBits)
+
+	bitrateIndex := int(h[2] >> 4)
+	sampleRateIndex := int((h[2] >> 2) & 0x03)
+	if bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return nil, false
+	}
+
+	table := frame.version
+	if table == 3 {
+		table = 2
+	}
+	frame.bitrate = mp3Bitrates[[2]int{table, frame.layer}][bitrateIndex]
+	frame.sampleRate = mp3SampleRates[sampleRateIndex] >> (frame.version - 1)
+
+	frame.channels = 2
+	if h[3]>>6 == 3 {
+		frame.channels = 1
+	}
+
+	padding := int((h[2] >> 1) & 0x01)
+	switch {
+	case frame.layer == 1:
+		frame.samplesPerFrame = 384
+		frame.length = (12*frame.bitrate*1000/frame.sampleRate + padding) * 4
+	case frame.layer == 3 && frame.version != 1:
+		frame.samplesPerFrame = 576
+		frame.length = 72*frame.bitrate*1000/frame.sampleRate + padding
+	default:
+		frame.samplesPerFrame = 1152
+		frame.length = 144*frame.bitrate*1000/frame.sampleRate + padding
+	}
+
+	return frame, frame.length > 4
+}
+
+func mp3StreamInfo(file *os.File, size int64) (*StreamInfo, error) {
+	start := int64(0)
+	header := make([]byte, 10)
+	if _, err := file.ReadAt(header, 0); err == nil && bytes.Equal(header[:3], []byte("ID3")) {
+		start = 10 + (int64(header[6])<<21 | int64(header[7])<<14 | int64(header[8])<<7 | int64(header[9]))
+		if header[5]&0x10 != 0 {
+			start += 10 // Footer present
+		}
+	}
+
+	end := size
+	trailer := make([]byte, 3)
+	if size >= 128 {
+		if _, err := file.ReadAt(trailer, size-128); err == nil && bytes.Equal(trailer, []byte("TAG")) {
+			end -= 128
+		}
+	}
+
+	data := make([]byte, mp3HeaderScan)
+	n, err := file.ReadAt(data, start)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	data = data[:n]
+
+	// Find the first frame whose successor is also a valid frame, to avoid
+	// false syncs inside padding or junk data
+	for i := 0; i+4 <= len(data); i++ {
+		frame, ok := parseMP3Frame(data[i:])
+		if !ok {
+			continue
+		}
+		next := i + frame.length
+		if next+4 <= len(data) {
+			if _, ok := parseMP3Frame(data[next:]); !ok {
+				continue
+			}
+		}
+
+		info := &StreamInfo{
+			SampleRate: frame.sampleRate,
+			Channels:   frame.channels,
+			BitDepth:   16,
+		}
+
+		audioBytes := end - start - int64(i)
+		if frames := mp3VBRFrameCount(data[i:], frame); frames > 0 {
+			samples := int64(frames) * int64(frame.samplesPerFrame)
+			info.Duration = time.Duration(samples) * time.Second / time.Duration(frame.sampleRate)
+			if seconds := info.Duration.Seconds(); seconds > 0 {
+				info.Bitrate = int(float64(audioBytes*8) / seconds)
+			}
+		} else {
+			info.Bitrate = frame.bitrate * 1000
+			info.Duration = time.Duration(audioBytes * 8 * int64(time.Second) / int64(info.Bitrate))
+		}
+
+		return info, nil
+	}
+
+	return nil, ErrNoFastPath
+}
+
+// mp3VBRFrameCount reads the frame count from a Xing/Info or VBRI header
+func mp3VBRFrameCount(data []byte, frame *mp3Frame) int {
+	// Xing header position depends on MPEG version and channel mode
+	offset := 4 + 32
+	switch {
+	case frame.version == 1 && frame.channels == 1:
+		offset = 4 + 17
+	case frame.version != 1 && frame.channels == 2:
+		offset = 4 + 17
+	case frame.version != 1:
+		offset = 4 + 9
+	}
+
+	if offset+12 <= len(data) {
+		tag := data[offset : offset+4]
+		if bytes.Equal(tag, []byte("Xing")) || bytes.Equal(tag, []byte("Info")) {
+			flags := binary.BigEndian.Uint32(data[offset+4:])
+			if flags&0x01 != 0 {
+				return int(binary.BigEndian.Uint32(data[offset+8:]))
+			}
+		}
+	}
+
+	// Fraunhofer VBRI header sits at a fixed offset
+	if 36+18 <= len(data) && bytes.Equal(data[36:40], []byte("VBRI")) {
+		return int(binary.BigEndian.Uint32(data[36+14:]))
+	}
+
+	return 0
+}
+
+func flacStreamInfo(file *os.File) (*StreamInfo, error) {
+	// Marker, block header and the 34-byte STREAMINFO block
+	data := make([]byte, 4+4+34)
+	if _, err := io.ReadFull(file, data); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(data[:4], []byte("fLaC")) || data[4]&0x7F != 0 {
+		return nil, ErrNoFastPath
+	}
+
+	block := data[8:]
+	sampleRate := int(block[10])<<12 | int(block[11])<<4 | int(block[12])>>4
+	channels := int((block[12]>>1)&0x07) + 1
+	bitDepth := int((block[12]&0x01)<<4|block[13]>>4) + 1
+	totalSamples := int64(block[13]&0x0F)<<32 | int64(binary.BigEndian.Uint32(block[14:18]))
+
+	if sampleRate == 0 || totalSamples == 0 {
+		return nil, ErrNoFastPath
+	}
+
+	return &StreamInfo{
+		Duration:   time.Duration(totalSamples) * time.Second / time.Duration(sampleRate),
+		SampleRate: sampleRate,
+		Channels:   channels,
+		BitDepth:   bitDepth,
+	}, nil
+}
+
+func wavStreamInfo(file *os.File) (*StreamInfo, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:4], []byte("RIFF")) || !bytes.Equal(header[8:12], []byte("WAVE")) {
+		return nil, ErrNoFastPath
+	}
+
+	info := &StreamInfo{}
+	byteRate := 0
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(file, chunk); err != nil {
+			return nil, ErrNoFastPath
+		}
+		id := string(chunk[:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:]))
+
+		switch id {
+		case "fmt ":
+			fmtData := make([]byte, 16)
+			if _, err := io.ReadFull(file, fmtData); err != nil {
+				return nil, err
+			}
+			info.Channels = int(binary.LittleEndian.Uint16(fmtData[2:]))
+			info.SampleRate = int(binary.LittleEndian.Uint32(fmtData[4:]))
+			byteRate = int(binary.LittleEndian.Uint32(fmtData[8:]))
+			info.BitDepth = int(binary.LittleEndian.Uint16(fmtData[14:]))
+			info.Bitrate = byteRate * 8
+			size -= 16
+		case "data":
+			if byteRate == 0 {
+				return nil, ErrNoFastPath
+			}
+			info.Duration = time.Duration(size * int64(time.Second) / int64(byteRate))
+			return info, nil
+		}
+
+		// Chunks are word aligned
+		if _, err := file.Seek(size+size%2, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
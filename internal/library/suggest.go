@@ -0,0 +1,372 @@
+package library
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// SuggestKind is what a suggestion names
+type SuggestKind string
+
+const (
+	SuggestArtist SuggestKind = "artist"
+	SuggestAlbum  SuggestKind = "album"
+	SuggestTitle  SuggestKind = "title"
+)
+
+const (
+	// suggestTrigramMin is the shortest query looked up by trigrams;
+	// shorter ones are matched against every entry, which is quick for
+	// a prefix test
+	suggestTrigramMin = 3
+
+	// suggestFuzzyMin is the shortest query allowed a typo, and
+	// suggestTwoTyposMin the shortest allowed two
+	suggestFuzzyMin    = 4
+	suggestTwoTyposMin = 8
+)
+
+// suggestKindWeight breaks near ties in favour of broader suggestions
+var suggestKindWeight = map[SuggestKind]float64{
+	SuggestArtist: 0.3,
+	SuggestAlbum:  0.2,
+	SuggestTitle:  0.1,
+}
+
+// Suggestion is an artist, album or title matching what has been typed so
+// far
+type Suggestion struct {
+	Kind    SuggestKind `json:"kind"`
+	Text    string      `json:"text"`
+	Artist  string      `json:"artist,omitempty"`  // Whose album or title it is
+	TrackID string      `json:"trackId,omitempty"` // A track with the title
+	Tracks  int         `json:"tracks"`            // How many tracks it covers
+	Typos   int         `json:"typos"`             // Edits between the query and the match
+	Score   float64     `json:"score"`
+}
+
+// suggestEntry is one artist, album or title in the index
+type suggestEntry struct {
+	kind   SuggestKind
+	text   string
+	artist string
+	folded []rune
+	starts []int // Where each word of folded starts
+	tracks map[string]bool
+}
+
+// SuggestIndex suggests artists, albums and titles as a search is typed,
+// from prefixes of any of their words, tolerating small typos. It is held
+// in memory and kept up to date track by track.
+type SuggestIndex struct {
+	mu       sync.RWMutex
+	entries  map[string]*suggestEntry
+	trigrams map[string]map[string]bool // Trigram to the keys of the entries with it
+	byTrack  map[string][]string        // Track ID to the keys of its entries
+}
+
+// NewSuggestIndex creates an empty suggestion index
+func NewSuggestIndex() *SuggestIndex {
+	return &SuggestIndex{
+		entries:  make(map[string]*suggestEntry),
+		trigrams: make(map[string]map[string]bool),
+		byTrack:  make(map[string][]string),
+	}
+}
+
+// Build replaces the index's contents with the tracks'
+func (x *SuggestIndex) Build(tracks []*domain.Track) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.entries = make(map[string]*suggestEntry)
+	x.trigrams = make(map[string]map[string]bool)
+	x.byTrack = make(map[string][]string)
+	for _, track := range tracks {
+		x.add(track)
+	}
+}
+
+// Update indexes a track, replacing what was indexed for it before
+func (x *SuggestIndex) Update(track *domain.Track) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.remove(track.ID)
+	x.add(track)
+}
+
+// Remove drops a track from the index
+func (x *SuggestIndex) Remove(trackID string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.remove(trackID)
+}
+
+// Len returns how many artists, albums and titles are indexed
+func (x *SuggestIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.entries)
+}
+
+func (x *SuggestIndex) add(track *domain.Track) {
+	if track.Transient {
+		return
+	}
+
+	artist := track.AlbumArtist
+	if artist == "" {
+		artist = track.Artist
+	}
+	x.addEntry(track.ID, SuggestArtist, track.Artist, "")
+	if track.AlbumArtist != track.Artist {
+		x.addEntry(track.ID, SuggestArtist, track.AlbumArtist, "")
+	}
+	x.addEntry(track.ID, SuggestAlbum, track.Album, artist)
+	x.addEntry(track.ID, SuggestTitle, track.Title, track.Artist)
+}
+
+func (x *SuggestIndex) addEntry(trackID string, kind SuggestKind, text, artist string) {
+	text = strings.TrimSpace(text)
+	folded := domain.FoldText(text)
+	if folded == "" {
+		return
+	}
+
+	key := string(kind) + "\x00" + folded + "\x00" + domain.FoldText(artist)
+	entry, ok := x.entries[key]
+	if !ok {
+		entry = &suggestEntry{
+			kind:   kind,
+			text:   text,
+			artist: artist,
+			folded: []rune(folded),
+			tracks: make(map[string]bool),
+		}
+		entry.starts = wordStarts(entry.folded)
+		x.entries[key] = entry
+		for _, gram := range trigrams(entry.folded) {
+			if x.trigrams[gram] == nil {
+				x.trigrams[gram] = make(map[string]bool)
+			}
+			x.trigrams[gram][key] = true
+		}
+	}
+	if !entry.tracks[trackID] {
+		entry.tracks[trackID] = true
+		x.byTrack[trackID] = append(x.byTrack[trackID], key)
+	}
+}
+
+func (x *SuggestIndex) remove(trackID string) {
+	for _, key := range x.byTrack[trackID] {
+		entry := x.entries[key]
+		delete(entry.tracks, trackID)
+		if len(entry.tracks) > 0 {
+			continue
+		}
+
+		delete(x.entries, key)
+		for _, gram := range trigrams(entry.folded) {
+			delete(x.trigrams[gram], key)
+			if len(x.trigrams[gram]) == 0 {
+				delete(x.trigrams, gram)
+			}
+		}
+	}
+	delete(x.byTrack, trackID)
+}
+
+// Suggest returns up to limit artists, albums and titles that a word of,
+// or run of words in, starts with the query, best first. Queries of
+// suggestFuzzyMin runes or more may be a typo away from the match.
+func (x *SuggestIndex) Suggest(query string, limit int) []Suggestion {
+	needle := []rune(domain.FoldText(strings.TrimSpace(query)))
+	if len(needle) == 0 || limit <= 0 {
+		return nil
+	}
+
+	typos := 0
+	switch {
+	case len(needle) >= suggestTwoTyposMin:
+		typos = 2
+	case len(needle) >= suggestFuzzyMin:
+		typos = 1
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var suggestions []Suggestion
+	for _, entry := range x.candidates(needle) {
+		distance, ok := entry.match(needle, typos)
+		if !ok {
+			continue
+		}
+
+		// An exact start beats a word inside, which beats a typo
+		score := 2.0
+		if distance == 0 && hasRunePrefix(entry.folded, needle) {
+			score = 3
+		}
+		score -= float64(distance)
+		score += suggestKindWeight[entry.kind] + 0.1*math.Log1p(float64(len(entry.tracks)))
+
+		suggestion := Suggestion{
+			Kind:   entry.kind,
+			Text:   entry.text,
+			Artist: entry.artist,
+			Tracks: len(entry.tracks),
+			Typos:  distance,
+			Score:  score,
+		}
+		if entry.kind == SuggestTitle {
+			suggestion.TrackID = anyTrack(entry.tracks)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		if suggestions[i].Text != suggestions[j].Text {
+			return suggestions[i].Text < suggestions[j].Text
+		}
+		return suggestions[i].Artist < suggestions[j].Artist
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// candidates returns the entries that might match a query: those sharing a
+// trigram with it, or every entry for queries too short to have one
+func (x *SuggestIndex) candidates(needle []rune) []*suggestEntry {
+	if len(needle) < suggestTrigramMin {
+		entries := make([]*suggestEntry, 0, len(x.entries))
+		for _, entry := range x.entries {
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	seen := make(map[string]bool)
+	var entries []*suggestEntry
+	for _, gram := range trigrams(needle) {
+		for key := range x.trigrams[gram] {
+			if !seen[key] {
+				seen[key] = true
+				entries = append(entries, x.entries[key])
+			}
+		}
+	}
+	return entries
+}
+
+// match reports whether the entry has a word from which it starts with
+// needle, give or take typos edits, and how many edits the closest takes
+func (e *suggestEntry) match(needle []rune, typos int) (int, bool) {
+	best := typos + 1
+	for _, start := range e.starts {
+		rest := e.folded[start:]
+		if hasRunePrefix(rest, needle) {
+			return 0, true
+		}
+		if typos == 0 {
+			continue
+		}
+
+		// The typed text may be a letter short of or past the matching
+		// prefix, as after a dropped or doubled letter
+		for n := len(needle) - typos; n <= len(needle)+typos; n++ {
+			if n < 1 || n > len(rest) {
+				continue
+			}
+			best = min(best, editDistance(needle, rest[:n]))
+		}
+	}
+	return best, best <= typos
+}
+
+// wordStarts returns where each word of folded text starts
+func wordStarts(folded []rune) []int {
+	var starts []int
+	for i, r := range folded {
+		if isWordRune(r) && (i == 0 || !isWordRune(folded[i-1])) {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// trigrams returns the three-rune runs of each word of folded text, with a
+// word's first two runes led by a space so prefixes carry the most weight
+func trigrams(folded []rune) []string {
+	var grams []string
+	for _, word := range strings.FieldsFunc(string(folded), func(r rune) bool { return !isWordRune(r) }) {
+		padded := []rune(" " + word)
+		for i := 0; i+3 <= len(padded); i++ {
+			grams = append(grams, string(padded[i:i+3]))
+		}
+	}
+	return grams
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// the insertions, deletions, substitutions and swaps of neighbours that
+// turn one into the other
+func editDistance(a, b []rune) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
+
+func hasRunePrefix(s, prefix []rune) bool {
+	if len(prefix) > len(s) {
+		return false
+	}
+	for i, r := range prefix {
+		if s[i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// anyTrack returns the least track ID of a set, so the choice is stable
+func anyTrack(tracks map[string]bool) string {
+	first := ""
+	for id := range tracks {
+		if first == "" || id < first {
+			first = id
+		}
+	}
+	return first
+}
@@ -0,0 +1,85 @@
+package library
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestSuggestIndex(t *testing.T) {
+	index := NewSuggestIndex()
+	index.Build([]*domain.Track{
+		{ID: "1", Title: "Hyperballad", Artist: "Björk", Album: "Post"},
+		{ID: "2", Title: "Army of Me", Artist: "Björk", Album: "Post"},
+		{ID: "3", Title: "Wish You Were Here", Artist: "Pink Floyd", Album: "Wish You Were Here"},
+		{ID: "4", Title: "Time", Artist: "Pink Floyd", Album: "The Dark Side of the Moon"},
+		{ID: "5", Title: "Postcards", Artist: "Someone", Album: "Letters"},
+	})
+	assert.Equal(t, 12, index.Len())
+
+	texts := func(suggestions []Suggestion) []string {
+		texts := make([]string, len(suggestions))
+		for i, s := range suggestions {
+			texts[i] = string(s.Kind) + ":" + s.Text
+		}
+		return texts
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"prefix", "po", []string{"album:Post", "title:Postcards"}},
+		{"accents", "bjo", []string{"artist:Björk"}},
+		{"any word", "dark", []string{"album:The Dark Side of the Moon"}},
+		{"several words", "pink fl", []string{"artist:Pink Floyd"}},
+		{"swapped letters", "bjrok", []string{"artist:Björk"}},
+		{"wrong letter", "hypertallad", []string{"title:Hyperballad"}},
+		{"dropped letter", "flyd", []string{"artist:Pink Floyd"}},
+		{"too short for a typo", "pnk", []string{}},
+		{"nothing", "zzzz", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, texts(index.Suggest(tt.query, 10)))
+		})
+	}
+
+	// Exact matches outrank typos, and the limit keeps the best
+	suggestions := index.Suggest("wish", 1)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, SuggestAlbum, suggestions[0].Kind)
+	assert.Zero(t, suggestions[0].Typos)
+
+	title := index.Suggest("army", 5)
+	require.Len(t, title, 1)
+	assert.Equal(t, "2", title[0].TrackID)
+	assert.Equal(t, "Björk", title[0].Artist)
+}
+
+func TestSuggestIndexUpdates(t *testing.T) {
+	index := NewSuggestIndex()
+	a := &domain.Track{ID: "a", Title: "One", Artist: "Band", Album: "Debut"}
+	b := &domain.Track{ID: "b", Title: "Two", Artist: "Band", Album: "Debut"}
+	index.Build([]*domain.Track{a, b})
+
+	// An entry stays while any track still has it
+	index.Remove("a")
+	assert.Empty(t, index.Suggest("one", 5))
+	require.Len(t, index.Suggest("debut", 5), 1)
+	assert.Equal(t, 1, index.Suggest("debut", 5)[0].Tracks)
+
+	// Retagging replaces the old names
+	b.Album = "Sophomore"
+	index.Update(b)
+	assert.Empty(t, index.Suggest("debut", 5))
+	assert.Len(t, index.Suggest("soph", 5), 1)
+
+	index.Remove("b")
+	assert.Zero(t, index.Len())
+}
@@ -0,0 +1,291 @@
+package library
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// ErrTagWriteUnsupported is returned for files tags cannot be written to
+var ErrTagWriteUnsupported = errors.New("writing tags is not supported for this format")
+
+// tagWriter writes the tags of a track into a file, reading the original
+// from src and writing the complete new file to dst
+type tagWriter func(src *os.File, size int64, dst io.Writer, track *domain.Track) error
+
+var tagWriters = map[string]tagWriter{
+	".mp3":  writeID3Tags,
+	".flac": writeFLACTags,
+	".ogg":  writeOggTags,
+	".opus": writeOggTags,
+	".m4a":  writeMP4Tags,
+	".mp4":  writeMP4Tags,
+}
+
+// writtenTag is a tag the library edits, with the names each format keeps
+// it under. Tags under other names that mean the same, such as LABEL for
+// the publisher, are replaced too.
+type writtenTag struct {
+	id3    string   // ID3v2 frame
+	vorbis []string // Vorbis comment names, the first being written
+	mp4    []string // iTunes tag list items, the first being written; none when MP4 has no standard item
+	value  func(t *domain.Track) string
+	number bool // A track or disc number, kept with any total the file has
+}
+
+var writtenTags = []writtenTag{
+	{id3: "TIT2", vorbis: []string{"TITLE"}, mp4: []string{"\xa9nam"}, value: func(t *domain.Track) string { return t.Title }},
+	{id3: "TPE1", vorbis: []string{"ARTIST"}, mp4: []string{"\xa9ART"}, value: func(t *domain.Track) string { return t.Artist }},
+	{id3: "TALB", vorbis: []string{"ALBUM"}, mp4: []string{"\xa9alb"}, value: func(t *domain.Track) string { return t.Album }},
+	{id3: "TPE2", vorbis: []string{"ALBUMARTIST", "ALBUM ARTIST"}, mp4: []string{"aART"}, value: func(t *domain.Track) string { return t.AlbumArtist }},
+	{id3: "TCON", vorbis: []string{"GENRE"}, mp4: []string{"\xa9gen", "gnre"}, value: func(t *domain.Track) string { return t.Genre }},
+	{id3: "TDRC", vorbis: []string{"DATE", "YEAR"}, mp4: []string{"\xa9day"}, value: func(t *domain.Track) string { return positive(t.Year) }},
+	{id3: "TRCK", vorbis: []string{"TRACKNUMBER"}, mp4: []string{"trkn"}, value: func(t *domain.Track) string { return positive(t.TrackNumber) }, number: true},
+	{id3: "TPOS", vorbis: []string{"DISCNUMBER"}, mp4: []string{"disk"}, value: func(t *domain.Track) string { return positive(t.DiscNumber) }, number: true},
+	{id3: "TSST", vorbis: []string{"DISCSUBTITLE", "SETSUBTITLE"}, value: func(t *domain.Track) string { return t.DiscSubtitle }},
+	{id3: "TCOM", vorbis: []string{"COMPOSER"}, mp4: []string{"\xa9wrt"}, value: func(t *domain.Track) string { return t.Composer }},
+	{id3: "TPUB", vorbis: []string{"ORGANIZATION", "LABEL", "PUBLISHER"}, value: func(t *domain.Track) string { return t.Publisher }},
+	{id3: "COMM", vorbis: []string{"COMMENT"}, mp4: []string{"\xa9cmt"}, value: func(t *domain.Track) string { return t.Comment }},
+}
+
+func positive(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// withTotal keeps the total of a track or disc number as the file had it,
+// such as the 12 of "3/12"
+func withTotal(number, old string) string {
+	if _, total, ok := strings.Cut(old, "/"); ok && number != "" && total != "" {
+		return number + "/" + total
+	}
+	return number
+}
+
+// CanWriteTags reports whether tags can be written into a track's file
+func CanWriteTags(track *domain.Track) bool {
+	_, ok := tagWriters[strings.ToLower(filepath.Ext(track.FilePath))]
+	return ok && track.GetSource().Kind == domain.SourceFile
+}
+
+// WriteTags writes the tags the library edits into a track's file,
+// replacing those there; empty ones are removed. Other tags, art and
+// ReplayGain are kept. The track's file size and checksum are updated to
+// match the new file.
+func WriteTags(track *domain.Track) error {
+	if !CanWriteTags(track) {
+		return ErrTagWriteUnsupported
+	}
+	writer := tagWriters[strings.ToLower(filepath.Ext(track.FilePath))]
+	if err := rewriteFile(track.FilePath, func(src *os.File, size int64, dst io.Writer) error {
+		return writer(src, size, dst, track)
+	}); err != nil {
+		return err
+	}
+	syncFileInfo(track)
+	return nil
+}
+
+// writeID3Tags writes the tags as ID3v2 text frames, in UTF-16 in v2.3 tags
+// and UTF-8 in v2.4 ones. The year goes in TYER in v2.3 tags.
+func writeID3Tags(src *os.File, size int64, dst io.Writer, track *domain.Track) error {
+	old := make(map[string]string)
+	keep := func(id string, body []byte) bool {
+		switch id {
+		case "TYER", "TDAT", "TDRC":
+			return false
+		case "COMM":
+			// Comments with a description belong to other applications
+			return len(body) >= 4 && txxxDescription(append([]byte{body[0]}, body[4:]...)) != ""
+		}
+		for _, tag := range writtenTags {
+			if tag.id3 == id {
+				old[id] = txxxDescription(body)
+				return false
+			}
+		}
+		return true
+	}
+
+	return rewriteID3(src, size, dst, keep, func(version byte) []byte {
+		var frames []byte
+		for _, tag := range writtenTags {
+			value := tag.value(track)
+			if tag.number {
+				value = withTotal(value, old[tag.id3])
+			}
+			if value == "" {
+				continue
+			}
+
+			id := tag.id3
+			switch {
+			case id == "COMM":
+				body := []byte{id3TextEncoding(version)}
+				body = append(body, "eng"...)
+				body = append(body, id3Text("", version)[1:]...) // No description
+				body = append(body, id3Text(value, version)[1:]...)
+				frames = append(frames, id3Frame(id, body, version)...)
+				continue
+			case id == "TDRC" && version == 3:
+				id = "TYER"
+			}
+			frames = append(frames, id3Frame(id, id3Text(value, version), version)...)
+		}
+		return frames
+	})
+}
+
+func id3TextEncoding(version byte) byte {
+	if version == 4 {
+		return 3 // UTF-8
+	}
+	return 1 // UTF-16 with byte order mark
+}
+
+// id3Text encodes the body of a text frame, terminated so it can also be
+// used for the description of a comment
+func id3Text(text string, version byte) []byte {
+	if version == 4 {
+		return append(append([]byte{3}, text...), 0)
+	}
+	b := []byte{1, 0xFF, 0xFE}
+	for _, unit := range utf16.Encode([]rune(text)) {
+		b = binary.LittleEndian.AppendUint16(b, unit)
+	}
+	return append(b, 0, 0)
+}
+
+// writeFLACTags writes the tags as Vorbis comments, adding a comment block
+// after STREAMINFO if there is none
+func writeFLACTags(src *os.File, size int64, dst io.Writer, track *domain.Track) error {
+	return rewriteFLACComments(src, size, dst, withTagComments(track))
+}
+
+// writeOggTags writes the tags as the Vorbis comments of an Ogg Vorbis or
+// Opus stream
+func writeOggTags(src *os.File, size int64, dst io.Writer, track *domain.Track) error {
+	return rewriteOggComments(src, size, dst, func(packet []byte) ([]byte, error) {
+		return editOggComments(packet, withTagComments(track))
+	})
+}
+
+// withTagComments returns an edit replacing the comments of a Vorbis
+// comment block the library edits
+func withTagComments(track *domain.Track) func([][]byte) [][]byte {
+	return func(comments [][]byte) [][]byte {
+		old := make(map[string]string)
+		kept := comments[:0]
+		for _, comment := range comments {
+			key, value, _ := strings.Cut(string(comment), "=")
+			if tag := vorbisTag(key); tag != nil {
+				old[tag.vorbis[0]] = value
+				continue
+			}
+			kept = append(kept, comment)
+		}
+
+		for _, tag := range writtenTags {
+			value := tag.value(track)
+			if tag.number {
+				value = withTotal(value, old[tag.vorbis[0]])
+			}
+			if value != "" {
+				kept = append(kept, []byte(tag.vorbis[0]+"="+value))
+			}
+		}
+		return kept
+	}
+}
+
+// vorbisTag returns the written tag a Vorbis comment name belongs to
+func vorbisTag(key string) *writtenTag {
+	for i, tag := range writtenTags {
+		for _, name := range tag.vorbis {
+			if strings.EqualFold(key, name) {
+				return &writtenTags[i]
+			}
+		}
+	}
+	return nil
+}
+
+// writeMP4Tags writes the tags as items of the iTunes tag list. MP4 has no
+// standard items for the publisher or disc subtitle, so those are left as
+// they are.
+func writeMP4Tags(src *os.File, size int64, dst io.Writer, track *domain.Track) error {
+	return rewriteMP4Tags(src, size, dst, func(ilst *mp4Atom) {
+		totals := make(map[string][]byte)
+		items := ilst.children[:0]
+		for _, item := range ilst.children {
+			tag := mp4Tag(item.kind)
+			if tag == nil {
+				items = append(items, item)
+				continue
+			}
+			// Number items hold the number and total as 16-bit integers
+			// after the data atom's 16-byte header
+			if tag.number && len(item.data) >= 22 {
+				totals[item.kind] = item.data[20:22]
+			}
+		}
+
+		for _, tag := range writtenTags {
+			if len(tag.mp4) == 0 {
+				continue
+			}
+			value := tag.value(track)
+			if value == "" {
+				continue
+			}
+			kind := tag.mp4[0]
+			if tag.number {
+				number, _ := strconv.Atoi(value)
+				payload := binary.BigEndian.AppendUint16([]byte{0, 0}, uint16(number))
+				total := totals[kind]
+				if total == nil {
+					total = []byte{0, 0}
+				}
+				payload = append(payload, total...)
+				if kind == "trkn" {
+					payload = append(payload, 0, 0)
+				}
+				items = append(items, &mp4Atom{kind: kind, data: mp4Data(0, payload)})
+				continue
+			}
+			items = append(items, &mp4Atom{kind: kind, data: mp4Data(1, []byte(value))})
+		}
+		ilst.children = items
+	})
+}
+
+// mp4Tag returns the written tag an iTunes tag list item belongs to
+func mp4Tag(kind string) *writtenTag {
+	for i, tag := range writtenTags {
+		for _, name := range tag.mp4 {
+			if kind == name {
+				return &writtenTags[i]
+			}
+		}
+	}
+	return nil
+}
+
+// mp4Data builds the data atom of a tag list item: type 1 for UTF-8 text
+// and 0 for binary values such as track numbers
+func mp4Data(dataType uint32, payload []byte) []byte {
+	data := binary.BigEndian.AppendUint32(nil, uint32(16+len(payload)))
+	data = append(data, "data"...)
+	data = binary.BigEndian.AppendUint32(data, dataType)
+	data = binary.BigEndian.AppendUint32(data, 0) // Locale
+	return append(data, payload...)
+}
@@ -0,0 +1,74 @@
+package library
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dhowden/tag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestWriteTags(t *testing.T) {
+	dir := t.TempDir()
+	audio := []byte{0xFF, 0xFB, 0x90, 0x00, 1, 2, 3, 4}
+	streamInfo := append([]byte{0, 0, 0, 34}, make([]byte, 34)...)
+	id3v24 := append(append([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 35},
+		id3Frame("TRCK", []byte("\x033/12"), 4)...), id3Frame("TXXX", []byte("\x03MOOD\x00calm"), 4)...)
+
+	oggAudio := paginateOgg([][]byte{audio}, 1, 2)[0].bytes()
+
+	tests := []struct {
+		name  string
+		ext   string
+		file  []byte
+		audio []byte // How the file ends, unchanged
+		total int    // Track total kept from the file
+		kept  string // Tag text that must survive
+	}{
+		{"ID3 without tag", ".mp3", audio, audio, 0, ""},
+		{"ID3 with tag", ".mp3", append(id3v24, audio...), audio, 12, "calm"},
+		{"FLAC without comments", ".flac", append(append(append([]byte("fLaC"), 0x80|streamInfo[0]), streamInfo[1:]...), audio...), audio, 0, ""},
+		{"FLAC with comments", ".flac", flacFile(audio, "TRACKNUMBER=3", "TRACKTOTAL=12", "MOOD=quiet"), audio, 12, "MOOD=quiet"},
+		{"Ogg Vorbis", ".ogg", oggVorbisFile(oggAudio, "TRACKNUMBER=3", "TRACKTOTAL=12", "MOOD=quiet"), oggAudio, 12, "MOOD=quiet"},
+		{"MP4", ".m4a", append([]byte("\x00\x00\x00\x10ftypM4A \x00\x00\x00\x00\x00\x00\x00\x08moov\x00\x00\x00\x10mdat"), audio...), audio, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "song"+tt.ext)
+			require.NoError(t, os.WriteFile(path, tt.file, 0o644))
+
+			// Writing twice replaces the first tags rather than adding to them
+			track := &domain.Track{FilePath: path, Title: "Old", Artist: "Someone", TrackNumber: 3, Comment: "Gone"}
+			require.NoError(t, WriteTags(track))
+			track.Title, track.Album, track.Year, track.TrackNumber, track.Comment = "New Title", "Album", 1999, 7, ""
+			require.NoError(t, WriteTags(track))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.True(t, bytes.HasSuffix(data, tt.audio))
+			assert.NotContains(t, string(data), "Old")
+			assert.NotContains(t, string(data), "Gone")
+			assert.Contains(t, string(data), tt.kept)
+
+			// Other readers see the new tags
+			m, err := tag.ReadFrom(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, "New Title", m.Title())
+			assert.Equal(t, "Album", m.Album())
+			assert.Equal(t, "Someone", m.Artist())
+			assert.Equal(t, 1999, m.Year())
+			number, total := m.Track()
+			assert.Equal(t, 7, number)
+			assert.Equal(t, tt.total, total)
+			assert.Empty(t, m.Comment())
+		})
+	}
+
+	assert.ErrorIs(t, WriteTags(&domain.Track{FilePath: filepath.Join(dir, "song.wav")}), ErrTagWriteUnsupported)
+}
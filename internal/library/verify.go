@@ -0,0 +1,289 @@
+package library
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/audio/decoder"
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// durationTolerance is how far a decoded duration may drift from the stored
+// one before the file is considered truncated
+const durationTolerance = 2 * time.Second
+
+// ComputeChecksum returns a SHA-1 of the audio payload of a file. Leading and
+// trailing tag blocks are skipped where the format allows, so editing tags
+// does not change the checksum.
+func ComputeChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	start, end, err := audioPayloadRange(file, path, info.Size())
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha1.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, start, end-start)); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// audioPayloadRange returns the byte range holding audio data
+func audioPayloadRange(file *os.File, path string, size int64) (int64, int64, error) {
+	start, end := int64(0), size
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		header := make([]byte, 10)
+		if _, err := file.ReadAt(header, 0); err == nil && bytes.Equal(header[:3], []byte("ID3")) {
+			tagSize := int64(header[6])<<21 | int64(header[7])<<14 | int64(header[8])<<7 | int64(header[9])
+			start = 10 + tagSize
+		}
+		if size >= 128 {
+			trailer := make([]byte, 3)
+			if _, err := file.ReadAt(trailer, size-128); err == nil && bytes.Equal(trailer, []byte("TAG")) {
+				end = size - 128
+			}
+		}
+
+	case ".flac":
+		// Skip the stream marker and all metadata blocks
+		offset := int64(4)
+		header := make([]byte, 4)
+		for {
+			if _, err := file.ReadAt(header, offset); err != nil {
+				return 0, 0, fmt.Errorf("%w: truncated FLAC metadata", domain.ErrTrackCorrupted)
+			}
+			length := int64(binary.BigEndian.Uint32(header) & 0x00FFFFFF)
+			offset += 4 + length
+			if header[0]&0x80 != 0 {
+				break
+			}
+		}
+		start = offset
+	}
+
+	if start > end {
+		return 0, 0, fmt.Errorf("%w: tag data exceeds file size", domain.ErrTrackCorrupted)
+	}
+
+	return start, end, nil
+}
+
+// VerifyResult summarises a library verification run
+type VerifyResult struct {
+	Checked  int
+	Valid    int
+	Invalid  int
+	Repaired int // Previously invalid tracks that now verify
+	Duration time.Duration
+}
+
+// Verifier checks library files for silent corruption or truncation and
+// records the outcome on each track
+type Verifier struct {
+	trackRepo domain.TrackRepository
+
+	isRunning  bool
+	cancelFunc context.CancelFunc
+	progress   float64
+
+	mu sync.RWMutex
+}
+
+// NewVerifier creates a new library verifier
+func NewVerifier(trackRepo domain.TrackRepository) *Verifier {
+	return &Verifier{
+		trackRepo: trackRepo,
+	}
+}
+
+// Verify checks every track in the library. Tracks without a stored
+// checksum get one computed; tracks with one are compared against it.
+func (v *Verifier) Verify(ctx context.Context) (*VerifyResult, error) {
+	v.mu.Lock()
+	if v.isRunning {
+		v.mu.Unlock()
+		return nil, fmt.Errorf("verification already in progress")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	v.isRunning = true
+	v.cancelFunc = cancel
+	v.progress = 0
+	v.mu.Unlock()
+
+	defer func() {
+		cancel()
+		v.mu.Lock()
+		v.isRunning = false
+		v.cancelFunc = nil
+		v.progress = 100
+		v.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	result := &VerifyResult{}
+
+	tracks, err := v.trackRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, track := range tracks {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		if track.IsNetworkPath() {
+			continue
+		}
+
+		wasValid := track.IsValid
+		if err := v.VerifyTrack(track); err != nil {
+			track.MarkInvalid(err.Error())
+			result.Invalid++
+			logger.Warn("Track failed verification",
+				logger.String("path", track.FilePath),
+				logger.Error(err))
+		} else {
+			track.MarkValid()
+			result.Valid++
+			if !wasValid {
+				result.Repaired++
+			}
+		}
+		result.Checked++
+
+		if err := v.trackRepo.UpdateStatus(track); err != nil {
+			logger.Warn("Failed to save verification result",
+				logger.String("path", track.FilePath),
+				logger.Error(err))
+		}
+
+		v.mu.Lock()
+		v.progress = float64(i+1) / float64(len(tracks)) * 100
+		v.mu.Unlock()
+	}
+
+	result.Duration = time.Since(startTime)
+
+	logger.Info("Library verification completed",
+		logger.Int("checked", result.Checked),
+		logger.Int("invalid", result.Invalid),
+		logger.Int("repaired", result.Repaired),
+		logger.Duration("duration", result.Duration),
+	)
+
+	return result, nil
+}
+
+// VerifyTrack checks a single file. It sets the track checksum if none is
+// stored and returns an error describing any problem found.
+func (v *Verifier) VerifyTrack(track *domain.Track) error {
+	info, err := os.Stat(track.FilePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return domain.ErrFileNotFound
+		}
+		return fmt.Errorf("%w: %v", domain.ErrFileAccessDenied, err)
+	}
+
+	if track.FileSize > 0 && info.Size() < track.FileSize {
+		return fmt.Errorf("%w: file shrank from %d to %d bytes", domain.ErrTrackCorrupted, track.FileSize, info.Size())
+	}
+
+	checksum, err := ComputeChecksum(track.FilePath)
+	if err != nil {
+		return err
+	}
+	if track.Checksum == "" {
+		track.Checksum = checksum
+	} else if track.Checksum != checksum {
+		return fmt.Errorf("%w: checksum mismatch", domain.ErrTrackCorrupted)
+	}
+
+	return verifyDecode(track)
+}
+
+// verifyDecode opens the file and decodes its start and end to catch
+// damaged headers and truncated audio
+func verifyDecode(track *domain.Track) error {
+	dec, err := decoder.CreateDecoderForFile(track.FilePath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrTrackCorrupted, err)
+	}
+	defer dec.Close()
+
+	duration := dec.Duration()
+	if track.Duration > 0 && duration < track.Duration-durationTolerance {
+		return fmt.Errorf("%w: truncated to %v of %v", domain.ErrTrackCorrupted, duration, track.Duration)
+	}
+
+	format := dec.Format()
+	buffer := make([]float32, 4096*max(format.Channels, 1))
+	if _, err := dec.Decode(buffer); err != nil && !errors.Is(err, decoder.ErrEndOfStream) {
+		return fmt.Errorf("%w: %v", domain.ErrTrackCorrupted, err)
+	}
+
+	// Decode the final second to make sure the tail is readable
+	if duration > time.Second {
+		if err := dec.Seek(duration - time.Second); err != nil && !errors.Is(err, decoder.ErrSeekNotSupported) {
+			return fmt.Errorf("%w: %v", domain.ErrTrackCorrupted, err)
+		}
+		if _, err := dec.Decode(buffer); err != nil && !errors.Is(err, decoder.ErrEndOfStream) {
+			return fmt.Errorf("%w: %v", domain.ErrTrackCorrupted, err)
+		}
+	}
+
+	return nil
+}
+
+// Cancel cancels a running verification
+func (v *Verifier) Cancel() {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if v.cancelFunc != nil {
+		v.cancelFunc()
+	}
+}
+
+// IsRunning returns whether a verification is in progress
+func (v *Verifier) IsRunning() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.isRunning
+}
+
+// GetProgress returns the verification progress (0-100)
+func (v *Verifier) GetProgress() float64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.progress
+}
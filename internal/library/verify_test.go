@@ -0,0 +1,129 @@
+package library
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestComputeChecksumSkipsTags(t *testing.T) {
+	dir := t.TempDir()
+	audio := []byte{0xFF, 0xFB, 0x90, 0x00, 1, 2, 3, 4, 5, 6, 7, 8}
+
+	tests := []struct {
+		name  string
+		ext   string
+		build func(tag string, audio []byte) []byte
+	}{
+		{"MP3", ".mp3", func(tag string, audio []byte) []byte {
+			id3 := append([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, byte(len(tag))}, tag...)
+			trailer := append([]byte("TAG"), make([]byte, 125)...)
+			copy(trailer[3:], tag)
+			return append(append(id3, audio...), trailer...)
+		}},
+		{"FLAC", ".flac", func(tag string, audio []byte) []byte {
+			file := append([]byte("fLaC"), 0, 0, 0, 34)
+			file = append(file, make([]byte, 34)...)
+			file = append(file, 0x84, 0, 0, byte(len(tag)))
+			return append(append(file, tag...), audio...)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "song"+tt.ext)
+			checksum := func(tag string, audio []byte) string {
+				require.NoError(t, os.WriteFile(path, tt.build(tag, audio), 0o644))
+				sum, err := ComputeChecksum(path)
+				require.NoError(t, err)
+				return sum
+			}
+
+			original := checksum("old title", audio)
+			assert.Len(t, original, 40)
+			assert.Equal(t, original, checksum("a much longer new title", audio), "retagging changed the checksum")
+
+			changed := append([]byte(nil), audio...)
+			changed[len(changed)-1]++
+			assert.NotEqual(t, original, checksum("old title", changed))
+		})
+	}
+}
+
+func TestComputeChecksumCorrupted(t *testing.T) {
+	dir := t.TempDir()
+
+	// An ID3 header claiming more data than the file holds
+	mp3 := filepath.Join(dir, "bad.mp3")
+	require.NoError(t, os.WriteFile(mp3, []byte{'I', 'D', '3', 3, 0, 0, 0, 0, 1, 0, 0xFF}, 0o644))
+	_, err := ComputeChecksum(mp3)
+	assert.ErrorIs(t, err, domain.ErrTrackCorrupted)
+
+	// FLAC metadata blocks that never end
+	flac := filepath.Join(dir, "bad.flac")
+	require.NoError(t, os.WriteFile(flac, append([]byte("fLaC"), 0, 0, 0, 34), 0o644))
+	_, err = ComputeChecksum(flac)
+	assert.ErrorIs(t, err, domain.ErrTrackCorrupted)
+}
+
+func TestVerifyTrack(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tone.wav")
+	writeWAV(t, path, 3*44100)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	checksum, err := ComputeChecksum(path)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		track domain.Track
+		err   error
+	}{
+		{"valid", domain.Track{FilePath: path, FileSize: info.Size(), Duration: 3 * time.Second, Checksum: checksum}, nil},
+		{"first check", domain.Track{FilePath: path, Duration: 3 * time.Second}, nil},
+		{"missing", domain.Track{FilePath: filepath.Join(dir, "gone.wav")}, domain.ErrFileNotFound},
+		{"shrank", domain.Track{FilePath: path, FileSize: info.Size() + 1000}, domain.ErrTrackCorrupted},
+		{"changed", domain.Track{FilePath: path, Checksum: "0123"}, domain.ErrTrackCorrupted},
+		{"truncated", domain.Track{FilePath: path, Duration: time.Minute}, domain.ErrTrackCorrupted},
+	}
+
+	v := NewVerifier(nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := tt.track
+			err := v.VerifyTrack(&track)
+			if tt.err == nil {
+				require.NoError(t, err)
+				assert.Equal(t, checksum, track.Checksum)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+// writeWAV writes a silent 16-bit stereo 44.1 kHz WAV file
+func writeWAV(t *testing.T, path string, frames int) {
+	data := make([]byte, frames*4)
+	b := []byte("RIFF")
+	b = binary.LittleEndian.AppendUint32(b, uint32(36+len(data)))
+	b = append(b, "WAVEfmt "...)
+	b = binary.LittleEndian.AppendUint32(b, 16)
+	b = binary.LittleEndian.AppendUint16(b, 1) // PCM
+	b = binary.LittleEndian.AppendUint16(b, 2)
+	b = binary.LittleEndian.AppendUint32(b, 44100)
+	b = binary.LittleEndian.AppendUint32(b, 44100*4)
+	b = binary.LittleEndian.AppendUint16(b, 4)
+	b = binary.LittleEndian.AppendUint16(b, 16)
+	b = append(b, "data"...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	require.NoError(t, os.WriteFile(path, append(b, data...), 0o644))
+}
@@ -0,0 +1,362 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// watchSettleDelay is how long a watch folder must be quiet before its
+// changes are applied, so files being copied in are read once, whole
+const watchSettleDelay = 2 * time.Second
+
+// TopicFilesChanged carries what each batch of watch folder changes did
+// to the library
+var TopicFilesChanged = events.NewTopic[*WatchChanges]("library:filesChanged")
+
+// WatchChanges is what the watcher changed in the library after a batch
+// of file system events
+type WatchChanges struct {
+	Added   []*domain.Track `json:"added"`
+	Updated []*domain.Track `json:"updated"`
+	Removed []*domain.Track `json:"removed"` // Flagged as missing
+}
+
+// Empty reports whether the batch changed nothing
+func (c *WatchChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Updated) == 0 && len(c.Removed) == 0
+}
+
+// Watcher keeps the library up to date with its watch folders as files
+// are added, changed and removed, one file at a time rather than by
+// rescanning. Each folder's patterns, recursion and hidden-file settings
+// apply as they do to a scan.
+type Watcher struct {
+	scanner   *Scanner
+	trackRepo domain.TrackRepository
+	fs        *fsnotify.Watcher
+	delay     time.Duration
+
+	mu      sync.Mutex
+	ctx     context.Context
+	folders map[string]*domain.WatchFolder // By pathKey of the folder
+	pending map[string]bool                // Paths changed since the last batch
+	timer   *time.Timer
+}
+
+// NewWatcher creates a watcher importing files with the scanner
+func NewWatcher(scanner *Scanner, trackRepo domain.TrackRepository) (*Watcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	return &Watcher{
+		scanner:   scanner,
+		trackRepo: trackRepo,
+		fs:        fsWatcher,
+		delay:     watchSettleDelay,
+		ctx:       context.Background(),
+		folders:   make(map[string]*domain.WatchFolder),
+		pending:   make(map[string]bool),
+	}, nil
+}
+
+// Start handles file system events until the context is done or the
+// watcher is closed
+func (w *Watcher) Start(ctx context.Context) {
+	w.mu.Lock()
+	w.ctx = ctx
+	w.mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-w.fs.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+					continue
+				}
+				w.queue(event.Name)
+			case err, ok := <-w.fs.Errors:
+				if !ok {
+					return
+				}
+				logger.Warn("File watcher error", logger.Error(err))
+			}
+		}
+	}()
+}
+
+// Close stops watching every folder
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+	return w.fs.Close()
+}
+
+// Watch starts watching a folder, and its subfolders when it is
+// recursive. Disabled folders are not watched.
+func (w *Watcher) Watch(folder *domain.WatchFolder) error {
+	if folder == nil || folder.Path == "" {
+		return domain.ErrInvalidLibraryPath
+	}
+	if !folder.IsEnabled {
+		return fmt.Errorf("watch folder is disabled: %s", folder.Path)
+	}
+
+	w.mu.Lock()
+	w.folders[pathKey(folder.Path)] = folder
+	w.mu.Unlock()
+
+	return w.addTree(folder, folder.Path)
+}
+
+// Unwatch stops watching a folder
+func (w *Watcher) Unwatch(path string) {
+	w.mu.Lock()
+	delete(w.folders, pathKey(path))
+	w.mu.Unlock()
+
+	for _, watched := range w.fs.WatchList() {
+		if isWithinAny(watched, []string{path}) {
+			w.fs.Remove(watched)
+		}
+	}
+}
+
+// addTree watches a folder and, for recursive watch folders, the
+// subfolders a scan would visit
+func (w *Watcher) addTree(folder *domain.WatchFolder, dir string) error {
+	opts := w.scanner.watchFolderOptions(folder)
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			logger.Warn("Error accessing path", logger.String("path", path), logger.Error(err))
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && !w.acceptsDir(opts, path, d) {
+			return filepath.SkipDir
+		}
+
+		if err := w.fs.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		if !opts.recursive {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// acceptsDir reports whether a watch folder's scan would visit a folder
+// below it
+func (w *Watcher) acceptsDir(opts scanOptions, path string, d fs.DirEntry) bool {
+	if !opts.recursive || d.Type()&os.ModeSymlink != 0 {
+		return false
+	}
+	if !opts.includeHidden && isHidden(path, d) {
+		return false
+	}
+	return !matchesAny(path, opts.excludePatterns)
+}
+
+// acceptsFile reports whether a watch folder's scan would import a file
+func (w *Watcher) acceptsFile(folder *domain.WatchFolder, opts scanOptions, path string, d fs.DirEntry) bool {
+	if !opts.recursive && pathKey(filepath.Dir(path)) != pathKey(folder.Path) {
+		return false
+	}
+	if !opts.includeHidden && isHidden(path, d) {
+		return false
+	}
+	return matchesAny(path, opts.filePatterns) && !matchesAny(path, opts.excludePatterns)
+}
+
+// folderFor returns the watch folder a path is in, the innermost when
+// folders are nested, or nil
+func (w *Watcher) folderFor(path string) *domain.WatchFolder {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var found *domain.WatchFolder
+	for _, folder := range w.folders {
+		if isWithinAny(path, []string{folder.Path}) && (found == nil || len(folder.Path) > len(found.Path)) {
+			found = folder
+		}
+	}
+	return found
+}
+
+// queue notes a changed path and puts off applying the batch until the
+// folders settle
+func (w *Watcher) queue(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending[path] = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(w.delay, w.flush)
+}
+
+// flush applies the changes queued since the last batch and publishes
+// what they did
+func (w *Watcher) flush() {
+	w.mu.Lock()
+	ctx := w.ctx
+
+	// A scan would race the imports for the same files; try again after
+	if w.scanner.IsScanning() {
+		w.timer = time.AfterFunc(w.delay, w.flush)
+		w.mu.Unlock()
+		return
+	}
+	paths := w.pending
+	w.pending = make(map[string]bool)
+	w.mu.Unlock()
+
+	changes := &WatchChanges{}
+	for path := range paths {
+		if ctx.Err() != nil {
+			return
+		}
+		w.apply(ctx, path, changes)
+	}
+
+	if !changes.Empty() {
+		logger.Info("Watch folders changed",
+			logger.Int("added", len(changes.Added)),
+			logger.Int("updated", len(changes.Updated)),
+			logger.Int("removed", len(changes.Removed)))
+		events.Publish(w.scanner.eventBus(), TopicFilesChanged, changes)
+	}
+}
+
+// apply brings the library up to date with one changed path
+func (w *Watcher) apply(ctx context.Context, path string, changes *WatchChanges) {
+	folder := w.folderFor(path)
+	if folder == nil {
+		return
+	}
+
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		w.removed(path, changes)
+		return
+	}
+	if err != nil {
+		logger.Warn("Error accessing path", logger.String("path", path), logger.Error(err))
+		return
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 && !w.scanner.followSymlinks {
+		return
+	}
+
+	opts := w.scanner.watchFolderOptions(folder)
+	d := fs.FileInfoToDirEntry(info)
+	if !info.IsDir() {
+		if w.acceptsFile(folder, opts, path, d) {
+			w.imported(ctx, path, opts.duplicates, changes)
+		}
+		return
+	}
+
+	// A new folder's files may have arrived before it was watched
+	if pathKey(path) == pathKey(folder.Path) || !w.acceptsDir(opts, path, d) {
+		return
+	}
+	if err := w.addTree(folder, path); err != nil {
+		logger.Warn("Failed to watch folder", logger.String("path", path), logger.Error(err))
+	}
+	filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil || ctx.Err() != nil:
+			return filepath.SkipDir
+		case d.IsDir():
+			if file != path && !w.acceptsDir(opts, file, d) {
+				return filepath.SkipDir
+			}
+		case w.acceptsFile(folder, opts, file, d):
+			w.imported(ctx, file, opts.duplicates, changes)
+		}
+		return nil
+	})
+}
+
+// imported imports a new or changed file
+func (w *Watcher) imported(ctx context.Context, path string, policy domain.DuplicatePolicy, changes *WatchChanges) {
+	existing, err := w.trackRepo.FindByPath(path)
+	if err != nil && !errors.Is(err, domain.ErrTrackNotFound) {
+		logger.Warn("Failed to look up track", logger.String("path", path), logger.Error(err))
+		return
+	}
+
+	track, err := w.scanner.ImportFile(ctx, path, policy)
+	switch {
+	case errors.Is(err, ErrDuplicateSkipped):
+		logger.Debug("Skipped copy of library track", logger.String("path", path))
+	case err != nil:
+		// Files still being written fail to read; their next write
+		// queues them again
+		logger.Warn("Failed to import watched file", logger.String("path", path), logger.Error(err))
+	case existing == nil:
+		changes.Added = append(changes.Added, track)
+	case track != existing:
+		changes.Updated = append(changes.Updated, track)
+	}
+}
+
+// removed flags the tracks of a vanished file, or of the files in a
+// vanished folder, as missing. Files that were moved keep their track,
+// which the import at the new path takes along.
+func (w *Watcher) removed(path string, changes *WatchChanges) {
+	var tracks []*domain.Track
+	if track, err := w.trackRepo.FindByPath(path); err == nil {
+		tracks = append(tracks, track)
+	}
+	if under, err := w.trackRepo.FindUnder(path); err == nil {
+		tracks = append(tracks, under...)
+	}
+
+	for _, track := range tracks {
+		if !track.IsValid {
+			continue
+		}
+		if _, err := os.Stat(track.FilePath); !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		track.MarkInvalid(domain.ErrFileNotFound.Error())
+		if err := w.trackRepo.UpdateStatus(track); err != nil {
+			logger.Warn("Failed to flag missing track",
+				logger.String("path", track.FilePath),
+				logger.Error(err))
+			continue
+		}
+		changes.Removed = append(changes.Removed, track)
+	}
+}
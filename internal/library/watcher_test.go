@@ -0,0 +1,128 @@
+package library
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
+)
+
+// watchTrackRepo knows tracks by path and records those flagged missing
+type watchTrackRepo struct {
+	domain.TrackRepository
+	tracks  map[string]*domain.Track
+	flagged chan *domain.Track
+}
+
+func (r *watchTrackRepo) FindByPath(path string) (*domain.Track, error) {
+	if track, ok := r.tracks[path]; ok {
+		return track, nil
+	}
+	return nil, domain.ErrTrackNotFound
+}
+
+func (r *watchTrackRepo) FindUnder(dir string) ([]*domain.Track, error) {
+	var tracks []*domain.Track
+	for path, track := range r.tracks {
+		if path != dir && isWithinAny(path, []string{dir}) {
+			tracks = append(tracks, track)
+		}
+	}
+	return tracks, nil
+}
+
+func (r *watchTrackRepo) UpdateStatus(track *domain.Track) error {
+	r.flagged <- track
+	return nil
+}
+
+func TestWatcherAccepts(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "Live")
+	require.NoError(t, os.Mkdir(nested, 0o755))
+
+	w, err := NewWatcher(NewScanner(nil, nil), nil)
+	require.NoError(t, err)
+	defer w.Close()
+
+	outer := &domain.WatchFolder{Path: root, IsEnabled: true, IsRecursive: true}
+	inner := &domain.WatchFolder{Path: nested, IsEnabled: true, ExcludePatterns: []string{"*bootleg*"}}
+	require.NoError(t, w.Watch(outer))
+	require.NoError(t, w.Watch(inner))
+	assert.Error(t, w.Watch(&domain.WatchFolder{Path: root}), "disabled folders are not watched")
+
+	assert.Same(t, inner, w.folderFor(filepath.Join(nested, "song.mp3")))
+	assert.Same(t, outer, w.folderFor(filepath.Join(root, "song.mp3")))
+	assert.Nil(t, w.folderFor(filepath.Join(filepath.Dir(root), "elsewhere.mp3")))
+
+	file := func(name string) fs.DirEntry {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.WriteFile(path, nil, 0o644))
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		return fs.FileInfoToDirEntry(info)
+	}
+
+	outerOpts := w.scanner.watchFolderOptions(outer)
+	innerOpts := w.scanner.watchFolderOptions(inner)
+	assert.True(t, w.acceptsFile(outer, outerOpts, filepath.Join(root, "song.mp3"), file("song.mp3")))
+	assert.False(t, w.acceptsFile(outer, outerOpts, filepath.Join(root, "cover.jpg"), file("cover.jpg")))
+	assert.False(t, w.acceptsFile(outer, outerOpts, filepath.Join(root, "song.mp3.partial"), file("song.mp3.partial")))
+	assert.False(t, w.acceptsFile(outer, outerOpts, filepath.Join(root, ".song.mp3"), file(".song.mp3")))
+	assert.False(t, w.acceptsFile(inner, innerOpts, filepath.Join(nested, "bootleg.mp3"), file("bootleg.mp3")))
+	assert.False(t, w.acceptsFile(inner, innerOpts, filepath.Join(nested, "Disc 2", "song.mp3"), file("song.mp3")),
+		"files below a folder that isn't recursive are left out")
+}
+
+func TestWatcherFlagsRemovedFiles(t *testing.T) {
+	root := t.TempDir()
+	album := filepath.Join(root, "Album")
+	require.NoError(t, os.Mkdir(album, 0o755))
+	song := filepath.Join(album, "song.mp3")
+	require.NoError(t, os.WriteFile(song, []byte("audio"), 0o644))
+
+	repo := &watchTrackRepo{
+		tracks:  map[string]*domain.Track{song: {ID: "1", FilePath: song, IsValid: true}},
+		flagged: make(chan *domain.Track, 1),
+	}
+	scanner := NewScanner(repo, nil)
+	bus := events.NewBus()
+	defer bus.Close()
+	scanner.SetEventBus(bus)
+	published := make(chan *WatchChanges, 1)
+	events.Subscribe(bus, TopicFilesChanged, func(changes *WatchChanges) { published <- changes })
+
+	w, err := NewWatcher(scanner, repo)
+	require.NoError(t, err)
+	defer w.Close()
+	w.delay = 10 * time.Millisecond
+	require.NoError(t, w.Watch(&domain.WatchFolder{Path: root, IsEnabled: true, IsRecursive: true}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w.Start(ctx)
+
+	require.NoError(t, os.RemoveAll(album))
+	select {
+	case track := <-repo.flagged:
+		assert.Equal(t, "1", track.ID)
+		assert.False(t, track.IsValid)
+	case <-time.After(5 * time.Second):
+		t.Fatal("removed file was not flagged")
+	}
+	select {
+	case changes := <-published:
+		require.Len(t, changes.Removed, 1)
+		assert.Empty(t, changes.Added)
+	case <-time.After(5 * time.Second):
+		t.Fatal("changes were not published")
+	}
+}
@@ -0,0 +1,317 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/connectivity"
+	"github.com/winramp/winramp/internal/domain"
+)
+
+const (
+	acoustIDEndpoint = "https://api.acoustid.org/v2/"
+
+	// acoustIDInterval keeps requests within AcoustID's limit of three a
+	// second
+	acoustIDInterval = 334 * time.Millisecond
+
+	// AcoustIDMinScore is the least score a lookup result needs to be
+	// taken as the recording
+	AcoustIDMinScore = 0.85
+
+	// acoustIDSubmitBatch is how many fingerprints are sent per submission
+	acoustIDSubmitBatch = 50
+)
+
+var (
+	ErrAcoustIDNotConfigured = errors.New("acoustid API key is not configured")
+	ErrAcoustIDNoUserKey     = errors.New("submitting to acoustid needs a user API key")
+)
+
+// FingerprintFunc returns the Chromaprint fingerprint of a track's audio
+type FingerprintFunc func(ctx context.Context, track *domain.Track) (string, error)
+
+// AcoustIDMatch is a recording a fingerprint was identified as
+type AcoustIDMatch struct {
+	ID            string  `json:"id"` // AcoustID track ID
+	Score         float64 `json:"score"`
+	RecordingMBID string  `json:"recordingMbid"`
+	Title         string  `json:"title"`
+	Artist        string  `json:"artist"`
+	ArtistMBID    string  `json:"artistMbid"`
+	Album         string  `json:"album"`
+}
+
+// AcoustIDSubmission is a fingerprint contributed with what the library
+// knows the recording as
+type AcoustIDSubmission struct {
+	Fingerprint string
+	Duration    time.Duration
+	Title       string
+	Artist      string
+	Album       string
+	AlbumArtist string
+	Year        int
+	TrackNumber int
+}
+
+// AcoustIDClient identifies recordings by their fingerprints with the
+// AcoustID service, and contributes fingerprints of tagged tracks back.
+// Lookups need the application's API key; submissions also need the
+// user's own key.
+type AcoustIDClient struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+	userKey  string
+	online   *connectivity.Monitor
+
+	interval    time.Duration // Least time between requests
+	lastRequest time.Time
+	rateMu      sync.Mutex
+}
+
+// NewAcoustIDClient creates a client with the application and user API
+// keys; the user key may be empty when nothing is submitted
+func NewAcoustIDClient(apiKey, userKey string, timeout time.Duration) *AcoustIDClient {
+	return &AcoustIDClient{
+		client:   &http.Client{Timeout: timeout},
+		endpoint: acoustIDEndpoint,
+		apiKey:   apiKey,
+		userKey:  userKey,
+		interval: acoustIDInterval,
+	}
+}
+
+// SetConnectivity makes requests fail with connectivity.ErrOffline while
+// the monitor is offline, and reports failed connections to it
+func (c *AcoustIDClient) SetConnectivity(monitor *connectivity.Monitor) {
+	c.online = monitor
+}
+
+// Configured reports whether lookups can be made
+func (c *AcoustIDClient) Configured() bool {
+	return c.apiKey != ""
+}
+
+// CanSubmit reports whether fingerprints can be submitted
+func (c *AcoustIDClient) CanSubmit() bool {
+	return c.apiKey != "" && c.userKey != ""
+}
+
+// Lookup identifies a fingerprint, returning the recordings it may be,
+// best first
+func (c *AcoustIDClient) Lookup(ctx context.Context, fingerprint string, duration time.Duration) ([]AcoustIDMatch, error) {
+	if !c.Configured() {
+		return nil, ErrAcoustIDNotConfigured
+	}
+
+	form := url.Values{
+		"client":      {c.apiKey},
+		"meta":        {"recordings releasegroups compress"},
+		"duration":    {strconv.Itoa(int(duration.Round(time.Second).Seconds()))},
+		"fingerprint": {fingerprint},
+	}
+	var response struct {
+		Results []struct {
+			ID         string  `json:"id"`
+			Score      float64 `json:"score"`
+			Recordings []struct {
+				ID      string `json:"id"`
+				Title   string `json:"title"`
+				Artists []struct {
+					ID         string `json:"id"`
+					Name       string `json:"name"`
+					JoinPhrase string `json:"joinphrase"`
+				} `json:"artists"`
+				ReleaseGroups []struct {
+					Title string `json:"title"`
+					Type  string `json:"type"`
+				} `json:"releasegroups"`
+			} `json:"recordings"`
+		} `json:"results"`
+	}
+	if err := c.post(ctx, "lookup", form, &response); err != nil {
+		return nil, err
+	}
+
+	var matches []AcoustIDMatch
+	for _, result := range response.Results {
+		for _, recording := range result.Recordings {
+			match := AcoustIDMatch{
+				ID:            result.ID,
+				Score:         result.Score,
+				RecordingMBID: recording.ID,
+				Title:         recording.Title,
+			}
+			var artist strings.Builder
+			for _, credit := range recording.Artists {
+				artist.WriteString(credit.Name + credit.JoinPhrase)
+			}
+			match.Artist = artist.String()
+			if len(recording.Artists) > 0 {
+				match.ArtistMBID = recording.Artists[0].ID
+			}
+
+			// An album is the likeliest release group to have ripped
+			for _, group := range recording.ReleaseGroups {
+				if match.Album == "" || group.Type == "Album" {
+					match.Album = group.Title
+				}
+				if group.Type == "Album" {
+					break
+				}
+			}
+			matches = append(matches, match)
+		}
+	}
+	return matches, nil
+}
+
+// Submit contributes fingerprints to AcoustID, in batches
+func (c *AcoustIDClient) Submit(ctx context.Context, submissions []AcoustIDSubmission) error {
+	if !c.CanSubmit() {
+		return ErrAcoustIDNoUserKey
+	}
+
+	for start := 0; start < len(submissions); start += acoustIDSubmitBatch {
+		batch := submissions[start:min(start+acoustIDSubmitBatch, len(submissions))]
+		form := url.Values{
+			"client": {c.apiKey},
+			"user":   {c.userKey},
+		}
+		for i, s := range batch {
+			set := func(name, value string) {
+				if value != "" && value != "0" {
+					form.Set(fmt.Sprintf("%s.%d", name, i), value)
+				}
+			}
+			set("fingerprint", s.Fingerprint)
+			set("duration", strconv.Itoa(int(s.Duration.Round(time.Second).Seconds())))
+			set("track", s.Title)
+			set("artist", s.Artist)
+			set("album", s.Album)
+			set("albumartist", s.AlbumArtist)
+			set("year", strconv.Itoa(s.Year))
+			set("trackno", strconv.Itoa(s.TrackNumber))
+		}
+		if err := c.post(ctx, "submit", form, &struct{}{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// post performs an API request, spacing requests to stay within the rate
+// limit. Fingerprints are too long for a URL, so parameters are sent as a
+// form.
+func (c *AcoustIDClient) post(ctx context.Context, method string, form url.Values, v interface{}) error {
+	if !c.online.Online() {
+		return connectivity.ErrOffline
+	}
+
+	c.rateMu.Lock()
+	if wait := c.interval - time.Since(c.lastRequest); wait > 0 {
+		select {
+		case <-ctx.Done():
+			c.rateMu.Unlock()
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	c.lastRequest = time.Now()
+	c.rateMu.Unlock()
+
+	form.Set("format", "json")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+method, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.online.ReportFailure()
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// Errors come with a message in the body, whatever the status
+	var status struct {
+		Status string `json:"status"`
+		Error  struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return fmt.Errorf("acoustid request failed with status %d", resp.StatusCode)
+	}
+	if status.Status != "ok" {
+		return fmt.Errorf("acoustid %s failed: %s", method, status.Error.Message)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// acoustIDProvider identifies tracks by their fingerprints
+type acoustIDProvider struct {
+	client      *AcoustIDClient
+	fingerprint FingerprintFunc
+}
+
+// Provider returns a metadata provider identifying tracks by their audio,
+// for files whose tags say nothing. Tracks without a fingerprint are
+// fingerprinted with the given function.
+func (c *AcoustIDClient) Provider(fingerprint FingerprintFunc) Provider {
+	return &acoustIDProvider{client: c, fingerprint: fingerprint}
+}
+
+func (p *acoustIDProvider) Name() string {
+	return "acoustid"
+}
+
+func (p *acoustIDProvider) Lookup(ctx context.Context, track *domain.Track) (*Fields, error) {
+	if !p.client.Configured() || track.Duration <= 0 {
+		return nil, ErrNoMatch
+	}
+
+	fingerprint := track.Fingerprint
+	if fingerprint == "" {
+		var err error
+		if fingerprint, err = p.fingerprint(ctx, track); err != nil {
+			return nil, err
+		}
+	}
+
+	matches, err := p.client.Lookup(ctx, fingerprint, track.Duration)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 || matches[0].Score < AcoustIDMinScore {
+		return nil, ErrNoMatch
+	}
+
+	best := matches[0]
+	return &Fields{
+		Title:      best.Title,
+		Artist:     best.Artist,
+		Album:      best.Album,
+		ArtistMBID: best.ArtistMBID,
+	}, nil
+}
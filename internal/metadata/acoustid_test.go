@@ -0,0 +1,141 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+const acoustIDTestLookup = `{
+	"status": "ok",
+	"results": [{
+		"id": "9ff43b6a-4f16-427c-93c2-92307ca505e0",
+		"score": 0.96,
+		"recordings": [{
+			"id": "cd2e7c47-16f5-46c6-a37c-a1eb7bf599ff",
+			"title": "Army of Me",
+			"artists": [{"id": "87c5dedd-371d-4a53-9f7f-80522fb7f3cb", "name": "Björk"}],
+			"releasegroups": [
+				{"title": "Army of Me", "type": "Single"},
+				{"title": "Post", "type": "Album"}
+			]
+		}]
+	}]
+}`
+
+// newAcoustIDTestServer answers lookups and submissions, keeping the last
+// form each received
+func newAcoustIDTestServer(t *testing.T, lookup string) (*AcoustIDClient, map[string]http.Header, map[string]map[string][]string) {
+	headers := make(map[string]http.Header)
+	forms := make(map[string]map[string][]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		headers[r.URL.Path] = r.Header
+		forms[r.URL.Path] = r.PostForm
+
+		if r.PostForm.Get("client") != "app-key" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status": "error", "error": {"code": 4, "message": "invalid API key"}}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/lookup":
+			w.Write([]byte(lookup))
+		case "/submit":
+			w.Write([]byte(`{"status": "ok", "submissions": [{"id": 1, "status": "pending"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewAcoustIDClient("app-key", "user-key", 5*time.Second)
+	client.endpoint = server.URL + "/"
+	client.interval = 0
+	return client, headers, forms
+}
+
+func TestAcoustIDLookup(t *testing.T) {
+	client, headers, forms := newAcoustIDTestServer(t, acoustIDTestLookup)
+
+	matches, err := client.Lookup(context.Background(), "AQADtEmUaEkSRZEG", 236400*time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, AcoustIDMatch{
+		ID:            "9ff43b6a-4f16-427c-93c2-92307ca505e0",
+		Score:         0.96,
+		RecordingMBID: "cd2e7c47-16f5-46c6-a37c-a1eb7bf599ff",
+		Title:         "Army of Me",
+		Artist:        "Björk",
+		ArtistMBID:    "87c5dedd-371d-4a53-9f7f-80522fb7f3cb",
+		Album:         "Post",
+	}, matches[0])
+
+	assert.Equal(t, "application/x-www-form-urlencoded", headers["/lookup"].Get("Content-Type"))
+	assert.Equal(t, []string{"236"}, forms["/lookup"]["duration"])
+	assert.Equal(t, []string{"AQADtEmUaEkSRZEG"}, forms["/lookup"]["fingerprint"])
+
+	client.apiKey = "wrong"
+	_, err = client.Lookup(context.Background(), "AQADtEmUaEkSRZEG", time.Minute)
+	assert.ErrorContains(t, err, "invalid API key")
+
+	client.apiKey = ""
+	_, err = client.Lookup(context.Background(), "AQADtEmUaEkSRZEG", time.Minute)
+	assert.ErrorIs(t, err, ErrAcoustIDNotConfigured)
+}
+
+func TestAcoustIDSubmit(t *testing.T) {
+	client, _, forms := newAcoustIDTestServer(t, acoustIDTestLookup)
+
+	err := client.Submit(context.Background(), []AcoustIDSubmission{
+		{Fingerprint: "AQADtEmU", Duration: 236 * time.Second, Title: "Army of Me", Artist: "Björk", Album: "Post", TrackNumber: 2},
+		{Fingerprint: "AQADtFmV", Duration: 4 * time.Minute, Title: "Hyperballad", Artist: "Björk"},
+	})
+	require.NoError(t, err)
+
+	form := forms["/submit"]
+	assert.Equal(t, []string{"user-key"}, form["user"])
+	assert.Equal(t, []string{"AQADtEmU"}, form["fingerprint.0"])
+	assert.Equal(t, []string{"2"}, form["trackno.0"])
+	assert.Equal(t, []string{"Hyperballad"}, form["track.1"])
+	assert.Equal(t, []string{"240"}, form["duration.1"])
+	assert.NotContains(t, form, "album.1", "unknown fields are left out")
+
+	client.userKey = ""
+	assert.ErrorIs(t, client.Submit(context.Background(), nil), ErrAcoustIDNoUserKey)
+}
+
+func TestAcoustIDProvider(t *testing.T) {
+	client, _, _ := newAcoustIDTestServer(t, acoustIDTestLookup)
+	fingerprinted := 0
+	provider := client.Provider(func(ctx context.Context, track *domain.Track) (string, error) {
+		fingerprinted++
+		return "AQADtEmUaEkSRZEG", nil
+	})
+
+	track := &domain.Track{FilePath: "/music/track01.mp3", Duration: 236 * time.Second}
+	changed, err := NewChain(MergeFill, provider).Enrich(context.Background(), track)
+	require.NoError(t, err)
+	assert.Equal(t, 1, fingerprinted)
+	assert.Equal(t, "acoustid", changed["title"])
+	assert.Equal(t, "Army of Me", track.Title)
+	assert.Equal(t, "Post", track.Album)
+
+	// Stored fingerprints are used as they are
+	track = &domain.Track{FilePath: "/music/track02.mp3", Duration: time.Minute, Fingerprint: "AQADtEmUaEkSRZEG"}
+	_, err = provider.Lookup(context.Background(), track)
+	require.NoError(t, err)
+	assert.Equal(t, 1, fingerprinted)
+
+	// Doubtful matches are not taken
+	client, _, _ = newAcoustIDTestServer(t, `{"status": "ok", "results": [{"id": "x", "score": 0.4, "recordings": [{"id": "y", "title": "Maybe"}]}]}`)
+	_, err = client.Provider(nil).Lookup(context.Background(), track)
+	assert.ErrorIs(t, err, ErrNoMatch)
+}
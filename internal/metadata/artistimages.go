@@ -0,0 +1,208 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	fanartEndpoint = "https://webservice.fanart.tv/v3/music/"
+
+	// maxArtistImageBytes caps downloads so a bad URL can't fill the cache
+	maxArtistImageBytes = 10 << 20
+
+	// lastFMPlaceholder is the image Last.fm serves for every artist since it
+	// stopped providing artist photos
+	lastFMPlaceholder = "2a96cbd8b46e442fc41c2b86b821562f"
+)
+
+// ErrNoArtistImage is returned when no source has an image of the artist
+var ErrNoArtistImage = errors.New("no artist image available")
+
+// errImageNotFound is returned by download when the server has no image at
+// the URL
+var errImageNotFound = errors.New("image not found")
+
+// LookupArtistID finds an artist's MusicBrainz ID by name. IDs are kept in
+// memory for the session, since names rarely change.
+func (s *ContextService) LookupArtistID(ctx context.Context, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", ErrNoContext
+	}
+
+	key := strings.ToLower(name)
+	s.mu.Lock()
+	id, ok := s.artistIDs[key]
+	s.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	var search struct {
+		Artists []struct {
+			ID    string `json:"id"`
+			Score int    `json:"score"`
+		} `json:"artists"`
+	}
+	params := url.Values{
+		"query": {fmt.Sprintf(`artist:"%s"`, mbEscape(name))},
+		"limit": {"1"},
+		"fmt":   {"json"},
+	}
+	if err := s.getMusicBrainz(ctx, musicBrainzEndpoint+"artist/?"+params.Encode(), &search); err != nil {
+		return "", err
+	}
+	if len(search.Artists) == 0 || search.Artists[0].Score < 90 {
+		return "", fmt.Errorf("%w: %s", ErrNoContext, name)
+	}
+
+	id = search.Artists[0].ID
+	s.mu.Lock()
+	s.artistIDs[key] = id
+	s.mu.Unlock()
+	return id, nil
+}
+
+// FetchArtistImage downloads the best available photo of an artist, trying
+// fanart.tv and then Last.fm. Sources without an API key are skipped.
+func (s *ContextService) FetchArtistImage(ctx context.Context, mbid string) ([]byte, error) {
+	if mbid == "" {
+		return nil, ErrNoArtistImage
+	}
+
+	// A source that could not be reached is reported rather than treated as
+	// having no image, so callers know to try again later
+	var imageURL string
+	var failed error
+	sources := []struct {
+		enabled bool
+		find    func(context.Context, string) (string, error)
+	}{
+		{s.fanartKey != "", s.fanartImageURL},
+		{s.apiKey != "", s.lastFMImageURL},
+	}
+	for _, source := range sources {
+		if !source.enabled {
+			continue
+		}
+		found, err := source.find(ctx, mbid)
+		if err == nil {
+			imageURL = found
+			break
+		}
+		if !errors.Is(err, ErrNoArtistImage) && failed == nil {
+			failed = err
+		}
+	}
+	if imageURL == "" {
+		if failed != nil {
+			return nil, failed
+		}
+		return nil, fmt.Errorf("%w: %s", ErrNoArtistImage, mbid)
+	}
+
+	return s.download(ctx, imageURL)
+}
+
+// fanartImageURL returns the most liked artist thumbnail on fanart.tv
+func (s *ContextService) fanartImageURL(ctx context.Context, mbid string) (string, error) {
+	var images struct {
+		ArtistThumb []struct {
+			URL   string `json:"url"`
+			Likes string `json:"likes"`
+		} `json:"artistthumb"`
+	}
+
+	requestURL := fanartEndpoint + url.PathEscape(mbid) + "?api_key=" + url.QueryEscape(s.fanartKey)
+	if err := s.getJSON(ctx, requestURL, &images); err != nil {
+		if errors.Is(err, errNotFound) {
+			return "", ErrNoArtistImage
+		}
+		return "", err
+	}
+	if len(images.ArtistThumb) == 0 {
+		return "", ErrNoArtistImage
+	}
+
+	thumbs := images.ArtistThumb
+	sort.SliceStable(thumbs, func(i, j int) bool {
+		a, _ := strconv.Atoi(thumbs[i].Likes)
+		b, _ := strconv.Atoi(thumbs[j].Likes)
+		return a > b
+	})
+	return thumbs[0].URL, nil
+}
+
+// lastFMImageURL returns the largest artist image Last.fm has, ignoring its
+// placeholder star
+func (s *ContextService) lastFMImageURL(ctx context.Context, mbid string) (string, error) {
+	var info struct {
+		Error  int `json:"error"`
+		Artist struct {
+			Image []struct {
+				URL  string `json:"#text"`
+				Size string `json:"size"`
+			} `json:"image"`
+		} `json:"artist"`
+	}
+
+	params := url.Values{
+		"method":  {"artist.getinfo"},
+		"mbid":    {mbid},
+		"api_key": {s.apiKey},
+		"format":  {"json"},
+	}
+	if err := s.getJSON(ctx, lastFMEndpoint+"?"+params.Encode(), &info); err != nil {
+		return "", err
+	}
+	if info.Error != 0 {
+		return "", ErrNoArtistImage
+	}
+
+	// Images are listed smallest first
+	images := info.Artist.Image
+	for i := len(images) - 1; i >= 0; i-- {
+		if images[i].URL != "" && !strings.Contains(images[i].URL, lastFMPlaceholder) {
+			return images[i].URL, nil
+		}
+	}
+	return "", ErrNoArtistImage
+}
+
+func (s *ContextService) download(ctx context.Context, imageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errImageNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image download failed with status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArtistImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxArtistImageBytes {
+		return nil, fmt.Errorf("artist image larger than %d bytes", maxArtistImageBytes)
+	}
+	return data, nil
+}
@@ -0,0 +1,407 @@
+package metadata
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/connectivity"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+const (
+	lastFMEndpoint      = "https://ws.audioscrobbler.com/2.0/"
+	musicBrainzEndpoint = "https://musicbrainz.org/ws/2/"
+	userAgent           = "WinRamp/1.0 ( https://github.com/winramp/winramp )"
+
+	// contextCacheTTL is how long fetched context is reused before refreshing
+	contextCacheTTL = 7 * 24 * time.Hour
+
+	// musicBrainzInterval keeps requests within MusicBrainz's rate limit
+	musicBrainzInterval = time.Second
+
+	maxSimilarArtists = 10
+	maxTags           = 8
+
+	// readMoreSuffix is the link text Last.fm appends to summaries
+	readMoreSuffix = "Read more on Last.fm"
+)
+
+// ErrNoContext is returned when no source knows the artist
+var ErrNoContext = errors.New("no context information available")
+
+// errNotFound is returned by getJSON for a 404 response
+var errNotFound = errors.New("not found")
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// NowPlayingContext holds background information about a track's artist and
+// album
+type NowPlayingContext struct {
+	Artist         string    `json:"artist"`
+	Album          string    `json:"album"`
+	ArtistBio      string    `json:"artistBio"`
+	ArtistURL      string    `json:"artistUrl"`
+	AlbumReview    string    `json:"albumReview"`
+	SimilarArtists []string  `json:"similarArtists"`
+	Tags           []string  `json:"tags"`
+	Source         string    `json:"source"` // "lastfm" or "musicbrainz"
+	FetchedAt      time.Time `json:"fetchedAt"`
+}
+
+// ContextService fetches artist biographies, album reviews and similar
+// artists from Last.fm, falling back to MusicBrainz when no Last.fm API key
+// is configured. Results are cached on disk.
+type ContextService struct {
+	client    *http.Client
+	cacheDir  string
+	apiKey    string
+	fanartKey string
+	online    *connectivity.Monitor
+
+	memory        map[string]*NowPlayingContext
+	artistIDs     map[string]string // MusicBrainz IDs by lower-case name
+	lastMBRequest time.Time
+	mu            sync.Mutex
+	mbMu          sync.Mutex
+}
+
+// NewContextService creates a context service caching under cacheDir. The
+// fanart.tv key is only used for artist images.
+func NewContextService(cacheDir string, lastFMKey, fanartKey string, timeout time.Duration) *ContextService {
+	return &ContextService{
+		client:    &http.Client{Timeout: timeout},
+		cacheDir:  filepath.Join(cacheDir, "context"),
+		apiKey:    lastFMKey,
+		fanartKey: fanartKey,
+		memory:    make(map[string]*NowPlayingContext),
+		artistIDs: make(map[string]string),
+	}
+}
+
+// Get returns context for an artist and album, from cache when fresh. If
+// fetching fails, stale cached context is returned instead of an error.
+func (s *ContextService) Get(ctx context.Context, artist, album string) (*NowPlayingContext, error) {
+	artist = strings.TrimSpace(artist)
+	album = strings.TrimSpace(album)
+	if artist == "" {
+		return nil, ErrNoContext
+	}
+
+	key := cacheKey(artist, album)
+	cached := s.cached(key)
+	if cached != nil && time.Since(cached.FetchedAt) < contextCacheTTL {
+		return cached, nil
+	}
+
+	fetched, err := s.fetch(ctx, artist, album)
+	if err != nil {
+		if cached != nil {
+			logger.Debug("Using stale now playing context",
+				logger.String("artist", artist),
+				logger.Error(err))
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	s.store(key, fetched)
+	return fetched, nil
+}
+
+func (s *ContextService) fetch(ctx context.Context, artist, album string) (*NowPlayingContext, error) {
+	if s.apiKey != "" {
+		result, err := s.fetchLastFM(ctx, artist, album)
+		if err == nil {
+			return result, nil
+		}
+		logger.Debug("Last.fm lookup failed, trying MusicBrainz",
+			logger.String("artist", artist),
+			logger.Error(err))
+	}
+
+	return s.fetchMusicBrainz(ctx, artist, album)
+}
+
+func (s *ContextService) fetchLastFM(ctx context.Context, artist, album string) (*NowPlayingContext, error) {
+	var artistInfo struct {
+		Error   int    `json:"error"`
+		Message string `json:"message"`
+		Artist  struct {
+			Name    string `json:"name"`
+			URL     string `json:"url"`
+			Similar struct {
+				Artist []struct {
+					Name string `json:"name"`
+				} `json:"artist"`
+			} `json:"similar"`
+			Tags struct {
+				Tag []struct {
+					Name string `json:"name"`
+				} `json:"tag"`
+			} `json:"tags"`
+			Bio struct {
+				Summary string `json:"summary"`
+			} `json:"bio"`
+		} `json:"artist"`
+	}
+
+	params := url.Values{
+		"method":      {"artist.getinfo"},
+		"artist":      {artist},
+		"autocorrect": {"1"},
+		"api_key":     {s.apiKey},
+		"format":      {"json"},
+	}
+	if err := s.getJSON(ctx, lastFMEndpoint+"?"+params.Encode(), &artistInfo); err != nil {
+		return nil, err
+	}
+	if artistInfo.Error != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoContext, artistInfo.Message)
+	}
+
+	result := &NowPlayingContext{
+		Artist:    artistInfo.Artist.Name,
+		Album:     album,
+		ArtistBio: cleanSummary(artistInfo.Artist.Bio.Summary),
+		ArtistURL: artistInfo.Artist.URL,
+		Source:    "lastfm",
+		FetchedAt: time.Now(),
+	}
+	for _, similar := range artistInfo.Artist.Similar.Artist {
+		if len(result.SimilarArtists) == maxSimilarArtists {
+			break
+		}
+		result.SimilarArtists = append(result.SimilarArtists, similar.Name)
+	}
+	for _, tag := range artistInfo.Artist.Tags.Tag {
+		if len(result.Tags) == maxTags {
+			break
+		}
+		result.Tags = append(result.Tags, tag.Name)
+	}
+
+	if album != "" {
+		var albumInfo struct {
+			Album struct {
+				Wiki struct {
+					Summary string `json:"summary"`
+				} `json:"wiki"`
+			} `json:"album"`
+		}
+		params.Set("method", "album.getinfo")
+		params.Set("album", album)
+		if err := s.getJSON(ctx, lastFMEndpoint+"?"+params.Encode(), &albumInfo); err == nil {
+			result.AlbumReview = cleanSummary(albumInfo.Album.Wiki.Summary)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *ContextService) fetchMusicBrainz(ctx context.Context, artist, album string) (*NowPlayingContext, error) {
+	var search struct {
+		Artists []struct {
+			ID             string `json:"id"`
+			Name           string `json:"name"`
+			Type           string `json:"type"`
+			Score          int    `json:"score"`
+			Disambiguation string `json:"disambiguation"`
+			Area           struct {
+				Name string `json:"name"`
+			} `json:"area"`
+			LifeSpan struct {
+				Begin string `json:"begin"`
+				End   string `json:"end"`
+			} `json:"life-span"`
+			Tags []struct {
+				Name  string `json:"name"`
+				Count int    `json:"count"`
+			} `json:"tags"`
+		} `json:"artists"`
+	}
+
+	params := url.Values{
+		"query": {fmt.Sprintf(`artist:"%s"`, mbEscape(artist))},
+		"limit": {"1"},
+		"fmt":   {"json"},
+	}
+	if err := s.getMusicBrainz(ctx, musicBrainzEndpoint+"artist/?"+params.Encode(), &search); err != nil {
+		return nil, err
+	}
+	if len(search.Artists) == 0 || search.Artists[0].Score < 90 {
+		return nil, fmt.Errorf("%w: %s", ErrNoContext, artist)
+	}
+
+	found := search.Artists[0]
+	result := &NowPlayingContext{
+		Artist:    found.Name,
+		Album:     album,
+		ArtistURL: "https://musicbrainz.org/artist/" + found.ID,
+		Source:    "musicbrainz",
+		FetchedAt: time.Now(),
+	}
+
+	// MusicBrainz has no biographies, so describe the artist from its facts
+	var bio []string
+	switch {
+	case found.Type != "" && found.Area.Name != "":
+		bio = append(bio, fmt.Sprintf("%s from %s", found.Type, found.Area.Name))
+	case found.Type != "":
+		bio = append(bio, found.Type)
+	}
+	if found.LifeSpan.Begin != "" {
+		span := "active since " + found.LifeSpan.Begin
+		if found.LifeSpan.End != "" {
+			span = fmt.Sprintf("active %s to %s", found.LifeSpan.Begin, found.LifeSpan.End)
+		}
+		bio = append(bio, span)
+	}
+	if found.Disambiguation != "" {
+		bio = append(bio, found.Disambiguation)
+	}
+	if len(bio) > 0 {
+		result.ArtistBio = strings.Join(bio, ", ") + "."
+	}
+
+	for _, tag := range found.Tags {
+		if len(result.Tags) == maxTags {
+			break
+		}
+		if tag.Count > 0 {
+			result.Tags = append(result.Tags, tag.Name)
+		}
+	}
+
+	return result, nil
+}
+
+// getMusicBrainz performs a MusicBrainz request, spacing requests to stay
+// within the service's rate limit
+func (s *ContextService) getMusicBrainz(ctx context.Context, requestURL string, v interface{}) error {
+	if !s.online.Online() {
+		return connectivity.ErrOffline
+	}
+
+	s.mbMu.Lock()
+	defer s.mbMu.Unlock()
+
+	if wait := musicBrainzInterval - time.Since(s.lastMBRequest); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	s.lastMBRequest = time.Now()
+
+	return s.getJSON(ctx, requestURL, v)
+}
+
+// SetConnectivity makes requests fail with connectivity.ErrOffline while
+// the monitor is offline, leaving only cached results, and reports failed
+// connections to it
+func (s *ContextService) SetConnectivity(monitor *connectivity.Monitor) {
+	s.online = monitor
+}
+
+// do sends a request unless offline
+func (s *ContextService) do(req *http.Request) (*http.Response, error) {
+	if !s.online.Online() {
+		return nil, connectivity.ErrOffline
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil && req.Context().Err() == nil {
+		s.online.ReportFailure()
+	}
+	return resp, err
+}
+
+func (s *ContextService) getJSON(ctx context.Context, requestURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed with status %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (s *ContextService) cached(key string) *NowPlayingContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if result, ok := s.memory[key]; ok {
+		return result
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.cacheDir, key+".json"))
+	if err != nil {
+		return nil
+	}
+	var result NowPlayingContext
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	s.memory[key] = &result
+	return &result
+}
+
+func (s *ContextService) store(key string, result *NowPlayingContext) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.memory[key] = result
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(s.cacheDir, 0700); err != nil {
+		logger.Warn("Failed to create context cache directory", logger.Error(err))
+		return
+	}
+	if err := os.WriteFile(filepath.Join(s.cacheDir, key+".json"), data, 0600); err != nil {
+		logger.Warn("Failed to cache now playing context", logger.Error(err))
+	}
+}
+
+// cacheKey derives a file name from the artist and album
+func cacheKey(artist, album string) string {
+	sum := sha1.Sum([]byte(strings.ToLower(artist) + "\x00" + strings.ToLower(album)))
+	return hex.EncodeToString(sum[:])
+}
+
+// cleanSummary strips markup and the trailing Last.fm link from a summary
+func cleanSummary(summary string) string {
+	text := html.UnescapeString(htmlTagPattern.ReplaceAllString(summary, ""))
+	text = strings.TrimSpace(text)
+	return strings.TrimSpace(strings.TrimSuffix(text, readMoreSuffix))
+}
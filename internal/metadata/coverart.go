@@ -0,0 +1,48 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// coverArtArchiveEndpoint serves the cover art of MusicBrainz release groups
+const coverArtArchiveEndpoint = "https://coverartarchive.org/release-group/"
+
+// ErrNoAlbumArt is returned when no source has a cover for the album
+var ErrNoAlbumArt = errors.New("no album art available")
+
+// FetchAlbumArt finds an album on MusicBrainz and downloads its front cover
+// from the Cover Art Archive
+func (s *ContextService) FetchAlbumArt(ctx context.Context, artist, album string) ([]byte, error) {
+	artist, album = strings.TrimSpace(artist), strings.TrimSpace(album)
+	if artist == "" || album == "" {
+		return nil, ErrNoAlbumArt
+	}
+
+	var search struct {
+		ReleaseGroups []struct {
+			ID    string `json:"id"`
+			Score int    `json:"score"`
+		} `json:"release-groups"`
+	}
+	params := url.Values{
+		"query": {fmt.Sprintf(`releasegroup:"%s" AND artist:"%s"`, mbEscape(album), mbEscape(artist))},
+		"limit": {"1"},
+		"fmt":   {"json"},
+	}
+	if err := s.getMusicBrainz(ctx, musicBrainzEndpoint+"release-group/?"+params.Encode(), &search); err != nil {
+		return nil, err
+	}
+	if len(search.ReleaseGroups) == 0 || search.ReleaseGroups[0].Score < musicBrainzMinScore {
+		return nil, fmt.Errorf("%w: %s by %s", ErrNoAlbumArt, album, artist)
+	}
+
+	data, err := s.download(ctx, coverArtArchiveEndpoint+search.ReleaseGroups[0].ID+"/front-500")
+	if errors.Is(err, errImageNotFound) {
+		return nil, fmt.Errorf("%w: %s by %s", ErrNoAlbumArt, album, artist)
+	}
+	return data, err
+}
@@ -0,0 +1,566 @@
+package metadata
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/connectivity"
+	"github.com/winramp/winramp/internal/domain"
+)
+
+const (
+	discogsEndpoint     = "https://api.discogs.com/"
+	discogsAuthorizeURL = "https://www.discogs.com/oauth/authorize"
+
+	// discogsInterval keeps requests within Discogs' limit of 60 a minute
+	// for authenticated clients
+	discogsInterval = time.Second
+
+	// discogsSearchResults is how many search results are considered
+	discogsSearchResults = 5
+)
+
+var (
+	ErrDiscogsNotConfigured   = errors.New("discogs is not configured")
+	ErrDiscogsUnauthorized    = errors.New("discogs rejected the credentials")
+	ErrDiscogsRateLimited     = errors.New("discogs rate limit reached")
+	ErrDiscogsNoAuthorization = errors.New("no discogs authorization in progress")
+)
+
+// discogsNameSuffix is the number Discogs appends to tell apart artists
+// and labels of the same name, as in "Nirvana (2)"
+var discogsNameSuffix = regexp.MustCompile(`\s+\(\d+\)$`)
+
+// DiscogsToken is an OAuth token and its secret. Access tokens are kept
+// between runs; request tokens only while the user authorizes.
+type DiscogsToken struct {
+	Token    string `json:"token"`
+	Secret   string `json:"secret"`
+	Username string `json:"username,omitempty"`
+}
+
+// DiscogsStatus is whether Discogs can be used and as whom
+type DiscogsStatus struct {
+	Configured bool   `json:"configured"` // Credentials for searching are set
+	Authorized bool   `json:"authorized"` // An account has been connected
+	Username   string `json:"username,omitempty"`
+}
+
+// DiscogsClient looks up releases on Discogs. Searching needs the
+// application's consumer key and secret, or a personal access token; an
+// account can also be connected with OAuth, whose token is stored in a
+// file so it survives restarts.
+type DiscogsClient struct {
+	client         *http.Client
+	endpoint       string
+	consumerKey    string
+	consumerSecret string
+	personalToken  string
+	tokenPath      string
+	online         *connectivity.Monitor
+
+	token        *DiscogsToken              // Connected account, nil if none
+	requestToken *DiscogsToken              // Authorization in progress
+	lookups      map[string]*discogsRelease // Provider lookups by album key; nil when not found
+	interval     time.Duration              // Least time between requests
+	lastRequest  time.Time
+	mu           sync.Mutex
+	rateMu       sync.Mutex
+}
+
+// NewDiscogsClient creates a client keeping its OAuth token at tokenPath.
+// A token saved by an earlier run is loaded.
+func NewDiscogsClient(consumerKey, consumerSecret, personalToken, tokenPath string, timeout time.Duration) *DiscogsClient {
+	c := &DiscogsClient{
+		client:         &http.Client{Timeout: timeout},
+		endpoint:       discogsEndpoint,
+		interval:       discogsInterval,
+		consumerKey:    consumerKey,
+		consumerSecret: consumerSecret,
+		personalToken:  personalToken,
+		tokenPath:      tokenPath,
+		lookups:        make(map[string]*discogsRelease),
+	}
+
+	if data, err := os.ReadFile(tokenPath); err == nil {
+		var token DiscogsToken
+		if json.Unmarshal(data, &token) == nil && token.Token != "" {
+			c.token = &token
+		}
+	}
+	return c
+}
+
+// SetConnectivity makes requests fail with connectivity.ErrOffline while
+// the monitor is offline, and reports failed connections to it
+func (c *DiscogsClient) SetConnectivity(monitor *connectivity.Monitor) {
+	c.online = monitor
+}
+
+// Status reports whether Discogs can be searched and which account is
+// connected
+func (c *DiscogsClient) Status() DiscogsStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := DiscogsStatus{
+		Configured: c.personalToken != "" || c.consumerKey != "" && c.consumerSecret != "",
+		Authorized: c.token != nil,
+	}
+	if c.token != nil {
+		status.Username = c.token.Username
+	}
+	return status
+}
+
+// BeginAuthorization starts connecting a Discogs account and returns the
+// page where the user approves it. Discogs then sends the user to callback
+// with a verifier, or shows it to them, for CompleteAuthorization.
+func (c *DiscogsClient) BeginAuthorization(ctx context.Context, callback string) (string, error) {
+	if c.consumerKey == "" || c.consumerSecret == "" {
+		return "", ErrDiscogsNotConfigured
+	}
+
+	values, err := c.oauthRequest(ctx, http.MethodGet, "oauth/request_token", &DiscogsToken{}, map[string]string{
+		"oauth_callback": callback,
+	})
+	if err != nil {
+		return "", err
+	}
+	token := &DiscogsToken{Token: values.Get("oauth_token"), Secret: values.Get("oauth_token_secret")}
+	if token.Token == "" {
+		return "", fmt.Errorf("%w: no request token", ErrDiscogsUnauthorized)
+	}
+
+	c.mu.Lock()
+	c.requestToken = token
+	c.mu.Unlock()
+
+	return discogsAuthorizeURL + "?" + url.Values{"oauth_token": {token.Token}}.Encode(), nil
+}
+
+// CompleteAuthorization exchanges the verifier the user was given for an
+// access token, which is saved, and returns the connected username
+func (c *DiscogsClient) CompleteAuthorization(ctx context.Context, verifier string) (string, error) {
+	c.mu.Lock()
+	request := c.requestToken
+	c.mu.Unlock()
+	if request == nil {
+		return "", ErrDiscogsNoAuthorization
+	}
+
+	values, err := c.oauthRequest(ctx, http.MethodPost, "oauth/access_token", request, map[string]string{
+		"oauth_verifier": strings.TrimSpace(verifier),
+	})
+	if err != nil {
+		return "", err
+	}
+	token := &DiscogsToken{Token: values.Get("oauth_token"), Secret: values.Get("oauth_token_secret")}
+	if token.Token == "" {
+		return "", fmt.Errorf("%w: no access token", ErrDiscogsUnauthorized)
+	}
+
+	c.mu.Lock()
+	c.token = token
+	c.requestToken = nil
+	c.mu.Unlock()
+
+	var identity struct {
+		Username string `json:"username"`
+	}
+	if err := c.getJSON(ctx, "oauth/identity", nil, &identity); err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	token.Username = identity.Username
+	c.mu.Unlock()
+	if err := c.saveToken(token); err != nil {
+		return "", err
+	}
+	return identity.Username, nil
+}
+
+// Disconnect forgets the connected account
+func (c *DiscogsClient) Disconnect() error {
+	c.mu.Lock()
+	c.token = nil
+	c.requestToken = nil
+	c.mu.Unlock()
+
+	if err := os.Remove(c.tokenPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove discogs token: %w", err)
+	}
+	return nil
+}
+
+func (c *DiscogsClient) saveToken(token *DiscogsToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.tokenPath), 0700); err != nil {
+		return fmt.Errorf("failed to create discogs token directory: %w", err)
+	}
+	if err := os.WriteFile(c.tokenPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to save discogs token: %w", err)
+	}
+	return nil
+}
+
+// discogsRelease is a release as the Discogs API returns it
+type discogsRelease struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	URI     string `json:"uri"`
+	Country string `json:"country"`
+	Year    int    `json:"year"`
+	Artists []struct {
+		Name string `json:"name"`
+		Join string `json:"join"`
+	} `json:"artists"`
+	Labels []struct {
+		Name  string `json:"name"`
+		Catno string `json:"catno"`
+	} `json:"labels"`
+	Genres       []string `json:"genres"`
+	Styles       []string `json:"styles"`
+	ExtraArtists []struct {
+		Name   string `json:"name"`
+		Role   string `json:"role"`
+		Tracks string `json:"tracks"`
+	} `json:"extraartists"`
+	Tracklist []struct {
+		Position string `json:"position"`
+		Title    string `json:"title"`
+		Type     string `json:"type_"`
+	} `json:"tracklist"`
+}
+
+// FindRelease searches Discogs for the release of the album with the
+// given key, preferring one from the given year when it is known, and
+// returns its details. The provider then uses the result for the album's
+// tracks rather than looking it up again.
+func (c *DiscogsClient) FindRelease(ctx context.Context, albumKey, artist, album string, year int) (*domain.AlbumRelease, error) {
+	found, err := c.findRelease(ctx, artist, album, year)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.lookups[albumKey] = found
+	c.mu.Unlock()
+
+	release := found.toAlbumRelease()
+	release.AlbumKey = albumKey
+	return release, nil
+}
+
+func (c *DiscogsClient) findRelease(ctx context.Context, artist, album string, year int) (*discogsRelease, error) {
+	if !c.Status().Configured {
+		return nil, ErrDiscogsNotConfigured
+	}
+
+	var search struct {
+		Results []struct {
+			ID   int    `json:"id"`
+			Year string `json:"year"`
+		} `json:"results"`
+	}
+	params := url.Values{
+		"type":          {"release"},
+		"artist":        {artist},
+		"release_title": {album},
+		"per_page":      {strconv.Itoa(discogsSearchResults)},
+	}
+	if err := c.getJSON(ctx, "database/search", params, &search); err != nil {
+		return nil, err
+	}
+	if len(search.Results) == 0 {
+		return nil, ErrNoMatch
+	}
+
+	// Discogs lists the likeliest match first; a release of the album's
+	// year is more likely the one the files came from
+	id := search.Results[0].ID
+	for _, result := range search.Results {
+		if year > 0 && result.Year == strconv.Itoa(year) {
+			id = result.ID
+			break
+		}
+	}
+
+	var release discogsRelease
+	if err := c.getJSON(ctx, "releases/"+strconv.Itoa(id), nil, &release); err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+func (r *discogsRelease) toAlbumRelease() *domain.AlbumRelease {
+	release := &domain.AlbumRelease{
+		Source:    "discogs",
+		ReleaseID: strconv.Itoa(r.ID),
+		URL:       r.URI,
+		Title:     r.Title,
+		Artist:    r.artist(),
+		Country:   r.Country,
+		Year:      r.Year,
+		Genres:    r.Genres,
+		Styles:    r.Styles,
+		FetchedAt: time.Now(),
+	}
+	if len(r.Labels) > 0 {
+		release.Label = discogsName(r.Labels[0].Name)
+		release.CatalogNumber = r.Labels[0].Catno
+	}
+	for _, credit := range r.ExtraArtists {
+		release.Credits = append(release.Credits, domain.ReleaseCredit{
+			Name:   discogsName(credit.Name),
+			Role:   credit.Role,
+			Tracks: credit.Tracks,
+		})
+	}
+	return release
+}
+
+// artist joins the release's artists as Discogs credits them
+func (r *discogsRelease) artist() string {
+	var b strings.Builder
+	for i, artist := range r.Artists {
+		b.WriteString(discogsName(artist.Name))
+		if i < len(r.Artists)-1 {
+			if join := strings.TrimSpace(artist.Join); join != "" && join != "," {
+				b.WriteString(" " + join + " ")
+			} else {
+				b.WriteString(", ")
+			}
+		}
+	}
+	return b.String()
+}
+
+// trackNumber returns the place of a title among the release's tracks,
+// counting from one, or 0 if it isn't on the release
+func (r *discogsRelease) trackNumber(title string) int {
+	want := domain.FoldText(title)
+	number := 0
+	for _, entry := range r.Tracklist {
+		if entry.Type != "" && entry.Type != "track" {
+			continue // Headings and index tracks
+		}
+		number++
+		if domain.FoldText(entry.Title) == want {
+			return number
+		}
+	}
+	return 0
+}
+
+// discogsName drops the number Discogs adds to tell names apart
+func discogsName(name string) string {
+	return discogsNameSuffix.ReplaceAllString(strings.TrimSpace(name), "")
+}
+
+// discogsProvider fills in tracks from the Discogs release of their album
+type discogsProvider struct {
+	client *DiscogsClient
+}
+
+// Provider returns a metadata provider looking tracks' albums up on
+// Discogs. Each album is looked up once, however many of its tracks are
+// asked about.
+func (c *DiscogsClient) Provider() Provider {
+	return &discogsProvider{client: c}
+}
+
+func (p *discogsProvider) Name() string {
+	return "discogs"
+}
+
+func (p *discogsProvider) Lookup(ctx context.Context, track *domain.Track) (*Fields, error) {
+	key := track.AlbumKey()
+	if key == "" {
+		return nil, ErrNoMatch
+	}
+	artist := track.AlbumArtist
+	if artist == "" {
+		artist = track.Artist
+	}
+
+	c := p.client
+	c.mu.Lock()
+	found, seen := c.lookups[key]
+	c.mu.Unlock()
+	if !seen {
+		var err error
+		found, err = c.findRelease(ctx, artist, track.Album, track.Year)
+		if err != nil && !errors.Is(err, ErrNoMatch) {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.lookups[key] = found
+		c.mu.Unlock()
+	}
+	if found == nil {
+		return nil, ErrNoMatch
+	}
+
+	release := found.toAlbumRelease()
+	fields := &Fields{
+		Album:       release.Title,
+		AlbumArtist: release.Artist,
+		Publisher:   release.Label,
+		Year:        release.Year,
+		TrackNumber: found.trackNumber(track.Title),
+	}
+	if len(release.Genres) > 0 {
+		fields.Genre = release.Genres[0]
+	}
+	return fields, nil
+}
+
+// getJSON performs an authenticated API request
+func (c *DiscogsClient) getJSON(ctx context.Context, path string, params url.Values, v interface{}) error {
+	requestURL := c.endpoint + path
+	if len(params) > 0 {
+		requestURL += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	c.mu.Lock()
+	switch {
+	case c.token != nil:
+		req.Header.Set("Authorization", oauthHeader(c.consumerKey, c.consumerSecret, c.token, nil))
+	case c.personalToken != "":
+		req.Header.Set("Authorization", "Discogs token="+c.personalToken)
+	case c.consumerKey != "":
+		req.Header.Set("Authorization", fmt.Sprintf("Discogs key=%s, secret=%s", c.consumerKey, c.consumerSecret))
+	}
+	c.mu.Unlock()
+
+	body, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// oauthRequest performs a step of the OAuth flow, signed with token, and
+// returns the form values Discogs answers with
+func (c *DiscogsClient) oauthRequest(ctx context.Context, method, path string, token *DiscogsToken, extra map[string]string) (url.Values, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", oauthHeader(c.consumerKey, c.consumerSecret, token, extra))
+
+	body, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return url.ParseQuery(string(body))
+}
+
+// do sends a request unless offline, spacing requests to stay within the
+// rate limit, and returns the response body
+func (c *DiscogsClient) do(req *http.Request) ([]byte, error) {
+	if !c.online.Online() {
+		return nil, connectivity.ErrOffline
+	}
+
+	c.rateMu.Lock()
+	if wait := c.interval - time.Since(c.lastRequest); wait > 0 {
+		select {
+		case <-req.Context().Done():
+			c.rateMu.Unlock()
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+	c.lastRequest = time.Now()
+	c.rateMu.Unlock()
+
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if req.Context().Err() == nil {
+			c.online.ReportFailure()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrDiscogsUnauthorized
+	case http.StatusNotFound:
+		return nil, ErrNoMatch
+	case http.StatusTooManyRequests:
+		return nil, ErrDiscogsRateLimited
+	default:
+		return nil, fmt.Errorf("discogs request failed with status %d", resp.StatusCode)
+	}
+}
+
+// oauthHeader builds an OAuth 1.0a Authorization header. Discogs accepts
+// PLAINTEXT signatures over HTTPS, which are the two secrets joined.
+func oauthHeader(consumerKey, consumerSecret string, token *DiscogsToken, extra map[string]string) string {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+
+	params := map[string]string{
+		"oauth_consumer_key":     consumerKey,
+		"oauth_nonce":            hex.EncodeToString(nonce),
+		"oauth_signature_method": "PLAINTEXT",
+		"oauth_timestamp":        strconv.FormatInt(time.Now().Unix(), 10),
+		"oauth_version":          "1.0",
+		"oauth_signature":        oauthEscape(consumerSecret) + "&" + oauthEscape(token.Secret),
+	}
+	if token.Token != "" {
+		params["oauth_token"] = token.Token
+	}
+	for key, value := range extra {
+		params[key] = value
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf(`%s="%s"`, key, oauthEscape(params[key]))
+	}
+	return "OAuth " + strings.Join(parts, ", ")
+}
+
+// oauthEscape percent-encodes as OAuth requires, which differs from query
+// escaping in encoding spaces as %20
+func oauthEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,170 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+const discogsTestRelease = `{
+	"id": 249504,
+	"title": "Nevermind",
+	"uri": "https://www.discogs.com/release/249504",
+	"country": "US",
+	"year": 1991,
+	"artists": [{"name": "Nirvana (2)", "join": ""}],
+	"labels": [{"name": "DGC", "catno": "DGC-24425"}],
+	"genres": ["Rock"],
+	"styles": ["Grunge"],
+	"extraartists": [{"name": "Butch Vig", "role": "Producer", "tracks": ""}],
+	"tracklist": [
+		{"position": "", "title": "Side A", "type_": "heading"},
+		{"position": "A1", "title": "Smells Like Teen Spirit", "type_": "track"},
+		{"position": "A2", "title": "In Bloom", "type_": "track"}
+	]
+}`
+
+// newDiscogsTestServer serves a search, a release and the OAuth steps,
+// counting the requests made to each path
+func newDiscogsTestServer(t *testing.T) (*httptest.Server, map[string]int) {
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		auth := r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/database/search":
+			assert.Equal(t, "Discogs token=secret", auth)
+			assert.Equal(t, "release", r.URL.Query().Get("type"))
+			fmt.Fprint(w, `{"results": [{"id": 1, "year": "2011"}, {"id": 249504, "year": "1991"}]}`)
+		case "/releases/249504":
+			fmt.Fprint(w, discogsTestRelease)
+		case "/oauth/request_token":
+			assert.Contains(t, auth, `oauth_signature="consumer-secret%26"`)
+			assert.Contains(t, auth, `oauth_callback="oob"`)
+			fmt.Fprint(w, "oauth_token=request&oauth_token_secret=request-secret")
+		case "/oauth/access_token":
+			assert.Contains(t, auth, `oauth_token="request"`)
+			assert.Contains(t, auth, `oauth_signature="consumer-secret%26request-secret"`)
+			assert.Contains(t, auth, `oauth_verifier="code"`)
+			fmt.Fprint(w, "oauth_token=access&oauth_token_secret=access-secret")
+		case "/oauth/identity":
+			assert.Contains(t, auth, `oauth_token="access"`)
+			fmt.Fprint(w, `{"username": "listener"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func newTestDiscogsClient(server *httptest.Server, key, secret, token, tokenPath string) *DiscogsClient {
+	c := NewDiscogsClient(key, secret, token, tokenPath, time.Second)
+	c.endpoint = server.URL + "/"
+	c.interval = 0
+	return c
+}
+
+func TestDiscogsFindRelease(t *testing.T) {
+	server, requests := newDiscogsTestServer(t)
+	c := newTestDiscogsClient(server, "", "", "secret", filepath.Join(t.TempDir(), "token.json"))
+
+	release, err := c.FindRelease(context.Background(), "nirvana\x00nevermind", "Nirvana", "Nevermind", 1991)
+	require.NoError(t, err)
+	assert.Equal(t, "nirvana\x00nevermind", release.AlbumKey)
+	assert.Equal(t, "249504", release.ReleaseID) // The result from the album's year
+	assert.Equal(t, "Nirvana", release.Artist)
+	assert.Equal(t, "DGC", release.Label)
+	assert.Equal(t, "DGC-24425", release.CatalogNumber)
+	assert.Equal(t, []domain.ReleaseCredit{{Name: "Butch Vig", Role: "Producer"}}, release.Credits)
+	require.NoError(t, release.Validate())
+
+	// The provider uses the release just found for the album's tracks
+	fields, err := c.Provider().Lookup(context.Background(), &domain.Track{
+		Title: "In Bloom", Artist: "Nirvana", Album: "Nevermind",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &Fields{
+		Album: "Nevermind", AlbumArtist: "Nirvana", Genre: "Rock", Publisher: "DGC",
+		Year: 1991, TrackNumber: 2,
+	}, fields)
+	assert.Equal(t, 1, requests["/database/search"])
+
+	_, err = c.Provider().Lookup(context.Background(), &domain.Track{Title: "Song"})
+	assert.ErrorIs(t, err, ErrNoMatch)
+}
+
+func TestDiscogsNotConfigured(t *testing.T) {
+	server, requests := newDiscogsTestServer(t)
+	c := newTestDiscogsClient(server, "", "", "", filepath.Join(t.TempDir(), "token.json"))
+
+	_, err := c.FindRelease(context.Background(), "a\x00b", "A", "B", 0)
+	assert.ErrorIs(t, err, ErrDiscogsNotConfigured)
+	_, err = c.BeginAuthorization(context.Background(), "oob")
+	assert.ErrorIs(t, err, ErrDiscogsNotConfigured)
+	assert.Empty(t, requests)
+}
+
+func TestDiscogsAuthorization(t *testing.T) {
+	server, _ := newDiscogsTestServer(t)
+	tokenPath := filepath.Join(t.TempDir(), "token.json")
+	c := newTestDiscogsClient(server, "consumer", "consumer-secret", "", tokenPath)
+
+	_, err := c.CompleteAuthorization(context.Background(), "code")
+	assert.ErrorIs(t, err, ErrDiscogsNoAuthorization)
+
+	authorize, err := c.BeginAuthorization(context.Background(), "oob")
+	require.NoError(t, err)
+	assert.Equal(t, discogsAuthorizeURL+"?oauth_token=request", authorize)
+
+	username, err := c.CompleteAuthorization(context.Background(), " code ")
+	require.NoError(t, err)
+	assert.Equal(t, "listener", username)
+
+	// The token is kept for the next run
+	restarted := newTestDiscogsClient(server, "consumer", "consumer-secret", "", tokenPath)
+	assert.Equal(t, DiscogsStatus{Configured: true, Authorized: true, Username: "listener"}, restarted.Status())
+
+	require.NoError(t, restarted.Disconnect())
+	assert.False(t, restarted.Status().Authorized)
+	assert.False(t, NewDiscogsClient("consumer", "consumer-secret", "", tokenPath, time.Second).Status().Authorized)
+}
+
+func TestDiscogsErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, ErrDiscogsUnauthorized},
+		{http.StatusNotFound, ErrNoMatch},
+		{http.StatusTooManyRequests, ErrDiscogsRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			c := newTestDiscogsClient(server, "", "", "secret", filepath.Join(t.TempDir(), "token.json"))
+			_, err := c.FindRelease(context.Background(), "a\x00b", "A", "B", 0)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestOAuthEscape(t *testing.T) {
+	assert.Equal(t, "a%20b%26c~d", oauthEscape("a b&c~d"))
+	assert.True(t, strings.HasPrefix(oauthHeader("key", "s", &DiscogsToken{}, nil), "OAuth oauth_consumer_key=\"key\", oauth_nonce="))
+}
@@ -0,0 +1,151 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// musicBrainzMinScore is the search score below which a recording is not
+// taken to be the track
+const musicBrainzMinScore = 90
+
+// mbArtistCredit is how MusicBrainz credits artists, joining their names
+// with phrases such as " & " or " feat. "
+type mbArtistCredit []struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+	Artist     struct {
+		ID string `json:"id"`
+	} `json:"artist"`
+}
+
+func (c mbArtistCredit) String() string {
+	var b strings.Builder
+	for _, credit := range c {
+		b.WriteString(credit.Name)
+		b.WriteString(credit.JoinPhrase)
+	}
+	return b.String()
+}
+
+// mbRelease is a release a recording appears on, with where on it
+type mbRelease struct {
+	Title        string         `json:"title"`
+	Date         string         `json:"date"`
+	ArtistCredit mbArtistCredit `json:"artist-credit"`
+	ReleaseGroup struct {
+		PrimaryType string `json:"primary-type"`
+	} `json:"release-group"`
+	TextRepresentation struct {
+		Language string `json:"language"`
+	} `json:"text-representation"`
+	Media []struct {
+		Position int `json:"position"`
+		Track    []struct {
+			Number string `json:"number"`
+		} `json:"track"`
+	} `json:"media"`
+}
+
+// musicBrainzProvider looks tracks up as MusicBrainz recordings by artist
+// and title
+type musicBrainzProvider struct {
+	service *ContextService
+}
+
+// MusicBrainzProvider returns a provider finding tracks on MusicBrainz,
+// sharing the service's rate limit and offline handling
+func (s *ContextService) MusicBrainzProvider() Provider {
+	return &musicBrainzProvider{service: s}
+}
+
+func (p *musicBrainzProvider) Name() string {
+	return "musicbrainz"
+}
+
+// Lookup finds the recording and picks the release it is most likely from:
+// the album the track is tagged with if MusicBrainz has it, else the first
+// album, else the first release
+func (p *musicBrainzProvider) Lookup(ctx context.Context, track *domain.Track) (*Fields, error) {
+	artist, title := strings.TrimSpace(track.Artist), strings.TrimSpace(track.Title)
+	if artist == "" || title == "" {
+		return nil, ErrNoMatch
+	}
+
+	var search struct {
+		Recordings []struct {
+			Title        string         `json:"title"`
+			Score        int            `json:"score"`
+			ArtistCredit mbArtistCredit `json:"artist-credit"`
+			Releases     []mbRelease    `json:"releases"`
+		} `json:"recordings"`
+	}
+
+	params := url.Values{
+		"query": {fmt.Sprintf(`recording:"%s" AND artist:"%s"`, mbEscape(title), mbEscape(artist))},
+		"limit": {"1"},
+		"fmt":   {"json"},
+	}
+	if err := p.service.getMusicBrainz(ctx, musicBrainzEndpoint+"recording/?"+params.Encode(), &search); err != nil {
+		return nil, err
+	}
+	if len(search.Recordings) == 0 || search.Recordings[0].Score < musicBrainzMinScore {
+		return nil, ErrNoMatch
+	}
+
+	recording := search.Recordings[0]
+	fields := &Fields{
+		Title:  recording.Title,
+		Artist: recording.ArtistCredit.String(),
+	}
+	if len(recording.ArtistCredit) > 0 {
+		fields.ArtistMBID = recording.ArtistCredit[0].Artist.ID
+	}
+
+	if release := pickRelease(recording.Releases, track.Album); release != nil {
+		fields.Album = release.Title
+		fields.AlbumArtist = release.ArtistCredit.String()
+		if len(release.Date) >= 4 {
+			fields.Year, _ = strconv.Atoi(release.Date[:4])
+		}
+		fields.Language = release.TextRepresentation.Language
+		if len(release.Media) > 0 {
+			fields.DiscNumber = release.Media[0].Position
+			if len(release.Media[0].Track) > 0 {
+				fields.TrackNumber, _ = strconv.Atoi(release.Media[0].Track[0].Number)
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// pickRelease chooses the release a recording was most likely taken from
+func pickRelease(releases []mbRelease, album string) *mbRelease {
+	if len(releases) == 0 {
+		return nil
+	}
+	if album != "" {
+		for i := range releases {
+			if domain.FoldText(releases[i].Title) == domain.FoldText(album) {
+				return &releases[i]
+			}
+		}
+	}
+	for i := range releases {
+		if releases[i].ReleaseGroup.PrimaryType == "Album" {
+			return &releases[i]
+		}
+	}
+	return &releases[0]
+}
+
+// mbEscape quotes a value for a MusicBrainz search phrase
+func mbEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
@@ -0,0 +1,244 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// ErrNoMatch is returned by a provider that knows nothing about a track
+var ErrNoMatch = errors.New("no metadata found")
+
+// Provider is a source of track metadata, such as the file's own tags or
+// an online database. Lookup returns what the source knows about a track,
+// leaving fields it doesn't know empty.
+type Provider interface {
+	Name() string
+	Lookup(ctx context.Context, track *domain.Track) (*Fields, error)
+}
+
+// Fields are the track fields providers can supply. Zero values are
+// missing.
+type Fields struct {
+	Title       string `json:"title,omitempty"`
+	Artist      string `json:"artist,omitempty"`
+	Album       string `json:"album,omitempty"`
+	AlbumArtist string `json:"albumArtist,omitempty"`
+	Genre       string `json:"genre,omitempty"`
+	Composer    string `json:"composer,omitempty"`
+	Publisher   string `json:"publisher,omitempty"`
+	Year        int    `json:"year,omitempty"`
+	TrackNumber int    `json:"trackNumber,omitempty"`
+	DiscNumber  int    `json:"discNumber,omitempty"`
+	ArtistMBID  string `json:"artistMbid,omitempty"`
+	Language    string `json:"language,omitempty"`
+	Advisory    string `json:"advisory,omitempty"`
+}
+
+// fieldAccess reads and writes each of Fields, by the names the frontend
+// knows them by. Optional fields are ones most tracks rightly lack, so a
+// chain doesn't keep asking providers for them.
+var fieldAccess = []struct {
+	name     string
+	text     func(*Fields) *string
+	num      func(*Fields) *int
+	optional bool
+}{
+	{name: "title", text: func(f *Fields) *string { return &f.Title }},
+	{name: "artist", text: func(f *Fields) *string { return &f.Artist }},
+	{name: "album", text: func(f *Fields) *string { return &f.Album }},
+	{name: "albumArtist", text: func(f *Fields) *string { return &f.AlbumArtist }},
+	{name: "genre", text: func(f *Fields) *string { return &f.Genre }},
+	{name: "composer", text: func(f *Fields) *string { return &f.Composer }},
+	{name: "publisher", text: func(f *Fields) *string { return &f.Publisher }},
+	{name: "year", num: func(f *Fields) *int { return &f.Year }},
+	{name: "trackNumber", num: func(f *Fields) *int { return &f.TrackNumber }},
+	{name: "discNumber", num: func(f *Fields) *int { return &f.DiscNumber }},
+	{name: "artistMbid", text: func(f *Fields) *string { return &f.ArtistMBID }},
+	{name: "language", text: func(f *Fields) *string { return &f.Language }, optional: true},
+	{name: "advisory", text: func(f *Fields) *string { return &f.Advisory }, optional: true},
+}
+
+// FieldsOf returns a track's current values of the fields providers supply
+func FieldsOf(track *domain.Track) *Fields {
+	return &Fields{
+		Title:       track.Title,
+		Artist:      track.Artist,
+		Album:       track.Album,
+		AlbumArtist: track.AlbumArtist,
+		Genre:       track.Genre,
+		Composer:    track.Composer,
+		Publisher:   track.Publisher,
+		Year:        track.Year,
+		TrackNumber: track.TrackNumber,
+		DiscNumber:  track.DiscNumber,
+		ArtistMBID:  track.ArtistMBID,
+		Language:    track.Language,
+		Advisory:    string(track.Advisory),
+	}
+}
+
+// ApplyTo sets a track's fields to these values
+func (f *Fields) ApplyTo(track *domain.Track) {
+	track.Title = f.Title
+	track.Artist = f.Artist
+	track.Album = f.Album
+	track.AlbumArtist = f.AlbumArtist
+	track.Genre = f.Genre
+	track.Composer = f.Composer
+	track.Publisher = f.Publisher
+	track.Year = f.Year
+	track.TrackNumber = f.TrackNumber
+	track.DiscNumber = f.DiscNumber
+	track.ArtistMBID = f.ArtistMBID
+	track.Language = f.Language
+	track.Advisory = domain.Advisory(f.Advisory)
+}
+
+// fill copies the fields f is missing from other, and returns the names of
+// those it copied
+func (f *Fields) fill(other *Fields) []string {
+	var filled []string
+	for _, field := range fieldAccess {
+		if field.text != nil {
+			if dst, src := field.text(f), field.text(other); *dst == "" && *src != "" {
+				*dst = *src
+				filled = append(filled, field.name)
+			}
+		} else if dst, src := field.num(f), field.num(other); *dst == 0 && *src > 0 {
+			*dst = *src
+			filled = append(filled, field.name)
+		}
+	}
+	return filled
+}
+
+// complete reports whether none of the fields are missing, leaving aside
+// optional ones
+func (f *Fields) complete() bool {
+	for _, field := range fieldAccess {
+		if field.optional {
+			continue
+		}
+		if field.text != nil && *field.text(f) == "" || field.num != nil && *field.num(f) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MergeStrategy is how a chain combines what its providers know with what
+// a track already has
+type MergeStrategy string
+
+const (
+	// MergeFill keeps the track's values and fills only the fields it is
+	// missing, each from the first provider that has it
+	MergeFill MergeStrategy = "fill"
+
+	// MergeReplace takes each field from the first provider that has it,
+	// keeping the track's value only when none does
+	MergeReplace MergeStrategy = "replace"
+)
+
+// ParseMergeStrategy parses a merge strategy name
+func ParseMergeStrategy(s string) (MergeStrategy, error) {
+	switch strategy := MergeStrategy(s); strategy {
+	case MergeFill, MergeReplace:
+		return strategy, nil
+	default:
+		return MergeFill, fmt.Errorf("%w: unknown merge strategy %q", domain.ErrInvalidInput, s)
+	}
+}
+
+// Chain asks providers for a track's metadata in priority order and merges
+// their answers
+type Chain struct {
+	providers []Provider
+	strategy  MergeStrategy
+}
+
+// NewChain creates a chain asking providers in the order given
+func NewChain(strategy MergeStrategy, providers ...Provider) *Chain {
+	return &Chain{providers: providers, strategy: strategy}
+}
+
+// Providers returns the names of the chain's providers in priority order
+func (c *Chain) Providers() []string {
+	names := make([]string, len(c.providers))
+	for i, provider := range c.providers {
+		names[i] = provider.Name()
+	}
+	return names
+}
+
+// Enrich fills in a track's metadata from the chain's providers, and
+// returns the fields it changed with the provider each came from. Each
+// provider is shown the track as enriched so far, so one that searches by
+// artist and title can use what an earlier one found. A provider that fails
+// is skipped; with MergeFill, providers stop being asked once nothing is
+// missing.
+func (c *Chain) Enrich(ctx context.Context, track *domain.Track) (map[string]string, error) {
+	current := FieldsOf(track)
+	merged := &Fields{}
+	if c.strategy != MergeReplace {
+		*merged = *current
+	}
+	sources := make(map[string]string)
+
+	for _, provider := range c.providers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if c.strategy != MergeReplace && merged.complete() {
+			break
+		}
+
+		probe := *track
+		probed := *merged
+		probed.fill(current)
+		probed.ApplyTo(&probe)
+
+		found, err := provider.Lookup(ctx, &probe)
+		if err != nil {
+			if !errors.Is(err, ErrNoMatch) {
+				logger.Debug("Metadata provider failed",
+					logger.String("provider", provider.Name()),
+					logger.String("path", track.FilePath),
+					logger.Error(err))
+			}
+			continue
+		}
+		for _, name := range merged.fill(found) {
+			sources[name] = provider.Name()
+		}
+	}
+	merged.fill(current)
+
+	// A provider may agree with the track, which changes nothing
+	for name := range sources {
+		if merged.Value(name) == current.Value(name) {
+			delete(sources, name)
+		}
+	}
+	merged.ApplyTo(track)
+	return sources, nil
+}
+
+// Value returns one of the fields by the name Chain.Enrich reports it
+// under, or nil for an unknown name
+func (f *Fields) Value(name string) interface{} {
+	for _, field := range fieldAccess {
+		if field.name != name {
+			continue
+		}
+		if field.text != nil {
+			return *field.text(f)
+		}
+		return *field.num(f)
+	}
+	return nil
+}
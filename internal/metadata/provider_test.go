@@ -0,0 +1,111 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// fakeProvider answers every lookup with the same fields, recording the
+// tracks it was shown
+type fakeProvider struct {
+	name   string
+	fields *Fields
+	err    error
+	seen   []domain.Track
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) Lookup(ctx context.Context, track *domain.Track) (*Fields, error) {
+	p.seen = append(p.seen, *track)
+	if p.err != nil {
+		return nil, p.err
+	}
+	copied := *p.fields
+	return &copied, nil
+}
+
+func TestChainFill(t *testing.T) {
+	filename := &fakeProvider{name: "filename", fields: &Fields{Artist: "Band", Title: "Song", TrackNumber: 3}}
+	broken := &fakeProvider{name: "broken", err: errors.New("connection refused")}
+	online := &fakeProvider{name: "online", fields: &Fields{Artist: "The Band", Album: "Debut", Year: 1999, TrackNumber: 4}}
+
+	track := &domain.Track{ID: "1", Title: "Song (Live)"}
+	changed, err := NewChain(MergeFill, filename, broken, online).Enrich(context.Background(), track)
+	require.NoError(t, err)
+
+	// The track's own title stays, and each gap is filled by the first
+	// provider with an answer
+	assert.Equal(t, "Song (Live)", track.Title)
+	assert.Equal(t, "Band", track.Artist)
+	assert.Equal(t, 3, track.TrackNumber)
+	assert.Equal(t, "Debut", track.Album)
+	assert.Equal(t, 1999, track.Year)
+	assert.Equal(t, map[string]string{
+		"artist":      "filename",
+		"trackNumber": "filename",
+		"album":       "online",
+		"year":        "online",
+	}, changed)
+
+	// Later providers search with what earlier ones found
+	require.Len(t, online.seen, 1)
+	assert.Equal(t, "Band", online.seen[0].Artist)
+	assert.Equal(t, "Song (Live)", online.seen[0].Title)
+}
+
+func TestChainReplace(t *testing.T) {
+	online := &fakeProvider{name: "online", fields: &Fields{Artist: "The Band", Title: "Song", Year: 1999}}
+	track := &domain.Track{Title: "Song", Artist: "band", Genre: "Rock", Year: 2001}
+
+	changed, err := NewChain(MergeReplace, online).Enrich(context.Background(), track)
+	require.NoError(t, err)
+
+	assert.Equal(t, "The Band", track.Artist)
+	assert.Equal(t, 1999, track.Year)
+	assert.Equal(t, "Rock", track.Genre, "kept when no provider has it")
+	assert.Equal(t, map[string]string{"artist": "online", "year": "online"}, changed)
+}
+
+func TestChainStopsWhenComplete(t *testing.T) {
+	complete := &Fields{
+		Title: "t", Artist: "a", Album: "b", AlbumArtist: "a", Genre: "g", Composer: "c",
+		Publisher: "p", Year: 2000, TrackNumber: 1, DiscNumber: 1, ArtistMBID: "id",
+	}
+	first := &fakeProvider{name: "first", fields: complete}
+	second := &fakeProvider{name: "second", fields: &Fields{}}
+
+	_, err := NewChain(MergeFill, first, second).Enrich(context.Background(), &domain.Track{})
+	require.NoError(t, err)
+	assert.Empty(t, second.seen)
+	assert.Equal(t, []string{"first", "second"}, NewChain(MergeFill, first, second).Providers())
+}
+
+func TestPickRelease(t *testing.T) {
+	releases := []mbRelease{
+		{Title: "Greatest Hits"},
+		{Title: "Debut"},
+		{Title: "Live à Paris"},
+	}
+	releases[1].ReleaseGroup.PrimaryType = "Album"
+
+	assert.Equal(t, "Live à Paris", pickRelease(releases, "LIVE A PARIS").Title)
+	assert.Equal(t, "Debut", pickRelease(releases, "Unknown").Title)
+	assert.Equal(t, "Greatest Hits", pickRelease(releases[:1], "").Title)
+	assert.Nil(t, pickRelease(nil, "Debut"))
+}
+
+func TestParseMergeStrategy(t *testing.T) {
+	strategy, err := ParseMergeStrategy("replace")
+	require.NoError(t, err)
+	assert.Equal(t, MergeReplace, strategy)
+
+	_, err = ParseMergeStrategy("append")
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
@@ -59,12 +59,13 @@ type StreamManager struct {
 func NewStreamManager() *StreamManager {
 	return &StreamManager{
 		streams: make(map[string]*Stream),
+		// No overall timeout: the body is read for as long as the stream plays
 		client: &http.Client{
-			Timeout: 30 * time.Second,
 			Transport: &http.Transport{
-				MaxIdleConns:        10,
-				MaxIdleConnsPerHost: 5,
-				IdleConnTimeout:     90 * time.Second,
+				MaxIdleConns:          10,
+				MaxIdleConnsPerHost:   5,
+				IdleConnTimeout:       90 * time.Second,
+				ResponseHeaderTimeout: 30 * time.Second,
 			},
 		},
 		cache: NewStreamCache(),
@@ -83,8 +84,8 @@ func (m *StreamManager) OpenStream(ctx context.Context, streamURL string) (*Stre
 		return nil, fmt.Errorf("%w: scheme %s not supported", ErrInvalidURL, u.Scheme)
 	}
 	
-	// Check cache
-	if cached := m.cache.Get(streamURL); cached != nil {
+	// Check cache; a stream that has been closed is reconnected
+	if cached := m.cache.Get(streamURL); cached != nil && cached.isOpen() {
 		return cached, nil
 	}
 	
@@ -176,6 +177,12 @@ func (s *Stream) Read(p []byte) (n int, err error) {
 	return reader.Read(p)
 }
 
+func (s *Stream) isOpen() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reader != nil
+}
+
 // Close closes the stream
 func (s *Stream) Close() error {
 	s.mu.Lock()
@@ -0,0 +1,26 @@
+package playlist
+
+import (
+	"github.com/winramp/winramp/internal/events"
+)
+
+// Topics the playlist manager publishes to its event bus. Both carry the
+// playlist ID.
+var (
+	TopicPlaylistChanged = events.NewTopic[string]("playlist:changed")
+	TopicPlaylistDeleted = events.NewTopic[string]("playlist:deleted")
+)
+
+// SetEventBus sets the bus playlist events are published to
+func (m *Manager) SetEventBus(bus *events.Bus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bus = bus
+}
+
+// eventBus returns the bus set with SetEventBus, or nil
+func (m *Manager) eventBus() *events.Bus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.bus
+}
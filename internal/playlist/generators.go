@@ -0,0 +1,233 @@
+package playlist
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+const (
+	// forgottenAfter is how long a favorite goes unplayed before it counts
+	// as forgotten
+	forgottenAfter = 365 * 24 * time.Hour
+
+	// favoriteRating is the lowest rating that makes a track a favorite
+	favoriteRating = 4
+
+	// onThisDayYears is how many years back "On this day" looks
+	onThisDayYears = 20
+
+	// generatedLimit caps the tracks a history generator returns
+	generatedLimit = 50
+)
+
+// Generator builds a playlist's tracks, such as from the library and
+// listening history. Smart playlists made from one are refreshed by running
+// it again.
+type Generator struct {
+	ID          string
+	Name        string
+	Description string
+	Generate    func(now time.Time) ([]*domain.Track, error)
+}
+
+// HistoryGenerators returns the generators that rediscover music from the
+// play history: forgotten favorites and what was playing on this day in
+// years gone by
+func HistoryGenerators(trackRepo domain.TrackRepository, historyRepo domain.PlayHistoryRepository) []Generator {
+	return []Generator{
+		{
+			ID:          "forgotten_favorites",
+			Name:        "Forgotten Favorites",
+			Description: "Highly rated tracks not played for a year",
+			Generate: func(now time.Time) ([]*domain.Track, error) {
+				return forgottenFavorites(trackRepo, historyRepo, now)
+			},
+		},
+		{
+			ID:          "on_this_day",
+			Name:        "On This Day",
+			Description: "Tracks played on this date in earlier years",
+			Generate: func(now time.Time) ([]*domain.Track, error) {
+				return onThisDay(trackRepo, historyRepo, now)
+			},
+		},
+	}
+}
+
+// forgottenFavorites returns favorite or highly rated tracks that have been
+// in the library for over a year without being played in it, best rated
+// first
+func forgottenFavorites(trackRepo domain.TrackRepository, historyRepo domain.PlayHistoryRepository, now time.Time) ([]*domain.Track, error) {
+	cutoff := now.Add(-forgottenAfter)
+	entries, err := historyRepo.FindSince(cutoff)
+	if err != nil {
+		return nil, err
+	}
+	played := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		played[entry.TrackID] = true
+	}
+
+	tracks, err := trackRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var forgotten []*domain.Track
+	for _, track := range tracks {
+		if track.Rating < favoriteRating && !track.Favorite {
+			continue
+		}
+		if played[track.ID] || track.IsAudiobook || track.DateAdded.After(cutoff) {
+			continue
+		}
+		if track.LastPlayed != nil && track.LastPlayed.After(cutoff) {
+			continue
+		}
+		forgotten = append(forgotten, track)
+	}
+
+	sort.SliceStable(forgotten, func(i, j int) bool {
+		if forgotten[i].Rating != forgotten[j].Rating {
+			return forgotten[i].Rating > forgotten[j].Rating
+		}
+		return forgotten[i].PlayCount > forgotten[j].PlayCount
+	})
+	if len(forgotten) > generatedLimit {
+		forgotten = forgotten[:generatedLimit]
+	}
+	return forgotten, nil
+}
+
+// onThisDay returns the tracks played on today's date in earlier years,
+// most played first, then most recent
+func onThisDay(trackRepo domain.TrackRepository, historyRepo domain.PlayHistoryRepository, now time.Time) ([]*domain.Track, error) {
+	counts := make(map[string]int)
+	var order []string // Most recent year first
+	for years := 1; years <= onThisDayYears; years++ {
+		day := time.Date(now.Year()-years, now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		entries, err := historyRepo.FindBetween(day, day.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if counts[entry.TrackID] == 0 {
+				order = append(order, entry.TrackID)
+			}
+			counts[entry.TrackID]++
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return counts[order[i]] > counts[order[j]]
+	})
+
+	var tracks []*domain.Track
+	for _, id := range order {
+		if len(tracks) == generatedLimit {
+			break
+		}
+		track, err := trackRepo.FindByID(id)
+		if err != nil || track == nil {
+			continue // Removed from the library since
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks, nil
+}
+
+// AddGenerator registers a generator as a smart playlist, creating the
+// playlist the first time, and fills it. It returns the playlist.
+func (m *Manager) AddGenerator(g Generator) (*domain.Playlist, error) {
+	m.mu.Lock()
+	if m.generators == nil {
+		m.generators = make(map[string]Generator)
+	}
+	m.generators[g.ID] = g
+
+	var playlist *domain.Playlist
+	for _, pl := range m.playlists {
+		if pl.Generator == g.ID {
+			playlist = pl
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	if playlist == nil {
+		var err error
+		playlist, err = domain.NewPlaylist(g.Name, domain.PlaylistTypeSmart)
+		if err != nil {
+			return nil, err
+		}
+		playlist.Description = g.Description
+		playlist.Generator = g.ID
+
+		m.mu.Lock()
+		m.playlists[playlist.ID] = playlist
+		m.mu.Unlock()
+		if m.repo != nil {
+			if err := m.repo.Create(playlist); err != nil {
+				logger.ErrorLog("Failed to save playlist", logger.Error(err))
+			}
+		}
+	}
+
+	if err := m.Refresh(playlist.ID); err != nil {
+		return nil, err
+	}
+	return playlist, nil
+}
+
+// Refresh rebuilds a smart playlist's tracks, by running its generator or
+// finding the tracks that meet its rules
+func (m *Manager) Refresh(id string) error {
+	playlist, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+
+	var tracks []*domain.Track
+	switch {
+	case playlist.IsGenerated():
+		tracks, err = m.generate(playlist)
+	case playlist.IsRuleBased():
+		tracks, err = m.findByRules(playlist.Rules)
+	default:
+		return fmt.Errorf("%w: %s is not a smart playlist", domain.ErrInvalidPlaylist, playlist.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to refresh %s: %w", playlist.Name, err)
+	}
+
+	playlist.SetTracks(tracks)
+	return m.Update(playlist)
+}
+
+// generate runs a generated playlist's generator
+func (m *Manager) generate(playlist *domain.Playlist) ([]*domain.Track, error) {
+	m.mu.RLock()
+	g, ok := m.generators[playlist.Generator]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown generator %q", domain.ErrInvalidPlaylist, playlist.Generator)
+	}
+	return g.Generate(time.Now())
+}
+
+// RefreshGenerated rebuilds every generated playlist, as when the day
+// changes. Failures are logged and the rest carry on.
+func (m *Manager) RefreshGenerated() {
+	for _, playlist := range m.GetAll() {
+		if !playlist.IsGenerated() {
+			continue
+		}
+		if err := m.Refresh(playlist.ID); err != nil {
+			logger.Warn("Failed to refresh playlist", logger.String("playlist", playlist.Name), logger.Error(err))
+		}
+	}
+}
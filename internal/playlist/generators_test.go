@@ -0,0 +1,126 @@
+package playlist
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestForgottenFavorites(t *testing.T) {
+	now := time.Date(2026, 6, 15, 20, 0, 0, 0, time.UTC)
+	longAgo := now.AddDate(-3, 0, 0)
+	recently := now.AddDate(0, -1, 0)
+
+	tracks := &fakeTracks{tracks: []*domain.Track{
+		{ID: "loved", Rating: 5, DateAdded: longAgo},
+		{ID: "liked", Rating: 4, DateAdded: longAgo, PlayCount: 9},
+		{ID: "favorite", Favorite: true, DateAdded: longAgo, PlayCount: 12},
+		{ID: "played", Rating: 5, DateAdded: longAgo},
+		{ID: "imported", Rating: 5, DateAdded: longAgo, LastPlayed: &recently},
+		{ID: "new", Rating: 5, DateAdded: recently},
+		{ID: "meh", Rating: 3, DateAdded: longAgo},
+		{ID: "book", Rating: 5, DateAdded: longAgo, IsAudiobook: true},
+	}}
+	history := &fakeHistory{entries: []*domain.PlayHistoryEntry{
+		{TrackID: "played", PlayedAt: now.AddDate(0, -2, 0)},
+		{TrackID: "loved", PlayedAt: now.AddDate(-2, 0, 0)},
+	}}
+
+	result, err := forgottenFavorites(tracks, history, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"loved", "liked", "favorite"}, trackIDs(result))
+}
+
+func TestOnThisDay(t *testing.T) {
+	now := time.Date(2026, 6, 15, 9, 0, 0, 0, time.UTC)
+	tracks := &fakeTracks{tracks: []*domain.Track{{ID: "a"}, {ID: "b"}, {ID: "c"}}}
+	history := &fakeHistory{entries: []*domain.PlayHistoryEntry{
+		{TrackID: "a", PlayedAt: time.Date(2025, 6, 15, 22, 0, 0, 0, time.UTC)},
+		{TrackID: "b", PlayedAt: time.Date(2024, 6, 15, 8, 0, 0, 0, time.UTC)},
+		{TrackID: "b", PlayedAt: time.Date(2020, 6, 15, 8, 0, 0, 0, time.UTC)},
+		{TrackID: "c", PlayedAt: time.Date(2025, 6, 16, 0, 0, 0, 0, time.UTC)}, // The day after
+		{TrackID: "c", PlayedAt: time.Date(2026, 6, 15, 8, 0, 0, 0, time.UTC)}, // This year
+		{TrackID: "gone", PlayedAt: time.Date(2023, 6, 15, 8, 0, 0, 0, time.UTC)},
+	}}
+
+	result, err := onThisDay(tracks, history, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "a"}, trackIDs(result))
+}
+
+func TestAddGenerator(t *testing.T) {
+	m := NewManager(nil)
+	calls := 0
+	g := Generator{ID: "test", Name: "Test", Generate: func(now time.Time) ([]*domain.Track, error) {
+		calls++
+		return []*domain.Track{{ID: "a"}, {ID: "b"}}, nil
+	}}
+
+	playlist, err := m.AddGenerator(g)
+	require.NoError(t, err)
+	assert.True(t, playlist.IsGenerated())
+	assert.Equal(t, []string{"a", "b"}, playlist.TrackIDs)
+	assert.NoError(t, playlist.Validate())
+
+	// Registering again reuses the playlist
+	again, err := m.AddGenerator(g)
+	require.NoError(t, err)
+	assert.Equal(t, playlist.ID, again.ID)
+	assert.Len(t, m.GetAll(), 1)
+
+	m.RefreshGenerated()
+	assert.Equal(t, 3, calls)
+
+	static, err := m.Create("Static")
+	require.NoError(t, err)
+	assert.ErrorIs(t, m.Refresh(static.ID), domain.ErrInvalidPlaylist)
+	assert.ErrorIs(t, m.Refresh("missing"), ErrPlaylistNotFound)
+}
+
+type fakeTracks struct {
+	domain.TrackRepository
+	tracks []*domain.Track
+}
+
+func (f *fakeTracks) FindAll() ([]*domain.Track, error) { return f.tracks, nil }
+
+func (f *fakeTracks) FindByID(id string) (*domain.Track, error) {
+	for _, track := range f.tracks {
+		if track.ID == id {
+			return track, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+type fakeHistory struct {
+	domain.PlayHistoryRepository
+	entries []*domain.PlayHistoryEntry
+}
+
+func (f *fakeHistory) FindSince(since time.Time) ([]*domain.PlayHistoryEntry, error) {
+	return f.FindBetween(since, time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func (f *fakeHistory) FindBetween(from, to time.Time) ([]*domain.PlayHistoryEntry, error) {
+	var entries []*domain.PlayHistoryEntry
+	for _, entry := range f.entries {
+		if !entry.PlayedAt.Before(from) && entry.PlayedAt.Before(to) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func trackIDs(tracks []*domain.Track) []string {
+	ids := make([]string, len(tracks))
+	for i, track := range tracks {
+		ids[i] = track.ID
+	}
+	return ids
+}
@@ -0,0 +1,81 @@
+package playlist
+
+import (
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// maxHistory is how many played tracks a session remembers
+const maxHistory = 100
+
+// PlayedEntry is one track played this session
+type PlayedEntry struct {
+	Track    *domain.Track
+	PlayedAt time.Time
+}
+
+// History is the stack of tracks played this session, most recent on top.
+// The top entry is the track playing now. Entries hold the tracks
+// themselves, so going back works for tracks that have since left the
+// queue and for transient entries.
+type History struct {
+	entries []PlayedEntry
+	mu      sync.RWMutex
+}
+
+// NewHistory creates an empty history
+func NewHistory() *History {
+	return &History{entries: make([]PlayedEntry, 0, maxHistory)}
+}
+
+// Push records a track as played now
+func (h *History) Push(track *domain.Track) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = append(h.entries, PlayedEntry{Track: track, PlayedAt: time.Now()})
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+}
+
+// Back drops the current track and returns the one played before it, which
+// becomes current. It returns nil and leaves the history alone when
+// nothing was played before the current track.
+func (h *History) Back() *domain.Track {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.entries) < 2 {
+		return nil
+	}
+
+	h.entries = h.entries[:len(h.entries)-1]
+	return h.entries[len(h.entries)-1].Track
+}
+
+// Entries returns up to limit entries, most recent first. A limit of zero
+// or less returns them all.
+func (h *History) Entries(limit int) []PlayedEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if limit <= 0 || limit > len(h.entries) {
+		limit = len(h.entries)
+	}
+
+	entries := make([]PlayedEntry, 0, limit)
+	for i := len(h.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, h.entries[i])
+	}
+	return entries
+}
+
+// Len returns the number of entries
+func (h *History) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.entries)
+}
@@ -3,16 +3,20 @@ package playlist
 import (
 	"errors"
 	"fmt"
+	"math/rand/v2"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
 	"github.com/winramp/winramp/internal/logger"
 )
 
 var (
 	ErrPlaylistNotFound = errors.New("playlist not found")
 	ErrEmptyQueue       = errors.New("queue is empty")
+	ErrQueueIndex       = errors.New("queue index out of range")
 )
 
 // Manager manages playlists and playback queue
@@ -20,8 +24,12 @@ type Manager struct {
 	playlists      map[string]*domain.Playlist
 	currentPlaylist *domain.Playlist
 	queue          *Queue
-	history        []string // Track IDs
+	history        *History
+	generators     map[string]Generator // By ID; see AddGenerator
 	repo           domain.PlaylistRepository
+	trackRepo      domain.TrackRepository // Finds the tracks meeting smart playlist rules
+	smartRefresh   *time.Timer            // Pending refresh after library changes; see LibraryChanged
+	bus            *events.Bus
 	mu             sync.RWMutex
 }
 
@@ -30,7 +38,7 @@ func NewManager(repo domain.PlaylistRepository) *Manager {
 	m := &Manager{
 		playlists: make(map[string]*domain.Playlist),
 		queue:     NewQueue(),
-		history:   make([]string, 0, 100),
+		history:   NewHistory(),
 		repo:      repo,
 	}
 	
@@ -74,6 +82,7 @@ func (m *Manager) Create(name string) (*domain.Playlist, error) {
 		}
 	}
 	
+	events.Publish(m.eventBus(), TopicPlaylistChanged, playlist.ID)
 	return playlist, nil
 }
 
@@ -103,6 +112,24 @@ func (m *Manager) GetAll() []*domain.Playlist {
 	return playlists
 }
 
+// FindContaining returns the playlists a track is on
+func (m *Manager) FindContaining(trackID string) []*domain.Playlist {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	var playlists []*domain.Playlist
+	for _, pl := range m.playlists {
+		for _, track := range pl.Tracks {
+			if track.ID == trackID {
+				playlists = append(playlists, pl)
+				break
+			}
+		}
+	}
+	
+	return playlists
+}
+
 // Update updates a playlist
 func (m *Manager) Update(playlist *domain.Playlist) error {
 	if playlist == nil {
@@ -120,6 +147,7 @@ func (m *Manager) Update(playlist *domain.Playlist) error {
 		}
 	}
 	
+	events.Publish(m.eventBus(), TopicPlaylistChanged, playlist.ID)
 	return nil
 }
 
@@ -141,6 +169,7 @@ func (m *Manager) Delete(id string) error {
 		}
 	}
 	
+	events.Publish(m.bus, TopicPlaylistDeleted, id)
 	return nil
 }
 
@@ -172,6 +201,20 @@ func (m *Manager) RemoveTrack(playlistID, trackID string) error {
 	return m.Update(playlist)
 }
 
+// Sort orders a playlist's tracks by a field; see domain.Playlist.Sort
+func (m *Manager) Sort(playlistID, field string, descending bool) error {
+	playlist, err := m.Get(playlistID)
+	if err != nil {
+		return err
+	}
+	
+	if err := playlist.Sort(field, descending); err != nil {
+		return err
+	}
+	
+	return m.Update(playlist)
+}
+
 // SetCurrentPlaylist sets the current playlist
 func (m *Manager) SetCurrentPlaylist(id string) error {
 	playlist, err := m.Get(id)
@@ -202,43 +245,58 @@ func (m *Manager) GetCurrentPlaylist() *domain.Playlist {
 	return m.currentPlaylist
 }
 
-// GetNextTrack returns the next track to play
+// GetNextTrack moves the queue on when the user skips ahead and returns
+// the new current track. Skipping leaves the current track even when it is
+// set to repeat.
 func (m *Manager) GetNextTrack() *domain.Track {
 	track := m.queue.Next()
 	if track != nil {
-		m.addToHistory(track.ID)
+		m.history.Push(track)
 	}
 	return track
 }
 
-// GetPreviousTrack returns the previous track from history
-func (m *Manager) GetPreviousTrack() *domain.Track {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	
-	if len(m.history) < 2 {
-		return nil
+// JumpTo makes the track at a queue index current, as when the user picks
+// it from the queue, and returns it
+func (m *Manager) JumpTo(index int) (*domain.Track, error) {
+	track, err := m.queue.JumpTo(index)
+	if err != nil {
+		return nil, err
 	}
-	
-	// Remove current track from history
-	m.history = m.history[:len(m.history)-1]
-	
-	// Get previous track ID
-	trackID := m.history[len(m.history)-1]
-	
-	// Find track in current playlist
-	if m.currentPlaylist != nil {
-		for _, track := range m.currentPlaylist.Tracks {
-			if track.ID == trackID {
-				return track
-			}
-		}
+	m.history.Push(track)
+	return track, nil
+}
+
+// FollowTrack keeps the queue in step when the player moves on to the
+// queue's next track by itself, as in a gapless transition. It returns
+// false when the track is not the one the queue would play next, such as
+// a track the user picked.
+func (m *Manager) FollowTrack(track *domain.Track) bool {
+	previous := m.queue.Current()
+	if !m.queue.AdvanceTo(track) {
+		return false
 	}
 	
-	return nil
+	// A repeated track is not a new entry in the history
+	if track != previous {
+		m.history.Push(track)
+	}
+	return true
 }
 
-// PeekNextTrack returns the next track without removing it from queue
+// GetPreviousTrack goes back to the track played before the current one
+// and returns it. When that track is still in the queue it becomes the
+// queue's current track, so playback carries on from there.
+func (m *Manager) GetPreviousTrack() *domain.Track {
+	track := m.history.Back()
+	if track != nil {
+		m.queue.MoveTo(track)
+	}
+	return track
+}
+
+// PeekNextTrack returns the track that plays when the current one ends,
+// without moving the queue
 func (m *Manager) PeekNextTrack() *domain.Track {
 	return m.queue.Peek()
 }
@@ -258,39 +316,57 @@ func (m *Manager) AddToQueueNext(track *domain.Track) {
 	m.queue.AddNext(track)
 }
 
-// ClearQueue clears the queue
-func (m *Manager) ClearQueue() {
-	m.queue.Clear()
+// AddTracksToQueue adds tracks to the end of the queue, or to play next
+// in order, as one change
+func (m *Manager) AddTracksToQueue(tracks []*domain.Track, next bool) {
+	if next {
+		m.queue.AddAllNext(tracks)
+	} else {
+		m.queue.AddAll(tracks)
+	}
 }
 
-// GetHistory returns the playback history
-func (m *Manager) GetHistory() []string {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// PlayTracks puts tracks in the queue and returns the first of them, which
+// becomes the current track. With replace the queue holds only the given
+// tracks; otherwise they are inserted after the current track.
+func (m *Manager) PlayTracks(tracks []*domain.Track, replace bool) *domain.Track {
+	if len(tracks) == 0 {
+		return nil
+	}
+	
+	var track *domain.Track
+	if replace {
+		track = m.queue.Replace(tracks)
+	} else {
+		track = m.queue.InsertAndAdvance(tracks)
+	}
 	
-	history := make([]string, len(m.history))
-	copy(history, m.history)
-	return history
+	m.history.Push(track)
+	return track
 }
 
-func (m *Manager) addToHistory(trackID string) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	
-	m.history = append(m.history, trackID)
-	
-	// Limit history size
-	if len(m.history) > 100 {
-		m.history = m.history[1:]
-	}
+// ClearQueue clears the queue
+func (m *Manager) ClearQueue() {
+	m.queue.Clear()
+}
+
+// GetHistory returns up to limit tracks played this session, most recent
+// first. A limit of zero or less returns them all.
+func (m *Manager) GetHistory(limit int) []PlayedEntry {
+	return m.history.Entries(limit)
 }
 
-// Queue manages the playback queue
+// Queue manages the playback queue. Tracks keep the order they were queued
+// in, and play in the order of a permutation of them, which shuffling
+// rearranges and Unshuffle puts back.
 type Queue struct {
-	tracks   []*domain.Track
-	position int
-	shuffle  bool
+	tracks   []*domain.Track // In queued order
+	order    []int           // Indexes into tracks in play order
+	position int             // Index into order of the current track; -1 before the first has played
+	shuffle  ShuffleMode
 	repeat   RepeatMode
+	family   bool // Shuffle passes over explicit tracks
+	noLive   bool // Shuffle passes over live recordings
 	mu       sync.RWMutex
 }
 
@@ -302,107 +378,348 @@ const (
 	RepeatAll
 )
 
+func (m RepeatMode) String() string {
+	switch m {
+	case RepeatOne:
+		return "one"
+	case RepeatAll:
+		return "all"
+	default:
+		return "off"
+	}
+}
+
+// ParseRepeatMode parses a repeat mode name as returned by String
+func ParseRepeatMode(s string) (RepeatMode, error) {
+	switch s {
+	case "off":
+		return RepeatOff, nil
+	case "one":
+		return RepeatOne, nil
+	case "all":
+		return RepeatAll, nil
+	default:
+		return RepeatOff, fmt.Errorf("%w: unknown repeat mode %q", domain.ErrInvalidInput, s)
+	}
+}
+
 // NewQueue creates a new queue
 func NewQueue() *Queue {
 	return &Queue{
 		tracks:   make([]*domain.Track, 0),
-		position: 0,
-		shuffle:  false,
+		order:    make([]int, 0),
+		position: -1,
+		shuffle:  ShuffleOff,
 		repeat:   RepeatOff,
 	}
 }
 
+// at returns the track at a play position. Callers must hold q.mu.
+func (q *Queue) at(i int) *domain.Track {
+	return q.tracks[q.order[i]]
+}
+
 // Add adds a track to the queue
 func (q *Queue) Add(track *domain.Track) {
-	q.mu.Lock()
-	defer q.mu.Unlock()
-	
-	q.tracks = append(q.tracks, track)
+	q.AddAll([]*domain.Track{track})
 }
 
 // AddNext adds a track to play next
 func (q *Queue) AddNext(track *domain.Track) {
+	q.AddAllNext([]*domain.Track{track})
+}
+
+// AddAll adds tracks to the end of the queue
+func (q *Queue) AddAll(tracks []*domain.Track) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	
-	if q.position >= len(q.tracks) {
+	for _, track := range tracks {
+		q.order = append(q.order, len(q.tracks))
 		q.tracks = append(q.tracks, track)
-	} else {
-		// Insert after current position
-		q.tracks = append(q.tracks[:q.position+1], append([]*domain.Track{track}, q.tracks[q.position+1:]...)...)
 	}
 }
 
-// Remove removes a track from the queue
-func (q *Queue) Remove(index int) error {
+// AddAllNext adds tracks to play next, keeping their order
+func (q *Queue) AddAllNext(tracks []*domain.Track) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	
+	q.insertAfterCurrent(tracks)
+}
+
+// Replace replaces the queue with tracks and returns the first, which
+// becomes the current track
+func (q *Queue) Replace(tracks []*domain.Track) *domain.Track {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	
-	if index < 0 || index >= len(q.tracks) {
-		return errors.New("index out of range")
+	q.tracks = append(make([]*domain.Track, 0, len(tracks)), tracks...)
+	q.order = identityOrder(len(tracks))
+	q.position = 0
+	if len(q.tracks) == 0 {
+		return nil
+	}
+	return q.at(0)
+}
+
+// InsertAndAdvance inserts tracks after the current track and moves to the
+// first of them, returning it
+func (q *Queue) InsertAndAdvance(tracks []*domain.Track) *domain.Track {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	
+	if len(tracks) == 0 {
+		return nil
 	}
 	
-	q.tracks = append(q.tracks[:index], q.tracks[index+1:]...)
+	q.position = q.insertAfterCurrent(tracks)
+	return q.at(q.position)
+}
+
+// insertAfterCurrent inserts tracks after the current one, both in play
+// order and in queued order so that unshuffling keeps them next, and
+// returns the play position of the first. Callers must hold q.mu.
+func (q *Queue) insertAfterCurrent(tracks []*domain.Track) int {
+	at := min(q.position+1, len(q.order))
+	if len(q.order) == 0 {
+		at = 0
+	}
 	
-	// Adjust position if necessary
-	if q.position > index {
-		q.position--
-	} else if q.position >= len(q.tracks) && len(q.tracks) > 0 {
-		q.position = len(q.tracks) - 1
+	// Where the new tracks go in queued order
+	queued := len(q.tracks)
+	if at > 0 {
+		queued = q.order[at-1] + 1
+	} else if len(q.order) > 0 {
+		queued = q.order[0]
 	}
 	
+	updated := make([]*domain.Track, 0, len(q.tracks)+len(tracks))
+	updated = append(updated, q.tracks[:queued]...)
+	updated = append(updated, tracks...)
+	updated = append(updated, q.tracks[queued:]...)
+	q.tracks = updated
+	
+	order := make([]int, 0, len(q.order)+len(tracks))
+	for _, i := range q.order[:at] {
+		order = append(order, shiftIndex(i, queued, len(tracks)))
+	}
+	for i := range tracks {
+		order = append(order, queued+i)
+	}
+	for _, i := range q.order[at:] {
+		order = append(order, shiftIndex(i, queued, len(tracks)))
+	}
+	q.order = order
+	return at
+}
+
+// Remove removes the track at a play position from the queue
+func (q *Queue) Remove(index int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	
+	if index < 0 || index >= len(q.order) {
+		return ErrQueueIndex
+	}
+	
+	target := q.order[index]
+	q.removeWhere(func(i int, _ *domain.Track) bool { return i == target })
 	return nil
 }
 
-// Next returns the next track in the queue
+// RemoveTrack removes every entry for a track from the queue, returning how
+// many were removed
+func (q *Queue) RemoveTrack(trackID string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	
+	return q.removeWhere(func(_ int, track *domain.Track) bool { return track.ID == trackID })
+}
+
+// removeWhere removes the tracks drop reports, by queued index, keeping the
+// rest in order, and returns how many it removed. Callers must hold q.mu.
+func (q *Queue) removeWhere(drop func(int, *domain.Track) bool) int {
+	// Where each kept track moves to in queued order, or -1
+	moved := make([]int, len(q.tracks))
+	kept := q.tracks[:0]
+	for i, track := range q.tracks {
+		if drop(i, track) {
+			moved[i] = -1
+			continue
+		}
+		moved[i] = len(kept)
+		kept = append(kept, track)
+	}
+	removed := len(q.tracks) - len(kept)
+	clear(q.tracks[len(kept):])
+	q.tracks = kept
+	
+	order := q.order[:0]
+	position := q.position
+	for at, i := range q.order {
+		if moved[i] >= 0 {
+			order = append(order, moved[i])
+		} else if at < q.position {
+			position--
+		}
+	}
+	q.order = order
+	q.position = position
+	
+	if q.position >= len(q.order) && len(q.order) > 0 {
+		q.position = len(q.order) - 1
+	}
+	
+	return removed
+}
+
+// Next moves to the next track when the user skips ahead. Unlike Advance
+// it ignores RepeatOne, so an explicit skip always leaves the current
+// track.
 func (q *Queue) Next() *domain.Track {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	
-	if len(q.tracks) == 0 {
+	if len(q.order) == 0 {
 		return nil
 	}
 	
-	// Handle repeat one
-	if q.repeat == RepeatOne && q.position < len(q.tracks) {
-		return q.tracks[q.position]
+	q.position = q.nextPosition(false)
+	if q.position >= len(q.order) {
+		return nil
 	}
+	return q.at(q.position)
+}
+
+// Advance moves on when the current track finishes by itself. With
+// RepeatOne the current track plays again.
+func (q *Queue) Advance() *domain.Track {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	
-	// Move to next position
-	q.position++
+	if len(q.order) == 0 {
+		return nil
+	}
 	
-	// Handle end of queue
-	if q.position >= len(q.tracks) {
-		if q.repeat == RepeatAll {
-			q.position = 0
-		} else {
-			q.position = len(q.tracks)
-			return nil
-		}
+	q.position = q.nextPosition(true)
+	if q.position >= len(q.order) {
+		return nil
+	}
+	return q.at(q.position)
+}
+
+// AdvanceTo advances the queue if track is the one Advance would move to,
+// and reports whether it did
+func (q *Queue) AdvanceTo(track *domain.Track) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	
+	next := q.nextPosition(true)
+	if track == nil || next >= len(q.order) || q.at(next) != track {
+		return false
+	}
+	
+	q.position = next
+	return true
+}
+
+// JumpTo makes the track at a play position current and returns it
+func (q *Queue) JumpTo(index int) (*domain.Track, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	
+	if index < 0 || index >= len(q.order) {
+		return nil, ErrQueueIndex
 	}
 	
-	return q.tracks[q.position]
+	q.position = index
+	return q.at(index), nil
 }
 
-// Peek returns the next track without advancing position
+// MoveTo makes a track current if it is in the queue, preferring the
+// nearest copy before the current position, and reports whether it was
+// found
+func (q *Queue) MoveTo(track *domain.Track) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	
+	for i := min(q.position, len(q.order)-1); i >= 0; i-- {
+		if q.at(i) == track {
+			q.position = i
+			return true
+		}
+	}
+	for i := max(q.position+1, 0); i < len(q.order); i++ {
+		if q.at(i) == track {
+			q.position = i
+			return true
+		}
+	}
+	return false
+}
+
+// Peek returns the track Advance would move to, without moving
 func (q *Queue) Peek() *domain.Track {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
 	
-	if len(q.tracks) == 0 {
+	next := q.nextPosition(true)
+	if next >= len(q.order) {
+		return nil
+	}
+	return q.at(next)
+}
+
+// Current returns the current track, or nil before the first has played
+// or after the end of the queue
+func (q *Queue) Current() *domain.Track {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	
+	if q.position < 0 || q.position >= len(q.order) {
 		return nil
 	}
+	return q.at(q.position)
+}
+
+// nextPosition returns the position after the current one, or
+// len(q.order) past the end of the queue. natural is set when the current
+// track finished by itself, which RepeatOne repeats. Callers must hold
+// q.mu.
+func (q *Queue) nextPosition(natural bool) int {
+	if natural && q.repeat == RepeatOne && q.position >= 0 && q.position < len(q.order) {
+		return q.position
+	}
 	
-	nextPos := q.position + 1
-	if nextPos >= len(q.tracks) {
+	next := q.skipHidden(q.position + 1)
+	if next >= len(q.order) {
 		if q.repeat == RepeatAll {
-			nextPos = 0
-		} else {
-			return nil
+			return q.skipHidden(0)
 		}
+		return len(q.order)
 	}
-	
-	return q.tracks[nextPos]
+	return next
+}
+
+// skipHidden returns the first position from i on whose track shuffle
+// doesn't pass over, or len(q.order). Tracks are only passed over while
+// shuffling; a queue played in order plays what was queued. Callers must
+// hold q.mu.
+func (q *Queue) skipHidden(i int) int {
+	if q.shuffle == ShuffleOff {
+		return i
+	}
+	for i < len(q.order) && q.passesOver(q.at(i)) {
+		i++
+	}
+	return i
+}
+
+// passesOver reports whether shuffle skips a track: explicit ones in
+// family mode, and live recordings when they are left out
+func (q *Queue) passesOver(track *domain.Track) bool {
+	return q.family && track.IsExplicit() || q.noLive && track.IsLive
 }
 
 // Previous returns the previous track in the queue
@@ -410,20 +727,20 @@ func (q *Queue) Previous() *domain.Track {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	
-	if len(q.tracks) == 0 {
+	if len(q.order) == 0 {
 		return nil
 	}
 	
 	q.position--
 	if q.position < 0 {
 		if q.repeat == RepeatAll {
-			q.position = len(q.tracks) - 1
+			q.position = len(q.order) - 1
 		} else {
 			q.position = 0
 		}
 	}
 	
-	return q.tracks[q.position]
+	return q.at(q.position)
 }
 
 // Clear clears the queue
@@ -432,34 +749,68 @@ func (q *Queue) Clear() {
 	defer q.mu.Unlock()
 	
 	q.tracks = make([]*domain.Track, 0)
-	q.position = 0
+	q.order = make([]int, 0)
+	q.position = -1
 }
 
-// GetTracks returns all tracks in the queue
+// GetTracks returns all tracks in the queue in play order
 func (q *Queue) GetTracks() []*domain.Track {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
 	
-	tracks := make([]*domain.Track, len(q.tracks))
-	copy(tracks, q.tracks)
+	tracks := make([]*domain.Track, len(q.order))
+	for i := range q.order {
+		tracks[i] = q.at(i)
+	}
 	return tracks
 }
 
-// SetShuffle enables or disables shuffle
+// SetShuffle enables or disables shuffling track by track
 func (q *Queue) SetShuffle(shuffle bool) {
+	if shuffle {
+		q.SetShuffleMode(ShuffleTracks)
+	} else {
+		q.SetShuffleMode(ShuffleOff)
+	}
+}
+
+// SetShuffleMode shuffles the tracks after the current one by mode, starting
+// from their queued order each time. Turning shuffle off leaves the queue
+// in its present order; Unshuffle restores the queued order.
+func (q *Queue) SetShuffleMode(mode ShuffleMode) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	
-	q.shuffle = shuffle
+	q.shuffle = mode
+	if mode == ShuffleOff || q.position >= len(q.order)-2 {
+		return
+	}
 	
-	if shuffle && len(q.tracks) > 1 {
-		// Shuffle tracks after current position
-		if q.position < len(q.tracks)-1 {
-			remaining := q.tracks[q.position+1:]
-			shuffleTracks(remaining)
-			q.tracks = append(q.tracks[:q.position+1], remaining...)
-		}
+	remaining := q.order[q.position+1:]
+	slices.Sort(remaining)
+	switch mode {
+	case ShuffleTracks:
+		rand.Shuffle(len(remaining), func(i, j int) {
+			remaining[i], remaining[j] = remaining[j], remaining[i]
+		})
+	case ShuffleAlbums:
+		shuffleGroups(remaining, q.tracks, (*domain.Track).AlbumKey, true)
+	case ShuffleArtists:
+		shuffleGroups(remaining, q.tracks, artistKey, false)
+	}
+}
+
+// Unshuffle turns shuffle off and puts the queue back in the order its
+// tracks were queued, keeping the current track current
+func (q *Queue) Unshuffle() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	
+	q.shuffle = ShuffleOff
+	if q.position >= 0 && q.position < len(q.order) {
+		q.position = q.order[q.position]
 	}
+	q.order = identityOrder(len(q.tracks))
 }
 
 // SetRepeat sets the repeat mode
@@ -469,6 +820,42 @@ func (q *Queue) SetRepeat(mode RepeatMode) {
 	q.repeat = mode
 }
 
+// SetFamilyMode sets whether shuffle passes over explicit tracks
+func (q *Queue) SetFamilyMode(enabled bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.family = enabled
+}
+
+// SetShuffleLive sets whether shuffle plays live recordings or passes over
+// them
+func (q *Queue) SetShuffleLive(enabled bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.noLive = !enabled
+}
+
+// IsShuffle returns whether shuffle is enabled
+func (q *Queue) IsShuffle() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.shuffle != ShuffleOff
+}
+
+// GetShuffleMode returns the shuffle mode
+func (q *Queue) GetShuffleMode() ShuffleMode {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.shuffle
+}
+
+// GetRepeat returns the repeat mode
+func (q *Queue) GetRepeat() RepeatMode {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.repeat
+}
+
 // GetPosition returns the current queue position
 func (q *Queue) GetPosition() int {
 	q.mu.RLock()
@@ -480,19 +867,59 @@ func (q *Queue) GetPosition() int {
 func (q *Queue) GetLength() int {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
-	return len(q.tracks)
+	return len(q.order)
+}
+
+// QueueDuration is how long the queue takes to play
+type QueueDuration struct {
+	Total    time.Duration // All tracks
+	Upcoming time.Duration // Tracks after the current one
+	Unknown  int           // Tracks of unknown length, left out of the sums
+	Endless  bool          // Repeat never lets the queue finish
+}
+
+// Duration sums the lengths of the tracks in the queue
+func (q *Queue) Duration() QueueDuration {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	
+	result := QueueDuration{
+		Endless: q.repeat != RepeatOff && len(q.order) > 0,
+	}
+	for i := range q.order {
+		track := q.at(i)
+		if track.Duration <= 0 {
+			result.Unknown++
+			continue
+		}
+		result.Total += track.Duration
+		if i > q.position {
+			result.Upcoming += track.Duration
+		}
+	}
+	return result
 }
 
 // IsEmpty returns true if the queue is empty
 func (q *Queue) IsEmpty() bool {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
-	return len(q.tracks) == 0
+	return len(q.order) == 0
+}
+
+// identityOrder is the play order of n tracks played as queued
+func identityOrder(n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	return order
 }
 
-func shuffleTracks(tracks []*domain.Track) {
-	for i := len(tracks) - 1; i > 0; i-- {
-		j := int(time.Now().UnixNano()) % (i + 1)
-		tracks[i], tracks[j] = tracks[j], tracks[i]
+// shiftIndex moves a queued index up by n if it is at or after from
+func shiftIndex(i, from, n int) int {
+	if i >= from {
+		return i + n
 	}
+	return i
 }
\ No newline at end of file
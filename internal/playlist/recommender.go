@@ -0,0 +1,377 @@
+package playlist
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+const (
+	// coPlayWindow is the history span used for co-play statistics
+	coPlayWindow = 180 * 24 * time.Hour
+
+	// sessionGap separates listening sessions; plays further apart than
+	// this are not counted as played together
+	sessionGap = 30 * time.Minute
+
+	// coPlayNeighbours is how many following plays in a session count as
+	// played together with a track
+	coPlayNeighbours = 3
+
+	// coPlayRefresh is how long co-play statistics are reused
+	coPlayRefresh = 10 * time.Minute
+
+	// autoDJPool is how many top candidates the auto-DJ picks from, so
+	// repeated sessions do not always play the same sequence
+	autoDJPool = 8
+
+	// autoDJRecent is how many recent plays the auto-DJ avoids repeating
+	autoDJRecent = 50
+
+	// bpmRange and yearRange are how far apart tracks' tempos and years
+	// can be and still count as alike
+	bpmRange  = 20
+	yearRange = 10
+)
+
+// Recommender suggests similar tracks from genre, BPM, year and co-play
+// statistics in the local play history. Nothing leaves the machine.
+type Recommender struct {
+	trackRepo   domain.TrackRepository
+	historyRepo domain.PlayHistoryRepository
+
+	coPlay      map[string]map[string]int
+	coPlayBuilt time.Time
+	family      bool // Explicit tracks are never suggested
+	noLive      bool // Live recordings are never suggested
+	mu          sync.Mutex
+}
+
+// NewRecommender creates a recommender over the library and play history
+func NewRecommender(trackRepo domain.TrackRepository, historyRepo domain.PlayHistoryRepository) *Recommender {
+	return &Recommender{
+		trackRepo:   trackRepo,
+		historyRepo: historyRepo,
+	}
+}
+
+// SetFamilyMode sets whether explicit tracks are left out of suggestions,
+// and so out of the auto-DJ
+func (r *Recommender) SetFamilyMode(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.family = enabled
+}
+
+// SetSuggestLive sets whether live recordings may be suggested
+func (r *Recommender) SetSuggestLive(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.noLive = !enabled
+}
+
+// Similar returns up to limit tracks most similar to seed, best first.
+// Tracks whose IDs are in exclude are skipped.
+func (r *Recommender) Similar(seed *domain.Track, limit int, exclude map[string]bool) ([]*domain.Track, error) {
+	scored, err := r.rank([]*domain.Track{seed}, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(scored) > limit {
+		scored = scored[:limit]
+	}
+
+	tracks := make([]*domain.Track, len(scored))
+	for i, s := range scored {
+		tracks[i] = s.track
+	}
+	return tracks, nil
+}
+
+// NextForAutoDJ picks a track to follow the given recently played tracks,
+// newest last. Recently played tracks and those in exclude are avoided.
+// It returns nil when the library has nothing suitable.
+func (r *Recommender) NextForAutoDJ(recent []*domain.Track, exclude map[string]bool) (*domain.Track, error) {
+	if len(recent) == 0 {
+		return nil, nil
+	}
+
+	skip := make(map[string]bool, len(exclude)+autoDJRecent)
+	for id := range exclude {
+		skip[id] = true
+	}
+	for _, track := range recent {
+		skip[track.ID] = true
+	}
+	if r.historyRepo != nil {
+		if entries, err := r.historyRepo.FindRecent(autoDJRecent); err == nil {
+			for _, entry := range entries {
+				skip[entry.TrackID] = true
+			}
+		}
+	}
+
+	// Weight towards the latest tracks so the mood drifts gradually
+	seeds := recent
+	if len(seeds) > 3 {
+		seeds = seeds[len(seeds)-3:]
+	}
+
+	scored, err := r.rank(seeds, skip)
+	if err != nil || len(scored) == 0 {
+		return nil, err
+	}
+
+	// Avoid the same artist twice in a row
+	last := seeds[len(seeds)-1]
+	pool := make([]scoredTrack, 0, autoDJPool)
+	for _, s := range scored {
+		if len(pool) == autoDJPool {
+			break
+		}
+		if last.Artist != "" && strings.EqualFold(s.track.Artist, last.Artist) {
+			continue
+		}
+		pool = append(pool, s)
+	}
+	if len(pool) == 0 {
+		pool = scored[:min(len(scored), autoDJPool)]
+	}
+
+	// Pick with probability proportional to score
+	total := 0.0
+	for _, s := range pool {
+		total += s.score
+	}
+	pick := rand.Float64() * total
+	for _, s := range pool {
+		pick -= s.score
+		if pick <= 0 {
+			return s.track, nil
+		}
+	}
+	return pool[len(pool)-1].track, nil
+}
+
+type scoredTrack struct {
+	track *domain.Track
+	score float64
+}
+
+// rank scores the library tracks alike in some way to the seeds, best
+// first. Later seeds weigh more.
+func (r *Recommender) rank(seeds []*domain.Track, exclude map[string]bool) ([]scoredTrack, error) {
+	coPlay := r.coPlayStats()
+	tracks, err := r.candidates(seeds, coPlay)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	family, noLive := r.family, r.noLive
+	r.mu.Unlock()
+
+	seedIDs := make(map[string]bool, len(seeds))
+	for _, seed := range seeds {
+		seedIDs[seed.ID] = true
+	}
+
+	scored := make([]scoredTrack, 0, len(tracks))
+	for _, track := range tracks {
+		if !track.IsValid || seedIDs[track.ID] || exclude[track.ID] || family && track.IsExplicit() || noLive && track.IsLive {
+			continue
+		}
+
+		score := 0.0
+		for i, seed := range seeds {
+			weight := float64(i+1) / float64(len(seeds))
+			score += weight * similarity(seed, track, coPlay[seed.ID][track.ID])
+		}
+		if score <= 0 {
+			continue
+		}
+
+		// Prefer tracks the user rates highly
+		score += 0.2 * float64(track.Rating)
+		scored = append(scored, scoredTrack{track: track, score: score})
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+
+	return scored, nil
+}
+
+// candidates returns the tracks that can score against the seeds: those
+// sharing a genre word, tempo, era or artist with one, which the library
+// is queried for rather than scored in full, and those played together
+// with one
+func (r *Recommender) candidates(seeds []*domain.Track, coPlay map[string]map[string]int) ([]*domain.Track, error) {
+	var conditions []domain.RuleCondition
+	either := func(field, operator string, value interface{}) {
+		conditions = append(conditions, domain.RuleCondition{Field: field, Operator: operator, Value: value, AndOr: "OR"})
+	}
+	between := func(field string, value, within float64) {
+		either(field, domain.OperatorBetween, []interface{}{max(value-within, 1), value + within})
+	}
+	for _, seed := range seeds {
+		if seed.Genre != "" {
+			words := genreWords(seed.Genre)
+			if len(words) == 0 {
+				words = []string{seed.Genre}
+			}
+			for _, word := range words {
+				either("genre", domain.OperatorContains, word)
+			}
+		}
+		if seed.BPM > 0 {
+			bpm := float64(seed.BPM)
+			between("bpm", bpm, bpmRange)
+			between("bpm", bpm*2, bpmRange)
+			between("bpm", bpm/2, bpmRange/2)
+		}
+		if seed.Year > 0 {
+			between("year", float64(seed.Year), yearRange)
+		}
+		if seed.Artist != "" {
+			either("artist", domain.OperatorEquals, seed.Artist)
+		}
+	}
+
+	var tracks []*domain.Track
+	if len(conditions) > 0 {
+		found, err := r.trackRepo.FindBySmartRules(&domain.SmartRules{Conditions: conditions}, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		tracks = found
+	}
+
+	// Tracks played together may have nothing else in common
+	found := make(map[string]bool, len(tracks))
+	for _, track := range tracks {
+		found[track.ID] = true
+	}
+	for _, seed := range seeds {
+		for id := range coPlay[seed.ID] {
+			if found[id] {
+				continue
+			}
+			found[id] = true
+			if track, err := r.trackRepo.FindByID(id); err == nil {
+				tracks = append(tracks, track)
+			}
+		}
+	}
+
+	return tracks, nil
+}
+
+// similarity scores how well candidate follows seed
+func similarity(seed, candidate *domain.Track, coPlays int) float64 {
+	score := 0.0
+
+	// Genre
+	if seed.Genre != "" && candidate.Genre != "" {
+		if strings.EqualFold(seed.Genre, candidate.Genre) {
+			score += 3
+		} else if sharesGenreWord(seed.Genre, candidate.Genre) {
+			score += 1.5
+		}
+	}
+
+	// Tempo, allowing for half and double time
+	if seed.BPM > 0 && candidate.BPM > 0 {
+		diff := math.Abs(float64(seed.BPM - candidate.BPM))
+		diff = math.Min(diff, math.Abs(float64(seed.BPM*2-candidate.BPM)))
+		diff = math.Min(diff, math.Abs(float64(seed.BPM-candidate.BPM*2)))
+		score += 2 * math.Max(0, 1-diff/bpmRange)
+	}
+
+	// Era
+	if seed.Year > 0 && candidate.Year > 0 {
+		diff := math.Abs(float64(seed.Year - candidate.Year))
+		score += 1.5 * math.Max(0, 1-diff/yearRange)
+	}
+
+	if seed.Artist != "" && strings.EqualFold(seed.Artist, candidate.Artist) {
+		score += 1
+	}
+
+	// Tracks the user has played together before
+	if coPlays > 0 {
+		score += 2 * math.Log1p(float64(coPlays))
+	}
+
+	return score
+}
+
+// sharesGenreWord reports whether two genres share a word, such as
+// "Progressive Rock" and "Rock"
+func sharesGenreWord(a, b string) bool {
+	words := make(map[string]bool)
+	for _, word := range genreWords(a) {
+		words[word] = true
+	}
+	for _, word := range genreWords(b) {
+		if words[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// genreWords splits a genre into its lower case words
+func genreWords(genre string) []string {
+	return strings.FieldsFunc(strings.ToLower(genre), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '/' || r == '&' || r == ','
+	})
+}
+
+// coPlayStats counts how often tracks were played close together within a
+// listening session, rebuilding the counts when they are stale
+func (r *Recommender) coPlayStats() map[string]map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.coPlay != nil && time.Since(r.coPlayBuilt) < coPlayRefresh {
+		return r.coPlay
+	}
+
+	stats := make(map[string]map[string]int)
+	if r.historyRepo != nil {
+		if entries, err := r.historyRepo.FindSince(time.Now().Add(-coPlayWindow)); err == nil {
+			for i, entry := range entries {
+				for j := i + 1; j < len(entries) && j <= i+coPlayNeighbours; j++ {
+					other := entries[j]
+					if other.PlayedAt.Sub(entries[j-1].PlayedAt) > sessionGap {
+						break
+					}
+					if other.TrackID == entry.TrackID {
+						continue
+					}
+					addCoPlay(stats, entry.TrackID, other.TrackID)
+					addCoPlay(stats, other.TrackID, entry.TrackID)
+				}
+			}
+		}
+	}
+
+	r.coPlay = stats
+	r.coPlayBuilt = time.Now()
+	return stats
+}
+
+func addCoPlay(stats map[string]map[string]int, a, b string) {
+	if stats[a] == nil {
+		stats[a] = make(map[string]int)
+	}
+	stats[a][b]++
+}
@@ -0,0 +1,99 @@
+package playlist
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// queriedTracks fails if the whole library is loaded, so only the tracks
+// queried for are scored
+type queriedTracks struct {
+	*fakeTracks
+}
+
+func (q queriedTracks) FindAll() ([]*domain.Track, error) {
+	return nil, errors.New("library loaded in full")
+}
+
+func (f *fakeHistory) FindRecent(limit int) ([]*domain.PlayHistoryEntry, error) {
+	var entries []*domain.PlayHistoryEntry
+	for i := len(f.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, f.entries[i])
+	}
+	return entries, nil
+}
+
+func recommenderLibrary() (*domain.Track, *Recommender) {
+	seed := &domain.Track{ID: "seed", Genre: "Progressive Rock", BPM: 120, Year: 1975, Artist: "Yes", IsValid: true}
+	tracks := &fakeTracks{tracks: []*domain.Track{
+		seed,
+		{ID: "same", Genre: "Progressive Rock", BPM: 122, Year: 1974, Artist: "Genesis", IsValid: true},
+		{ID: "double", Genre: "Jazz", BPM: 240, IsValid: true},
+		{ID: "word", Genre: "Rock", Artist: "Queen", IsValid: true},
+		{ID: "coplayed", Genre: "Ambient", Year: 2015, IsValid: true},
+		{ID: "artist", Artist: "yes", IsValid: true},
+		{ID: "unrelated", Genre: "Jazz", BPM: 90, Year: 2010, IsValid: true},
+		{ID: "explicit", Genre: "Progressive Rock", Advisory: domain.AdvisoryExplicit, IsValid: true},
+		{ID: "live", Genre: "Progressive Rock", IsLive: true, IsValid: true},
+		{ID: "invalid", Genre: "Progressive Rock"},
+		{ID: "excluded", Genre: "Progressive Rock", IsValid: true},
+	}}
+
+	played := time.Now().Add(-48 * time.Hour)
+	history := &fakeHistory{entries: []*domain.PlayHistoryEntry{
+		{TrackID: "seed", PlayedAt: played},
+		{TrackID: "coplayed", PlayedAt: played.Add(3 * time.Minute)},
+		{TrackID: "double", PlayedAt: time.Now().Add(-time.Hour)},
+	}}
+	return seed, NewRecommender(queriedTracks{tracks}, history)
+}
+
+func TestRecommenderSimilar(t *testing.T) {
+	seed, r := recommenderLibrary()
+	r.SetFamilyMode(true)
+	r.SetSuggestLive(false)
+
+	// Best first: genre, tempo and era, then tempo at double time, a genre
+	// word, the same artist and a track played alongside the seed. Tracks
+	// with nothing in common, or left out, are not suggested.
+	exclude := map[string]bool{"excluded": true}
+	similar, err := r.Similar(seed, 10, exclude)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"same", "double", "word", "coplayed", "artist"}, trackIDs(similar))
+
+	similar, err = r.Similar(seed, 2, exclude)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"same", "double"}, trackIDs(similar))
+
+	r.SetFamilyMode(false)
+	r.SetSuggestLive(true)
+	similar, err = r.Similar(seed, 10, nil)
+	require.NoError(t, err)
+	assert.Contains(t, trackIDs(similar), "explicit")
+	assert.Contains(t, trackIDs(similar), "live")
+	assert.NotContains(t, trackIDs(similar), "invalid")
+}
+
+func TestRecommenderAutoDJ(t *testing.T) {
+	seed, r := recommenderLibrary()
+	r.SetFamilyMode(true)
+	r.SetSuggestLive(false)
+
+	// Recently played tracks and the seed's artist are avoided
+	for i := 0; i < 20; i++ {
+		next, err := r.NextForAutoDJ([]*domain.Track{seed}, map[string]bool{"excluded": true})
+		require.NoError(t, err)
+		require.NotNil(t, next)
+		assert.Contains(t, []string{"same", "word"}, next.ID)
+	}
+
+	next, err := r.NextForAutoDJ(nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, next)
+}
@@ -0,0 +1,154 @@
+package playlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// Session is the playback state saved between runs, so the player can
+// resume where it left off
+type Session struct {
+	TrackIDs []string      `json:"trackIds"` // The queue in queued order
+	Order    []int         `json:"order"`    // Play order, as indexes into TrackIDs
+	Position int           `json:"position"` // Index into Order of the current track; -1 if none
+	Offset   time.Duration `json:"offset"`   // How far into the current track playback was
+	Playing  bool          `json:"playing"`
+	Shuffle  string        `json:"shuffle"`
+	Repeat   string        `json:"repeat"`
+	SavedAt  time.Time     `json:"savedAt"`
+}
+
+// Session returns the queue's part of the playback state. Transient
+// entries such as radio streams are left out, since they can't be found
+// again by ID.
+func (q *Queue) Session() *Session {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	// Where each kept track ends up in TrackIDs, or -1
+	moved := make([]int, len(q.tracks))
+	session := &Session{
+		TrackIDs: make([]string, 0, len(q.tracks)),
+		Order:    make([]int, 0, len(q.order)),
+		Position: -1,
+		Shuffle:  q.shuffle.String(),
+		Repeat:   q.repeat.String(),
+	}
+	for i, track := range q.tracks {
+		moved[i] = -1
+		if !track.Transient {
+			moved[i] = len(session.TrackIDs)
+			session.TrackIDs = append(session.TrackIDs, track.ID)
+		}
+	}
+	for at, i := range q.order {
+		if moved[i] < 0 {
+			continue
+		}
+		if at <= q.position {
+			session.Position = len(session.Order)
+		}
+		session.Order = append(session.Order, moved[i])
+	}
+	return session
+}
+
+// Restore replaces the queue with a saved session's, looking its tracks up
+// with find. Tracks that can no longer be found are dropped, and a play
+// order that doesn't fit the tracks is replaced by their queued order.
+func (q *Queue) Restore(session *Session, find func(id string) *domain.Track) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.tracks = make([]*domain.Track, len(session.TrackIDs))
+	for i, id := range session.TrackIDs {
+		q.tracks[i] = find(id)
+	}
+	q.order = session.Order
+	if !isPermutation(q.order, len(q.tracks)) {
+		q.order = identityOrder(len(q.tracks))
+	}
+	q.position = min(max(session.Position, -1), len(q.order)-1)
+	q.shuffle, _ = ParseShuffleMode(session.Shuffle)
+	q.repeat, _ = ParseRepeatMode(session.Repeat)
+
+	q.removeWhere(func(_ int, track *domain.Track) bool { return track == nil })
+}
+
+// isPermutation reports whether order holds each of 0 to n-1 once
+func isPermutation(order []int, n int) bool {
+	if len(order) != n {
+		return false
+	}
+	seen := make([]bool, n)
+	for _, i := range order {
+		if i < 0 || i >= n || seen[i] {
+			return false
+		}
+		seen[i] = true
+	}
+	return true
+}
+
+// SessionStore keeps the playback session in a JSON file
+type SessionStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewSessionStore creates a store for the session at path
+func NewSessionStore(path string) *SessionStore {
+	return &SessionStore{path: path}
+}
+
+// Load reads the saved session, or returns nil if none has been saved
+func (s *SessionStore) Load() (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse session: %w", err)
+	}
+	return &session, nil
+}
+
+// Save writes the session, replacing the file whole so a crash part way
+// through leaves the previous session intact
+func (s *SessionStore) Save(session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package playlist
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestSessionRoundTrip(t *testing.T) {
+	library := map[string]*domain.Track{}
+	q := NewQueue()
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		library[id] = &domain.Track{ID: id, Title: id}
+		q.Add(library[id])
+	}
+	q.Add(&domain.Track{ID: "radio", Transient: true})
+	q.SetRepeat(RepeatAll)
+	current := q.Advance()
+	q.SetShuffle(true)
+	played := q.GetTracks()
+
+	session := q.Session()
+	session.Offset = 42 * time.Second
+	session.Playing = true
+
+	store := NewSessionStore(filepath.Join(t.TempDir(), "session.json"))
+	loaded, err := store.Load()
+	require.NoError(t, err)
+	assert.Nil(t, loaded)
+
+	require.NoError(t, store.Save(session))
+	loaded, err = store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, session, loaded)
+
+	// A track deleted since is dropped from the restored queue
+	delete(library, "c")
+	restored := NewQueue()
+	restored.Restore(loaded, func(id string) *domain.Track { return library[id] })
+
+	var want []*domain.Track
+	for _, track := range played {
+		if track.ID != "c" && !track.Transient {
+			want = append(want, track)
+		}
+	}
+	assert.Equal(t, want, restored.GetTracks())
+	assert.Equal(t, current, restored.Current())
+	assert.Equal(t, ShuffleTracks, restored.GetShuffleMode())
+	assert.Equal(t, RepeatAll, restored.GetRepeat())
+
+	// Unshuffling still restores the queued order
+	restored.Unshuffle()
+	assert.Equal(t, []*domain.Track{library["a"], library["b"], library["d"], library["e"]}, restored.GetTracks())
+}
+
+func TestRestoreBadOrder(t *testing.T) {
+	tracks := map[string]*domain.Track{"a": {ID: "a"}, "b": {ID: "b"}}
+	q := NewQueue()
+	q.Restore(&Session{TrackIDs: []string{"a", "b"}, Order: []int{1, 1}, Position: 7},
+		func(id string) *domain.Track { return tracks[id] })
+
+	assert.Equal(t, []*domain.Track{tracks["a"], tracks["b"]}, q.GetTracks())
+	assert.Equal(t, tracks["b"], q.Current())
+	assert.Equal(t, ShuffleOff, q.GetShuffleMode())
+}
@@ -0,0 +1,233 @@
+package playlist
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+const (
+	// ShareFileExtension is the extension used for shared queue files
+	ShareFileExtension = ".wrq"
+
+	shareFormat        = "winramp-queue"
+	shareFormatVersion = 2 // Newest version this build reads
+
+	// Queues are written with the oldest version that can hold them, so
+	// queues without transient entries stay readable by older builds
+	shareBaseVersion      = 1
+	shareTransientVersion = 2
+
+	// shareDurationTolerance is how far durations may differ for a tag match
+	shareDurationTolerance = 3 * time.Second
+)
+
+// ErrInvalidShareFile is returned for files that are not shared queues
+var ErrInvalidShareFile = errors.New("invalid shared queue file")
+
+// SharedTrack identifies a track by metadata and fingerprint only, so it can
+// be matched against another library without sending any audio
+type SharedTrack struct {
+	Title       string `json:"title"`
+	Artist      string `json:"artist"`
+	Album       string `json:"album,omitempty"`
+	TrackNumber int    `json:"track,omitempty"`
+	DiscNumber  int    `json:"disc,omitempty"`
+	DurationMs  int64  `json:"durationMs"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Checksum    string `json:"checksum,omitempty"`
+
+	// Source is set for transient entries, which are played from where
+	// they are rather than matched against the library
+	Source *domain.Source `json:"source,omitempty"`
+}
+
+// SharedQueue is the content of a shared queue file
+type SharedQueue struct {
+	Format    string        `json:"format"`
+	Version   int           `json:"version"`
+	Name      string        `json:"name"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Tracks    []SharedTrack `json:"tracks"`
+}
+
+// NewSharedQueue describes tracks for sharing
+func NewSharedQueue(name string, tracks []*domain.Track) *SharedQueue {
+	shared := &SharedQueue{
+		Format:    shareFormat,
+		Version:   shareBaseVersion,
+		Name:      name,
+		CreatedAt: time.Now(),
+		Tracks:    make([]SharedTrack, 0, len(tracks)),
+	}
+
+	for _, track := range tracks {
+		entry := SharedTrack{
+			Title:       track.GetDisplayTitle(),
+			Artist:      track.Artist,
+			Album:       track.Album,
+			TrackNumber: track.TrackNumber,
+			DiscNumber:  track.DiscNumber,
+			DurationMs:  track.Duration.Milliseconds(),
+			Fingerprint: track.Fingerprint,
+			Checksum:    track.Checksum,
+		}
+		if track.Transient {
+			source := track.GetSource()
+			entry.Source = &source
+			shared.Version = shareTransientVersion
+		}
+		shared.Tracks = append(shared.Tracks, entry)
+	}
+
+	return shared
+}
+
+// WriteSharedQueue writes a shared queue as gzip-compressed JSON
+func WriteSharedQueue(w io.Writer, shared *SharedQueue) error {
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(shared); err != nil {
+		gz.Close()
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadSharedQueue reads a shared queue file
+func ReadSharedQueue(r io.Reader) (*SharedQueue, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidShareFile, err)
+	}
+	defer gz.Close()
+
+	var shared SharedQueue
+	if err := json.NewDecoder(gz).Decode(&shared); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidShareFile, err)
+	}
+	if shared.Format != shareFormat {
+		return nil, ErrInvalidShareFile
+	}
+	if shared.Version > shareFormatVersion {
+		return nil, fmt.Errorf("%w: version %d is newer than supported", ErrInvalidShareFile, shared.Version)
+	}
+
+	return &shared, nil
+}
+
+// ResolveResult holds the outcome of matching a shared queue against the
+// local library, in the shared order
+type ResolveResult struct {
+	Matched []*domain.Track
+	Missing []SharedTrack
+}
+
+// ResolveSharedQueue finds each shared track in the local library. Exact
+// audio checksums are tried first, then acoustic fingerprints, then artist
+// and title with a similar duration. Transient entries become transient
+// tracks again, unless they name a file that doesn't exist here.
+func ResolveSharedQueue(shared *SharedQueue, trackRepo domain.TrackRepository) (*ResolveResult, error) {
+	tracks, err := trackRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	byChecksum := make(map[string]*domain.Track)
+	byFingerprint := make(map[string]*domain.Track)
+	byTags := make(map[string][]*domain.Track)
+	for _, track := range tracks {
+		if !track.IsValid {
+			continue
+		}
+		if track.Checksum != "" {
+			byChecksum[track.Checksum] = track
+		}
+		if track.Fingerprint != "" {
+			byFingerprint[track.Fingerprint] = track
+		}
+		key := tagKey(track.Artist, track.GetDisplayTitle())
+		byTags[key] = append(byTags[key], track)
+	}
+
+	result := &ResolveResult{}
+	for _, shared := range shared.Tracks {
+		if shared.Source != nil {
+			if track := resolveTransientTrack(shared); track != nil {
+				result.Matched = append(result.Matched, track)
+			} else {
+				result.Missing = append(result.Missing, shared)
+			}
+			continue
+		}
+		if match := resolveSharedTrack(shared, byChecksum, byFingerprint, byTags); match != nil {
+			result.Matched = append(result.Matched, match)
+		} else {
+			result.Missing = append(result.Missing, shared)
+		}
+	}
+
+	return result, nil
+}
+
+func resolveSharedTrack(shared SharedTrack, byChecksum, byFingerprint map[string]*domain.Track, byTags map[string][]*domain.Track) *domain.Track {
+	if track, ok := byChecksum[shared.Checksum]; ok && shared.Checksum != "" {
+		return track
+	}
+	if track, ok := byFingerprint[shared.Fingerprint]; ok && shared.Fingerprint != "" {
+		return track
+	}
+
+	duration := time.Duration(shared.DurationMs) * time.Millisecond
+	var best *domain.Track
+	for _, track := range byTags[tagKey(shared.Artist, shared.Title)] {
+		diff := track.Duration - duration
+		if diff < 0 {
+			diff = -diff
+		}
+		if duration > 0 && track.Duration > 0 && diff > shareDurationTolerance {
+			continue
+		}
+		// Prefer the same release when the library has several versions
+		if best == nil || (strings.EqualFold(track.Album, shared.Album) && !strings.EqualFold(best.Album, shared.Album)) {
+			best = track
+		}
+	}
+
+	return best
+}
+
+// resolveTransientTrack recreates a transient entry, or returns nil if it
+// can't be played on this machine
+func resolveTransientTrack(shared SharedTrack) *domain.Track {
+	if shared.Source.IsLocal() {
+		if _, err := os.Stat(shared.Source.URI); err != nil {
+			return nil
+		}
+	}
+
+	track, err := domain.NewTransientTrack(*shared.Source, shared.Title)
+	if err != nil {
+		return nil
+	}
+	track.Artist = shared.Artist
+	track.Album = shared.Album
+	track.TrackNumber = shared.TrackNumber
+	track.DiscNumber = shared.DiscNumber
+	track.Duration = time.Duration(shared.DurationMs) * time.Millisecond
+	return track
+}
+
+// tagKey normalises artist and title for matching
+func tagKey(artist, title string) string {
+	normalize := func(s string) string {
+		return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	}
+	return normalize(artist) + "\x00" + normalize(title)
+}
@@ -0,0 +1,101 @@
+package playlist
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+// ShuffleMode is how the queue is shuffled
+type ShuffleMode int
+
+const (
+	ShuffleOff     ShuffleMode = iota
+	ShuffleTracks              // Every track in random order
+	ShuffleAlbums              // Albums in random order, each played in sequence
+	ShuffleArtists             // Artists in random order, each artist's tracks kept together
+)
+
+func (m ShuffleMode) String() string {
+	switch m {
+	case ShuffleTracks:
+		return "tracks"
+	case ShuffleAlbums:
+		return "albums"
+	case ShuffleArtists:
+		return "artists"
+	default:
+		return "off"
+	}
+}
+
+// ParseShuffleMode parses a shuffle mode name as returned by String
+func ParseShuffleMode(s string) (ShuffleMode, error) {
+	switch s {
+	case "off":
+		return ShuffleOff, nil
+	case "tracks":
+		return ShuffleTracks, nil
+	case "albums":
+		return ShuffleAlbums, nil
+	case "artists":
+		return ShuffleArtists, nil
+	default:
+		return ShuffleOff, fmt.Errorf("%w: unknown shuffle mode %q", domain.ErrInvalidInput, s)
+	}
+}
+
+// shuffleGroups puts a play order, of indexes into tracks, into a random
+// order of groups, keeping each group's tracks together. Tracks without a
+// key are groups of their own. Within a group tracks keep their order, or
+// are put in disc and track order when inSequence is set.
+func shuffleGroups(order []int, tracks []*domain.Track, key func(*domain.Track) string, inSequence bool) {
+	var groups [][]int
+	index := make(map[string]int)
+	for _, i := range order {
+		k := key(tracks[i])
+		g, ok := index[k]
+		if !ok || k == "" {
+			g = len(groups)
+			groups = append(groups, nil)
+			if k != "" {
+				index[k] = g
+			}
+		}
+		groups[g] = append(groups[g], i)
+	}
+
+	rand.Shuffle(len(groups), func(i, j int) {
+		groups[i], groups[j] = groups[j], groups[i]
+	})
+
+	n := 0
+	for _, group := range groups {
+		if inSequence {
+			slices.SortStableFunc(group, func(a, b int) int {
+				return compareAlbumOrder(tracks[a], tracks[b])
+			})
+		}
+		n += copy(order[n:], group)
+	}
+}
+
+// compareAlbumOrder orders tracks of an album by disc, then track number
+func compareAlbumOrder(a, b *domain.Track) int {
+	if a.DiscNumber != b.DiscNumber {
+		return a.DiscNumber - b.DiscNumber
+	}
+	return a.TrackNumber - b.TrackNumber
+}
+
+// artistKey groups tracks by album artist, falling back to the track artist
+func artistKey(track *domain.Track) string {
+	artist := track.AlbumArtist
+	if artist == "" {
+		artist = track.Artist
+	}
+	return strings.ToLower(artist)
+}
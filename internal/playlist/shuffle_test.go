@@ -0,0 +1,194 @@
+package playlist
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func TestShuffleAlbums(t *testing.T) {
+	var tracks []*domain.Track
+	for _, album := range []string{"A", "B", "C", "D"} {
+		// Queued out of order, as a search might add them
+		for _, number := range []int{3, 1, 2} {
+			tracks = append(tracks, &domain.Track{
+				ID:          fmt.Sprintf("%s%d", album, number),
+				Album:       album,
+				Artist:      "Band",
+				TrackNumber: number,
+			})
+		}
+	}
+	single := &domain.Track{ID: "single", Artist: "Band"}
+	tracks = append(tracks, single)
+
+	q := NewQueue()
+	q.AddAll(tracks)
+	q.SetShuffleMode(ShuffleAlbums)
+	assert.Equal(t, ShuffleAlbums, q.GetShuffleMode())
+	assert.True(t, q.IsShuffle())
+
+	shuffled := q.GetTracks()
+	require.Len(t, shuffled, len(tracks))
+	seen := make(map[string]bool)
+	for i := 0; i < len(shuffled); {
+		if shuffled[i] == single {
+			i++
+			continue
+		}
+		album := shuffled[i].Album
+		assert.False(t, seen[album], "album %s split up", album)
+		seen[album] = true
+		for number := 1; number <= 3; number++ {
+			assert.Equal(t, fmt.Sprintf("%s%d", album, number), shuffled[i].ID)
+			i++
+		}
+	}
+	assert.Len(t, seen, 4)
+}
+
+func TestShuffleArtistsKeepsCurrent(t *testing.T) {
+	q := NewQueue()
+	for i := 0; i < 9; i++ {
+		q.Add(&domain.Track{ID: fmt.Sprint(i), Artist: fmt.Sprintf("Artist %d", i%3)})
+	}
+	current := q.Advance()
+	q.SetShuffleMode(ShuffleArtists)
+
+	tracks := q.GetTracks()
+	assert.Equal(t, current, tracks[0])
+
+	// Each artist's tracks are together, in their queued order
+	for i := 1; i < len(tracks); i++ {
+		if tracks[i].Artist == tracks[i-1].Artist {
+			assert.Less(t, tracks[i-1].ID, tracks[i].ID)
+		}
+	}
+	changes := 0
+	for i := 2; i < len(tracks); i++ {
+		if tracks[i].Artist != tracks[i-1].Artist {
+			changes++
+		}
+	}
+	assert.Equal(t, 2, changes)
+}
+
+func TestUnshuffle(t *testing.T) {
+	q := NewQueue()
+	var tracks []*domain.Track
+	for i := 0; i < 20; i++ {
+		tracks = append(tracks, &domain.Track{ID: fmt.Sprintf("%02d", i)})
+	}
+	q.AddAll(tracks)
+	q.Advance()
+	q.Advance()
+	q.SetShuffle(true)
+
+	// Every track is still queued once, with the played ones in place
+	shuffled := q.GetTracks()
+	assert.ElementsMatch(t, tracks, shuffled)
+	assert.Equal(t, tracks[:2], shuffled[:2])
+	assert.NotEqual(t, tracks, shuffled, "20 tracks shuffled into their own order")
+
+	// Play on, queue one next and drop one, then put the rest back
+	current := q.Advance()
+	next := &domain.Track{ID: "next"}
+	q.AddNext(next)
+	dropped := q.Peek()
+	require.Equal(t, next, dropped)
+	require.NoError(t, q.Remove(q.GetPosition()+1))
+	q.AddNext(next)
+	q.RemoveTrack(shuffled[5].ID)
+
+	q.Unshuffle()
+	assert.False(t, q.IsShuffle())
+	assert.Equal(t, current, q.Current())
+	assert.Equal(t, next, q.Peek())
+
+	var want []*domain.Track
+	for _, track := range tracks {
+		if track == shuffled[5] {
+			continue
+		}
+		want = append(want, track)
+		if track == current {
+			want = append(want, next)
+		}
+	}
+	assert.Equal(t, want, q.GetTracks())
+}
+
+func TestShuffleOffKeepsOrder(t *testing.T) {
+	q := NewQueue()
+	for i := 0; i < 10; i++ {
+		q.Add(&domain.Track{ID: fmt.Sprint(i)})
+	}
+	q.SetShuffle(true)
+	shuffled := q.GetTracks()
+
+	q.SetShuffle(false)
+	assert.False(t, q.IsShuffle())
+	assert.Equal(t, shuffled, q.GetTracks())
+}
+
+func TestShuffleFamilyMode(t *testing.T) {
+	q := NewQueue()
+	for i := 0; i < 10; i++ {
+		track := &domain.Track{ID: fmt.Sprint(i)}
+		if i%2 == 1 {
+			track.Advisory = domain.AdvisoryExplicit
+		}
+		q.Add(track)
+	}
+	q.SetShuffle(true)
+	q.SetFamilyMode(true)
+
+	var played []string
+	for track := q.Advance(); track != nil; track = q.Advance() {
+		assert.False(t, track.IsExplicit(), "track %s", track.ID)
+		played = append(played, track.ID)
+	}
+	assert.Len(t, played, 5)
+	assert.Len(t, q.GetTracks(), 10, "explicit tracks stay queued")
+
+	// Played in order, the queue plays what was queued
+	q.SetShuffle(false)
+	q.SetRepeat(RepeatAll)
+	explicit := 0
+	for i := 0; i < 10; i++ {
+		if q.Advance().IsExplicit() {
+			explicit++
+		}
+	}
+	assert.Equal(t, 5, explicit)
+}
+
+func TestShuffleLive(t *testing.T) {
+	q := NewQueue()
+	for i := 0; i < 6; i++ {
+		q.Add(&domain.Track{ID: fmt.Sprint(i), IsLive: i < 3})
+	}
+	q.SetShuffle(true)
+	q.SetShuffleLive(false)
+
+	played := 0
+	for track := q.Advance(); track != nil; track = q.Advance() {
+		assert.False(t, track.IsLive, "track %s", track.ID)
+		played++
+	}
+	assert.Equal(t, 3, played)
+}
+
+func TestParseShuffleMode(t *testing.T) {
+	for _, mode := range []ShuffleMode{ShuffleOff, ShuffleTracks, ShuffleAlbums, ShuffleArtists} {
+		parsed, err := ParseShuffleMode(mode.String())
+		require.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+	_, err := ParseShuffleMode("genres")
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
@@ -0,0 +1,127 @@
+package playlist
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// smartRefreshDelay is how long library changes must settle before rule
+// playlists are refreshed, so a scan or a run of edits refreshes them once
+const smartRefreshDelay = 2 * time.Second
+
+// SetTrackRepository sets where smart playlists find the tracks meeting
+// their rules
+func (m *Manager) SetTrackRepository(repo domain.TrackRepository) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.trackRepo = repo
+}
+
+// CreateSmart creates a smart playlist of the tracks meeting rules, and
+// fills it
+func (m *Manager) CreateSmart(name string, rules domain.SmartRules) (*domain.Playlist, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	playlist, err := domain.NewPlaylist(name, domain.PlaylistTypeSmart)
+	if err != nil {
+		return nil, err
+	}
+	playlist.Rules = &rules
+
+	tracks, err := m.findByRules(playlist.Rules)
+	if err != nil {
+		return nil, err
+	}
+	playlist.SetTracks(tracks)
+
+	m.mu.Lock()
+	m.playlists[playlist.ID] = playlist
+	m.mu.Unlock()
+
+	if m.repo != nil {
+		if err := m.repo.Create(playlist); err != nil {
+			logger.ErrorLog("Failed to save playlist", logger.Error(err))
+		}
+	}
+
+	events.Publish(m.eventBus(), TopicPlaylistChanged, playlist.ID)
+	return playlist, nil
+}
+
+// UpdateSmart renames a rule-based smart playlist and replaces its rules,
+// refilling it
+func (m *Manager) UpdateSmart(id, name string, rules domain.SmartRules) (*domain.Playlist, error) {
+	playlist, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !playlist.IsRuleBased() {
+		return nil, fmt.Errorf("%w: %s has no rules", domain.ErrInvalidPlaylist, playlist.Name)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", domain.ErrInvalidPlaylist)
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	tracks, err := m.findByRules(&rules)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist.Name = name
+	playlist.Rules = &rules
+	playlist.SetTracks(tracks)
+
+	return playlist, m.Update(playlist)
+}
+
+// RefreshSmart rebuilds every rule-based playlist, as when the library
+// changes. Failures are logged and the rest carry on.
+func (m *Manager) RefreshSmart() {
+	for _, playlist := range m.GetAll() {
+		if !playlist.IsRuleBased() {
+			continue
+		}
+		if err := m.Refresh(playlist.ID); err != nil {
+			logger.Warn("Failed to refresh playlist", logger.String("playlist", playlist.Name), logger.Error(err))
+		}
+	}
+}
+
+// LibraryChanged refreshes the rule-based playlists once changes to the
+// library settle. Calls in quick succession refresh them once.
+func (m *Manager) LibraryChanged() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.smartRefresh != nil {
+		m.smartRefresh.Reset(smartRefreshDelay)
+		return
+	}
+	m.smartRefresh = time.AfterFunc(smartRefreshDelay, func() {
+		m.mu.Lock()
+		m.smartRefresh = nil
+		m.mu.Unlock()
+		m.RefreshSmart()
+	})
+}
+
+// findByRules returns the library tracks meeting rules
+func (m *Manager) findByRules(rules *domain.SmartRules) ([]*domain.Track, error) {
+	m.mu.RLock()
+	repo := m.trackRepo
+	m.mu.RUnlock()
+	if repo == nil {
+		return nil, errors.New("smart playlists need a track repository")
+	}
+	return repo.FindBySmartRules(rules, time.Now())
+}
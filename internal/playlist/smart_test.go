@@ -0,0 +1,62 @@
+package playlist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/winramp/winramp/internal/domain"
+)
+
+func (f *fakeTracks) FindBySmartRules(rules *domain.SmartRules, now time.Time) ([]*domain.Track, error) {
+	return rules.Apply(f.tracks, now), nil
+}
+
+func TestSmartPlaylists(t *testing.T) {
+	tracks := &fakeTracks{tracks: []*domain.Track{
+		{ID: "a", Genre: "Jazz", Rating: 5},
+		{ID: "b", Genre: "Rock", Rating: 4},
+		{ID: "c", Genre: "Jazz", Rating: 2},
+	}}
+	m := NewManager(nil)
+	m.SetTrackRepository(tracks)
+
+	jazz := domain.SmartRules{Conditions: []domain.RuleCondition{
+		{Field: "genre", Operator: domain.OperatorEquals, Value: "jazz"},
+	}}
+	playlist, err := m.CreateSmart("Jazz", jazz)
+	require.NoError(t, err)
+	assert.True(t, playlist.IsRuleBased())
+	assert.Equal(t, []string{"a", "c"}, playlist.TrackIDs)
+	assert.NoError(t, playlist.Validate())
+
+	// The library changing shows on refresh
+	tracks.tracks = append(tracks.tracks, &domain.Track{ID: "d", Genre: "Jazz"})
+	m.RefreshSmart()
+	assert.Equal(t, []string{"a", "c", "d"}, playlist.TrackIDs)
+
+	liked := domain.SmartRules{
+		Conditions: []domain.RuleCondition{{Field: "rating", Operator: domain.OperatorGreater, Value: 3.0}},
+		OrderBy:    "rating",
+	}
+	updated, err := m.UpdateSmart(playlist.ID, "Liked", liked)
+	require.NoError(t, err)
+	assert.Equal(t, "Liked", updated.Name)
+	assert.Equal(t, []string{"b", "a"}, updated.TrackIDs)
+
+	// Rules are checked before anything changes
+	bad := domain.SmartRules{Conditions: []domain.RuleCondition{{Field: "mood", Operator: domain.OperatorEquals, Value: "sad"}}}
+	_, err = m.UpdateSmart(playlist.ID, "Sad", bad)
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	assert.Equal(t, "Liked", updated.Name)
+	_, err = m.CreateSmart("Sad", bad)
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	static, err := m.Create("Static")
+	require.NoError(t, err)
+	_, err = m.UpdateSmart(static.ID, "Static", jazz)
+	assert.ErrorIs(t, err, domain.ErrInvalidPlaylist)
+	assert.Len(t, m.GetAll(), 2)
+}
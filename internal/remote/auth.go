@@ -0,0 +1,240 @@
+package remote
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+const (
+	// pairingTTL is how long a pairing PIN shown in the UI stays valid
+	pairingTTL = 2 * time.Minute
+
+	// maxPairingAttempts is how many wrong PINs end a pairing, so the PIN
+	// can't be guessed in its lifetime
+	maxPairingAttempts = 5
+
+	pairingPINDigits = 6
+
+	// lastSeenInterval limits how often a client's last use is written
+	lastSeenInterval = time.Minute
+)
+
+var (
+	ErrPairingNotStarted = errors.New("no pairing in progress")
+	ErrPairingFailed     = errors.New("wrong or expired pairing PIN")
+)
+
+// TopicClientPaired is published when a client completes pairing
+var TopicClientPaired = events.NewTopic[*domain.RemoteClient]("remote:clientPaired")
+
+// pairing is a pairing in progress
+type pairing struct {
+	pin      string
+	scope    domain.ClientScope
+	expires  time.Time
+	attempts int
+}
+
+// authState holds the pairing in progress and when clients were last seen
+type authState struct {
+	mu      sync.Mutex
+	pairing *pairing
+	seen    map[string]time.Time
+}
+
+// StartPairing creates a one-time PIN for the UI to show. A client that
+// sends it to /pair before it expires is given a token with the scope.
+// Starting again replaces any earlier PIN.
+func (s *Server) StartPairing(scope domain.ClientScope) (string, time.Time, error) {
+	// Every known scope includes read access
+	if !scope.Allows(domain.ScopeRead) {
+		return "", time.Time{}, fmt.Errorf("%w: unknown scope %q", domain.ErrInvalidRemoteClient, scope)
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate PIN: %w", err)
+	}
+
+	p := &pairing{
+		pin:     fmt.Sprintf("%0*d", pairingPINDigits, n.Int64()),
+		scope:   scope,
+		expires: time.Now().Add(pairingTTL),
+	}
+
+	s.auth.mu.Lock()
+	s.auth.pairing = p
+	s.auth.mu.Unlock()
+
+	return p.pin, p.expires, nil
+}
+
+// CancelPairing ends the pairing in progress, if any
+func (s *Server) CancelPairing() {
+	s.auth.mu.Lock()
+	defer s.auth.mu.Unlock()
+	s.auth.pairing = nil
+}
+
+// pair checks a PIN against the pairing in progress and, when it matches,
+// ends the pairing and returns the scope to grant
+func (s *Server) pair(pin string) (domain.ClientScope, error) {
+	s.auth.mu.Lock()
+	defer s.auth.mu.Unlock()
+
+	p := s.auth.pairing
+	if p == nil || time.Now().After(p.expires) {
+		s.auth.pairing = nil
+		return "", ErrPairingNotStarted
+	}
+
+	if subtle.ConstantTimeCompare([]byte(pin), []byte(p.pin)) != 1 {
+		p.attempts++
+		if p.attempts >= maxPairingAttempts {
+			s.auth.pairing = nil
+			logger.Warn("Remote pairing cancelled after repeated wrong PINs")
+		}
+		return "", ErrPairingFailed
+	}
+
+	s.auth.pairing = nil
+	return p.scope, nil
+}
+
+func (s *Server) handlePair(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		PIN  string `json:"pin"`
+		Name string `json:"name"` // Shown in the list of paired clients
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+
+	client, token, err := s.completePairing(body.PIN, body.Name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrPairingNotStarted), errors.Is(err, ErrPairingFailed):
+			status = http.StatusForbidden
+		case errors.Is(err, domain.ErrInvalidRemoteClient):
+			status = http.StatusBadRequest
+		}
+		writeError(w, status, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"token":  token,
+		"client": client,
+	})
+}
+
+// completePairing pairs a client that sent the right PIN, returning it and
+// the token it authenticates with from now on
+func (s *Server) completePairing(pin, name string) (*domain.RemoteClient, string, error) {
+	scope, err := s.pair(strings.TrimSpace(pin))
+	if err != nil {
+		return nil, "", err
+	}
+
+	client, token, err := domain.NewRemoteClient(name, scope)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.clients.Create(client); err != nil {
+		return nil, "", err
+	}
+
+	logger.Info("Remote client paired",
+		logger.String("name", client.Name),
+		logger.String("scope", string(client.Scope)))
+	events.Publish(s.bus, TopicClientPaired, client)
+	return client, token, nil
+}
+
+// clientForToken returns the paired client a bearer token belongs to.
+// Unknown and revoked tokens give domain.ErrRemoteClientNotFound.
+func (s *Server) clientForToken(token string) (*domain.RemoteClient, error) {
+	return s.clients.FindByTokenHash(domain.HashClientToken(token))
+}
+
+// authorize wraps a handler so it only runs for clients whose token has at
+// least the required scope
+func (s *Server) authorize(required domain.ClientScope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="winramp"`)
+			writeError(w, http.StatusUnauthorized, errors.New("authorization required"))
+			return
+		}
+
+		client, err := s.clientForToken(token)
+		if errors.Is(err, domain.ErrRemoteClientNotFound) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="winramp", error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or revoked token"))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if !client.Scope.Allows(required) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("%s scope required", required))
+			return
+		}
+
+		s.noteSeen(client)
+		next(w, r)
+	}
+}
+
+// noteSeen records a client's use, at most once per lastSeenInterval
+func (s *Server) noteSeen(client *domain.RemoteClient) {
+	now := time.Now()
+
+	s.auth.mu.Lock()
+	if now.Sub(s.auth.seen[client.ID]) < lastSeenInterval {
+		s.auth.mu.Unlock()
+		return
+	}
+	s.auth.seen[client.ID] = now
+	s.auth.mu.Unlock()
+
+	if err := s.clients.UpdateLastSeen(client.ID, now); err != nil {
+		logger.Debug("Failed to record remote client use", logger.Error(err))
+	}
+}
+
+func (s *Server) handleClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := s.clients.FindAll()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"clients": clients})
+}
+
+func (s *Server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := s.clients.Delete(r.PathValue("id")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrRemoteClientNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,111 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/winramp/winramp/internal/events"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// eventBuffer is how many events a slow client may fall behind by before
+// events are dropped for it
+const eventBuffer = 64
+
+// noisyTopics are left out of event streams unless asked for by name, as
+// they are sent many times a second
+var noisyTopics = map[string]bool{
+	"player:positionChanged": true,
+}
+
+// handleEvents streams bus events as server-sent events, each named after
+// its topic with a JSON payload. The topics query parameter limits the
+// stream to a comma-separated list of topics.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	var topics []string
+	if list := r.URL.Query().Get("topics"); list != "" {
+		topics = strings.Split(list, ",")
+	}
+
+	pending, done, unsubscribe := s.subscribeEvents(topics)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-done:
+			return
+		case event := <-pending:
+			data, err := json.Marshal(eventPayload(event.Payload))
+			if err != nil {
+				logger.Debug("Failed to encode event", logger.String("topic", event.Topic), logger.Error(err))
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Topic, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// subscribeEvents queues the bus events for a client's stream, limited to
+// topics when given. done is closed when the server shuts down.
+func (s *Server) subscribeEvents(topics []string) (pending <-chan events.Event, done <-chan struct{}, unsubscribe func()) {
+	var wanted map[string]bool
+	if len(topics) > 0 {
+		wanted = make(map[string]bool)
+		for _, topic := range topics {
+			wanted[strings.TrimSpace(topic)] = true
+		}
+	}
+
+	s.mu.Lock()
+	done = s.done
+	s.mu.Unlock()
+
+	queue := make(chan events.Event, eventBuffer)
+	unsubscribe = s.bus.SubscribeAll(func(event events.Event) {
+		if (wanted != nil && !wanted[event.Topic]) || (wanted == nil && noisyTopics[event.Topic]) {
+			return
+		}
+		select {
+		case queue <- event:
+		default:
+			// The client can't keep up; it misses events rather than
+			// holding up the bus
+		}
+	})
+	return queue, done, unsubscribe
+}
+
+// eventPayload converts payloads that don't encode usefully on their own:
+// durations become seconds, errors their message and enumerations such as
+// player states their name
+func eventPayload(payload interface{}) interface{} {
+	switch v := payload.(type) {
+	case time.Duration:
+		return v.Seconds()
+	case error:
+		return map[string]string{"message": v.Error()}
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return payload
+	}
+}
@@ -0,0 +1,460 @@
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+	"github.com/winramp/winramp/internal/remote/remotepb"
+)
+
+// methodScopes is the scope each gRPC method needs. Methods missing here
+// need admin, so a new method is never left open by accident.
+var methodScopes = map[string]domain.ClientScope{
+	remotepb.Remote_GetVersion_FullMethodName: "",
+	remotepb.Remote_Pair_FullMethodName:       "",
+
+	remotepb.Remote_GetPlayerState_FullMethodName: domain.ScopeRead,
+	remotepb.Remote_GetQueue_FullMethodName:       domain.ScopeRead,
+	remotepb.Remote_SearchTracks_FullMethodName:   domain.ScopeRead,
+	remotepb.Remote_GetTrack_FullMethodName:       domain.ScopeRead,
+	remotepb.Remote_StreamEvents_FullMethodName:   domain.ScopeRead,
+
+	remotepb.Remote_Play_FullMethodName:       domain.ScopeControl,
+	remotepb.Remote_Pause_FullMethodName:      domain.ScopeControl,
+	remotepb.Remote_Stop_FullMethodName:       domain.ScopeControl,
+	remotepb.Remote_Next_FullMethodName:       domain.ScopeControl,
+	remotepb.Remote_Previous_FullMethodName:   domain.ScopeControl,
+	remotepb.Remote_Seek_FullMethodName:       domain.ScopeControl,
+	remotepb.Remote_SetVolume_FullMethodName:  domain.ScopeControl,
+	remotepb.Remote_PlayTracks_FullMethodName: domain.ScopeControl,
+	remotepb.Remote_JumpTo_FullMethodName:     domain.ScopeControl,
+	remotepb.Remote_Enqueue_FullMethodName:    domain.ScopeControl,
+
+	remotepb.Remote_ListClients_FullMethodName:  domain.ScopeAdmin,
+	remotepb.Remote_RevokeClient_FullMethodName: domain.ScopeAdmin,
+}
+
+// StartGRPC listens on addr and serves the gRPC service in the background,
+// with the same pairing, tokens, limits and certificate as the HTTP API
+func (s *Server) StartGRPC(addr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.grpcServer != nil {
+		return errors.New("remote control gRPC server already running")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	if s.limits.MaxConnections > 0 {
+		listener = newLimitListener(listener, s.limits.MaxConnections)
+	}
+
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unaryGuard),
+		grpc.ChainStreamInterceptor(s.streamGuard),
+	}
+	if s.cert != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{*s.cert},
+			MinVersion:   tls.VersionTLS12,
+		})))
+	}
+
+	server := grpc.NewServer(options...)
+	remotepb.RegisterRemoteServer(server, &grpcService{s: s})
+
+	s.grpcServer = server
+	s.grpcListener = listener
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			logger.ErrorLog("Remote control gRPC server stopped", logger.Error(err))
+		}
+	}()
+
+	logger.Info("Remote control gRPC server started",
+		logger.String("address", listener.Addr().String()),
+		logger.Bool("tls", s.cert != nil))
+	return nil
+}
+
+// GRPCAddr returns the address the gRPC service listens on, or "" when
+// stopped
+func (s *Server) GRPCAddr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.grpcListener == nil {
+		return ""
+	}
+	return s.grpcListener.Addr().String()
+}
+
+// stopGRPC stops the gRPC service, letting calls finish until ctx is done
+func stopGRPC(ctx context.Context, server *grpc.Server) error {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return ctx.Err()
+	}
+}
+
+// unaryGuard rate limits, authorizes and logs unary calls
+func (s *Server) unaryGuard(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	if err := s.guardCall(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	resp, err := handler(ctx, req)
+	logger.Debug("Remote gRPC call",
+		logger.String("method", info.FullMethod),
+		logger.String("code", status.Code(err).String()),
+		logger.Duration("took", time.Since(start)))
+	return resp, err
+}
+
+// streamGuard rate limits and authorizes streaming calls
+func (s *Server) streamGuard(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.guardCall(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// guardCall checks a call against the caller's rate limit and the scope
+// its method needs
+func (s *Server) guardCall(ctx context.Context, method string) error {
+	ip := peerIP(ctx)
+	if _, ok := s.rates.allow(ip, time.Now()); !ok {
+		logger.Debug("Remote gRPC call rate limited",
+			logger.String("client", ip),
+			logger.String("method", method))
+		return status.Error(codes.ResourceExhausted, "too many requests")
+	}
+
+	required, ok := methodScopes[method]
+	if !ok {
+		required = domain.ScopeAdmin
+	}
+	if required == "" {
+		return nil
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get("authorization") {
+			if t, ok := strings.CutPrefix(value, "Bearer "); ok {
+				token = t
+			}
+		}
+	}
+	if token == "" {
+		return status.Error(codes.Unauthenticated, "authorization required")
+	}
+
+	client, err := s.clientForToken(token)
+	if errors.Is(err, domain.ErrRemoteClientNotFound) {
+		return status.Error(codes.Unauthenticated, "invalid or revoked token")
+	}
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if !client.Scope.Allows(required) {
+		return status.Errorf(codes.PermissionDenied, "%s scope required", required)
+	}
+
+	s.noteSeen(client)
+	return nil
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// grpcService implements the gRPC service over the server's backend
+type grpcService struct {
+	remotepb.UnimplementedRemoteServer
+	s *Server
+}
+
+func (g *grpcService) GetVersion(ctx context.Context, req *remotepb.GetVersionRequest) (*remotepb.GetVersionResponse, error) {
+	return &remotepb.GetVersionResponse{Api: APIVersion}, nil
+}
+
+func (g *grpcService) Pair(ctx context.Context, req *remotepb.PairRequest) (*remotepb.PairResponse, error) {
+	client, token, err := g.s.completePairing(req.Pin, req.Name)
+	switch {
+	case errors.Is(err, ErrPairingNotStarted), errors.Is(err, ErrPairingFailed):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, domain.ErrInvalidRemoteClient):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &remotepb.PairResponse{Token: token, Client: clientMessage(client)}, nil
+}
+
+func (g *grpcService) GetPlayerState(ctx context.Context, req *remotepb.GetPlayerStateRequest) (*remotepb.PlayerState, error) {
+	return playerStateMessage(g.s.backend.GetPlayerState()), nil
+}
+
+func (g *grpcService) GetQueue(ctx context.Context, req *remotepb.GetQueueRequest) (*remotepb.TrackList, error) {
+	return trackListMessage(g.s.backend.GetQueueTracks()), nil
+}
+
+func (g *grpcService) SearchTracks(ctx context.Context, req *remotepb.SearchTracksRequest) (*remotepb.TrackList, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	return trackListMessage(g.s.backend.SearchTracks(req.Query)), nil
+}
+
+func (g *grpcService) GetTrack(ctx context.Context, req *remotepb.GetTrackRequest) (*remotepb.Track, error) {
+	track, err := g.s.backend.GetTrack(req.Id)
+	if errors.Is(err, domain.ErrTrackNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &remotepb.Track{
+		Id:          track.ID,
+		Title:       track.GetDisplayTitle(),
+		Artist:      track.GetDisplayArtist(),
+		Album:       track.Album,
+		TrackNumber: int32(track.TrackNumber),
+		DiscNumber:  int32(track.DiscNumber),
+		Duration:    track.Duration.Seconds(),
+		Year:        int32(track.Year),
+		Genre:       track.Genre,
+		Rating:      int32(track.Rating),
+		Favorite:    track.Favorite,
+	}, nil
+}
+
+// StreamEvents sends bus events as they are published, until the client
+// goes away or the server shuts down
+func (g *grpcService) StreamEvents(req *remotepb.StreamEventsRequest, stream remotepb.Remote_StreamEventsServer) error {
+	pending, done, unsubscribe := g.s.subscribeEvents(req.Topics)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-done:
+			return status.Error(codes.Unavailable, "server shutting down")
+		case event := <-pending:
+			data, err := json.Marshal(eventPayload(event.Payload))
+			if err != nil {
+				logger.Debug("Failed to encode event", logger.String("topic", event.Topic), logger.Error(err))
+				continue
+			}
+			payload := &structpb.Value{}
+			if err := payload.UnmarshalJSON(data); err != nil {
+				logger.Debug("Failed to encode event", logger.String("topic", event.Topic), logger.Error(err))
+				continue
+			}
+			if err := stream.Send(&remotepb.Event{Topic: event.Topic, Payload: payload}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (g *grpcService) Play(ctx context.Context, req *remotepb.PlayRequest) (*remotepb.PlayerState, error) {
+	return g.action(g.s.backend.Play)
+}
+
+func (g *grpcService) Pause(ctx context.Context, req *remotepb.PauseRequest) (*remotepb.PlayerState, error) {
+	return g.action(g.s.backend.Pause)
+}
+
+func (g *grpcService) Stop(ctx context.Context, req *remotepb.StopRequest) (*remotepb.PlayerState, error) {
+	return g.action(g.s.backend.Stop)
+}
+
+func (g *grpcService) Next(ctx context.Context, req *remotepb.NextRequest) (*remotepb.PlayerState, error) {
+	return g.action(g.s.backend.Next)
+}
+
+func (g *grpcService) Previous(ctx context.Context, req *remotepb.PreviousRequest) (*remotepb.PlayerState, error) {
+	return g.action(g.s.backend.Previous)
+}
+
+func (g *grpcService) Seek(ctx context.Context, req *remotepb.SeekRequest) (*remotepb.PlayerState, error) {
+	if req.Position < 0 {
+		return nil, status.Error(codes.InvalidArgument, "position must not be negative")
+	}
+	return g.action(func() error { return g.s.backend.Seek(req.Position) })
+}
+
+func (g *grpcService) SetVolume(ctx context.Context, req *remotepb.SetVolumeRequest) (*remotepb.PlayerState, error) {
+	return g.action(func() error { return g.s.backend.SetVolume(req.Volume) })
+}
+
+func (g *grpcService) PlayTracks(ctx context.Context, req *remotepb.PlayTracksRequest) (*remotepb.PlayerState, error) {
+	if err := g.s.backend.PlayTracks(req.Ids, req.Replace); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return playerStateMessage(g.s.backend.GetPlayerState()), nil
+}
+
+func (g *grpcService) JumpTo(ctx context.Context, req *remotepb.JumpToRequest) (*remotepb.PlayerState, error) {
+	return g.action(func() error { return g.s.backend.JumpTo(int(req.Index)) })
+}
+
+func (g *grpcService) Enqueue(ctx context.Context, req *remotepb.EnqueueRequest) (*remotepb.EnqueueResponse, error) {
+	added, err := g.s.backend.EnqueueTracks(req.Ids, req.Next)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &remotepb.EnqueueResponse{Added: int32(added)}, nil
+}
+
+func (g *grpcService) ListClients(ctx context.Context, req *remotepb.ListClientsRequest) (*remotepb.ListClientsResponse, error) {
+	clients, err := g.s.clients.FindAll()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &remotepb.ListClientsResponse{Clients: make([]*remotepb.Client, len(clients))}
+	for i, client := range clients {
+		resp.Clients[i] = clientMessage(client)
+	}
+	return resp, nil
+}
+
+func (g *grpcService) RevokeClient(ctx context.Context, req *remotepb.RevokeClientRequest) (*remotepb.RevokeClientResponse, error) {
+	if err := g.s.clients.Delete(req.Id); err != nil {
+		if errors.Is(err, domain.ErrRemoteClientNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &remotepb.RevokeClientResponse{}, nil
+}
+
+// action runs a player action and returns the state after it, as the HTTP
+// API does
+func (g *grpcService) action(fn func() error) (*remotepb.PlayerState, error) {
+	if err := fn(); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return playerStateMessage(g.s.backend.GetPlayerState()), nil
+}
+
+// playerStateMessage converts the backend's player state map
+func playerStateMessage(state map[string]interface{}) *remotepb.PlayerState {
+	msg := &remotepb.PlayerState{
+		State:       mapString(state, "state"),
+		Position:    mapFloat(state, "position"),
+		Duration:    mapFloat(state, "duration"),
+		Volume:      mapFloat(state, "volume"),
+		QueueIndex:  int32(mapFloat(state, "queueIndex")),
+		QueueLength: int32(mapFloat(state, "queueLength")),
+	}
+	if track, ok := state["track"].(map[string]interface{}); ok {
+		msg.Track = trackMessage(track)
+	}
+	return msg
+}
+
+func trackListMessage(tracks []map[string]interface{}) *remotepb.TrackList {
+	list := &remotepb.TrackList{Tracks: make([]*remotepb.Track, len(tracks))}
+	for i, track := range tracks {
+		list.Tracks[i] = trackMessage(track)
+	}
+	return list
+}
+
+// trackMessage converts a track map as the desktop frontend receives it
+func trackMessage(track map[string]interface{}) *remotepb.Track {
+	favorite, _ := track["favorite"].(bool)
+	return &remotepb.Track{
+		Id:          mapString(track, "id"),
+		Title:       mapString(track, "title"),
+		Artist:      mapString(track, "artist"),
+		Album:       mapString(track, "album"),
+		TrackNumber: int32(mapFloat(track, "trackNumber")),
+		DiscNumber:  int32(mapFloat(track, "discNumber")),
+		Duration:    mapFloat(track, "duration"),
+		Year:        int32(mapFloat(track, "year")),
+		Genre:       mapString(track, "genre"),
+		Rating:      int32(mapFloat(track, "rating")),
+		Favorite:    favorite,
+	}
+}
+
+func clientMessage(client *domain.RemoteClient) *remotepb.Client {
+	msg := &remotepb.Client{
+		Id:        client.ID,
+		Name:      client.Name,
+		Scope:     string(client.Scope),
+		CreatedAt: timestamppb.New(client.CreatedAt),
+	}
+	if client.LastSeenAt != nil {
+		msg.LastSeenAt = timestamppb.New(*client.LastSeenAt)
+	}
+	return msg
+}
+
+func mapString(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// mapFloat reads a number from a map, whichever numeric type it was
+// stored as
+func mapFloat(m map[string]interface{}, key string) float64 {
+	switch v := m[key].(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
@@ -0,0 +1,242 @@
+package remote
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// idleBucketTTL is how long a client's rate limit is remembered after its
+// last request
+const idleBucketTTL = 10 * time.Minute
+
+// Limits protect the host from clients that misbehave. Zero values turn a
+// limit off, except MaxStreams, where zero means no audio is streamed.
+type Limits struct {
+	MaxConnections    int     // Open connections across all clients
+	RequestsPerSecond float64 // Sustained requests per client address
+	Burst             int     // Requests a client may make at once
+	MaxStreams        int     // Tracks streamed at the same time
+}
+
+// DefaultLimits returns the limits a new server starts with
+func DefaultLimits() Limits {
+	return Limits{
+		MaxConnections:    10,
+		RequestsPerSecond: 20,
+		Burst:             40,
+		MaxStreams:        4,
+	}
+}
+
+// SetLimits changes the server's limits. Rate and stream limits apply
+// straight away; the connection limit applies from the next Start.
+func (s *Server) SetLimits(limits Limits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limits = limits
+	s.rates.configure(limits.RequestsPerSecond, limits.Burst)
+	if limits.MaxStreams > 0 {
+		s.streams = make(chan struct{}, limits.MaxStreams)
+	} else {
+		s.streams = nil
+	}
+}
+
+// acquireStream claims one of the stream slots, returning a function that
+// frees it. ok is false when every slot is taken or streaming is off.
+func (s *Server) acquireStream() (release func(), ok bool) {
+	s.mu.Lock()
+	slots := s.streams
+	s.mu.Unlock()
+
+	if slots == nil {
+		return nil, false
+	}
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, true
+	default:
+		return nil, false
+	}
+}
+
+// guard rate limits requests by client address and logs each one
+func (s *Server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ip := clientIP(r)
+
+		if wait, ok := s.rates.allow(ip, start); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, errors.New("too many requests"))
+			logger.Debug("Remote request rate limited",
+				logger.String("client", ip),
+				logger.String("path", r.URL.Path))
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		logger.Debug("Remote request",
+			logger.String("client", ip),
+			logger.String("method", r.Method),
+			logger.String("path", r.URL.Path),
+			logger.Int("status", recorder.status),
+			logger.Duration("took", time.Since(start)))
+	})
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// statusRecorder remembers the status a handler responded with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush passes flushes through, as event streams rely on them
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// bucket is one client's token bucket
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter hands out requests to each client address at a steady rate,
+// allowing short bursts
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*bucket)}
+}
+
+// configure sets the rate and burst, starting every client afresh
+func (l *rateLimiter) configure(rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rate = rate
+	l.burst = float64(max(burst, 1))
+	clear(l.buckets)
+}
+
+// allow takes a token from a client's bucket. When the bucket is empty it
+// returns how long until the next token.
+func (l *rateLimiter) allow(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return 0, true
+	}
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// sweep forgets clients that have gone quiet. Must be called with l.mu
+// held.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < idleBucketTTL {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleBucketTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// limitListener caps the number of connections open at once. Accept waits
+// for a connection to close when the cap is reached.
+type limitListener struct {
+	net.Listener
+	slots chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newLimitListener(listener net.Listener, n int) net.Listener {
+	return &limitListener{
+		Listener: listener,
+		slots:    make(chan struct{}, n),
+		done:     make(chan struct{}),
+	}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	select {
+	case l.slots <- struct{}{}:
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		<-l.slots
+		return nil, err
+	}
+	return &limitConn{Conn: conn, release: func() { <-l.slots }}, nil
+}
+
+func (l *limitListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+// limitConn frees its listener slot once closed
+type limitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
@@ -0,0 +1,303 @@
+package remote
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
+	"github.com/winramp/winramp/internal/remote/remotepb"
+)
+
+func TestPairing(t *testing.T) {
+	s := NewServer(&fakeBackend{}, events.NewBus(), newFakeClients())
+
+	_, _, err := s.completePairing("123456", "script")
+	assert.ErrorIs(t, err, ErrPairingNotStarted)
+
+	_, _, err = s.StartPairing(domain.ClientScope("owner"))
+	assert.ErrorIs(t, err, domain.ErrInvalidRemoteClient)
+
+	pin, expires, err := s.StartPairing(domain.ScopeRead)
+	require.NoError(t, err)
+	assert.Len(t, pin, pairingPINDigits)
+	assert.WithinDuration(t, time.Now().Add(pairingTTL), expires, time.Second)
+
+	client, token, err := s.completePairing(" "+pin+" ", "script")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ScopeRead, client.Scope)
+	found, err := s.clientForToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, client.ID, found.ID)
+
+	// A PIN works once
+	_, _, err = s.completePairing(pin, "again")
+	assert.ErrorIs(t, err, ErrPairingNotStarted)
+
+	// Too many wrong guesses end the pairing, even for the right PIN
+	pin, _, err = s.StartPairing(domain.ScopeControl)
+	require.NoError(t, err)
+	for i := 0; i < maxPairingAttempts; i++ {
+		_, _, err = s.completePairing("wrong", "guess")
+		assert.ErrorIs(t, err, ErrPairingFailed)
+	}
+	_, _, err = s.completePairing(pin, "late")
+	assert.ErrorIs(t, err, ErrPairingNotStarted)
+
+	// Expired PINs don't pair
+	pin, _, err = s.StartPairing(domain.ScopeControl)
+	require.NoError(t, err)
+	s.auth.pairing.expires = time.Now().Add(-time.Second)
+	_, _, err = s.completePairing(pin, "late")
+	assert.ErrorIs(t, err, ErrPairingNotStarted)
+}
+
+func TestGRPCScopes(t *testing.T) {
+	s, client := startGRPC(t)
+
+	tokens := map[domain.ClientScope]string{}
+	for _, scope := range []domain.ClientScope{domain.ScopeRead, domain.ScopeControl, domain.ScopeAdmin} {
+		pin, _, err := s.StartPairing(scope)
+		require.NoError(t, err)
+		resp, err := client.Pair(context.Background(), &remotepb.PairRequest{Pin: pin, Name: string(scope)})
+		require.NoError(t, err)
+		assert.Equal(t, string(scope), resp.Client.Scope)
+		tokens[scope] = resp.Token
+	}
+
+	calls := map[string]func(ctx context.Context) error{
+		"state": func(ctx context.Context) error {
+			_, err := client.GetPlayerState(ctx, &remotepb.GetPlayerStateRequest{})
+			return err
+		},
+		"play": func(ctx context.Context) error {
+			_, err := client.Play(ctx, &remotepb.PlayRequest{})
+			return err
+		},
+		"clients": func(ctx context.Context) error {
+			_, err := client.ListClients(ctx, &remotepb.ListClientsRequest{})
+			return err
+		},
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		call  string
+		code  codes.Code
+	}{
+		{"no token", "", "state", codes.Unauthenticated},
+		{"unknown token", "not-a-token", "state", codes.Unauthenticated},
+		{"read reads", tokens[domain.ScopeRead], "state", codes.OK},
+		{"read can't control", tokens[domain.ScopeRead], "play", codes.PermissionDenied},
+		{"control controls", tokens[domain.ScopeControl], "play", codes.OK},
+		{"control can't admin", tokens[domain.ScopeControl], "clients", codes.PermissionDenied},
+		{"admin admins", tokens[domain.ScopeAdmin], "clients", codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+tt.token)
+			}
+			assert.Equal(t, tt.code, status.Code(calls[tt.call](ctx)))
+		})
+	}
+
+	// The version needs no token
+	version, err := client.GetVersion(context.Background(), &remotepb.GetVersionRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, APIVersion, version.Api)
+}
+
+func TestGRPCControlAndEvents(t *testing.T) {
+	s, client := startGRPC(t)
+
+	pin, _, err := s.StartPairing(domain.ScopeControl)
+	require.NoError(t, err)
+	paired, err := client.Pair(context.Background(), &remotepb.PairRequest{Pin: pin, Name: "script"})
+	require.NoError(t, err)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+paired.Token)
+
+	state, err := client.Play(ctx, &remotepb.PlayRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "playing", state.State)
+	assert.Equal(t, "Intro", state.Track.Title)
+	assert.Equal(t, 90.5, state.Track.Duration)
+	assert.EqualValues(t, 3, state.QueueLength)
+
+	_, err = client.Seek(ctx, &remotepb.SeekRequest{Position: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	added, err := client.Enqueue(ctx, &remotepb.EnqueueRequest{Ids: []string{"a", "b"}, Next: true})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, added.Added)
+
+	_, err = client.GetTrack(ctx, &remotepb.GetTrackRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := client.StreamEvents(streamCtx, &remotepb.StreamEventsRequest{Topics: []string{"test:volume"}})
+	require.NoError(t, err)
+
+	// Keep publishing until the stream has subscribed
+	topic := events.NewTopic[float64]("test:volume")
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(20 * time.Millisecond):
+				events.Publish(s.bus, topic, 0.5)
+			}
+		}
+	}()
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "test:volume", event.Topic)
+	assert.Equal(t, 0.5, event.Payload.GetNumberValue())
+}
+
+// startGRPC serves a server's gRPC service on a free port and connects to it
+func startGRPC(t *testing.T) (*Server, remotepb.RemoteClient) {
+	bus := events.NewBus()
+	t.Cleanup(bus.Close)
+
+	s := NewServer(&fakeBackend{state: "stopped"}, bus, newFakeClients())
+	require.NoError(t, s.StartGRPC("127.0.0.1:0"))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+
+	conn, err := grpc.NewClient(s.GRPCAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return s, remotepb.NewRemoteClient(conn)
+}
+
+type fakeBackend struct {
+	mu       sync.Mutex
+	state    string
+	enqueued []string
+}
+
+func (b *fakeBackend) setState(state string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = state
+	return nil
+}
+
+func (b *fakeBackend) Play() error                    { return b.setState("playing") }
+func (b *fakeBackend) Pause() error                   { return b.setState("paused") }
+func (b *fakeBackend) Stop() error                    { return b.setState("stopped") }
+func (b *fakeBackend) Next() error                    { return nil }
+func (b *fakeBackend) Previous() error                { return nil }
+func (b *fakeBackend) Seek(seconds float64) error     { return nil }
+func (b *fakeBackend) SetVolume(volume float64) error { return nil }
+func (b *fakeBackend) JumpTo(index int) error         { return nil }
+
+func (b *fakeBackend) GetPlayerState() map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return map[string]interface{}{
+		"state":       b.state,
+		"queueLength": 3,
+		"track": map[string]interface{}{
+			"id":       "t1",
+			"title":    "Intro",
+			"duration": 90.5,
+		},
+	}
+}
+
+func (b *fakeBackend) GetQueueTracks() []map[string]interface{} {
+	return nil
+}
+
+func (b *fakeBackend) PlayTracks(ids []string, replaceQueue bool) error {
+	return nil
+}
+
+func (b *fakeBackend) EnqueueTracks(ids []string, next bool) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.enqueued = append(b.enqueued, ids...)
+	return len(ids), nil
+}
+
+func (b *fakeBackend) SearchTracks(query string) []map[string]interface{} {
+	return nil
+}
+
+func (b *fakeBackend) GetTrack(id string) (*domain.Track, error) {
+	return nil, domain.ErrTrackNotFound
+}
+
+type fakeClients struct {
+	mu      sync.Mutex
+	clients map[string]*domain.RemoteClient
+}
+
+func newFakeClients() *fakeClients {
+	return &fakeClients{clients: make(map[string]*domain.RemoteClient)}
+}
+
+func (r *fakeClients) Create(client *domain.RemoteClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[client.ID] = client
+	return nil
+}
+
+func (r *fakeClients) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.clients[id]; !ok {
+		return domain.ErrRemoteClientNotFound
+	}
+	delete(r.clients, id)
+	return nil
+}
+
+func (r *fakeClients) FindAll() ([]*domain.RemoteClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make([]*domain.RemoteClient, 0, len(r.clients))
+	for _, client := range r.clients {
+		result = append(result, client)
+	}
+	return result, nil
+}
+
+func (r *fakeClients) FindByTokenHash(hash string) (*domain.RemoteClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, client := range r.clients {
+		if client.TokenHash == hash {
+			return client, nil
+		}
+	}
+	return nil, domain.ErrRemoteClientNotFound
+}
+
+func (r *fakeClients) UpdateLastSeen(id string, at time.Time) error {
+	return nil
+}
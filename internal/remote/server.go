@@ -0,0 +1,365 @@
+// Package remote serves a versioned HTTP API for controlling WinRamp from
+// scripts and other programs: playback control, library queries and a
+// stream of the events published on the backend's event bus. It also
+// streams library tracks. The same API is served over gRPC, defined in
+// remotepb/remote.proto, for clients generated in other languages. Clients
+// pair with a PIN shown in the UI and then authenticate with a scoped
+// token.
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// APIVersion prefixes every route. Routes are only ever added to a
+// version; changes that would break clients go into a new one.
+const APIVersion = "v1"
+
+// Backend is the part of the application the API drives. Results are the
+// same maps the desktop frontend receives.
+type Backend interface {
+	Play() error
+	Pause() error
+	Stop() error
+	Next() error
+	Previous() error
+	Seek(seconds float64) error
+	SetVolume(volume float64) error
+	JumpTo(index int) error
+	GetPlayerState() map[string]interface{}
+	GetQueueTracks() []map[string]interface{}
+	PlayTracks(ids []string, replaceQueue bool) error
+	EnqueueTracks(ids []string, next bool) (int, error)
+	SearchTracks(query string) []map[string]interface{}
+	GetTrack(id string) (*domain.Track, error)
+}
+
+// Server is the remote control API server
+type Server struct {
+	backend Backend
+	bus     *events.Bus
+	clients domain.RemoteClientRepository
+	mux     *http.ServeMux
+	handler http.Handler
+	auth    authState
+	rates   *rateLimiter
+
+	mu       sync.Mutex
+	limits   Limits
+	streams  chan struct{}    // Stream slots, nil when streaming is off
+	cert     *tls.Certificate // Served over TLS when set
+	server   *http.Server
+	listener net.Listener
+	done     chan struct{} // Closed on shutdown to end event streams
+
+	grpcServer   *grpc.Server
+	grpcListener net.Listener
+}
+
+// NewServer creates a server for a backend, streaming events from bus and
+// authenticating against the paired clients
+func NewServer(backend Backend, bus *events.Bus, clients domain.RemoteClientRepository) *Server {
+	s := &Server{
+		backend: backend,
+		bus:     bus,
+		clients: clients,
+		mux:     http.NewServeMux(),
+		auth:    authState{seen: make(map[string]time.Time)},
+		rates:   newRateLimiter(),
+		done:    make(chan struct{}),
+	}
+	s.routes()
+	s.handler = s.guard(s.mux)
+	s.SetLimits(DefaultLimits())
+	return s
+}
+
+func (s *Server) routes() {
+	prefix := "/" + APIVersion
+	read := func(pattern string, handler http.HandlerFunc) {
+		s.mux.HandleFunc(pattern, s.authorize(domain.ScopeRead, handler))
+	}
+	control := func(pattern string, handler http.HandlerFunc) {
+		s.mux.HandleFunc(pattern, s.authorize(domain.ScopeControl, handler))
+	}
+	admin := func(pattern string, handler http.HandlerFunc) {
+		s.mux.HandleFunc(pattern, s.authorize(domain.ScopeAdmin, handler))
+	}
+
+	s.mux.HandleFunc("GET "+prefix+"/version", s.handleVersion)
+	s.mux.HandleFunc("POST "+prefix+"/pair", s.handlePair)
+
+	read("GET "+prefix+"/player", s.handlePlayerState)
+	control("POST "+prefix+"/player/play", s.action(s.backend.Play))
+	control("POST "+prefix+"/player/pause", s.action(s.backend.Pause))
+	control("POST "+prefix+"/player/stop", s.action(s.backend.Stop))
+	control("POST "+prefix+"/player/next", s.action(s.backend.Next))
+	control("POST "+prefix+"/player/previous", s.action(s.backend.Previous))
+	control("POST "+prefix+"/player/seek", s.handleSeek)
+	control("POST "+prefix+"/player/volume", s.handleVolume)
+
+	read("GET "+prefix+"/queue", s.handleQueue)
+	control("POST "+prefix+"/queue", s.handleEnqueue)
+	control("POST "+prefix+"/queue/play", s.handlePlayTracks)
+	control("POST "+prefix+"/queue/jump", s.handleJump)
+
+	read("GET "+prefix+"/library/search", s.handleSearch)
+	read("GET "+prefix+"/library/tracks/{id}/stream", s.handleStream)
+
+	read("GET "+prefix+"/events", s.handleEvents)
+
+	admin("GET "+prefix+"/clients", s.handleClients)
+	admin("DELETE "+prefix+"/clients/{id}", s.handleRevoke)
+}
+
+// SetCertificate serves the API over TLS with a certificate, such as the
+// one from LoadOrCreateCertificate. It applies from the next Start.
+func (s *Server) SetCertificate(cert tls.Certificate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cert = &cert
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the server's certificate,
+// for clients to pin, or "" when it doesn't use TLS
+func (s *Server) Fingerprint() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cert == nil {
+		return ""
+	}
+	return CertificateFingerprint(*s.cert)
+}
+
+// Handler returns the API's HTTP handler
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Start listens on addr and serves the API in the background
+func (s *Server) Start(addr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server != nil {
+		return errors.New("remote control server already running")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	if s.limits.MaxConnections > 0 {
+		listener = newLimitListener(listener, s.limits.MaxConnections)
+	}
+	if s.cert != nil {
+		listener = tls.NewListener(listener, &tls.Config{
+			Certificates: []tls.Certificate{*s.cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+	}
+
+	s.listener = listener
+	s.server = &http.Server{
+		Handler:           s.handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.ErrorLog("Remote control server stopped", logger.Error(err))
+		}
+	}(s.server)
+
+	logger.Info("Remote control server started",
+		logger.String("address", listener.Addr().String()),
+		logger.Bool("tls", s.cert != nil))
+	return nil
+}
+
+// Addr returns the address the server listens on, or "" when stopped
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Shutdown stops the HTTP and gRPC servers, ending event streams and
+// waiting for other requests to finish
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	server, grpcServer := s.server, s.grpcServer
+	s.server, s.listener = nil, nil
+	s.grpcServer, s.grpcListener = nil, nil
+	if server != nil || grpcServer != nil {
+		close(s.done)
+		s.done = make(chan struct{})
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	if grpcServer != nil {
+		errs = append(errs, stopGRPC(ctx, grpcServer))
+	}
+	if server != nil {
+		errs = append(errs, server.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// action handles a request that takes no input and returns the player
+// state after the action
+func (s *Server) action(fn func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := fn(); err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.backend.GetPlayerState())
+	}
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"api": APIVersion,
+	})
+}
+
+func (s *Server) handlePlayerState(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.backend.GetPlayerState())
+}
+
+func (s *Server) handleSeek(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Position *float64 `json:"position"` // Seconds
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Position == nil || *body.Position < 0 {
+		writeError(w, http.StatusBadRequest, errors.New("position in seconds is required"))
+		return
+	}
+
+	s.action(func() error { return s.backend.Seek(*body.Position) })(w, r)
+}
+
+func (s *Server) handleVolume(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Volume *float64 `json:"volume"` // 0 to 1
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Volume == nil {
+		writeError(w, http.StatusBadRequest, errors.New("volume is required"))
+		return
+	}
+
+	s.action(func() error { return s.backend.SetVolume(*body.Volume) })(w, r)
+}
+
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tracks": s.backend.GetQueueTracks(),
+	})
+}
+
+func (s *Server) handleEnqueue(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		IDs  []string `json:"ids"`
+		Next bool     `json:"next"` // After the current track rather than at the end
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+
+	added, err := s.backend.EnqueueTracks(body.IDs, body.Next)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"added": added})
+}
+
+func (s *Server) handlePlayTracks(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		IDs     []string `json:"ids"`
+		Replace bool     `json:"replace"` // Replace the queue instead of inserting
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+
+	if err := s.backend.PlayTracks(body.IDs, body.Replace); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.backend.GetPlayerState())
+}
+
+func (s *Server) handleJump(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Index int `json:"index"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+
+	s.action(func() error { return s.backend.JumpTo(body.Index) })(w, r)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, errors.New("query parameter q is required"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tracks": s.backend.SearchTracks(query),
+	})
+}
+
+// maxRequestBody caps request bodies, which are all small JSON objects
+const maxRequestBody = 1 << 20
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Debug("Failed to write remote response", logger.Error(err))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
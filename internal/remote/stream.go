@@ -0,0 +1,63 @@
+package remote
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/logger"
+)
+
+// streamRetryAfter is the Retry-After sent, in seconds, when every stream
+// slot is taken
+const streamRetryAfter = 30
+
+// handleStream serves a library track's audio file as it is stored, with
+// range requests so clients can seek
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	track, err := s.backend.GetTrack(r.PathValue("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrTrackNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+
+	release, ok := s.acquireStream()
+	if !ok {
+		s.mu.Lock()
+		enabled := s.streams != nil
+		s.mu.Unlock()
+		if !enabled {
+			writeError(w, http.StatusForbidden, errors.New("streaming is disabled"))
+			return
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(streamRetryAfter))
+		writeError(w, http.StatusServiceUnavailable, errors.New("too many streams"))
+		return
+	}
+	defer release()
+
+	file, err := os.Open(track.FilePath)
+	if err != nil {
+		writeError(w, http.StatusNotFound, errors.New("track file is missing"))
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	logger.Debug("Streaming track",
+		logger.String("track", track.ID),
+		logger.String("client", clientIP(r)))
+	http.ServeContent(w, r, filepath.Base(track.FilePath), info.ModTime(), file)
+}
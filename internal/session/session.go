@@ -0,0 +1,28 @@
+// Package session reports changes to the user's desktop session, such as
+// the workstation being locked and unlocked
+package session
+
+import "errors"
+
+// ErrUnavailable is returned by Watch where session notifications can't be
+// received
+var ErrUnavailable = errors.New("session notifications are not available")
+
+// Change is a change to the user's session
+type Change int
+
+const (
+	Locked Change = iota
+	Unlocked
+)
+
+func (c Change) String() string {
+	switch c {
+	case Locked:
+		return "locked"
+	case Unlocked:
+		return "unlocked"
+	default:
+		return "unknown"
+	}
+}
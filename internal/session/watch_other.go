@@ -0,0 +1,10 @@
+//go:build !windows
+
+package session
+
+import "context"
+
+// Watch is not supported outside Windows
+func Watch(ctx context.Context, handler func(Change)) error {
+	return ErrUnavailable
+}
@@ -0,0 +1,197 @@
+//go:build windows
+
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	wmDestroy          = 0x0002 // WM_DESTROY
+	wmClose            = 0x0010 // WM_CLOSE
+	wmWTSSessionChange = 0x02B1 // WM_WTSSESSION_CHANGE
+
+	wtsSessionLock       = 0x7 // WTS_SESSION_LOCK
+	wtsSessionUnlock     = 0x8 // WTS_SESSION_UNLOCK
+	notifyForThisSession = 0   // NOTIFY_FOR_THIS_SESSION
+
+	errorClassAlreadyExists = 1410 // ERROR_CLASS_ALREADY_EXISTS
+)
+
+// hwndMessage is HWND_MESSAGE, the parent of message-only windows
+var hwndMessage = ^uintptr(2)
+
+var (
+	user32   = syscall.NewLazyDLL("user32.dll")
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+	wtsapi32 = syscall.NewLazyDLL("wtsapi32.dll")
+
+	procRegisterClassEx = user32.NewProc("RegisterClassExW")
+	procCreateWindowEx  = user32.NewProc("CreateWindowExW")
+	procDestroyWindow   = user32.NewProc("DestroyWindow")
+	procDefWindowProc   = user32.NewProc("DefWindowProcW")
+	procGetMessage      = user32.NewProc("GetMessageW")
+	procDispatchMessage = user32.NewProc("DispatchMessageW")
+	procPostMessage     = user32.NewProc("PostMessageW")
+	procPostQuitMessage = user32.NewProc("PostQuitMessage")
+	procGetModuleHandle = kernel32.NewProc("GetModuleHandleW")
+	procWTSRegister     = wtsapi32.NewProc("WTSRegisterSessionNotification")
+	procWTSUnRegister   = wtsapi32.NewProc("WTSUnRegisterSessionNotification")
+)
+
+// wndClassEx mirrors WNDCLASSEXW
+type wndClassEx struct {
+	size       uint32
+	style      uint32
+	wndProc    uintptr
+	clsExtra   int32
+	wndExtra   int32
+	instance   uintptr
+	icon       uintptr
+	cursor     uintptr
+	background uintptr
+	menuName   *uint16
+	className  *uint16
+	iconSm     uintptr
+}
+
+// msg mirrors MSG
+type msg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	pt      [2]int32
+	private uint32
+}
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+	className    = syscall.StringToUTF16Ptr("WinRampSessionWatcher")
+
+	handlersMu sync.Mutex
+	handlers   = make(map[uintptr]func(Change))
+)
+
+// Watch calls handler whenever the workstation is locked or unlocked until
+// the context is cancelled. Notifications arrive on a hidden window with a
+// thread of its own; handler runs on that thread and should return quickly.
+func Watch(ctx context.Context, handler func(Change)) error {
+	ready := make(chan error, 1)
+	go run(ctx, handler, ready)
+	return <-ready
+}
+
+func run(ctx context.Context, handler func(Change), ready chan<- error) {
+	// Window messages go to the thread that created the window
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hwnd, err := createWindow()
+	if err != nil {
+		ready <- err
+		return
+	}
+
+	handlersMu.Lock()
+	handlers[hwnd] = handler
+	handlersMu.Unlock()
+	defer func() {
+		handlersMu.Lock()
+		delete(handlers, hwnd)
+		handlersMu.Unlock()
+	}()
+
+	if ret, _, err := procWTSRegister.Call(hwnd, notifyForThisSession); ret == 0 {
+		procDestroyWindow.Call(hwnd)
+		ready <- fmt.Errorf("%w: %v", ErrUnavailable, err)
+		return
+	}
+	defer procWTSUnRegister.Call(hwnd)
+
+	ready <- nil
+
+	go func() {
+		<-ctx.Done()
+		procPostMessage.Call(hwnd, wmClose, 0, 0)
+	}()
+
+	var m msg
+	for {
+		ret, _, _ := procGetMessage.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+		if int32(ret) <= 0 {
+			return
+		}
+		procDispatchMessage.Call(uintptr(unsafe.Pointer(&m)))
+	}
+}
+
+// createWindow creates a message-only window, registering its class the
+// first time. Callbacks are never freed, so the class and its window
+// procedure are shared by every watcher.
+func createWindow() (uintptr, error) {
+	instance, _, _ := procGetModuleHandle.Call(0)
+
+	registerOnce.Do(func() {
+		class := wndClassEx{
+			wndProc:   syscall.NewCallback(wndProc),
+			instance:  instance,
+			className: className,
+		}
+		class.size = uint32(unsafe.Sizeof(class))
+		if ret, _, err := procRegisterClassEx.Call(uintptr(unsafe.Pointer(&class))); ret == 0 {
+			var errno syscall.Errno
+			if !errors.As(err, &errno) || errno != errorClassAlreadyExists {
+				registerErr = fmt.Errorf("failed to register window class: %w", err)
+			}
+		}
+	})
+	if registerErr != nil {
+		return 0, registerErr
+	}
+
+	hwnd, _, err := procCreateWindowEx.Call(0,
+		uintptr(unsafe.Pointer(className)), 0, 0,
+		0, 0, 0, 0,
+		hwndMessage, 0, instance, 0)
+	if hwnd == 0 {
+		return 0, fmt.Errorf("failed to create window: %w", err)
+	}
+	return hwnd, nil
+}
+
+func wndProc(hwnd uintptr, message uint32, wParam, lParam uintptr) uintptr {
+	switch message {
+	case wmWTSSessionChange:
+		handlersMu.Lock()
+		handler := handlers[hwnd]
+		handlersMu.Unlock()
+
+		if handler != nil {
+			switch wParam {
+			case wtsSessionLock:
+				handler(Locked)
+			case wtsSessionUnlock:
+				handler(Unlocked)
+			}
+		}
+		return 0
+	case wmClose:
+		procDestroyWindow.Call(hwnd)
+		return 0
+	case wmDestroy:
+		procPostQuitMessage.Call(0)
+		return 0
+	}
+
+	ret, _, _ := procDefWindowProc.Call(hwnd, uintptr(message), wParam, lParam)
+	return ret
+}
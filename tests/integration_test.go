@@ -1,9 +1,16 @@
 package tests
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,10 +20,13 @@ import (
 	"github.com/winramp/winramp/internal/audio"
 	"github.com/winramp/winramp/internal/audio/decoder"
 	"github.com/winramp/winramp/internal/config"
+	"github.com/winramp/winramp/internal/connectivity"
 	"github.com/winramp/winramp/internal/domain"
+	"github.com/winramp/winramp/internal/events"
 	"github.com/winramp/winramp/internal/infrastructure/db"
 	"github.com/winramp/winramp/internal/library"
 	"github.com/winramp/winramp/internal/playlist"
+	"github.com/winramp/winramp/internal/remote"
 )
 
 func TestIntegration_FullPlaybackFlow(t *testing.T) {
@@ -27,7 +37,7 @@ func TestIntegration_FullPlaybackFlow(t *testing.T) {
 	}
 	
 	// Initialize components
-	cfg := config.Get()
+	config.Get()
 	database := setupTestDatabase(t)
 	defer database.Close()
 	
@@ -113,6 +123,317 @@ func TestIntegration_PlaylistManagement(t *testing.T) {
 	assert.False(t, queue.IsEmpty())
 }
 
+func TestIntegration_QueueNavigation(t *testing.T) {
+	queue := playlist.NewQueue()
+	track1, _ := domain.NewTrack("track1.mp3")
+	track2, _ := domain.NewTrack("track2.mp3")
+	track3, _ := domain.NewTrack("track3.mp3")
+	queue.AddAll([]*domain.Track{track1, track2, track3})
+	
+	// Nothing is current until the first track plays
+	assert.Nil(t, queue.Current())
+	assert.Equal(t, track1, queue.Next())
+	
+	// Repeat one replays a finished track but not a skipped one
+	queue.SetRepeat(playlist.RepeatOne)
+	assert.Equal(t, track1, queue.Peek())
+	assert.Equal(t, track1, queue.Advance())
+	assert.Equal(t, track2, queue.Next())
+	
+	// A gapless transition moves the queue only onto the expected track
+	queue.SetRepeat(playlist.RepeatOff)
+	assert.False(t, queue.AdvanceTo(track1))
+	assert.True(t, queue.AdvanceTo(track3))
+	assert.Equal(t, track3, queue.Current())
+	assert.Nil(t, queue.Next())
+	
+	jumped, err := queue.JumpTo(1)
+	require.NoError(t, err)
+	assert.Equal(t, track2, jumped)
+	_, err = queue.JumpTo(3)
+	assert.ErrorIs(t, err, playlist.ErrQueueIndex)
+}
+
+func TestIntegration_QueueDuration(t *testing.T) {
+	queue := playlist.NewQueue()
+	track1, _ := domain.NewTrack("track1.mp3")
+	track2, _ := domain.NewTrack("track2.mp3")
+	track3, _ := domain.NewTrack("track3.mp3")
+	track1.Duration = 3 * time.Minute
+	track2.Duration = 4 * time.Minute
+	queue.AddAll([]*domain.Track{track1, track2, track3})
+	queue.Next()
+	
+	duration := queue.Duration()
+	assert.Equal(t, 7*time.Minute, duration.Total)
+	assert.Equal(t, 4*time.Minute, duration.Upcoming, "the current track is not upcoming")
+	assert.Equal(t, 1, duration.Unknown)
+	assert.False(t, duration.Endless)
+	
+	queue.SetRepeat(playlist.RepeatAll)
+	assert.True(t, queue.Duration().Endless)
+}
+
+func TestIntegration_PreviousFromHistory(t *testing.T) {
+	mgr := playlist.NewManager(nil)
+	track1, _ := domain.NewTrack("track1.mp3")
+	track2, _ := domain.NewTrack("track2.mp3")
+	track3, _ := domain.NewTrack("track3.mp3")
+	
+	mgr.PlayTracks([]*domain.Track{track1, track2}, true)
+	assert.Nil(t, mgr.GetPreviousTrack(), "nothing was played before the first track")
+	
+	mgr.GetNextTrack()
+	mgr.PlayTracks([]*domain.Track{track3}, true)
+	
+	// Going back works even though the queue was replaced
+	assert.Equal(t, track2, mgr.GetPreviousTrack())
+	assert.Equal(t, track1, mgr.GetPreviousTrack())
+	
+	history := mgr.GetHistory(0)
+	require.Len(t, history, 1)
+	assert.Equal(t, track1, history[0].Track)
+	assert.False(t, history[0].PlayedAt.IsZero())
+}
+
+func TestIntegration_EventBus(t *testing.T) {
+	bus := events.NewBus()
+	defer bus.Close()
+	
+	mgr := playlist.NewManager(nil)
+	mgr.SetEventBus(bus)
+	
+	changed := make(chan string, 1)
+	all := make(chan events.Event, 2)
+	events.Subscribe(bus, playlist.TopicPlaylistChanged, func(id string) {
+		changed <- id
+	})
+	unsubscribe := bus.SubscribeAll(func(event events.Event) {
+		all <- event
+	})
+	
+	pl, err := mgr.Create("Events")
+	require.NoError(t, err)
+	
+	select {
+	case id := <-changed:
+		assert.Equal(t, pl.ID, id)
+	case <-time.After(time.Second):
+		t.Fatal("playlist change was not delivered")
+	}
+	select {
+	case event := <-all:
+		assert.Equal(t, playlist.TopicPlaylistChanged.Name(), event.Topic)
+	case <-time.After(time.Second):
+		t.Fatal("event was not delivered to SubscribeAll")
+	}
+	
+	// Unsubscribed handlers hear nothing more
+	unsubscribe()
+	require.NoError(t, mgr.Delete(pl.ID))
+	select {
+	case event := <-all:
+		t.Fatalf("unexpected event %s", event.Topic)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestIntegration_OfflineMode(t *testing.T) {
+	bus := events.NewBus()
+	defer bus.Close()
+	
+	statuses := make(chan connectivity.Status, 4)
+	events.Subscribe(bus, connectivity.TopicChanged, func(status connectivity.Status) {
+		statuses <- status
+	})
+	
+	monitor := connectivity.NewMonitor("", bus)
+	monitor.SetOfflineMode(true)
+	assert.False(t, monitor.Online())
+	
+	// Work waits for the connection, keeping only the latest per key
+	ran := make(chan string, 4)
+	monitor.WhenOnline("art", func() { ran <- "art 1" })
+	monitor.WhenOnline("art", func() { ran <- "art 2" })
+	monitor.WhenOnline("context", func() { ran <- "context" })
+	select {
+	case work := <-ran:
+		t.Fatalf("%s ran while offline", work)
+	case <-time.After(50 * time.Millisecond):
+	}
+	
+	monitor.SetOfflineMode(false)
+	assert.True(t, monitor.Online())
+	for _, want := range []string{"art 2", "context"} {
+		select {
+		case work := <-ran:
+			assert.Equal(t, want, work)
+		case <-time.After(time.Second):
+			t.Fatalf("%s did not run once online", want)
+		}
+	}
+	
+	for _, online := range []bool{false, true} {
+		select {
+		case status := <-statuses:
+			assert.Equal(t, online, status.Online)
+			assert.Equal(t, !online, status.OfflineMode)
+		case <-time.After(time.Second):
+			t.Fatal("status change was not published")
+		}
+	}
+}
+
+func TestIntegration_RemoteControl(t *testing.T) {
+	bus := events.NewBus()
+	defer bus.Close()
+	
+	backend := &mockRemoteBackend{state: "stopped"}
+	remoteServer := remote.NewServer(backend, bus, &mockRemoteClientRepo{clients: make(map[string]*domain.RemoteClient)})
+	server := httptest.NewServer(remoteServer.Handler())
+	defer server.Close()
+	
+	request := func(method, path, token, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	pair := func(scope domain.ClientScope, name string) string {
+		pin, _, err := remoteServer.StartPairing(scope)
+		require.NoError(t, err)
+		resp := request("POST", "/v1/pair", "", `{"pin":"`+pin+`","name":"`+name+`"}`)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var paired map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&paired))
+		return paired["token"].(string)
+	}
+	
+	// Clients must pair before using the API
+	resp := request("POST", "/v1/player/play", "", "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	
+	_, _, err := remoteServer.StartPairing(domain.ScopeControl)
+	require.NoError(t, err)
+	resp = request("POST", "/v1/pair", "", `{"pin":"not-the-pin","name":"guess"}`)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	
+	token := pair(domain.ScopeControl, "script")
+	
+	resp = request("POST", "/v1/player/play", token, "")
+	var state map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&state))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "playing", state["state"])
+	
+	// Bad input is rejected before reaching the player
+	resp = request("POST", "/v1/player/seek", token, `{}`)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	
+	resp = request("POST", "/v1/queue", token, `{"ids":["a","b"],"next":true}`)
+	resp.Body.Close()
+	assert.Equal(t, []string{"a", "b"}, backend.enqueued)
+	
+	// Read-only clients can look but not touch, and only admins manage clients
+	viewer := pair(domain.ScopeRead, "display")
+	resp = request("GET", "/v1/player", viewer, "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = request("POST", "/v1/player/pause", viewer, "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = request("GET", "/v1/clients", token, "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	
+	// Revoked tokens stop working
+	admin := pair(domain.ScopeAdmin, "admin")
+	resp = request("GET", "/v1/clients", admin, "")
+	var listed struct {
+		Clients []*domain.RemoteClient `json:"clients"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	resp.Body.Close()
+	require.Len(t, listed.Clients, 3)
+	for _, client := range listed.Clients {
+		if client.Name == "display" {
+			resp = request("DELETE", "/v1/clients/"+client.ID, admin, "")
+			resp.Body.Close()
+			assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		}
+	}
+	resp = request("GET", "/v1/player", viewer, "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	
+	// Tracks stream with range support
+	audioPath := filepath.Join(t.TempDir(), "song.mp3")
+	require.NoError(t, os.WriteFile(audioPath, []byte("0123456789"), 0644))
+	backend.tracks = map[string]*domain.Track{"t1": {ID: "t1", FilePath: audioPath}}
+	req, err := http.NewRequest("GET", server.URL+"/v1/library/tracks/t1/stream", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Range", "bytes=2-5")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "2345", string(body))
+	
+	remoteServer.SetLimits(remote.Limits{RequestsPerSecond: 1, Burst: 1})
+	resp = request("GET", "/v1/library/tracks/t1/stream", token, "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "streaming is off without stream slots")
+	resp = request("GET", "/v1/player", token, "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+	remoteServer.SetLimits(remote.DefaultLimits())
+	
+	// Unversioned routes don't exist
+	resp = request("GET", "/player", token, "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	
+	// Events arrive named after their topic, with enumerations by name
+	resp = request("GET", "/v1/events?topics=player:stateChanged", token, "")
+	defer resp.Body.Close()
+	
+	// Keep publishing until the stream has subscribed
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(20 * time.Millisecond):
+				events.Publish(bus, audio.TopicPositionChanged, time.Second)
+				events.Publish(bus, audio.TopicStateChanged, audio.StatePaused)
+			}
+		}
+	}()
+	
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: player:stateChanged\n", line)
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: \"paused\"\n", line)
+}
+
 func TestIntegration_LibraryScanning(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping library scanning test in short mode")
@@ -292,4 +613,105 @@ func (r *mockPlaylistRepo) GetVersion(playlistID string, version int) (*domain.P
 
 func (r *mockPlaylistRepo) Count() (int64, error) {
 	return int64(len(r.playlists)), nil
-}
\ No newline at end of file
+}
+type mockRemoteBackend struct {
+	state    string
+	enqueued []string
+	tracks   map[string]*domain.Track
+}
+
+func (b *mockRemoteBackend) Play() error {
+	b.state = "playing"
+	return nil
+}
+
+func (b *mockRemoteBackend) Pause() error {
+	b.state = "paused"
+	return nil
+}
+
+func (b *mockRemoteBackend) Stop() error {
+	b.state = "stopped"
+	return nil
+}
+
+func (b *mockRemoteBackend) Next() error                  { return nil }
+func (b *mockRemoteBackend) Previous() error              { return nil }
+func (b *mockRemoteBackend) Seek(seconds float64) error   { return nil }
+func (b *mockRemoteBackend) SetVolume(volume float64) error { return nil }
+func (b *mockRemoteBackend) JumpTo(index int) error       { return nil }
+
+func (b *mockRemoteBackend) GetPlayerState() map[string]interface{} {
+	return map[string]interface{}{"state": b.state}
+}
+
+func (b *mockRemoteBackend) GetQueueTracks() []map[string]interface{} {
+	return nil
+}
+
+func (b *mockRemoteBackend) PlayTracks(ids []string, replaceQueue bool) error {
+	return nil
+}
+
+func (b *mockRemoteBackend) EnqueueTracks(ids []string, next bool) (int, error) {
+	b.enqueued = append(b.enqueued, ids...)
+	return len(ids), nil
+}
+
+func (b *mockRemoteBackend) SearchTracks(query string) []map[string]interface{} {
+	return nil
+}
+
+func (b *mockRemoteBackend) GetTrack(id string) (*domain.Track, error) {
+	if track, ok := b.tracks[id]; ok {
+		return track, nil
+	}
+	return nil, domain.ErrTrackNotFound
+}
+
+type mockRemoteClientRepo struct {
+	mu      sync.Mutex
+	clients map[string]*domain.RemoteClient
+}
+
+func (r *mockRemoteClientRepo) Create(client *domain.RemoteClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[client.ID] = client
+	return nil
+}
+
+func (r *mockRemoteClientRepo) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.clients[id]; !ok {
+		return domain.ErrRemoteClientNotFound
+	}
+	delete(r.clients, id)
+	return nil
+}
+
+func (r *mockRemoteClientRepo) FindAll() ([]*domain.RemoteClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make([]*domain.RemoteClient, 0, len(r.clients))
+	for _, client := range r.clients {
+		result = append(result, client)
+	}
+	return result, nil
+}
+
+func (r *mockRemoteClientRepo) FindByTokenHash(hash string) (*domain.RemoteClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, client := range r.clients {
+		if client.TokenHash == hash {
+			return client, nil
+		}
+	}
+	return nil, domain.ErrRemoteClientNotFound
+}
+
+func (r *mockRemoteClientRepo) UpdateLastSeen(id string, at time.Time) error {
+	return nil
+}